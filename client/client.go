@@ -0,0 +1,227 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client is a Go client of the version 2 of the Snap REST API.
+// Each operation of the API is a method of Client taking and returning the
+// types of the API, so that tools automating Snap don't have to build the
+// HTTP requests and decode the JSON bodies.
+//
+//	c, err := client.New("http://localhost:8181", client.APIKey("..."))
+//	tasks, err := c.GetTasks()
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/auth"
+)
+
+// APIVersion is the version of the REST API used by the client
+const APIVersion = "v2"
+
+// Client calls the REST API of a Snap daemon.  It is safe for concurrent use.
+type Client struct {
+	// URL of the daemon, e.g. http://localhost:8181
+	URL string
+
+	http     *http.Client
+	username string
+	password string
+	apiKey   string
+	token    string
+}
+
+// Option configures a Client created by New
+type Option func(c *Client)
+
+// BasicAuth authenticates the requests with a user name and password.
+func BasicAuth(username, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// APIKey authenticates the requests with an API key.
+func APIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// BearerToken authenticates the requests with a JWT.
+func BearerToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// Timeout bounds the duration of the requests; the tasks are watched
+// without timeout.
+func Timeout(t time.Duration) Option {
+	return func(c *Client) {
+		c.http.Timeout = t
+	}
+}
+
+// Insecure skips the verification of the certificate of the daemon.
+func Insecure() Option {
+	return func(c *Client) {
+		c.http.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+}
+
+// HTTPClient sends the requests with the given HTTP client, e.g. one
+// presenting a client certificate.
+func HTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// New returns a client of the daemon listening at the URL.
+func New(rawurl string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("URL %s is not in the format of http(s)://<ip>:<port>", rawurl)
+	}
+	c := &Client{
+		URL:  strings.TrimSuffix(rawurl, "/"),
+		http: &http.Client{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Error is returned when the daemon answers a request with an error.
+type Error struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+	Message    string
+	Fields     map[string]string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (status %d)", e.Message, e.StatusCode)
+}
+
+// IsNotFound returns true when the error tells that the requested plugin,
+// task or metric does not exist.
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+// newRequest returns a request of the path under the version of the API
+func (c *Client) newRequest(method, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	u := c.URL + "/" + APIVersion + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	case c.apiKey != "":
+		req.Header.Set(auth.APIKeyHeader, c.apiKey)
+	case c.password != "":
+		username := c.username
+		if username == "" {
+			username = "snap"
+		}
+		req.SetBasicAuth(username, c.password)
+	}
+	return req, nil
+}
+
+// doJSON sends the value in as the JSON body of the request, then decodes
+// the body of the response in out; both are optional.
+func (c *Client) doJSON(method, path string, query url.Values, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := c.newRequest(method, path, query, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.do(req, out)
+}
+
+// do sends the request and decodes the body of the response in out
+func (c *Client) do(req *http.Request, out interface{}) error {
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if err := responseError(rsp); err != nil {
+		return err
+	}
+	if out == nil || rsp.StatusCode == http.StatusNoContent {
+		io.Copy(ioutil.Discard, rsp.Body)
+		return nil
+	}
+	return json.NewDecoder(rsp.Body).Decode(out)
+}
+
+// responseError returns the error sent by the daemon, if any
+func responseError(rsp *http.Response) error {
+	if rsp.StatusCode < 400 {
+		return nil
+	}
+	b, _ := ioutil.ReadAll(rsp.Body)
+	e := &Error{StatusCode: rsp.StatusCode}
+	var body struct {
+		Message string            `json:"message"`
+		Fields  map[string]string `json:"fields"`
+	}
+	if err := json.Unmarshal(b, &body); err == nil && body.Message != "" {
+		e.Message = body.Message
+		e.Fields = body.Fields
+	} else {
+		e.Message = strings.TrimSpace(string(b))
+		if e.Message == "" {
+			e.Message = http.StatusText(rsp.StatusCode)
+		}
+	}
+	return e
+}
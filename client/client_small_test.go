@@ -0,0 +1,175 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/v2"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeDaemon answers the requests of the client like the REST API of a
+// daemon with a single task
+func fakeDaemon(requests *[]*http.Request) http.Handler {
+	write := func(w http.ResponseWriter, code int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(body)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v2/tasks":
+			write(w, 200, v2.TasksResponse{Tasks: v2.Tasks{{ID: "t1", Name: "task-1"}}})
+		case "POST /v2/tasks":
+			var tr struct{ Name string }
+			json.NewDecoder(r.Body).Decode(&tr)
+			write(w, 201, v2.Task{ID: "t2", Name: tr.Name, TaskState: "Stopped"})
+		case "POST /v2/templates/cpu/tasks":
+			var tr struct{ Variables map[string]string }
+			json.NewDecoder(r.Body).Decode(&tr)
+			write(w, 201, v2.Task{ID: "t3", Name: "cpu-" + tr.Variables["host"], TaskState: "Stopped"})
+		case "PUT /v2/tasks/t1":
+			w.WriteHeader(204)
+		case "GET /v2/tasks/missing":
+			write(w, 404, v2.Error{ErrorMessage: "task not found", Fields: map[string]string{"id": "missing"}})
+		case "POST /v2/plugins":
+			f, h, err := r.FormFile("plugin_data")
+			if err != nil {
+				write(w, 400, v2.FromError(err))
+				return
+			}
+			b, _ := ioutil.ReadAll(f)
+			write(w, 201, v2.Plugin{Name: h.Filename, Type: "collector", Version: len(b)})
+		case "GET /v2/metrics":
+			write(w, 200, v2.MetricsResonse{Metrics: v2.Metrics{{Namespace: r.URL.Query().Get("ns") + "/foo", Version: 1}}})
+		case "GET /v2/tasks/t1/watch":
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, e := range []v2.StreamedTaskEvent{
+				{EventType: v2.TaskWatchStreamOpen},
+				{EventType: v2.TaskWatchMetricEvent, Event: v2.StreamedMetrics{{Namespace: "/intel/mock/foo", Data: strings.Repeat("x", 100000)}}},
+				{EventType: v2.TaskWatchTaskStopped},
+			} {
+				fmt.Fprintf(w, "data: %s\n\n", e.ToJSON())
+			}
+			w.(http.Flusher).Flush()
+			// the daemon keeps the stream open once the task is stopped
+			<-r.Context().Done()
+		default:
+			w.WriteHeader(500)
+		}
+	})
+}
+
+func TestClient(t *testing.T) {
+	Convey("Given a client of a daemon", t, func() {
+		requests := []*http.Request{}
+		srv := httptest.NewServer(fakeDaemon(&requests))
+		defer srv.Close()
+		c, err := New(srv.URL, APIKey("secret"))
+		So(err, ShouldBeNil)
+
+		Convey("an invalid URL is rejected", func() {
+			_, err := New("localhost:8181")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("the requests are authenticated", func() {
+			_, err := c.GetTasks()
+			So(err, ShouldBeNil)
+			So(requests[0].Header.Get("X-Api-Key"), ShouldEqual, "secret")
+
+			c, _ = New(srv.URL, BasicAuth("", "pass"))
+			c.GetTasks()
+			user, pass, ok := requests[1].BasicAuth()
+			So(ok, ShouldBeTrue)
+			So(user, ShouldEqual, "snap")
+			So(pass, ShouldEqual, "pass")
+		})
+		Convey("tasks are created, listed and started", func() {
+			task, err := c.CreateTask(&core.TaskCreationRequest{Name: "task-2"})
+			So(err, ShouldBeNil)
+			So(task.ID, ShouldEqual, "t2")
+			So(task.Name, ShouldEqual, "task-2")
+
+			tasks, err := c.GetTasks()
+			So(err, ShouldBeNil)
+			So(tasks, ShouldHaveLength, 1)
+			So(tasks[0].ID, ShouldEqual, "t1")
+
+			So(c.StartTask("t1"), ShouldBeNil)
+			So(requests[2].URL.Query().Get("action"), ShouldEqual, "start")
+		})
+		Convey("a task is created from a template", func() {
+			task, err := c.CreateTaskFromTemplate("cpu", &core.TaskCreationRequest{Variables: map[string]string{"host": "db1"}})
+			So(err, ShouldBeNil)
+			So(task.Name, ShouldEqual, "cpu-db1")
+		})
+		Convey("the errors of the daemon are returned typed", func() {
+			_, err := c.GetTask("missing")
+			So(err, ShouldNotBeNil)
+			So(IsNotFound(err), ShouldBeTrue)
+			So(err.(*Error).Message, ShouldEqual, "task not found")
+			So(err.(*Error).Fields, ShouldResemble, map[string]string{"id": "missing"})
+		})
+		Convey("a plugin binary is loaded", func() {
+			dir, err := ioutil.TempDir("", "snap-client")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "snap-plugin-collector-mock")
+			So(ioutil.WriteFile(path, []byte("binary"), 0700), ShouldBeNil)
+
+			p, err := c.LoadPlugin(path, LoadOptions{})
+			So(err, ShouldBeNil)
+			So(p.Name, ShouldEqual, "snap-plugin-collector-mock")
+			So(p.Version, ShouldEqual, len("binary"))
+		})
+		Convey("the catalog is queried by namespace", func() {
+			mts, err := c.FetchMetrics("/intel/mock/*", 0)
+			So(err, ShouldBeNil)
+			So(mts, ShouldHaveLength, 1)
+			So(mts[0].Namespace, ShouldEqual, "/intel/mock/*/foo")
+		})
+		Convey("a task is watched until it stops", func() {
+			w, err := c.WatchTask("t1")
+			So(err, ShouldBeNil)
+			events := []v2.StreamedTaskEvent{}
+			for e := range w.Events {
+				events = append(events, e)
+			}
+			So(w.Err(), ShouldBeNil)
+			So(events, ShouldHaveLength, 2)
+			So(events[0].EventType, ShouldEqual, v2.TaskWatchMetricEvent)
+			So(events[0].Event[0].Namespace, ShouldEqual, "/intel/mock/foo")
+			So(events[0].Event[0].Data, ShouldHaveLength, 100000)
+			So(events[1].EventType, ShouldEqual, v2.TaskWatchTaskStopped)
+		})
+	})
+}
@@ -0,0 +1,78 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/v2"
+)
+
+// GetMetricCatalog returns all the metrics of the catalog.
+func (c *Client) GetMetricCatalog() ([]v2.Metric, error) {
+	return c.getMetrics(nil)
+}
+
+// FetchMetrics returns the metrics of the catalog under the namespace,
+// e.g. /intel/mock/*; all the versions of the metrics are returned when
+// ver is 0.
+func (c *Client) FetchMetrics(ns string, ver int) ([]v2.Metric, error) {
+	q := url.Values{"ns": {ns}}
+	if ver > 0 {
+		q.Set("ver", strconv.Itoa(ver))
+	}
+	return c.getMetrics(q)
+}
+
+// SearchMetrics returns a page of the metrics of the catalog selected by the
+// options, and the key to search the next page after, empty on the last
+// page.
+func (c *Client) SearchMetrics(o core.MetricSearchOptions) ([]v2.Metric, string, error) {
+	q := url.Values{"limit": {strconv.Itoa(o.Limit)}}
+	if o.Namespace != "" {
+		q.Set("ns", o.Namespace)
+	}
+	if o.Plugin != "" {
+		q.Set("plugin", o.Plugin)
+	}
+	if o.Version > 0 {
+		q.Set("ver", strconv.Itoa(o.Version))
+	}
+	if !o.Since.IsZero() {
+		q.Set("since", strconv.FormatInt(o.Since.Unix(), 10))
+	}
+	if o.After != "" {
+		q.Set("after", o.After)
+	}
+	var rsp v2.MetricsResonse
+	if err := c.doJSON("GET", "/metrics", q, nil, &rsp); err != nil {
+		return nil, "", err
+	}
+	return rsp.Metrics, rsp.Next, nil
+}
+
+func (c *Client) getMetrics(q url.Values) ([]v2.Metric, error) {
+	var rsp v2.MetricsResonse
+	if err := c.doJSON("GET", "/metrics", q, nil, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp.Metrics, nil
+}
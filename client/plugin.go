@@ -0,0 +1,189 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/mgmt/rest/v2"
+)
+
+// PluginFilter selects the plugins listed by GetPlugins; the zero value
+// selects all the loaded plugins.
+type PluginFilter struct {
+	Type string
+	Name string
+	// Running lists the running instances of the plugins instead
+	Running bool
+}
+
+// LoadOptions are the optional settings of a plugin loaded by LoadPlugin.
+type LoadOptions struct {
+	// Signature is the path of the signature file (.asc) of the plugin
+	Signature string
+	// Upgrade replaces the loaded versions of the plugin, see UpgradePlugin
+	Upgrade bool
+	// MemoryLimit in bytes and CPULimit in cores bound the resources of the
+	// plugin; no limit is set when zero
+	MemoryLimit int64
+	CPULimit    float64
+}
+
+// GetPlugins returns the plugins selected by the filter.
+func (c *Client) GetPlugins(f PluginFilter) ([]v2.Plugin, error) {
+	q := url.Values{}
+	if f.Type != "" {
+		q.Set("type", f.Type)
+	}
+	if f.Name != "" {
+		q.Set("name", f.Name)
+	}
+	if f.Running {
+		q.Set("running", "true")
+	}
+	var rsp v2.PluginsResponse
+	if err := c.doJSON("GET", "/plugins", q, nil, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp.Plugins, nil
+}
+
+// GetPlugin returns a loaded plugin.
+func (c *Client) GetPlugin(typ, name string, ver int) (*v2.Plugin, error) {
+	p := &v2.Plugin{}
+	if err := c.doJSON("GET", pluginPath(typ, name, ver), nil, nil, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// LoadPlugin sends the plugin binary at path to the daemon and loads it.
+func (c *Client) LoadPlugin(path string, opts LoadOptions) (*v2.Plugin, error) {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	if err := addFile(w, "plugin_data", path); err != nil {
+		return nil, err
+	}
+	if opts.Signature != "" {
+		if err := addFile(w, "signature", opts.Signature); err != nil {
+			return nil, err
+		}
+	}
+	if opts.MemoryLimit > 0 {
+		w.WriteField("memory_limit", strconv.FormatInt(opts.MemoryLimit, 10))
+	}
+	if opts.CPULimit > 0 {
+		w.WriteField("cpu_limit", strconv.FormatFloat(opts.CPULimit, 'f', -1, 64))
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	var q url.Values
+	if opts.Upgrade {
+		q = url.Values{"upgrade": {"true"}}
+	}
+	req, err := c.newRequest("POST", "/plugins", q, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	p := &v2.Plugin{}
+	if err := c.do(req, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// UpgradePlugin loads the plugin binary at path in place of the loaded
+// versions of the plugin with the same type and name; their running tasks
+// are moved to it.
+func (c *Client) UpgradePlugin(path string) (*v2.Plugin, error) {
+	return c.LoadPlugin(path, LoadOptions{Upgrade: true})
+}
+
+// UnloadPlugin unloads a plugin.
+func (c *Client) UnloadPlugin(typ, name string, ver int) error {
+	return c.doJSON("DELETE", pluginPath(typ, name, ver), nil, nil, nil)
+}
+
+// GetPluginConfig returns the config of a plugin.
+func (c *Client) GetPluginConfig(typ, name string, ver int) (*cdata.ConfigDataNode, error) {
+	cfg := cdata.NewNode()
+	if err := c.doJSON("GET", pluginPath(typ, name, ver)+"/config", nil, nil, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// SetPluginConfig merges the items in the config of a plugin and returns
+// the resulting config.
+func (c *Client) SetPluginConfig(typ, name string, ver int, items map[string]interface{}) (*cdata.ConfigDataNode, error) {
+	cfg := cdata.NewNode()
+	if err := c.doJSON("PUT", pluginPath(typ, name, ver)+"/config", nil, items, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// DeletePluginConfig removes the keys from the config of a plugin and
+// returns the resulting config.
+func (c *Client) DeletePluginConfig(typ, name string, ver int, keys ...string) (*cdata.ConfigDataNode, error) {
+	cfg := cdata.NewNode()
+	if err := c.doJSON("DELETE", pluginPath(typ, name, ver)+"/config", nil, keys, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// GetPluginPolicy returns the rules of the config policy of a plugin by
+// namespace.
+func (c *Client) GetPluginPolicy(typ, name string, ver int) ([]v2.PluginPolicy, error) {
+	var policies v2.PluginPolicies
+	if err := c.doJSON("GET", pluginPath(typ, name, ver)+"/policy", nil, nil, &policies); err != nil {
+		return nil, err
+	}
+	return policies.Policies, nil
+}
+
+func pluginPath(typ, name string, ver int) string {
+	return fmt.Sprintf("/plugins/%s/%s/%d", typ, name, ver)
+}
+
+// addFile writes the file at path in the form field
+func addFile(w *multipart.Writer, field, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	part, err := w.CreateFormFile(field, filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, f)
+	return err
+}
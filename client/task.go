@@ -0,0 +1,219 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/v2"
+)
+
+// GetTasks returns the tasks the caller can access, oldest first.
+func (c *Client) GetTasks() ([]v2.Task, error) {
+	var rsp v2.TasksResponse
+	if err := c.doJSON("GET", "/tasks", nil, nil, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp.Tasks, nil
+}
+
+// GetTask returns a task along with its workflow and schedule.
+func (c *Client) GetTask(id string) (*v2.Task, error) {
+	t := &v2.Task{}
+	if err := c.doJSON("GET", "/tasks/"+id, nil, nil, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// CreateTask creates a task; it is started when the request says so.
+func (c *Client) CreateTask(tr *core.TaskCreationRequest) (*v2.Task, error) {
+	t := &v2.Task{}
+	if err := c.doJSON("POST", "/tasks", nil, tr, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ImportTask creates a task from a manifest in JSON or YAML, e.g. one
+// returned by ExportTask.
+func (c *Client) ImportTask(manifest []byte) (*v2.Task, error) {
+	req, err := c.newRequest("POST", "/tasks/import", nil, bytes.NewReader(manifest))
+	if err != nil {
+		return nil, err
+	}
+	t := &v2.Task{}
+	if err := c.do(req, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ExportTask returns the portable manifest of a task.
+func (c *Client) ExportTask(id string) (*core.TaskCreationRequest, error) {
+	tr := &core.TaskCreationRequest{}
+	if err := c.doJSON("GET", "/tasks/"+id+"/export", nil, nil, tr); err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// GetTaskHistory returns the state transitions of a task, oldest first.
+func (c *Client) GetTaskHistory(id string) (*v2.TaskHistory, error) {
+	h := &v2.TaskHistory{}
+	if err := c.doJSON("GET", "/tasks/"+id+"/history", nil, nil, h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// StartTask starts a task.
+func (c *Client) StartTask(id string) error {
+	return c.updateTaskState(id, "start")
+}
+
+// StopTask stops a task.
+func (c *Client) StopTask(id string) error {
+	return c.updateTaskState(id, "stop")
+}
+
+// EnableTask enables a disabled task; it is not started.
+func (c *Client) EnableTask(id string) error {
+	return c.updateTaskState(id, "enable")
+}
+
+// RemoveTask removes a stopped task.
+func (c *Client) RemoveTask(id string) error {
+	return c.doJSON("DELETE", "/tasks/"+id, nil, nil, nil)
+}
+
+func (c *Client) updateTaskState(id, action string) error {
+	return c.doJSON("PUT", "/tasks/"+id, url.Values{"action": {action}}, nil, nil)
+}
+
+// TaskWatcher receives the events of a task watched with WatchTask.
+type TaskWatcher struct {
+	// Events receives the metrics collected by the task and the changes of
+	// its state.  It is closed when the task stops, ends or is disabled,
+	// when the watcher is closed or when the stream fails; see Err.
+	Events <-chan v2.StreamedTaskEvent
+
+	body io.Closer
+	done chan struct{}
+	once sync.Once
+	mu   sync.Mutex
+	err  error
+}
+
+// maxTaskEventSize is the size of the largest event of a task watch, e.g.
+// the metrics of a run collecting many of them
+const maxTaskEventSize = 16 << 20
+
+// WatchTask streams the events of a task until the watcher is closed.  The
+// watch is not bound by the timeout of the client.
+func (c *Client) WatchTask(id string) (*TaskWatcher, error) {
+	req, err := c.newRequest("GET", "/tasks/"+id+"/watch", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	hc := *c.http
+	hc.Timeout = 0
+	rsp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := responseError(rsp); err != nil {
+		rsp.Body.Close()
+		return nil, err
+	}
+	events := make(chan v2.StreamedTaskEvent)
+	w := &TaskWatcher{
+		Events: events,
+		body:   rsp.Body,
+		done:   make(chan struct{}),
+	}
+	sc := bufio.NewScanner(rsp.Body)
+	sc.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxTaskEventSize)
+	go w.read(sc, events)
+	return w, nil
+}
+
+// read hands the events of the stream to the channel until the end of
+// the watch
+func (w *TaskWatcher) read(sc *bufio.Scanner, events chan<- v2.StreamedTaskEvent) {
+	defer close(events)
+	defer w.Close()
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		var e v2.StreamedTaskEvent
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &e); err != nil {
+			w.setErr(err)
+			return
+		}
+		if e.EventType == v2.TaskWatchStreamOpen {
+			continue
+		}
+		select {
+		case events <- e:
+		case <-w.done:
+			return
+		}
+		switch e.EventType {
+		case v2.TaskWatchTaskStopped, v2.TaskWatchTaskEnded, v2.TaskWatchTaskDisabled:
+			// the daemon stops watching the task
+			return
+		}
+	}
+	select {
+	case <-w.done:
+	default:
+		w.setErr(sc.Err())
+	}
+}
+
+// Err returns the error which ended the watch, if any.
+func (w *TaskWatcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+func (w *TaskWatcher) setErr(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.err = err
+}
+
+// Close ends the watch.
+func (w *TaskWatcher) Close() {
+	w.once.Do(func() {
+		close(w.done)
+		w.body.Close()
+	})
+}
@@ -0,0 +1,64 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/v2"
+)
+
+// GetTemplates returns the task templates, sorted by name.
+func (c *Client) GetTemplates() ([]*core.TaskTemplate, error) {
+	var rsp v2.Templates
+	if err := c.doJSON("GET", "/templates", nil, nil, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp.Templates, nil
+}
+
+// GetTemplate returns a task template.
+func (c *Client) GetTemplate(name string) (*core.TaskTemplate, error) {
+	t := &core.TaskTemplate{}
+	if err := c.doJSON("GET", "/templates/"+name, nil, nil, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// AddTemplate registers a task template.
+func (c *Client) AddTemplate(t *core.TaskTemplate) error {
+	return c.doJSON("POST", "/templates", nil, t, nil)
+}
+
+// RemoveTemplate removes a task template.
+func (c *Client) RemoveTemplate(name string) error {
+	return c.doJSON("DELETE", "/templates/"+name, nil, nil, nil)
+}
+
+// CreateTaskFromTemplate creates a task from a template given the values of
+// its variables in the request; the options the request sets take
+// precedence over the ones of the template.
+func (c *Client) CreateTaskFromTemplate(name string, tr *core.TaskCreationRequest) (*v2.Task, error) {
+	t := &v2.Task{}
+	if err := c.doJSON("POST", "/templates/"+name+"/tasks", nil, tr, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
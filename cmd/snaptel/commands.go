@@ -35,7 +35,7 @@ var (
 				{
 					Name:        "create",
 					Description: "Creates a new task in the snap scheduler",
-					Usage:       "There are two ways to create a task.\n\t1) Use a task manifest with [--task-manifest]\n\t2) Provide a workflow manifest and schedule details.\n\n\t* Note: Start and stop date/time are optional.\n",
+					Usage:       "There are three ways to create a task.\n\t1) Use a task manifest with [--task-manifest, -f]\n\t2) Provide a workflow manifest and schedule details.\n\t3) Use a task template with [--template] and the values of its variables with [--var]\n\n\t* Note: Start and stop date/time are optional.\n",
 					Action:      createTask,
 					Flags: []cli.Flag{
 						flTaskManifest,
@@ -51,14 +51,17 @@ var (
 						flTaskSchedNoStart,
 						flTaskDeadline,
 						flTaskMaxFailures,
+						flTaskTemplate,
+						flTaskTemplateVar,
 					},
 				},
 				{
 					Name:   "list",
-					Usage:  "list",
+					Usage:  "list [--state <state>]",
 					Action: listTask,
 					Flags: []cli.Flag{
 						flVerbose,
+						flTaskState,
 					},
 				},
 				{
@@ -159,11 +162,15 @@ var (
 			Subcommands: []cli.Command{
 				{
 					Name:   "list",
-					Usage:  "list",
+					Usage:  "list [<namespace pattern>]",
 					Action: listMetrics,
 					Flags: []cli.Flag{
 						flMetricVersion,
 						flMetricNamespace,
+						flPluginName,
+						flMetricSince,
+						flMetricLimit,
+						flMetricAfter,
 						flVerbose,
 					},
 				},
@@ -178,6 +185,37 @@ var (
 				},
 			},
 		},
+		{
+			Name: "template",
+			Subcommands: []cli.Command{
+				{
+					Name:   "list",
+					Usage:  "list",
+					Action: listTemplates,
+				},
+				{
+					Name:   "add",
+					Usage:  "add --file <template_file>",
+					Action: addTemplate,
+					Flags: []cli.Flag{
+						flTemplateFile,
+					},
+				},
+				{
+					Name:   "remove",
+					Usage:  "remove <template_name>",
+					Action: removeTemplate,
+				},
+			},
+		},
+		{
+			Name:   "top",
+			Usage:  "Shows a live dashboard of the tasks and the running plugins",
+			Action: top,
+			Flags: []cli.Flag{
+				flRefresh,
+			},
+		},
 	}
 	tribeWarning  = "Can only be used when tribe mode is enabled."
 	tribeCommands = []cli.Command{
@@ -45,6 +45,16 @@ var (
 		Usage:  "Ignore certificate errors when Snap's API is running HTTPS",
 		EnvVar: "SNAP_INSECURE",
 	}
+	flClientCert = cli.StringFlag{
+		Name:   "client-cert",
+		Usage:  "A path to the client certificate presented to Snap's API running HTTPS",
+		EnvVar: "SNAP_CLIENT_CERT",
+	}
+	flClientKey = cli.StringFlag{
+		Name:   "client-key",
+		Usage:  "A path to the private key of the client certificate",
+		EnvVar: "SNAP_CLIENT_KEY",
+	}
 	flRunning = cli.BoolFlag{
 		Name:  "running",
 		Usage: "Shows running plugins",
@@ -103,7 +113,7 @@ var (
 		Value: "",
 	}
 	flTaskManifest = cli.StringFlag{
-		Name:  "task-manifest, t",
+		Name:  "task-manifest, t, f",
 		Usage: "File path for task manifest to use for task creation.",
 	}
 
@@ -152,6 +162,24 @@ var (
 		Name:  "max-failures",
 		Usage: "The number of consecutive failures before Snap disables the task",
 	}
+	flTaskTemplate = cli.StringFlag{
+		Name:  "template",
+		Usage: "Name of the task template to create the task from",
+	}
+	flTaskTemplateVar = cli.StringSliceFlag{
+		Name:  "var",
+		Usage: "Value of a variable of the task template, as name=value [can be repeated]",
+	}
+	flTaskState = cli.StringFlag{
+		Name:  "state",
+		Usage: "List only the tasks in the state [Running, Stopped, Disabled, Ended, Stopping or Suspended]",
+	}
+
+	// template
+	flTemplateFile = cli.StringFlag{
+		Name:  "file, f",
+		Usage: "File path of the task template, in JSON or YAML",
+	}
 
 	// metric
 	flMetricVersion = cli.IntFlag{
@@ -162,6 +190,25 @@ var (
 		Name:  "metric-namespace, m",
 		Usage: "A metric namespace",
 	}
+	flMetricSince = cli.DurationFlag{
+		Name:  "since",
+		Usage: "List only the metrics advertised within the duration [ex: 10m, 1h]",
+	}
+	flMetricLimit = cli.IntFlag{
+		Name:  "limit, l",
+		Usage: "The number of metrics listed per page. 0 (default) lists all the metrics.",
+	}
+	flMetricAfter = cli.StringFlag{
+		Name:  "after",
+		Usage: "List the page of metrics after the key printed with the previous page",
+	}
+
+	// top
+	flRefresh = cli.DurationFlag{
+		Name:  "refresh, r",
+		Usage: "Interval between the refreshes of the dashboard",
+		Value: 2 * time.Second,
+	}
 
 	// general
 	flVerbose = cli.BoolFlag{
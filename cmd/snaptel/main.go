@@ -20,6 +20,7 @@ limitations under the License.
 package main
 
 import (
+	"crypto/tls"
 	"fmt"
 	"os"
 	"sort"
@@ -61,7 +62,7 @@ func main() {
 	app.Name = "snaptel"
 	app.Version = gitversion
 	app.Usage = "The open telemetry framework"
-	app.Flags = []cli.Flag{flURL, flSecure, flAPIVer, flPassword, flConfig, flTimeout}
+	app.Flags = []cli.Flag{flURL, flSecure, flClientCert, flClientKey, flAPIVer, flPassword, flConfig, flTimeout}
 	app.Commands = append(commands, tribeCommands...)
 	sort.Sort(ByCommand(app.Commands))
 	app.Before = beforeAction
@@ -83,6 +84,13 @@ func beforeAction(ctx *cli.Context) error {
 	if err != nil {
 		return fmt.Errorf("%v", err)
 	}
+	if certPath := ctx.String("client-cert"); certPath != "" {
+		cert, err := tls.LoadX509KeyPair(certPath, ctx.String("client-key"))
+		if err != nil {
+			return fmt.Errorf("Unable to load the client certificate: %v", err)
+		}
+		client.ClientCertificate(cert)(pClient)
+	}
 	pClient.Password = password
 	pClient.Username = username
 	if err = checkTribeCommand(ctx); err != nil {
@@ -28,14 +28,19 @@ import (
 	"text/tabwriter"
 	"time"
 
+	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/mgmt/rest/client"
 	"github.com/intelsdi-x/snap/mgmt/rest/v1/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/v2"
 	"github.com/urfave/cli"
 
 	"github.com/intelsdi-x/snap/pkg/stringutils"
 )
 
 func listMetrics(ctx *cli.Context) error {
+	if len(ctx.Args()) > 0 || ctx.IsSet("plugin-name") || ctx.IsSet("since") || ctx.IsSet("limit") || ctx.IsSet("after") {
+		return searchMetrics(ctx)
+	}
 	ns := ctx.String("metric-namespace")
 	ver := ctx.Int("metric-version")
 	verbose := ctx.Bool("verbose")
@@ -100,6 +105,71 @@ func listMetrics(ctx *cli.Context) error {
 	return nil
 }
 
+// searchMetrics lists the metrics matching the namespace pattern given as
+// argument, e.g. /intel/psutil/*, and the flags, a page at a time when a
+// limit is set
+func searchMetrics(ctx *cli.Context) error {
+	c, err := newAPIClient(ctx)
+	if err != nil {
+		return err
+	}
+	opts := core.MetricSearchOptions{
+		Namespace: ctx.String("metric-namespace"),
+		Plugin:    ctx.String("plugin-name"),
+		Version:   ctx.Int("metric-version"),
+		After:     ctx.String("after"),
+		Limit:     ctx.Int("limit"),
+	}
+	if len(ctx.Args()) > 0 {
+		opts.Namespace = ctx.Args().First()
+	}
+	if since := ctx.Duration("since"); since > 0 {
+		opts.Since = time.Now().Add(-since)
+	}
+	mts, next, err := c.SearchMetrics(opts)
+	if err != nil {
+		return fmt.Errorf("Error searching metrics: %v\n", err)
+	}
+	if len(mts) == 0 {
+		fmt.Println("No metrics found.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	if ctx.Bool("verbose") {
+		printFields(w, false, 0, "NAMESPACE", "VERSION", "UNIT", "DESCRIPTION")
+	} else {
+		printFields(w, false, 0, "NAMESPACE", "VERSION", "LAST ADVERTISED TIME")
+	}
+	for _, mt := range mts {
+		namespace := searchedNamespace(mt)
+		if ctx.Bool("verbose") {
+			printFields(w, false, 0, namespace, mt.Version, mt.Unit, mt.Description)
+		} else {
+			printFields(w, false, 0, namespace, mt.Version, time.Unix(mt.LastAdvertisedTimestamp, 0).Format(time.RFC1123))
+		}
+	}
+	w.Flush()
+	if next != "" {
+		fmt.Printf("\nMore metrics are listed with --after %s\n", next)
+	}
+	return nil
+}
+
+// searchedNamespace returns the namespace of the metric with its dynamic
+// elements named, like getNamespace
+func searchedNamespace(mt v2.Metric) string {
+	ns := mt.Namespace
+	if mt.Dynamic {
+		fc := stringutils.GetFirstChar(ns)
+		slice := strings.Split(ns, fc)
+		for _, v := range mt.DynamicElements {
+			slice[v.Index+1] = "[" + v.Name + "]"
+		}
+		ns = strings.Join(slice, fc)
+	}
+	return ns
+}
+
 func printMetric(metric *client.GetMetricResult, idx int) error {
 	if metric.Err != nil {
 		return fmt.Errorf("%v", metric.Err)
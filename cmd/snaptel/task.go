@@ -26,6 +26,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -33,7 +34,9 @@ import (
 	"text/tabwriter"
 	"time"
 
+	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/mgmt/rest/client"
+	"github.com/intelsdi-x/snap/mgmt/rest/v1/rbody"
 	"github.com/intelsdi-x/snap/scheduler/wmap"
 	"github.com/robfig/cron"
 	"github.com/urfave/cli"
@@ -89,14 +92,17 @@ type task struct {
 
 func createTask(ctx *cli.Context) error {
 	var err error
-	if ctx.IsSet("task-manifest") {
+	if ctx.IsSet("template") {
+		fmt.Println("Using task template to create task")
+		err = createTaskUsingTemplate(ctx)
+	} else if ctx.IsSet("task-manifest") {
 		fmt.Println("Using task manifest to create task")
 		err = createTaskUsingTaskManifest(ctx)
 	} else if ctx.IsSet("workflow-manifest") {
 		fmt.Println("Using workflow manifest to create task")
 		err = createTaskUsingWFManifest(ctx)
 	} else {
-		return newUsageError("Must provide either --task-manifest or --workflow-manifest arguments", ctx)
+		return newUsageError("Must provide either --task-manifest, --workflow-manifest or --template arguments", ctx)
 	}
 	return err
 }
@@ -247,6 +253,11 @@ func (t *task) setScheduleFromCliOptions(ctx *cli.Context) error {
 		return nil
 	}
 
+	// A composite schedule is given by the task manifest only
+	if t.Schedule.Type == "composite" {
+		return nil
+	}
+
 	// Grab the interval for the schedule (if one was provided). Note that if an
 	// interval value was not passed in and there is no interval defined for the
 	// schedule associated with this task, it's an error
@@ -536,9 +547,19 @@ func listTask(ctx *cli.Context) error {
 	if tasks.Err != nil {
 		return fmt.Errorf("Error getting tasks:\n%v\n", tasks.Err)
 	}
+	if state := ctx.String("state"); state != "" {
+		if !isTaskState(state) {
+			return newUsageError(fmt.Sprintf("Unknown task state %s", state), ctx)
+		}
+		tasks.ScheduledTasks = filterTasksByState(tasks.ScheduledTasks, state)
+	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
 	if tasks.Len() == 0 {
+		if ctx.IsSet("state") {
+			fmt.Printf("No task found in the state %s.\n", ctx.String("state"))
+			return nil
+		}
 		fmt.Println("No task found. Have you created a task?")
 		return nil
 	}
@@ -575,6 +596,27 @@ func listTask(ctx *cli.Context) error {
 	return nil
 }
 
+// isTaskState returns true when the state names a state of the tasks
+func isTaskState(state string) bool {
+	for _, s := range core.TaskStateLookup {
+		if strings.EqualFold(s, state) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterTasksByState returns the tasks in the state, regardless of its case
+func filterTasksByState(tasks []rbody.ScheduledTask, state string) []rbody.ScheduledTask {
+	filtered := []rbody.ScheduledTask{}
+	for _, t := range tasks {
+		if strings.EqualFold(t.State, state) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
+
 func fixSize(verbose bool, msg string, width int) string {
 	if len(msg) < width {
 		for i := len(msg); i < width; i++ {
@@ -807,7 +849,7 @@ func validateScheduleExists(schedule *client.Schedule) error {
 	if schedule == nil {
 		return fmt.Errorf("Error: Task manifest did not include a schedule")
 	}
-	if *schedule == (client.Schedule{}) {
+	if reflect.DeepEqual(*schedule, client.Schedule{}) {
 		return fmt.Errorf("Error: Task manifest included an empty schedule. Task manifests need to include a schedule.")
 	}
 	return nil
@@ -0,0 +1,151 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ghodss/yaml"
+	"github.com/urfave/cli"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+func listTemplates(ctx *cli.Context) error {
+	c, err := newAPIClient(ctx)
+	if err != nil {
+		return err
+	}
+	templates, err := c.GetTemplates()
+	if err != nil {
+		return fmt.Errorf("Error getting task templates:\n%v\n", err)
+	}
+	if len(templates) == 0 {
+		fmt.Println("No task template found.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	printFields(w, false, 0, "NAME", "VARIABLES", "DESCRIPTION")
+	for _, t := range templates {
+		vars := ""
+		for i, v := range t.Variables {
+			if i > 0 {
+				vars += ","
+			}
+			vars += v.Name
+			if v.Default != nil {
+				vars += "=" + *v.Default
+			}
+		}
+		printFields(w, false, 0, t.Name, vars, t.Description)
+	}
+	w.Flush()
+	return nil
+}
+
+func addTemplate(ctx *cli.Context) error {
+	path := ctx.String("file")
+	if path == "" {
+		return newUsageError("Must provide the template file with --file", ctx)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("File error - %v\n", err)
+	}
+	// JSON is a subset of YAML so both formats are handled by the conversion
+	js, err := yaml.YAMLToJSON(content)
+	if err != nil {
+		return fmt.Errorf("Error parsing the template file - %v\n", err)
+	}
+	t := &core.TaskTemplate{}
+	if err := json.Unmarshal(js, t); err != nil {
+		return fmt.Errorf("Error parsing the template file - %v\n", err)
+	}
+	c, err := newAPIClient(ctx)
+	if err != nil {
+		return err
+	}
+	if err := c.AddTemplate(t); err != nil {
+		return fmt.Errorf("Error adding task template:\n%v\n", err)
+	}
+	fmt.Println("Task template added")
+	fmt.Printf("Name: %s\n", t.Name)
+	return nil
+}
+
+func removeTemplate(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		return newUsageError("Incorrect usage", ctx)
+	}
+	c, err := newAPIClient(ctx)
+	if err != nil {
+		return err
+	}
+	name := ctx.Args().First()
+	if err := c.RemoveTemplate(name); err != nil {
+		return fmt.Errorf("Error removing task template:\n%v\n", err)
+	}
+	fmt.Println("Task template removed")
+	fmt.Printf("Name: %s\n", name)
+	return nil
+}
+
+// createTaskUsingTemplate creates a task from the template given with
+// --template and the values of its variables given with --var
+func createTaskUsingTemplate(ctx *cli.Context) error {
+	tr := &core.TaskCreationRequest{
+		Name:      ctx.String("name"),
+		Deadline:  ctx.String("deadline"),
+		Start:     !ctx.IsSet("no-start"),
+		Variables: map[string]string{},
+	}
+	for _, v := range ctx.StringSlice("var") {
+		i := strings.Index(v, "=")
+		if i <= 0 {
+			return newUsageError(fmt.Sprintf("Invalid template variable '%s', expected name=value", v), ctx)
+		}
+		tr.Variables[v[:i]] = v[i+1:]
+	}
+	if ctx.IsSet("max-failures") {
+		maxFailures, err := stringValToInt(ctx.String("max-failures"))
+		if err != nil {
+			return err
+		}
+		tr.MaxFailures = maxFailures
+	}
+	c, err := newAPIClient(ctx)
+	if err != nil {
+		return err
+	}
+	t, err := c.CreateTaskFromTemplate(ctx.String("template"), tr)
+	if err != nil {
+		return fmt.Errorf("Error creating task: %v\n", err)
+	}
+	fmt.Println("Task created")
+	fmt.Printf("ID: %s\n", t.ID)
+	fmt.Printf("Name: %s\n", t.Name)
+	fmt.Printf("State: %s\n", t.TaskState)
+	return nil
+}
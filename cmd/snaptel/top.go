@@ -0,0 +1,274 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+	"golang.org/x/crypto/ssh/terminal"
+
+	snapclient "github.com/intelsdi-x/snap/client"
+	"github.com/intelsdi-x/snap/mgmt/rest/v2"
+)
+
+// clearScreen moves the cursor home and clears the terminal
+const clearScreen = "\033[H\033[2J"
+
+// maxTaskWatches bounds the watches the dashboard keeps open, each of them
+// holding a connection to snapteld
+const maxTaskWatches = 32
+
+// dashboard holds what snaptel top shows: the tasks and the running plugins
+// polled on every refresh, and the activity of the running tasks streamed
+// by their watches in between.
+type dashboard struct {
+	c *snapclient.Client
+	// changed is signaled when a watched task changes state
+	changed chan struct{}
+
+	sync.Mutex
+	tasks    []v2.Task
+	plugins  []v2.Plugin
+	activity map[string]*taskActivity
+	err      error
+	updated  time.Time
+}
+
+// taskActivity is what the watch of a running task received
+type taskActivity struct {
+	watcher   *snapclient.TaskWatcher
+	metrics   int
+	lastEvent time.Time
+}
+
+func top(ctx *cli.Context) error {
+	c, err := newAPIClient(ctx)
+	if err != nil {
+		return err
+	}
+	d := &dashboard{
+		c:        c,
+		changed:  make(chan struct{}, 1),
+		activity: map[string]*taskActivity{},
+	}
+	defer d.close()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	t := time.NewTicker(ctx.Duration("refresh"))
+	defer t.Stop()
+	for {
+		d.refresh()
+		width, _, _ := terminal.GetSize(int(os.Stdout.Fd()))
+		var b bytes.Buffer
+		d.render(&b, ctx.GlobalString("url"), width, time.Now())
+		fmt.Print(clearScreen + b.String())
+		select {
+		case <-t.C:
+		case <-d.changed:
+		case <-sigs:
+			return nil
+		}
+	}
+}
+
+// newAPIClient returns a client of the v2 API authenticated like the
+// client of the other commands
+func newAPIClient(ctx *cli.Context) (*snapclient.Client, error) {
+	tc := &tls.Config{InsecureSkipVerify: ctx.GlobalBool("insecure")}
+	if certPath := ctx.GlobalString("client-cert"); certPath != "" {
+		cert, err := tls.LoadX509KeyPair(certPath, ctx.GlobalString("client-key"))
+		if err != nil {
+			return nil, fmt.Errorf("Unable to load the client certificate: %v", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	opts := []snapclient.Option{
+		snapclient.HTTPClient(&http.Client{Transport: &http.Transport{TLSClientConfig: tc}}),
+		snapclient.Timeout(ctx.GlobalDuration("timeout")),
+	}
+	if pClient.Password != "" {
+		opts = append(opts, snapclient.BasicAuth(pClient.Username, pClient.Password))
+	}
+	return snapclient.New(ctx.GlobalString("url"), opts...)
+}
+
+// refresh polls the tasks and the running plugins, then watches the tasks
+// which started running, up to maxTaskWatches at once.  The watches are
+// opened without the lock so that a slow snapteld does not block render.
+func (d *dashboard) refresh() {
+	tasks, err := d.c.GetTasks()
+	var plugins []v2.Plugin
+	if err == nil {
+		plugins, err = d.c.GetPlugins(snapclient.PluginFilter{Running: true})
+	}
+	d.Lock()
+	d.err = err
+	if err != nil {
+		d.Unlock()
+		return
+	}
+	d.tasks = tasks
+	d.plugins = plugins
+	d.updated = time.Now()
+	var ids []string
+	for _, t := range tasks {
+		if len(d.activity)+len(ids) >= maxTaskWatches {
+			break
+		}
+		if _, ok := d.activity[t.ID]; ok || t.TaskState != "Running" {
+			continue
+		}
+		ids = append(ids, t.ID)
+	}
+	d.Unlock()
+
+	// only refresh adds watches, so the tasks picked are not watched yet
+	for _, id := range ids {
+		w, err := d.c.WatchTask(id)
+		if err != nil {
+			continue
+		}
+		a := &taskActivity{watcher: w}
+		d.Lock()
+		d.activity[id] = a
+		d.Unlock()
+		go d.watch(id, a)
+	}
+}
+
+// watch accounts the events of a running task until its watch ends
+func (d *dashboard) watch(id string, a *taskActivity) {
+	for e := range a.watcher.Events {
+		d.Lock()
+		a.metrics += len(e.Event)
+		a.lastEvent = time.Now()
+		d.Unlock()
+		if e.EventType != v2.TaskWatchMetricEvent {
+			select {
+			case d.changed <- struct{}{}:
+			default:
+			}
+		}
+	}
+	d.Lock()
+	delete(d.activity, id)
+	d.Unlock()
+}
+
+func (d *dashboard) close() {
+	d.Lock()
+	defer d.Unlock()
+	for _, a := range d.activity {
+		a.watcher.Close()
+	}
+}
+
+// render writes the dashboard at the time now for a terminal of the width
+func (d *dashboard) render(out io.Writer, url string, width int, now time.Time) {
+	d.Lock()
+	defer d.Unlock()
+	fmt.Fprintf(out, "snaptel top - %s - %s\n", url, now.Format(timeFormat))
+	if d.err != nil {
+		fmt.Fprintf(out, "Error: %v\n", d.err)
+	}
+	if !d.updated.IsZero() && now.Sub(d.updated) > time.Second {
+		fmt.Fprintf(out, "Last updated %s\n", ago(now, d.updated))
+	}
+
+	states := map[string]int{}
+	for _, t := range d.tasks {
+		states[t.TaskState]++
+	}
+	names := make([]string, 0, len(states))
+	for s := range states {
+		names = append(names, s)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(out, "\nTasks: %d", len(d.tasks))
+	for _, s := range names {
+		fmt.Fprintf(out, ", %d %s", states[s], s)
+	}
+	fmt.Fprint(out, "\n\n")
+
+	w := tabwriter.NewWriter(out, 0, 8, 1, '\t', 0)
+	printFields(w, false, 0, "ID", "NAME", "STATE", "HIT", "MISS", "FAIL", "METRICS", "LAST EVENT", "LAST FAILURE")
+	for _, t := range d.tasks {
+		metrics, lastEvent := "-", "-"
+		if a, ok := d.activity[t.ID]; ok {
+			metrics = trunc(a.metrics)
+			if !a.lastEvent.IsZero() {
+				lastEvent = ago(now, a.lastEvent)
+			}
+		}
+		printFields(w, false, 0,
+			t.ID,
+			fixSize(false, t.Name, 20),
+			t.TaskState,
+			trunc(t.HitCount),
+			trunc(t.MissCount),
+			trunc(t.FailedCount),
+			metrics,
+			lastEvent,
+			fixSize(false, t.LastFailureMessage, max(width-150, 20)),
+		)
+	}
+	w.Flush()
+
+	fmt.Fprintf(out, "\nRunning plugins: %d\n\n", len(d.plugins))
+	w = tabwriter.NewWriter(out, 0, 8, 1, '\t', 0)
+	printFields(w, false, 0, "TYPE", "NAME", "VERSION", "HIT", "LAST HIT", "CPU", "MEMORY")
+	for _, p := range d.plugins {
+		cpu, mem := "-", "-"
+		if p.ResourceUsage != nil {
+			cpu = fmt.Sprintf("%.1f%%", p.ResourceUsage.CPUPercent)
+			mem = fmt.Sprintf("%.1fMB", float64(p.ResourceUsage.MemoryBytes)/(1<<20))
+		}
+		lastHit := "never"
+		if p.LastHitTimestamp > 0 {
+			lastHit = ago(now, time.Unix(p.LastHitTimestamp, 0))
+		}
+		printFields(w, false, 0, p.Type, p.Name, p.Version, trunc(p.HitCount), lastHit, cpu, mem)
+	}
+	w.Flush()
+}
+
+// ago returns how long before now the time t is, to the second
+func ago(now, t time.Time) string {
+	return (now.Sub(t) / time.Second * time.Second).String() + " ago"
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,292 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/fileutils"
+)
+
+// autodiscovery loads the plugins found in the auto discover paths and, when
+// a watch interval is set, polls the paths to load the plugins dropped in
+// them and unload the plugins whose file is removed.  A plugin whose file or
+// signature file changes is reloaded.
+type autodiscovery struct {
+	sync.Mutex
+	control  *pluginControl
+	interval time.Duration
+	dirs     []string
+	// files holds the state of the files of the auto discover paths when
+	// they were last loaded, by path
+	files map[string]*autoloadedFile
+	quit  chan struct{}
+}
+
+// autoloadedFile is the state of a file of an auto discover path when it was
+// last loaded (or skipped); plugin is nil unless it was loaded
+type autoloadedFile struct {
+	modTime    time.Time
+	size       int64
+	sigModTime time.Time
+	plugin     core.CatalogedPlugin
+}
+
+func newAutodiscovery(c *pluginControl, interval time.Duration) *autodiscovery {
+	return &autodiscovery{
+		control:  c,
+		interval: interval,
+		files:    map[string]*autoloadedFile{},
+	}
+}
+
+// watch adds a directory to the watched ones
+func (a *autodiscovery) watch(dir string) {
+	a.Lock()
+	defer a.Unlock()
+	a.dirs = append(a.dirs, dir)
+}
+
+// load loads the plugin of the given file of an auto discover path and
+// records the state of the file
+func (a *autodiscovery) load(dir string, file os.FileInfo) {
+	path := filepath.Join(dir, file.Name())
+	state := statAutoloadedFile(path)
+	state.plugin = a.control.autoloadFile(dir, file)
+	a.Lock()
+	a.files[path] = state
+	a.Unlock()
+}
+
+// unload unloads the plugin loaded from the given file, if any, and forgets
+// the file
+func (a *autodiscovery) unload(path string) {
+	a.Lock()
+	state, ok := a.files[path]
+	delete(a.files, path)
+	a.Unlock()
+	if !ok || state.plugin == nil {
+		return
+	}
+	f := log.Fields{
+		"_block":           "autodiscovery",
+		"plugin-file-name": path,
+		"plugin-name":      state.plugin.Name(),
+		"plugin-version":   state.plugin.Version(),
+		"plugin-type":      state.plugin.TypeName(),
+	}
+	if _, err := a.control.Unload(state.plugin); err != nil {
+		controlLogger.WithFields(f).Error(err)
+		return
+	}
+	controlLogger.WithFields(f).Info("Unloading plugin")
+}
+
+// start starts polling the watched directories if a watch interval is set
+func (a *autodiscovery) start() {
+	if a.interval <= 0 {
+		return
+	}
+	controlLogger.WithFields(log.Fields{
+		"_block":   "autodiscovery",
+		"interval": a.interval.String(),
+	}).Info("watching the auto discover paths")
+	a.quit = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				a.scan()
+			case <-a.quit:
+				return
+			}
+		}
+	}()
+}
+
+// stop stops polling the watched directories
+func (a *autodiscovery) stop() {
+	if a.quit != nil {
+		close(a.quit)
+		a.quit = nil
+	}
+}
+
+// scan loads the new and changed files of the watched directories and
+// unloads the plugins of the removed ones
+func (a *autodiscovery) scan() {
+	a.Lock()
+	dirs := append([]string{}, a.dirs...)
+	a.Unlock()
+
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			controlLogger.WithFields(log.Fields{
+				"_block":           "autodiscovery",
+				"autodiscoverpath": dir,
+			}).Error(err)
+			// keep the plugins of a directory which can not be read
+			a.Lock()
+			for path := range a.files {
+				if filepath.Dir(path) == dir {
+					seen[path] = true
+				}
+			}
+			a.Unlock()
+			continue
+		}
+		for _, file := range files {
+			path := filepath.Join(dir, file.Name())
+			seen[path] = true
+			a.Lock()
+			previous, ok := a.files[path]
+			a.Unlock()
+			if ok && !previous.changed(statAutoloadedFile(path)) {
+				continue
+			}
+			if ok {
+				a.unload(path)
+			}
+			a.load(dir, file)
+		}
+	}
+
+	a.Lock()
+	var removed []string
+	for path := range a.files {
+		if !seen[path] {
+			removed = append(removed, path)
+		}
+	}
+	a.Unlock()
+	for _, path := range removed {
+		a.unload(path)
+	}
+}
+
+// changed returns true when the file or its signature file changed
+func (f *autoloadedFile) changed(current *autoloadedFile) bool {
+	return !f.modTime.Equal(current.modTime) || f.size != current.size || !f.sigModTime.Equal(current.sigModTime)
+}
+
+// statAutoloadedFile returns the current state of a file of an auto discover
+// path and of its signature file, following symlinks
+func statAutoloadedFile(path string) *autoloadedFile {
+	state := &autoloadedFile{}
+	if fi, err := os.Stat(path); err == nil {
+		state.modTime = fi.ModTime()
+		state.size = fi.Size()
+	}
+	if fi, err := os.Stat(path + ".asc"); err == nil {
+		state.sigModTime = fi.ModTime()
+	}
+	return state
+}
+
+// autoloadFile loads the plugin of the given file of an auto discover path,
+// along with its signature file (<file>.asc) if any.  Directories, task
+// manifests, signature files and files which are not executable are skipped.
+// It returns the loaded plugin, or nil.
+func (p *pluginControl) autoloadFile(dir string, file os.FileInfo) core.CatalogedPlugin {
+	fileName := file.Name()
+	f := log.Fields{
+		"_block":           "autoload",
+		"autodiscoverpath": dir,
+		"plugin":           fileName,
+	}
+
+	statCheck := file
+	if file.Mode()&os.ModeSymlink != 0 {
+		realPath, err := filepath.EvalSymlinks(filepath.Join(dir, fileName))
+		if err != nil {
+			controlLogger.WithFields(f).WithField("error", err).Error("Cannot follow symlink")
+			return nil
+		}
+		statCheck, err = os.Stat(realPath)
+		if err != nil {
+			controlLogger.WithFields(f).WithFields(log.Fields{
+				"error":       err,
+				"target-path": realPath,
+			}).Error("Target of symlink inacessible")
+			return nil
+		}
+	}
+
+	if statCheck.IsDir() {
+		controlLogger.WithFields(f).Warning("Ignoring subdirectory: ", fileName)
+		return nil
+	}
+	// Ignore tasks files (JSON and YAML)
+	fname := strings.ToLower(fileName)
+	if strings.HasSuffix(fname, ".json") || strings.HasSuffix(fname, ".yaml") || strings.HasSuffix(fname, ".yml") {
+		controlLogger.WithFields(f).Warning("Ignoring JSON/Yaml file: ", fileName)
+		return nil
+	}
+	// only a plugin package (which would have a suffix of '.aci') or a file
+	// which is not a plugin signing file (which would have a suffix of '.asc')
+	// is loaded as a plugin; a signing file is read along with its plugin
+	if !strings.HasSuffix(fileName, ".aci") && strings.HasSuffix(fileName, ".asc") {
+		return nil
+	}
+	// check to make sure the file is executable by someone (even if it isn't
+	// you); if no one can execute this file then skip it (and include a
+	// warning in the log output)
+	if !fileutils.IsExecutable(statCheck) {
+		controlLogger.WithFields(f).Warn("Auto-loading of plugin '", fileName, "' skipped (plugin not executable)")
+		return nil
+	}
+	rp, err := core.NewRequestedPlugin(filepath.Join(dir, fileName), p.GetTempDir(), nil)
+	if err != nil {
+		controlLogger.WithFields(f).Error(err)
+		return nil
+	}
+	signatureFile := filepath.Join(dir, fileName+".asc")
+	if _, err := os.Stat(signatureFile); err == nil {
+		if err = rp.ReadSignatureFile(signatureFile); err != nil {
+			controlLogger.WithFields(f).WithField("plugin", fileName+".asc").Error(err)
+		}
+	}
+	pl, err := p.Load(rp)
+	if err != nil {
+		controlLogger.WithFields(f).Error(err)
+		return nil
+	}
+	controlLogger.WithFields(log.Fields{
+		"_block":           "autoload",
+		"autodiscoverpath": dir,
+		"plugin-file-name": fileName,
+		"plugin-name":      pl.Name(),
+		"plugin-version":   pl.Version(),
+		"plugin-type":      pl.TypeName(),
+	}).Info("Loading plugin")
+	return pl
+}
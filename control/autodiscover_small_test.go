@@ -0,0 +1,72 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAutodiscovery(t *testing.T) {
+	Convey("Watching an auto discover path", t, func() {
+		dir, err := ioutil.TempDir("", "snap-autodiscover")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		a := newAutodiscovery(&pluginControl{}, time.Second)
+		a.watch(dir)
+
+		file := filepath.Join(dir, "snap-plugin-collector-test")
+		So(ioutil.WriteFile(file, []byte("not executable"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "task.yaml"), []byte("---"), 0755), ShouldBeNil)
+
+		Convey("records the files which are found", func() {
+			a.scan()
+			So(a.files, ShouldContainKey, file)
+			So(a.files, ShouldContainKey, filepath.Join(dir, "task.yaml"))
+			Convey("skips the files which are not plugins", func() {
+				So(a.files[file].plugin, ShouldBeNil)
+			})
+			Convey("forgets the files which are removed", func() {
+				So(os.Remove(file), ShouldBeNil)
+				a.scan()
+				So(a.files, ShouldNotContainKey, file)
+			})
+		})
+		Convey("detects the files which change", func() {
+			state := statAutoloadedFile(file)
+			So(state.changed(statAutoloadedFile(file)), ShouldBeFalse)
+
+			So(ioutil.WriteFile(file, []byte("a longer content"), 0644), ShouldBeNil)
+			So(state.changed(statAutoloadedFile(file)), ShouldBeTrue)
+
+			state = statAutoloadedFile(file)
+			So(ioutil.WriteFile(file+".asc", []byte("signature"), 0644), ShouldBeNil)
+			So(state.changed(statAutoloadedFile(file)), ShouldBeTrue)
+		})
+	})
+}
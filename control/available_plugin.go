@@ -20,6 +20,7 @@ limitations under the License.
 package control
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -27,6 +28,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -39,6 +41,7 @@ import (
 	"github.com/intelsdi-x/snap/core/control_event"
 	"github.com/intelsdi-x/snap/core/ctypes"
 	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/tracing"
 )
 
 const (
@@ -55,6 +58,10 @@ var (
 	ErrBadKey            = errors.New("bad key")
 	ErrMsgInsecurePlugin = "secure framework can't connect to insecure plugin"
 	ErrMsgInsecureClient = "insecure framework can't connect to secure plugin"
+	// ErrPluginUnhealthy - The error message for a call to a plugin whose instances all failed their health checks
+	ErrPluginUnhealthy = errors.New("Plugin is unhealthy: it failed its last health checks")
+	// ErrPluginNotRunning - The error message for a call to a plugin without any running instance
+	ErrPluginNotRunning = errors.New("Plugin has no running instance: it died and is being restarted or exceeded its restart limit")
 )
 
 // availablePlugin represents a plugin which is
@@ -71,12 +78,14 @@ type availablePlugin struct {
 	lastHitTime        time.Time
 	emitter            gomit.Emitter
 	failedHealthChecks int
+	unhealthy          int32
 	healthChan         chan error
 	ePlugin            executablePlugin
 	execPath           string
 	fromPackage        bool
 	pprofPort          string
 	isRemote           bool
+	resources          *pluginResources
 }
 
 // newAvailablePlugin returns an availablePlugin with information from a
@@ -102,9 +111,22 @@ func newAvailablePlugin(resp plugin.Response, emitter gomit.Emitter, ep executab
 		ePlugin:     ep,
 		pprofPort:   resp.PprofAddress,
 		isRemote:    false,
+		resources:   &pluginResources{},
 	}
 	ap.key = fmt.Sprintf("%s"+core.Separator+"%s"+core.Separator+"%d", ap.pluginType.String(), ap.name, ap.version)
 
+	// The payloads exchanged with gRPC plugins are compressed with the first
+	// compression of the plugin snapteld supports
+	compression := plugin.NegotiateCompression(resp.Meta.Compressions)
+	if compression != "" {
+		log.WithFields(log.Fields{
+			"_module":     "control-aplugin",
+			"_block":      "newAvailablePlugin",
+			"plugin_name": ap.name,
+			"compression": compression,
+		}).Debug("compressing the payloads exchanged with the plugin")
+	}
+
 	// Create RPC Client
 	switch resp.Type {
 	case plugin.CollectorPluginType:
@@ -121,7 +143,7 @@ func newAvailablePlugin(resp plugin.Response, emitter gomit.Emitter, ep executab
 			}
 			ap.client = c
 		case plugin.GRPC:
-			c, e := client.NewCollectorGrpcClient(resp.ListenAddress, DefaultClientTimeout, security)
+			c, e := client.NewCollectorGrpcClient(resp.ListenAddress, DefaultClientTimeout, security, compression)
 			if e != nil {
 				return nil, errors.New("error while creating client connection: " + e.Error())
 			}
@@ -130,7 +152,8 @@ func newAvailablePlugin(resp plugin.Response, emitter gomit.Emitter, ep executab
 			c, e := client.NewStreamCollectorGrpcClient(
 				resp.ListenAddress,
 				DefaultClientTimeout,
-				security)
+				security,
+				compression)
 			if e != nil {
 				return nil, errors.New("error while creating client connection: " + e.Error())
 			}
@@ -147,7 +170,7 @@ func newAvailablePlugin(resp plugin.Response, emitter gomit.Emitter, ep executab
 			}
 			ap.client = c
 		case plugin.GRPC:
-			c, e := client.NewPublisherGrpcClient(resp.ListenAddress, DefaultClientTimeout, security)
+			c, e := client.NewPublisherGrpcClient(resp.ListenAddress, DefaultClientTimeout, security, compression)
 			if e != nil {
 				return nil, errors.New("error while creating client connection: " + e.Error())
 			}
@@ -164,7 +187,7 @@ func newAvailablePlugin(resp plugin.Response, emitter gomit.Emitter, ep executab
 			}
 			ap.client = c
 		case plugin.GRPC:
-			c, e := client.NewProcessorGrpcClient(resp.ListenAddress, DefaultClientTimeout, security)
+			c, e := client.NewProcessorGrpcClient(resp.ListenAddress, DefaultClientTimeout, security, compression)
 			if e != nil {
 				return nil, errors.New("error while creating client connection: " + e.Error())
 			}
@@ -178,7 +201,8 @@ func newAvailablePlugin(resp plugin.Response, emitter gomit.Emitter, ep executab
 			c, e := client.NewStreamCollectorGrpcClient(
 				resp.ListenAddress,
 				DefaultClientTimeout,
-				security)
+				security,
+				compression)
 			if e != nil {
 				return nil, errors.New("error while creating client connection: " + e.Error())
 			}
@@ -294,11 +318,19 @@ func (a *availablePlugin) Kill(r string) error {
 	}
 
 	if a.ePlugin != nil {
-		return a.ePlugin.Kill()
+		err := a.ePlugin.Kill()
+		a.resources.release()
+		return err
 	}
 	return nil
 }
 
+// IsHealthy returns false when the plugin failed DefaultHealthCheckFailureLimit
+// health checks in a row
+func (a *availablePlugin) IsHealthy() bool {
+	return atomic.LoadInt32(&a.unhealthy) == 0
+}
+
 // CheckHealth checks the health of a plugin and updates
 // a.failedHealthChecks
 func (a *availablePlugin) CheckHealth() {
@@ -315,8 +347,14 @@ func (a *availablePlugin) CheckHealth() {
 					"block":       "check-health",
 					"plugin_name": a,
 				}).Debug("health is ok")
+				defer a.emitter.Emit(&control_event.HealthCheckRecoveredEvent{
+					Name:    a.name,
+					Version: a.version,
+					Type:    int(a.pluginType),
+				})
 			}
 			a.failedHealthChecks = 0
+			atomic.StoreInt32(&a.unhealthy, 0)
 		} else {
 			a.healthCheckFailed()
 		}
@@ -335,6 +373,9 @@ func (a *availablePlugin) healthCheckFailed() {
 	}).Warning("heartbeat missed")
 	a.failedHealthChecks++
 	if a.failedHealthChecks >= DefaultHealthCheckFailureLimit {
+		// a single missed heartbeat does not keep the tasks from calling
+		// the plugin, only the failure limit does
+		atomic.StoreInt32(&a.unhealthy, 1)
 		log.WithFields(log.Fields{
 			"_module":     "control-aplugin",
 			"block":       "check-health",
@@ -427,7 +468,19 @@ func (ap *availablePlugins) getPool(key string) (strategy.Pool, serror.SnapError
 	return pool, nil
 }
 
-func (ap *availablePlugins) collectMetrics(pluginKey string, metricTypes []core.Metric, taskID string) ([]core.Metric, error) {
+// collectMetrics collects the metrics from the plugin; the call is recorded
+// as a child of the given span, which may be nil
+func (ap *availablePlugins) collectMetrics(ctx context.Context, pluginKey string, metricTypes []core.Metric, taskID string) ([]core.Metric, error) {
+	return ap.collect(ctx, pluginKey, metricTypes, taskID, true)
+}
+
+// collectPastMetrics collects metrics at a past time, which are neither
+// taken from nor added to the cache of the plugin
+func (ap *availablePlugins) collectPastMetrics(pluginKey string, metricTypes []core.Metric, taskID string) ([]core.Metric, error) {
+	return ap.collect(context.Background(), pluginKey, metricTypes, taskID, false)
+}
+
+func (ap *availablePlugins) collect(ctx context.Context, pluginKey string, metricTypes []core.Metric, taskID string, cached bool) ([]core.Metric, error) {
 	var results []core.Metric
 	pool, serr := ap.getPool(pluginKey)
 	if serr != nil {
@@ -443,7 +496,10 @@ func (ap *availablePlugins) collectMetrics(pluginKey string, metricTypes []core.
 		return nil, errors.New("Plugin strategy not set")
 	}
 
-	metricsToCollect, metricsFromCache := pool.CheckCache(metricTypes, taskID)
+	metricsToCollect, metricsFromCache := metricTypes, []core.Metric(nil)
+	if cached {
+		metricsToCollect, metricsFromCache = pool.CheckCache(metricTypes, taskID)
+	}
 
 	if len(metricsToCollect) == 0 {
 		return metricsFromCache, nil
@@ -457,7 +513,7 @@ func (ap *availablePlugins) collectMetrics(pluginKey string, metricTypes []core.
 
 	pool.RLock()
 	defer pool.RUnlock()
-	p, serr := pool.SelectAP(taskID, cfg)
+	p, serr := selectHealthyAP(pool, pluginKey, taskID, cfg)
 	if serr != nil {
 		return nil, serr
 	}
@@ -469,12 +525,24 @@ func (ap *availablePlugins) collectMetrics(pluginKey string, metricTypes []core.
 	}
 
 	// collect metrics
-	metrics, err := cli.CollectMetrics(metricsToCollect)
+	span := pluginSpan(tracing.FromContext(ctx), "plugin.collect", p.(*availablePlugin))
+	span.SetAttribute("snap.metrics.cached", strconv.Itoa(len(metricsFromCache)))
+	var metrics []core.Metric
+	var err error
+	if cc, ok := cli.(client.PluginContextClient); ok {
+		metrics, err = cc.CollectMetricsContext(tracing.NewContext(ctx, span), metricsToCollect)
+	} else if err = ctx.Err(); err == nil {
+		metrics, err = cli.CollectMetrics(metricsToCollect)
+	}
+	span.SetAttribute("snap.metrics.collected", strconv.Itoa(len(metrics)))
+	finishSpan(span, err)
 	if err != nil {
 		return nil, serror.New(err)
 	}
 
-	pool.UpdateCache(metrics, taskID)
+	if cached {
+		pool.UpdateCache(metrics, taskID)
+	}
 
 	results = make([]core.Metric, len(metricsFromCache)+len(metrics))
 	idx := 0
@@ -521,7 +589,7 @@ func (ap *availablePlugins) streamMetrics(
 
 	pool.RLock()
 	defer pool.RUnlock()
-	p, serr := pool.SelectAP(taskID, cfg)
+	p, serr := selectHealthyAP(pool, pluginKey, taskID, cfg)
 	if serr != nil {
 		return nil, nil, serr
 	}
@@ -547,7 +615,7 @@ func (ap *availablePlugins) streamMetrics(
 	return metricChan, errChan, nil
 }
 
-func (ap *availablePlugins) publishMetrics(metrics []core.Metric, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) []error {
+func (ap *availablePlugins) publishMetrics(ctx context.Context, metrics []core.Metric, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) []error {
 	key := fmt.Sprintf("%s"+core.Separator+"%s"+core.Separator+"%d", plugin.PublisherPluginType.String(), pluginName, pluginVersion)
 	pool, serr := ap.getPool(key)
 	if serr != nil {
@@ -560,7 +628,7 @@ func (ap *availablePlugins) publishMetrics(metrics []core.Metric, pluginName str
 	pool.RLock()
 	defer pool.RUnlock()
 
-	p, serr := pool.SelectAP(taskID, config)
+	p, serr := selectHealthyAP(pool, key, taskID, config)
 	if serr != nil {
 		return []error{serr}
 	}
@@ -570,7 +638,14 @@ func (ap *availablePlugins) publishMetrics(metrics []core.Metric, pluginName str
 		return []error{errors.New("unable to cast client to PluginPublisherClient")}
 	}
 
-	err := cli.Publish(metrics, config)
+	span := pluginSpan(tracing.FromContext(ctx), "plugin.publish", p.(*availablePlugin))
+	var err error
+	if cc, ok := cli.(client.PluginContextClient); ok {
+		err = cc.PublishContext(tracing.NewContext(ctx, span), metrics, config)
+	} else if err = ctx.Err(); err == nil {
+		err = cli.Publish(metrics, config)
+	}
+	finishSpan(span, err)
 	if err != nil {
 		return []error{err}
 	}
@@ -579,7 +654,7 @@ func (ap *availablePlugins) publishMetrics(metrics []core.Metric, pluginName str
 	return nil
 }
 
-func (ap *availablePlugins) processMetrics(metrics []core.Metric, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) ([]core.Metric, []error) {
+func (ap *availablePlugins) processMetrics(ctx context.Context, metrics []core.Metric, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) ([]core.Metric, []error) {
 	var errs []error
 	key := fmt.Sprintf("%s"+core.Separator+"%s"+core.Separator+"%d", plugin.ProcessorPluginType.String(), pluginName, pluginVersion)
 	pool, serr := ap.getPool(key)
@@ -593,7 +668,7 @@ func (ap *availablePlugins) processMetrics(metrics []core.Metric, pluginName str
 
 	pool.RLock()
 	defer pool.RUnlock()
-	p, err := pool.SelectAP(taskID, config)
+	p, err := selectHealthyAP(pool, key, taskID, config)
 	if err != nil {
 		errs = append(errs, err)
 		return nil, errs
@@ -604,7 +679,15 @@ func (ap *availablePlugins) processMetrics(metrics []core.Metric, pluginName str
 		return nil, []error{errors.New("unable to cast client to PluginProcessorClient")}
 	}
 
-	mts, errp := cli.Process(metrics, config)
+	span := pluginSpan(tracing.FromContext(ctx), "plugin.process", p.(*availablePlugin))
+	var mts []core.Metric
+	var errp error
+	if cc, ok := cli.(client.PluginContextClient); ok {
+		mts, errp = cc.ProcessContext(tracing.NewContext(ctx, span), metrics, config)
+	} else if errp = ctx.Err(); errp == nil {
+		mts, errp = cli.Process(metrics, config)
+	}
+	finishSpan(span, errp)
 	if errp != nil {
 		return nil, []error{errp}
 	}
@@ -613,6 +696,48 @@ func (ap *availablePlugins) processMetrics(metrics []core.Metric, pluginName str
 	return mts, nil
 }
 
+// pluginSpan starts the span of a call to the available plugin, nil when
+// the parent is nil
+func pluginSpan(parent *tracing.Span, name string, a *availablePlugin) *tracing.Span {
+	span := parent.Child(name)
+	span.SetAttribute("snap.plugin.type", a.TypeName())
+	span.SetAttribute("snap.plugin.name", a.Name())
+	span.SetAttribute("snap.plugin.version", strconv.Itoa(a.Version()))
+	span.SetAttribute("snap.plugin.instance", strconv.FormatUint(uint64(a.ID()), 10))
+	return span
+}
+
+func finishSpan(span *tracing.Span, err error) {
+	if err != nil {
+		span.SetErrors(err)
+	}
+	span.Finish()
+}
+
+// selectHealthyAP selects an available plugin from the pool so a call to a
+// plugin which died or stopped answering fails right away with a clear error
+// instead of waiting for the call to time out.  The pool skips the unhealthy
+// instances, the call only fails when all of them are.  As SelectAP, it
+// should be protected by the lock of the pool.
+func selectHealthyAP(pool strategy.Pool, key, taskID string, config map[string]ctypes.ConfigValue) (strategy.AvailablePlugin, serror.SnapError) {
+	fields := map[string]interface{}{"pool-key": key}
+	aps := pool.Plugins()
+	if len(aps) == 0 {
+		return nil, serror.New(ErrPluginNotRunning, fields)
+	}
+	healthy := false
+	for _, p := range aps {
+		if ap, ok := p.(*availablePlugin); !ok || ap.IsHealthy() {
+			healthy = true
+			break
+		}
+	}
+	if !healthy {
+		return nil, serror.New(ErrPluginUnhealthy, fields)
+	}
+	return pool.SelectAP(taskID, config)
+}
+
 func (ap *availablePlugins) findLatestPool(pType, name string) (strategy.Pool, serror.SnapError) {
 	// see if there exists a pool at all which matches name version.
 	var latest strategy.Pool
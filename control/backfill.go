@@ -0,0 +1,99 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// ErrBackfillUnsupported - The error message for the metrics of a collector which cannot query past metrics
+var ErrBackfillUnsupported = errors.New("Collector does not support collecting past metrics")
+
+// CollectMetricsAt collects the metrics of the subscription group as they
+// were at the given past time.  The time is passed to the collectors in the
+// config item core.BackfillTimestampConfigKey, formatted as RFC 3339, which
+// the collectors able to query their past metrics declare in their config
+// policy.  The metrics are neither taken from nor added to the cache and
+// are timestamped with the given time.
+func (p *pluginControl) CollectMetricsAt(id string, allTags map[string]map[string]string, at time.Time) ([]core.Metric, []error) {
+	return p.collectMetrics(context.Background(), id, allTags, at)
+}
+
+// backdated returns copies of the metric types whose config holds the time
+// to collect the metrics at
+func backdated(mts []core.Metric, at time.Time) ([]core.Metric, error) {
+	backdated := make([]core.Metric, len(mts))
+	for i, m := range mts {
+		mt, ok := m.(*metricType)
+		if !ok || !supportsBackfill(mt) {
+			return nil, fmt.Errorf("%v: %s", ErrBackfillUnsupported, m.Namespace())
+		}
+		cp := *mt
+		table := map[string]ctypes.ConfigValue{}
+		if mt.config != nil {
+			for k, v := range mt.config.Table() {
+				table[k] = v
+			}
+		}
+		cp.config = cdata.FromTable(table)
+		cp.config.AddItem(core.BackfillTimestampConfigKey, ctypes.ConfigValueStr{Value: at.UTC().Format(time.RFC3339Nano)})
+		backdated[i] = &cp
+	}
+	return backdated, nil
+}
+
+// supportsBackfill returns true when the config policy of the metric has a
+// rule for the time to collect the metric at
+func supportsBackfill(mt *metricType) bool {
+	node, ok := mt.policy.(*cpolicy.ConfigPolicyNode)
+	if !ok || node == nil {
+		return false
+	}
+	for _, r := range node.RulesAsTable() {
+		if r.Name == core.BackfillTimestampConfigKey {
+			return true
+		}
+	}
+	return false
+}
+
+// backdate returns the metric timestamped with the time it was collected at
+func backdate(m core.Metric, at time.Time) core.Metric {
+	return plugin.MetricType{
+		Namespace_:          m.Namespace(),
+		Version_:            m.Version(),
+		LastAdvertisedTime_: m.LastAdvertisedTime(),
+		Config_:             m.Config(),
+		Data_:               m.Data(),
+		Tags_:               m.Tags(),
+		Description_:        m.Description(),
+		Unit_:               m.Unit(),
+		Timestamp_:          at,
+	}
+}
@@ -0,0 +1,69 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+func TestBackdated(t *testing.T) {
+	Convey("Collecting metrics at a past time", t, func() {
+		at := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+		policy := cpolicy.NewPolicyNode()
+		rule, err := cpolicy.NewStringRule(core.BackfillTimestampConfigKey, false)
+		So(err, ShouldBeNil)
+		policy.Add(rule)
+		config := cdata.NewNode()
+		config.AddItem("host", ctypes.ConfigValueStr{Value: "db1"})
+		mt := &metricType{namespace: core.NewNamespace("intel", "load"), policy: policy, config: config}
+
+		Convey("passes the time in the config of copies of the metric types", func() {
+			mts, err := backdated([]core.Metric{mt}, at)
+			So(err, ShouldBeNil)
+			So(mts, ShouldHaveLength, 1)
+			table := mts[0].Config().Table()
+			So(table[core.BackfillTimestampConfigKey], ShouldResemble, ctypes.ConfigValueStr{Value: "2017-03-01T12:00:00Z"})
+			So(table["host"], ShouldResemble, ctypes.ConfigValueStr{Value: "db1"})
+			So(mt.config.Table(), ShouldNotContainKey, core.BackfillTimestampConfigKey)
+		})
+		Convey("fails for the collectors without a rule for the time", func() {
+			mt.policy = cpolicy.NewPolicyNode()
+			_, err := backdated([]core.Metric{mt}, at)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, ErrBackfillUnsupported.Error())
+		})
+		Convey("timestamps the collected metrics with the time", func() {
+			m := backdate(plugin.MetricType{Namespace_: core.NewNamespace("intel", "load"), Data_: 1, Timestamp_: time.Now()}, at)
+			So(m.Timestamp(), ShouldEqual, at)
+			So(m.Data(), ShouldEqual, 1)
+		})
+	})
+}
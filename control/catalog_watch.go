@@ -0,0 +1,273 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/gomit"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/client"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/control_event"
+)
+
+// catalogWatcherPrefix prefixes the names of the catalog watchers registered
+// with the event manager
+const catalogWatcherPrefix = "catalog-watcher-"
+
+// catalogRefresh polls the running collectors, when a refresh interval is
+// set, for the metrics they advertise and adds the new ones to the metric
+// catalog.  The metrics a collector stops advertising are kept.
+type catalogRefresh struct {
+	control  *pluginControl
+	interval time.Duration
+	quit     chan struct{}
+}
+
+func newCatalogRefresh(c *pluginControl, interval time.Duration) *catalogRefresh {
+	return &catalogRefresh{
+		control:  c,
+		interval: interval,
+	}
+}
+
+// start starts polling the running collectors if a refresh interval is set
+func (r *catalogRefresh) start() {
+	if r.interval <= 0 {
+		return
+	}
+	controlLogger.WithFields(log.Fields{
+		"_block":   "catalog-refresh",
+		"interval": r.interval.String(),
+	}).Info("refreshing the metric catalog")
+	r.quit = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.control.refreshCatalog()
+			case <-r.quit:
+				return
+			}
+		}
+	}()
+}
+
+// stop stops polling the running collectors
+func (r *catalogRefresh) stop() {
+	if r.quit != nil {
+		close(r.quit)
+		r.quit = nil
+	}
+}
+
+// refreshCatalog asks a running instance of every collector for the metrics
+// it advertises; the collectors which are not running are skipped
+func (p *pluginControl) refreshCatalog() {
+	refreshed := map[string]bool{}
+	for _, sap := range p.pluginRunner.AvailablePlugins().all() {
+		ap, ok := sap.(*availablePlugin)
+		if !ok || (ap.pluginType != plugin.CollectorPluginType && ap.pluginType != plugin.StreamCollectorPluginType) {
+			continue
+		}
+		key := fmt.Sprintf("%s"+core.Separator+"%s"+core.Separator+"%d", ap.TypeName(), ap.Name(), ap.Version())
+		if refreshed[key] {
+			continue
+		}
+		refreshed[key] = true
+		lp, err := p.pluginManager.get(key)
+		if err != nil {
+			continue
+		}
+		mts, err := ap.client.(client.PluginCollectorClient).GetMetricTypes(p.metricTypesConfig(lp))
+		if err != nil {
+			controlLogger.WithFields(log.Fields{
+				"_block": "catalog-refresh",
+				"plugin": key,
+			}).Warn(err)
+			continue
+		}
+		p.advertise(lp, mts)
+	}
+}
+
+// metricTypesConfig returns the config the metric types of the collector
+// are asked with, holding the defaults of its config policy
+func (p *pluginControl) metricTypesConfig(lp *loadedPlugin) plugin.ConfigType {
+	cfgNode := p.pluginManager.GetPluginConfig().getPluginConfigDataNode(core.PluginType(lp.Type), lp.Name(), lp.Version())
+	if lp.ConfigPolicy != nil {
+		defaults := cdata.NewNode()
+		for _, cpolicy := range lp.ConfigPolicy.GetAll() {
+			cpolicy.AddDefaults(defaults.Table())
+		}
+		cfgNode = cfgNode.ReverseMerge(defaults)
+	}
+	return plugin.ConfigType{ConfigDataNode: cfgNode}
+}
+
+// advertise adds the metric types advertised by a loaded collector which
+// are not in the catalog yet, then emits the change of the catalog
+func (p *pluginControl) advertise(lp *loadedPlugin, mts []core.Metric) {
+	p.catalogMutex.Lock()
+	before := p.catalogMetrics()
+	for _, mt := range mts {
+		mt = advertisedMetricType(mt, lp.Meta.Version)
+		if _, ok := before[fmt.Sprintf("%s:%d", mt.Namespace(), mt.Version())]; ok || mt.Version() < 1 {
+			continue
+		}
+		mt = p.pluginManager.AddStandardAndWorkflowTags(mt, nil)
+		if err := p.metricCatalog.AddLoadedMetricType(lp, mt); err != nil {
+			controlLogger.WithFields(log.Fields{
+				"_block":           "catalog-refresh",
+				"plugin":           lp.Key(),
+				"metric-namespace": mt.Namespace().String(),
+			}).Error(err)
+		}
+	}
+	event := p.catalogChange(control_event.CatalogMetricsAdvertised, catalogPlugin(lp), before)
+	if event != nil {
+		atomic.AddUint64(&p.catalogGeneration, 1)
+	}
+	p.catalogMutex.Unlock()
+	if event != nil {
+		p.eventManager.Emit(event)
+	}
+}
+
+// advertisedMetricType returns the metric type advertised by a collector
+// with the version of the plugin when it has none
+func advertisedMetricType(mt core.Metric, version int) core.Metric {
+	if mt.Version() > 0 {
+		return mt
+	}
+	return &metricType{
+		namespace:          mt.Namespace(),
+		version:            version,
+		lastAdvertisedTime: mt.LastAdvertisedTime(),
+		config:             mt.Config(),
+		data:               mt.Data(),
+		tags:               mt.Tags(),
+		description:        mt.Description(),
+		unit:               mt.Unit(),
+	}
+}
+
+// catalogMetrics returns the metrics of the catalog by key,
+// <namespace>:<version>
+func (p *pluginControl) catalogMetrics() map[string]core.Metric {
+	metrics := map[string]core.Metric{}
+	mts, err := p.metricCatalog.Fetch(core.Namespace{})
+	if err != nil {
+		return metrics
+	}
+	for _, mt := range mts {
+		metrics[fmt.Sprintf("%s:%d", mt.Namespace(), mt.Version())] = mt
+	}
+	return metrics
+}
+
+// catalogChange returns the event of the change of the catalog since it held
+// the metrics before, nil when no metric was added nor removed
+func (p *pluginControl) catalogChange(reason, plugin string, before map[string]core.Metric) *control_event.MetricCatalogChangedEvent {
+	added, removed := diffMetrics(before, p.catalogMetrics())
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	return &control_event.MetricCatalogChangedEvent{
+		Reason:  reason,
+		Plugin:  plugin,
+		Added:   added,
+		Removed: removed,
+	}
+}
+
+// catalogPlugin returns the plugin of a change of the catalog as
+// type:name:version
+func catalogPlugin(lp *loadedPlugin) string {
+	return fmt.Sprintf("%s:%s:%d", lp.TypeName(), lp.Name(), lp.Version())
+}
+
+// diffMetrics returns the sorted keys of the metrics added and removed
+// between the previous and the current metrics
+func diffMetrics(previous, current map[string]core.Metric) ([]string, []string) {
+	var added, removed []string
+	for k := range current {
+		if _, ok := previous[k]; !ok {
+			added = append(added, k)
+		}
+	}
+	for k := range previous {
+		if _, ok := current[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// catalogWatcher hands the changes of the metric catalog, and of the metrics
+// resolved for the tasks, to a watcher
+type catalogWatcher func(core.CatalogChange)
+
+func (w catalogWatcher) HandleGomitEvent(e gomit.Event) {
+	switch v := e.Body.(type) {
+	case *control_event.MetricCatalogChangedEvent:
+		w(core.CatalogChange{
+			Reason:    v.Reason,
+			Plugin:    v.Plugin,
+			Added:     v.Added,
+			Removed:   v.Removed,
+			Timestamp: e.Header.Time,
+		})
+	case *control_event.TaskMetricsResolvedEvent:
+		w(core.CatalogChange{
+			Reason:    control_event.CatalogTaskMetricsResolved,
+			TaskID:    v.TaskId,
+			Added:     v.Added,
+			Removed:   v.Removed,
+			Timestamp: e.Header.Time,
+		})
+	}
+}
+
+// WatchMetricCatalog hands the changes of the metric catalog, and of the
+// metrics resolved for the tasks after one, to the function until
+// UnwatchMetricCatalog is called with the same name.  The function is
+// called by the emitter of the change and must not block.
+func (p *pluginControl) WatchMetricCatalog(name string, f func(core.CatalogChange)) error {
+	return p.eventManager.RegisterHandler(catalogWatcherPrefix+name, catalogWatcher(f))
+}
+
+// UnwatchMetricCatalog stops handing the changes of the metric catalog to
+// the watcher registered under the name
+func (p *pluginControl) UnwatchMetricCatalog(name string) error {
+	return p.eventManager.UnregisterHandler(catalogWatcherPrefix + name)
+}
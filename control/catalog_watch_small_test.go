@@ -0,0 +1,104 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/gomit"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/control_event"
+)
+
+func TestDiffMetrics(t *testing.T) {
+	Convey("The keys of the added and removed metrics are sorted", t, func() {
+		previous := map[string]core.Metric{"/intel/foo:1": nil, "/intel/bar:1": nil}
+		current := map[string]core.Metric{"/intel/foo:1": nil, "/intel/qux:1": nil, "/intel/baz:2": nil}
+		added, removed := diffMetrics(previous, current)
+		So(added, ShouldResemble, []string{"/intel/baz:2", "/intel/qux:1"})
+		So(removed, ShouldResemble, []string{"/intel/bar:1"})
+	})
+}
+
+func TestAdvertise(t *testing.T) {
+	Convey("Given a catalog holding the metrics of a collector", t, func() {
+		c := &pluginControl{
+			metricCatalog: newMetricCatalog(),
+			pluginManager: newPluginManager(),
+			eventManager:  gomit.NewEventController(),
+			catalogMutex:  &sync.RWMutex{},
+		}
+		lp := &loadedPlugin{
+			Meta:         plugin.PluginMeta{Name: "mock", Version: 2},
+			Type:         plugin.CollectorPluginType,
+			ConfigPolicy: cpolicy.New(),
+			Details:      &pluginDetails{},
+		}
+		foo := &metricType{namespace: core.NewNamespace("intel", "mock", "foo"), version: 2, lastAdvertisedTime: time.Now()}
+		So(c.metricCatalog.AddLoadedMetricType(lp, foo), ShouldBeNil)
+		changes := make(chan core.CatalogChange, 2)
+		So(c.WatchMetricCatalog("test", func(change core.CatalogChange) { changes <- change }), ShouldBeNil)
+
+		Convey("the new metrics advertised are added and watched", func() {
+			bar := &metricType{namespace: core.NewNamespace("intel", "mock", "bar"), lastAdvertisedTime: time.Now()}
+			c.advertise(lp, []core.Metric{foo, bar})
+			mts, err := c.metricCatalog.Fetch(core.NewNamespace("intel", "mock"))
+			So(err, ShouldBeNil)
+			So(mts, ShouldHaveLength, 2)
+			So(c.CatalogGeneration(), ShouldEqual, 1)
+			var change core.CatalogChange
+			select {
+			case change = <-changes:
+			case <-time.After(time.Second):
+			}
+			So(change.Reason, ShouldEqual, control_event.CatalogMetricsAdvertised)
+			So(change.Plugin, ShouldEqual, "collector:mock:2")
+			So(change.Added, ShouldResemble, []string{"/intel/mock/bar:2"})
+			So(change.Removed, ShouldBeEmpty)
+
+			Convey("and the catalog does not change when they are advertised again", func() {
+				c.advertise(lp, []core.Metric{foo, bar})
+				So(c.CatalogGeneration(), ShouldEqual, 1)
+				select {
+				case change = <-changes:
+					So(change, ShouldBeNil)
+				case <-time.After(100 * time.Millisecond):
+				}
+			})
+		})
+		Convey("the changes are not handed to a watcher anymore once unwatched", func() {
+			So(c.UnwatchMetricCatalog("test"), ShouldBeNil)
+			c.advertise(lp, []core.Metric{&metricType{namespace: core.NewNamespace("intel", "mock", "baz"), version: 2}})
+			select {
+			case change := <-changes:
+				So(change, ShouldBeNil)
+			case <-time.After(100 * time.Millisecond):
+			}
+		})
+	})
+}
@@ -51,6 +51,17 @@ var (
 	defaultTLSCertPath       = ""
 	defaultTLSKeyPath        = ""
 	defaultCACertPaths       = ""
+	defaultPluginCgroupPath  = ""
+	// the auto discover paths are not watched by default
+	defaultAutoDiscoverWatchInterval = time.Duration(0)
+	defaultPluginRegistry            = ""
+	defaultMaxConcurrentCollects     = 10
+	// the metrics are not shared by the tasks by default
+	defaultSharedCacheExpiration = time.Duration(0)
+	// the running collectors are not asked for new metrics by default
+	defaultCatalogRefreshInterval = time.Duration(0)
+	// the secrets read from Vault without a lease are read again every 5 minutes
+	defaultVaultSecretTTL = 5 * time.Minute
 )
 
 type pluginConfig struct {
@@ -76,22 +87,48 @@ type pluginConfigItem struct {
 //         UnmarshalJSON method in this same file needs to be modified to
 //         match the field mapping that is defined here
 type Config struct {
-	MaxRunningPlugins int                          `json:"max_running_plugins"yaml:"max_running_plugins"`
-	PluginLoadTimeout int                          `json:"plugin_load_timeout"yaml:"plugin_load_timeout"`
-	PluginTrust       int                          `json:"plugin_trust_level"yaml:"plugin_trust_level"`
-	AutoDiscoverPath  string                       `json:"auto_discover_path"yaml:"auto_discover_path"`
-	KeyringPaths      string                       `json:"keyring_paths"yaml:"keyring_paths"`
-	CacheExpiration   jsonutil.Duration            `json:"cache_expiration"yaml:"cache_expiration"`
-	Plugins           *pluginConfig                `json:"plugins"yaml:"plugins"`
-	Tags              map[string]map[string]string `json:"tags,omitempty"yaml:"tags"`
-	ListenAddr        string                       `json:"listen_addr,omitempty"yaml:"listen_addr"`
-	ListenPort        int                          `json:"listen_port,omitempty"yaml:"listen_port"`
-	Pprof             bool                         `json:"pprof"yaml:"pprof"`
-	MaxPluginRestarts int                          `json:"max_plugin_restarts"yaml:"max_plugin_restarts"`
-	TempDirPath       string                       `json:"temp_dir_path"yaml:"temp_dir_path"`
-	TLSCertPath       string                       `json:"tls_cert_path"yaml:"tls_cert_path"`
-	TLSKeyPath        string                       `json:"tls_key_path"yaml:"tls_key_path"`
-	CACertPaths       string                       `json:"ca_cert_paths"yaml:"ca_cert_paths"`
+	MaxRunningPlugins         int                          `json:"max_running_plugins"yaml:"max_running_plugins"`
+	PluginLoadTimeout         int                          `json:"plugin_load_timeout"yaml:"plugin_load_timeout"`
+	PluginTrust               int                          `json:"plugin_trust_level"yaml:"plugin_trust_level"`
+	AutoDiscoverPath          string                       `json:"auto_discover_path"yaml:"auto_discover_path"`
+	KeyringPaths              string                       `json:"keyring_paths"yaml:"keyring_paths"`
+	CacheExpiration           jsonutil.Duration            `json:"cache_expiration"yaml:"cache_expiration"`
+	Plugins                   *pluginConfig                `json:"plugins"yaml:"plugins"`
+	Tags                      map[string]map[string]string `json:"tags,omitempty"yaml:"tags"`
+	ListenAddr                string                       `json:"listen_addr,omitempty"yaml:"listen_addr"`
+	ListenPort                int                          `json:"listen_port,omitempty"yaml:"listen_port"`
+	ListenSocketMode          string                       `json:"listen_socket_mode,omitempty"yaml:"listen_socket_mode"`
+	PluginListenAddr          string                       `json:"plugin_listen_addr,omitempty"yaml:"plugin_listen_addr"`
+	PluginSocketMode          string                       `json:"plugin_socket_mode,omitempty"yaml:"plugin_socket_mode"`
+	Pprof                     bool                         `json:"pprof"yaml:"pprof"`
+	MaxPluginRestarts         int                          `json:"max_plugin_restarts"yaml:"max_plugin_restarts"`
+	TempDirPath               string                       `json:"temp_dir_path"yaml:"temp_dir_path"`
+	TLSCertPath               string                       `json:"tls_cert_path"yaml:"tls_cert_path"`
+	TLSKeyPath                string                       `json:"tls_key_path"yaml:"tls_key_path"`
+	CACertPaths               string                       `json:"ca_cert_paths"yaml:"ca_cert_paths"`
+	PluginCgroupPath          string                       `json:"plugin_cgroup_path"yaml:"plugin_cgroup_path"`
+	AutoDiscoverWatchInterval jsonutil.Duration            `json:"auto_discover_watch_interval"yaml:"auto_discover_watch_interval"`
+	PluginRegistry            string                       `json:"plugin_registry"yaml:"plugin_registry"`
+	MaxConcurrentCollects     int                          `json:"max_concurrent_collects"yaml:"max_concurrent_collects"`
+	SharedCacheExpiration     jsonutil.Duration            `json:"shared_cache_expiration"yaml:"shared_cache_expiration"`
+	SharedCacheNamespaces     map[string]jsonutil.Duration `json:"shared_cache_namespaces,omitempty"yaml:"shared_cache_namespaces"`
+	CatalogRefreshInterval    jsonutil.Duration            `json:"catalog_refresh_interval"yaml:"catalog_refresh_interval"`
+	Vault                     *VaultConfig                 `json:"vault"yaml:"vault"`
+}
+
+// VaultConfig holds the settings of the Vault server holding the secrets
+// referenced by the config of the plugins, e.g. vault:secret/db#password.
+// The address and the token default to the VAULT_ADDR and VAULT_TOKEN
+// environment variables, the token also when the token file cannot be read.
+// The paths the tasks may refer to are restricted to the allowed paths, and
+// to the paths of their tenant for the tasks of a tenant, as soon as any is
+// set.
+type VaultConfig struct {
+	Address      string              `json:"address"yaml:"address"`
+	TokenFile    string              `json:"token_file"yaml:"token_file"`
+	SecretTTL    jsonutil.Duration   `json:"secret_ttl"yaml:"secret_ttl"`
+	AllowedPaths []string            `json:"allowed_paths"yaml:"allowed_paths"`
+	TenantPaths  map[string][]string `json:"tenant_paths"yaml:"tenant_paths"`
 }
 
 const (
@@ -107,6 +144,9 @@ const (
 					"auto_discover_path": {
 						"type": "string"
 					},
+					"auto_discover_watch_interval": {
+						"type": "string"
+					},
 					"cache_expiration": {
 						"type": "string"
 					},
@@ -138,6 +178,17 @@ const (
 					"listen_port": {
 						"type": "integer"
 					},
+					"listen_socket_mode": {
+						"type": "string",
+						"pattern": "^(0?[0-7]{3})?$"
+					},
+					"plugin_listen_addr": {
+						"type": "string"
+					},
+					"plugin_socket_mode": {
+						"type": "string",
+						"pattern": "^(0?[0-7]{3})?$"
+					},
 					"pprof": {
 						"type": "boolean"
 					},
@@ -155,6 +206,59 @@ const (
 					},
 					"ca_cert_paths": {
 						"type": "string"
+					},
+					"plugin_cgroup_path": {
+						"type": "string"
+					},
+					"plugin_registry": {
+						"type": "string"
+					},
+					"max_concurrent_collects": {
+						"type": "integer",
+						"minimum": 1
+					},
+					"shared_cache_expiration": {
+						"type": "string"
+					},
+					"catalog_refresh_interval": {
+						"type": "string"
+					},
+					"shared_cache_namespaces": {
+						"type": ["object", "null"],
+						"properties" : {},
+						"additionalProperties": {
+							"type": "string"
+						}
+					},
+					"vault": {
+						"type": ["object", "null"],
+						"properties": {
+							"address": {
+								"type": "string"
+							},
+							"token_file": {
+								"type": "string"
+							},
+							"secret_ttl": {
+								"type": "string"
+							},
+							"allowed_paths": {
+								"type": ["array", "null"],
+								"items": {
+									"type": "string"
+								}
+							},
+							"tenant_paths": {
+								"type": ["object", "null"],
+								"additionalProperties": {
+									"type": "array",
+									"items": {
+										"type": "string"
+									}
+								}
+							}
+						},
+						"additionalProperties": false
 					}
 				},
 				"additionalProperties": false
@@ -165,22 +269,29 @@ const (
 // get the default snapteld configuration
 func GetDefaultConfig() *Config {
 	return &Config{
-		ListenAddr:        defaultListenAddr,
-		ListenPort:        defaultListenPort,
-		MaxRunningPlugins: defaultMaxRunningPlugins,
-		PluginLoadTimeout: defaultPluginLoadTimeout,
-		PluginTrust:       defaultPluginTrust,
-		AutoDiscoverPath:  defaultAutoDiscoverPath,
-		KeyringPaths:      defaultKeyringPaths,
-		CacheExpiration:   jsonutil.Duration{defaultCacheExpiration},
-		Plugins:           newPluginConfig(),
-		Tags:              newPluginTags(),
-		Pprof:             defaultPprof,
-		MaxPluginRestarts: MaxPluginRestartCount,
-		TempDirPath:       defaultTempDirPath,
-		TLSCertPath:       defaultTLSCertPath,
-		TLSKeyPath:        defaultTLSKeyPath,
-		CACertPaths:       defaultCACertPaths,
+		ListenAddr:                defaultListenAddr,
+		ListenPort:                defaultListenPort,
+		MaxRunningPlugins:         defaultMaxRunningPlugins,
+		PluginLoadTimeout:         defaultPluginLoadTimeout,
+		PluginTrust:               defaultPluginTrust,
+		AutoDiscoverPath:          defaultAutoDiscoverPath,
+		KeyringPaths:              defaultKeyringPaths,
+		CacheExpiration:           jsonutil.Duration{defaultCacheExpiration},
+		Plugins:                   newPluginConfig(),
+		Tags:                      newPluginTags(),
+		Pprof:                     defaultPprof,
+		MaxPluginRestarts:         MaxPluginRestartCount,
+		TempDirPath:               defaultTempDirPath,
+		TLSCertPath:               defaultTLSCertPath,
+		TLSKeyPath:                defaultTLSKeyPath,
+		CACertPaths:               defaultCACertPaths,
+		PluginCgroupPath:          defaultPluginCgroupPath,
+		AutoDiscoverWatchInterval: jsonutil.Duration{defaultAutoDiscoverWatchInterval},
+		PluginRegistry:            defaultPluginRegistry,
+		MaxConcurrentCollects:     defaultMaxConcurrentCollects,
+		SharedCacheExpiration:     jsonutil.Duration{defaultSharedCacheExpiration},
+		CatalogRefreshInterval:    jsonutil.Duration{defaultCatalogRefreshInterval},
+		Vault:                     &VaultConfig{SecretTTL: jsonutil.Duration{defaultVaultSecretTTL}},
 	}
 }
 
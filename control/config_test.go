@@ -315,5 +315,8 @@ func TestControlDefaultConfig(t *testing.T) {
 		Convey("max_plugin_restarts should be set to 3", func() {
 			So(cfg.MaxPluginRestarts, ShouldEqual, 3)
 		})
+		Convey("MaxConcurrentCollects should equal 10", func() {
+			So(cfg.MaxConcurrentCollects, ShouldEqual, 10)
+		})
 	})
 }
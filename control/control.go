@@ -20,20 +20,24 @@ limitations under the License.
 package control
 
 import (
+	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"net"
 	"os"
-	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/intelsdi-x/gomit"
 	"github.com/intelsdi-x/snap/control/plugin"
@@ -46,7 +50,9 @@ import (
 	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/grpc/controlproxy/rpc"
 	"github.com/intelsdi-x/snap/pkg/aci"
+	"github.com/intelsdi-x/snap/pkg/netutil"
 	"github.com/intelsdi-x/snap/pkg/psigning"
+	"github.com/intelsdi-x/snap/pkg/secrets"
 )
 
 const (
@@ -63,6 +69,8 @@ var (
 		"_module": "control",
 	})
 
+	// ErrPluginFrozen - error message when subscribing to a frozen plugin
+	ErrPluginFrozen = errors.New("Plugin is frozen and accepts no new subscriptions")
 	// ErrLoadedPluginNotFound - error message when a loaded plugin is not found
 	ErrLoadedPluginNotFound = errors.New("Loaded plugin not found")
 
@@ -76,6 +84,13 @@ type pluginControl struct {
 	Config  *Config
 
 	autodiscoverPaths []string
+	autodiscovery     *autodiscovery
+	catalogRefresh    *catalogRefresh
+	secrets           *secrets.Resolver
+	vault             *secrets.Vault
+	// taskTenants holds the tenants of the tasks by subscription id
+	taskTenants      map[string]string
+	taskTenantsMutex sync.RWMutex
 	eventManager      *gomit.EventController
 
 	pluginManager  managesPlugins
@@ -83,6 +98,9 @@ type pluginControl struct {
 	pluginRunner   runsPlugins
 	signingManager managesSigning
 
+	// trustMutex guards the trust level and the keyrings, which are
+	// changed when the configuration of snapteld is reloaded
+	trustMutex   sync.RWMutex
 	pluginTrust  int
 	keyringFiles []string
 	// used to cleanly shutdown the GRPC server
@@ -92,6 +110,21 @@ type pluginControl struct {
 
 	subscriptionGroups ManagesSubscriptionGroups
 	grpcSecurity       client.GRPCSecurity
+
+	// catalogMutex serializes changes to the plugin catalog (load, unload
+	// and swap) against the validation and subscription of dependencies so
+	// that subscriptions are taken against a consistent catalog snapshot.
+	catalogMutex *sync.RWMutex
+	// catalogGeneration is incremented every time the plugin catalog changes
+	catalogGeneration uint64
+
+	// frozenPlugins holds the keys of the plugins which accept no new
+	// subscriptions
+	frozenPlugins map[string]struct{}
+	frozenMutex   *sync.RWMutex
+
+	// sharedCache holds the metrics shared by the tasks
+	sharedCache *sharedCache
 }
 
 type subscribedPlugin struct {
@@ -204,7 +237,13 @@ func New(cfg *Config) *pluginControl {
 		OptSetTags(cfg.Tags),
 		MaxPluginRestarts(cfg),
 	}
-	c := &pluginControl{}
+	c := &pluginControl{
+		catalogMutex:  &sync.RWMutex{},
+		frozenPlugins: map[string]struct{}{},
+		frozenMutex:   &sync.RWMutex{},
+		sharedCache:   newSharedCache(cfg),
+	}
+	c.secrets, c.vault = newSecrets(cfg)
 	c.Config = cfg
 	// Initialize components
 	// Event Manager
@@ -220,11 +259,22 @@ func New(cfg *Config) *pluginControl {
 		"_block": "new",
 	}).Debug("metric catalog created")
 
+	pluginSocketMode, err := netutil.ParseFileMode(cfg.PluginSocketMode)
+	if err != nil {
+		controlLogger.WithFields(log.Fields{
+			"_block": "new",
+			"error":  err,
+		}).Error("plugin socket permissions are left unchanged")
+	}
 	managerOpts := []pluginManagerOpt{
 		OptSetPprof(cfg.Pprof),
 		OptSetTempDirPath(cfg.TempDirPath),
+		OptSetPluginListenAddr(cfg.PluginListenAddr, pluginSocketMode),
+	}
+	runnerOpts := []pluginRunnerOpt{
+		OptSetRunnerSocketMode(pluginSocketMode),
+		OptSetRunnerCgroupPath(cfg.PluginCgroupPath),
 	}
-	runnerOpts := []pluginRunnerOpt{}
 	if cfg.IsTLSEnabled() {
 		if cfg.CACertPaths != "" {
 			certPaths := filepath.SplitList(cfg.CACertPaths)
@@ -266,7 +316,7 @@ func New(cfg *Config) *pluginControl {
 	c.subscriptionGroups = newSubscriptionGroups(c)
 
 	// Start stuff
-	err := c.pluginRunner.Start()
+	err = c.pluginRunner.Start()
 	if err != nil {
 		panic(err)
 	}
@@ -302,6 +352,21 @@ func (p *pluginControl) HandleGomitEvent(e gomit.Event) {
 				}).Error(err)
 			}
 		}
+	case *control_event.MetricCatalogChangedEvent:
+		// the groups are processed on the load and unload events; the
+		// metrics of a swap or advertised by a running collector may match
+		// the wildcards of the requested metrics
+		if v.Reason != control_event.CatalogPluginsSwapped && v.Reason != control_event.CatalogMetricsAdvertised {
+			return
+		}
+		serrs := p.subscriptionGroups.Process()
+		if serrs != nil {
+			for _, err := range serrs {
+				controlLogger.WithFields(log.Fields{
+					"_block": "MetricCatalogChangedEvent",
+				}).Error(err)
+			}
+		}
 	default:
 		runnerLog.WithFields(log.Fields{
 			"_block": "handle-events",
@@ -334,6 +399,7 @@ func (p *pluginControl) Start() error {
 
 		paths := filepath.SplitList(p.Config.AutoDiscoverPath)
 		p.SetAutodiscoverPaths(paths)
+		p.autodiscovery = newAutodiscovery(p, p.Config.AutoDiscoverWatchInterval.Duration)
 		for _, pa := range paths {
 			fullPath, err := filepath.Abs(pa)
 			if err != nil {
@@ -352,116 +418,51 @@ func (p *pluginControl) Start() error {
 					"autodiscoverpath": pa,
 				}).Fatal(err)
 			}
+			p.autodiscovery.watch(fullPath)
 			for _, file := range files {
-				fileName := file.Name()
-
-				statCheck := file
-				if file.Mode()&os.ModeSymlink != 0 {
-					realPath, err := filepath.EvalSymlinks(filepath.Join(fullPath, fileName))
-					if err != nil {
-						controlLogger.WithFields(log.Fields{
-							"_block":           "start",
-							"autodiscoverpath": pa,
-							"error":            err,
-							"plugin":           fileName,
-						}).Error("Cannot follow symlink")
-						continue
-					}
-					statCheck, err = os.Stat(realPath)
-					if err != nil {
-						controlLogger.WithFields(log.Fields{
-							"_block":           "start",
-							"autodiscoverpath": pa,
-							"error":            err,
-							"plugin":           fileName,
-							"target-path":      realPath,
-						}).Error("Target of symlink inacessible")
-						continue
-					}
-				}
-
-				if statCheck.IsDir() {
-					controlLogger.WithFields(log.Fields{
-						"_block":           "start",
-						"autodiscoverpath": pa,
-					}).Warning("Ignoring subdirectory: ", fileName)
-					continue
-				}
-				// Ignore tasks files (JSON and YAML)
-				fname := strings.ToLower(fileName)
-				if strings.HasSuffix(fname, ".json") || strings.HasSuffix(fname, ".yaml") || strings.HasSuffix(fname, ".yml") {
-					controlLogger.WithFields(log.Fields{
-						"_block":           "start",
-						"autodiscoverpath": pa,
-					}).Warning("Ignoring JSON/Yaml file: ", fileName)
-					continue
-				}
-				// if the file is a plugin package (which would have a suffix of '.aci') or if the file
-				// is not a plugin signing file (which would have a suffix of '.asc'), then attempt to
-				// automatically load the file as a plugin
-				if strings.HasSuffix(fileName, ".aci") || !(strings.HasSuffix(fileName, ".asc")) {
-					// check to makd sure the file is executable by someone (even if it isn't you); if no one
-					// can execute this file then skip it (and include a warning in the log output)
-					if (statCheck.Mode() & 0111) == 0 {
-						controlLogger.WithFields(log.Fields{
-							"_block":           "start",
-							"autodiscoverpath": pa,
-							"plugin":           fileName,
-						}).Warn("Auto-loading of plugin '", fileName, "' skipped (plugin not executable)")
-						continue
-					}
-					rp, err := core.NewRequestedPlugin(path.Join(fullPath, fileName), p.GetTempDir(), nil)
-					if err != nil {
-						controlLogger.WithFields(log.Fields{
-							"_block":           "start",
-							"autodiscoverpath": pa,
-							"plugin":           fileName,
-						}).Error(err)
-					}
-					signatureFile := fileName + ".asc"
-					if _, err := os.Stat(path.Join(fullPath, signatureFile)); err == nil {
-						err = rp.ReadSignatureFile(path.Join(fullPath, signatureFile))
-						if err != nil {
-							controlLogger.WithFields(log.Fields{
-								"_block":           "start",
-								"autodiscoverpath": pa,
-								"plugin":           fileName + ".asc",
-							}).Error(err)
-						}
-					}
-					pl, err := p.Load(rp)
-					if err != nil {
-						controlLogger.WithFields(log.Fields{
-							"_block":           "start",
-							"autodiscoverpath": fullPath,
-							"plugin":           fileName,
-						}).Error(err)
-					} else {
-						controlLogger.WithFields(log.Fields{
-							"_block":           "start",
-							"autodiscoverpath": fullPath,
-							"plugin-file-name": fileName,
-							"plugin-name":      pl.Name(),
-							"plugin-version":   pl.Version(),
-							"plugin-type":      pl.TypeName(),
-						}).Info("Loading plugin")
-					}
-				}
+				p.autodiscovery.load(fullPath, file)
 			}
 		}
+		p.autodiscovery.start()
 	} else {
 		controlLogger.WithFields(log.Fields{
 			"_block": "start",
 		}).Info("auto discover path is disabled")
 	}
 
-	lis, err := net.Listen("tcp", fmt.Sprintf("%v:%v", p.Config.ListenAddr, p.Config.ListenPort))
+	p.catalogRefresh = newCatalogRefresh(p, p.Config.CatalogRefreshInterval.Duration)
+	p.catalogRefresh.start()
+
+	// renew the leases of the secrets given to the plugins
+	p.vault.Start()
+
+	listenSocketMode, err := netutil.ParseFileMode(p.Config.ListenSocketMode)
+	if err != nil {
+		return err
+	}
+	listenAddr := netutil.JoinHostPort(p.Config.ListenAddr, p.Config.ListenPort)
+	if _, ok := netutil.UnixSocketPath(p.Config.ListenAddr); ok {
+		listenAddr = p.Config.ListenAddr
+	}
+	lis, err := netutil.Listen(listenAddr, listenSocketMode)
 	if err != nil {
 		controlLogger.WithField("error", err.Error()).Error("Failed to start control grpc listener")
 		return err
 	}
 
 	opts := []grpc.ServerOption{}
+	// other snapteld instances processing or publishing metrics through this
+	// one authenticate with the certificates securing the plugins
+	if p.grpcSecurity.TLSEnabled {
+		security := p.grpcSecurity
+		security.SecureSide = client.SecureServer
+		creds, err := client.NewCredentials(security)
+		if err != nil {
+			lis.Close()
+			return err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
 	p.closingChan = make(chan bool, 1)
 	p.grpcServer = grpc.NewServer(opts...)
 	rpc.RegisterMetricManagerServer(p.grpcServer, &ControlGRPCServer{p})
@@ -495,6 +496,19 @@ func (p *pluginControl) Stop() {
 	p.grpcServer.Stop()
 	p.wg.Wait()
 
+	// stop watching the auto discover paths
+	if p.autodiscovery != nil {
+		p.autodiscovery.stop()
+	}
+
+	// stop refreshing the metric catalog
+	if p.catalogRefresh != nil {
+		p.catalogRefresh.stop()
+	}
+
+	// stop renewing the leases of the secrets
+	p.vault.Stop()
+
 	// stop runner
 	err := p.pluginRunner.Stop()
 	if err != nil {
@@ -541,10 +555,19 @@ func (p *pluginControl) Load(rp *core.RequestedPlugin) (core.CatalogedPlugin, se
 		return nil, se
 	}
 
+	p.catalogMutex.Lock()
+	before := p.catalogMetrics()
 	pl, se := p.pluginManager.LoadPlugin(details, p.eventManager)
 	if se != nil {
+		p.catalogMutex.Unlock()
 		return nil, se
 	}
+	atomic.AddUint64(&p.catalogGeneration, 1)
+	change := p.catalogChange(control_event.CatalogPluginLoaded, catalogPlugin(pl), before)
+	p.catalogMutex.Unlock()
+	if change != nil {
+		defer p.eventManager.Emit(change)
+	}
 
 	// If plugin was loaded from a package, remove ExecPath for
 	// the temporary plugin that was used for load
@@ -567,11 +590,12 @@ func (p *pluginControl) verifySignature(rp *core.RequestedPlugin) (bool, serror.
 	f := map[string]interface{}{
 		"_block": "verifySignature",
 	}
-	switch p.pluginTrust {
+	trust, keyringFiles := p.trustSettings()
+	switch trust {
 	case PluginTrustDisabled:
 		return false, nil
 	case PluginTrustEnabled:
-		err := p.signingManager.ValidateSignature(p.keyringFiles, rp.Path(), rp.Signature())
+		err := p.signingManager.ValidateSignature(keyringFiles, rp.Path(), rp.Signature())
 		if err != nil {
 			return false, serror.New(err)
 		}
@@ -580,7 +604,7 @@ func (p *pluginControl) verifySignature(rp *core.RequestedPlugin) (bool, serror.
 			controlLogger.WithFields(f).Warn("Loading unsigned plugin ", rp.Path())
 			return false, nil
 		}
-		err := p.signingManager.ValidateSignature(p.keyringFiles, rp.Path(), rp.Signature())
+		err := p.signingManager.ValidateSignature(keyringFiles, rp.Path(), rp.Signature())
 		if err != nil {
 			return false, serror.New(err)
 		}
@@ -592,6 +616,10 @@ func (p *pluginControl) verifySignature(rp *core.RequestedPlugin) (bool, serror.
 func (p *pluginControl) returnPluginDetails(rp *core.RequestedPlugin) (*pluginDetails, serror.SnapError) {
 	details := &pluginDetails{}
 	var serr serror.SnapError
+	// Download the plugin requested from a URL or from the plugin registry
+	if err := p.fetchPlugin(rp); err != nil {
+		return nil, serror.New(err)
+	}
 	//Check plugin signing
 	details.Signed, serr = p.verifySignature(rp)
 	if serr != nil {
@@ -606,6 +634,10 @@ func (p *pluginControl) returnPluginDetails(rp *core.RequestedPlugin) (*pluginDe
 	details.CACertPaths = rp.CACertPaths()
 	details.TLSEnabled = rp.TLSEnabled()
 	details.Uri = rp.Uri()
+	details.Limits = rp.ResourceLimits()
+	if !details.Limits.IsZero() && !resourceLimitsSupported(runtime.GOOS) {
+		return nil, serror.New(ErrResourceLimitsUnsupported)
+	}
 
 	if rp.Uri() != nil {
 		// Is a standalone plugin
@@ -622,7 +654,7 @@ func (p *pluginControl) returnPluginDetails(rp *core.RequestedPlugin) (*pluginDe
 		if err != nil {
 			return nil, serror.New(err)
 		}
-		details.ExecPath = path.Join(tempPath, "rootfs")
+		details.ExecPath = filepath.Join(tempPath, "rootfs")
 		if details.Manifest, err = aci.Manifest(f); err != nil {
 			return nil, serror.New(err)
 		}
@@ -638,6 +670,31 @@ func (p *pluginControl) returnPluginDetails(rp *core.RequestedPlugin) (*pluginDe
 }
 
 func (p *pluginControl) Unload(pl core.Plugin) (core.CatalogedPlugin, serror.SnapError) {
+	up, change, se := p.unload(pl)
+	if se != nil {
+		return nil, se
+	}
+	if change != nil {
+		defer p.eventManager.Emit(change)
+	}
+
+	event := &control_event.UnloadPluginEvent{
+		Name:    up.Meta.Name,
+		Version: up.Meta.Version,
+		Type:    int(up.Meta.Type),
+	}
+	defer p.eventManager.Emit(event)
+	return up, nil
+}
+
+// unload validates that the plugin can be unloaded and removes it from the
+// catalog.  Both steps are done while holding the catalog lock so a task can
+// not subscribe to the plugin in between.  The change of the metric catalog
+// is returned, nil when the plugin exposed no metric.
+func (p *pluginControl) unload(pl core.Plugin) (*loadedPlugin, *control_event.MetricCatalogChangedEvent, serror.SnapError) {
+	p.catalogMutex.Lock()
+	defer p.catalogMutex.Unlock()
+
 	up, err := p.pluginManager.get(fmt.Sprintf("%s"+core.Separator+"%s"+core.Separator+"%d", pl.TypeName(), pl.Name(), pl.Version()))
 	if err != nil {
 		se := serror.New(ErrPluginNotFound, map[string]interface{}{
@@ -645,7 +702,7 @@ func (p *pluginControl) Unload(pl core.Plugin) (core.CatalogedPlugin, serror.Sna
 			"plugin-version": pl.Version(),
 			"plugin-type":    pl.TypeName(),
 		})
-		return nil, se
+		return nil, nil, se
 	}
 
 	if errs := p.subscriptionGroups.validatePluginUnloading(up); errs != nil {
@@ -660,22 +717,98 @@ func (p *pluginControl) Unload(pl core.Plugin) (core.CatalogedPlugin, serror.Sna
 			"plugin-type":    pl.TypeName(),
 			"impacted-tasks": impactOnTasks,
 		})
-		return nil, se
+		return nil, nil, se
 	}
 
 	// unload the plugin means removing it from plugin catalog
 	// and, for collector plugins, removing its metrics from metric catalog
+	before := p.catalogMetrics()
 	if _, err := p.pluginManager.UnloadPlugin(pl); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	p.frozenMutex.Lock()
+	delete(p.frozenPlugins, up.Key())
+	p.frozenMutex.Unlock()
+	atomic.AddUint64(&p.catalogGeneration, 1)
+	return up, p.catalogChange(control_event.CatalogPluginUnloaded, catalogPlugin(up), before), nil
+}
 
-	event := &control_event.UnloadPluginEvent{
-		Name:    up.Meta.Name,
-		Version: up.Meta.Version,
-		Type:    int(up.Meta.Type),
+// FreezePlugin keeps the loaded plugins with the given name and version
+// running for the tasks which are already subscribed to them but rejects any
+// new subscription.  The tasks which depend on a frozen plugin are reported
+// as impacted in a control_event.PluginFrozenEvent.
+func (p *pluginControl) FreezePlugin(name string, version int) serror.SnapError {
+	return p.setFrozen(name, version, true)
+}
+
+// ThawPlugin lets the plugins with the given name and version frozen with
+// FreezePlugin accept new subscriptions again.
+func (p *pluginControl) ThawPlugin(name string, version int) serror.SnapError {
+	return p.setFrozen(name, version, false)
+}
+
+// IsPluginFrozen returns true when the plugin with the given key
+// (type:name:version) is frozen.
+func (p *pluginControl) IsPluginFrozen(key string) bool {
+	p.frozenMutex.RLock()
+	defer p.frozenMutex.RUnlock()
+	_, ok := p.frozenPlugins[key]
+	return ok
+}
+
+func (p *pluginControl) setFrozen(name string, version int, frozen bool) serror.SnapError {
+	p.catalogMutex.Lock()
+	var lps []*loadedPlugin
+	for _, lp := range p.pluginManager.all() {
+		if lp.Name() == name && lp.Version() == version {
+			lps = append(lps, lp)
+		}
 	}
-	defer p.eventManager.Emit(event)
-	return up, nil
+	if len(lps) == 0 {
+		p.catalogMutex.Unlock()
+		return serror.New(ErrPluginNotFound, map[string]interface{}{
+			"plugin-name":    name,
+			"plugin-version": version,
+		})
+	}
+	p.frozenMutex.Lock()
+	for _, lp := range lps {
+		if frozen {
+			p.frozenPlugins[lp.Key()] = struct{}{}
+		} else {
+			delete(p.frozenPlugins, lp.Key())
+		}
+	}
+	p.frozenMutex.Unlock()
+	// validating dependencies gives a different result from now on
+	atomic.AddUint64(&p.catalogGeneration, 1)
+	p.catalogMutex.Unlock()
+
+	for _, lp := range lps {
+		impacted := p.subscriptionGroups.subscribedTasks(lp)
+		controlLogger.WithFields(log.Fields{
+			"_block":         "set-frozen",
+			"plugin":         lp.Key(),
+			"frozen":         frozen,
+			"impacted-tasks": impacted,
+		}).Warn("plugin frozen state changed")
+		if frozen {
+			defer p.eventManager.Emit(&control_event.PluginFrozenEvent{
+				Name:          lp.Name(),
+				Version:       lp.Version(),
+				Type:          int(lp.Type),
+				ImpactedTasks: impacted,
+			})
+		} else {
+			defer p.eventManager.Emit(&control_event.PluginThawedEvent{
+				Name:          lp.Name(),
+				Version:       lp.Version(),
+				Type:          int(lp.Type),
+				ImpactedTasks: impacted,
+			})
+		}
+	}
+	return nil
 }
 
 func (p *pluginControl) SwapPlugins(in *core.RequestedPlugin, out core.CatalogedPlugin) serror.SnapError {
@@ -687,9 +820,38 @@ func (p *pluginControl) SwapPlugins(in *core.RequestedPlugin, out core.Cataloged
 		defer os.RemoveAll(filepath.Dir(details.ExecPath))
 	}
 
+	lp, up, change, serr := p.swapPlugins(details, out)
+	if serr != nil {
+		return serr
+	}
+	if change != nil {
+		defer p.eventManager.Emit(change)
+	}
+
+	event := &control_event.SwapPluginsEvent{
+		LoadedPluginName:      lp.Meta.Name,
+		LoadedPluginVersion:   lp.Meta.Version,
+		UnloadedPluginName:    up.Meta.Name,
+		UnloadedPluginVersion: up.Meta.Version,
+		PluginType:            int(lp.Meta.Type),
+	}
+	defer p.eventManager.Emit(event)
+
+	return nil
+}
+
+// swapPlugins loads the plugin described by details and unloads out while
+// holding the catalog lock, rolling back the load when the swap fails.  The
+// change of the metric catalog is returned, nil when there is none.
+func (p *pluginControl) swapPlugins(details *pluginDetails, out core.CatalogedPlugin) (*loadedPlugin, *loadedPlugin, *control_event.MetricCatalogChangedEvent, serror.SnapError) {
+	p.catalogMutex.Lock()
+	defer p.catalogMutex.Unlock()
+
+	before := p.catalogMetrics()
+
 	lp, err := p.pluginManager.LoadPlugin(details, p.eventManager)
 	if err != nil {
-		return err
+		return nil, nil, nil, err
 	}
 
 	// Make sure plugin types and names are the same
@@ -708,9 +870,9 @@ func (p *pluginControl) SwapPlugins(in *core.RequestedPlugin, out core.Cataloged
 				"original-unload-error": serr.Error(),
 				"rollback-unload-error": err.Error(),
 			})
-			return se
+			return nil, nil, nil, se
 		}
-		return serr
+		return nil, nil, nil, serr
 	}
 	up, err := p.pluginManager.UnloadPlugin(out)
 	if err != nil {
@@ -721,24 +883,17 @@ func (p *pluginControl) SwapPlugins(in *core.RequestedPlugin, out core.Cataloged
 				"original-unload-error": err.Error(),
 				"rollback-unload-error": err2.Error(),
 			})
-			return se
+			return nil, nil, nil, se
 		}
-		return err
+		return nil, nil, nil, err
 	}
-
-	event := &control_event.SwapPluginsEvent{
-		LoadedPluginName:      lp.Meta.Name,
-		LoadedPluginVersion:   lp.Meta.Version,
-		UnloadedPluginName:    up.Meta.Name,
-		UnloadedPluginVersion: up.Meta.Version,
-		PluginType:            int(lp.Meta.Type),
-	}
-	defer p.eventManager.Emit(event)
-
-	return nil
+	atomic.AddUint64(&p.catalogGeneration, 1)
+	return lp, up, p.catalogChange(control_event.CatalogPluginsSwapped, catalogPlugin(lp), before), nil
 }
 
 func (p *pluginControl) ValidateDeps(requested []core.RequestedMetric, plugins []core.SubscribedPlugin, configTree *cdata.ConfigDataTree, asserts ...core.SubscribedPluginAssert) []serror.SnapError {
+	p.catalogMutex.RLock()
+	defer p.catalogMutex.RUnlock()
 	return p.subscriptionGroups.ValidateDeps(requested, plugins, configTree, asserts...)
 }
 
@@ -746,15 +901,64 @@ func (p *pluginControl) ValidateDeps(requested []core.RequestedMetric, plugins [
 // array of core.RequestedMetrics to the corresponding plugins while processors and publishers provided in the array of core.Plugin
 // will be subscribed directly.  The ID provides a logical grouping of subscriptions.
 func (p *pluginControl) SubscribeDeps(id string, requested []core.RequestedMetric, plugins []core.SubscribedPlugin, configTree *cdata.ConfigDataTree) (serrs []serror.SnapError) {
+	p.catalogMutex.RLock()
+	defer p.catalogMutex.RUnlock()
 	return p.subscriptionGroups.Add(id, requested, configTree, plugins)
 }
 
+// RemoteControlCredentials returns the transport credentials securing the
+// connections to the control of other snapteld instances, nil when TLS is
+// not enabled
+func (p *pluginControl) RemoteControlCredentials() (credentials.TransportCredentials, error) {
+	return client.NewCredentials(p.grpcSecurity)
+}
+
+// CatalogGeneration returns a number which is incremented every time a plugin
+// is loaded, unloaded or swapped.  Callers can compare generations taken
+// before and after validating or subscribing to detect a change in the catalog.
+func (p *pluginControl) CatalogGeneration() uint64 {
+	return atomic.LoadUint64(&p.catalogGeneration)
+}
+
 // UnsubscribeDeps unsubscribes a group of dependencies provided the subscription group ID
 func (p *pluginControl) UnsubscribeDeps(id string) []serror.SnapError {
 	// update view and unsubscribe to plugins
-	return p.subscriptionGroups.Remove(id)
+	serrs := p.subscriptionGroups.Remove(id)
+	p.SetTaskTenant(id, "")
+	return serrs
+}
+
+// PluginSubscriptions returns the tasks subscribed to every loaded plugin,
+// in the order of the plugin keys.  A plugin without subscribers can be
+// unloaded without impacting any task.
+func (p *pluginControl) PluginSubscriptions() []core.PluginSubscription {
+	table := p.pluginManager.all()
+	plugins := make([]*loadedPlugin, 0, len(table))
+	for _, lp := range table {
+		plugins = append(plugins, lp)
+	}
+	sort.Sort(loadedPluginsByKey(plugins))
+	subs := make([]core.PluginSubscription, len(plugins))
+	for i, lp := range plugins {
+		subs[i] = core.PluginSubscription{
+			Type:    lp.TypeName(),
+			Name:    lp.Name(),
+			Version: lp.Version(),
+			Tasks:   p.subscriptionGroups.subscribedTasks(lp),
+		}
+		if pool, err := p.pluginRunner.AvailablePlugins().getPool(lp.Key()); err == nil && pool != nil {
+			subs[i].Subscribers = pool.SubscriptionCount()
+		}
+	}
+	return subs
 }
 
+type loadedPluginsByKey []*loadedPlugin
+
+func (l loadedPluginsByKey) Len() int           { return len(l) }
+func (l loadedPluginsByKey) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l loadedPluginsByKey) Less(i, j int) bool { return l[i].Key() < l[j].Key() }
+
 func (p *pluginControl) verifyPlugin(lp *loadedPlugin) error {
 	if lp.Details.Uri != nil {
 		// remote plugin
@@ -772,7 +976,8 @@ func (p *pluginControl) verifyPlugin(lp *loadedPlugin) error {
 		return fmt.Errorf(fmt.Sprintf("Current plugin checksum (%x) does not match checksum when plugin was first loaded (%x).", cs, lp.Details.CheckSum))
 	}
 	if lp.Details.Signed {
-		return p.signingManager.ValidateSignature(p.keyringFiles, lp.Details.Path, lp.Details.Signature)
+		_, keyringFiles := p.trustSettings()
+		return p.signingManager.ValidateSignature(keyringFiles, lp.Details.Path, lp.Details.Signature)
 	}
 	return nil
 }
@@ -902,6 +1107,29 @@ func (p *pluginControl) AvailablePlugins() []core.AvailablePlugin {
 	return caps
 }
 
+// PluginRestarts returns the number of times the running instances of each
+// plugin were restarted after they failed
+func (p *pluginControl) PluginRestarts() []core.PluginRestarts {
+	aps := p.pluginRunner.AvailablePlugins()
+	aps.RLock()
+	defer aps.RUnlock()
+	restarts := []core.PluginRestarts{}
+	for key, pool := range aps.table {
+		tnv := strings.Split(key, core.Separator)
+		if len(tnv) != 3 {
+			continue
+		}
+		version, _ := strconv.Atoi(tnv[2])
+		restarts = append(restarts, core.PluginRestarts{
+			Type:     tnv[0],
+			Name:     tnv[1],
+			Version:  version,
+			Restarts: pool.RestartCount(),
+		})
+	}
+	return restarts
+}
+
 // MetricCatalog returns the entire metric catalog
 // NOTE: The returned data from this function should be considered constant and read only
 func (p *pluginControl) MetricCatalog() ([]core.CatalogedMetric, error) {
@@ -986,6 +1214,19 @@ func (p *pluginControl) MetricExists(mns core.Namespace, ver int) bool {
 // of metrics and errors.  If an error is encountered no metrics will be
 // returned.
 func (p *pluginControl) CollectMetrics(id string, allTags map[string]map[string]string) (metrics []core.Metric, errs []error) {
+	return p.CollectMetricsContext(context.Background(), id, allTags)
+}
+
+// CollectMetricsContext collects the metrics as CollectMetrics does, making
+// the calls to the plugins with the context: they are cancelled with it and
+// recorded as children of the span it carries, if any.
+func (p *pluginControl) CollectMetricsContext(ctx context.Context, id string, allTags map[string]map[string]string) (metrics []core.Metric, errs []error) {
+	return p.collectMetrics(ctx, id, allTags, time.Time{})
+}
+
+// collectMetrics collects the metrics of the subscription group, at the given
+// past time when it is set
+func (p *pluginControl) collectMetrics(ctx context.Context, id string, allTags map[string]map[string]string, at time.Time) (metrics []core.Metric, errs []error) {
 	// If control is not started we don't want tasks to be able to
 	// go through a workflow.
 	if !p.Started {
@@ -1027,6 +1268,14 @@ func (p *pluginControl) CollectMetrics(id string, allTags map[string]map[string]
 	cError := make(chan error)
 	var wg sync.WaitGroup
 
+	// The plugins are called concurrently, at most MaxConcurrentCollects at
+	// a time
+	maxCollects := p.Config.MaxConcurrentCollects
+	if maxCollects < 1 {
+		maxCollects = defaultMaxConcurrentCollects
+	}
+	sem := make(chan struct{}, maxCollects)
+
 	// For each available plugin call available plugin using RPC client and wait for response (goroutines)
 	for pluginKey, pmt := range pluginToMetricMap {
 		// merge global plugin config into the config for the metric
@@ -1038,14 +1287,24 @@ func (p *pluginControl) CollectMetrics(id string, allTags map[string]map[string]
 
 		wg.Add(1)
 
-		go func(pluginKey string, mt []core.Metric) {
-			mts, err := p.pluginRunner.AvailablePlugins().collectMetrics(pluginKey, mt, id)
+		go func(pluginKey string, pl core.Plugin, mt []core.Metric) {
+			sem <- struct{}{}
+			var mts []core.Metric
+			mt, err := p.resolveCollectSecrets(id, mt)
+			if err == nil {
+				if at.IsZero() {
+					mts, err = p.collectShared(ctx, pluginKey, mt, id)
+				} else if mt, err = backdated(mt, at); err == nil {
+					mts, err = p.pluginRunner.AvailablePlugins().collectPastMetrics(pluginKey, mt, id)
+				}
+			}
+			<-sem
 			if err != nil {
-				cError <- err
+				cError <- collectorError(err, pl)
 			} else {
 				cMetrics <- mts
 			}
-		}(pluginKey, pmt.metricTypes)
+		}(pluginKey, pmt.plugin, pmt.metricTypes)
 	}
 
 	go func() {
@@ -1055,6 +1314,9 @@ func (p *pluginControl) CollectMetrics(id string, allTags map[string]map[string]
 			// passed to CollectMetrics so we will help them out here.
 			for i := range m {
 				m[i] = p.pluginManager.AddStandardAndWorkflowTags(m[i], allTags)
+				if !at.IsZero() {
+					m[i] = backdate(m[i], at)
+				}
 			}
 			metrics = append(metrics, m...)
 			wg.Done()
@@ -1072,12 +1334,47 @@ func (p *pluginControl) CollectMetrics(id string, allTags map[string]map[string]
 	close(cMetrics)
 	close(cError)
 
-	if len(errs) > 0 {
-		return nil, errs
-	}
+	// The metrics of the plugins which succeeded are returned along with the
+	// errors of the ones which failed
 	return
 }
 
+// collectShared collects the metrics which are not in the shared cache and
+// caches them for the other tasks
+func (p *pluginControl) collectShared(ctx context.Context, pluginKey string, mts []core.Metric, id string) ([]core.Metric, error) {
+	if !p.sharedCache.enabled() {
+		return p.pluginRunner.AvailablePlugins().collectMetrics(ctx, pluginKey, mts, id)
+	}
+	metricsToCollect, fromCache := p.sharedCache.check(mts)
+	if len(metricsToCollect) == 0 {
+		return fromCache, nil
+	}
+	collected, err := p.pluginRunner.AvailablePlugins().collectMetrics(ctx, pluginKey, metricsToCollect, id)
+	if err != nil {
+		return nil, err
+	}
+	p.sharedCache.update(metricsToCollect, collected)
+	return append(collected, fromCache...), nil
+}
+
+// collectorError adds the collector plugin which failed to the fields of the
+// error so that the caller can tell which plugins failed
+func collectorError(err error, pl core.Plugin) error {
+	fields := map[string]interface{}{
+		"plugin-type":    core.CollectorPluginType.String(),
+		"plugin-name":    pl.Name(),
+		"plugin-version": pl.Version(),
+	}
+	if serr, ok := err.(serror.SnapError); ok {
+		for k, v := range serr.Fields() {
+			fields[k] = v
+		}
+		serr.SetFields(fields)
+		return serr
+	}
+	return serror.New(err, fields)
+}
+
 func (p *pluginControl) StreamMetrics(
 	id string,
 	allTags map[string]map[string]string,
@@ -1128,6 +1425,12 @@ func (p *pluginControl) StreamMetrics(
 
 // PublishMetrics
 func (p *pluginControl) PublishMetrics(metrics []core.Metric, config map[string]ctypes.ConfigValue, taskID, pluginName string, pluginVersion int) []error {
+	return p.PublishMetricsContext(context.Background(), metrics, config, taskID, pluginName, pluginVersion)
+}
+
+// PublishMetricsContext publishes the metrics as PublishMetrics does, making
+// the call to the plugin with the context.
+func (p *pluginControl) PublishMetricsContext(ctx context.Context, metrics []core.Metric, config map[string]ctypes.ConfigValue, taskID, pluginName string, pluginVersion int) []error {
 	// If control is not started we don't want tasks to be able to
 	// go through a workflow.
 	if !p.Started {
@@ -1143,12 +1446,23 @@ func (p *pluginControl) PublishMetrics(metrics []core.Metric, config map[string]
 	for k, v := range config {
 		merged[k] = v
 	}
+	// resolve the references to secrets, from the cache of their provider
+	merged, err := p.resolveSecrets(taskID, merged)
+	if err != nil {
+		return []error{err}
+	}
 
-	return p.pluginRunner.AvailablePlugins().publishMetrics(metrics, pluginName, pluginVersion, merged, taskID)
+	return p.pluginRunner.AvailablePlugins().publishMetrics(ctx, metrics, pluginName, pluginVersion, merged, taskID)
 }
 
 // ProcessMetrics
 func (p *pluginControl) ProcessMetrics(metrics []core.Metric, config map[string]ctypes.ConfigValue, taskID, pluginName string, pluginVersion int) ([]core.Metric, []error) {
+	return p.ProcessMetricsContext(context.Background(), metrics, config, taskID, pluginName, pluginVersion)
+}
+
+// ProcessMetricsContext processes the metrics as ProcessMetrics does, making
+// the call to the plugin with the context.
+func (p *pluginControl) ProcessMetricsContext(ctx context.Context, metrics []core.Metric, config map[string]ctypes.ConfigValue, taskID, pluginName string, pluginVersion int) ([]core.Metric, []error) {
 	// If control is not started we don't want tasks to be able to
 	// go through a workflow.
 	if !p.Started {
@@ -1164,8 +1478,13 @@ func (p *pluginControl) ProcessMetrics(metrics []core.Metric, config map[string]
 	for k, v := range config {
 		merged[k] = v
 	}
+	// resolve the references to secrets, from the cache of their provider
+	merged, err := p.resolveSecrets(taskID, merged)
+	if err != nil {
+		return nil, []error{err}
+	}
 
-	return p.pluginRunner.AvailablePlugins().processMetrics(metrics, pluginName, pluginVersion, merged, taskID)
+	return p.pluginRunner.AvailablePlugins().processMetrics(ctx, metrics, pluginName, pluginVersion, merged, taskID)
 }
 
 func (p *pluginControl) SetAutodiscoverPaths(paths []string) {
@@ -1181,13 +1500,45 @@ func (p *pluginControl) GetTempDir() string {
 }
 
 func (p *pluginControl) SetPluginTrustLevel(trust int) {
+	p.trustMutex.Lock()
+	defer p.trustMutex.Unlock()
 	p.pluginTrust = trust
 }
 
 func (p *pluginControl) SetKeyringFile(keyring string) {
+	p.trustMutex.Lock()
+	defer p.trustMutex.Unlock()
 	p.keyringFiles = append(p.keyringFiles, keyring)
 }
 
+// SetKeyringFiles replaces the keyrings the signatures of the plugins are
+// validated against.  The plugins already loaded are not validated again.
+func (p *pluginControl) SetKeyringFiles(keyrings []string) {
+	p.trustMutex.Lock()
+	defer p.trustMutex.Unlock()
+	p.keyringFiles = append([]string{}, keyrings...)
+}
+
+// PluginTrustLevel returns the trust level of the plugins
+func (p *pluginControl) PluginTrustLevel() int {
+	p.trustMutex.RLock()
+	defer p.trustMutex.RUnlock()
+	return p.pluginTrust
+}
+
+// KeyringFiles returns the keyrings the signatures of the plugins are
+// validated against
+func (p *pluginControl) KeyringFiles() []string {
+	_, keyringFiles := p.trustSettings()
+	return keyringFiles
+}
+
+func (p *pluginControl) trustSettings() (int, []string) {
+	p.trustMutex.RLock()
+	defer p.trustMutex.RUnlock()
+	return p.pluginTrust, append([]string{}, p.keyringFiles...)
+}
+
 type requestedPlugin struct {
 	name    string
 	version int
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -34,6 +35,7 @@ import (
 	"github.com/intelsdi-x/gomit"
 	log "github.com/sirupsen/logrus"
 	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
 
 	"github.com/intelsdi-x/snap/control/fixtures"
 	"github.com/intelsdi-x/snap/control/plugin"
@@ -41,6 +43,9 @@ import (
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/grpc/common"
+	"github.com/intelsdi-x/snap/grpc/controlproxy/rpc"
+	"github.com/intelsdi-x/snap/pkg/rpcutil"
 	"github.com/intelsdi-x/snap/plugin/helper"
 )
 
@@ -81,6 +86,41 @@ func TestMain(m *testing.M) {
 	os.Exit(retCode)
 }
 
+func TestSecureControlRPC(t *testing.T) {
+	Convey("Having a control with TLS enabled", t, func() {
+		l, _ := net.Listen("tcp", ":0")
+		l.Close()
+		cfg := (*configTLSMock)(GetDefaultConfig()).
+			setTLSCertPath(tlsTestSrv + fixtures.TestCrtFileExt).
+			setTLSKeyPath(tlsTestSrv + fixtures.TestKeyFileExt).
+			setCACertPaths(tlsTestCA + fixtures.TestCrtFileExt).
+			export()
+		cfg.ListenPort = l.Addr().(*net.TCPAddr).Port
+		c := New(cfg)
+		So(c.Start(), ShouldBeNil)
+		Reset(func() {
+			c.Stop()
+		})
+		Convey("remote instances should reach it with its credentials", func() {
+			creds, err := c.RemoteControlCredentials()
+			So(err, ShouldBeNil)
+			conn, err := rpcutil.GetClientConnectionWithCreds(context.Background(), "127.0.0.1", cfg.ListenPort, creds)
+			So(err, ShouldBeNil)
+			defer conn.Close()
+			_, err = rpc.NewMetricManagerClient(conn).GetAutodiscoverPaths(context.Background(), &common.Empty{})
+			So(err, ShouldBeNil)
+		})
+		Convey("plaintext connections should be refused", func() {
+			conn, err := rpcutil.GetClientConnection(context.Background(), "127.0.0.1", cfg.ListenPort)
+			if err == nil {
+				defer conn.Close()
+				_, err = rpc.NewMetricManagerClient(conn).GetAutodiscoverPaths(context.Background(), &common.Empty{})
+			}
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
 func TestSecureCollector(t *testing.T) {
 	log.SetLevel(log.DebugLevel)
 	Convey("Having a secure collector", t, func() {
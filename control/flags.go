@@ -42,11 +42,22 @@ var (
 		EnvVar: "SNAP_TRUST_LEVEL",
 	}
 
+	flInsecure = cli.BoolFlag{
+		Name:   "insecure",
+		Usage:  "Load plugins without verifying their signature, overriding the plugin trust level",
+		EnvVar: "SNAP_INSECURE",
+	}
+
 	flAutoDiscover = cli.StringFlag{
 		Name:   "auto-discover, a",
 		Usage:  "Auto discover paths separated by colons.",
 		EnvVar: "SNAP_AUTODISCOVER_PATH",
 	}
+	flAutoDiscoverWatch = cli.StringFlag{
+		Name:   "auto-discover-watch-interval",
+		Usage:  "How often the auto discover paths are checked to load new plugins and unload removed ones (default: disabled)",
+		EnvVar: "SNAP_AUTODISCOVER_WATCH_INTERVAL",
+	}
 	flKeyringPaths = cli.StringFlag{
 		Name:   "keyring-paths, k",
 		Usage:  "Keyring paths for signing verification separated by colons",
@@ -79,7 +90,7 @@ var (
 
 	flControlRpcAddr = cli.StringFlag{
 		Name:   "control-listen-addr",
-		Usage:  "Listen address for control RPC server",
+		Usage:  "Listen address (IPv4, IPv6 or unix:///path/to/socket) for control RPC server",
 		EnvVar: "SNAP_CONTROL_LISTEN_ADDR",
 	}
 
@@ -89,5 +100,5 @@ var (
 		EnvVar: "SNAP_TEMP_DIR_PATH",
 	}
 
-	Flags = []cli.Flag{flNumberOfPLs, flPluginLoadTimeout, flAutoDiscover, flPluginTrust, flKeyringPaths, flCache, flControlRpcPort, flControlRpcAddr, flTempDirPath, flTLSCert, flTLSKey, flCACertPaths}
+	Flags = []cli.Flag{flNumberOfPLs, flPluginLoadTimeout, flAutoDiscover, flAutoDiscoverWatch, flPluginTrust, flInsecure, flKeyringPaths, flCache, flControlRpcPort, flControlRpcAddr, flTempDirPath, flTLSCert, flTLSKey, flCACertPaths}
 )
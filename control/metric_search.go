@@ -0,0 +1,81 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"sort"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// SearchMetrics returns the metrics of the catalog selected by the options,
+// in the order of their keys, and the key to list the next page after, empty
+// on the last page.  Only the metrics under the leading elements of the
+// namespace pattern holding no wildcard are looked up.
+func (p *pluginControl) SearchMetrics(o core.MetricSearchOptions) ([]core.CatalogedMetric, string, error) {
+	pattern, err := core.ParseMetricPattern(o.Namespace)
+	if err != nil {
+		return nil, "", err
+	}
+	mts, err := p.metricCatalog.Fetch(core.NewNamespace(pattern.Prefix()...))
+	if err != nil {
+		// nothing is cataloged under the prefix
+		return []core.CatalogedMetric{}, "", nil
+	}
+	selected := map[string]*metricType{}
+	keys := []string{}
+	for _, mt := range mts {
+		if !searchMatches(mt, pattern, o) {
+			continue
+		}
+		key := core.MetricKey(mt)
+		if key <= o.After {
+			continue
+		}
+		selected[key] = mt
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	next := ""
+	if o.Limit > 0 && len(keys) > o.Limit {
+		keys = keys[:o.Limit]
+		next = keys[len(keys)-1]
+	}
+	cmts := make([]core.CatalogedMetric, len(keys))
+	for i, key := range keys {
+		cmts[i] = selected[key]
+	}
+	return cmts, next, nil
+}
+
+// searchMatches returns true when the metric type is selected by the
+// namespace pattern and the other options of the search
+func searchMatches(mt *metricType, pattern core.MetricPattern, o core.MetricSearchOptions) bool {
+	if o.Version > 0 && mt.Version() != o.Version {
+		return false
+	}
+	if !o.Since.IsZero() && mt.LastAdvertisedTime().Before(o.Since) {
+		return false
+	}
+	if o.Plugin != "" && (mt.Plugin == nil || mt.Plugin.Name() != o.Plugin) {
+		return false
+	}
+	return pattern.Matches(mt.Namespace())
+}
@@ -0,0 +1,100 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+func TestSearchMetrics(t *testing.T) {
+	Convey("Given a metric catalog", t, func() {
+		c := &pluginControl{metricCatalog: newMetricCatalog()}
+		psutil := &loadedPlugin{Meta: plugin.PluginMeta{Name: "psutil"}}
+		mock := &loadedPlugin{Meta: plugin.PluginMeta{Name: "mock"}}
+		now := time.Now()
+		for _, mt := range []*metricType{
+			{Plugin: psutil, namespace: core.NewNamespace("intel", "psutil", "cpu0", "user"), version: 1, lastAdvertisedTime: now},
+			{Plugin: psutil, namespace: core.NewNamespace("intel", "psutil", "cpu1", "user"), version: 1, lastAdvertisedTime: now},
+			{Plugin: psutil, namespace: core.NewNamespace("intel", "psutil", "load", "load1"), version: 2, lastAdvertisedTime: now.Add(-time.Hour)},
+			{Plugin: mock, namespace: core.NewNamespace("intel", "mock", "foo"), version: 1, lastAdvertisedTime: now},
+		} {
+			c.metricCatalog.Add(mt)
+		}
+		keys := func(mts []core.CatalogedMetric) []string {
+			ks := []string{}
+			for _, mt := range mts {
+				ks = append(ks, core.MetricKey(mt))
+			}
+			return ks
+		}
+
+		Convey("the metrics under a namespace ending with * are listed in the order of their keys", func() {
+			mts, next, err := c.SearchMetrics(core.MetricSearchOptions{Namespace: "/intel/psutil/*"})
+			So(err, ShouldBeNil)
+			So(next, ShouldBeEmpty)
+			So(keys(mts), ShouldResemble, []string{"/intel/psutil/cpu0/user:1", "/intel/psutil/cpu1/user:1", "/intel/psutil/load/load1:2"})
+		})
+		Convey("the elements of the namespace are matched as globs", func() {
+			mts, _, err := c.SearchMetrics(core.MetricSearchOptions{Namespace: "/intel/*/cpu?/user"})
+			So(err, ShouldBeNil)
+			So(keys(mts), ShouldResemble, []string{"/intel/psutil/cpu0/user:1", "/intel/psutil/cpu1/user:1"})
+		})
+		Convey("the metrics are selected by plugin, version and last advertised time", func() {
+			mts, _, err := c.SearchMetrics(core.MetricSearchOptions{Plugin: "mock"})
+			So(err, ShouldBeNil)
+			So(keys(mts), ShouldResemble, []string{"/intel/mock/foo:1"})
+			mts, _, err = c.SearchMetrics(core.MetricSearchOptions{Version: 2})
+			So(err, ShouldBeNil)
+			So(keys(mts), ShouldResemble, []string{"/intel/psutil/load/load1:2"})
+			mts, _, err = c.SearchMetrics(core.MetricSearchOptions{Namespace: "/intel/psutil/*", Since: now.Add(-time.Minute)})
+			So(err, ShouldBeNil)
+			So(keys(mts), ShouldResemble, []string{"/intel/psutil/cpu0/user:1", "/intel/psutil/cpu1/user:1"})
+		})
+		Convey("the metrics are listed a page at a time", func() {
+			mts, next, err := c.SearchMetrics(core.MetricSearchOptions{Limit: 3})
+			So(err, ShouldBeNil)
+			So(keys(mts), ShouldResemble, []string{"/intel/mock/foo:1", "/intel/psutil/cpu0/user:1", "/intel/psutil/cpu1/user:1"})
+			So(next, ShouldEqual, "/intel/psutil/cpu1/user:1")
+			mts, next, err = c.SearchMetrics(core.MetricSearchOptions{Limit: 3, After: next})
+			So(err, ShouldBeNil)
+			So(keys(mts), ShouldResemble, []string{"/intel/psutil/load/load1:2"})
+			So(next, ShouldBeEmpty)
+		})
+		Convey("nothing is found under an unknown namespace", func() {
+			mts, _, err := c.SearchMetrics(core.MetricSearchOptions{Namespace: "/intel/docker/*"})
+			So(err, ShouldBeNil)
+			So(mts, ShouldBeEmpty)
+		})
+		Convey("malformed patterns are rejected", func() {
+			_, _, err := c.SearchMetrics(core.MetricSearchOptions{Namespace: "/intel/[psutil/*"})
+			So(err, ShouldNotBeNil)
+			_, _, err = c.SearchMetrics(core.MetricSearchOptions{Namespace: "*/psutil"})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
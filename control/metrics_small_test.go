@@ -190,12 +190,26 @@ func prepareTestCases() []testCase {
 		core.STD_TAG_PLUGIN_RUNNING_ON: hostname,
 	}
 
+	// the tags of the most specific branch win
+	overriddenTags := map[string]map[string]string{
+		"/":  {"dc": "default", "rack": "r0"},
+		foo:  {"dc": "foo"},
+		bar:  {"dc": "bar"},
+		"/x": {"dc": "x"},
+	}
+	foobarOverridden := map[string]string{
+		core.STD_TAG_PLUGIN_RUNNING_ON: hostname,
+		"dc":                           "bar",
+		"rack":                         "r0",
+	}
+
 	testCases := []testCase{
 		{foobazMetric, allTags, foobazExpected},
 		{foobarMetric, allTags, foobarExpected},
 		{tarqazMetric, allTags, tarqazExpected},
 		{stdMetric, allTags, stdExpected},
 		{foobazMetric, nil, stdExpected},
+		{foobarMetric, overriddenTags, foobarOverridden},
 	}
 
 	return testCases
@@ -88,6 +88,9 @@ func (m *monitor) Start(availablePlugins *availablePlugins) {
 					for _, ap := range availablePlugins.all() {
 						if !ap.IsRemote() {
 							go ap.CheckHealth()
+							if rc, ok := ap.(checksResources); ok {
+								go rc.CheckResources()
+							}
 						}
 					}
 					availablePlugins.RUnlock()
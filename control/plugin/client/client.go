@@ -22,6 +22,8 @@ package client
 import (
 	"time"
 
+	"golang.org/x/net/context"
+
 	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
 	"github.com/intelsdi-x/snap/core"
@@ -66,3 +68,16 @@ type PluginPublisherClient interface {
 	PluginClient
 	Publish([]core.Metric, map[string]ctypes.ConfigValue) error
 }
+
+// PluginContextClient is implemented by the clients making the calls to the
+// plugin with the context of the job they are made for.  The calls are
+// cancelled with the context and bounded by the earliest of its deadline
+// and the timeout of the client.  The trace context of the span carried by
+// the context, see tracing.FromContext, is propagated to the plugin in the
+// W3C traceparent format so that it can record its spans in the trace of the
+// task run.
+type PluginContextClient interface {
+	CollectMetricsContext(context.Context, []core.Metric) ([]core.Metric, error)
+	ProcessContext(context.Context, []core.Metric, map[string]ctypes.ConfigValue) ([]core.Metric, error)
+	PublishContext(context.Context, []core.Metric, map[string]ctypes.ConfigValue) error
+}
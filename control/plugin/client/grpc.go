@@ -25,6 +25,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -44,7 +45,9 @@ import (
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/netutil"
 	"github.com/intelsdi-x/snap/pkg/rpcutil"
+	"github.com/intelsdi-x/snap/pkg/tracing"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -124,9 +127,9 @@ func SecurityTLSOff() GRPCSecurity {
 }
 
 // NewCollectorGrpcClient returns a collector gRPC Client.
-func NewCollectorGrpcClient(address string, timeout time.Duration, security GRPCSecurity) (PluginCollectorClient, error) {
+func NewCollectorGrpcClient(address string, timeout time.Duration, security GRPCSecurity, compression string) (PluginCollectorClient, error) {
 	ctx := context.Background()
-	p, err := newPluginGrpcClient(ctx, address, timeout, security, plugin.CollectorPluginType)
+	p, err := newPluginGrpcClient(ctx, address, timeout, security, compression, plugin.CollectorPluginType)
 	if err != nil {
 		return nil, err
 	}
@@ -134,9 +137,9 @@ func NewCollectorGrpcClient(address string, timeout time.Duration, security GRPC
 }
 
 // NewStreamCollectorGrpcClient returns a stream collector gRPC client
-func NewStreamCollectorGrpcClient(address string, timeout time.Duration, security GRPCSecurity) (PluginStreamCollectorClient, error) {
+func NewStreamCollectorGrpcClient(address string, timeout time.Duration, security GRPCSecurity, compression string) (PluginStreamCollectorClient, error) {
 	ctx := context.Background()
-	p, err := newPluginGrpcClient(ctx, address, timeout, security, plugin.StreamCollectorPluginType)
+	p, err := newPluginGrpcClient(ctx, address, timeout, security, compression, plugin.StreamCollectorPluginType)
 	if err != nil {
 		return nil, err
 	}
@@ -144,9 +147,9 @@ func NewStreamCollectorGrpcClient(address string, timeout time.Duration, securit
 }
 
 // NewProcessorGrpcClient returns a processor gRPC Client.
-func NewProcessorGrpcClient(address string, timeout time.Duration, security GRPCSecurity) (PluginProcessorClient, error) {
+func NewProcessorGrpcClient(address string, timeout time.Duration, security GRPCSecurity, compression string) (PluginProcessorClient, error) {
 	ctx := context.Background()
-	p, err := newPluginGrpcClient(ctx, address, timeout, security, plugin.ProcessorPluginType)
+	p, err := newPluginGrpcClient(ctx, address, timeout, security, compression, plugin.ProcessorPluginType)
 	if err != nil {
 		return nil, err
 	}
@@ -154,9 +157,9 @@ func NewProcessorGrpcClient(address string, timeout time.Duration, security GRPC
 }
 
 // NewPublisherGrpcClient returns a publisher gRPC Client.
-func NewPublisherGrpcClient(address string, timeout time.Duration, security GRPCSecurity) (PluginPublisherClient, error) {
+func NewPublisherGrpcClient(address string, timeout time.Duration, security GRPCSecurity, compression string) (PluginPublisherClient, error) {
 	ctx := context.Background()
-	p, err := newPluginGrpcClient(ctx, address, timeout, security, plugin.PublisherPluginType)
+	p, err := newPluginGrpcClient(ctx, address, timeout, security, compression, plugin.PublisherPluginType)
 	if err != nil {
 		return nil, err
 	}
@@ -209,6 +212,12 @@ func loadCACerts(certPaths []string) (rootCAs *x509.CertPool, err error) {
 	return rootCAs, nil
 }
 
+// NewCredentials returns the transport credentials securing a gRPC
+// connection, nil when security is disabled
+func NewCredentials(security GRPCSecurity) (credentials.TransportCredentials, error) {
+	return buildCredentials(security)
+}
+
 func buildCredentials(security GRPCSecurity) (creds credentials.TransportCredentials, err error) {
 	if !security.TLSEnabled {
 		return nil, nil
@@ -254,8 +263,9 @@ func buildCredentials(security GRPCSecurity) (creds credentials.TransportCredent
 	return creds, nil
 }
 
-// newPluginGrpcClient returns a configured gRPC Client.
-func newPluginGrpcClient(ctx context.Context, address string, timeout time.Duration, security GRPCSecurity, typ plugin.PluginType) (interface{}, error) {
+// newPluginGrpcClient returns a configured gRPC Client compressing the
+// payloads with the given compression, if any.
+func newPluginGrpcClient(ctx context.Context, address string, timeout time.Duration, security GRPCSecurity, compression string, typ plugin.PluginType) (interface{}, error) {
 	address, port, err := parseAddress(address)
 	if err != nil {
 		return nil, err
@@ -265,30 +275,58 @@ func newPluginGrpcClient(ctx context.Context, address string, timeout time.Durat
 	if creds, err = buildCredentials(security); err != nil {
 		return nil, err
 	}
-	p, err = newGrpcClient(ctx, address, int(port), timeout, typ, creds)
+	opts, err := compressionDialOptions(compression)
+	if err != nil {
+		return nil, err
+	}
+	p, err = newGrpcClient(ctx, address, int(port), timeout, typ, creds, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return p, nil
 }
 
+// compressionDialOptions returns the options of a connection compressing the
+// payloads with the given compression
+func compressionDialOptions(compression string) ([]grpc.DialOption, error) {
+	switch compression {
+	case "":
+		return nil, nil
+	case plugin.GzipCompression:
+		return []grpc.DialOption{
+			grpc.WithCompressor(grpc.NewGZIPCompressor()),
+			grpc.WithDecompressor(grpc.NewGZIPDecompressor()),
+		}, nil
+	case plugin.SnappyCompression:
+		return []grpc.DialOption{
+			grpc.WithCompressor(snappyCompressor{}),
+			grpc.WithDecompressor(snappyDecompressor{}),
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported compression: %s", compression)
+}
+
+// parseAddress splits the address a plugin listens on into host and port.
+// Unix domain socket addresses are returned unchanged with a zero port.
 func parseAddress(address string) (string, int64, error) {
-	addr := strings.Split(address, ":")
-	if len(addr) != 2 {
+	if _, ok := netutil.UnixSocketPath(address); ok {
+		return address, 0, nil
+	}
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
 		return "", 0, fmt.Errorf("bad address")
 	}
-	address = addr[0]
-	port, err := strconv.ParseInt(addr[1], 10, 64)
+	port, err := strconv.ParseInt(portStr, 10, 64)
 	if err != nil {
 		return "", 0, err
 	}
-	return address, port, nil
+	return host, port, nil
 }
 
-func newGrpcClient(ctx context.Context, addr string, port int, timeout time.Duration, typ plugin.PluginType, creds credentials.TransportCredentials) (*grpcClient, error) {
+func newGrpcClient(ctx context.Context, addr string, port int, timeout time.Duration, typ plugin.PluginType, creds credentials.TransportCredentials, opts ...grpc.DialOption) (*grpcClient, error) {
 	var conn *grpc.ClientConn
 	var err error
-	if conn, err = rpcutil.GetClientConnectionWithCreds(ctx, addr, port, creds); err != nil {
+	if conn, err = rpcutil.GetClientConnectionWithCreds(ctx, addr, port, creds, opts...); err != nil {
 		return nil, err
 	}
 	p := &grpcClient{
@@ -323,6 +361,19 @@ func getContext(timeout time.Duration) context.Context {
 	return ctxTimeout
 }
 
+// callContext returns the context of a call made with the given context,
+// bounded by the timeout of the client and carrying the traceparent of the
+// span of the context, if any
+func (g *grpcClient) callContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(parent, g.timeout)
+	if span := tracing.FromContext(parent); span != nil {
+		ctx = metadata.NewContext(ctx, metadata.New(map[string]string{
+			"traceparent": span.SpanContext().Traceparent(),
+		}))
+	}
+	return ctx, cancel
+}
+
 func (g *grpcClient) Ping() error {
 	_, err := g.plugin.Ping(getContext(g.timeout), &rpc.Empty{})
 	if err != nil {
@@ -361,26 +412,62 @@ func (g *grpcClient) Close() error {
 }
 
 func (g *grpcClient) Publish(metrics []core.Metric, config map[string]ctypes.ConfigValue) error {
+	return g.PublishContext(context.Background(), metrics, config)
+}
+
+// PublishContext publishes the metrics with the context of the job
+func (g *grpcClient) PublishContext(ctx context.Context, metrics []core.Metric, config map[string]ctypes.ConfigValue) error {
 	arg := &rpc.PubProcArg{
 		Metrics: NewMetrics(metrics),
 		Config:  ToConfigMap(config),
 	}
-	reply, err := g.publisher.Publish(getContext(g.timeout), arg)
+	ctx, cancel := g.callContext(ctx)
+	defer cancel()
+	var trailer metadata.MD
+	reply, err := g.publisher.Publish(ctx, arg, grpc.Trailer(&trailer))
 	if err != nil {
 		return err
 	}
 	if reply.Error != "" {
-		return errors.New(reply.Error)
+		return publishError(errors.New(reply.Error), trailer, len(metrics))
 	}
 	return nil
 }
 
+// publishError returns the error of a publisher, a core.PublishError when
+// the plugin acknowledged the batch in the trailer of its reply
+func publishError(err error, trailer metadata.MD, size int) error {
+	published, hasPublished := trailer[core.PublishedTrailer]
+	kind, hasKind := trailer[core.PublishErrorTrailer]
+	if !hasPublished && !hasKind {
+		return err
+	}
+	pe := &core.PublishError{Err: err}
+	if hasPublished && len(published) > 0 {
+		if n, perr := strconv.Atoi(published[0]); perr == nil && n > 0 {
+			pe.Published = n
+		}
+		if pe.Published > size {
+			pe.Published = size
+		}
+	}
+	pe.Retryable = hasKind && len(kind) > 0 && kind[0] == core.RetryablePublishError
+	return pe
+}
+
 func (g *grpcClient) Process(metrics []core.Metric, config map[string]ctypes.ConfigValue) ([]core.Metric, error) {
+	return g.ProcessContext(context.Background(), metrics, config)
+}
+
+// ProcessContext processes the metrics with the context of the job
+func (g *grpcClient) ProcessContext(ctx context.Context, metrics []core.Metric, config map[string]ctypes.ConfigValue) ([]core.Metric, error) {
 	arg := &rpc.PubProcArg{
 		Metrics: NewMetrics(metrics),
 		Config:  ToConfigMap(config),
 	}
-	reply, err := g.processor.Process(getContext(g.timeout), arg)
+	ctx, cancel := g.callContext(ctx)
+	defer cancel()
+	reply, err := g.processor.Process(ctx, arg)
 
 	if err != nil {
 		return nil, err
@@ -396,10 +483,17 @@ func (g *grpcClient) Process(metrics []core.Metric, config map[string]ctypes.Con
 }
 
 func (g *grpcClient) CollectMetrics(mts []core.Metric) ([]core.Metric, error) {
+	return g.CollectMetricsContext(context.Background(), mts)
+}
+
+// CollectMetricsContext collects the metrics with the context of the job
+func (g *grpcClient) CollectMetricsContext(ctx context.Context, mts []core.Metric) ([]core.Metric, error) {
 	arg := &rpc.MetricsArg{
 		Metrics: NewMetrics(mts),
 	}
-	reply, err := g.collector.CollectMetrics(getContext(g.timeout), arg)
+	ctx, cancel := g.callContext(ctx)
+	defer cancel()
+	reply, err := g.collector.CollectMetrics(ctx, arg)
 
 	if err != nil {
 		return nil, err
@@ -22,9 +22,14 @@ limitations under the License.
 package client
 
 import (
+	"bytes"
+	"errors"
 	"testing"
 	"time"
 
+	"google.golang.org/grpc/metadata"
+
+	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/core"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -47,6 +52,52 @@ func TestToCoreMetric(t *testing.T) {
 	})
 }
 
+func TestPublishError(t *testing.T) {
+	Convey("Test publishError", t, func() {
+		err := errors.New("backend unreachable")
+		Convey("an error without acknowledgement is returned as is", func() {
+			So(publishError(err, metadata.MD{}, 10), ShouldEqual, err)
+		})
+		Convey("the acknowledgement of the plugin is returned with the error", func() {
+			md := metadata.MD{core.PublishedTrailer: []string{"4"}, core.PublishErrorTrailer: []string{core.RetryablePublishError}}
+			So(publishError(err, md, 10), ShouldResemble, &core.PublishError{Err: err, Published: 4, Retryable: true})
+			md = metadata.MD{core.PublishedTrailer: []string{"40"}}
+			So(publishError(err, md, 10), ShouldResemble, &core.PublishError{Err: err, Published: 10})
+			md = metadata.MD{core.PublishErrorTrailer: []string{core.FatalPublishError}}
+			So(publishError(err, md, 10), ShouldResemble, &core.PublishError{Err: err})
+		})
+	})
+}
+
+func TestCompressionDialOptions(t *testing.T) {
+	Convey("Test compressionDialOptions", t, func() {
+		opts, err := compressionDialOptions("")
+		So(err, ShouldBeNil)
+		So(opts, ShouldBeEmpty)
+		opts, err = compressionDialOptions(plugin.GzipCompression)
+		So(err, ShouldBeNil)
+		So(opts, ShouldHaveLength, 2)
+		opts, err = compressionDialOptions(plugin.SnappyCompression)
+		So(err, ShouldBeNil)
+		So(opts, ShouldHaveLength, 2)
+		_, err = compressionDialOptions("lz4")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestSnappyCompression(t *testing.T) {
+	Convey("A payload compressed with snappy is decompressed unchanged", t, func() {
+		payload := bytes.Repeat([]byte("intel/mock/foo "), 100)
+		var buf bytes.Buffer
+		So(snappyCompressor{}.Do(&buf, payload), ShouldBeNil)
+		So(buf.Len(), ShouldBeLessThan, len(payload))
+		out, err := snappyDecompressor{}.Do(&buf)
+		So(err, ShouldBeNil)
+		So(out, ShouldResemble, payload)
+		So(snappyCompressor{}.Type(), ShouldEqual, snappyDecompressor{}.Type())
+	})
+}
+
 func testCases() []*metric {
 	now := time.Now()
 	tc := []*metric{
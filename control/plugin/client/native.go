@@ -25,7 +25,6 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
-	"net"
 	"net/rpc"
 	"time"
 	"unicode"
@@ -39,6 +38,7 @@ import (
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/netutil"
 )
 
 // CallsRPC provides an interface for RPC clients
@@ -316,7 +316,7 @@ func (p *PluginNativeClient) GetType() string {
 
 func newNativeClient(address string, timeout time.Duration, t plugin.PluginType, pub *rsa.PublicKey, secure bool) (*PluginNativeClient, error) {
 	// Attempt to dial address error on timeout or problem
-	conn, err := net.DialTimeout("tcp", address, timeout)
+	conn, err := netutil.Dial(address, timeout)
 	// Return nil RPCClient and err if encoutered
 	if err != nil {
 		return nil, err
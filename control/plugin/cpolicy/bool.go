@@ -152,3 +152,8 @@ func (b *BoolRule) Minimum() ctypes.ConfigValue {
 func (b *BoolRule) Maximum() ctypes.ConfigValue {
 	return nil
 }
+
+// Allowed returns nil as any bool value is allowed
+func (b *BoolRule) Allowed() []ctypes.ConfigValue {
+	return nil
+}
@@ -42,23 +42,26 @@ type FloatRule struct {
 	default_ *float64
 	minimum  *float64
 	maximum  *float64
+	allowed  []float64
 }
 
 // MarshalJSON marshals a FloatRule into JSON
 func (f *FloatRule) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
-		Key      string             `json:"key"`
-		Required bool               `json:"required"`
-		Default  ctypes.ConfigValue `json:"default,omitempty"`
-		Minimum  ctypes.ConfigValue `json:"minimum,omitempty"`
-		Maximum  ctypes.ConfigValue `json:"maximum,omitempty"`
-		Type     string             `json:"type"`
+		Key      string               `json:"key"`
+		Required bool                 `json:"required"`
+		Default  ctypes.ConfigValue   `json:"default,omitempty"`
+		Minimum  ctypes.ConfigValue   `json:"minimum,omitempty"`
+		Maximum  ctypes.ConfigValue   `json:"maximum,omitempty"`
+		Allowed  []ctypes.ConfigValue `json:"allowed,omitempty"`
+		Type     string               `json:"type"`
 	}{
 		Key:      f.key,
 		Required: f.required,
 		Default:  f.Default(),
 		Minimum:  f.Minimum(),
 		Maximum:  f.Maximum(),
+		Allowed:  f.Allowed(),
 		Type:     FloatType,
 	})
 }
@@ -101,6 +104,14 @@ func (f *FloatRule) GobEncode() ([]byte, error) {
 			return nil, err
 		}
 	}
+	if len(f.allowed) == 0 {
+		encoder.Encode(false)
+	} else {
+		encoder.Encode(true)
+		if err := encoder.Encode(f.allowed); err != nil {
+			return nil, err
+		}
+	}
 	return w.Bytes(), nil
 }
 
@@ -117,7 +128,9 @@ func (f *FloatRule) GobDecode(buf []byte) error {
 	var is_default_set bool
 	decoder.Decode(&is_default_set)
 	if is_default_set {
-		return decoder.Decode(&f.default_)
+		if err := decoder.Decode(&f.default_); err != nil {
+			return err
+		}
 	}
 	var is_minimum_set bool
 	decoder.Decode(&is_minimum_set)
@@ -133,6 +146,11 @@ func (f *FloatRule) GobDecode(buf []byte) error {
 			return err
 		}
 	}
+	var is_allowed_set bool
+	decoder.Decode(&is_allowed_set)
+	if is_allowed_set {
+		return decoder.Decode(&f.allowed)
+	}
 	return nil
 }
 
@@ -161,17 +179,28 @@ func (f *FloatRule) Key() string {
 
 // Validate Validates a config value against this rule.
 func (f *FloatRule) Validate(cv ctypes.ConfigValue) error {
-	// Check that type is correct
-	if cv.Type() != FloatType {
+	// Check that type is correct, an integer being a float without a
+	// fractional part.
+	v, ok := floatValue(cv)
+	if !ok {
 		return wrongType(f.key, cv.Type(), FloatType)
 	}
-	// Check minimum. Type should be safe now because of the check above.
-	if f.minimum != nil && cv.(ctypes.ConfigValueFloat).Value < *f.minimum {
-		return errors.New(fmt.Sprintf("value is under minimum (%s value %f < %f)", f.key, cv.(ctypes.ConfigValueFloat).Value, *f.minimum))
+	// Check minimum.
+	if f.minimum != nil && v < *f.minimum {
+		return errors.New(fmt.Sprintf("value is under minimum (%s value %f < %f)", f.key, v, *f.minimum))
+	}
+	// Check maximum.
+	if f.maximum != nil && v > *f.maximum {
+		return errors.New(fmt.Sprintf("value is over maximum (%s value %f > %f)", f.key, v, *f.maximum))
 	}
-	// Check maximum. Type should be safe now because of the check above.
-	if f.maximum != nil && cv.(ctypes.ConfigValueFloat).Value > *f.maximum {
-		return errors.New(fmt.Sprintf("value is over maximum (%s value %f > %f)", f.key, cv.(ctypes.ConfigValueFloat).Value, *f.maximum))
+	// Check the value is one of the allowed ones, when they are set.
+	if len(f.allowed) > 0 {
+		for _, a := range f.allowed {
+			if v == a {
+				return nil
+			}
+		}
+		return notAllowed(f.key, v, f.allowed)
 	}
 	return nil
 }
@@ -212,3 +241,32 @@ func (i *FloatRule) Maximum() ctypes.ConfigValue {
 	}
 	return nil
 }
+
+// SetAllowed sets the values allowed for this rule
+func (f *FloatRule) SetAllowed(values ...float64) {
+	f.allowed = values
+}
+
+// Allowed returns the values allowed for this rule, or nil when any value
+// between the minimum and the maximum is allowed
+func (f *FloatRule) Allowed() []ctypes.ConfigValue {
+	if len(f.allowed) == 0 {
+		return nil
+	}
+	allowed := make([]ctypes.ConfigValue, len(f.allowed))
+	for n, a := range f.allowed {
+		allowed[n] = ctypes.ConfigValueFloat{Value: a}
+	}
+	return allowed
+}
+
+// floatValue returns the value of a float or of an integer
+func floatValue(cv ctypes.ConfigValue) (float64, bool) {
+	switch v := cv.(type) {
+	case ctypes.ConfigValueFloat:
+		return v.Value, true
+	case ctypes.ConfigValueInt:
+		return float64(v.Value), true
+	}
+	return 0, false
+}
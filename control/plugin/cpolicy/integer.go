@@ -42,6 +42,7 @@ type IntRule struct {
 	default_ *int
 	minimum  *int
 	maximum  *int
+	allowed  []int
 }
 
 // NewIntegerRule returns a new int-typed rule. Arguments are key(string), required(bool), default(int)
@@ -69,18 +70,20 @@ func (i *IntRule) Type() string {
 // MarshalJSON marshals a IntRule into JSON
 func (i *IntRule) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
-		Key      string             `json:"key"`
-		Required bool               `json:"required"`
-		Default  ctypes.ConfigValue `json:"default,omitempty"`
-		Minimum  ctypes.ConfigValue `json:"minimum,omitempty"`
-		Maximum  ctypes.ConfigValue `json:"maximum,omitempty"`
-		Type     string             `json:"type"`
+		Key      string               `json:"key"`
+		Required bool                 `json:"required"`
+		Default  ctypes.ConfigValue   `json:"default,omitempty"`
+		Minimum  ctypes.ConfigValue   `json:"minimum,omitempty"`
+		Maximum  ctypes.ConfigValue   `json:"maximum,omitempty"`
+		Allowed  []ctypes.ConfigValue `json:"allowed,omitempty"`
+		Type     string               `json:"type"`
 	}{
 		Key:      i.key,
 		Required: i.required,
 		Default:  i.Default(),
 		Minimum:  i.Minimum(),
 		Maximum:  i.Maximum(),
+		Allowed:  i.Allowed(),
 		Type:     IntegerType,
 	})
 }
@@ -119,6 +122,14 @@ func (i *IntRule) GobEncode() ([]byte, error) {
 			return nil, err
 		}
 	}
+	if len(i.allowed) == 0 {
+		encoder.Encode(false)
+	} else {
+		encoder.Encode(true)
+		if err := encoder.Encode(i.allowed); err != nil {
+			return nil, err
+		}
+	}
 	return w.Bytes(), nil
 }
 
@@ -135,7 +146,9 @@ func (i *IntRule) GobDecode(buf []byte) error {
 	var is_default_set bool
 	decoder.Decode(&is_default_set)
 	if is_default_set {
-		return decoder.Decode(&i.default_)
+		if err := decoder.Decode(&i.default_); err != nil {
+			return err
+		}
 	}
 	var is_minimum_set bool
 	decoder.Decode(&is_minimum_set)
@@ -151,6 +164,11 @@ func (i *IntRule) GobDecode(buf []byte) error {
 			return err
 		}
 	}
+	var is_allowed_set bool
+	decoder.Decode(&is_allowed_set)
+	if is_allowed_set {
+		return decoder.Decode(&i.allowed)
+	}
 	return nil
 }
 
@@ -163,18 +181,28 @@ func (i *IntRule) Key() string {
 func (i *IntRule) Validate(cv ctypes.ConfigValue) error {
 	// Check that type is correct
 	// when unmarshalling JSON numbers are converted to floats which is the reason
-	// we are checking for integer below.
+	// floats without a fractional part are accepted.
 	// http://golang.org/pkg/encoding/json/#Marshal
-	if cv.Type() != IntegerType && cv.Type() != FloatType {
+	v, ok := intValue(cv)
+	if !ok {
 		return wrongType(i.key, cv.Type(), IntegerType)
 	}
-	// Check minimum. Type should be safe now because of the check above.
-	if i.minimum != nil && cv.(ctypes.ConfigValueInt).Value < *i.minimum {
-		return errors.New(fmt.Sprintf("value is under minimum (%s value %d < %d)", i.key, cv.(ctypes.ConfigValueInt).Value, *i.minimum))
+	// Check minimum.
+	if i.minimum != nil && v < *i.minimum {
+		return errors.New(fmt.Sprintf("value is under minimum (%s value %d < %d)", i.key, v, *i.minimum))
 	}
-	// Check maximum. Type should be safe now because of the check above.
-	if i.maximum != nil && cv.(ctypes.ConfigValueInt).Value > *i.maximum {
-		return errors.New(fmt.Sprintf("value is over maximum (%s value %d > %d)", i.key, cv.(ctypes.ConfigValueInt).Value, *i.maximum))
+	// Check maximum.
+	if i.maximum != nil && v > *i.maximum {
+		return errors.New(fmt.Sprintf("value is over maximum (%s value %d > %d)", i.key, v, *i.maximum))
+	}
+	// Check the value is one of the allowed ones, when they are set.
+	if len(i.allowed) > 0 {
+		for _, a := range i.allowed {
+			if v == a {
+				return nil
+			}
+		}
+		return notAllowed(i.key, v, i.allowed)
 	}
 	return nil
 }
@@ -215,3 +243,35 @@ func (i *IntRule) Maximum() ctypes.ConfigValue {
 	}
 	return nil
 }
+
+// SetAllowed sets the values allowed for this rule
+func (i *IntRule) SetAllowed(values ...int) {
+	i.allowed = values
+}
+
+// Allowed returns the values allowed for this rule, or nil when any value
+// between the minimum and the maximum is allowed
+func (i *IntRule) Allowed() []ctypes.ConfigValue {
+	if len(i.allowed) == 0 {
+		return nil
+	}
+	allowed := make([]ctypes.ConfigValue, len(i.allowed))
+	for n, a := range i.allowed {
+		allowed[n] = ctypes.ConfigValueInt{Value: a}
+	}
+	return allowed
+}
+
+// intValue returns the value of an integer, or of a float without a
+// fractional part
+func intValue(cv ctypes.ConfigValue) (int, bool) {
+	switch v := cv.(type) {
+	case ctypes.ConfigValueInt:
+		return v.Value, true
+	case ctypes.ConfigValueFloat:
+		if v.Value == float64(int(v.Value)) {
+			return int(v.Value), true
+		}
+	}
+	return 0, false
+}
@@ -156,6 +156,22 @@ func TestConfigPolicyRuleInteger(t *testing.T) {
 				So(err2, ShouldBeNil)
 			})
 
+			Convey("error with a value which is not allowed", func() {
+				r, _ := NewIntegerRule("thekey", true, 1)
+				r.SetMaximum(10)
+				r.SetAllowed(1, 2, 4, 8)
+				So(r.Validate(ctypes.ConfigValueInt{Value: 4}), ShouldBeNil)
+				So(r.Validate(ctypes.ConfigValueInt{Value: 3}), ShouldResemble, errors.New("value is not allowed (thekey value 3 not in [1 2 4 8])"))
+
+				buf, err := r.GobEncode()
+				So(err, ShouldBeNil)
+				r2 := &IntRule{}
+				So(r2.GobDecode(buf), ShouldBeNil)
+				So(r2.Default(), ShouldResemble, r.Default())
+				So(r2.Maximum(), ShouldResemble, r.Maximum())
+				So(r2.Allowed(), ShouldResemble, r.Allowed())
+			})
+
 		})
 
 	})
@@ -25,6 +25,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/intelsdi-x/snap/core/ctypes"
@@ -91,6 +92,9 @@ func (c *ConfigPolicyNode) CopyRules() ([]Rule, error) {
 			} else {
 				newStringRule, err = NewStringRule(rule.Key(), rule.Required())
 			}
+			if err == nil {
+				newStringRule.allowed = rule.(*StringRule).allowed
+			}
 			rules = append(rules, newStringRule)
 		case *FloatRule:
 			var newFloatRule *FloatRule
@@ -99,6 +103,10 @@ func (c *ConfigPolicyNode) CopyRules() ([]Rule, error) {
 			} else {
 				newFloatRule, err = NewFloatRule(rule.Key(), rule.Required())
 			}
+			if err == nil {
+				fr := rule.(*FloatRule)
+				newFloatRule.minimum, newFloatRule.maximum, newFloatRule.allowed = fr.minimum, fr.maximum, fr.allowed
+			}
 			rules = append(rules, newFloatRule)
 		case *IntRule:
 			var newIntRule *IntRule
@@ -107,6 +115,10 @@ func (c *ConfigPolicyNode) CopyRules() ([]Rule, error) {
 			} else {
 				newIntRule, err = NewIntegerRule(rule.Key(), rule.Required())
 			}
+			if err == nil {
+				ir := rule.(*IntRule)
+				newIntRule.minimum, newIntRule.maximum, newIntRule.allowed = ir.minimum, ir.maximum, ir.allowed
+			}
 			rules = append(rules, newIntRule)
 		default:
 			return []Rule{}, errors.New(fmt.Sprint("Unknown rule type"))
@@ -169,21 +181,30 @@ func (p *ConfigPolicyNode) Add(rules ...Rule) {
 
 type RuleTableSlice []RuleTable
 
+func (r RuleTableSlice) Len() int           { return len(r) }
+func (r RuleTableSlice) Less(i, j int) bool { return r[i].Name < r[j].Name }
+func (r RuleTableSlice) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+
 type RuleTable struct {
-	Name     string      `json:"name"`
-	Type     string      `json:"type"`
-	Default  interface{} `json:"default,omitempty"`
-	Required bool        `json:"required"`
-	Minimum  interface{} `json:"minimum,omitempty"`
-	Maximum  interface{} `json:"maximum,omitempty"`
+	Name     string        `json:"name"`
+	Type     string        `json:"type"`
+	Default  interface{}   `json:"default,omitempty"`
+	Required bool          `json:"required"`
+	Minimum  interface{}   `json:"minimum,omitempty"`
+	Maximum  interface{}   `json:"maximum,omitempty"`
+	Allowed  []interface{} `json:"allowed,omitempty"`
 }
 
 func (p *ConfigPolicyNode) RulesAsTable() RuleTableSlice {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
-	rt := make([]RuleTable, 0, len(p.rules))
+	rt := make(RuleTableSlice, 0, len(p.rules))
 	for _, r := range p.rules {
+		var allowed []interface{}
+		for _, a := range r.Allowed() {
+			allowed = append(allowed, a)
+		}
 		rt = append(rt, RuleTable{
 			Name:     r.Key(),
 			Type:     r.Type(),
@@ -191,8 +212,11 @@ func (p *ConfigPolicyNode) RulesAsTable() RuleTableSlice {
 			Required: r.Required(),
 			Minimum:  r.Minimum(),
 			Maximum:  r.Maximum(),
+			Allowed:  allowed,
 		})
 	}
+	// the rules are sorted by name so that forms list them in the same order
+	sort.Sort(rt)
 	return rt
 }
 
@@ -227,13 +251,15 @@ func (c *ConfigPolicyNode) Process(m map[string]ctypes.ConfigValue) (*map[string
 			// Validate versus matching data
 			e := rule.Validate(cv)
 			if e != nil {
-				pErrors.AddError(e)
+				pErrors.AddError(&RuleError{Key: key, Type: rule.Type(), Err: e})
+			} else {
+				m[key] = typed(rule, cv)
 			}
 		} else {
 			// If it was required add error
 			if rule.Required() {
 				e := fmt.Errorf("required key missing (%s)", key)
-				pErrors.AddError(e)
+				pErrors.AddError(&RuleError{Key: key, Type: rule.Type(), Err: e})
 			} else {
 				// If default returns we should add it
 				cv := rule.Default()
@@ -326,6 +352,13 @@ func addRulesToConfigPolicyNode(rules map[string]interface{}, cpn *ConfigPolicyN
 					max := int(max_)
 					r.maximum = &max
 				}
+				if a, ok := rule["allowed"].([]interface{}); ok {
+					for _, v := range a {
+						if v, ok := v.(float64); ok {
+							r.allowed = append(r.allowed, int(v))
+						}
+					}
+				}
 				cpn.Add(r)
 			case "string":
 				r, _ := NewStringRule(k, req)
@@ -335,6 +368,13 @@ func addRulesToConfigPolicyNode(rules map[string]interface{}, cpn *ConfigPolicyN
 						r.default_ = &def
 					}
 				}
+				if a, ok := rule["allowed"].([]interface{}); ok {
+					for _, v := range a {
+						if v, ok := v.(string); ok {
+							r.allowed = append(r.allowed, v)
+						}
+					}
+				}
 
 				cpn.Add(r)
 			case "bool":
@@ -359,6 +399,13 @@ func addRulesToConfigPolicyNode(rules map[string]interface{}, cpn *ConfigPolicyN
 					max, _ := m.(float64)
 					r.maximum = &max
 				}
+				if a, ok := rule["allowed"].([]interface{}); ok {
+					for _, v := range a {
+						if v, ok := v.(float64); ok {
+							r.allowed = append(r.allowed, v)
+						}
+					}
+				}
 				cpn.Add(r)
 			default:
 				return errors.New("unknown type")
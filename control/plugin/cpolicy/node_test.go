@@ -243,5 +243,62 @@ func TestConfigPolicyNode(t *testing.T) {
 
 		So(len(pe.Errors()), ShouldEqual, 1)
 	})
+	Convey("Test config values are stored with the type of their rule", t, func() {
+		n := NewPolicyNode()
+
+		m := map[string]ctypes.ConfigValue{}
+		m["num"] = ctypes.ConfigValueInt{Value: 5}
+		m["port"] = ctypes.ConfigValueFloat{Value: 8080}
+
+		r1, _ := NewFloatRule("num", false)
+		r2, _ := NewIntegerRule("port", false)
+		n.Add(r1, r2)
+
+		m2, pe := n.Process(m)
+
+		So(len(pe.Errors()), ShouldEqual, 0)
+		So((*m2)["num"], ShouldResemble, ctypes.ConfigValueFloat{Value: 5})
+		So((*m2)["port"], ShouldResemble, ctypes.ConfigValueInt{Value: 8080})
+	})
+	Convey("Test errors tell the config item breaking the rule", t, func() {
+		n := NewPolicyNode()
+
+		m := map[string]ctypes.ConfigValue{}
+		m["port"] = ctypes.ConfigValueFloat{Value: 80.5}
 
+		r1, _ := NewIntegerRule("port", false)
+		r2, _ := NewStringRule("password", true)
+		n.Add(r1, r2)
+
+		_, pe := n.Process(m)
+
+		So(len(pe.Errors()), ShouldEqual, 2)
+		keys := []string{}
+		for _, e := range pe.Errors() {
+			So(e, ShouldHaveSameTypeAs, &RuleError{})
+			keys = append(keys, e.(*RuleError).Key)
+		}
+		So(keys, ShouldContain, "port")
+		So(keys, ShouldContain, "password")
+		So(errorsMsg(pe.Errors()), ShouldContain, "type mismatch (port wanted type 'integer' but provided type 'float')")
+	})
+	Convey("Test copied rules keep their constraints", t, func() {
+		n := NewPolicyNode()
+
+		r1, _ := NewIntegerRule("port", false)
+		r1.SetMinimum(1)
+		r1.SetMaximum(65535)
+		r2, _ := NewStringRule("proto", false, "tcp")
+		r2.SetAllowed("tcp", "udp")
+		n.Add(r1, r2)
+
+		rules, err := n.CopyRules()
+		So(err, ShouldBeNil)
+		n2 := NewPolicyNode()
+		n2.Add(rules...)
+		So(n2.RulesAsTable(), ShouldResemble, n.RulesAsTable())
+		So(n2.RulesAsTable()[0].Name, ShouldEqual, "port")
+		So(n2.RulesAsTable()[0].Maximum, ShouldResemble, ctypes.ConfigValueInt{Value: 65535})
+		So(n2.RulesAsTable()[1].Allowed, ShouldHaveLength, 2)
+	})
 }
@@ -41,12 +41,46 @@ type Rule interface {
 	Type() string
 	Minimum() ctypes.ConfigValue
 	Maximum() ctypes.ConfigValue
+	Allowed() []ctypes.ConfigValue
 }
 
 type rule struct {
 	Description string
 }
 
+// RuleError is the error of a config item breaking a rule of a policy node.
+// Key is the config item the error is about, e.g. to report the error on the
+// field of a form.
+type RuleError struct {
+	Key  string
+	Type string
+	Err  error
+}
+
+func (r *RuleError) Error() string {
+	return r.Err.Error()
+}
+
 func wrongType(key, inType, reqType string) error {
 	return errors.New(fmt.Sprintf("type mismatch (%s wanted type '%s' but provided type '%s')", key, reqType, inType))
 }
+
+func notAllowed(key string, value interface{}, allowed interface{}) error {
+	return fmt.Errorf("value is not allowed (%s value %v not in %v)", key, value, allowed)
+}
+
+// typed returns the value with the type of the rule, e.g. an integer given
+// to a float rule becomes a float
+func typed(r Rule, cv ctypes.ConfigValue) ctypes.ConfigValue {
+	switch r.Type() {
+	case FloatType:
+		if f, ok := floatValue(cv); ok {
+			return ctypes.ConfigValueFloat{Value: f}
+		}
+	case IntegerType:
+		if i, ok := intValue(cv); ok {
+			return ctypes.ConfigValueInt{Value: i}
+		}
+	}
+	return cv
+}
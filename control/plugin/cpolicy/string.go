@@ -38,6 +38,7 @@ type StringRule struct {
 	key      string
 	required bool
 	default_ *string
+	allowed  []string
 }
 
 // Returns a new string-typed rule. Arguments are key(string), required(bool), default(string).
@@ -66,14 +67,16 @@ func (s *StringRule) Type() string {
 // MarshalJSON marshals a StringRule into JSON
 func (s *StringRule) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
-		Key      string             `json:"key"`
-		Required bool               `json:"required"`
-		Default  ctypes.ConfigValue `json:"default"`
-		Type     string             `json:"type"`
+		Key      string               `json:"key"`
+		Required bool                 `json:"required"`
+		Default  ctypes.ConfigValue   `json:"default"`
+		Allowed  []ctypes.ConfigValue `json:"allowed,omitempty"`
+		Type     string               `json:"type"`
 	}{
 		Key:      s.key,
 		Required: s.required,
 		Default:  s.Default(),
+		Allowed:  s.Allowed(),
 		Type:     StringType,
 	})
 }
@@ -96,6 +99,14 @@ func (s *StringRule) GobEncode() ([]byte, error) {
 			return nil, err
 		}
 	}
+	if len(s.allowed) == 0 {
+		encoder.Encode(false)
+	} else {
+		encoder.Encode(true)
+		if err := encoder.Encode(s.allowed); err != nil {
+			return nil, err
+		}
+	}
 	return w.Bytes(), nil
 }
 
@@ -112,7 +123,14 @@ func (s *StringRule) GobDecode(buf []byte) error {
 	var is_default_set bool
 	decoder.Decode(&is_default_set)
 	if is_default_set {
-		return decoder.Decode(&s.default_)
+		if err := decoder.Decode(&s.default_); err != nil {
+			return err
+		}
+	}
+	var is_allowed_set bool
+	decoder.Decode(&is_allowed_set)
+	if is_allowed_set {
+		return decoder.Decode(&s.allowed)
 	}
 	return nil
 }
@@ -128,6 +146,16 @@ func (s *StringRule) Validate(cv ctypes.ConfigValue) error {
 	if cv.Type() != StringType {
 		return wrongType(s.key, cv.Type(), StringType)
 	}
+	// Check the value is one of the allowed ones, when they are set.
+	if len(s.allowed) > 0 {
+		v := cv.(ctypes.ConfigValueStr).Value
+		for _, a := range s.allowed {
+			if v == a {
+				return nil
+			}
+		}
+		return notAllowed(s.key, v, s.allowed)
+	}
 	return nil
 }
 
@@ -151,3 +179,21 @@ func (s *StringRule) Minimum() ctypes.ConfigValue {
 func (s *StringRule) Maximum() ctypes.ConfigValue {
 	return nil
 }
+
+// SetAllowed sets the values allowed for this rule
+func (s *StringRule) SetAllowed(values ...string) {
+	s.allowed = values
+}
+
+// Allowed returns the values allowed for this rule, or nil when any value
+// is allowed
+func (s *StringRule) Allowed() []ctypes.ConfigValue {
+	if len(s.allowed) == 0 {
+		return nil
+	}
+	allowed := make([]ctypes.ConfigValue, len(s.allowed))
+	for i, a := range s.allowed {
+		allowed[i] = ctypes.ConfigValueStr{Value: a}
+	}
+	return allowed
+}
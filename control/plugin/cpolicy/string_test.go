@@ -90,6 +90,20 @@ func TestConfigPolicyRuleString(t *testing.T) {
 				So(e, ShouldResemble, errors.New("type mismatch (thekey wanted type 'string' but provided type 'integer')"))
 			})
 
+			Convey("errors with a value which is not allowed", func() {
+				r, _ := NewStringRule("thekey", true)
+				r.SetAllowed("tcp", "udp")
+				So(r.Validate(ctypes.ConfigValueStr{Value: "udp"}), ShouldBeNil)
+				So(r.Validate(ctypes.ConfigValueStr{Value: "http"}), ShouldResemble, errors.New("value is not allowed (thekey value http not in [tcp udp])"))
+				So(r.Allowed(), ShouldResemble, []ctypes.ConfigValue{ctypes.ConfigValueStr{Value: "tcp"}, ctypes.ConfigValueStr{Value: "udp"}})
+
+				buf, err := r.GobEncode()
+				So(err, ShouldBeNil)
+				r2 := &StringRule{}
+				So(r2.GobDecode(buf), ShouldBeNil)
+				So(r2.Allowed(), ShouldResemble, r.Allowed())
+			})
+
 		})
 
 	})
@@ -25,7 +25,7 @@ import (
 	"fmt"
 	"io"
 	"os/exec"
-	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -58,7 +58,7 @@ func (cw *commandWrapper) Path() string { return cw.cmd.Path }
 func (cw *commandWrapper) Kill() error {
 	// first, kill the process wrapped up in the commandWrapper
 	if cw.cmd.Process == nil {
-		err := fmt.Errorf("Process for plugin '%s' not started; cannot kill", path.Base(cw.Path()))
+		err := fmt.Errorf("Process for plugin '%s' not started; cannot kill", filepath.Base(cw.Path()))
 		log.WithFields(log.Fields{
 			"_block": "Kill",
 		}).Warn(err)
@@ -76,6 +76,14 @@ func (cw *commandWrapper) Kill() error {
 }
 func (cw *commandWrapper) Start() error { return cw.cmd.Start() }
 
+// Pid returns the process id of the plugin, or 0 when it is not started.
+func (e *ExecutablePlugin) Pid() int {
+	if cw, ok := e.cmd.(*commandWrapper); ok && cw.cmd.Process != nil {
+		return cw.cmd.Process.Pid
+	}
+	return 0
+}
+
 // NewExecutablePlugin returns a new ExecutablePlugin.
 func NewExecutablePlugin(a Arg, commands ...string) (*ExecutablePlugin, error) {
 	jsonArgs, err := json.Marshal(a)
@@ -146,7 +154,7 @@ func (e *ExecutablePlugin) Run(timeout time.Duration) (Response, error) {
 				}
 
 				execLogger.
-					WithField("plugin", path.Base(e.cmd.Path())).
+					WithField("plugin", filepath.Base(e.cmd.Path())).
 					WithField("io", "stdout").
 					WithField("scanner_err", errScanner).
 					WithField("read_string_err", errRead).
@@ -166,7 +174,7 @@ func (e *ExecutablePlugin) Run(timeout time.Duration) (Response, error) {
 	case <-doneChan:
 	case <-time.After(timeout):
 		// We timed out waiting for the plugin's response.  Set err.
-		err = fmt.Errorf("timed out waiting for plugin %s", path.Base(e.cmd.Path()))
+		err = fmt.Errorf("timed out waiting for plugin %s", filepath.Base(e.cmd.Path()))
 	}
 	if err != nil {
 		execLogger.WithFields(log.Fields{
@@ -38,6 +38,9 @@ const (
 	SnapGOBContentType = "snap.gob"
 	// SnapJSON snap metrics serialized into json
 	SnapJSONContentType = "snap.json"
+	// SnapMsgpack snap metrics serialized into msgpack, smaller and cheaper
+	// to encode than json for large batches
+	SnapMsgpackContentType = "snap.msgpack"
 )
 
 type ConfigType struct {
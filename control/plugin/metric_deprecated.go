@@ -96,6 +96,17 @@ func MarshalMetricTypes(contentType string, metrics []MetricType) ([]byte, strin
 			return nil, "", err
 		}
 		return b, SnapJSONContentType, nil
+	case SnapMsgpackContentType:
+		b, err := marshalMsgpack(metrics)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"_module": "control-plugin",
+				"block":   "marshal-content-type",
+				"error":   err.Error(),
+			}).Error("error while marshalling")
+			return nil, "", err
+		}
+		return b, SnapMsgpackContentType, nil
 	default:
 		// We don't recognize this content type. Log and return error.
 		es := fmt.Sprintf("invalid snap content type: %s", contentType)
@@ -136,6 +147,17 @@ func UnmarshallMetricTypes(contentType string, payload []byte) ([]MetricType, er
 			return nil, err
 		}
 		return metrics, nil
+	case SnapMsgpackContentType:
+		metrics, err := unmarshalMsgpack(payload)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"_module": "control-plugin",
+				"block":   "unmarshal-content-type",
+				"error":   err.Error(),
+			}).Error("error while unmarshalling")
+			return nil, err
+		}
+		return metrics, nil
 	default:
 		// We don't recognize this content type as one we can unmarshal. Log and return error.
 		es := fmt.Sprintf("invalid snap content type for unmarshalling: %s", contentType)
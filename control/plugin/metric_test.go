@@ -139,6 +139,38 @@ func TestMetric(t *testing.T) {
 		})
 	})
 
+	Convey("marshall using snap.msgpack", t, func() {
+		config := cdata.NewNode()
+		config.AddItem("user", ctypes.ConfigValueStr{Value: "root"})
+		m := []MetricType{
+			*NewMetricType(core.NewNamespace("foo", "bar"), time.Now(), map[string]string{"host": "a"}, "B", 1),
+			*NewMetricType(core.NewNamespace("foo", "baz"), time.Now(), nil, "", "2"),
+		}
+		m[0].Config_ = config
+		a, c, e := MarshalMetricTypes("snap.msgpack", m)
+		So(e, ShouldBeNil)
+		So(len(a), ShouldBeGreaterThan, 0)
+		So(c, ShouldEqual, "snap.msgpack")
+
+		Convey("unmarshal snap.msgpack", func() {
+			m, e = UnmarshallMetricTypes("snap.msgpack", a)
+			So(e, ShouldBeNil)
+			So(m[0].Namespace().String(), ShouldResemble, "/foo/bar")
+			So(m[0].Data(), ShouldEqual, 1)
+			So(m[0].Tags(), ShouldResemble, map[string]string{"host": "a"})
+			So(m[0].Unit(), ShouldEqual, "B")
+			So(m[0].Config().Table(), ShouldResemble, config.Table())
+			So(m[1].Namespace().String(), ShouldResemble, "/foo/baz")
+			So(m[1].Data(), ShouldResemble, "2")
+		})
+
+		Convey("error on bad corrupt data", func() {
+			a = []byte{1, 0, 1, 1, 1, 1, 1, 0, 0, 1}
+			m, e = UnmarshallMetricTypes("snap.msgpack", a)
+			So(e, ShouldNotBeNil)
+		})
+	})
+
 	Convey("error on unmarshall using bad content type", t, func() {
 		m := []MetricType{
 			*NewMetricType(core.NewNamespace("foo", "bar"), time.Now(), nil, "", 1),
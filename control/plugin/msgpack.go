@@ -0,0 +1,67 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"reflect"
+
+	"github.com/hashicorp/go-msgpack/codec"
+
+	"github.com/intelsdi-x/snap/core/cdata"
+)
+
+// msgpackConfigExt is the msgpack extension tag of the config of a metric
+const msgpackConfigExt = 1
+
+// msgpackHandle encodes the metrics exchanged as snap.msgpack.  The config
+// of a metric, which has no exported fields, is encoded as an extension
+// holding its gob encoding.
+var msgpackHandle = newMsgpackHandle()
+
+func newMsgpackHandle() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{RawToString: true, WriteExt: true}
+	h.AddExt(reflect.TypeOf(cdata.ConfigDataNode{}), msgpackConfigExt,
+		func(v reflect.Value) ([]byte, error) {
+			c := v.Interface().(cdata.ConfigDataNode)
+			return c.GobEncode()
+		},
+		func(v reflect.Value, b []byte) error {
+			c := v.Addr().Interface().(*cdata.ConfigDataNode)
+			return c.GobDecode(b)
+		})
+	return h
+}
+
+func marshalMsgpack(metrics []MetricType) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := codec.NewEncoder(&buf, msgpackHandle).Encode(metrics); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func unmarshalMsgpack(payload []byte) ([]MetricType, error) {
+	var metrics []MetricType
+	if err := codec.NewDecoderBytes(payload, msgpackHandle).Decode(&metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
@@ -80,6 +80,49 @@ const (
 	STREAMGRPC RPCType = 3
 )
 
+// RPCTypes lists the RPC protocols plugins can use to talk to snapteld
+var RPCTypes = []RPCType{NativeRPC, GRPC, STREAMGRPC}
+
+// Returns string for matching enum RPC type
+func (r RPCType) String() string {
+	switch r {
+	case NativeRPC:
+		return "native-rpc"
+	case GRPC:
+		return "grpc"
+	case STREAMGRPC:
+		return "stream-grpc"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	// SnappyCompression compresses the payloads exchanged with a gRPC plugin
+	// with snappy
+	SnappyCompression = "snappy"
+	// GzipCompression compresses the payloads exchanged with a gRPC plugin
+	// with gzip
+	GzipCompression = "gzip"
+)
+
+// SupportedCompressions lists the compressions of the payloads exchanged with
+// the gRPC plugins supported by snapteld
+var SupportedCompressions = []string{SnappyCompression, GzipCompression}
+
+// NegotiateCompression returns the first compression supported by the plugin
+// that snapteld supports; an empty string means no compression
+func NegotiateCompression(pluginCompressions []string) string {
+	for _, c := range pluginCompressions {
+		for _, s := range SupportedCompressions {
+			if c == s {
+				return c
+			}
+		}
+	}
+	return ""
+}
+
 var (
 	// Timeout settings
 	// How much time must elapse before a lack of Ping results in a timeout
@@ -134,6 +177,10 @@ type PluginMeta struct {
 	RoutingStrategy RoutingStrategyType
 	// TLSEnabled identifies status of plugin security
 	TLSEnabled bool
+	// Compressions are the compressions of the payloads exchanged over gRPC
+	// supported by the plugin in priority order, e.g. snappy.  The payloads
+	// are not compressed when snapteld supports none of them.
+	Compressions []string
 }
 
 // Arg contains arguments passed to startup of Plugin
@@ -144,6 +191,9 @@ type Arg struct {
 	PingTimeoutDuration time.Duration
 
 	NoDaemon bool
+	// The listen address, either a host (IPv4 or IPv6) or a Unix domain
+	// socket address (unix:///path/to/socket); 127.0.0.1 when empty
+	ListenAddr string `json:"ListenAddr,omitempty"`
 	// The listen port
 	listenPort string
 
@@ -168,6 +218,12 @@ func (a Arg) SetKeyPath(keyPath string) Arg {
 	return a
 }
 
+// SetListenAddr sets the address the plugin listens on in plugin arguments
+func (a Arg) SetListenAddr(listenAddr string) Arg {
+	a.ListenAddr = listenAddr
+	return a
+}
+
 // SetTLSEnabled sets flag enabling TLS security in plugin arguments
 func (a Arg) SetTLSEnabled(tlsEnabled bool) Arg {
 	a.TLSEnabled = tlsEnabled
@@ -26,13 +26,14 @@ package plugin
 
 import (
 	"fmt"
-	"net"
 	"net/rpc"
 	"regexp"
 	"runtime"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/pkg/netutil"
 )
 
 var (
@@ -77,6 +78,13 @@ func CacheTTL(t time.Duration) metaOp {
 	}
 }
 
+// Compressions is an option that can be be provided to the func NewPluginMeta.
+func Compressions(c ...string) metaOp {
+	return func(m *PluginMeta) {
+		m.Compressions = c
+	}
+}
+
 // NewPluginMeta constructs and returns a PluginMeta struct
 func NewPluginMeta(name string, version int, pluginType PluginType, acceptContentTypes, returnContentTypes []string, opts ...metaOp) *PluginMeta {
 	// An empty accepted content type default to "snap.*"
@@ -199,12 +207,16 @@ func Start(m *PluginMeta, c Plugin, requestString string) (error, int) {
 		}
 	}
 
-	l, err := net.Listen("tcp", "127.0.0.1:"+s.ListenPort())
+	l, err := netutil.Listen(s.listenAddr(), 0)
 	if err != nil {
 		s.Logger().Error(err.Error())
 		panic(err)
 	}
-	s.SetListenAddress(l.Addr().String())
+	if l.Addr().Network() == "unix" {
+		s.SetListenAddress(netutil.UnixScheme + l.Addr().String())
+	} else {
+		s.SetListenAddress(l.Addr().String())
+	}
 	s.Logger().Debugf("Listening %s\n", l.Addr())
 	s.Logger().Debugf("Session token %s\n", s.Token())
 
@@ -101,3 +101,16 @@ func TestPlugin(t *testing.T) {
 		So(mockPluginMeta.CacheTTL, ShouldEqual, time.Duration(100*time.Millisecond))
 	})
 }
+
+func TestNegotiateCompression(t *testing.T) {
+	Convey("The first compression of the plugin snapteld supports is used", t, func() {
+		So(NegotiateCompression([]string{"lz4", "gzip", "snappy"}), ShouldEqual, GzipCompression)
+		So(NegotiateCompression([]string{"snappy", "gzip"}), ShouldEqual, SnappyCompression)
+		mockPluginMeta := NewPluginMeta("test", 1, PublisherPluginType, nil, nil, Compressions("gzip"))
+		So(NegotiateCompression(mockPluginMeta.Compressions), ShouldEqual, GzipCompression)
+	})
+	Convey("The payloads are not compressed when no compression is shared", t, func() {
+		So(NegotiateCompression([]string{"lz4"}), ShouldEqual, "")
+		So(NegotiateCompression(nil), ShouldEqual, "")
+	})
+}
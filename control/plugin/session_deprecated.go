@@ -33,7 +33,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"os"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -43,6 +45,7 @@ import (
 	"github.com/intelsdi-x/snap/control/plugin/encrypter"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/netutil"
 )
 
 // Started plugin session state
@@ -145,6 +148,19 @@ func (s *SessionState) Logger() *log.Logger {
 	return s.logger
 }
 
+// listenAddr returns the address the plugin listens on, given by the
+// ListenAddr and listenPort arguments
+func (s *SessionState) listenAddr() string {
+	if _, ok := netutil.UnixSocketPath(s.ListenAddr); ok {
+		return s.ListenAddr
+	}
+	host := s.ListenAddr
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), s.listenPort)
+}
+
 // ListenAddress gets the SessionState listen address
 func (s *SessionState) ListenAddress() string {
 	return s.listenAddress
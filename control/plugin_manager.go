@@ -32,12 +32,14 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/appc/spec/schema"
+	"github.com/pborman/uuid"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/intelsdi-x/gomit"
@@ -47,6 +49,7 @@ import (
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/netutil"
 )
 
 const (
@@ -186,6 +189,7 @@ type pluginDetails struct {
 	CACertPaths string
 	TLSEnabled  bool
 	Uri         *url.URL
+	Limits      core.PluginResourceLimits
 }
 
 type loadedPlugin struct {
@@ -209,6 +213,11 @@ func (lp *loadedPlugin) PluginPath() string {
 	return lp.Details.Path
 }
 
+// Signature returns the signature the plugin was loaded with, if any
+func (lp *loadedPlugin) Signature() []byte {
+	return lp.Details.Signature
+}
+
 // Key returns plugin type, name and version
 func (lp *loadedPlugin) Key() string {
 	return fmt.Sprintf("%s"+core.Separator+"%s"+core.Separator+"%d", lp.TypeName(), lp.Name(), lp.Version())
@@ -244,6 +253,12 @@ func (lp *loadedPlugin) LoadedTimestamp() *time.Time {
 	return &lp.LoadedTime
 }
 
+// ResourceLimits returns the resource limits applied to the running
+// instances of the plugin
+func (lp *loadedPlugin) ResourceLimits() core.PluginResourceLimits {
+	return lp.Details.Limits
+}
+
 func (lp *loadedPlugin) Policy() *cpolicy.ConfigPolicy {
 	return lp.ConfigPolicy
 }
@@ -260,6 +275,10 @@ type pluginManager struct {
 	pprof             bool
 	tempDirPath       string
 	grpcSecurity      client.GRPCSecurity
+	// pluginListenAddr is the host plugins listen on or the directory of
+	// the Unix domain sockets they listen on
+	pluginListenAddr string
+	pluginSocketMode os.FileMode
 }
 
 func newPluginManager(opts ...pluginManagerOpt) *pluginManager {
@@ -299,6 +318,24 @@ func OptSetPprof(pprof bool) pluginManagerOpt {
 	}
 }
 
+// OptSetPluginListenAddr sets the address plugins listen on, either a host or
+// a directory in which plugins listen on Unix domain sockets given the
+// permissions in mode (unless mode is 0).  Plugins listen on the default host
+// instead of a directory on Windows.
+func OptSetPluginListenAddr(addr string, mode os.FileMode) pluginManagerOpt {
+	return func(p *pluginManager) {
+		if _, ok := netutil.UnixSocketPath(addr); ok && !netutil.UnixSocketsSupported() {
+			runnerLog.WithFields(log.Fields{
+				"_block":             "set-plugin-listen-addr",
+				"plugin-listen-addr": addr,
+			}).Warn(netutil.ErrUnixSocketUnsupported)
+			addr = ""
+		}
+		p.pluginListenAddr = addr
+		p.pluginSocketMode = mode
+	}
+}
+
 // OptEnableManagerTLS enables the TLS configuration in plugin manager.
 func OptEnableManagerTLS(grpcSecurity client.GRPCSecurity) pluginManagerOpt {
 	return func(p *pluginManager) {
@@ -403,6 +440,9 @@ func (p *pluginManager) LoadPlugin(details *pluginDetails, emitter gomit.Emitter
 				"path":   lPlugin.Details.Exec,
 			}).Debug(fmt.Sprintf("plugin load timeout set to %ds", p.pluginLoadTimeout))
 			resp, err = ePlugin.Run(time.Second * time.Duration(p.pluginLoadTimeout))
+			if err == nil {
+				err = securePluginSocket(resp, p.pluginSocketMode)
+			}
 			if err != nil {
 				pmLogger.WithFields(log.Fields{
 					"_block": "load-plugin",
@@ -571,19 +611,7 @@ func (p *pluginManager) LoadPlugin(details *pluginDetails, emitter gomit.Emitter
 				// If the version is 0 default it to the plugin version
 				// This honors the plugins explicit version but falls back
 				// to the plugin version as default
-				if nmt.Version() < 1 {
-					// Since we have to override version we convert to a internal struct
-					nmt = &metricType{
-						namespace:          nmt.Namespace(),
-						version:            resp.Meta.Version,
-						lastAdvertisedTime: nmt.LastAdvertisedTime(),
-						config:             nmt.Config(),
-						data:               nmt.Data(),
-						tags:               nmt.Tags(),
-						description:        nmt.Description(),
-						unit:               nmt.Unit(),
-					}
-				}
+				nmt = advertisedMetricType(nmt, resp.Meta.Version)
 				// We quit and throw an error on bad metric versions (<1)
 				// the is a safety catch otherwise the catalog will be corrupted
 				if nmt.Version() < 1 {
@@ -762,7 +790,22 @@ func (p *pluginManager) UnloadPlugin(pl core.Plugin) (*loadedPlugin, serror.Snap
 
 // GenerateArgs generates the cli args to send when stating a plugin
 func (p *pluginManager) GenerateArgs(logLevel int) plugin.Arg {
-	return plugin.NewArg(logLevel, p.pprof)
+	arg := plugin.NewArg(logLevel, p.pprof)
+	if dir, ok := netutil.UnixSocketPath(p.pluginListenAddr); ok {
+		// every plugin instance is given its own socket
+		return arg.SetListenAddr(netutil.UnixScheme + filepath.Join(dir, uuid.New()+".sock"))
+	}
+	return arg.SetListenAddr(p.pluginListenAddr)
+}
+
+// securePluginSocket gives the Unix domain socket a started plugin listens on
+// the given permissions
+func securePluginSocket(resp plugin.Response, mode os.FileMode) error {
+	path, ok := netutil.UnixSocketPath(resp.ListenAddress)
+	if !ok || mode == 0 {
+		return nil
+	}
+	return os.Chmod(path, mode)
 }
 
 func (p *pluginManager) teardown() {
@@ -810,12 +853,41 @@ func split(ns string) []string {
 	return strings.Split(ns, sep)
 }
 
+// branches sorts namespace branches from the least to the most specific
+type branches []string
+
+func (b branches) Len() int {
+	return len(b)
+}
+
+func (b branches) Less(i, j int) bool {
+	li, lj := len(split(b[i])), len(split(b[j]))
+	if li != lj {
+		return li < lj
+	}
+	return b[i] < b[j]
+}
+
+func (b branches) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
+
+func sortBranches(tags map[string]map[string]string) []string {
+	b := make(branches, 0, len(tags))
+	for ns := range tags {
+		b = append(b, ns)
+	}
+	sort.Sort(b)
+	return b
+}
+
 func (p *pluginManager) AddStandardAndWorkflowTags(m core.Metric, allTags map[string]map[string]string) core.Metric {
 	hostname := hostnameReader.Hostname()
 
-	tags := m.Tags()
-	if tags == nil {
-		tags = map[string]string{}
+	// the tags are copied as the metric may be shared by several tasks
+	tags := map[string]string{}
+	for k, v := range m.Tags() {
+		tags[k] = v
 	}
 	// apply standard tag
 	tags[core.STD_TAG_PLUGIN_RUNNING_ON] = hostname
@@ -828,10 +900,10 @@ func (p *pluginManager) AddStandardAndWorkflowTags(m core.Metric, allTags map[st
 			}
 		}
 	}
-	// apply tags from workflow
-	for ns, nsTags := range allTags {
+	// apply tags from workflow; the tags of the most specific branch win
+	for _, ns := range sortBranches(allTags) {
 		if hasPrefix(m.Namespace().Strings(), split(ns)) {
-			for k, v := range nsTags {
+			for k, v := range allTags[ns] {
 				tags[k] = v
 			}
 		}
@@ -0,0 +1,203 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/fileutils"
+)
+
+const (
+	// pluginDownloadTimeout bounds the download of a plugin, its signature
+	// or the index of the plugin registry
+	pluginDownloadTimeout = 5 * time.Minute
+	// downloadedPluginName is the file name of a plugin whose URL has none
+	downloadedPluginName = "snap-plugin"
+)
+
+var (
+	// ErrPluginCheckSumMismatch - The error message for a downloaded plugin which does not match its checksum
+	ErrPluginCheckSumMismatch = errors.New("Downloaded plugin does not match its checksum")
+	// ErrNoPluginRegistry - The error message for a registry plugin loaded without a plugin registry configured
+	ErrNoPluginRegistry = errors.New("No plugin registry configured")
+	// ErrRegistryPluginNotFound - The error message for a plugin missing from the plugin registry
+	ErrRegistryPluginNotFound = errors.New("Plugin not found in the plugin registry")
+
+	pluginDownloadClient = &http.Client{Timeout: pluginDownloadTimeout}
+)
+
+// pluginRegistryIndex is the index of a plugin registry: a JSON document
+// listing the plugins available for download.  The URLs of the plugins may
+// be relative to the URL of the index.
+//
+//   {
+//     "plugins": [
+//       {
+//         "type": "collector",
+//         "name": "cpu",
+//         "version": 6,
+//         "url": "collector/cpu/6/snap-plugin-collector-cpu",
+//         "sha256": "5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef",
+//         "signature_url": "collector/cpu/6/snap-plugin-collector-cpu.asc"
+//       }
+//     ]
+//   }
+type pluginRegistryIndex struct {
+	Plugins []pluginRegistryEntry `json:"plugins"`
+}
+
+type pluginRegistryEntry struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	core.PluginSource
+}
+
+// lookup returns the entry of the index matching the reference, the latest
+// version when the reference has none
+func (idx *pluginRegistryIndex) lookup(ref *core.PluginRegistryRef) *pluginRegistryEntry {
+	var found *pluginRegistryEntry
+	for i, e := range idx.Plugins {
+		if e.Type != ref.Type || e.Name != ref.Name {
+			continue
+		}
+		if ref.Version > 0 && e.Version == ref.Version {
+			return &idx.Plugins[i]
+		}
+		if ref.Version < 1 && (found == nil || e.Version > found.Version) {
+			found = &idx.Plugins[i]
+		}
+	}
+	return found
+}
+
+// fetchPlugin downloads the plugin requested from a URL or from the plugin
+// registry into the temporary directory of the plugins, checks its checksum
+// and downloads its signature.  Local plugins are left untouched.
+func (p *pluginControl) fetchPlugin(rp *core.RequestedPlugin) error {
+	src := rp.Source()
+	if ref := rp.RegistryRef(); ref != nil {
+		var err error
+		if src, err = p.lookupRegistryPlugin(ref); err != nil {
+			return err
+		}
+	}
+	if src == nil {
+		return nil
+	}
+	f := log.Fields{
+		"_block": "fetch-plugin",
+		"url":    src.URL,
+	}
+	controlLogger.WithFields(f).Info("downloading plugin")
+	b, err := download(src.URL)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(b)
+	if hex.EncodeToString(sum[:]) != src.SHA256 {
+		controlLogger.WithFields(f).WithField("sha256", hex.EncodeToString(sum[:])).Error(ErrPluginCheckSumMismatch)
+		return fmt.Errorf("%v: %s", ErrPluginCheckSumMismatch, src.URL)
+	}
+	u, _ := url.Parse(src.URL)
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		name = downloadedPluginName
+	}
+	file, err := fileutils.WriteFile(name, p.GetTempDir(), b)
+	if err != nil {
+		return err
+	}
+	if err := rp.SetDownloaded(file); err != nil {
+		return err
+	}
+	if src.SignatureURL != "" {
+		sig, err := download(src.SignatureURL)
+		if err != nil {
+			return err
+		}
+		rp.SetSignature(sig)
+	}
+	return nil
+}
+
+// lookupRegistryPlugin returns the source of a plugin of the registry
+func (p *pluginControl) lookupRegistryPlugin(ref *core.PluginRegistryRef) (*core.PluginSource, error) {
+	if p.Config.PluginRegistry == "" {
+		return nil, ErrNoPluginRegistry
+	}
+	base, err := url.Parse(p.Config.PluginRegistry)
+	if err != nil {
+		return nil, err
+	}
+	b, err := download(p.Config.PluginRegistry)
+	if err != nil {
+		return nil, err
+	}
+	var idx pluginRegistryIndex
+	if err := json.Unmarshal(b, &idx); err != nil {
+		return nil, fmt.Errorf("Invalid plugin registry index %s: %v", p.Config.PluginRegistry, err)
+	}
+	e := idx.lookup(ref)
+	if e == nil {
+		return nil, fmt.Errorf("%v: %s", ErrRegistryPluginNotFound, ref)
+	}
+	src := e.PluginSource
+	for _, u := range []*string{&src.URL, &src.SignatureURL} {
+		if *u == "" {
+			continue
+		}
+		rel, err := url.Parse(*u)
+		if err != nil {
+			return nil, err
+		}
+		*u = base.ResolveReference(rel).String()
+	}
+	if err := src.Validate(); err != nil {
+		return nil, err
+	}
+	return &src, nil
+}
+
+// download returns the content found at an http(s) URL
+func download(u string) ([]byte, error) {
+	resp, err := pluginDownloadClient.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to download %s: %s", u, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
@@ -0,0 +1,117 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFetchPlugin(t *testing.T) {
+	Convey("Fetching a remote plugin", t, func() {
+		content := []byte("#!/bin/sh\n")
+		sum := sha256.Sum256(content)
+		checksum := hex.EncodeToString(sum[:])
+		index := fmt.Sprintf(`{"plugins": [
+			{"type": "collector", "name": "mock", "version": 1, "url": "plugins/snap-plugin-collector-mock", "sha256": "%s"},
+			{"type": "collector", "name": "mock", "version": 2, "url": "plugins/snap-plugin-collector-mock", "sha256": "%s", "signature_url": "plugins/snap-plugin-collector-mock.asc"}
+		]}`, checksum, checksum)
+		mux := http.NewServeMux()
+		mux.HandleFunc("/registry/index.json", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(index))
+		})
+		mux.HandleFunc("/registry/plugins/snap-plugin-collector-mock", func(w http.ResponseWriter, r *http.Request) {
+			w.Write(content)
+		})
+		mux.HandleFunc("/registry/plugins/snap-plugin-collector-mock.asc", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("signature"))
+		})
+		ts := httptest.NewServer(mux)
+		defer ts.Close()
+
+		dir, err := ioutil.TempDir("", "snap-remote")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		cfg := GetDefaultConfig()
+		cfg.TempDirPath = dir
+		c := &pluginControl{Config: cfg}
+
+		Convey("downloads the plugin of a URL", func() {
+			rp, err := core.NewRemoteRequestedPlugin(core.PluginSource{
+				URL:    ts.URL + "/registry/plugins/snap-plugin-collector-mock",
+				SHA256: checksum,
+			})
+			So(err, ShouldBeNil)
+			So(c.fetchPlugin(rp), ShouldBeNil)
+			So(rp.Path(), ShouldStartWith, dir)
+			b, err := ioutil.ReadFile(rp.Path())
+			So(err, ShouldBeNil)
+			So(b, ShouldResemble, content)
+			So(rp.CheckSum(), ShouldEqual, sum)
+		})
+		Convey("refuses a plugin which does not match its checksum", func() {
+			other := sha256.Sum256([]byte("other"))
+			rp, err := core.NewRemoteRequestedPlugin(core.PluginSource{
+				URL:    ts.URL + "/registry/plugins/snap-plugin-collector-mock",
+				SHA256: hex.EncodeToString(other[:]),
+			})
+			So(err, ShouldBeNil)
+			err = c.fetchPlugin(rp)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, ErrPluginCheckSumMismatch.Error())
+			So(rp.Path(), ShouldEqual, "")
+		})
+		Convey("refuses a registry plugin without a plugin registry", func() {
+			rp := core.NewRegistryRequestedPlugin(&core.PluginRegistryRef{Type: "collector", Name: "mock"})
+			So(c.fetchPlugin(rp), ShouldEqual, ErrNoPluginRegistry)
+		})
+		Convey("with a plugin registry", func() {
+			cfg.PluginRegistry = ts.URL + "/registry/index.json"
+			Convey("downloads the latest version of a plugin with its signature", func() {
+				rp := core.NewRegistryRequestedPlugin(&core.PluginRegistryRef{Type: "collector", Name: "mock"})
+				So(c.fetchPlugin(rp), ShouldBeNil)
+				So(rp.Path(), ShouldNotEqual, "")
+				So(rp.Signature(), ShouldResemble, []byte("signature"))
+			})
+			Convey("downloads the given version of a plugin", func() {
+				rp := core.NewRegistryRequestedPlugin(&core.PluginRegistryRef{Type: "collector", Name: "mock", Version: 1})
+				So(c.fetchPlugin(rp), ShouldBeNil)
+				So(rp.Signature(), ShouldBeNil)
+			})
+			Convey("fails for a plugin missing from the registry", func() {
+				rp := core.NewRegistryRequestedPlugin(&core.PluginRegistryRef{Type: "collector", Name: "mock", Version: 3})
+				err := c.fetchPlugin(rp)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, ErrRegistryPluginNotFound.Error())
+			})
+		})
+	})
+}
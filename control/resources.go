@@ -0,0 +1,381 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/control_event"
+)
+
+const (
+	// ResourceMemory - the name of the memory resource in limit events
+	ResourceMemory = "memory"
+	// ResourceCPU - the name of the CPU resource in limit events
+	ResourceCPU = "cpu"
+
+	// cgroupCPUPeriod is the cpu.max period (in microseconds) used to turn a
+	// CPU percentage into a quota
+	cgroupCPUPeriod = 100000
+	// clockTicks is the unit of the CPU times in /proc/<pid>/stat (USER_HZ)
+	clockTicks = 100
+)
+
+var (
+	// ErrNoProcessUsage - The error message for a process whose resource usage could not be read
+	ErrNoProcessUsage = errors.New("Unable to read the resource usage of the plugin process")
+	// ErrResourceLimitsUnsupported - The error message for a plugin loaded with resource limits on a platform which cannot enforce them
+	ErrResourceLimitsUnsupported = errors.New("Plugin resource limits are not supported on this platform")
+)
+
+// resourceLimitsSupported returns true when the resource usage of the plugin
+// processes can be sampled on the platform, /proc on Linux and ps on the
+// other Unix platforms; it is not on Windows.
+func resourceLimitsSupported(goos string) bool {
+	return goos != "windows"
+}
+
+// pidPlugin is implemented by the executable plugins which know the id of
+// the process they run in
+type pidPlugin interface {
+	Pid() int
+}
+
+// checksResources is implemented by the available plugins whose resource
+// usage is sampled by the monitor
+type checksResources interface {
+	CheckResources()
+}
+
+// pluginResources holds the resource limits of a running plugin instance and
+// its last sampled usage
+type pluginResources struct {
+	sync.Mutex
+	limits    core.PluginResourceLimits
+	usage     core.PluginResourceUsage
+	cpuTime   time.Duration
+	sampledAt time.Time
+	cgroup    string
+	exceeded  bool
+}
+
+// sample records the CPU time and memory of the process read at the given
+// time and returns the resulting usage; the CPU usage is averaged since the
+// previous sample.
+func (r *pluginResources) sample(cpuTime time.Duration, memory int64, at time.Time) core.PluginResourceUsage {
+	r.Lock()
+	defer r.Unlock()
+	usage := core.PluginResourceUsage{MemoryBytes: memory}
+	if !r.sampledAt.IsZero() && at.After(r.sampledAt) && cpuTime >= r.cpuTime {
+		usage.CPUPercent = float64(cpuTime-r.cpuTime) / float64(at.Sub(r.sampledAt)) * 100
+	}
+	r.cpuTime = cpuTime
+	r.sampledAt = at
+	r.usage = usage
+	return usage
+}
+
+// release removes the cgroup created for the plugin instance, if any
+func (r *pluginResources) release() {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	if r.cgroup == "" {
+		return
+	}
+	if err := os.Remove(r.cgroup); err != nil {
+		log.WithFields(log.Fields{
+			"_module": "control-aplugin",
+			"block":   "release-resources",
+			"cgroup":  r.cgroup,
+		}).Warn(err)
+	}
+	r.cgroup = ""
+}
+
+// exceededLimit returns the first resource of the usage which is over its
+// limit, along with the used amount and the limit.
+func exceededLimit(limits core.PluginResourceLimits, usage core.PluginResourceUsage) (string, float64, float64, bool) {
+	if limits.MemoryBytes > 0 && usage.MemoryBytes > limits.MemoryBytes {
+		return ResourceMemory, float64(usage.MemoryBytes), float64(limits.MemoryBytes), true
+	}
+	if limits.CPUPercent > 0 && usage.CPUPercent > limits.CPUPercent {
+		return ResourceCPU, usage.CPUPercent, limits.CPUPercent, true
+	}
+	return "", 0, 0, false
+}
+
+// pid returns the id of the plugin process or 0 when it is unknown
+func (a *availablePlugin) pid() int {
+	if p, ok := a.ePlugin.(pidPlugin); ok {
+		return p.Pid()
+	}
+	return 0
+}
+
+// ResourceLimits returns the resource limits of the available plugin
+func (a *availablePlugin) ResourceLimits() core.PluginResourceLimits {
+	if a.resources == nil {
+		return core.PluginResourceLimits{}
+	}
+	a.resources.Lock()
+	defer a.resources.Unlock()
+	return a.resources.limits
+}
+
+// ResourceUsage returns the resource usage of the available plugin when it
+// was last sampled by the monitor
+func (a *availablePlugin) ResourceUsage() core.PluginResourceUsage {
+	if a.resources == nil {
+		return core.PluginResourceUsage{}
+	}
+	a.resources.Lock()
+	defer a.resources.Unlock()
+	return a.resources.usage
+}
+
+// setResourceLimits records the resource limits of the available plugin and,
+// when a cgroup path is given on Linux, has the kernel enforce them.  The
+// limits are enforced by the monitor in every case.
+func (a *availablePlugin) setResourceLimits(limits core.PluginResourceLimits, cgroupPath string) error {
+	if a.resources == nil || limits.IsZero() {
+		return nil
+	}
+	a.resources.Lock()
+	defer a.resources.Unlock()
+	a.resources.limits = limits
+	pid := a.pid()
+	if cgroupPath != "" && runtime.GOOS != "linux" {
+		log.WithFields(log.Fields{
+			"_module":     "control-aplugin",
+			"block":       "set-resource-limits",
+			"plugin_name": a,
+			"cgroup_path": cgroupPath,
+		}).Warn("plugin cgroups are only created on Linux, the resource limits are only enforced by sampling")
+	}
+	if cgroupPath == "" || runtime.GOOS != "linux" || pid == 0 {
+		return nil
+	}
+	cgroup := filepath.Join(cgroupPath, fmt.Sprintf("%s-%s-%d-%d", a.TypeName(), a.name, a.version, a.id))
+	if err := applyCgroupLimits(cgroupPath, cgroup, pid, limits); err != nil {
+		os.Remove(cgroup)
+		return err
+	}
+	a.resources.cgroup = cgroup
+	return nil
+}
+
+// CheckResources samples the resource usage of the plugin and, when it is
+// over one of the limits, emits a ResourceLimitExceededEvent and a
+// DeadAvailablePluginEvent so that the plugin is killed and restarted.
+func (a *availablePlugin) CheckResources() {
+	if a.resources == nil {
+		return
+	}
+	pid := a.pid()
+	if pid == 0 {
+		return
+	}
+	cpuTime, memory, err := processUsage(pid)
+	if err != nil {
+		l := log.WithFields(log.Fields{
+			"_module":     "control-aplugin",
+			"block":       "check-resources",
+			"plugin_name": a,
+			"error":       err,
+		})
+		// the limits of the plugin are not enforced without its usage
+		if a.ResourceLimits().IsZero() {
+			l.Debug(ErrNoProcessUsage)
+		} else {
+			l.Warn(ErrNoProcessUsage)
+		}
+		return
+	}
+	usage := a.resources.sample(cpuTime, memory, time.Now())
+
+	a.resources.Lock()
+	resource, used, limit, exceeded := exceededLimit(a.resources.limits, usage)
+	if !exceeded || a.resources.exceeded {
+		a.resources.Unlock()
+		return
+	}
+	// the plugin is reported only once, it is replaced by a new instance
+	a.resources.exceeded = true
+	a.resources.Unlock()
+
+	log.WithFields(log.Fields{
+		"_module":     "control-aplugin",
+		"block":       "check-resources",
+		"plugin_name": a,
+		"resource":    resource,
+		"usage":       used,
+		"limit":       limit,
+	}).Warning("resource limit exceeded")
+	a.emitter.Emit(&control_event.ResourceLimitExceededEvent{
+		Name:     a.name,
+		Version:  a.version,
+		Type:     int(a.pluginType),
+		Key:      a.key,
+		Id:       a.ID(),
+		Resource: resource,
+		Usage:    used,
+		Limit:    limit,
+	})
+	a.emitter.Emit(&control_event.DeadAvailablePluginEvent{
+		Name:    a.name,
+		Version: a.version,
+		Type:    int(a.pluginType),
+		Key:     a.key,
+		Id:      a.ID(),
+		String:  a.String(),
+	})
+}
+
+// applyCgroupLimits creates a cgroup (v2) for the process under the given
+// parent, sets its memory and CPU limits and moves the process into it.
+func applyCgroupLimits(parent, cgroup string, pid int, limits core.PluginResourceLimits) error {
+	// the controllers have to be enabled for the children of the parent
+	if err := ioutil.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+memory +cpu"), 0644); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cgroup, 0755); err != nil {
+		return err
+	}
+	if limits.MemoryBytes > 0 {
+		if err := ioutil.WriteFile(filepath.Join(cgroup, "memory.max"), []byte(strconv.FormatInt(limits.MemoryBytes, 10)), 0644); err != nil {
+			return err
+		}
+	}
+	if limits.CPUPercent > 0 {
+		quota := int64(limits.CPUPercent * cgroupCPUPeriod / 100)
+		if err := ioutil.WriteFile(filepath.Join(cgroup, "cpu.max"), []byte(fmt.Sprintf("%d %d", quota, cgroupCPUPeriod)), 0644); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(filepath.Join(cgroup, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644)
+}
+
+// processUsage returns the CPU time consumed by a process and its resident
+// memory in bytes.  It reads /proc on Linux and uses ps elsewhere.
+func processUsage(pid int) (time.Duration, int64, error) {
+	if runtime.GOOS == "linux" {
+		return procUsage(pid)
+	}
+	return psUsage(pid)
+}
+
+func procUsage(pid int) (time.Duration, int64, error) {
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	statm, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	return parseProcUsage(string(stat), string(statm), os.Getpagesize())
+}
+
+// parseProcUsage parses the content of /proc/<pid>/stat and /proc/<pid>/statm
+func parseProcUsage(stat, statm string, pageSize int) (time.Duration, int64, error) {
+	// the command name may contain spaces, the fields start after it
+	i := strings.LastIndex(stat, ")")
+	if i < 0 {
+		return 0, 0, ErrNoProcessUsage
+	}
+	fields := strings.Fields(stat[i+1:])
+	// utime and stime are the 14th and 15th fields of the file
+	if len(fields) < 13 {
+		return 0, 0, ErrNoProcessUsage
+	}
+	utime, err := strconv.ParseInt(fields[11], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	stime, err := strconv.ParseInt(fields[12], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	mfields := strings.Fields(statm)
+	if len(mfields) < 2 {
+		return 0, 0, ErrNoProcessUsage
+	}
+	resident, err := strconv.ParseInt(mfields[1], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	cpuTime := time.Duration(utime+stime) * time.Second / clockTicks
+	return cpuTime, resident * int64(pageSize), nil
+}
+
+func psUsage(pid int) (time.Duration, int64, error) {
+	out, err := exec.Command("ps", "-o", "rss=,time=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return 0, 0, err
+	}
+	return parsePsUsage(string(out))
+}
+
+// parsePsUsage parses the output of `ps -o rss=,time=`, the resident memory
+// being in kilobytes and the CPU time formatted as [[dd-]hh:]mm:ss[.ss]
+func parsePsUsage(out string) (time.Duration, int64, error) {
+	fields := strings.Fields(out)
+	if len(fields) < 2 {
+		return 0, 0, ErrNoProcessUsage
+	}
+	rss, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	var days int64
+	t := fields[1]
+	if i := strings.Index(t, "-"); i >= 0 {
+		if days, err = strconv.ParseInt(t[:i], 10, 64); err != nil {
+			return 0, 0, err
+		}
+		t = t[i+1:]
+	}
+	var seconds float64
+	for _, part := range strings.Split(t, ":") {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		seconds = seconds*60 + v
+	}
+	cpuTime := time.Duration(days)*24*time.Hour + time.Duration(seconds*float64(time.Second))
+	return cpuTime, rss * 1024, nil
+}
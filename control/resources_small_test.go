@@ -0,0 +1,100 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+func TestPluginResources(t *testing.T) {
+	Convey("Reading the resource usage of a plugin process", t, func() {
+		Convey("from /proc", func() {
+			stat := "1234 (snap plugin) S 1 1234 1234 0 -1 4194560 1 0 0 0 150 50 0 0 20 0 8 0 100 0 0"
+			cpuTime, memory, err := parseProcUsage(stat, "2000 300 100 1 0 500 0", 4096)
+			So(err, ShouldBeNil)
+			So(cpuTime, ShouldEqual, 2*time.Second)
+			So(memory, ShouldEqual, 300*4096)
+		})
+		Convey("from ps", func() {
+			cpuTime, memory, err := parsePsUsage("  2048 01:02:03\n")
+			So(err, ShouldBeNil)
+			So(cpuTime, ShouldEqual, time.Hour+2*time.Minute+3*time.Second)
+			So(memory, ShouldEqual, 2048*1024)
+
+			cpuTime, _, err = parsePsUsage("2048 1-00:00:01")
+			So(err, ShouldBeNil)
+			So(cpuTime, ShouldEqual, 24*time.Hour+time.Second)
+
+			cpuTime, _, err = parsePsUsage("2048 0:01.50")
+			So(err, ShouldBeNil)
+			So(cpuTime, ShouldEqual, 1500*time.Millisecond)
+		})
+		Convey("from a malformed output", func() {
+			_, _, err := parsePsUsage("")
+			So(err, ShouldNotBeNil)
+			_, _, err = parseProcUsage("1234 snap", "", 4096)
+			So(err, ShouldNotBeNil)
+		})
+	})
+	Convey("Sampling the resource usage", t, func() {
+		r := &pluginResources{}
+		now := time.Now()
+		usage := r.sample(time.Second, 1024, now)
+		So(usage.CPUPercent, ShouldEqual, 0)
+		So(usage.MemoryBytes, ShouldEqual, 1024)
+		Convey("averages the CPU usage since the previous sample", func() {
+			usage = r.sample(3*time.Second, 2048, now.Add(4*time.Second))
+			So(usage.CPUPercent, ShouldEqual, 50)
+			So(usage.MemoryBytes, ShouldEqual, 2048)
+		})
+	})
+	Convey("Checking the resource limits", t, func() {
+		limits := core.PluginResourceLimits{MemoryBytes: 1024, CPUPercent: 50}
+		_, _, _, exceeded := exceededLimit(limits, core.PluginResourceUsage{MemoryBytes: 1024, CPUPercent: 50})
+		So(exceeded, ShouldBeFalse)
+
+		resource, used, limit, exceeded := exceededLimit(limits, core.PluginResourceUsage{MemoryBytes: 2048})
+		So(exceeded, ShouldBeTrue)
+		So(resource, ShouldEqual, ResourceMemory)
+		So(used, ShouldEqual, 2048)
+		So(limit, ShouldEqual, 1024)
+
+		resource, _, _, exceeded = exceededLimit(limits, core.PluginResourceUsage{CPUPercent: 75})
+		So(exceeded, ShouldBeTrue)
+		So(resource, ShouldEqual, ResourceCPU)
+
+		Convey("without limits nothing is exceeded", func() {
+			_, _, _, exceeded := exceededLimit(core.PluginResourceLimits{}, core.PluginResourceUsage{MemoryBytes: 1 << 30, CPUPercent: 400})
+			So(exceeded, ShouldBeFalse)
+		})
+	})
+	Convey("The resource limits are only supported where the usage is sampled", t, func() {
+		So(resourceLimitsSupported("linux"), ShouldBeTrue)
+		So(resourceLimitsSupported("darwin"), ShouldBeTrue)
+		So(resourceLimitsSupported("windows"), ShouldBeFalse)
+	})
+}
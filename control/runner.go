@@ -23,7 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"path"
+	"path/filepath"
 	"time"
 
 	"github.com/intelsdi-x/gomit"
@@ -77,6 +77,10 @@ type runner struct {
 	pluginManager     managesPlugins
 	grpcSecurity      client.GRPCSecurity
 	pluginLoadTimeout int
+	pluginSocketMode  os.FileMode
+	pluginCgroupPath  string
+	// closed on Stop to cancel the pending plugin restarts
+	quit chan struct{}
 }
 
 func newRunner(opts ...pluginRunnerOpt) *runner {
@@ -102,6 +106,22 @@ func OptEnableRunnerTLS(grpcSecurity client.GRPCSecurity) pluginRunnerOpt {
 	}
 }
 
+// OptSetRunnerSocketMode sets the permissions given to the Unix domain
+// sockets started plugins listen on
+func OptSetRunnerSocketMode(mode os.FileMode) pluginRunnerOpt {
+	return func(r *runner) {
+		r.pluginSocketMode = mode
+	}
+}
+
+// OptSetRunnerCgroupPath sets the cgroup (v2) directory under which the
+// running plugins with resource limits are placed
+func OptSetRunnerCgroupPath(path string) pluginRunnerOpt {
+	return func(r *runner) {
+		r.pluginCgroupPath = path
+	}
+}
+
 func optDefaultRunnerSecurity() pluginRunnerOpt {
 	return func(r *runner) {
 		r.grpcSecurity = client.SecurityTLSOff()
@@ -156,6 +176,7 @@ func (r *runner) Start() error {
 		}
 	}
 
+	r.quit = make(chan struct{})
 	// Start the monitor
 	r.monitor.Start(r.availablePlugins)
 	runnerLog.WithFields(log.Fields{
@@ -170,6 +191,9 @@ func (r *runner) Stop() []error {
 
 	// Stop the monitor
 	r.monitor.Stop()
+	if r.quit != nil {
+		close(r.quit)
+	}
 
 	// TODO: Actually stop the plugins
 
@@ -194,6 +218,9 @@ func (r *runner) startPlugin(p executablePlugin) (*availablePlugin, error) {
 	resultChan := make(chan result)
 	go func() {
 		resp, err := p.Run(time.Second * time.Duration(r.pluginLoadTimeout))
+		if err == nil {
+			err = securePluginSocket(resp, r.pluginSocketMode)
+		}
 		if err != nil {
 			e := errors.New("error starting plugin: " + err.Error())
 			runnerLog.WithFields(log.Fields{
@@ -274,65 +301,7 @@ func (r *runner) stopPlugin(reason string, ap *availablePlugin) error {
 func (r *runner) HandleGomitEvent(e gomit.Event) {
 	switch v := e.Body.(type) {
 	case *control_event.DeadAvailablePluginEvent:
-		runnerLog.WithFields(log.Fields{
-			"_block":  "handle-events",
-			"event":   v.Namespace(),
-			"aplugin": v.String,
-		}).Warning("handling dead available plugin event")
-
-		pool, err := r.availablePlugins.getPool(v.Key)
-		if err != nil {
-			runnerLog.WithFields(log.Fields{
-				"_block":  "handle-events",
-				"aplugin": v.String,
-			}).Error(err.Error())
-			return
-		}
-
-		if pool != nil {
-			pool.Kill(v.Id, "plugin dead")
-		}
-
-		if pool.Eligible() {
-			if pool.RestartCount() < MaxPluginRestartCount || MaxPluginRestartCount == -1 {
-				e := r.restartPlugin(v.Key)
-				if e != nil {
-					runnerLog.WithFields(log.Fields{
-						"_block":  "handle-events",
-						"aplugin": v.String,
-					}).Error(e.Error())
-					return
-				}
-				pool.IncRestartCount()
-
-				runnerLog.WithFields(log.Fields{
-					"_block":        "handle-events",
-					"aplugin":       v.String,
-					"restart-count": pool.RestartCount(),
-				}).Warning("plugin restarted")
-
-				r.emitter.Emit(&control_event.RestartedAvailablePluginEvent{
-					Id:      v.Id,
-					Name:    v.Name,
-					Version: v.Version,
-					Key:     v.Key,
-					Type:    v.Type,
-				})
-			} else {
-				runnerLog.WithFields(log.Fields{
-					"_block":  "handle-events",
-					"aplugin": v.String,
-				}).Warning("plugin disabled due to exceeding restart limit: ", MaxPluginRestartCount)
-
-				r.emitter.Emit(&control_event.MaxPluginRestartsExceededEvent{
-					Id:      v.Id,
-					Name:    v.Name,
-					Version: v.Version,
-					Key:     v.Key,
-					Type:    v.Type,
-				})
-			}
-		}
+		r.handleDeadPlugin(v)
 	case *control_event.PluginUnsubscriptionEvent:
 		runnerLog.WithFields(log.Fields{
 			"_block":         "subscribe-pool",
@@ -365,11 +334,11 @@ func (r *runner) runPlugin(name string, details *pluginDetails) error {
 		if err != nil {
 			return err
 		}
-		details.ExecPath = path.Join(tempPath, "rootfs")
+		details.ExecPath = filepath.Join(tempPath, "rootfs")
 	}
 	commands := make([]string, len(details.Exec))
 	for i, e := range details.Exec {
-		commands[i] = path.Join(details.ExecPath, e)
+		commands[i] = filepath.Join(details.ExecPath, e)
 	}
 	ePlugin, err := plugin.NewExecutablePlugin(r.pluginManager.GenerateArgs(int(log.GetLevel())).
 		SetCertPath(details.CertPath).
@@ -398,6 +367,13 @@ func (r *runner) runPlugin(name string, details *pluginDetails) error {
 	if details.IsPackage {
 		ap.fromPackage = true
 	}
+	if err := ap.setResourceLimits(details.Limits, r.pluginCgroupPath); err != nil {
+		runnerLog.WithFields(log.Fields{
+			"_block": "run-plugin",
+			"path":   commands,
+			"error":  err,
+		}).Warn("unable to apply the resource limits, they are enforced by sampling only")
+	}
 	return nil
 }
 
@@ -0,0 +1,221 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/secrets"
+)
+
+// newSecrets returns the resolver of the references to secrets in the config
+// of the plugins, with the Vault provider set by the configuration
+func newSecrets(cfg *Config) (*secrets.Resolver, *secrets.Vault) {
+	vc := cfg.Vault
+	if vc == nil {
+		vc = &VaultConfig{}
+	}
+	address := vc.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	vault := secrets.NewVault(address, vaultToken(vc), vc.SecretTTL.Duration)
+	resolver := secrets.NewResolver()
+	resolver.Register(secrets.VaultProvider, vault)
+	return resolver, vault
+}
+
+// vaultToken returns the token read from the token file of the config, the
+// VAULT_TOKEN environment variable when there is none or it cannot be read
+func vaultToken(vc *VaultConfig) string {
+	token := os.Getenv("VAULT_TOKEN")
+	if vc.TokenFile == "" {
+		return token
+	}
+	b, err := ioutil.ReadFile(vc.TokenFile)
+	if err != nil {
+		controlLogger.WithFields(log.Fields{
+			"_block":     "vault-token",
+			"token-file": vc.TokenFile,
+			"error":      err,
+		}).Error("cannot read the vault token")
+		return token
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// errSecretPathNotAllowed is the error of a reference to a Vault secret
+// outside of the paths the task may read
+var errSecretPathNotAllowed = errors.New("path not allowed")
+
+// SetTaskTenant sets the tenant of the task subscribing under the id, whose
+// references to Vault secrets are restricted to the paths of the tenant.  It
+// is cleared when the task is unsubscribed.
+func (p *pluginControl) SetTaskTenant(id, tenant string) {
+	p.taskTenantsMutex.Lock()
+	defer p.taskTenantsMutex.Unlock()
+	if tenant == "" {
+		delete(p.taskTenants, id)
+		return
+	}
+	if p.taskTenants == nil {
+		p.taskTenants = map[string]string{}
+	}
+	p.taskTenants[id] = tenant
+}
+
+func (p *pluginControl) taskTenant(id string) string {
+	p.taskTenantsMutex.RLock()
+	defer p.taskTenantsMutex.RUnlock()
+	return p.taskTenants[id]
+}
+
+// secretsCheck returns the check of the references to Vault secrets in the
+// config of the task subscribed under the id, nil when the paths are not
+// restricted.  The path of a reference must be one of the allowed paths of
+// the Vault config, or of the paths of the tenant of the task, or below one.
+func (p *pluginControl) secretsCheck(id string) secrets.Check {
+	if p.Config == nil || p.Config.Vault == nil {
+		return nil
+	}
+	vc := p.Config.Vault
+	if len(vc.AllowedPaths) == 0 && len(vc.TenantPaths) == 0 {
+		return nil
+	}
+	allowed := vc.AllowedPaths
+	if tenant := p.taskTenant(id); tenant != "" {
+		allowed = append(append([]string{}, allowed...), vc.TenantPaths[tenant]...)
+	}
+	return func(ref secrets.Reference) error {
+		if ref.Provider != secrets.VaultProvider {
+			return nil
+		}
+		// the paths are compared once cleaned, so that '..' can not
+		// leave an allowed path
+		if path.Clean(ref.Path) != ref.Path {
+			return errSecretPathNotAllowed
+		}
+		for _, prefix := range allowed {
+			prefix = strings.Trim(prefix, "/")
+			if ref.Path == prefix || strings.HasPrefix(ref.Path, prefix+"/") {
+				return nil
+			}
+		}
+		return errSecretPathNotAllowed
+	}
+}
+
+// resolveSecrets returns the config with the references to secrets replaced
+// by their values, for the task subscribed under the id.  The config given
+// keeps the references, so that the secrets are neither exported nor saved
+// with the task.
+func (p *pluginControl) resolveSecrets(id string, config map[string]ctypes.ConfigValue) (map[string]ctypes.ConfigValue, error) {
+	resolved, _, err := p.secrets.ResolveTable(config, p.secretsCheck(id))
+	return resolved, err
+}
+
+// ResolveSecret returns the value with the reference to a secret it holds
+// replaced by the secret, for the task subscribed under the id, e.g. for the
+// settings of its built-in publishers.  The other values are returned as
+// they are.
+func (p *pluginControl) ResolveSecret(id, value string) (string, error) {
+	secret, _, err := p.secrets.Resolve(value, p.secretsCheck(id))
+	return secret, err
+}
+
+// resolveMetricSecrets checks the references to secrets in the config of
+// the metrics the task subscribing under the id subscribes to.  They are
+// resolved again, from the cache of their provider, when the metrics are
+// collected.
+func (p *pluginControl) resolveMetricSecrets(id string, metrics map[string]metricTypes) []serror.SnapError {
+	var serrs []serror.SnapError
+	for _, pmt := range metrics {
+		for _, m := range pmt.metricTypes {
+			mt, ok := m.(*metricType)
+			if !ok || mt.config == nil {
+				continue
+			}
+			if _, err := p.resolveSecrets(id, mt.config.Table()); err != nil {
+				serrs = append(serrs, serror.New(fmt.Errorf("invalid config of metric %s: %v", mt.Namespace(), err), map[string]interface{}{
+					"metric":  mt.Namespace().String(),
+					"version": mt.Version(),
+				}))
+			}
+		}
+	}
+	return serrs
+}
+
+// resolveCollectSecrets returns the metrics the task collects with the
+// references to secrets in their config replaced by their values, so that
+// the collectors are given the secrets renewed or rotated since the task
+// started.  The metrics of the subscription group keep the references.
+func (p *pluginControl) resolveCollectSecrets(id string, mts []core.Metric) ([]core.Metric, error) {
+	check := p.secretsCheck(id)
+	resolved := make([]core.Metric, len(mts))
+	for i, m := range mts {
+		resolved[i] = m
+		mt, ok := m.(*metricType)
+		if !ok || mt.config == nil {
+			continue
+		}
+		table, isRef, err := p.secrets.ResolveTable(mt.config.Table(), check)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config of metric %s: %v", mt.Namespace(), err)
+		}
+		if isRef {
+			cp := *mt
+			cp.config = cdata.FromTable(table)
+			resolved[i] = &cp
+		}
+	}
+	return resolved, nil
+}
+
+// resolvePluginSecrets checks the references to secrets in the config of
+// the processors and publishers the task subscribing under the id subscribes
+// to.  They are resolved again, from the cache of their provider, when the
+// plugins are called.
+func (p *pluginControl) resolvePluginSecrets(id string, plugins []core.SubscribedPlugin) []serror.SnapError {
+	var serrs []serror.SnapError
+	for _, plg := range plugins {
+		if plg.Config() == nil {
+			continue
+		}
+		if _, err := p.resolveSecrets(id, plg.Config().Table()); err != nil {
+			serrs = append(serrs, serror.New(fmt.Errorf("invalid config of plugin %s:%s:%d: %v", plg.TypeName(), plg.Name(), plg.Version(), err), map[string]interface{}{
+				"name":    plg.Name(),
+				"version": plg.Version(),
+			}))
+		}
+	}
+	return serrs
+}
@@ -0,0 +1,168 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/secrets"
+)
+
+type mockSecrets map[string]string
+
+func (m mockSecrets) Secret(path, key string) (string, error) {
+	if v, ok := m[path+"#"+key]; ok {
+		return v, nil
+	}
+	return "", errors.New("not found")
+}
+
+func TestResolveMetricSecrets(t *testing.T) {
+	Convey("Given a control resolving secrets", t, func() {
+		c := &pluginControl{secrets: secrets.NewResolver()}
+		provider := mockSecrets{"secret/db#password": "s3cr3t"}
+		c.secrets.Register("mock", provider)
+		cfg := cdata.NewNode()
+		cfg.AddItem("password", ctypes.ConfigValueStr{Value: "mock:secret/db#password"})
+		mt := &metricType{namespace: core.NewNamespace("intel", "db"), config: cfg}
+		metrics := map[string]metricTypes{"collector:db:1": {metricTypes: []core.Metric{mt}}}
+
+		Convey("the metrics are given the secrets when they are collected", func() {
+			So(c.resolveMetricSecrets("task", metrics), ShouldBeEmpty)
+			mts, err := c.resolveCollectSecrets("task", []core.Metric{mt})
+			So(err, ShouldBeNil)
+			So(mts[0].Config().Table()["password"], ShouldResemble, ctypes.ConfigValueStr{Value: "s3cr3t"})
+
+			Convey("while the config of the task keeps the references", func() {
+				So(mt.Config().Table()["password"], ShouldResemble, ctypes.ConfigValueStr{Value: "mock:secret/db#password"})
+			})
+			Convey("and the secrets rotated since", func() {
+				provider["secret/db#password"] = "r0tated"
+				mts, err := c.resolveCollectSecrets("task", []core.Metric{mt})
+				So(err, ShouldBeNil)
+				So(mts[0].Config().Table()["password"], ShouldResemble, ctypes.ConfigValueStr{Value: "r0tated"})
+			})
+		})
+		Convey("the secrets which cannot be read fail the subscription", func() {
+			cfg.AddItem("password", ctypes.ConfigValueStr{Value: "mock:secret/db#passwd"})
+			serrs := c.resolveMetricSecrets("task", metrics)
+			So(serrs, ShouldHaveLength, 1)
+			So(serrs[0].Error(), ShouldEqual, "invalid config of metric /intel/db: password: cannot resolve mock:secret/db#passwd: not found")
+
+			Convey("and the collection", func() {
+				_, err := c.resolveCollectSecrets("task", []core.Metric{mt})
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestSecretsAllowedPaths(t *testing.T) {
+	Convey("Given a control restricting the paths of the Vault secrets", t, func() {
+		c := &pluginControl{secrets: secrets.NewResolver(), Config: GetDefaultConfig()}
+		c.Config.Vault.AllowedPaths = []string{"secret/shared"}
+		c.Config.Vault.TenantPaths = map[string][]string{"team-a": {"secret/team-a/"}}
+		c.secrets.Register(secrets.VaultProvider, mockSecrets{
+			"secret/shared/db#password":    "shared",
+			"secret/team-a/db#password":    "a",
+			"secret/team-ab/db#password":   "ab",
+			"secret/team-b/db#password":    "b",
+			"secret/team-a/../db#password": "escaped",
+		})
+		c.SetTaskTenant("task-a", "team-a")
+		resolve := func(id, ref string) (string, error) {
+			resolved, err := c.resolveSecrets(id, map[string]ctypes.ConfigValue{"password": ctypes.ConfigValueStr{Value: ref}})
+			if err != nil {
+				return "", err
+			}
+			return resolved["password"].(ctypes.ConfigValueStr).Value, nil
+		}
+
+		Convey("the tasks read the allowed paths", func() {
+			v, err := resolve("task", "vault:secret/shared/db#password")
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, "shared")
+			v, err = resolve("task-a", "vault:secret/shared/db#password")
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, "shared")
+		})
+		Convey("the tasks of a tenant read the paths of their tenant", func() {
+			v, err := resolve("task-a", "vault:secret/team-a/db#password")
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, "a")
+		})
+		Convey("the other paths are rejected", func() {
+			for _, c := range []struct{ id, ref string }{
+				{"task", "vault:secret/team-a/db#password"},
+				{"task-a", "vault:secret/team-b/db#password"},
+				{"task-a", "vault:secret/team-ab/db#password"},
+				{"task-a", "vault:secret/team-a/../db#password"},
+			} {
+				_, err := resolve(c.id, c.ref)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEndWith, errSecretPathNotAllowed.Error())
+			}
+		})
+		Convey("the settings of the built-in publishers are restricted alike", func() {
+			v, err := c.ResolveSecret("task-a", "vault:secret/team-a/db#password")
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, "a")
+			_, err = c.ResolveSecret("task-a", "vault:secret/team-b/db#password")
+			So(err, ShouldNotBeNil)
+			v, err = c.ResolveSecret("task-a", "plain")
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, "plain")
+		})
+		Convey("the tenant of a task is cleared when it is unsubscribed", func() {
+			c.SetTaskTenant("task-a", "")
+			_, err := resolve("task-a", "vault:secret/team-a/db#password")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestVaultToken(t *testing.T) {
+	Convey("Given a vault token in the environment", t, func() {
+		defer os.Setenv("VAULT_TOKEN", os.Getenv("VAULT_TOKEN"))
+		os.Setenv("VAULT_TOKEN", "env-token")
+
+		Convey("the token file takes precedence", func() {
+			f, err := ioutil.TempFile("", "vault-token")
+			So(err, ShouldBeNil)
+			defer os.Remove(f.Name())
+			f.WriteString("file-token\n")
+			f.Close()
+			So(vaultToken(&VaultConfig{TokenFile: f.Name()}), ShouldEqual, "file-token")
+		})
+		Convey("the token is kept when the token file cannot be read", func() {
+			So(vaultToken(&VaultConfig{TokenFile: "/nonexistent/vault-token"}), ShouldEqual, "env-token")
+		})
+	})
+}
@@ -0,0 +1,212 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/pkg/chrono"
+)
+
+var sharedCacheLogger = controlLogger.WithField("_block", "shared-cache")
+
+// sharedCache holds the metrics collected for all the tasks, so that the
+// tasks collecting the same metrics with the same config within the
+// expiration of their namespace share one call to the plugin, where the
+// cache of the plugin pools is held per task.  The expiration of a
+// namespace is the one of its most specific prefix in namespaces, where *
+// matches any element, else the default one; the metrics expiring after 0
+// are not shared.
+type sharedCache struct {
+	expiration time.Duration
+	namespaces map[string]time.Duration
+	// prefixes are the keys of namespaces, from the least to the most
+	// specific
+	prefixes branches
+
+	sync.Mutex
+	table map[string]*sharedCacheEntry
+}
+
+type sharedCacheEntry struct {
+	time    time.Time
+	ttl     time.Duration
+	metrics []core.Metric
+}
+
+func newSharedCache(cfg *Config) *sharedCache {
+	c := &sharedCache{
+		expiration: cfg.SharedCacheExpiration.Duration,
+		namespaces: map[string]time.Duration{},
+		table:      map[string]*sharedCacheEntry{},
+	}
+	for ns, d := range cfg.SharedCacheNamespaces {
+		c.namespaces[ns] = d.Duration
+		c.prefixes = append(c.prefixes, ns)
+	}
+	sort.Sort(c.prefixes)
+	return c
+}
+
+// enabled returns true when the metrics of some namespaces are shared
+func (c *sharedCache) enabled() bool {
+	if c.expiration > 0 {
+		return true
+	}
+	for _, d := range c.namespaces {
+		if d > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ttl returns how long the metrics of the namespace are shared
+func (c *sharedCache) ttl(ns core.Namespace) time.Duration {
+	for i := len(c.prefixes) - 1; i >= 0; i-- {
+		if matchesPrefix(ns, split(c.prefixes[i])) {
+			return c.namespaces[c.prefixes[i]]
+		}
+	}
+	return c.expiration
+}
+
+// check returns the requested metrics to collect and the metrics of the
+// other ones, taken from the cache
+func (c *sharedCache) check(mts []core.Metric) (metricsToCollect []core.Metric, fromCache []core.Metric) {
+	c.Lock()
+	defer c.Unlock()
+	now := chrono.Chrono.Now()
+	for _, mt := range mts {
+		key := sharedCacheKey(mt)
+		if e, ok := c.table[key]; ok && now.Sub(e.time) < e.ttl {
+			fromCache = append(fromCache, e.metrics...)
+			continue
+		}
+		metricsToCollect = append(metricsToCollect, mt)
+	}
+	if len(fromCache) > 0 {
+		sharedCacheLogger.WithFields(log.Fields{
+			"hits":   len(mts) - len(metricsToCollect),
+			"misses": len(metricsToCollect),
+		}).Debug("metrics taken from the shared cache")
+	}
+	return metricsToCollect, fromCache
+}
+
+// update caches the collected metrics under the requested metrics they
+// answer, i.e. whose namespace matches theirs where the dynamic elements
+// of the requested ones are *; the expired metrics are dropped
+func (c *sharedCache) update(requested []core.Metric, collected []core.Metric) {
+	c.Lock()
+	defer c.Unlock()
+	now := chrono.Chrono.Now()
+	for key, e := range c.table {
+		if now.Sub(e.time) >= e.ttl {
+			delete(c.table, key)
+		}
+	}
+	entries := map[string]*sharedCacheEntry{}
+	for _, m := range collected {
+		for _, mt := range requested {
+			if !answers(mt.Namespace(), m.Namespace()) {
+				continue
+			}
+			key := sharedCacheKey(mt)
+			e, ok := entries[key]
+			if !ok {
+				ttl := c.ttl(mt.Namespace())
+				if ttl <= 0 {
+					break
+				}
+				e = &sharedCacheEntry{time: now, ttl: ttl}
+				entries[key] = e
+			}
+			e.metrics = append(e.metrics, m)
+			break
+		}
+	}
+	for key, e := range entries {
+		c.table[key] = e
+	}
+}
+
+// matchesPrefix returns true when the namespace starts with the elements
+// of the prefix, where * matches any element
+func matchesPrefix(ns core.Namespace, prefix []string) bool {
+	if len(prefix) > len(ns) {
+		return false
+	}
+	for i, e := range prefix {
+		if e != "*" && e != ns[i].Value {
+			return false
+		}
+	}
+	return true
+}
+
+// answers returns true when the metric of the namespace answers the request
+// of the metric of the requested namespace
+func answers(requested, ns core.Namespace) bool {
+	if len(requested) != len(ns) {
+		return false
+	}
+	for i := range requested {
+		if requested[i].Value != "*" && requested[i].Value != ns[i].Value {
+			return false
+		}
+	}
+	return true
+}
+
+// sharedCacheKey identifies the requested metric by its namespace, version
+// and config, so that the tasks configuring a plugin differently do not
+// share its metrics
+func sharedCacheKey(mt core.Metric) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s:%d", mt.Namespace(), mt.Version())
+	b.WriteString(configKey(mt.Config()))
+	return b.String()
+}
+
+func configKey(cfg *cdata.ConfigDataNode) string {
+	if cfg == nil {
+		return ""
+	}
+	table := cfg.Table()
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&b, ":%s=%#v", k, table[k])
+	}
+	return b.String()
+}
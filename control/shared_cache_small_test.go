@@ -0,0 +1,88 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/vrischmann/jsonutil"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/chrono"
+)
+
+func TestSharedCache(t *testing.T) {
+	Convey("Given a shared cache", t, func() {
+		chrono.Chrono.Pause()
+		defer chrono.Chrono.Continue()
+		cfg := GetDefaultConfig()
+		cfg.SharedCacheExpiration = jsonutil.Duration{time.Second}
+		cfg.SharedCacheNamespaces = map[string]jsonutil.Duration{
+			"/intel/docker":         {5 * time.Second},
+			"/intel/docker/*/stats": {0},
+		}
+		c := newSharedCache(cfg)
+		config := cdata.NewNode()
+		config.AddItem("host", ctypes.ConfigValueStr{Value: "db1"})
+		load := &metricType{namespace: core.NewNamespace("intel", "load"), version: 1, config: config}
+		docker := &metricType{namespace: core.NewNamespace("intel", "docker", "*", "cpu"), version: 1, config: config}
+		collected := []core.Metric{
+			plugin.MetricType{Namespace_: core.NewNamespace("intel", "load"), Data_: 1},
+			plugin.MetricType{Namespace_: core.NewNamespace("intel", "docker", "a", "cpu"), Data_: 2},
+			plugin.MetricType{Namespace_: core.NewNamespace("intel", "docker", "b", "cpu"), Data_: 3},
+		}
+		So(c.enabled(), ShouldBeTrue)
+
+		Convey("the metrics of every request are collected once", func() {
+			toCollect, fromCache := c.check([]core.Metric{load, docker})
+			So(toCollect, ShouldHaveLength, 2)
+			So(fromCache, ShouldBeEmpty)
+			c.update(toCollect, collected)
+			toCollect, fromCache = c.check([]core.Metric{load, docker})
+			So(toCollect, ShouldBeEmpty)
+			So(fromCache, ShouldResemble, collected)
+		})
+		Convey("the metrics expire after the expiration of their namespace", func() {
+			c.update([]core.Metric{load, docker}, collected)
+			chrono.Chrono.Forward(2 * time.Second)
+			toCollect, fromCache := c.check([]core.Metric{load, docker})
+			So(toCollect, ShouldResemble, []core.Metric{load})
+			So(fromCache, ShouldHaveLength, 2)
+			So(c.ttl(core.NewNamespace("intel", "docker", "a", "stats", "rx")), ShouldEqual, 0)
+		})
+		Convey("the metrics requested with another config are not shared", func() {
+			c.update([]core.Metric{load}, collected)
+			other := cdata.NewNode()
+			other.AddItem("host", ctypes.ConfigValueStr{Value: "db2"})
+			toCollect, _ := c.check([]core.Metric{&metricType{namespace: core.NewNamespace("intel", "load"), version: 1, config: other}})
+			So(toCollect, ShouldHaveLength, 1)
+		})
+	})
+	Convey("The shared cache is disabled by default", t, func() {
+		So(newSharedCache(GetDefaultConfig()).enabled(), ShouldBeFalse)
+	})
+}
@@ -362,10 +362,11 @@ func (p *pool) SubscriptionCount() int {
 	return len(p.subs)
 }
 
-// SelectAP selects an available plugin from the pool
+// SelectAP selects an available plugin from the pool, skipping the unhealthy
+// ones unless all of them are
 // the method is not thread safe, it should be protected outside of the body
 func (p *pool) SelectAP(taskID string, config map[string]ctypes.ConfigValue) (AvailablePlugin, serror.SnapError) {
-	aps := p.plugins.Values()
+	aps := healthyPlugins(p.plugins.Values())
 
 	var id string
 	switch p.Strategy().String() {
@@ -386,6 +387,28 @@ func (p *pool) SelectAP(taskID string, config map[string]ctypes.ConfigValue) (Av
 	return ap, nil
 }
 
+// checksHealth is implemented by the available plugins tracking whether
+// they answer their health checks
+type checksHealth interface {
+	IsHealthy() bool
+}
+
+// healthyPlugins returns the healthy available plugins, all of them when
+// none is healthy
+func healthyPlugins(aps []AvailablePlugin) []AvailablePlugin {
+	healthy := make([]AvailablePlugin, 0, len(aps))
+	for _, ap := range aps {
+		if hc, ok := ap.(checksHealth); ok && !hc.IsHealthy() {
+			continue
+		}
+		healthy = append(healthy, ap)
+	}
+	if len(healthy) == 0 {
+		return aps
+	}
+	return healthy
+}
+
 func idFromCfg(cfg map[string]ctypes.ConfigValue) string {
 	//TODO: check for nil map
 	var buff bytes.Buffer
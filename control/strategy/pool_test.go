@@ -229,6 +229,36 @@ func TestPoolSelectAPDefaultRouter(t *testing.T) {
 	})
 }
 
+// unhealthyPlugin is an available plugin which failed its health checks
+type unhealthyPlugin struct {
+	*MockAvailablePlugin
+}
+
+func (unhealthyPlugin) IsHealthy() bool {
+	return false
+}
+
+func TestPoolSelectAPSkipsUnhealthy(t *testing.T) {
+	Convey("Given a pool with an unhealthy plugin instance", t, func() {
+		sick := unhealthyPlugin{NewMockAvailablePlugin().WithID(1).WithLastHit(time.Now().Add(-time.Hour))}
+		healthy := NewMockAvailablePlugin().WithID(2).WithLastHit(time.Now())
+		pool, err := NewPool(healthy.String(), sick, healthy)
+		So(err, ShouldBeNil)
+
+		Convey("Then the healthy instance is selected", func() {
+			ap, err := pool.SelectAP("TaskID", nil)
+			So(err, ShouldBeNil)
+			So(ap, ShouldEqual, healthy)
+		})
+		Convey("Then an unhealthy instance is still selected when all of them are", func() {
+			pool.Kill(2, "test")
+			ap, err := pool.SelectAP("TaskID", nil)
+			So(err, ShouldBeNil)
+			So(ap, ShouldResemble, sick)
+		})
+	})
+}
+
 func TestPoolSelectAPConfigRouter(t *testing.T) {
 	Convey("Given task id and configuration", t, func() {
 		cfg := map[string]ctypes.ConfigValue{"foo": ctypes.ConfigValueStr{"bar"}}
@@ -25,9 +25,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sort"
 	"sync"
 
 	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/control_event"
@@ -62,6 +64,7 @@ type ManagesSubscriptionGroups interface {
 		configTree *cdata.ConfigDataTree, asserts ...core.SubscribedPluginAssert) (serrs []serror.SnapError)
 	validateMetric(metric core.Metric) (serrs []serror.SnapError)
 	validatePluginUnloading(*loadedPlugin) (errs []serror.SnapError)
+	subscribedTasks(*loadedPlugin) []string
 }
 
 type subscriptionGroup struct {
@@ -134,6 +137,10 @@ func (s subscriptionGroups) add(id string, requested []core.RequestedMetric,
 
 	errs := subscriptionGroup.process(id)
 	if errs != nil {
+		// the group is not kept, so the plugins and the metrics it subscribed
+		// to before failing are released
+		subscriptionGroup.unsubscribePlugins(id, subscriptionGroup.plugins)
+		subscriptionGroup.subscribeMetrics(nil)
 		return errs
 	}
 	s.subscriptionMap[id] = subscriptionGroup
@@ -153,6 +160,7 @@ func (s subscriptionGroups) remove(id string) []serror.SnapError {
 		return []serror.SnapError{serror.New(ErrSubscriptionGroupDoesNotExist)}
 	}
 	serrs := subscriptionGroup.unsubscribePlugins(id, s.subscriptionMap[id].plugins)
+	subscriptionGroup.subscribeMetrics(nil)
 	delete(s.subscriptionMap, id)
 	return serrs
 }
@@ -191,13 +199,33 @@ func (s subscriptionGroups) get(id string) (map[string]metricTypes, []serror.Sna
 // (subscriptionGroup.metrics) for all subscription groups are updated based
 // on the requested metrics (subscriptionGroup.requestedMetrics).  Similarly
 // the required plugins (subscriptionGroup.plugins) are also updated.
+//
+// A control_event.TaskMetricsResolvedEvent is emitted for the groups whose
+// resulting metrics changed, e.g. when a requested metric with a wildcard
+// matches the metrics of a newly loaded collector.
 func (s *subscriptionGroups) Process() (errs []serror.SnapError) {
 	s.Lock()
 	defer s.Unlock()
 	for id, group := range s.subscriptionMap {
+		previous := metricsByKey(group.metrics)
 		if serrs := group.process(id); serrs != nil {
 			errs = append(errs, serrs...)
 		}
+		added, removed := diffMetrics(previous, metricsByKey(group.metrics))
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+		controlLogger.WithFields(log.Fields{
+			"_block":          "subscriptionGroups.Process",
+			"task-id":         id,
+			"added-metrics":   len(added),
+			"removed-metrics": len(removed),
+		}).Info("metrics of the task resolved again")
+		s.eventManager.Emit(&control_event.TaskMetricsResolvedEvent{
+			TaskId:  id,
+			Added:   added,
+			Removed: removed,
+		})
 	}
 	return errs
 }
@@ -268,6 +296,20 @@ func (s *subscriptionGroups) validatePluginUnloading(pluginToUnload *loadedPlugi
 	return errs
 }
 
+// subscribedTasks returns the ids of the tasks subscribed to the given plugin
+func (s *subscriptionGroups) subscribedTasks(plg *loadedPlugin) []string {
+	s.Lock()
+	defer s.Unlock()
+	ids := []string{}
+	for id, group := range s.subscriptionMap {
+		if group.pluginIsSubscribed(plg) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 func (p *subscriptionGroups) validatePluginSubscription(pl core.SubscribedPlugin, mergedConfig *cdata.ConfigDataNode) []serror.SnapError {
 	var serrs = []serror.SnapError{}
 	controlLogger.WithFields(log.Fields{
@@ -279,15 +321,19 @@ func (p *subscriptionGroups) validatePluginSubscription(pl core.SubscribedPlugin
 		serrs = append(serrs, pluginNotFoundError(pl))
 		return serrs
 	}
+	if p.IsPluginFrozen(lp.Key()) {
+		serrs = append(serrs, pluginFrozenError(lp))
+		return serrs
+	}
 
 	if lp.ConfigPolicy != nil {
 		ncd := lp.ConfigPolicy.Get([]string{""})
 		_, errs := ncd.Process(mergedConfig.Table())
 		if errs != nil && errs.HasErrors() {
 			for _, e := range errs.Errors() {
-				se := serror.New(e)
-				se.SetFields(map[string]interface{}{"name": pl.Name(), "version": pl.Version()})
-				serrs = append(serrs, se)
+				serrs = append(serrs, configPolicyError(e,
+					fmt.Sprintf("plugin %s:%s:%d", pl.TypeName(), pl.Name(), pl.Version()),
+					map[string]interface{}{"name": pl.Name(), "version": pl.Version()}))
 			}
 		}
 	}
@@ -349,7 +395,13 @@ func (s *subscriptionGroups) validateMetric(
 			ncdTable, errs := m.policy.Process(m.Config().Table())
 			if errs != nil && errs.HasErrors() {
 				for _, e := range errs.Errors() {
-					serrs = append(serrs, serror.New(e))
+					serrs = append(serrs, configPolicyError(e,
+						fmt.Sprintf("metric %s", m.Namespace()),
+						map[string]interface{}{
+							"metric":  m.Namespace().String(),
+							"version": m.Version(),
+							"plugin":  m.Plugin.Name(),
+						}))
 				}
 				continue
 			}
@@ -360,6 +412,17 @@ func (s *subscriptionGroups) validateMetric(
 	return serrs
 }
 
+// configPolicyError returns the error of a config item breaking the config
+// policy of a plugin or of a metric, telling which one and which config item.
+func configPolicyError(e error, of string, fields map[string]interface{}) serror.SnapError {
+	if re, ok := e.(*cpolicy.RuleError); ok {
+		fields["key"] = re.Key
+		fields["rule"] = re.Type
+		e = fmt.Errorf("invalid config of %s: %v", of, re.Err)
+	}
+	return serror.New(e, fields)
+}
+
 // pluginIsSubscribed returns true if a provided plugin has been found among subscribed plugins
 // in the following subscription group
 func (s *subscriptionGroup) pluginIsSubscribed(plugin *loadedPlugin) bool {
@@ -460,6 +523,13 @@ func (s *subscriptionGroup) process(id string) (serrs []serror.SnapError) {
 			plugins = append(plugins, s)
 		}
 	}
+	// checks the references to secrets in the config of the metrics and of
+	// the plugins before subscribing to anything, the task keeping its
+	// previous subscriptions when a secret cannot be read
+	if errs := append(s.resolveMetricSecrets(id, pluginToMetricMap), s.resolvePluginSecrets(id, plugins)...); len(errs) > 0 {
+		return append(serrs, errs...)
+	}
+
 	// calculates those plugins that need to be subscribed and unsubscribed to
 	subs, unsubs := comparePlugins(plugins, s.plugins)
 	controlLogger.WithFields(log.Fields{
@@ -467,8 +537,13 @@ func (s *subscriptionGroup) process(id string) (serrs []serror.SnapError) {
 		"unsubs": fmt.Sprintf("%+v", unsubs),
 	}).Debug("subscriptions")
 	if len(subs) > 0 {
-		if errs := s.subscribePlugins(id, subs); errs != nil {
+		subscribed, errs := s.subscribePlugins(id, subs)
+		if errs != nil {
 			serrs = append(serrs, errs...)
+			// the group only holds the plugins it could subscribe to, the
+			// other ones are subscribed to the next time it is processed
+			_, failed := comparePlugins(subscribed, subs)
+			plugins, _ = comparePlugins(plugins, failed)
 		}
 	}
 	if len(unsubs) > 0 {
@@ -477,6 +552,8 @@ func (s *subscriptionGroup) process(id string) (serrs []serror.SnapError) {
 		}
 	}
 
+	s.subscribeMetrics(pluginToMetricMap)
+
 	// updating view
 	// metrics are grouped by plugin
 	s.metrics = pluginToMetricMap
@@ -486,8 +563,11 @@ func (s *subscriptionGroup) process(id string) (serrs []serror.SnapError) {
 	return serrs
 }
 
+// subscribePlugins subscribes the task to the plugins and starts them as
+// needed.  It returns the plugins subscribed to, fewer than the given ones
+// when a plugin cannot be subscribed to or started.
 func (s *subscriptionGroup) subscribePlugins(id string,
-	plugins []core.SubscribedPlugin) (serrs []serror.SnapError) {
+	plugins []core.SubscribedPlugin) (subscribed []core.SubscribedPlugin, serrs []serror.SnapError) {
 	plgs := make([]*loadedPlugin, len(plugins))
 	// First range through plugins to verify if all required plugins
 	// are available
@@ -495,14 +575,18 @@ func (s *subscriptionGroup) subscribePlugins(id string,
 		plg, err := s.pluginManager.get(key(sub))
 		if err != nil {
 			serrs = append(serrs, pluginNotFoundError(sub))
-			return serrs
+			return subscribed, serrs
+		}
+		if s.IsPluginFrozen(plg.Key()) {
+			serrs = append(serrs, pluginFrozenError(plg))
+			return subscribed, serrs
 		}
 		plgs[i] = plg
 	}
 
 	// If all plugins are available, subscribe to pools and start
 	// plugins as needed
-	for _, plg := range plgs {
+	for i, plg := range plgs {
 		controlLogger.WithFields(log.Fields{
 			"name":    plg.Name(),
 			"type":    plg.TypeName(),
@@ -514,54 +598,56 @@ func (s *subscriptionGroup) subscribePlugins(id string,
 			pool, err := s.pluginRunner.AvailablePlugins().getOrCreatePool(plg.Key())
 			if err != nil {
 				serrs = append(serrs, serror.New(err))
-				return serrs
+				return subscribed, serrs
 			}
 			if pool.Count() < 1 {
 				var resp plugin.Response
 				res, err := http.Get(plg.Details.Uri.String())
 				if err != nil {
 					serrs = append(serrs, serror.New(err))
-					return serrs
+					return subscribed, serrs
 				}
 				body, err := ioutil.ReadAll(res.Body)
 				if err != nil {
 					serrs = append(serrs, serror.New(err))
-					return serrs
+					return subscribed, serrs
 				}
 				err = json.Unmarshal(body, &resp)
 				if err != nil {
 					serrs = append(serrs, serror.New(err))
-					return serrs
+					return subscribed, serrs
 				}
 				ap, err := newAvailablePlugin(resp, s.eventManager, nil, s.grpcSecurity)
 				if err != nil {
 					serrs = append(serrs, serror.New(err))
-					return serrs
+					return subscribed, serrs
 				}
 				ap.SetIsRemote(true)
 				err = pool.Insert(ap)
 				if err != nil {
 					serrs = append(serrs, serror.New(err))
-					return serrs
+					return subscribed, serrs
 				}
 			}
+			subscribed = append(subscribed, plugins[i])
 		} else {
 			pool, err := s.pluginRunner.AvailablePlugins().getOrCreatePool(plg.Key())
 			if err != nil {
 				serrs = append(serrs, serror.New(err))
-				return serrs
+				return subscribed, serrs
 			}
 			pool.Subscribe(id)
+			subscribed = append(subscribed, plugins[i])
 			if pool.Eligible() {
 				err = s.verifyPlugin(plg)
 				if err != nil {
 					serrs = append(serrs, serror.New(err))
-					return serrs
+					return subscribed, serrs
 				}
 				err = s.pluginRunner.runPlugin(plg.Name(), plg.Details)
 				if err != nil {
 					serrs = append(serrs, serror.New(err))
-					return serrs
+					return subscribed, serrs
 				}
 			}
 		}
@@ -569,10 +655,50 @@ func (s *subscriptionGroup) subscribePlugins(id string,
 		serr := s.sendPluginSubscriptionEvent(id, plg)
 		if serr != nil {
 			serrs = append(serrs, serr)
-			return serrs
+			return subscribed, serrs
 		}
 	}
-	return serrs
+	return subscribed, serrs
+}
+
+// subscribeMetrics counts the subscription of the group to the metrics of
+// the catalog: it subscribes to the given metrics the group did not have
+// and unsubscribes from the ones it does not have anymore
+func (s *subscriptionGroup) subscribeMetrics(metrics map[string]metricTypes) {
+	previous, current := metricsByKey(s.metrics), metricsByKey(metrics)
+	for k, mt := range current {
+		if _, ok := previous[k]; ok {
+			continue
+		}
+		if err := s.metricCatalog.Subscribe(mt.Namespace().Strings(), mt.Version()); err != nil {
+			controlLogger.WithFields(log.Fields{
+				"_block": "subscriptionGroup.subscribeMetrics",
+				"metric": k,
+			}).Debug(err)
+		}
+	}
+	for k, mt := range previous {
+		if _, ok := current[k]; ok {
+			continue
+		}
+		// the metrics of an unloaded plugin are not in the catalog anymore
+		if err := s.metricCatalog.Unsubscribe(mt.Namespace().Strings(), mt.Version()); err != nil {
+			controlLogger.WithFields(log.Fields{
+				"_block": "subscriptionGroup.unsubscribeMetrics",
+				"metric": k,
+			}).Debug(err)
+		}
+	}
+}
+
+func metricsByKey(metrics map[string]metricTypes) map[string]core.Metric {
+	mts := map[string]core.Metric{}
+	for _, pmt := range metrics {
+		for _, mt := range pmt.Metrics() {
+			mts[fmt.Sprintf("%s:%d", mt.Namespace(), mt.Version())] = mt
+		}
+	}
+	return mts
 }
 
 func (p *subscriptionGroup) unsubscribePlugins(id string,
@@ -682,6 +808,16 @@ func pluginNotFoundError(pl core.SubscribedPlugin) serror.SnapError {
 	return se
 }
 
+func pluginFrozenError(lp *loadedPlugin) serror.SnapError {
+	se := serror.New(ErrPluginFrozen)
+	se.SetFields(map[string]interface{}{
+		"name":    lp.Name(),
+		"version": lp.Version(),
+		"type":    lp.TypeName(),
+	})
+	return se
+}
+
 func key(p core.SubscribedPlugin) string {
 	return fmt.Sprintf("%v"+core.Separator+"%v"+core.Separator+"%v", p.TypeName(), p.Name(), p.Version())
 }
@@ -655,6 +655,66 @@ func TestSubscriptionGroups_AddRemoveStatic(t *testing.T) {
 	})
 }
 
+type lstnToFrozenEvents struct {
+	frozen chan *control_event.PluginFrozenEvent
+}
+
+func (l *lstnToFrozenEvents) HandleGomitEvent(e gomit.Event) {
+	if v, ok := e.Body.(*control_event.PluginFrozenEvent); ok {
+		l.frozen <- v
+	}
+}
+
+func TestFreezePlugin(t *testing.T) {
+	c := New(getTestSGConfig())
+
+	lpe := newLstnToPluginEvents()
+	c.eventManager.RegisterHandler("TestFreezePlugin", lpe)
+	lfe := &lstnToFrozenEvents{frozen: make(chan *control_event.PluginFrozenEvent, 1)}
+	c.eventManager.RegisterHandler("TestFreezePlugin_Frozen", lfe)
+	c.Start()
+
+	Convey("Loading a mock collector plugin", t, func() {
+		_, err := loadPlg(c, helper.PluginFilePath("snap-plugin-collector-mock1"))
+		So(err, ShouldBeNil)
+		<-lpe.load
+
+		requested := []core.RequestedMetric{mockRequestedMetric{namespace: core.NewNamespace("intel", "mock", "foo")}}
+		serrs := c.subscriptionGroups.Add("task-id", requested, cdata.NewTree(), []core.SubscribedPlugin{})
+		So(serrs, ShouldBeEmpty)
+		<-lpe.sub
+
+		Convey("Freezing the plugin reports the impacted tasks", func() {
+			serr := c.FreezePlugin("mock", 1)
+			So(serr, ShouldBeNil)
+			ev := <-lfe.frozen
+			So(ev.ImpactedTasks, ShouldResemble, []string{"task-id"})
+			So(c.IsPluginFrozen(fmt.Sprintf("%s"+core.Separator+"mock"+core.Separator+"1", core.CollectorPluginType)), ShouldBeTrue)
+
+			Convey("New subscriptions to the frozen plugin are rejected", func() {
+				serrs := c.ValidateDeps(requested, []core.SubscribedPlugin{}, cdata.NewTree())
+				So(serrs, ShouldNotBeEmpty)
+				So(serrs[0].Error(), ShouldEqual, ErrPluginFrozen.Error())
+				serrs = c.subscriptionGroups.Add("task-id-2", requested, cdata.NewTree(), []core.SubscribedPlugin{})
+				So(serrs, ShouldNotBeEmpty)
+				So(serrs[0].Error(), ShouldEqual, ErrPluginFrozen.Error())
+			})
+			Convey("Thawing the plugin accepts new subscriptions again", func() {
+				serr := c.ThawPlugin("mock", 1)
+				So(serr, ShouldBeNil)
+				serrs := c.subscriptionGroups.Add("task-id-2", requested, cdata.NewTree(), []core.SubscribedPlugin{})
+				So(serrs, ShouldBeEmpty)
+				<-lpe.sub
+			})
+		})
+		Convey("Freezing an unknown plugin returns an error", func() {
+			serr := c.FreezePlugin("unknown", 1)
+			So(serr, ShouldNotBeNil)
+			So(serr.Error(), ShouldEqual, ErrPluginNotFound.Error())
+		})
+	})
+}
+
 func TestSubscriptionGroups_AddRemoveDynamic(t *testing.T) {
 	c := New(getTestSGConfig())
 
@@ -0,0 +1,119 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+func TestSubscribeMetrics(t *testing.T) {
+	Convey("Given a subscription group", t, func() {
+		c := &pluginControl{metricCatalog: newMetricCatalog()}
+		foo := &metricType{namespace: core.NewNamespace("intel", "foo"), version: 1}
+		bar := &metricType{namespace: core.NewNamespace("intel", "bar"), version: 1}
+		c.metricCatalog.Add(foo)
+		c.metricCatalog.Add(bar)
+		// the catalog holds the metric types added to it
+		group := &subscriptionGroup{pluginControl: c}
+
+		Convey("the metrics it resolves are subscribed once", func() {
+			metrics := map[string]metricTypes{"collector:mock:1": {metricTypes: []core.Metric{foo, bar}}}
+			group.subscribeMetrics(metrics)
+			group.metrics = metrics
+			group.subscribeMetrics(metrics)
+			So(foo.SubscriptionCount(), ShouldEqual, 1)
+			So(bar.SubscriptionCount(), ShouldEqual, 1)
+
+			Convey("and unsubscribed when they are not resolved anymore", func() {
+				metrics = map[string]metricTypes{"collector:mock:1": {metricTypes: []core.Metric{foo}}}
+				group.subscribeMetrics(metrics)
+				group.metrics = metrics
+				So(foo.SubscriptionCount(), ShouldEqual, 1)
+				So(bar.SubscriptionCount(), ShouldEqual, 0)
+
+				Convey("or when the group is removed", func() {
+					group.subscribeMetrics(nil)
+					So(foo.SubscriptionCount(), ShouldEqual, 0)
+				})
+			})
+		})
+	})
+}
+
+func TestSubscriptionGroupsAddFailure(t *testing.T) {
+	Convey("Given a task requesting a metric which is not in the catalog", t, func() {
+		c := New(GetDefaultConfig())
+		lp := &loadedPlugin{
+			Meta:         plugin.PluginMeta{Name: "mock", Version: 1},
+			Type:         plugin.CollectorPluginType,
+			ConfigPolicy: cpolicy.New(),
+			Details:      &pluginDetails{},
+		}
+		So(c.pluginManager.(*pluginManager).loadedPlugins.add(lp), ShouldBeNil)
+		foo := &metricType{namespace: core.NewNamespace("intel", "mock", "foo"), version: 1}
+		So(c.metricCatalog.AddLoadedMetricType(lp, foo), ShouldBeNil)
+		missing := &metricType{namespace: core.NewNamespace("intel", "mock", "missing"), version: 1}
+
+		Convey("the subscription group is not added and releases its subscriptions", func() {
+			errs := c.subscriptionGroups.Add("task", []core.RequestedMetric{foo, missing}, cdata.NewTree(), nil)
+			So(errs, ShouldNotBeEmpty)
+			_, _, err := c.subscriptionGroups.Get("task")
+			So(err, ShouldEqual, ErrSubscriptionGroupDoesNotExist)
+			So(foo.SubscriptionCount(), ShouldEqual, 0)
+			pool, err := c.pluginRunner.AvailablePlugins().getPool(lp.Key())
+			So(err, ShouldBeNil)
+			So(pool.SubscriptionCount(), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestConfigPolicyError(t *testing.T) {
+	Convey("Given a config breaking the config policy of a metric", t, func() {
+		node := cpolicy.NewPolicyNode()
+		rule, _ := cpolicy.NewStringRule("proto", true)
+		rule.SetAllowed("tcp", "udp")
+		node.Add(rule)
+		_, errs := node.Process(map[string]ctypes.ConfigValue{"proto": ctypes.ConfigValueStr{Value: "http"}})
+		So(errs.Errors(), ShouldHaveLength, 1)
+
+		Convey("the error tells the metric and the config item", func() {
+			se := configPolicyError(errs.Errors()[0], "metric /intel/foo", map[string]interface{}{"metric": "/intel/foo"})
+			So(se.Error(), ShouldEqual, "invalid config of metric /intel/foo: value is not allowed (proto value http not in [tcp udp])")
+			So(se.Fields()["key"], ShouldEqual, "proto")
+			So(se.Fields()["rule"], ShouldEqual, cpolicy.StringType)
+			So(se.Fields()["metric"], ShouldEqual, "/intel/foo")
+		})
+		Convey("the other errors are kept", func() {
+			se := configPolicyError(errors.New("boom"), "metric /intel/foo", map[string]interface{}{})
+			So(se.Error(), ShouldEqual, "boom")
+		})
+	})
+}
@@ -0,0 +1,129 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/strategy"
+	"github.com/intelsdi-x/snap/core/control_event"
+)
+
+var (
+	// PluginRestartBackoff is the delay before the second restart of a dead
+	// plugin; the first restart is immediate and the delay doubles with
+	// every following restart.
+	PluginRestartBackoff = time.Second
+	// MaxPluginRestartBackoff is the longest delay between two restarts of a
+	// dead plugin.
+	MaxPluginRestartBackoff = time.Minute
+)
+
+// restartBackoff returns how long to wait before restarting a plugin which
+// was already restarted the given number of times.
+func restartBackoff(restarts int) time.Duration {
+	if restarts <= 0 {
+		return 0
+	}
+	delay := PluginRestartBackoff
+	for i := 1; i < restarts && delay < MaxPluginRestartBackoff; i++ {
+		delay *= 2
+	}
+	if delay > MaxPluginRestartBackoff {
+		delay = MaxPluginRestartBackoff
+	}
+	return delay
+}
+
+// handleDeadPlugin kills an available plugin which failed its health checks
+// and restarts it with an exponential backoff until the restart limit of its
+// pool is reached.
+func (r *runner) handleDeadPlugin(v *control_event.DeadAvailablePluginEvent) {
+	logger := runnerLog.WithFields(log.Fields{
+		"_block":  "handle-dead-plugin",
+		"aplugin": v.String,
+	})
+	logger.Warning("handling dead available plugin event")
+
+	pool, err := r.availablePlugins.getPool(v.Key)
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	if pool == nil {
+		return
+	}
+	pool.Kill(v.Id, "plugin dead")
+
+	if !pool.Eligible() {
+		return
+	}
+	if pool.RestartCount() >= MaxPluginRestartCount && MaxPluginRestartCount != -1 {
+		logger.Warning("plugin disabled due to exceeding restart limit: ", MaxPluginRestartCount)
+		r.emitter.Emit(&control_event.MaxPluginRestartsExceededEvent{
+			Id:      v.Id,
+			Name:    v.Name,
+			Version: v.Version,
+			Key:     v.Key,
+			Type:    v.Type,
+		})
+		return
+	}
+
+	delay := restartBackoff(pool.RestartCount())
+	pool.IncRestartCount()
+	if delay == 0 {
+		r.restartDeadPlugin(v, pool)
+		return
+	}
+	logger.WithField("backoff", delay.String()).Warning("plugin restart scheduled")
+	quit := r.quit
+	go func() {
+		select {
+		case <-time.After(delay):
+			r.restartDeadPlugin(v, pool)
+		case <-quit:
+		}
+	}()
+}
+
+// restartDeadPlugin starts a new instance of a dead plugin and emits a
+// RestartedAvailablePluginEvent once it runs.
+func (r *runner) restartDeadPlugin(v *control_event.DeadAvailablePluginEvent, pool strategy.Pool) {
+	logger := runnerLog.WithFields(log.Fields{
+		"_block":  "restart-dead-plugin",
+		"aplugin": v.String,
+	})
+	if err := r.restartPlugin(v.Key); err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	logger.WithField("restart-count", pool.RestartCount()).Warning("plugin restarted")
+
+	r.emitter.Emit(&control_event.RestartedAvailablePluginEvent{
+		Id:      v.Id,
+		Name:    v.Name,
+		Version: v.Version,
+		Key:     v.Key,
+		Type:    v.Type,
+	})
+}
@@ -0,0 +1,57 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"testing"
+
+	"github.com/intelsdi-x/gomit"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRestartBackoff(t *testing.T) {
+	Convey("Restarting a dead plugin", t, func() {
+		Convey("the first restart is immediate", func() {
+			So(restartBackoff(0), ShouldEqual, 0)
+		})
+		Convey("the delay doubles with every restart", func() {
+			So(restartBackoff(1), ShouldEqual, PluginRestartBackoff)
+			So(restartBackoff(2), ShouldEqual, 2*PluginRestartBackoff)
+			So(restartBackoff(3), ShouldEqual, 4*PluginRestartBackoff)
+		})
+		Convey("the delay is capped", func() {
+			So(restartBackoff(100), ShouldEqual, MaxPluginRestartBackoff)
+		})
+	})
+	Convey("An available plugin", t, func() {
+		ap := &availablePlugin{emitter: gomit.NewEventController()}
+		Convey("is healthy until it reaches the health check failure limit", func() {
+			So(ap.IsHealthy(), ShouldBeTrue)
+			for i := 1; i < DefaultHealthCheckFailureLimit; i++ {
+				ap.healthCheckFailed()
+				So(ap.IsHealthy(), ShouldBeTrue)
+			}
+			ap.healthCheckFailed()
+			So(ap.IsHealthy(), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,124 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
+)
+
+var (
+	// ErrNoPluginToUpgrade - The error message for an upgrade to a plugin which is not loaded
+	ErrNoPluginToUpgrade = errors.New("No loaded plugin with the same type and name to upgrade")
+	// ErrUpgradeNotNewer - The error message for an upgrade to a version which is not newer than the loaded ones
+	ErrUpgradeNotNewer = errors.New("Upgraded plugin version must be newer than the loaded versions")
+)
+
+var (
+	// upgradeDrainTimeout is how long the previous versions of an upgraded
+	// plugin are given to lose their subscriptions before they are kept
+	// loaded for good
+	upgradeDrainTimeout = 5 * time.Minute
+	// upgradeDrainInterval is how often the subscriptions of the previous
+	// versions of an upgraded plugin are checked
+	upgradeDrainInterval = time.Second
+)
+
+// UpgradePlugin loads a new version of a plugin which is already loaded.  The
+// subscriptions of the running tasks which do not pin the version of the
+// plugin move to the new version as it is loaded, without the tasks being
+// stopped.  Every previous version is unloaded in the background once no task
+// is subscribed to it anymore; a version still used after the drain timeout
+// (e.g. requested explicitly by a task) stays loaded.
+func (p *pluginControl) UpgradePlugin(rp *core.RequestedPlugin) (core.CatalogedPlugin, serror.SnapError) {
+	f := map[string]interface{}{
+		"_block": "upgrade-plugin",
+	}
+	cp, serr := p.Load(rp)
+	if serr != nil {
+		return nil, serr
+	}
+	f["plugin-type"] = cp.TypeName()
+	f["plugin-name"] = cp.Name()
+	f["plugin-version"] = cp.Version()
+
+	var previous []*loadedPlugin
+	newer := true
+	for _, lp := range p.pluginManager.all() {
+		if lp.TypeName() != cp.TypeName() || lp.Name() != cp.Name() || lp.Version() == cp.Version() {
+			continue
+		}
+		if lp.Version() > cp.Version() {
+			newer = false
+		}
+		previous = append(previous, lp)
+	}
+	if len(previous) == 0 || !newer {
+		se := serror.New(ErrNoPluginToUpgrade, f)
+		if !newer {
+			se = serror.New(ErrUpgradeNotNewer, f)
+		}
+		// roll back the load
+		if _, err := p.Unload(cp); err != nil {
+			se.SetFields(map[string]interface{}{"rollback-unload-error": err.Error()})
+		}
+		controlLogger.WithFields(f).Error(se)
+		return nil, se
+	}
+
+	for _, lp := range previous {
+		go p.drainAndUnload(lp)
+	}
+	controlLogger.WithFields(f).Info("plugin upgraded")
+	return cp, nil
+}
+
+// drainAndUnload unloads the given plugin as soon as no task is subscribed
+// to it.
+func (p *pluginControl) drainAndUnload(lp *loadedPlugin) {
+	logger := controlLogger.WithFields(log.Fields{
+		"_block":         "drain-and-unload",
+		"plugin-type":    lp.TypeName(),
+		"plugin-name":    lp.Name(),
+		"plugin-version": lp.Version(),
+	})
+	deadline := time.Now().Add(upgradeDrainTimeout)
+	for {
+		tasks := p.subscriptionGroups.subscribedTasks(lp)
+		if len(tasks) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			logger.WithField("subscribed-tasks", tasks).Warn("previous plugin version is still used, keeping it loaded")
+			return
+		}
+		time.Sleep(upgradeDrainInterval)
+	}
+	if _, err := p.Unload(lp); err != nil {
+		logger.WithField("error", err.Error()).Error("unable to unload previous plugin version")
+		return
+	}
+	logger.Info("previous plugin version unloaded")
+}
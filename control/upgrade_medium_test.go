@@ -0,0 +1,97 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/plugin/helper"
+)
+
+func TestUpgradePlugin(t *testing.T) {
+	upgradeDrainInterval = 10 * time.Millisecond
+	Convey("Given a task subscribed to the latest version of collector mock", t, func() {
+		c := New(getTestSGConfig())
+		c.Start()
+		defer c.Stop()
+		_, err := loadPlg(c, helper.PluginFilePath("snap-plugin-collector-mock1"))
+		So(err, ShouldBeNil)
+
+		requested := mockRequestedMetric{namespace: core.NewNamespace("intel", "mock", "foo"), version: -1}
+		serrs := c.SubscribeDeps("task-id", []core.RequestedMetric{requested}, nil, cdata.NewTree())
+		So(serrs, ShouldBeEmpty)
+
+		Convey("upgrading the plugin moves the task to the new version", func() {
+			rp, rerr := core.NewRequestedPlugin(helper.PluginFilePath("snap-plugin-collector-mock2"), c.GetTempDir(), nil)
+			So(rerr, ShouldBeNil)
+			cp, err := c.UpgradePlugin(rp)
+			So(err, ShouldBeNil)
+			So(cp.Version(), ShouldEqual, 2)
+
+			mts, _, gerr := c.subscriptionGroups.Get("task-id")
+			So(gerr, ShouldBeNil)
+			So(mts, ShouldContainKey, "collector"+core.Separator+"mock"+core.Separator+"2")
+
+			Convey("and unloads the previous version once drained", func() {
+				So(waitFor(func() bool { return len(c.PluginCatalog()) == 1 }), ShouldBeTrue)
+				So(c.PluginCatalog()[0].Version(), ShouldEqual, 2)
+			})
+		})
+		Convey("upgrading to an older version is refused", func() {
+			rp, rerr := core.NewRequestedPlugin(helper.PluginFilePath("snap-plugin-collector-mock2"), c.GetTempDir(), nil)
+			So(rerr, ShouldBeNil)
+			_, err := c.Load(rp)
+			So(err, ShouldBeNil)
+			rp, rerr = core.NewRequestedPlugin(helper.PluginFilePath("snap-plugin-collector-mock1"), c.GetTempDir(), nil)
+			So(rerr, ShouldBeNil)
+			// collector mock 1 is already loaded
+			_, err = c.UpgradePlugin(rp)
+			So(err, ShouldNotBeNil)
+		})
+	})
+	Convey("Upgrading a plugin which is not loaded", t, func() {
+		c := New(getTestSGConfig())
+		c.Start()
+		defer c.Stop()
+		rp, rerr := core.NewRequestedPlugin(helper.PluginFilePath("snap-plugin-collector-mock2"), c.GetTempDir(), nil)
+		So(rerr, ShouldBeNil)
+		_, err := c.UpgradePlugin(rp)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, ErrNoPluginToUpgrade.Error())
+		So(c.PluginCatalog(), ShouldBeEmpty)
+	})
+}
+
+func waitFor(cond func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+// The kinds of the findings of the task advisor
+const (
+	// AdvisoryDeprecatedMetric - A task collects a metric marked as deprecated
+	AdvisoryDeprecatedMetric = "deprecated-metric"
+	// AdvisoryNewerPlugin - A task uses a plugin version older than the latest loaded one
+	AdvisoryNewerPlugin = "newer-plugin"
+	// AdvisoryShortInterval - A task is scheduled more often than its runs allow
+	AdvisoryShortInterval = "short-interval"
+	// AdvisoryFailingPublisher - A publisher of a task keeps failing
+	AdvisoryFailingPublisher = "failing-publisher"
+)
+
+// The severities of the findings of the task advisor
+const (
+	AdvisorySeverityInfo    = "info"
+	AdvisorySeverityWarning = "warning"
+)
+
+// TaskAdvisory is an actionable finding of the task advisor about a task.
+//
+// swagger:model TaskAdvisory
+type TaskAdvisory struct {
+	TaskID   string `json:"task_id"`
+	TaskName string `json:"task_name"`
+	// Kind is one of deprecated-metric, newer-plugin, short-interval and
+	// failing-publisher
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"`
+	// Subject is what the finding is about: a metric namespace, a plugin
+	// (type:name:version) or a schedule interval
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+	// Suggestion is the replacement of the subject, if any: a metric
+	// namespace, a plugin version or an interval
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// AdvisorReport lists the findings of the task advisor over the running
+// tasks.
+//
+// swagger:model AdvisorReport
+type AdvisorReport struct {
+	// Timestamp is when the tasks were scanned (Unix time)
+	Timestamp    int64          `json:"timestamp"`
+	TasksScanned int            `json:"tasks_scanned"`
+	Findings     []TaskAdvisory `json:"findings"`
+}
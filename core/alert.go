@@ -0,0 +1,49 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+// The states of an alert
+const (
+	// AlertFiring - The condition of the alert holds for the series
+	AlertFiring = "firing"
+	// AlertResolved - The condition of the alert no longer holds for the series
+	AlertResolved = "resolved"
+)
+
+// Alert is the state of an alert of a task for one series of metrics (a
+// namespace and its tags).
+//
+// swagger:model Alert
+type Alert struct {
+	TaskID   string `json:"task_id"`
+	TaskName string `json:"task_name"`
+	// Name is the name of the alert node of the task workflow
+	Name      string            `json:"name"`
+	Condition string            `json:"condition"`
+	Namespace string            `json:"namespace"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	// State is firing or resolved
+	State string `json:"state"`
+	// Value is the value the condition was last evaluated against: the
+	// value of the metric, or the average over the window of the alert
+	Value float64 `json:"value"`
+	// Since is when the alert entered its state (Unix time)
+	Since int64 `json:"since"`
+}
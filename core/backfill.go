@@ -0,0 +1,63 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+// BackfillTimestampConfigKey is the config item the collector plugins read
+// the past time to collect the metrics at from, in RFC 3339 format, when a
+// task is backfilled.  The collectors declaring a rule for it in their config
+// policy support the backfill.
+const BackfillTimestampConfigKey = "backfill_timestamp"
+
+// The states of a backfill
+const (
+	BackfillRunning   = "running"
+	BackfillCompleted = "completed"
+	BackfillCanceled  = "canceled"
+)
+
+// Backfill runs the workflow of a task for a range of past timestamps, spaced
+// by the interval of its schedule, to repair gaps in the data it published.
+//
+// swagger:model Backfill
+type Backfill struct {
+	// Start and End bound the timestamps of the runs (Unix time)
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	// Speed is how many times faster than its schedule the task is run;
+	// zero runs it as fast as the workers allow
+	Speed float64 `json:"speed,omitempty"`
+}
+
+// BackfillStatus reports the progress of the backfill of a task.
+//
+// swagger:model BackfillStatus
+type BackfillStatus struct {
+	Backfill
+	// State is running, completed or canceled
+	State string `json:"state"`
+	// Runs is the number of runs done out of Total
+	Runs  int `json:"runs"`
+	Total int `json:"total"`
+	// Current is the timestamp of the last run (Unix time)
+	Current int64 `json:"current,omitempty"`
+	// FailedRuns is the number of runs whose collection failed
+	FailedRuns int    `json:"failed_runs"`
+	LastError  string `json:"last_error,omitempty"`
+}
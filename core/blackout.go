@@ -0,0 +1,65 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInvalidBlackout - The error message for a blackout period which does not end after it starts
+	ErrInvalidBlackout = errors.New("Blackout period must end after it starts")
+)
+
+// BlackoutPeriod is a period during which the fires of a task are
+// suppressed, e.g. a planned downtime of the systems the task publishes to.
+// The suppressed fires are counted apart from the missed ones.
+type BlackoutPeriod struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Covers returns true when t is within the period, its end excluded
+func (b BlackoutPeriod) Covers(t time.Time) bool {
+	return !t.Before(b.Start) && t.Before(b.End)
+}
+
+// ValidateBlackouts returns an error when one of the periods does not end
+// after it starts
+func ValidateBlackouts(periods []BlackoutPeriod) error {
+	for _, b := range periods {
+		if !b.End.After(b.Start) {
+			return ErrInvalidBlackout
+		}
+	}
+	return nil
+}
+
+// Blackout returns the period of the calendar covering t, nil when none does
+func Blackout(periods []BlackoutPeriod, t time.Time) *BlackoutPeriod {
+	for i := range periods {
+		if periods[i].Covers(t) {
+			return &periods[i]
+		}
+	}
+	return nil
+}
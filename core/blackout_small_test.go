@@ -0,0 +1,67 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBlackouts(t *testing.T) {
+	start := time.Date(2017, 12, 24, 0, 0, 0, 0, time.UTC)
+	periods := []BlackoutPeriod{
+		{Start: start, End: start.Add(48 * time.Hour), Reason: "holidays"},
+		{Start: start.Add(7 * 24 * time.Hour), End: start.Add(8 * 24 * time.Hour)},
+	}
+	Convey("A blackout period covers the times from its start to its end", t, func() {
+		So(periods[0].Covers(start), ShouldBeTrue)
+		So(periods[0].Covers(start.Add(time.Hour)), ShouldBeTrue)
+		So(periods[0].Covers(start.Add(48*time.Hour)), ShouldBeFalse)
+		So(periods[0].Covers(start.Add(-time.Second)), ShouldBeFalse)
+	})
+	Convey("The period of a calendar covering a time is found", t, func() {
+		So(Blackout(periods, start.Add(time.Hour)), ShouldEqual, &periods[0])
+		So(Blackout(periods, start.Add(7*24*time.Hour)), ShouldEqual, &periods[1])
+		So(Blackout(periods, start.Add(72*time.Hour)), ShouldBeNil)
+		So(Blackout(nil, start), ShouldBeNil)
+	})
+	Convey("A period which does not end after it starts is invalid", t, func() {
+		So(ValidateBlackouts(periods), ShouldBeNil)
+		So(ValidateBlackouts([]BlackoutPeriod{{Start: start, End: start}}), ShouldEqual, ErrInvalidBlackout)
+	})
+	Convey("The blackouts of a task manifest are parsed and validated", t, func() {
+		tr := &TaskCreationRequest{}
+		err := json.Unmarshal([]byte(`{"blackouts": [{"start": "2017-12-24T00:00:00Z", "end": "2017-12-26T00:00:00Z", "reason": "holidays"}]}`), tr)
+		So(err, ShouldBeNil)
+		So(tr.Blackouts, ShouldResemble, periods[:1])
+		opts, err := tr.options()
+		So(err, ShouldBeNil)
+		So(opts, ShouldNotBeEmpty)
+
+		tr.Blackouts[0].End = start.Add(-time.Hour)
+		_, err = tr.options()
+		So(err, ShouldEqual, ErrInvalidBlackout)
+	})
+}
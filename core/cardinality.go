@@ -0,0 +1,62 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+// NamespaceCardinality is the number of unique namespaces matching a
+// namespace pattern, whose dynamic elements are replaced by '*'.
+//
+// swagger:model NamespaceCardinality
+type NamespaceCardinality struct {
+	Namespace  string `json:"namespace"`
+	Namespaces int    `json:"namespaces"`
+}
+
+// TaskCardinality is the number of unique namespaces produced by the latest
+// run of a task, and by the namespace patterns with the most of them.
+//
+// swagger:model TaskCardinality
+type TaskCardinality struct {
+	TaskID   string `json:"task_id"`
+	TaskName string `json:"task_name"`
+	// Limit is the maximum of namespaces of the task, zero when unlimited,
+	// and Mode whether it is enforced or only warned about
+	Limit int    `json:"limit"`
+	Mode  string `json:"mode,omitempty"`
+	// Namespaces is the number of unique namespaces collected by the run,
+	// Dropped the number of them whose metrics were dropped by a limit
+	Namespaces int                    `json:"namespaces"`
+	Dropped    int                    `json:"dropped"`
+	Top        []NamespaceCardinality `json:"top"`
+}
+
+// CardinalityReport reports the unique metric namespaces produced by the
+// latest run of the tasks against the global and per-task limits.
+//
+// swagger:model CardinalityReport
+type CardinalityReport struct {
+	// Limit is the maximum of namespaces over all the tasks, zero when
+	// unlimited, and Mode whether it is enforced or only warned about
+	Limit int    `json:"limit"`
+	Mode  string `json:"mode,omitempty"`
+	// Namespaces is the number of unique namespaces over all the tasks
+	Namespaces int                    `json:"namespaces"`
+	Top        []NamespaceCardinality `json:"top"`
+	Tasks      []TaskCardinality      `json:"tasks"`
+}
@@ -0,0 +1,39 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import "time"
+
+// CatalogChange describes a change of the metric catalog, or of the metrics
+// resolved for a task after one when TaskID is set.  Added and Removed hold
+// the keys of the metrics, <namespace>:<version>.
+type CatalogChange struct {
+	// Reason is why the catalog changed: plugin-loaded, plugin-unloaded,
+	// plugins-swapped or metrics-advertised; it is task-metrics-resolved for
+	// the metrics resolved for a task
+	Reason string `json:"reason"`
+	// Plugin is the key of the plugin, type:name:version, whose metrics
+	// changed
+	Plugin    string    `json:"plugin,omitempty"`
+	TaskID    string    `json:"task_id,omitempty"`
+	Added     []string  `json:"added,omitempty"`
+	Removed   []string  `json:"removed,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
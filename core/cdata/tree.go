@@ -22,6 +22,7 @@ package cdata
 import (
 	"bytes"
 	"encoding/gob"
+	"io"
 
 	"github.com/intelsdi-x/snap/pkg/ctree"
 )
@@ -30,6 +31,8 @@ import (
 // at a specific namespace (merging the relevant hierarchy). Uses pkg.ConfigTree.
 type ConfigDataTree struct {
 	cTree *ctree.ConfigTree
+	// defaults apply to every namespace, below the config added to the tree
+	defaults *ConfigDataNode
 }
 
 // Returns a new ConfigDataTree.
@@ -45,13 +48,36 @@ func (c *ConfigDataTree) GobEncode() ([]byte, error) {
 	if err := encoder.Encode(c.cTree); err != nil {
 		return nil, err
 	}
+	if c.defaults != nil {
+		if err := encoder.Encode(c.defaults); err != nil {
+			return nil, err
+		}
+	}
 	return w.Bytes(), nil
 }
 
 func (c *ConfigDataTree) GobDecode(buf []byte) error {
 	r := bytes.NewBuffer(buf)
 	decoder := gob.NewDecoder(r)
-	return decoder.Decode(&c.cTree)
+	if err := decoder.Decode(&c.cTree); err != nil {
+		return err
+	}
+	// the defaults are only encoded when set
+	var defaults ConfigDataNode
+	switch err := decoder.Decode(&defaults); err {
+	case nil:
+		c.defaults = &defaults
+	case io.EOF:
+	default:
+		return err
+	}
+	return nil
+}
+
+// SetDefaults sets the config applying to every namespace of the tree,
+// overridden by the config added at the namespace and its parents.
+func (c *ConfigDataTree) SetDefaults(cdn *ConfigDataNode) {
+	c.defaults = cdn
 }
 
 // Adds a ConfigDataNode at the provided namespace.
@@ -60,16 +86,21 @@ func (c *ConfigDataTree) Add(ns []string, cdn *ConfigDataNode) {
 }
 
 // Returns a ConfigDataNode that is a merged version of the namespace provided.
+// The defaults of the tree, if any, are merged below it.
 func (c *ConfigDataTree) Get(ns []string) *ConfigDataNode {
-	n := c.cTree.Get(ns)
-	if n == nil {
-		return nil
-	}
-	switch t := n.(type) {
+	var cdn *ConfigDataNode
+	switch t := c.cTree.Get(ns).(type) {
+	case nil:
 	case ConfigDataNode:
-		return &t
+		cdn = &t
 	default:
-		return t.(*ConfigDataNode)
-
+		cdn = t.(*ConfigDataNode)
+	}
+	if c.defaults == nil {
+		return cdn
+	}
+	if cdn == nil {
+		cdn = NewNode()
 	}
+	return cdn.ReverseMerge(c.defaults)
 }
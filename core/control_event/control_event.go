@@ -34,7 +34,23 @@ const (
 	MetricSubscribed         = "Control.MetricSubscribed"
 	MetricUnsubscribed       = "Control.MetricUnsubscribed"
 	HealthCheckFailed        = "Control.PluginHealthCheckFailed"
+	HealthCheckRecovered     = "Control.PluginHealthCheckRecovered"
+	ResourceLimitExceeded    = "Control.PluginResourceLimitExceeded"
 	MoveSubscription         = "Control.PluginSubscriptionMoved"
+	PluginFrozen             = "Control.PluginFrozen"
+	PluginThawed             = "Control.PluginThawed"
+	MetricCatalogChanged     = "Control.MetricCatalogChanged"
+	TaskMetricsResolved      = "Control.TaskMetricsResolved"
+)
+
+// Reasons of a MetricCatalogChangedEvent; the metrics resolved for a task
+// change for CatalogTaskMetricsResolved
+const (
+	CatalogPluginLoaded        = "plugin-loaded"
+	CatalogPluginUnloaded      = "plugin-unloaded"
+	CatalogPluginsSwapped      = "plugins-swapped"
+	CatalogMetricsAdvertised   = "metrics-advertised"
+	CatalogTaskMetricsResolved = "task-metrics-resolved"
 )
 
 type StartPluginEvent struct {
@@ -70,6 +86,32 @@ func (e UnloadPluginEvent) Namespace() string {
 	return PluginUnloaded
 }
 
+// PluginFrozenEvent is emitted when a plugin stops accepting new
+// subscriptions; ImpactedTasks holds the ids of the tasks depending on it.
+type PluginFrozenEvent struct {
+	Name          string
+	Version       int
+	Type          int
+	ImpactedTasks []string
+}
+
+func (e PluginFrozenEvent) Namespace() string {
+	return PluginFrozen
+}
+
+// PluginThawedEvent is emitted when a frozen plugin accepts new
+// subscriptions again.
+type PluginThawedEvent struct {
+	Name          string
+	Version       int
+	Type          int
+	ImpactedTasks []string
+}
+
+func (e PluginThawedEvent) Namespace() string {
+	return PluginThawed
+}
+
 type DeadAvailablePluginEvent struct {
 	Name    string
 	Version int
@@ -150,3 +192,59 @@ type HealthCheckFailedEvent struct {
 func (hfe HealthCheckFailedEvent) Namespace() string {
 	return HealthCheckFailed
 }
+
+// HealthCheckRecoveredEvent is emitted when a plugin which failed its health
+// checks answers one again.
+type HealthCheckRecoveredEvent struct {
+	Name    string
+	Version int
+	Type    int
+}
+
+func (hre HealthCheckRecoveredEvent) Namespace() string {
+	return HealthCheckRecovered
+}
+
+// ResourceLimitExceededEvent is emitted when a running plugin uses more of a
+// resource than the limit it was loaded with.
+type ResourceLimitExceededEvent struct {
+	Name     string
+	Version  int
+	Type     int
+	Key      string
+	Id       uint32
+	Resource string
+	Usage    float64
+	Limit    float64
+}
+
+func (e *ResourceLimitExceededEvent) Namespace() string {
+	return ResourceLimitExceeded
+}
+
+// MetricCatalogChangedEvent is emitted when metrics are added to or removed
+// from the metric catalog.  Added and Removed hold the keys of the metrics,
+// <namespace>:<version>, and Plugin the key of the plugin, type:name:version.
+type MetricCatalogChangedEvent struct {
+	Reason  string
+	Plugin  string
+	Added   []string
+	Removed []string
+}
+
+func (e *MetricCatalogChangedEvent) Namespace() string {
+	return MetricCatalogChanged
+}
+
+// TaskMetricsResolvedEvent is emitted when the metrics resolved for the
+// requested metrics of a task change after a change of the metric catalog,
+// e.g. a collect node with a wildcard matching newly advertised metrics.
+type TaskMetricsResolvedEvent struct {
+	TaskId  string
+	Added   []string
+	Removed []string
+}
+
+func (e *TaskMetricsResolvedEvent) Namespace() string {
+	return TaskMetricsResolved
+}
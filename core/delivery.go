@@ -0,0 +1,72 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+// The gRPC trailers a publisher plugin acknowledges a batch of metrics with.
+// A plugin setting none of them keeps the former contract: the batch is
+// published when the call returns no error, else none of it is and the
+// failure is fatal.
+const (
+	// PublishedTrailer - The number of metrics of the batch published, which
+	// are the first ones of the batch
+	PublishedTrailer = "snap-published"
+	// PublishErrorTrailer - The kind of the error of the publisher,
+	// RetryablePublishError or FatalPublishError
+	PublishErrorTrailer = "snap-publish-error"
+	// RetryablePublishError - A transient failure, e.g. the backend of the
+	// publisher is unreachable, the rest of the batch is published again
+	RetryablePublishError = "retryable"
+	// FatalPublishError - A failure which publishing again would not fix,
+	// e.g. metrics the backend rejects
+	FatalPublishError = "fatal"
+)
+
+// PublishError is the failure of a publisher to publish a batch of metrics.
+// Published is the number of metrics of the batch the publisher
+// acknowledged, the first ones; with Retryable the scheduler publishes the
+// others again, delivering every metric at least once.
+type PublishError struct {
+	Err       error
+	Published int
+	Retryable bool
+}
+
+func (e *PublishError) Error() string {
+	return e.Err.Error()
+}
+
+// TaskDeliveryStats counts the deliveries of the metrics of a task to its
+// publishers since it was created
+type TaskDeliveryStats struct {
+	// Published is the number of metrics the publishers acknowledged
+	Published uint64 `json:"published"`
+	// Retries is the number of batches published again after a retryable
+	// failure
+	Retries uint64 `json:"retries"`
+	// RetryableFailures and FatalFailures count the failed publish calls
+	RetryableFailures uint64 `json:"retryable_failures"`
+	FatalFailures     uint64 `json:"fatal_failures"`
+	// Undelivered is the number of metrics given up, after a fatal failure or
+	// once the deadline of the publish passed
+	Undelivered uint64 `json:"undelivered"`
+	// Deduplicated is the number of metrics not published again, their
+	// batch being acknowledged already
+	Deduplicated uint64 `json:"deduplicated"`
+}
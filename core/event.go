@@ -0,0 +1,61 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import "time"
+
+// EventRecord is a scheduler event kept in the event log of the scheduler.
+//
+// swagger:model EventRecord
+type EventRecord struct {
+	// Seq is the position of the event in the log
+	Seq       uint64    `json:"seq"`
+	Timestamp time.Time `json:"timestamp"`
+	// Namespace is the namespace of the event, e.g. Scheduler.TaskStarted
+	Namespace string `json:"namespace"`
+	TaskID    string `json:"task_id,omitempty"`
+	// TaskName and TaskLabels are recorded for the events of the tasks
+	// which stopped running
+	TaskName   string            `json:"task_name,omitempty"`
+	TaskLabels map[string]string `json:"task_labels,omitempty"`
+	// Details are the other fields of the event, e.g. the reason a task
+	// was disabled
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// EventQuery selects records of the event log; empty fields match all the
+// records.
+type EventQuery struct {
+	TaskID    string
+	Namespace string
+	// Since and Until bound the timestamps of the records, both included
+	Since time.Time
+	Until time.Time
+	// Limit keeps the most recent records only when greater than 0
+	Limit int
+}
+
+// Matches returns true when the record is selected by the query
+func (q EventQuery) Matches(r EventRecord) bool {
+	return (q.TaskID == "" || q.TaskID == r.TaskID) &&
+		(q.Namespace == "" || q.Namespace == r.Namespace) &&
+		!r.Timestamp.Before(q.Since) &&
+		(q.Until.IsZero() || !r.Timestamp.After(q.Until))
+}
@@ -0,0 +1,40 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+// MaintenanceWindow is a period during which the tasks of the scheduler, or
+// some of them, are paused for host maintenance.  The paused tasks do not
+// fire during the window and the fires they skip are neither missed nor
+// failed.
+//
+// swagger:model MaintenanceWindow
+type MaintenanceWindow struct {
+	Reason string `json:"reason,omitempty"`
+	// TaskIDs are the IDs of the paused tasks; empty when all the tasks are
+	// paused, including the ones started during the window
+	TaskIDs []string `json:"task_ids,omitempty"`
+	// Labels are the labels selecting the paused tasks, if they were
+	// selected by their labels
+	Labels map[string]string `json:"labels,omitempty"`
+	// Start is when the window started (Unix time)
+	Start int64 `json:"start"`
+	// End is when the window ended (Unix time); zero while it is open
+	End int64 `json:"end,omitempty"`
+}
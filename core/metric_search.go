@@ -0,0 +1,106 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrInvalidMetricPattern - The error message for a namespace pattern which
+// does not start with its separator or holds a malformed element
+var ErrInvalidMetricPattern = errors.New("invalid metric namespace pattern")
+
+// MetricSearchOptions selects the metrics of the catalog, by page of at most
+// Limit metrics when Limit is above 0.  The metrics are listed in the order
+// of their keys, <namespace>:<version>, from the first key after After.
+type MetricSearchOptions struct {
+	// Namespace selects the metrics matching the pattern, when it is set.
+	// Each element of the pattern is matched as with path.Match, e.g.
+	// /intel/psutil/cpu*/user; a trailing * matches one or more elements.
+	Namespace string
+	// Plugin selects the metrics exposed by the plugin of the name
+	Plugin string
+	// Version selects the metrics of the version when it is above 0
+	Version int
+	// Since selects the metrics last advertised at or after the time
+	Since time.Time
+	After string
+	Limit int
+}
+
+// MetricPattern is a namespace pattern of MetricSearchOptions split in
+// elements
+type MetricPattern []string
+
+// ParseMetricPattern splits the namespace pattern in elements on its first
+// character, like the namespaces of the REST API
+func ParseMetricPattern(pattern string) (MetricPattern, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	sep := pattern[:1]
+	if sep == "*" || sep == "?" || sep == "[" || sep == "\\" || len(pattern) < 2 {
+		return nil, fmt.Errorf("%v '%s' (expected /<element>/...)", ErrInvalidMetricPattern, pattern)
+	}
+	p := MetricPattern(strings.Split(strings.TrimPrefix(pattern, sep), sep))
+	for _, e := range p {
+		if _, err := path.Match(e, ""); err != nil {
+			return nil, fmt.Errorf("%v '%s' (malformed element '%s')", ErrInvalidMetricPattern, pattern, e)
+		}
+	}
+	return p, nil
+}
+
+// Prefix returns the leading elements of the pattern holding no wildcard,
+// under which all the matching namespaces are
+func (p MetricPattern) Prefix() []string {
+	for i, e := range p {
+		if strings.ContainsAny(e, "*?[\\") {
+			return p[:i]
+		}
+	}
+	return p
+}
+
+// Matches returns true when the namespace matches the pattern; an empty
+// pattern matches all the namespaces
+func (p MetricPattern) Matches(ns Namespace) bool {
+	for i, e := range p {
+		if i == len(p)-1 && e == "*" {
+			return len(ns) >= len(p)
+		}
+		if i >= len(ns) {
+			return false
+		}
+		if ok, _ := path.Match(e, ns[i].Value); !ok {
+			return false
+		}
+	}
+	return len(ns) == len(p) || len(p) == 0
+}
+
+// MetricKey returns the key of the metric in the order of the search
+func MetricKey(m CatalogedMetric) string {
+	return fmt.Sprintf("%s:%d", m.Namespace().String(), m.Version())
+}
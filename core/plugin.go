@@ -143,6 +143,33 @@ type RequestedPlugin struct {
 	tlsEnabled  bool
 	autoLoaded  bool
 	uri         *url.URL
+	limits      PluginResourceLimits
+	// source is where the plugin is downloaded from before it is loaded
+	source *PluginSource
+	// registryRef names the plugin of the plugin registry to download
+	registryRef *PluginRegistryRef
+}
+
+// PluginResourceLimits holds the resources each running instance of a plugin
+// is allowed to use.  A zero value means no limit.
+type PluginResourceLimits struct {
+	// MemoryBytes is the maximum resident memory of a plugin instance
+	MemoryBytes int64 `json:"memory_bytes,omitempty"`
+	// CPUPercent is the maximum CPU usage of a plugin instance, 100 being
+	// one full core
+	CPUPercent float64 `json:"cpu_percent,omitempty"`
+}
+
+// IsZero returns true when no limit is set
+func (l PluginResourceLimits) IsZero() bool {
+	return l.MemoryBytes <= 0 && l.CPUPercent <= 0
+}
+
+// PluginResourceUsage holds the resources used by a running plugin instance
+// when it was last sampled.
+type PluginResourceUsage struct {
+	MemoryBytes int64   `json:"memory_bytes"`
+	CPUPercent  float64 `json:"cpu_percent"`
 }
 
 // NewRequestedPlugin returns a Requested Plugin which represents the plugin path and signature
@@ -237,6 +264,12 @@ func (p *RequestedPlugin) TLSEnabled() bool {
 	return p.tlsEnabled
 }
 
+// ResourceLimits returns the resource limits applied to the running
+// instances of the requested plugin
+func (p *RequestedPlugin) ResourceLimits() PluginResourceLimits {
+	return p.limits
+}
+
 func (p *RequestedPlugin) CheckSum() [sha256.Size]byte {
 	return p.checkSum
 }
@@ -273,6 +306,12 @@ func (p *RequestedPlugin) SetTLSEnabled(tlsEnabled bool) {
 	p.tlsEnabled = tlsEnabled
 }
 
+// SetResourceLimits sets the resource limits applied to the running
+// instances of the requested plugin
+func (p *RequestedPlugin) SetResourceLimits(limits PluginResourceLimits) {
+	p.limits = limits
+}
+
 func (p *RequestedPlugin) SetSignature(data []byte) {
 	p.signature = data
 }
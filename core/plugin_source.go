@@ -0,0 +1,135 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPluginURL - The error message for a plugin source which is not an http(s) URL
+	ErrInvalidPluginURL = errors.New("Plugin URL must be an absolute http or https URL")
+	// ErrInvalidPluginCheckSum - The error message for a plugin source without a valid SHA-256 checksum
+	ErrInvalidPluginCheckSum = errors.New("Plugin checksum must be the hex encoded SHA-256 of the plugin")
+	// ErrInvalidRegistryRef - The error message for a malformed reference to a plugin of the registry
+	ErrInvalidRegistryRef = errors.New("Registry plugin must be given as <type>:<name>[:<version>]")
+)
+
+// PluginSource is where a plugin is downloaded from before it is loaded.
+// The downloaded plugin must match the SHA-256 checksum; its signature is
+// downloaded from SignatureURL when given.
+type PluginSource struct {
+	URL          string `json:"url"`
+	SHA256       string `json:"sha256"`
+	SignatureURL string `json:"signature_url,omitempty"`
+}
+
+// Validate returns an error when the URLs or the checksum of the source are
+// invalid
+func (s PluginSource) Validate() error {
+	if !isHTTPURL(s.URL) {
+		return fmt.Errorf("%v: '%s'", ErrInvalidPluginURL, s.URL)
+	}
+	if s.SignatureURL != "" && !isHTTPURL(s.SignatureURL) {
+		return fmt.Errorf("%v: '%s'", ErrInvalidPluginURL, s.SignatureURL)
+	}
+	if b, err := hex.DecodeString(s.SHA256); err != nil || len(b) != 32 {
+		return ErrInvalidPluginCheckSum
+	}
+	return nil
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// PluginRegistryRef names a plugin of the plugin registry of the daemon.  A
+// version lower than 1 stands for the latest version of the registry.
+type PluginRegistryRef struct {
+	Type    string
+	Name    string
+	Version int
+}
+
+// ParsePluginRegistryRef parses a reference to a plugin of the registry
+// given as <type>:<name>[:<version>]
+func ParsePluginRegistryRef(s string) (*PluginRegistryRef, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return nil, ErrInvalidRegistryRef
+	}
+	if _, err := ToPluginType(parts[0]); err != nil {
+		return nil, err
+	}
+	ref := &PluginRegistryRef{Type: parts[0], Name: parts[1]}
+	if len(parts) == 3 {
+		v, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return nil, ErrInvalidRegistryRef
+		}
+		ref.Version = v
+	}
+	return ref, nil
+}
+
+func (r *PluginRegistryRef) String() string {
+	if r.Version < 1 {
+		return r.Type + ":" + r.Name
+	}
+	return fmt.Sprintf("%s:%s:%d", r.Type, r.Name, r.Version)
+}
+
+// NewRemoteRequestedPlugin returns a requested plugin which is downloaded
+// from the given source when it is loaded
+func NewRemoteRequestedPlugin(src PluginSource) (*RequestedPlugin, error) {
+	if err := src.Validate(); err != nil {
+		return nil, err
+	}
+	return &RequestedPlugin{source: &src}, nil
+}
+
+// NewRegistryRequestedPlugin returns a requested plugin which is looked up
+// in the plugin registry of the daemon and downloaded when it is loaded
+func NewRegistryRequestedPlugin(ref *PluginRegistryRef) *RequestedPlugin {
+	return &RequestedPlugin{registryRef: ref}
+}
+
+// Source returns where the plugin is downloaded from, nil for a local plugin
+func (p *RequestedPlugin) Source() *PluginSource {
+	return p.source
+}
+
+// RegistryRef returns the plugin of the plugin registry to download, nil
+// when the plugin is not loaded from the registry
+func (p *RequestedPlugin) RegistryRef() *PluginRegistryRef {
+	return p.registryRef
+}
+
+// SetDownloaded sets the path of the downloaded plugin and its checksum
+func (p *RequestedPlugin) SetDownloaded(path string) error {
+	p.path = path
+	return p.generateCheckSum()
+}
@@ -32,19 +32,81 @@ import (
 // swagger:model Schedule
 type Schedule struct {
 	// required: true
-	// enum: simple, windowed, streaming, cron
+	// enum: simple, windowed, streaming, cron, composite
 	Type string `json:"type"`
 	// required: true
 	Interval       string     `json:"interval"`
 	StartTimestamp *time.Time `json:"start_timestamp,omitempty"`
 	StopTimestamp  *time.Time `json:"stop_timestamp,omitempty"`
 	Count          uint       `json:"count,omitempty"`
+	// Aligned fires a simple or windowed schedule on the multiples of its
+	// interval, e.g. at the start of every minute for an interval of 1m
+	Aligned bool `json:"aligned,omitempty"`
+	// Schedules are the schedules of a composite schedule, which fires
+	// whenever one of them fires
+	Schedules []Schedule `json:"schedules,omitempty"`
 }
 
+// MinimumScheduleInterval is the shortest interval a task can be scheduled on
+const MinimumScheduleInterval = 10 * time.Millisecond
+
 var (
 	ErrMissingScheduleInterval = errors.New("missing `interval` in configuration of schedule")
+	// ErrScheduleIntervalTooShort - The error message for a schedule interval shorter than MinimumScheduleInterval
+	ErrScheduleIntervalTooShort = fmt.Errorf("`interval` in configuration of schedule must be at least %v", MinimumScheduleInterval)
+	// ErrMissingSchedules - The error message for a composite schedule without `schedules`
+	ErrMissingSchedules = errors.New("missing `schedules` in configuration of composite schedule")
+	// ErrStreamingInComposite - The error message for a streaming schedule within a composite schedule
+	ErrStreamingInComposite = errors.New("a composite schedule cannot hold a streaming schedule")
 )
 
+// ScheduleTypes lists the types of schedule a task can be created with
+var ScheduleTypes = []string{"simple", "windowed", "cron", "streaming", "composite"}
+
+// ScheduleFromSchedule returns the portable representation of the given
+// schedule.  It is the inverse of makeSchedule.
+func ScheduleFromSchedule(sch schedule.Schedule) (*Schedule, error) {
+	switch v := sch.(type) {
+	case *schedule.WindowedSchedule:
+		s := &Schedule{
+			Type:           "simple",
+			Interval:       v.Interval.String(),
+			StartTimestamp: v.StartTime,
+			StopTimestamp:  v.StopTime,
+			Count:          v.Count,
+			Aligned:        v.Aligned,
+		}
+		if v.StartTime != nil || v.StopTime != nil || v.Count != 0 {
+			s.Type = "windowed"
+		}
+		return s, nil
+	case *schedule.CronSchedule:
+		return &Schedule{
+			Type:     "cron",
+			Interval: v.Entry(),
+		}, nil
+	case *schedule.StreamingSchedule:
+		return &Schedule{
+			Type: "streaming",
+		}, nil
+	case *schedule.CompositeSchedule:
+		s := &Schedule{
+			Type:      "composite",
+			Schedules: make([]Schedule, len(v.Schedules)),
+		}
+		for i, sub := range v.Schedules {
+			ss, err := ScheduleFromSchedule(sub)
+			if err != nil {
+				return nil, err
+			}
+			s.Schedules[i] = *ss
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown schedule type `%T`", sch)
+	}
+}
+
 func makeSchedule(s Schedule) (schedule.Schedule, error) {
 	switch s.Type {
 	case "simple", "windowed":
@@ -56,6 +118,9 @@ func makeSchedule(s Schedule) (schedule.Schedule, error) {
 		if err != nil {
 			return nil, err
 		}
+		if d > 0 && d < MinimumScheduleInterval {
+			return nil, ErrScheduleIntervalTooShort
+		}
 
 		sch := schedule.NewWindowedSchedule(
 			d,
@@ -63,6 +128,7 @@ func makeSchedule(s Schedule) (schedule.Schedule, error) {
 			s.StopTimestamp,
 			s.Count,
 		)
+		sch.Aligned = s.Aligned
 
 		err = sch.Validate()
 		if err != nil {
@@ -82,7 +148,133 @@ func makeSchedule(s Schedule) (schedule.Schedule, error) {
 		return sch, nil
 	case "streaming":
 		return schedule.NewStreamingSchedule(), nil
+	case "composite":
+		if len(s.Schedules) == 0 {
+			return nil, ErrMissingSchedules
+		}
+		schedules := make([]schedule.Schedule, len(s.Schedules))
+		for i, sub := range s.Schedules {
+			if sub.Type == "streaming" {
+				return nil, ErrStreamingInComposite
+			}
+			sch, err := makeSchedule(sub)
+			if err != nil {
+				return nil, err
+			}
+			schedules[i] = sch
+		}
+		sch := schedule.NewCompositeSchedule(schedules...)
+		if err := sch.Validate(); err != nil {
+			return nil, err
+		}
+		return sch, nil
 	default:
 		return nil, fmt.Errorf("unknown schedule type `%s`", s.Type)
 	}
 }
+
+// ScheduleSimulation is the outcome of running a schedule over a period
+// against the recorded durations of the task runs.
+//
+// swagger:model ScheduleSimulation
+type ScheduleSimulation struct {
+	Schedule *Schedule `json:"schedule"`
+	// Period is how long the schedule was simulated for
+	Period string `json:"period"`
+	// Samples is the number of recorded run durations the simulation used
+	Samples        int    `json:"samples"`
+	MeanDuration   string `json:"mean_duration"`
+	MaxDuration    string `json:"max_duration"`
+	ScheduledFires uint   `json:"scheduled_fires"`
+	Fires          uint   `json:"fires"`
+	// MissedFires is the number of fires skipped because a run was still
+	// going when they were due
+	MissedFires uint `json:"missed_fires"`
+	// Overlaps is the number of runs lasting past the next fire
+	Overlaps  uint    `json:"overlaps"`
+	MissRatio float64 `json:"miss_ratio"`
+	// SuggestedInterval is the shortest interval expected to miss almost no
+	// fires, set when the simulated interval is too short
+	SuggestedInterval string `json:"suggested_interval,omitempty"`
+	Recommendation    string `json:"recommendation"`
+}
+
+// RunSimulationRequest is a set of tasks whose schedules are simulated
+// together over a virtual time range, without running anything.
+//
+// swagger:model RunSimulationRequest
+type RunSimulationRequest struct {
+	Start time.Time       `json:"start"`
+	End   time.Time       `json:"end"`
+	Tasks []SimulatedTask `json:"tasks"`
+	// CollisionWindow is how close the fires of different tasks are to
+	// collide, 1s when empty
+	CollisionWindow string `json:"collision_window,omitempty"`
+	// Slot is the length of the slots the load is estimated over, a sixtieth
+	// of the range when empty
+	Slot string `json:"slot,omitempty"`
+}
+
+// SimulatedTask is a task of a run simulation: an existing task, given by its
+// id, or a schedule.  The runs last the given duration or, when empty, the
+// recorded durations of the runs of the task, else of all the tasks of the
+// daemon.
+type SimulatedTask struct {
+	Name     string    `json:"name,omitempty"`
+	TaskID   string    `json:"task_id,omitempty"`
+	Schedule *Schedule `json:"schedule,omitempty"`
+	Duration string    `json:"duration,omitempty"`
+}
+
+// RunSimulation is the outcome of a run simulation: when each task would
+// fire, the fires of different tasks colliding, and the load of the runs.
+//
+// swagger:model RunSimulation
+type RunSimulation struct {
+	Start time.Time           `json:"start"`
+	End   time.Time           `json:"end"`
+	Tasks []SimulatedTaskRuns `json:"tasks"`
+	// CollisionCount is the number of collisions, of which the first ones
+	// are listed
+	CollisionCount int                 `json:"collision_count"`
+	Collisions     []ScheduleCollision `json:"collisions"`
+	// HotSpots are the busiest slots of the range
+	HotSpots []LoadSlot `json:"hot_spots"`
+	// PeakConcurrentRuns is the largest number of runs going at once
+	PeakConcurrentRuns int `json:"peak_concurrent_runs"`
+	// MeanConcurrentRuns is the time spent running by all the tasks divided
+	// by the length of the range
+	MeanConcurrentRuns float64 `json:"mean_concurrent_runs"`
+	// Workers is the size of the worker pools; the runs beyond it wait for
+	// a worker
+	Workers    uint `json:"workers"`
+	Overloaded bool `json:"overloaded"`
+}
+
+// SimulatedTaskRuns are the simulated runs of a task.  The first fires are
+// listed.
+type SimulatedTaskRuns struct {
+	Name         string      `json:"name"`
+	TaskID       string      `json:"task_id,omitempty"`
+	Schedule     *Schedule   `json:"schedule"`
+	MeanDuration string      `json:"mean_duration"`
+	FireCount    uint        `json:"fire_count"`
+	MissedFires  uint        `json:"missed_fires"`
+	Fires        []time.Time `json:"fires"`
+}
+
+// ScheduleCollision is a set of fires of different tasks within the
+// collision window of each other.  Tasks are the names of the tasks.
+type ScheduleCollision struct {
+	At    time.Time `json:"at"`
+	Tasks []string  `json:"tasks"`
+}
+
+// LoadSlot is the load of the runs over a slot of a run simulation.  Load is
+// the time spent running by all the tasks divided by the length of the slot.
+type LoadSlot struct {
+	Start              time.Time `json:"start"`
+	Fires              uint      `json:"fires"`
+	PeakConcurrentRuns int       `json:"peak_concurrent_runs"`
+	Load               float64   `json:"load"`
+}
@@ -26,6 +26,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/intelsdi-x/snap/pkg/schedule"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -67,6 +68,67 @@ func TestMakeSchedule(t *testing.T) {
 		So(err.Error(), ShouldEqual, "Interval must be greater than 0")
 	})
 
+	Convey("Simple schedule with a duration shorter than the minimum", t, func() {
+		sched1 := &Schedule{Type: "simple", Interval: "5ms"}
+		rsched, err := makeSchedule(*sched1)
+		So(rsched, ShouldBeNil)
+		So(err, ShouldEqual, ErrScheduleIntervalTooShort)
+	})
+
+	Convey("Simple aligned schedule", t, func() {
+		sched1 := &Schedule{Type: "simple", Interval: "1m0s", Aligned: true}
+		rsched, err := makeSchedule(*sched1)
+		So(err, ShouldBeNil)
+		So(rsched.(*schedule.WindowedSchedule).Aligned, ShouldBeTrue)
+		s, err := ScheduleFromSchedule(rsched)
+		So(err, ShouldBeNil)
+		So(s, ShouldResemble, sched1)
+	})
+
+	Convey("Composite schedule", t, func() {
+		sched1 := &Schedule{Type: "composite", Schedules: []Schedule{
+			{Type: "simple", Interval: "5m0s"},
+			{Type: "cron", Interval: "0 0 0 * * *"},
+		}}
+		rsched, err := makeSchedule(*sched1)
+		So(err, ShouldBeNil)
+		So(rsched.(*schedule.CompositeSchedule).Schedules, ShouldHaveLength, 2)
+		s, err := ScheduleFromSchedule(rsched)
+		So(err, ShouldBeNil)
+		So(s, ShouldResemble, sched1)
+	})
+
+	Convey("Composite schedule without schedules", t, func() {
+		rsched, err := makeSchedule(Schedule{Type: "composite"})
+		So(rsched, ShouldBeNil)
+		So(err, ShouldEqual, ErrMissingSchedules)
+	})
+
+	Convey("Composite schedule with an invalid schedule", t, func() {
+		rsched, err := makeSchedule(Schedule{Type: "composite", Schedules: []Schedule{
+			{Type: "simple", Interval: "5m"},
+			{Type: "simple"},
+		}})
+		So(rsched, ShouldBeNil)
+		So(err, ShouldEqual, ErrMissingScheduleInterval)
+	})
+
+	Convey("Composite schedule with a streaming schedule", t, func() {
+		rsched, err := makeSchedule(Schedule{Type: "composite", Schedules: []Schedule{
+			{Type: "simple", Interval: "5m"},
+			{Type: "streaming"},
+		}})
+		So(rsched, ShouldBeNil)
+		So(err, ShouldEqual, ErrStreamingInComposite)
+	})
+
+	Convey("Simple schedule with a sub-second duration", t, func() {
+		sched1 := &Schedule{Type: "simple", Interval: "10ms"}
+		rsched, err := makeSchedule(*sched1)
+		So(err, ShouldBeNil)
+		So(rsched, ShouldNotBeNil)
+	})
+
 	Convey("Simple schedule with proper duration", t, func() {
 		sched1 := &Schedule{Type: "simple", Interval: "1s"}
 		rsched, err := makeSchedule(*sched1)
@@ -20,6 +20,10 @@ limitations under the License.
 package scheduler_event
 
 import (
+	"time"
+
+	"github.com/intelsdi-x/gomit"
+
 	"github.com/intelsdi-x/snap/core"
 )
 
@@ -31,10 +35,39 @@ const (
 	TaskStopped            = "Scheduler.TaskStopped"
 	TaskEnded              = "Scheduler.TaskEnded"
 	TaskDisabled           = "Scheduler.TaskDisabled"
+	TaskErrored            = "Scheduler.TaskErrored"
+	TaskFailureLimit       = "Scheduler.TaskFailureLimitReached"
+	TaskSuspended          = "Scheduler.TaskSuspended"
+	TaskResumed            = "Scheduler.TaskResumed"
 	MetricCollected        = "Scheduler.MetricsCollected"
 	MetricCollectionFailed = "Scheduler.MetricCollectionFailed"
+	EventsBatched          = "Scheduler.EventsBatched"
+	TaskRunCompleted       = "Scheduler.TaskRunCompleted"
+	TaskLeadershipChanged  = "Scheduler.TaskLeadershipChanged"
+	AlertFired             = "Scheduler.AlertFired"
+	AlertResolved          = "Scheduler.AlertResolved"
+	MaintenanceStarted     = "Scheduler.MaintenanceStarted"
+	MaintenanceEnded       = "Scheduler.MaintenanceEnded"
+	EventReplayed          = "Scheduler.EventReplayed"
 )
 
+// TaskEvent is an event about a single task.  Handlers may be subscribed to
+// the events of some tasks only.
+type TaskEvent interface {
+	gomit.EventBody
+	GetTaskID() string
+}
+
+// TaskEndEvent is an event of a task which stopped running: it was stopped,
+// it ended, it was disabled or its schedule errored.  It carries the name and
+// the labels of the task and why it stopped running.
+type TaskEndEvent interface {
+	TaskEvent
+	GetTaskName() string
+	GetTaskLabels() map[string]string
+	GetWhy() string
+}
+
 type PluginsUnsubscribedEvent struct {
 	TaskID  string
 	Plugins []core.SubscribedPlugin
@@ -44,6 +77,10 @@ func (e PluginsUnsubscribedEvent) Namespace() string {
 	return PluginsUnsubscribed
 }
 
+func (e PluginsUnsubscribedEvent) GetTaskID() string {
+	return e.TaskID
+}
+
 type TaskStartedEvent struct {
 	TaskID string
 	Source string
@@ -53,6 +90,10 @@ func (e TaskStartedEvent) Namespace() string {
 	return TaskStarted
 }
 
+func (e TaskStartedEvent) GetTaskID() string {
+	return e.TaskID
+}
+
 type TaskCreatedEvent struct {
 	TaskID        string
 	StartOnCreate bool
@@ -63,6 +104,10 @@ func (e TaskCreatedEvent) Namespace() string {
 	return TaskCreated
 }
 
+func (e TaskCreatedEvent) GetTaskID() string {
+	return e.TaskID
+}
+
 type TaskDeletedEvent struct {
 	TaskID string
 	Source string
@@ -72,33 +117,251 @@ func (e TaskDeletedEvent) Namespace() string {
 	return TaskDeleted
 }
 
+func (e TaskDeletedEvent) GetTaskID() string {
+	return e.TaskID
+}
+
 type TaskStoppedEvent struct {
-	TaskID string
-	Source string
+	TaskID     string
+	TaskName   string
+	TaskLabels map[string]string
+	Source     string
+	Why        string
 }
 
 func (e TaskStoppedEvent) Namespace() string {
 	return TaskStopped
 }
 
+func (e TaskStoppedEvent) GetTaskID() string {
+	return e.TaskID
+}
+
+func (e TaskStoppedEvent) GetTaskName() string {
+	return e.TaskName
+}
+
+func (e TaskStoppedEvent) GetTaskLabels() map[string]string {
+	return e.TaskLabels
+}
+
+func (e TaskStoppedEvent) GetWhy() string {
+	return e.Why
+}
+
 type TaskEndedEvent struct {
-	TaskID string
-	Source string
+	TaskID     string
+	TaskName   string
+	TaskLabels map[string]string
+	Source     string
+	Why        string
 }
 
 func (e TaskEndedEvent) Namespace() string {
 	return TaskEnded
 }
 
+func (e TaskEndedEvent) GetTaskID() string {
+	return e.TaskID
+}
+
+func (e TaskEndedEvent) GetTaskName() string {
+	return e.TaskName
+}
+
+func (e TaskEndedEvent) GetTaskLabels() map[string]string {
+	return e.TaskLabels
+}
+
+func (e TaskEndedEvent) GetWhy() string {
+	return e.Why
+}
+
 type TaskDisabledEvent struct {
-	TaskID string
-	Why    string
+	TaskID     string
+	TaskName   string
+	TaskLabels map[string]string
+	Why        string
 }
 
 func (e TaskDisabledEvent) Namespace() string {
 	return TaskDisabled
 }
 
+func (e TaskDisabledEvent) GetTaskID() string {
+	return e.TaskID
+}
+
+func (e TaskDisabledEvent) GetTaskName() string {
+	return e.TaskName
+}
+
+func (e TaskDisabledEvent) GetTaskLabels() map[string]string {
+	return e.TaskLabels
+}
+
+func (e TaskDisabledEvent) GetWhy() string {
+	return e.Why
+}
+
+// TaskErroredEvent is emitted when the schedule of a task errored; the task
+// is then disabled.
+type TaskErroredEvent struct {
+	TaskID     string
+	TaskName   string
+	TaskLabels map[string]string
+	Why        string
+}
+
+func (e TaskErroredEvent) Namespace() string {
+	return TaskErrored
+}
+
+func (e TaskErroredEvent) GetTaskID() string {
+	return e.TaskID
+}
+
+func (e TaskErroredEvent) GetTaskName() string {
+	return e.TaskName
+}
+
+func (e TaskErroredEvent) GetTaskLabels() map[string]string {
+	return e.TaskLabels
+}
+
+func (e TaskErroredEvent) GetWhy() string {
+	return e.Why
+}
+
+// TaskFailureLimitReachedEvent is emitted when a task reached its limit of
+// consecutive failures and its failure policy keeps it running.
+type TaskFailureLimitReachedEvent struct {
+	TaskID              string
+	ConsecutiveFailures int
+	Policy              string
+	Why                 string
+}
+
+func (e TaskFailureLimitReachedEvent) Namespace() string {
+	return TaskFailureLimit
+}
+
+func (e TaskFailureLimitReachedEvent) GetTaskID() string {
+	return e.TaskID
+}
+
+// TaskSuspendedEvent is emitted when a task is suspended because its metric
+// manager cannot be reached.
+type TaskSuspendedEvent struct {
+	TaskID string
+	Why    string
+}
+
+func (e TaskSuspendedEvent) Namespace() string {
+	return TaskSuspended
+}
+
+func (e TaskSuspendedEvent) GetTaskID() string {
+	return e.TaskID
+}
+
+// TaskResumedEvent is emitted when a suspended task reached its metric manager
+// again and resumed running.
+type TaskResumedEvent struct {
+	TaskID string
+}
+
+func (e TaskResumedEvent) Namespace() string {
+	return TaskResumed
+}
+
+func (e TaskResumedEvent) GetTaskID() string {
+	return e.TaskID
+}
+
+// TaskRunCompletedEvent is emitted every time a run of a task workflow ends.
+type TaskRunCompletedEvent struct {
+	TaskID    string
+	StartTime time.Time
+	Duration  time.Duration
+	Success   bool
+	Partial   bool
+	Errors    []error
+	// FailedPlugins lists the plugins (type:name:version) which failed
+	// during the run
+	FailedPlugins []string
+}
+
+func (e TaskRunCompletedEvent) Namespace() string {
+	return TaskRunCompleted
+}
+
+func (e TaskRunCompletedEvent) GetTaskID() string {
+	return e.TaskID
+}
+
+// TaskLeadershipChangedEvent is emitted when this node becomes or stops being
+// the leader firing a singleton task.
+type TaskLeadershipChangedEvent struct {
+	TaskID string
+	Leader bool
+	Term   uint64
+}
+
+func (e TaskLeadershipChangedEvent) Namespace() string {
+	return TaskLeadershipChanged
+}
+
+func (e TaskLeadershipChangedEvent) GetTaskID() string {
+	return e.TaskID
+}
+
+type AlertFiredEvent struct {
+	TaskID string
+	Alert  core.Alert
+}
+
+func (e AlertFiredEvent) Namespace() string {
+	return AlertFired
+}
+
+func (e AlertFiredEvent) GetTaskID() string {
+	return e.TaskID
+}
+
+type AlertResolvedEvent struct {
+	TaskID string
+	Alert  core.Alert
+}
+
+func (e AlertResolvedEvent) Namespace() string {
+	return AlertResolved
+}
+
+func (e AlertResolvedEvent) GetTaskID() string {
+	return e.TaskID
+}
+
+// MaintenanceStartedEvent is emitted when the scheduler enters maintenance
+// mode and pauses its tasks.
+type MaintenanceStartedEvent struct {
+	Window core.MaintenanceWindow
+}
+
+func (e MaintenanceStartedEvent) Namespace() string {
+	return MaintenanceStarted
+}
+
+// MaintenanceEndedEvent is emitted when the scheduler leaves maintenance mode
+// and resumes its tasks.
+type MaintenanceEndedEvent struct {
+	Window core.MaintenanceWindow
+}
+
+func (e MaintenanceEndedEvent) Namespace() string {
+	return MaintenanceEnded
+}
+
 type MetricCollectedEvent struct {
 	TaskID  string
 	Metrics []core.Metric
@@ -108,6 +371,10 @@ func (e MetricCollectedEvent) Namespace() string {
 	return MetricCollected
 }
 
+func (e MetricCollectedEvent) GetTaskID() string {
+	return e.TaskID
+}
+
 type MetricCollectionFailedEvent struct {
 	TaskID string
 	Errors []error
@@ -116,3 +383,31 @@ type MetricCollectionFailedEvent struct {
 func (e MetricCollectionFailedEvent) Namespace() string {
 	return MetricCollectionFailed
 }
+
+func (e MetricCollectionFailedEvent) GetTaskID() string {
+	return e.TaskID
+}
+
+// EventReplayedEvent is an event read back from the event log of the
+// scheduler, handed to a handler subscribed with a replay of the past events.
+type EventReplayedEvent struct {
+	Record core.EventRecord
+}
+
+func (e EventReplayedEvent) Namespace() string {
+	return EventReplayed
+}
+
+func (e EventReplayedEvent) GetTaskID() string {
+	return e.Record.TaskID
+}
+
+// EventsBatchedEvent holds high frequency events which were coalesced by the
+// scheduler and are emitted together, in the order they occurred.
+type EventsBatchedEvent struct {
+	Events []gomit.EventBody
+}
+
+func (e EventsBatchedEvent) Namespace() string {
+	return EventsBatched
+}
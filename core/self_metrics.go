@@ -0,0 +1,94 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import "sort"
+
+// Histogram counts observed values in buckets bounded by increasing upper
+// bounds.  Values greater than the last bound are only part of Count.
+type Histogram struct {
+	Bounds []float64
+	// Counts are the numbers of values lower than or equal to each bound
+	// and greater than the previous one
+	Counts []uint64
+	Count  uint64
+	Sum    float64
+}
+
+// NewHistogram returns an empty histogram with the given bounds
+func NewHistogram(bounds []float64) *Histogram {
+	return &Histogram{
+		Bounds: bounds,
+		Counts: make([]uint64, len(bounds)),
+	}
+}
+
+// Observe adds a value to the histogram
+func (h *Histogram) Observe(v float64) {
+	if i := sort.SearchFloat64s(h.Bounds, v); i < len(h.Bounds) {
+		h.Counts[i]++
+	}
+	h.Count++
+	h.Sum += v
+}
+
+// Copy returns a copy of the histogram
+func (h *Histogram) Copy() Histogram {
+	c := *h
+	c.Counts = append([]uint64{}, h.Counts...)
+	return c
+}
+
+// WorkQueueDepth is the number of jobs waiting for a worker in a queue of
+// the scheduler.
+type WorkQueueDepth struct {
+	// Queue is the name of a dedicated queue, empty for the shared one
+	Queue string
+	// Phase is collect, process or publish
+	Phase string
+	Depth int
+}
+
+// PluginRestarts is the number of times the running instances of a plugin
+// were restarted after they failed.
+type PluginRestarts struct {
+	Type     string
+	Name     string
+	Version  int
+	Restarts int
+}
+
+// InflightMetrics is the state of the memory budget of the metrics being
+// published by the scheduler.  The sizes are estimates, in bytes.
+type InflightMetrics struct {
+	// Budget is zero when the metrics in flight are not limited
+	Budget int64
+	Used   int64
+	// SpilledBatches and SpilledBytes are the batches waiting on disk for
+	// the memory to be available
+	SpilledBatches int
+	SpilledBytes   int64
+	// SpillsTotal counts the batches ever spilled to disk
+	SpillsTotal uint64
+	// DroppedBatches and DroppedMetrics count the batches dropped because
+	// they did not fit in the budget and could not be spilled
+	DroppedBatches uint64
+	DroppedMetrics uint64
+}
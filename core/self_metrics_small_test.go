@@ -0,0 +1,47 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHistogram(t *testing.T) {
+	Convey("Values are counted in the bucket of the lowest bound they do not exceed", t, func() {
+		h := NewHistogram([]float64{0.1, 1, 10})
+		for _, v := range []float64{0.05, 0.1, 0.5, 20} {
+			h.Observe(v)
+		}
+		So(h.Counts, ShouldResemble, []uint64{2, 1, 0})
+		So(h.Count, ShouldEqual, 4)
+		So(h.Sum, ShouldAlmostEqual, 20.65)
+
+		Convey("copies do not change with the histogram", func() {
+			c := h.Copy()
+			h.Observe(5)
+			So(c.Counts, ShouldResemble, []uint64{2, 1, 0})
+			So(c.Count, ShouldEqual, 4)
+		})
+	})
+}
@@ -30,3 +30,15 @@ var (
 	// group does not exist
 	ErrSubscriptionGroupDoesNotExist = errors.New("Subscription does not exist")
 )
+
+// PluginSubscription holds the tasks subscribed to a loaded plugin
+type PluginSubscription struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	// Subscribers is the number of tasks the pool of the plugin counts; it
+	// differs from the number of tasks when a subscription was left behind
+	Subscribers int `json:"subscribers"`
+	// Tasks are the ids of the tasks whose dependencies include the plugin
+	Tasks []string `json:"tasks"`
+}
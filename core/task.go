@@ -20,14 +20,18 @@ limitations under the License.
 package core
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"reflect"
+	"strings"
 	"time"
 
+	"github.com/ghodss/yaml"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/intelsdi-x/snap/core/serror"
@@ -44,19 +48,64 @@ const (
 	TaskFiring
 	TaskEnded
 	TaskStopping
+	TaskSuspended
 )
 
 var (
 	TaskStateLookup = map[TaskState]string{
-		TaskDisabled: "Disabled", // on error, not resumable
-		TaskStopped:  "Stopped",  // stopped but resumable
-		TaskSpinning: "Running",  // running
-		TaskFiring:   "Running",  // running (firing can happen so briefly we don't want to try and render it as a string state)
-		TaskEnded:    "Ended",    // ended, but resumable if the schedule is still valid and might fire again
-		TaskStopping: "Stopping", // channel has been closed, wait for TaskStopped state
+		TaskDisabled:  "Disabled",  // on error, not resumable
+		TaskStopped:   "Stopped",   // stopped but resumable
+		TaskSpinning:  "Running",   // running
+		TaskFiring:    "Running",   // running (firing can happen so briefly we don't want to try and render it as a string state)
+		TaskEnded:     "Ended",     // ended, but resumable if the schedule is still valid and might fire again
+		TaskStopping:  "Stopping",  // channel has been closed, wait for TaskStopped state
+		TaskSuspended: "Suspended", // running, but waiting for its metric manager to be reachable again
 	}
 )
 
+// FailurePolicy defines what happens to a task once it reached its limit of
+// consecutive failures (see OptionStopOnFailure).
+type FailurePolicy int
+
+const (
+	// FailurePolicyDisable disables the task; it has to be enabled before it can be started again
+	FailurePolicyDisable FailurePolicy = iota
+	// FailurePolicyStop stops the task; it can be started again
+	FailurePolicyStop
+	// FailurePolicyAlert emits an alert and keeps the task running
+	FailurePolicyAlert
+	// FailurePolicyRestart emits an alert and resumes the task after a cooldown
+	FailurePolicyRestart
+)
+
+var (
+	FailurePolicyLookup = map[FailurePolicy]string{
+		FailurePolicyDisable: "disable",
+		FailurePolicyStop:    "stop",
+		FailurePolicyAlert:   "alert-and-continue",
+		FailurePolicyRestart: "restart-after-cooldown",
+	}
+
+	// ErrUnknownFailurePolicy - The error message for an unknown failure policy
+	ErrUnknownFailurePolicy = errors.New("Unknown failure policy, expected 'disable', 'stop', 'alert-and-continue' or 'restart-after-cooldown'")
+	// ErrInvalidTaskLabel - The error message for a task label with an empty key or a key holding '='
+	ErrInvalidTaskLabel = errors.New("Invalid task label")
+)
+
+func (p FailurePolicy) String() string {
+	return FailurePolicyLookup[p]
+}
+
+// ParseFailurePolicy returns the failure policy with the given name.
+func ParseFailurePolicy(name string) (FailurePolicy, error) {
+	for p, n := range FailurePolicyLookup {
+		if n == name {
+			return p, nil
+		}
+	}
+	return FailurePolicyDisable, ErrUnknownFailurePolicy
+}
+
 type TaskWatcherCloser interface {
 	Close() error
 }
@@ -73,6 +122,13 @@ func (t TaskState) String() string {
 	return TaskStateLookup[t]
 }
 
+// TaskStateTransition records when and why a task entered a state.
+type TaskStateTransition struct {
+	Time   time.Time `json:"time"`
+	State  TaskState `json:"state"`
+	Reason string    `json:"reason"`
+}
+
 type Task interface {
 	ID() string
 	// Status() WorkflowState TODO, switch to string
@@ -95,6 +151,30 @@ type Task interface {
 	MaxMetricsBuffer() int64
 	SetMaxMetricsBuffer(int64)
 	GetStopOnFailure() int
+	SetFailurePolicy(FailurePolicy)
+	GetFailurePolicy() FailurePolicy
+	SetFailureCooldown(time.Duration)
+	GetFailureCooldown() time.Duration
+	SetSingleton(bool)
+	IsSingleton() bool
+	SetDedicatedQueue(name string, size int)
+	GetDedicatedQueue() (string, int)
+	SetPinnedThread(*TaskPinnedThread)
+	GetPinnedThread() *TaskPinnedThread
+	SetPublishRateLimit(*TaskPublishRateLimit)
+	GetPublishRateLimit() *TaskPublishRateLimit
+	SetCardinalityLimit(*TaskCardinalityLimit)
+	GetCardinalityLimit() *TaskCardinalityLimit
+	SetBlackouts([]BlackoutPeriod)
+	GetBlackouts() []BlackoutPeriod
+	BlackedOutCount() uint
+	SetOwner(*TaskOwner)
+	GetOwner() *TaskOwner
+	SetTimeouts(TaskTimeouts)
+	GetTimeouts() TaskTimeouts
+	SetLabels(map[string]string)
+	GetLabels() map[string]string
+	StateHistory() []TaskStateTransition
 	Option(...TaskOption) TaskOption
 	WMap() *wmap.WorkflowMap
 	Schedule() schedule.Schedule
@@ -139,6 +219,203 @@ func OptionStopOnFailure(v int) TaskOption {
 	}
 }
 
+// OptionFailurePolicy sets what happens to the task once it reached its
+// limit of consecutive failures.  The default policy disables the task.
+func OptionFailurePolicy(p FailurePolicy) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.GetFailurePolicy()
+		t.SetFailurePolicy(p)
+		log.WithFields(log.Fields{
+			"_module":        "core",
+			"_block":         "OptionFailurePolicy",
+			"task-id":        t.ID(),
+			"task-name":      t.GetName(),
+			"failure policy": t.GetFailurePolicy().String(),
+		}).Debug("Setting failure policy for task")
+		return OptionFailurePolicy(previous)
+	}
+}
+
+// OptionFailureCooldown sets how long a task with the restart-after-cooldown
+// failure policy waits before it resumes.
+func OptionFailureCooldown(d time.Duration) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.GetFailureCooldown()
+		t.SetFailureCooldown(d)
+		return OptionFailureCooldown(previous)
+	}
+}
+
+// OptionSingleton marks a task which is fired by a single member of a tribe
+// agreement at a time.  The other members keep the task running on standby
+// and one of them takes over when the member firing it is lost.
+func OptionSingleton(b bool) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.IsSingleton()
+		t.SetSingleton(b)
+		return OptionSingleton(previous)
+	}
+}
+
+// DedicatedQueue runs the jobs of a task on the dedicated worker queue with
+// the given name instead of the queues shared by all tasks.  Tasks naming the
+// same queue share it.  Size is the number of workers of each of the collect,
+// process and publish pools of the queue; 0 uses the size of the shared pools.
+func DedicatedQueue(name string, size int) TaskOption {
+	return func(t Task) TaskOption {
+		previousName, previousSize := t.GetDedicatedQueue()
+		t.SetDedicatedQueue(name, size)
+		log.WithFields(log.Fields{
+			"_module":    "core",
+			"_block":     "DedicatedQueue",
+			"task-id":    t.ID(),
+			"task-name":  t.GetName(),
+			"queue-name": name,
+			"queue-size": size,
+		}).Debug("Setting dedicated queue for task")
+		return DedicatedQueue(previousName, previousSize)
+	}
+}
+
+// OptionPinnedThread runs the collect jobs of a task on a dedicated goroutine
+// locked to its OS thread, bound to the given CPUs on Linux, instead of the
+// collect workers shared with other tasks.  A nil value uses the workers.
+func OptionPinnedThread(p *TaskPinnedThread) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.GetPinnedThread()
+		t.SetPinnedThread(p)
+		if p != nil {
+			log.WithFields(log.Fields{
+				"_module":   "core",
+				"_block":    "OptionPinnedThread",
+				"task-id":   t.ID(),
+				"task-name": t.GetName(),
+				"cpus":      p.CPUs,
+			}).Debug("Setting pinned thread for task")
+		}
+		return OptionPinnedThread(previous)
+	}
+}
+
+// OptionPublishRateLimit caps the metrics published by a task per second.
+// A nil value publishes the metrics without limit.
+func OptionPublishRateLimit(l *TaskPublishRateLimit) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.GetPublishRateLimit()
+		t.SetPublishRateLimit(l)
+		if l != nil {
+			log.WithFields(log.Fields{
+				"_module":            "core",
+				"_block":             "OptionPublishRateLimit",
+				"task-id":            t.ID(),
+				"task-name":          t.GetName(),
+				"metrics-per-second": l.MetricsPerSecond,
+				"policy":             l.Policy,
+			}).Debug("Setting publish rate limit for task")
+		}
+		return OptionPublishRateLimit(previous)
+	}
+}
+
+// OptionCardinalityLimit caps the unique metric namespaces a task produces
+// per run.  A nil value does not limit them.
+func OptionCardinalityLimit(l *TaskCardinalityLimit) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.GetCardinalityLimit()
+		t.SetCardinalityLimit(l)
+		if l != nil {
+			log.WithFields(log.Fields{
+				"_module":        "core",
+				"_block":         "OptionCardinalityLimit",
+				"task-id":        t.ID(),
+				"task-name":      t.GetName(),
+				"max-namespaces": l.MaxNamespaces,
+				"mode":           l.Mode,
+			}).Debug("Setting cardinality limit for task")
+		}
+		return OptionCardinalityLimit(previous)
+	}
+}
+
+// OptionBlackouts sets the blackout calendar of a task: the periods during
+// which its fires are suppressed.
+func OptionBlackouts(periods []BlackoutPeriod) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.GetBlackouts()
+		t.SetBlackouts(periods)
+		if len(periods) > 0 {
+			log.WithFields(log.Fields{
+				"_module":   "core",
+				"_block":    "OptionBlackouts",
+				"task-id":   t.ID(),
+				"task-name": t.GetName(),
+				"periods":   len(periods),
+			}).Debug("Setting blackout calendar for task")
+		}
+		return OptionBlackouts(previous)
+	}
+}
+
+// OptionOwner sets the caller who created a task and the tenant the task
+// belongs to.  A nil value leaves the task to no tenant.
+func OptionOwner(o *TaskOwner) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.GetOwner()
+		t.SetOwner(o)
+		if o != nil {
+			log.WithFields(log.Fields{
+				"_module":   "core",
+				"_block":    "OptionOwner",
+				"task-id":   t.ID(),
+				"task-name": t.GetName(),
+				"owner":     o.Name,
+				"tenant":    o.Tenant,
+			}).Debug("Setting owner for task")
+		}
+		return OptionOwner(previous)
+	}
+}
+
+// OptionTimeouts sets the timeouts of the collect, process and publish
+// phases of a task.  A zero timeout falls back to the default of the
+// scheduler, then to the deadline of the task.
+func OptionTimeouts(to TaskTimeouts) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.GetTimeouts()
+		t.SetTimeouts(to)
+		log.WithFields(log.Fields{
+			"_module":         "core",
+			"_block":          "OptionTimeouts",
+			"task-id":         t.ID(),
+			"task-name":       t.GetName(),
+			"collect-timeout": to.Collect,
+			"process-timeout": to.Process,
+			"publish-timeout": to.Publish,
+		}).Debug("Setting phase timeouts for task")
+		return OptionTimeouts(previous)
+	}
+}
+
+// OptionLabels sets the labels of a task, which select it in the listings
+// of the tasks.  Neither the labels nor the name of a task change once it is
+// created.
+func OptionLabels(l map[string]string) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.GetLabels()
+		t.SetLabels(l)
+		if len(l) > 0 {
+			log.WithFields(log.Fields{
+				"_module":   "core",
+				"_block":    "OptionLabels",
+				"task-id":   t.ID(),
+				"task-name": t.GetName(),
+				"labels":    l,
+			}).Debug("Setting labels for task")
+		}
+		return OptionLabels(previous)
+	}
+}
+
 // SetTaskName sets the name of the task.
 // This is optional.
 // If task name is not set, the task name is then defaulted to "Task-<task-id>"
@@ -174,20 +451,322 @@ func SetMaxCollectDuration(d time.Duration) TaskOption {
 	}
 }
 
+// TaskOverrides holds the settings of a cloned task which differ from the
+// task it was cloned from.  Zero values keep the setting of the original task.
+type TaskOverrides struct {
+	// Name of the cloned task; when empty a name is generated
+	Name string
+	// Interval replaces the interval of a windowed (simple) schedule
+	Interval time.Duration
+	// Start starts the cloned task once it is created
+	Start bool
+	// Options are applied after the options copied from the original task
+	Options []TaskOption
+}
+
 type TaskErrors interface {
 	Errors() []serror.SnapError
 }
 
 type TaskCreationRequest struct {
-	Name               string            `json:"name"`
-	Version            int               `json:"version"`
-	Deadline           string            `json:"deadline"`
-	Workflow           *wmap.WorkflowMap `json:"workflow"`
-	Schedule           *Schedule         `json:"schedule"`
-	Start              bool              `json:"start"`
-	MaxFailures        int               `json:"max-failures"`
-	MaxCollectDuration string            `json:"max-collect-duration"`
-	MaxMetricsBuffer   int64             `json:"max-metrics-buffer"`
+	Name               string                `json:"name"`
+	Version            int                   `json:"version"`
+	Deadline           string                `json:"deadline,omitempty"`
+	Workflow           *wmap.WorkflowMap     `json:"workflow"`
+	Schedule           *Schedule             `json:"schedule"`
+	Start              bool                  `json:"start"`
+	MaxFailures        int                   `json:"max-failures,omitempty"`
+	MaxCollectDuration string                `json:"max-collect-duration,omitempty"`
+	MaxMetricsBuffer   int64                 `json:"max-metrics-buffer,omitempty"`
+	FailurePolicy      string                `json:"failure-policy,omitempty"`
+	FailureCooldown    string                `json:"failure-cooldown,omitempty"`
+	Singleton          bool                  `json:"singleton,omitempty"`
+	Queue              *TaskQueue            `json:"queue,omitempty"`
+	PinnedThread       *TaskPinnedThread     `json:"pinned-thread,omitempty"`
+	PublishRateLimit   *TaskPublishRateLimit `json:"publish-rate-limit,omitempty"`
+	CardinalityLimit   *TaskCardinalityLimit `json:"cardinality-limit,omitempty"`
+	Blackouts          []BlackoutPeriod      `json:"blackouts,omitempty"`
+	CollectTimeout     string                `json:"collect-timeout,omitempty"`
+	ProcessTimeout     string                `json:"process-timeout,omitempty"`
+	PublishTimeout     string                `json:"publish-timeout,omitempty"`
+	Labels             map[string]string     `json:"labels,omitempty"`
+	// Preset names the task preset (see TaskPreset) whose options apply to
+	// the options which are not set in the request
+	Preset string `json:"preset,omitempty"`
+	// Template names the task template (see TaskTemplate) the task is
+	// created from, given the values of its variables
+	Template  string            `json:"template,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// TaskQueue names the dedicated worker queue of a task
+type TaskQueue struct {
+	Name string `json:"name"`
+	Size int    `json:"size,omitempty"`
+}
+
+// TaskPinnedThread runs the collect jobs of a task on a locked OS thread.
+// CPUs, when given, is the set of CPUs the thread is bound to (Linux only).
+type TaskPinnedThread struct {
+	CPUs []int `json:"cpus,omitempty"`
+}
+
+// TaskTimeouts bounds how long the jobs of each phase of a task workflow may
+// wait for a worker before they are refused.  Each phase gets its own
+// deadline, counted from the submission of its jobs, so that a slow
+// publisher does not get the collection of the task marked as missed.
+type TaskTimeouts struct {
+	Collect time.Duration
+	Process time.Duration
+	Publish time.Duration
+}
+
+// TaskResourceUsage holds the approximate resources used by the runs of a
+// task since it was created, so that the tasks loading a busy host can be
+// told apart.
+type TaskResourceUsage struct {
+	// WorkflowSeconds is the time the workers spent running the collect,
+	// process and publish jobs of the task
+	WorkflowSeconds float64 `json:"workflow_seconds"`
+	// MetricsProcessed is the number of metrics handled by these jobs and
+	// BytesProcessed an estimate of their size
+	MetricsProcessed uint64 `json:"metrics_processed"`
+	BytesProcessed   uint64 `json:"bytes_processed"`
+}
+
+// The policies applied to the metrics of a task beyond its publish rate limit
+const (
+	// PublishRateLimitDrop - The metrics beyond the limit are dropped
+	PublishRateLimitDrop = "drop"
+	// PublishRateLimitBuffer - The metrics beyond the limit are published later
+	PublishRateLimitBuffer = "buffer"
+)
+
+// TaskPublishRateLimit caps the number of metrics a task hands to its
+// publishers per second, over all its publish nodes.  The metrics beyond the
+// cap are dropped, or with the buffer policy kept until the rate allows
+// publishing them; MaxBuffered then bounds the metrics kept per publish node
+// (10 seconds worth of metrics by default), the oldest ones being dropped.
+type TaskPublishRateLimit struct {
+	MetricsPerSecond float64 `json:"metrics-per-second"`
+	Policy           string  `json:"policy,omitempty"`
+	MaxBuffered      int     `json:"max-buffered,omitempty"`
+}
+
+// The modes of a cardinality limit
+const (
+	// CardinalityWarn - The namespaces beyond the limit are reported
+	CardinalityWarn = "warn"
+	// CardinalityEnforce - The metrics of the namespaces beyond the limit are dropped
+	CardinalityEnforce = "enforce"
+)
+
+// TaskCardinalityLimit caps the number of unique metric namespaces a task
+// produces per run, guarding against the explosion of the namespaces
+// returned by a wildcard collector.  In the warn mode, the default, the runs
+// beyond the limit are logged; in the enforce mode the metrics of the
+// namespaces beyond it are dropped, the namespaces of the previous run being
+// kept first.
+type TaskCardinalityLimit struct {
+	MaxNamespaces int    `json:"max-namespaces"`
+	Mode          string `json:"mode,omitempty"`
+}
+
+// TaskOwner is the caller of the REST API who created a task and the tenant
+// the task belongs to.  The callers only see and change the tasks of their
+// tenant, unless they are admins, and the quotas of the scheduler apply per
+// tenant.  The owner is set from the identity of the caller, never from the
+// task manifest.
+type TaskOwner struct {
+	Name   string `json:"name"`
+	Tenant string `json:"tenant"`
+}
+
+// ValidateTaskLabels returns an error when a label of a task has an empty
+// key or a key holding '=', which separates the key from the value in the
+// label selectors
+func ValidateTaskLabels(labels map[string]string) error {
+	for k := range labels {
+		if k == "" || strings.Contains(k, "=") {
+			return fmt.Errorf("%v '%s'", ErrInvalidTaskLabel, k)
+		}
+	}
+	return nil
+}
+
+// TaskListOptions selects the tasks listed, by page of at most Limit tasks
+// when Limit is above 0.  The tasks are listed in the order of their ids,
+// from the first id after After.
+type TaskListOptions struct {
+	// Name selects the tasks of the name, when it is set
+	Name string
+	// Labels selects the tasks holding all the labels
+	Labels map[string]string
+	// Visible selects the tasks for which it returns true, when it is set;
+	// the tasks it leaves out are not counted in the pages
+	Visible func(Task) bool
+	After   string
+	Limit   int
+}
+
+// ParseTaskLabel parses a label selector of the form key=value
+func ParseTaskLabel(selector string) (string, string, error) {
+	i := strings.Index(selector, "=")
+	if i <= 0 {
+		return "", "", fmt.Errorf("%v '%s' (expected key=value)", ErrInvalidTaskLabel, selector)
+	}
+	return selector[:i], selector[i+1:], nil
+}
+
+// TenantOf returns the tenant of a task, empty when it has no owner
+func TenantOf(t Task) string {
+	if o := t.GetOwner(); o != nil {
+		return o.Tenant
+	}
+	return ""
+}
+
+// TaskAdmitter reviews a task creation request before the task is created.
+// The request may be modified in place; returning an error rejects it.
+type TaskAdmitter interface {
+	AdmitTask(tr *TaskCreationRequest) error
+}
+
+// TaskManifestVersion is the version of the task manifest schema
+const TaskManifestVersion = 1
+
+var (
+	// ErrUnknownManifestFormat - The error message for an unsupported task manifest format
+	ErrUnknownManifestFormat = errors.New("Unknown task manifest format, expected 'json' or 'yaml'")
+	// ErrMissingManifestVersion - The error message for a task manifest without a version
+	ErrMissingManifestVersion = errors.New("Task manifest must include a version")
+	// ErrUnsupportedManifestVersion - The error message for a task manifest of an unsupported version
+	ErrUnsupportedManifestVersion = errors.New("Unsupported task manifest version")
+	// ErrInvalidTaskManifest - The error message for a task manifest which cannot be parsed
+	ErrInvalidTaskManifest = errors.New("Invalid task manifest")
+)
+
+// TaskManifest returns a task creation request which recreates the given task
+// with the same workflow, schedule and options.  The ID, state and run
+// statistics of the task are not part of the manifest so a task created from
+// it gets a new ID.
+func TaskManifest(t Task) (*TaskCreationRequest, error) {
+	sch, err := ScheduleFromSchedule(t.Schedule())
+	if err != nil {
+		return nil, err
+	}
+	tr := &TaskCreationRequest{
+		Name:             t.GetName(),
+		Version:          TaskManifestVersion,
+		Deadline:         t.DeadlineDuration().String(),
+		Workflow:         t.WMap(),
+		Schedule:         sch,
+		MaxFailures:      t.GetStopOnFailure(),
+		MaxMetricsBuffer: t.MaxMetricsBuffer(),
+		Singleton:        t.IsSingleton(),
+	}
+	if t.MaxCollectDuration() > 0 {
+		tr.MaxCollectDuration = t.MaxCollectDuration().String()
+	}
+	if t.GetFailurePolicy() != FailurePolicyDisable {
+		tr.FailurePolicy = t.GetFailurePolicy().String()
+	}
+	if t.GetFailureCooldown() > 0 {
+		tr.FailureCooldown = t.GetFailureCooldown().String()
+	}
+	if name, size := t.GetDedicatedQueue(); name != "" {
+		tr.Queue = &TaskQueue{Name: name, Size: size}
+	}
+	tr.PinnedThread = t.GetPinnedThread()
+	tr.PublishRateLimit = t.GetPublishRateLimit()
+	tr.CardinalityLimit = t.GetCardinalityLimit()
+	tr.Blackouts = t.GetBlackouts()
+	to := t.GetTimeouts()
+	if to.Collect > 0 {
+		tr.CollectTimeout = to.Collect.String()
+	}
+	if to.Process > 0 {
+		tr.ProcessTimeout = to.Process.String()
+	}
+	if to.Publish > 0 {
+		tr.PublishTimeout = to.Publish.String()
+	}
+	tr.Labels = t.GetLabels()
+	return tr, nil
+}
+
+// ExportTask returns the manifest of the given task (see TaskManifest)
+// encoded in the given format, either "json" or "yaml".
+func ExportTask(t Task, format string) ([]byte, error) {
+	tr, err := TaskManifest(t)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "json", "":
+		return json.MarshalIndent(tr, "", "  ")
+	case "yaml", "yml":
+		return yaml.Marshal(tr)
+	default:
+		return nil, ErrUnknownManifestFormat
+	}
+}
+
+// ImportTask creates a task from a JSON or YAML manifest produced by
+// ExportTask.  The task is created through the given function pointer in the
+// same way as CreateTaskFromContent.
+func ImportTask(content []byte,
+	mode *bool,
+	fp func(sch schedule.Schedule,
+		wfMap *wmap.WorkflowMap,
+		startOnCreate bool,
+		opts ...TaskOption) (Task, TaskErrors),
+	admitters ...TaskAdmitter) (Task, error) {
+	// JSON is a subset of YAML so both formats are handled by the conversion
+	js, err := yaml.YAMLToJSON(content)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", ErrInvalidTaskManifest, err)
+	}
+	tr, err := createTaskRequest(ioutil.NopCloser(bytes.NewReader(js)))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", ErrInvalidTaskManifest, err)
+	}
+	return createTaskFromRequest(tr, mode, fp, admitters...)
+}
+
+// CreateTaskFromManifest creates a task from a JSON or YAML task manifest
+// holding the schedule, the workflow and the options of the task in one
+// document.  Unlike CreateTaskFromContent the manifest must include the
+// version of its schema, TaskManifestVersion.  The task is created through
+// the given function pointer in the same way as CreateTaskFromContent.
+func CreateTaskFromManifest(r io.Reader,
+	mode *bool,
+	fp func(sch schedule.Schedule,
+		wfMap *wmap.WorkflowMap,
+		startOnCreate bool,
+		opts ...TaskOption) (Task, TaskErrors),
+	admitters ...TaskAdmitter) (Task, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is a subset of YAML so both formats are handled by the conversion
+	js, err := yaml.YAMLToJSON(content)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", ErrInvalidTaskManifest, err)
+	}
+	tr, err := createTaskRequest(ioutil.NopCloser(bytes.NewReader(js)))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", ErrInvalidTaskManifest, err)
+	}
+	switch tr.Version {
+	case TaskManifestVersion:
+	case 0:
+		return nil, ErrMissingManifestVersion
+	default:
+		return nil, fmt.Errorf("%v %d (expected %d)", ErrUnsupportedManifestVersion, tr.Version, TaskManifestVersion)
+	}
+	return createTaskFromRequest(tr, mode, fp, admitters...)
 }
 
 func (tr *TaskCreationRequest) UnmarshalJSON(data []byte) error {
@@ -206,6 +785,10 @@ func (tr *TaskCreationRequest) UnmarshalJSON(data []byte) error {
 				return fmt.Errorf("%v (while parsing 'deadline')", err)
 			}
 		case "workflow":
+			// report every problem of the workflow with the field it is in
+			if err := wmap.Validate([]byte(v)); err != nil {
+				return err
+			}
 			if err := json.Unmarshal(v, &(tr.Workflow)); err != nil {
 				return err
 			}
@@ -233,6 +816,66 @@ func (tr *TaskCreationRequest) UnmarshalJSON(data []byte) error {
 			if err := json.Unmarshal(v, &(tr.MaxMetricsBuffer)); err != nil {
 				return fmt.Errorf("%v (while parsing 'max-metrics-buffer')", err)
 			}
+		case "failure-policy":
+			if err := json.Unmarshal(v, &(tr.FailurePolicy)); err != nil {
+				return fmt.Errorf("%v (while parsing 'failure-policy')", err)
+			}
+		case "failure-cooldown":
+			if err := json.Unmarshal(v, &(tr.FailureCooldown)); err != nil {
+				return fmt.Errorf("%v (while parsing 'failure-cooldown')", err)
+			}
+		case "singleton":
+			if err := json.Unmarshal(v, &(tr.Singleton)); err != nil {
+				return fmt.Errorf("%v (while parsing 'singleton')", err)
+			}
+		case "queue":
+			if err := json.Unmarshal(v, &(tr.Queue)); err != nil {
+				return fmt.Errorf("%v (while parsing 'queue')", err)
+			}
+		case "pinned-thread":
+			if err := json.Unmarshal(v, &(tr.PinnedThread)); err != nil {
+				return fmt.Errorf("%v (while parsing 'pinned-thread')", err)
+			}
+		case "publish-rate-limit":
+			if err := json.Unmarshal(v, &(tr.PublishRateLimit)); err != nil {
+				return fmt.Errorf("%v (while parsing 'publish-rate-limit')", err)
+			}
+		case "cardinality-limit":
+			if err := json.Unmarshal(v, &(tr.CardinalityLimit)); err != nil {
+				return fmt.Errorf("%v (while parsing 'cardinality-limit')", err)
+			}
+		case "blackouts":
+			if err := json.Unmarshal(v, &(tr.Blackouts)); err != nil {
+				return fmt.Errorf("%v (while parsing 'blackouts')", err)
+			}
+		case "collect-timeout":
+			if err := json.Unmarshal(v, &(tr.CollectTimeout)); err != nil {
+				return fmt.Errorf("%v (while parsing 'collect-timeout')", err)
+			}
+		case "process-timeout":
+			if err := json.Unmarshal(v, &(tr.ProcessTimeout)); err != nil {
+				return fmt.Errorf("%v (while parsing 'process-timeout')", err)
+			}
+		case "publish-timeout":
+			if err := json.Unmarshal(v, &(tr.PublishTimeout)); err != nil {
+				return fmt.Errorf("%v (while parsing 'publish-timeout')", err)
+			}
+		case "labels":
+			if err := json.Unmarshal(v, &(tr.Labels)); err != nil {
+				return fmt.Errorf("%v (while parsing 'labels')", err)
+			}
+		case "preset":
+			if err := json.Unmarshal(v, &(tr.Preset)); err != nil {
+				return fmt.Errorf("%v (while parsing 'preset')", err)
+			}
+		case "template":
+			if err := json.Unmarshal(v, &(tr.Template)); err != nil {
+				return fmt.Errorf("%v (while parsing 'template')", err)
+			}
+		case "variables":
+			if err := json.Unmarshal(v, &(tr.Variables)); err != nil {
+				return fmt.Errorf("%v (while parsing 'variables')", err)
+			}
 		default:
 			return fmt.Errorf("Unrecognized key '%v' in task creation request", k)
 		}
@@ -244,17 +887,36 @@ func (tr *TaskCreationRequest) UnmarshalJSON(data []byte) error {
 // . Content can be retrieved from a configuration file or a HTTP REST request body
 // . Mode is used to specify if the created task should start right away or not
 // . function pointer is responsible for effectively creating and returning the created task
+// . admitters, if any, review (and may modify or reject) the request before it is validated
 func CreateTaskFromContent(body io.ReadCloser,
 	mode *bool,
 	fp func(sch schedule.Schedule,
 		wfMap *wmap.WorkflowMap,
 		startOnCreate bool,
-		opts ...TaskOption) (Task, TaskErrors)) (Task, error) {
+		opts ...TaskOption) (Task, TaskErrors),
+	admitters ...TaskAdmitter) (Task, error) {
 
 	tr, err := createTaskRequest(body)
 	if err != nil {
 		return nil, err
 	}
+	return createTaskFromRequest(tr, mode, fp, admitters...)
+}
+
+// createTaskFromRequest creates the task of a parsed task creation request
+func createTaskFromRequest(tr *TaskCreationRequest,
+	mode *bool,
+	fp func(sch schedule.Schedule,
+		wfMap *wmap.WorkflowMap,
+		startOnCreate bool,
+		opts ...TaskOption) (Task, TaskErrors),
+	admitters ...TaskAdmitter) (Task, error) {
+
+	for _, a := range admitters {
+		if err := a.AdmitTask(tr); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := validateTaskRequest(tr); err != nil {
 		return nil, err
@@ -265,6 +927,39 @@ func CreateTaskFromContent(body io.ReadCloser,
 		return nil, err
 	}
 
+	opts, err := tr.options()
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == nil {
+		mode = &tr.Start
+	}
+
+	if fp == nil {
+		return nil, errors.New("Missing workflow creation routine")
+	}
+	task, errs := fp(sch, tr.Workflow, *mode, opts...)
+	if errs != nil && len(errs.Errors()) != 0 {
+		var errMsg string
+		for _, e := range errs.Errors() {
+			errMsg = errMsg + e.Error() + " -- "
+
+			log.WithFields(log.Fields{
+				"_file":     "core/task.go",
+				"_function": "CreateTaskFromContent",
+				"_error":    e.Error(),
+				"_fields":   e.Fields(),
+			}).Error("error creating task")
+		}
+
+		return nil, errors.New(errMsg[:len(errMsg)-4])
+	}
+	return task, nil
+}
+
+// options returns the task options set by the request
+func (tr *TaskCreationRequest) options() ([]TaskOption, error) {
 	var opts []TaskOption
 	if tr.Deadline != "" {
 		dl, err := time.ParseDuration(tr.Deadline)
@@ -284,10 +979,6 @@ func CreateTaskFromContent(body io.ReadCloser,
 		opts = append(opts, OptionStopOnFailure(tr.MaxFailures))
 	}
 
-	if mode == nil {
-		mode = &tr.Start
-	}
-
 	if tr.MaxMetricsBuffer != 0 {
 		opts = append(opts, SetMaxMetricsBuffer(tr.MaxMetricsBuffer))
 	}
@@ -300,26 +991,82 @@ func CreateTaskFromContent(body io.ReadCloser,
 		opts = append(opts, SetMaxCollectDuration(dl))
 	}
 
-	if fp == nil {
-		return nil, errors.New("Missing workflow creation routine")
+	if tr.FailurePolicy != "" {
+		p, err := ParseFailurePolicy(tr.FailurePolicy)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, OptionFailurePolicy(p))
 	}
-	task, errs := fp(sch, tr.Workflow, *mode, opts...)
-	if errs != nil && len(errs.Errors()) != 0 {
-		var errMsg string
-		for _, e := range errs.Errors() {
-			errMsg = errMsg + e.Error() + " -- "
 
-			log.WithFields(log.Fields{
-				"_file":     "core/task.go",
-				"_function": "CreateTaskFromContent",
-				"_error":    e.Error(),
-				"_fields":   e.Fields(),
-			}).Error("error creating task")
+	if tr.FailureCooldown != "" {
+		cd, err := time.ParseDuration(tr.FailureCooldown)
+		if err != nil {
+			return nil, err
 		}
+		opts = append(opts, OptionFailureCooldown(cd))
+	}
 
-		return nil, errors.New(errMsg[:len(errMsg)-4])
+	if tr.Singleton {
+		opts = append(opts, OptionSingleton(true))
 	}
-	return task, nil
+
+	if tr.Queue != nil && tr.Queue.Name != "" {
+		opts = append(opts, DedicatedQueue(tr.Queue.Name, tr.Queue.Size))
+	}
+
+	if tr.PinnedThread != nil {
+		opts = append(opts, OptionPinnedThread(tr.PinnedThread))
+	}
+
+	if tr.PublishRateLimit != nil {
+		opts = append(opts, OptionPublishRateLimit(tr.PublishRateLimit))
+	}
+
+	if tr.CardinalityLimit != nil {
+		opts = append(opts, OptionCardinalityLimit(tr.CardinalityLimit))
+	}
+
+	if len(tr.Blackouts) > 0 {
+		if err := ValidateBlackouts(tr.Blackouts); err != nil {
+			return nil, err
+		}
+		opts = append(opts, OptionBlackouts(tr.Blackouts))
+	}
+
+	if tr.CollectTimeout != "" || tr.ProcessTimeout != "" || tr.PublishTimeout != "" {
+		var to TaskTimeouts
+		for _, t := range []struct {
+			name  string
+			value string
+			d     *time.Duration
+		}{
+			{"collect-timeout", tr.CollectTimeout, &to.Collect},
+			{"process-timeout", tr.ProcessTimeout, &to.Process},
+			{"publish-timeout", tr.PublishTimeout, &to.Publish},
+		} {
+			if t.value == "" {
+				continue
+			}
+			d, err := time.ParseDuration(t.value)
+			if err != nil {
+				return nil, err
+			}
+			if d < 0 {
+				return nil, fmt.Errorf("Invalid %s '%s' (expected a positive duration)", t.name, t.value)
+			}
+			*t.d = d
+		}
+		opts = append(opts, OptionTimeouts(to))
+	}
+
+	if len(tr.Labels) > 0 {
+		if err := ValidateTaskLabels(tr.Labels); err != nil {
+			return nil, err
+		}
+		opts = append(opts, OptionLabels(tr.Labels))
+	}
+	return opts, nil
 }
 
 func createTaskRequest(body io.ReadCloser) (*TaskCreationRequest, error) {
@@ -344,11 +1091,20 @@ func UnmarshalBody(in interface{}, body io.ReadCloser) (int, error) {
 }
 
 func validateTaskRequest(tr *TaskCreationRequest) error {
-	if tr.Schedule == nil || *tr.Schedule == (Schedule{}) {
+	// a preset is expanded and cleared by the TaskPresets admitter
+	if tr.Preset != "" {
+		return fmt.Errorf("%v '%s'", ErrUnknownTaskPreset, tr.Preset)
+	}
+	// and so is a template by the task manager holding the templates
+	if tr.Template != "" {
+		return fmt.Errorf("%v '%s'", ErrUnknownTaskTemplate, tr.Template)
+	}
+
+	if tr.Schedule == nil || reflect.DeepEqual(*tr.Schedule, Schedule{}) {
 		return fmt.Errorf("Task must include a schedule, and the schedule must not be empty")
 	}
 
-	if tr.Workflow == nil || *tr.Workflow == (wmap.WorkflowMap{}) {
+	if tr.Workflow == nil || tr.Workflow.Collect == nil {
 		return fmt.Errorf("Task must include a workflow, and the workflow must not be empty")
 	}
 	return nil
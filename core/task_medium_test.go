@@ -22,6 +22,7 @@ limitations under the License.
 package core
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -306,3 +307,12 @@ func TestCreateTaskFromContent(t *testing.T) {
 		})
 	})
 }
+
+func TestTaskRequestLabels(t *testing.T) {
+	Convey("The labels of a task creation request are parsed", t, func() {
+		var tr TaskCreationRequest
+		err := json.Unmarshal([]byte(`{"name": "t", "labels": {"team": "storage"}}`), &tr)
+		So(err, ShouldBeNil)
+		So(tr.Labels, ShouldResemble, map[string]string{"team": "storage"})
+	})
+}
@@ -0,0 +1,188 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrUnknownTaskPreset - The error message for a task manifest referring to a preset which is not registered
+	ErrUnknownTaskPreset = errors.New("Unknown task preset")
+)
+
+// TaskPreset is a named set of task options registered in the daemon
+// configuration.  A task manifest refers to it with its "preset" field
+// instead of repeating the options; the options set in the manifest itself
+// take precedence over the ones of the preset.
+type TaskPreset struct {
+	Deadline           string                `json:"deadline,omitempty"yaml:"deadline"`
+	MaxFailures        int                   `json:"max-failures,omitempty"yaml:"max-failures"`
+	MaxCollectDuration string                `json:"max-collect-duration,omitempty"yaml:"max-collect-duration"`
+	MaxMetricsBuffer   int64                 `json:"max-metrics-buffer,omitempty"yaml:"max-metrics-buffer"`
+	FailurePolicy      string                `json:"failure-policy,omitempty"yaml:"failure-policy"`
+	FailureCooldown    string                `json:"failure-cooldown,omitempty"yaml:"failure-cooldown"`
+	Singleton          bool                  `json:"singleton,omitempty"yaml:"singleton"`
+	Queue              *TaskQueue            `json:"queue,omitempty"yaml:"queue"`
+	PinnedThread       *TaskPinnedThread     `json:"pinned-thread,omitempty"yaml:"pinned-thread"`
+	PublishRateLimit   *TaskPublishRateLimit `json:"publish-rate-limit,omitempty"yaml:"publish-rate-limit"`
+	CardinalityLimit   *TaskCardinalityLimit `json:"cardinality-limit,omitempty"yaml:"cardinality-limit"`
+	Blackouts          []BlackoutPeriod      `json:"blackouts,omitempty"yaml:"blackouts"`
+	CollectTimeout     string                `json:"collect-timeout,omitempty"yaml:"collect-timeout"`
+	ProcessTimeout     string                `json:"process-timeout,omitempty"yaml:"process-timeout"`
+	PublishTimeout     string                `json:"publish-timeout,omitempty"yaml:"publish-timeout"`
+	Labels             map[string]string     `json:"labels,omitempty"yaml:"labels"`
+}
+
+// Validate returns an error when one of the options of the preset is invalid
+func (p *TaskPreset) Validate() error {
+	for _, d := range []struct{ name, value string }{
+		{"deadline", p.Deadline},
+		{"max-collect-duration", p.MaxCollectDuration},
+		{"failure-cooldown", p.FailureCooldown},
+		{"collect-timeout", p.CollectTimeout},
+		{"process-timeout", p.ProcessTimeout},
+		{"publish-timeout", p.PublishTimeout},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d.value); err != nil {
+			return fmt.Errorf("%v (while parsing '%s')", err, d.name)
+		}
+	}
+	if p.FailurePolicy != "" {
+		if _, err := ParseFailurePolicy(p.FailurePolicy); err != nil {
+			return err
+		}
+	}
+	if err := ValidateBlackouts(p.Blackouts); err != nil {
+		return err
+	}
+	return ValidateTaskLabels(p.Labels)
+}
+
+// Options returns the task options setting the options of the preset, e.g.
+// to apply them to the tasks which do not set their own
+func (p *TaskPreset) Options() ([]TaskOption, error) {
+	tr := &TaskCreationRequest{}
+	p.apply(tr)
+	return tr.options()
+}
+
+// apply sets the options of the preset which are not set in the request
+func (p *TaskPreset) apply(tr *TaskCreationRequest) {
+	if tr.Deadline == "" {
+		tr.Deadline = p.Deadline
+	}
+	if tr.MaxFailures == 0 {
+		tr.MaxFailures = p.MaxFailures
+	}
+	if tr.MaxCollectDuration == "" {
+		tr.MaxCollectDuration = p.MaxCollectDuration
+	}
+	if tr.MaxMetricsBuffer == 0 {
+		tr.MaxMetricsBuffer = p.MaxMetricsBuffer
+	}
+	if tr.FailurePolicy == "" {
+		tr.FailurePolicy = p.FailurePolicy
+	}
+	if tr.FailureCooldown == "" {
+		tr.FailureCooldown = p.FailureCooldown
+	}
+	if !tr.Singleton {
+		tr.Singleton = p.Singleton
+	}
+	if tr.Queue == nil && p.Queue != nil {
+		q := *p.Queue
+		tr.Queue = &q
+	}
+	if tr.PinnedThread == nil && p.PinnedThread != nil {
+		pt := TaskPinnedThread{CPUs: append([]int{}, p.PinnedThread.CPUs...)}
+		tr.PinnedThread = &pt
+	}
+	if tr.PublishRateLimit == nil && p.PublishRateLimit != nil {
+		l := *p.PublishRateLimit
+		tr.PublishRateLimit = &l
+	}
+	if tr.CardinalityLimit == nil && p.CardinalityLimit != nil {
+		l := *p.CardinalityLimit
+		tr.CardinalityLimit = &l
+	}
+	if len(tr.Blackouts) == 0 && len(p.Blackouts) > 0 {
+		tr.Blackouts = append([]BlackoutPeriod{}, p.Blackouts...)
+	}
+	if tr.CollectTimeout == "" {
+		tr.CollectTimeout = p.CollectTimeout
+	}
+	if tr.ProcessTimeout == "" {
+		tr.ProcessTimeout = p.ProcessTimeout
+	}
+	if tr.PublishTimeout == "" {
+		tr.PublishTimeout = p.PublishTimeout
+	}
+	// the labels of the request are added to the ones of the preset
+	if len(p.Labels) > 0 {
+		labels := make(map[string]string, len(p.Labels)+len(tr.Labels))
+		for k, v := range p.Labels {
+			labels[k] = v
+		}
+		for k, v := range tr.Labels {
+			labels[k] = v
+		}
+		tr.Labels = labels
+	}
+}
+
+// TaskPresets holds the task presets by name.  It implements TaskAdmitter
+// to expand the preset a task creation request refers to.
+type TaskPresets map[string]*TaskPreset
+
+// Validate returns an error when one of the presets is invalid
+func (ps TaskPresets) Validate() error {
+	for name, p := range ps {
+		if p == nil {
+			continue
+		}
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("%v (in task preset '%s')", err, name)
+		}
+	}
+	return nil
+}
+
+// AdmitTask sets the options of the preset the request refers to, if any.
+// The preset is cleared from the request once expanded.
+func (ps TaskPresets) AdmitTask(tr *TaskCreationRequest) error {
+	if tr.Preset == "" {
+		return nil
+	}
+	p, ok := ps[tr.Preset]
+	if !ok {
+		return fmt.Errorf("%v '%s'", ErrUnknownTaskPreset, tr.Preset)
+	}
+	if p != nil {
+		p.apply(tr)
+	}
+	tr.Preset = ""
+	return nil
+}
@@ -0,0 +1,111 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTaskPresets(t *testing.T) {
+	presets := TaskPresets{
+		"critical": &TaskPreset{
+			Deadline:        "1s",
+			MaxFailures:     3,
+			FailurePolicy:   "restart-after-cooldown",
+			FailureCooldown: "30s",
+			Queue:           &TaskQueue{Name: "critical", Size: 10},
+		},
+		"bulk": &TaskPreset{
+			Deadline:    "1m",
+			MaxFailures: -1,
+			Labels:      map[string]string{"class": "bulk", "team": "ops"},
+		},
+	}
+	Convey("Given task presets", t, func() {
+		So(presets.Validate(), ShouldBeNil)
+
+		Convey("a request referring to a preset gets its options", func() {
+			tr := &TaskCreationRequest{Preset: "critical"}
+			So(presets.AdmitTask(tr), ShouldBeNil)
+			So(tr.Preset, ShouldBeEmpty)
+			So(tr.Deadline, ShouldEqual, "1s")
+			So(tr.MaxFailures, ShouldEqual, 3)
+			So(tr.FailurePolicy, ShouldEqual, "restart-after-cooldown")
+			So(tr.FailureCooldown, ShouldEqual, "30s")
+			So(tr.Queue, ShouldResemble, &TaskQueue{Name: "critical", Size: 10})
+			Convey("without sharing the queue of the preset", func() {
+				tr.Queue.Size = 1
+				So(presets["critical"].Queue.Size, ShouldEqual, 10)
+			})
+		})
+		Convey("the options set in the request take precedence", func() {
+			tr := &TaskCreationRequest{Preset: "bulk", Deadline: "5m"}
+			So(presets.AdmitTask(tr), ShouldBeNil)
+			So(tr.Deadline, ShouldEqual, "5m")
+			So(tr.MaxFailures, ShouldEqual, -1)
+		})
+		Convey("the labels of the request are added to the ones of the preset", func() {
+			tr := &TaskCreationRequest{Preset: "bulk", Labels: map[string]string{"team": "storage"}}
+			So(presets.AdmitTask(tr), ShouldBeNil)
+			So(tr.Labels, ShouldResemble, map[string]string{"class": "bulk", "team": "storage"})
+			So(presets["bulk"].Labels["team"], ShouldEqual, "ops")
+		})
+		Convey("the options of a preset are turned into task options", func() {
+			opts, err := presets["critical"].Options()
+			So(err, ShouldBeNil)
+			// deadline, max-failures, failure-policy, failure-cooldown and queue
+			So(opts, ShouldHaveLength, 5)
+			opts, err = (&TaskPreset{}).Options()
+			So(err, ShouldBeNil)
+			So(opts, ShouldBeEmpty)
+		})
+		Convey("a request without preset is left unchanged", func() {
+			tr := &TaskCreationRequest{Deadline: "5m"}
+			So(presets.AdmitTask(tr), ShouldBeNil)
+			So(*tr, ShouldResemble, TaskCreationRequest{Deadline: "5m"})
+		})
+		Convey("a request referring to an unknown preset is rejected", func() {
+			tr := &TaskCreationRequest{Preset: "unknown"}
+			err := presets.AdmitTask(tr)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, ErrUnknownTaskPreset.Error())
+		})
+	})
+	Convey("A preset with an invalid option is invalid", t, func() {
+		So(TaskPresets{"bad": &TaskPreset{Deadline: "soon"}}.Validate(), ShouldNotBeNil)
+		So(TaskPresets{"bad": &TaskPreset{FailurePolicy: "retry"}}.Validate(), ShouldNotBeNil)
+		So(TaskPresets{"bad": &TaskPreset{Labels: map[string]string{"a=b": "c"}}}.Validate(), ShouldNotBeNil)
+	})
+	Convey("The preset of a manifest", t, func() {
+		var tr TaskCreationRequest
+		So(json.Unmarshal([]byte(`{"version": 1, "preset": "bulk"}`), &tr), ShouldBeNil)
+		So(tr.Preset, ShouldEqual, "bulk")
+		Convey("is rejected when no presets expand it", func() {
+			err := validateTaskRequest(&tr)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, ErrUnknownTaskPreset.Error())
+		})
+	})
+}
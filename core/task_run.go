@@ -0,0 +1,47 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import "context"
+
+// TaskRun describes the run of a task.  It is carried by the context of the
+// run, which is handed to the metric manager and the plugins.
+type TaskRun struct {
+	TaskID   string
+	TaskName string
+	// Run is the number of the run since the task was created, from 1; it
+	// is 0 when the metrics are not handled by a run, e.g. when a backfill
+	// collects them or a stopping task flushes them
+	Run uint
+}
+
+type taskRunKey struct{}
+
+// NewTaskRunContext returns a copy of the context carrying the run of a task.
+func NewTaskRunContext(ctx context.Context, r TaskRun) context.Context {
+	return context.WithValue(ctx, taskRunKey{}, r)
+}
+
+// TaskRunFromContext returns the run of a task carried by the context, if
+// any.
+func TaskRunFromContext(ctx context.Context) (TaskRun, bool) {
+	r, ok := ctx.Value(taskRunKey{}).(TaskRun)
+	return r, ok
+}
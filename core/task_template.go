@@ -0,0 +1,287 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var (
+	// ErrUnknownTaskTemplate - The error message for a task manifest referring to a template which is not registered
+	ErrUnknownTaskTemplate = errors.New("Unknown task template")
+	// ErrTaskTemplateExists - The error message for a task template registered under the name of another one
+	ErrTaskTemplateExists = errors.New("Task template already registered")
+	// ErrInvalidTaskTemplateName - The error message for a task template name which is empty or holds other characters than letters, digits, '.', '_' and '-'
+	ErrInvalidTaskTemplateName = errors.New("Invalid task template name")
+	// ErrInvalidTemplateVariable - The error message for a task template variable which is declared twice or has an invalid name
+	ErrInvalidTemplateVariable = errors.New("Invalid task template variable")
+	// ErrUndeclaredTemplateVariable - The error message for a task template referring to a variable it does not declare
+	ErrUndeclaredTemplateVariable = errors.New("Undeclared task template variable")
+	// ErrUnknownTemplateVariable - The error message for a value given to a variable the task template does not declare
+	ErrUnknownTemplateVariable = errors.New("Unknown task template variable")
+	// ErrMissingTemplateVariable - The error message for a required task template variable given no value
+	ErrMissingTemplateVariable = errors.New("Missing value of task template variable")
+	// ErrInvalidTemplateVariableValue - The error message for a value, or a default, which is not of the type of its task template variable
+	ErrInvalidTemplateVariableValue = errors.New("Invalid value of task template variable")
+	// ErrNestedTaskTemplate - The error message for a task template whose task refers to a template
+	ErrNestedTaskTemplate = errors.New("Task template must not refer to a template")
+)
+
+var (
+	taskTemplateName = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+	templateVariable = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	// templateReference matches the references to the variables in the
+	// strings of the task of a template, e.g. {{interval}}
+	templateReference = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_-]+)\s*\}\}`)
+	jsonNumber        = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+)
+
+const (
+	// StringTemplateVariable is the type of the task template variables whose
+	// values are strings, the default
+	StringTemplateVariable = "string"
+	// NumberTemplateVariable is the type of the task template variables whose
+	// values are numbers
+	NumberTemplateVariable = "number"
+	// BooleanTemplateVariable is the type of the task template variables
+	// whose values are true or false
+	BooleanTemplateVariable = "boolean"
+)
+
+// TaskTemplateVariable is a variable of a task template
+type TaskTemplateVariable struct {
+	Name        string `json:"name"yaml:"name"`
+	Description string `json:"description,omitempty"yaml:"description"`
+	// Type is the type of the values of the variable: string, the default,
+	// number or boolean
+	Type string `json:"type,omitempty"yaml:"type"`
+	// Default is the value of the variable when the task is not given one;
+	// a variable without a default is required
+	Default *string `json:"default,omitempty"yaml:"default"`
+}
+
+// checkValue returns an error when the value is not of the type of the
+// variable
+func (v TaskTemplateVariable) checkValue(value string) error {
+	switch v.Type {
+	case NumberTemplateVariable:
+		if !jsonNumber.MatchString(value) {
+			return fmt.Errorf("%v '%s' (expected a number, got '%s')", ErrInvalidTemplateVariableValue, v.Name, value)
+		}
+	case BooleanTemplateVariable:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("%v '%s' (expected true or false, got '%s')", ErrInvalidTemplateVariableValue, v.Name, value)
+		}
+	}
+	return nil
+}
+
+// TaskTemplate is a task manifest parameterized by variables, registered by
+// an admin so that the users create tasks from it by giving its name and the
+// values of its variables.  The strings of the task, the keys of its objects
+// included, refer to the variables as {{name}}.  A string which is only a
+// reference to a variable of type number or boolean is replaced by the
+// number or the boolean, e.g. for "max-failures"; the values of the string
+// variables are always strings.
+//
+// swagger:model TaskTemplate
+type TaskTemplate struct {
+	Name        string                 `json:"name"yaml:"name"`
+	Description string                 `json:"description,omitempty"yaml:"description"`
+	Variables   []TaskTemplateVariable `json:"variables,omitempty"yaml:"variables"`
+	// Task is the task manifest of the template
+	Task json.RawMessage `json:"task"yaml:"task"`
+}
+
+// Validate returns an error when the template has an invalid name, declares
+// a variable twice, of an unknown type or with a default not of its type, or
+// refers to a variable it does not declare
+func (t *TaskTemplate) Validate() error {
+	if !taskTemplateName.MatchString(t.Name) {
+		return fmt.Errorf("%v '%s'", ErrInvalidTaskTemplateName, t.Name)
+	}
+	declared := make(map[string]bool, len(t.Variables))
+	for _, v := range t.Variables {
+		if !templateVariable.MatchString(v.Name) || declared[v.Name] {
+			return fmt.Errorf("%v '%s'", ErrInvalidTemplateVariable, v.Name)
+		}
+		switch v.Type {
+		case "", StringTemplateVariable, NumberTemplateVariable, BooleanTemplateVariable:
+		default:
+			return fmt.Errorf("%v '%s' (unknown type '%s')", ErrInvalidTemplateVariable, v.Name, v.Type)
+		}
+		if v.Default != nil {
+			if err := v.checkValue(*v.Default); err != nil {
+				return err
+			}
+		}
+		declared[v.Name] = true
+	}
+	var task map[string]interface{}
+	if err := json.Unmarshal(t.Task, &task); err != nil {
+		return fmt.Errorf("%v (while parsing 'task')", err)
+	}
+	if _, ok := task["template"]; ok {
+		return ErrNestedTaskTemplate
+	}
+	for _, m := range templateReference.FindAllStringSubmatch(string(t.Task), -1) {
+		if !declared[m[1]] {
+			return fmt.Errorf("%v '%s'", ErrUndeclaredTemplateVariable, m[1])
+		}
+	}
+	return nil
+}
+
+// Render returns the task creation request of the template given the values
+// of its variables
+func (t *TaskTemplate) Render(values map[string]string) (*TaskCreationRequest, error) {
+	vars := make(map[string]string, len(t.Variables))
+	types := make(map[string]string, len(t.Variables))
+	for _, v := range t.Variables {
+		if value, ok := values[v.Name]; ok {
+			if err := v.checkValue(value); err != nil {
+				return nil, err
+			}
+			vars[v.Name] = value
+		} else if v.Default != nil {
+			vars[v.Name] = *v.Default
+		} else {
+			return nil, fmt.Errorf("%v '%s'", ErrMissingTemplateVariable, v.Name)
+		}
+		types[v.Name] = v.Type
+	}
+	for name := range values {
+		if _, ok := vars[name]; !ok {
+			return nil, fmt.Errorf("%v '%s'", ErrUnknownTemplateVariable, name)
+		}
+	}
+	d := json.NewDecoder(bytes.NewReader(t.Task))
+	d.UseNumber()
+	var task interface{}
+	if err := d.Decode(&task); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(substitute(task, vars, types))
+	if err != nil {
+		return nil, err
+	}
+	tr := &TaskCreationRequest{}
+	if err := json.Unmarshal(b, tr); err != nil {
+		return nil, fmt.Errorf("%v (in task template '%s')", err, t.Name)
+	}
+	if tr.Template != "" {
+		return nil, ErrNestedTaskTemplate
+	}
+	return tr, nil
+}
+
+// Expand sets the schedule, the workflow and the options of the task the
+// request renders the template to, given the variables of the request.  The
+// options set in the request itself take precedence over the ones of the
+// template.  The template and the variables are cleared from the request once
+// expanded.
+func (t *TaskTemplate) Expand(tr *TaskCreationRequest) error {
+	rendered, err := t.Render(tr.Variables)
+	if err != nil {
+		return err
+	}
+	if tr.Name == "" {
+		tr.Name = rendered.Name
+	}
+	if tr.Version == 0 {
+		tr.Version = rendered.Version
+	}
+	if tr.Schedule == nil {
+		tr.Schedule = rendered.Schedule
+	}
+	if tr.Workflow == nil {
+		tr.Workflow = rendered.Workflow
+	}
+	if !tr.Start {
+		tr.Start = rendered.Start
+	}
+	if tr.Preset == "" {
+		tr.Preset = rendered.Preset
+	}
+	p := &TaskPreset{
+		Deadline:           rendered.Deadline,
+		MaxFailures:        rendered.MaxFailures,
+		MaxCollectDuration: rendered.MaxCollectDuration,
+		MaxMetricsBuffer:   rendered.MaxMetricsBuffer,
+		FailurePolicy:      rendered.FailurePolicy,
+		FailureCooldown:    rendered.FailureCooldown,
+		Singleton:          rendered.Singleton,
+		Queue:              rendered.Queue,
+		PinnedThread:       rendered.PinnedThread,
+		PublishRateLimit:   rendered.PublishRateLimit,
+		CardinalityLimit:   rendered.CardinalityLimit,
+		Blackouts:          rendered.Blackouts,
+		CollectTimeout:     rendered.CollectTimeout,
+		ProcessTimeout:     rendered.ProcessTimeout,
+		PublishTimeout:     rendered.PublishTimeout,
+		Labels:             rendered.Labels,
+	}
+	p.apply(tr)
+	tr.Template = ""
+	tr.Variables = nil
+	return nil
+}
+
+// substitute replaces the references to the variables in the strings of the
+// decoded JSON value; a string only referring to a variable of type number or
+// boolean is replaced by a value of this type
+func substitute(v interface{}, vars, types map[string]string) interface{} {
+	switch v := v.(type) {
+	case string:
+		if m := templateReference.FindStringSubmatch(v); m != nil && m[0] == v {
+			switch types[m[1]] {
+			case BooleanTemplateVariable:
+				return vars[m[1]] == "true"
+			case NumberTemplateVariable:
+				return json.Number(vars[m[1]])
+			}
+		}
+		return replaceReferences(v, vars)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[replaceReferences(k, vars)] = substitute(e, vars, types)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = substitute(e, vars, types)
+		}
+		return a
+	default:
+		return v
+	}
+}
+
+func replaceReferences(s string, vars map[string]string) string {
+	return templateReference.ReplaceAllStringFunc(s, func(ref string) string {
+		return vars[templateReference.FindStringSubmatch(ref)[1]]
+	})
+}
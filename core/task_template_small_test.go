@@ -0,0 +1,126 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTaskTemplate(t *testing.T) {
+	interval, failures := "10s", "5"
+	template := &TaskTemplate{
+		Name: "cpu",
+		Variables: []TaskTemplateVariable{
+			{Name: "interval", Default: &interval},
+			{Name: "host"},
+			{Name: "max-failures", Type: NumberTemplateVariable, Default: &failures},
+			{Name: "start", Type: BooleanTemplateVariable},
+			{Name: "team"},
+		},
+		Task: json.RawMessage(`{
+			"version": 1,
+			"name": "cpu-{{host}}",
+			"schedule": {"type": "simple", "interval": "{{interval}}"},
+			"max-failures": "{{max-failures}}",
+			"start": "{{start}}",
+			"labels": {"host": "{{ host }}", "team": "{{team}}"},
+			"workflow": {
+				"collect": {
+					"metrics": {"/intel/{{host}}/cpu": {}}
+				}
+			}
+		}`),
+	}
+	Convey("Given a task template", t, func() {
+		So(template.Validate(), ShouldBeNil)
+
+		Convey("it renders the task with the values of its variables", func() {
+			tr, err := template.Render(map[string]string{"host": "db1", "max-failures": "3", "start": "true", "team": "storage"})
+			So(err, ShouldBeNil)
+			So(tr.Name, ShouldEqual, "cpu-db1")
+			So(tr.Schedule, ShouldResemble, &Schedule{Type: "simple", Interval: "10s"})
+			So(tr.MaxFailures, ShouldEqual, 3)
+			So(tr.Start, ShouldBeTrue)
+			So(tr.Labels, ShouldResemble, map[string]string{"host": "db1", "team": "storage"})
+			So(tr.Workflow.Collect.Metrics, ShouldContainKey, "/intel/db1/cpu")
+		})
+		Convey("the values of the string variables stay strings", func() {
+			tr, err := template.Render(map[string]string{"host": "true", "start": "false", "team": "42"})
+			So(err, ShouldBeNil)
+			So(tr.Labels, ShouldResemble, map[string]string{"host": "true", "team": "42"})
+		})
+		Convey("it rejects the values not of the type of their variable", func() {
+			_, err := template.Render(map[string]string{"host": "db1", "max-failures": "many", "start": "true", "team": "storage"})
+			So(err.Error(), ShouldStartWith, ErrInvalidTemplateVariableValue.Error())
+			So(err.Error(), ShouldContainSubstring, "max-failures")
+			_, err = template.Render(map[string]string{"host": "db1", "start": "yes", "team": "storage"})
+			So(err.Error(), ShouldStartWith, ErrInvalidTemplateVariableValue.Error())
+		})
+		Convey("it requires the values of the variables without default", func() {
+			_, err := template.Render(map[string]string{"interval": "1s"})
+			So(err.Error(), ShouldStartWith, ErrMissingTemplateVariable.Error())
+		})
+		Convey("it rejects the values of undeclared variables", func() {
+			_, err := template.Render(map[string]string{"host": "db1", "start": "true", "team": "storage", "port": "80"})
+			So(err.Error(), ShouldStartWith, ErrUnknownTemplateVariable.Error())
+		})
+		Convey("it reports the errors of the rendered task", func() {
+			_, err := template.Render(map[string]string{"host": "db1", "max-failures": "3.5", "start": "true", "team": "storage"})
+			So(err.Error(), ShouldContainSubstring, "max-failures")
+		})
+		Convey("a request is expanded, its options taking precedence", func() {
+			tr := &TaskCreationRequest{
+				Template:    "cpu",
+				Variables:   map[string]string{"host": "db1", "max-failures": "3", "start": "false", "team": "web"},
+				MaxFailures: 5,
+				Labels:      map[string]string{"team": "storage"},
+			}
+			So(template.Expand(tr), ShouldBeNil)
+			So(tr.Template, ShouldBeEmpty)
+			So(tr.Variables, ShouldBeNil)
+			So(tr.Name, ShouldEqual, "cpu-db1")
+			So(tr.Schedule, ShouldNotBeNil)
+			So(tr.Workflow, ShouldNotBeNil)
+			So(tr.MaxFailures, ShouldEqual, 5)
+			So(tr.Labels, ShouldResemble, map[string]string{"host": "db1", "team": "storage"})
+		})
+	})
+	Convey("Invalid task templates are rejected", t, func() {
+		for _, c := range []struct {
+			template *TaskTemplate
+			err      error
+		}{
+			{&TaskTemplate{Name: "", Task: json.RawMessage(`{}`)}, ErrInvalidTaskTemplateName},
+			{&TaskTemplate{Name: "a/b", Task: json.RawMessage(`{}`)}, ErrInvalidTaskTemplateName},
+			{&TaskTemplate{Name: "t", Variables: []TaskTemplateVariable{{Name: "a"}, {Name: "a"}}, Task: json.RawMessage(`{}`)}, ErrInvalidTemplateVariable},
+			{&TaskTemplate{Name: "t", Variables: []TaskTemplateVariable{{Name: "a", Type: "int"}}, Task: json.RawMessage(`{}`)}, ErrInvalidTemplateVariable},
+			{&TaskTemplate{Name: "t", Variables: []TaskTemplateVariable{{Name: "a", Type: NumberTemplateVariable, Default: &interval}}, Task: json.RawMessage(`{}`)}, ErrInvalidTemplateVariableValue},
+			{&TaskTemplate{Name: "t", Task: json.RawMessage(`{"name": "{{a}}"}`)}, ErrUndeclaredTemplateVariable},
+			{&TaskTemplate{Name: "t", Task: json.RawMessage(`{"template": "u"}`)}, ErrNestedTaskTemplate},
+		} {
+			So(c.template.Validate().Error(), ShouldStartWith, c.err.Error())
+		}
+	})
+}
@@ -23,6 +23,8 @@ import (
 	"errors"
 	"time"
 
+	"google.golang.org/grpc/credentials"
+
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/ctypes"
@@ -48,7 +50,14 @@ type ControlProxy struct {
 }
 
 func New(addr string, port int) (ControlProxy, error) {
-	conn, err := rpcutil.GetClientConnection(context.Background(), addr, port)
+	return NewWithCredentials(addr, port, nil)
+}
+
+// NewWithCredentials returns a ControlProxy to the control listening on the
+// given address whose connection is secured with the given credentials, or
+// not secured when creds is nil.
+func NewWithCredentials(addr string, port int, creds credentials.TransportCredentials) (ControlProxy, error) {
+	conn, err := rpcutil.GetClientConnectionWithCreds(context.Background(), addr, port, creds)
 	if err != nil {
 		return ControlProxy{}, err
 	}
@@ -126,12 +135,14 @@ func (c ControlProxy) CollectMetrics(taskID string, AllTags map[string]map[strin
 		errs = append(errs, err)
 		return nil, errs
 	}
+	// the metrics of the collectors which succeeded come along with the
+	// errors of the ones which failed
+	metrics := common.ToCoreMetrics(reply.Metrics)
 	rerrs := replyErrorsToErrors(reply.Errors)
 	if len(rerrs) > 0 {
 		errs = append(errs, rerrs...)
-		return nil, errs
+		return metrics, errs
 	}
-	metrics := common.ToCoreMetrics(reply.Metrics)
 	return metrics, nil
 }
 
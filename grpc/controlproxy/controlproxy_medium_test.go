@@ -211,6 +211,27 @@ func TestCollectMetrics(t *testing.T) {
 		})
 	})
 
+	Convey("Control.CollectMetrics returns metrics and an error", t, func() {
+		reply := &rpc.CollectMetricsResponse{
+			Metrics: []*common.Metric{&common.Metric{
+				Namespace:          common.ToNamespace(core.NewNamespace("testing", "this")),
+				Version:            6,
+				Tags:               map[string]string{},
+				Timestamp:          &common.Time{Sec: time.Now().Unix(), Nsec: int64(time.Now().Nanosecond())},
+				LastAdvertisedTime: &common.Time{Sec: time.Now().Unix(), Nsec: int64(time.Now().Nanosecond())},
+			}},
+			Errors: []string{"error in collect"},
+		}
+
+		proxy := ControlProxy{Client: mockClient{CollectReply: reply}}
+		mts, errs := proxy.CollectMetrics("", map[string]map[string]string{})
+
+		Convey("So the metrics of the collectors which succeeded should be returned", func() {
+			So(len(mts), ShouldEqual, 1)
+			So(len(errs), ShouldEqual, 1)
+		})
+	})
+
 	Convey("Control.CollectMetrics returns successfully", t, func() {
 		reply := &rpc.CollectMetricsResponse{
 			Metrics: []*common.Metric{&common.Metric{
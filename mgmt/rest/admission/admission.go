@@ -0,0 +1,143 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package admission reviews task creation requests received by the REST API
+// against an organizational policy before the tasks are created.  Requests
+// may be rejected or modified by an embedded policy and by an external
+// webhook.
+package admission
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vrischmann/jsonutil"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	defaultWebhookTimeout = 10 * time.Second
+)
+
+var (
+	admissionLogger = log.WithField("_module", "_mgmt-rest-admission")
+)
+
+// Config holds the admission configuration of the REST API.
+//   Note: if this struct is modified, then the constraints in the rest
+//         package need to be modified to match the field mapping that is
+//         defined here
+type Config struct {
+	// WebhookURL is the HTTP endpoint consulted for every task creation
+	// request; an empty URL disables the webhook
+	WebhookURL string `json:"webhook_url"yaml:"webhook_url"`
+	// WebhookTimeout bounds the time spent waiting for the webhook
+	WebhookTimeout jsonutil.Duration `json:"webhook_timeout"yaml:"webhook_timeout"`
+	// NamePattern is a regular expression task names have to match
+	NamePattern string `json:"name_pattern"yaml:"name_pattern"`
+	// RequiredTags are the tag keys every collected metric has to receive
+	// from the workflow
+	RequiredTags []string `json:"required_tags"yaml:"required_tags"`
+	// DefaultTags are added to all metrics of the workflow unless the
+	// workflow already sets them
+	DefaultTags map[string]string `json:"default_tags"yaml:"default_tags"`
+	// ForbiddenPublishers are the names of publisher plugins tasks must not
+	// publish to
+	ForbiddenPublishers []string `json:"forbidden_publishers"yaml:"forbidden_publishers"`
+}
+
+// RejectedError is returned when a task creation request is rejected.
+type RejectedError struct {
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("Task rejected by admission control: %s", e.Reason)
+}
+
+func rejected(format string, a ...interface{}) error {
+	return &RejectedError{Reason: fmt.Sprintf(format, a...)}
+}
+
+// Controller admits task creation requests according to its policy and
+// webhook.  It implements core.TaskAdmitter.
+type Controller struct {
+	policy  *policy
+	webhook *webhook
+}
+
+// New returns a Controller configured from the given config.
+func New(cfg *Config) (*Controller, error) {
+	c := &Controller{
+		policy: &policy{
+			requiredTags:        cfg.RequiredTags,
+			defaultTags:         cfg.DefaultTags,
+			forbiddenPublishers: cfg.ForbiddenPublishers,
+		},
+	}
+	if cfg.NamePattern != "" {
+		re, err := regexp.Compile(cfg.NamePattern)
+		if err != nil {
+			return nil, fmt.Errorf("%v (while parsing 'restapi::admission::name_pattern')", err)
+		}
+		c.policy.namePattern = re
+	}
+	if cfg.WebhookURL != "" {
+		timeout := cfg.WebhookTimeout.Duration
+		if timeout <= 0 {
+			timeout = defaultWebhookTimeout
+		}
+		c.webhook = &webhook{
+			url:    cfg.WebhookURL,
+			client: &http.Client{Timeout: timeout},
+		}
+	}
+	return c, nil
+}
+
+// AdmitTask applies the policy to the request, then consults the webhook.
+// The webhook sees the request as modified by the policy and its own
+// modifications are checked against the policy again.
+func (c *Controller) AdmitTask(tr *core.TaskCreationRequest) error {
+	if err := c.policy.admit(tr); err != nil {
+		return c.logRejection(tr, err)
+	}
+	if c.webhook == nil {
+		return nil
+	}
+	if err := c.webhook.admit(tr); err != nil {
+		return c.logRejection(tr, err)
+	}
+	if err := c.policy.admit(tr); err != nil {
+		return c.logRejection(tr, err)
+	}
+	return nil
+}
+
+func (c *Controller) logRejection(tr *core.TaskCreationRequest, err error) error {
+	admissionLogger.WithFields(log.Fields{
+		"_block":    "admit-task",
+		"task-name": tr.Name,
+	}).Warn(err)
+	return err
+}
@@ -0,0 +1,170 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const manifest = `{
+	"version": 1,
+	"name": "team-a-cpu",
+	"schedule": {"type": "simple", "interval": "1s"},
+	"workflow": {
+		"collect": {
+			"metrics": {"/intel/mock/foo": {}, "/intel/mock/bar": {}},
+			"tags": {"/intel/mock": {"team": "a"}},
+			"process": [{
+				"plugin_name": "passthru",
+				"publish": [{"plugin_name": "file"}]
+			}]
+		}
+	}
+}`
+
+func newRequest() *core.TaskCreationRequest {
+	tr := &core.TaskCreationRequest{}
+	So(json.Unmarshal([]byte(manifest), tr), ShouldBeNil)
+	return tr
+}
+
+func TestPolicy(t *testing.T) {
+	Convey("Given an admission policy", t, func() {
+		tr := newRequest()
+		Convey("an invalid name pattern is reported", func() {
+			_, err := New(&Config{NamePattern: "("})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("task names have to match the name pattern", func() {
+			c, err := New(&Config{NamePattern: "^team-[a-z]+-"})
+			So(err, ShouldBeNil)
+			So(c.AdmitTask(tr), ShouldBeNil)
+			tr.Name = "cpu"
+			err = c.AdmitTask(tr)
+			So(err, ShouldHaveSameTypeAs, &RejectedError{})
+			So(err.Error(), ShouldContainSubstring, "task name 'cpu'")
+		})
+		Convey("default tags are added to the workflow", func() {
+			c, err := New(&Config{DefaultTags: map[string]string{"team": "none", "dc": "dc1"}})
+			So(err, ShouldBeNil)
+			So(c.AdmitTask(tr), ShouldBeNil)
+			So(tr.Workflow.Collect.Tags["/"], ShouldResemble, map[string]string{"team": "none", "dc": "dc1"})
+			So(tr.Workflow.Collect.Tags["/intel/mock"]["team"], ShouldEqual, "a")
+		})
+		Convey("every metric has to receive the required tags", func() {
+			c, err := New(&Config{RequiredTags: []string{"team"}})
+			So(err, ShouldBeNil)
+			So(c.AdmitTask(tr), ShouldBeNil)
+			tr.Workflow.Collect.Tags = map[string]map[string]string{"/intel/mock/foo": {"team": "a"}}
+			err = c.AdmitTask(tr)
+			So(err, ShouldHaveSameTypeAs, &RejectedError{})
+			So(err.Error(), ShouldContainSubstring, "'/intel/mock/bar' is missing the required tag 'team'")
+		})
+		Convey("required tags may be given by the tags of the task", func() {
+			c, err := New(&Config{RequiredTags: []string{"dc"}})
+			So(err, ShouldBeNil)
+			tr.Workflow.Tags = map[string]string{"dc": "dc1"}
+			So(c.AdmitTask(tr), ShouldBeNil)
+		})
+		Convey("required tags may be given by the default tags", func() {
+			c, err := New(&Config{RequiredTags: []string{"dc"}, DefaultTags: map[string]string{"dc": "dc1"}})
+			So(err, ShouldBeNil)
+			So(c.AdmitTask(tr), ShouldBeNil)
+		})
+		Convey("tasks cannot publish to forbidden publishers", func() {
+			c, err := New(&Config{ForbiddenPublishers: []string{"influxdb"}})
+			So(err, ShouldBeNil)
+			So(c.AdmitTask(tr), ShouldBeNil)
+			c, err = New(&Config{ForbiddenPublishers: []string{"file"}})
+			So(err, ShouldBeNil)
+			err = c.AdmitTask(tr)
+			So(err, ShouldHaveSameTypeAs, &RejectedError{})
+			So(err.Error(), ShouldContainSubstring, "publishing to 'file' is forbidden")
+		})
+	})
+}
+
+func TestWebhook(t *testing.T) {
+	Convey("Given an admission webhook", t, func() {
+		var review Review
+		answer := func(w http.ResponseWriter, r *http.Request) {}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			review = Review{}
+			json.NewDecoder(r.Body).Decode(&review)
+			answer(w, r)
+		}))
+		defer srv.Close()
+		c, err := New(&Config{WebhookURL: srv.URL, NamePattern: "^team-"})
+		So(err, ShouldBeNil)
+		tr := newRequest()
+
+		Convey("the webhook is sent the request", func() {
+			answer = func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(Review{Allowed: true})
+			}
+			So(c.AdmitTask(tr), ShouldBeNil)
+			So(review.Task, ShouldNotBeNil)
+			So(review.Task.Name, ShouldEqual, "team-a-cpu")
+			So(tr.Name, ShouldEqual, "team-a-cpu")
+		})
+		Convey("the webhook can reject the request", func() {
+			answer = func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(Review{Allowed: false, Reason: "not today"})
+			}
+			err := c.AdmitTask(tr)
+			So(err, ShouldHaveSameTypeAs, &RejectedError{})
+			So(err.(*RejectedError).Reason, ShouldEqual, "not today")
+		})
+		Convey("the webhook can modify the request", func() {
+			answer = func(w http.ResponseWriter, r *http.Request) {
+				review.Task.Name = "team-a-cpu-v2"
+				review.Task.Deadline = "5s"
+				json.NewEncoder(w).Encode(Review{Allowed: true, Task: review.Task})
+			}
+			So(c.AdmitTask(tr), ShouldBeNil)
+			So(tr.Name, ShouldEqual, "team-a-cpu-v2")
+			So(tr.Deadline, ShouldEqual, "5s")
+		})
+		Convey("modifications by the webhook are checked against the policy", func() {
+			answer = func(w http.ResponseWriter, r *http.Request) {
+				review.Task.Name = "cpu"
+				json.NewEncoder(w).Encode(Review{Allowed: true, Task: review.Task})
+			}
+			So(c.AdmitTask(tr), ShouldHaveSameTypeAs, &RejectedError{})
+		})
+		Convey("the request is rejected when the webhook fails", func() {
+			answer = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			err := c.AdmitTask(tr)
+			So(err, ShouldHaveSameTypeAs, &RejectedError{})
+			So(err.Error(), ShouldContainSubstring, "500")
+		})
+	})
+}
@@ -0,0 +1,130 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// allMetrics is the tag branch whose tags are given to every metric
+const allMetrics = "/"
+
+// policy is the admission policy evaluated in process
+type policy struct {
+	namePattern         *regexp.Regexp
+	requiredTags        []string
+	defaultTags         map[string]string
+	forbiddenPublishers []string
+}
+
+func (p *policy) admit(tr *core.TaskCreationRequest) error {
+	if p.namePattern != nil && !p.namePattern.MatchString(tr.Name) {
+		return rejected("task name '%s' does not match '%s'", tr.Name, p.namePattern)
+	}
+	if tr.Workflow == nil || tr.Workflow.Collect == nil {
+		// the workflow is reported missing when the request is validated
+		return nil
+	}
+	collect := tr.Workflow.Collect
+	if len(p.defaultTags) > 0 {
+		if collect.Tags == nil {
+			collect.Tags = map[string]map[string]string{}
+		}
+		if collect.Tags[allMetrics] == nil {
+			collect.Tags[allMetrics] = map[string]string{}
+		}
+		for k, v := range p.defaultTags {
+			if _, ok := collect.Tags[allMetrics][k]; !ok {
+				collect.Tags[allMetrics][k] = v
+			}
+		}
+	}
+	for ns := range collect.Metrics {
+		for _, key := range p.requiredTags {
+			if _, ok := tr.Workflow.Tags[key]; ok {
+				continue
+			}
+			if !tagged(collect.Tags, ns, key) {
+				return rejected("metric '%s' is missing the required tag '%s'", ns, key)
+			}
+		}
+	}
+	for _, name := range p.forbiddenPublishers {
+		if publishesTo(collect.Process, collect.Publish, name) {
+			return rejected("publishing to '%s' is forbidden", name)
+		}
+	}
+	return nil
+}
+
+// tagged returns true if the given tag key is applied to the metric by a tag
+// branch of the workflow
+func tagged(tags map[string]map[string]string, ns, key string) bool {
+	for branch, branchTags := range tags {
+		if _, ok := branchTags[key]; !ok {
+			continue
+		}
+		if hasPrefix(split(ns), split(branch)) {
+			return true
+		}
+	}
+	return false
+}
+
+// publishesTo returns true if the given publisher plugin is used anywhere in
+// the given workflow nodes
+func publishesTo(process []wmap.ProcessWorkflowMapNode, publish []wmap.PublishWorkflowMapNode, name string) bool {
+	for _, pub := range publish {
+		if pub.PluginName == name {
+			return true
+		}
+	}
+	for _, pr := range process {
+		if publishesTo(pr.Process, pr.Publish, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefix(ns, prefix []string) bool {
+	if len(prefix) > len(ns) {
+		return false
+	}
+	for i := range prefix {
+		if ns[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func split(ns string) []string {
+	// the first character is the separator
+	if len(ns) <= 1 {
+		return nil
+	}
+	sep := string(ns[0])
+	return strings.Split(strings.Trim(ns, sep), sep)
+}
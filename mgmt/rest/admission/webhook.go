@@ -0,0 +1,81 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// Review is the body exchanged with the admission webhook.  The webhook is
+// sent the task creation request and answers with whether it is allowed,
+// the reason for rejecting it and, optionally, the request to use instead.
+type Review struct {
+	Allowed bool                      `json:"allowed"`
+	Reason  string                    `json:"reason,omitempty"`
+	Task    *core.TaskCreationRequest `json:"task,omitempty"`
+}
+
+// webhook consults an external HTTP endpoint
+type webhook struct {
+	url    string
+	client *http.Client
+}
+
+// admit posts the request to the webhook.  Requests are rejected when the
+// webhook cannot be reached or does not answer with a valid review.
+func (w *webhook) admit(tr *core.TaskCreationRequest) error {
+	b, err := json.Marshal(Review{Task: tr})
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return rejected("admission webhook failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return rejected("admission webhook failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return rejected("admission webhook returned %s", resp.Status)
+	}
+	var review Review
+	if err := json.Unmarshal(body, &review); err != nil {
+		return rejected("admission webhook returned an invalid review: %v", err)
+	}
+	if !review.Allowed {
+		reason := review.Reason
+		if reason == "" {
+			reason = fmt.Sprintf("denied by %s", w.url)
+		}
+		return &RejectedError{Reason: reason}
+	}
+	if review.Task != nil {
+		*tr = *review.Task
+	}
+	return nil
+}
@@ -2,6 +2,8 @@ package api
 
 import (
 	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/core"
 )
 
 type API interface {
@@ -10,6 +12,7 @@ type API interface {
 	BindTaskManager(Tasks)
 	BindTribeManager(Tribe)
 	BindConfigManager(Config)
+	BindTaskAdmitter(core.TaskAdmitter)
 }
 
 type Route struct {
@@ -0,0 +1,9 @@
+package api
+
+import (
+	"github.com/intelsdi-x/snap/mgmt/rest/audit"
+)
+
+type AuditLog interface {
+	Query(audit.Filter) []audit.Record
+}
@@ -0,0 +1,18 @@
+package api
+
+// DaemonInfo describes the daemon serving the API so that clients can adapt
+// to its version and to the features it has enabled.
+type DaemonInfo struct {
+	// Version of snapteld
+	Version string `json:"version"`
+	// GoVersion is the version of Go snapteld was built with
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	// Subsystems tells which optional subsystems are enabled, e.g. "tribe"
+	Subsystems map[string]bool `json:"subsystems"`
+	// ScheduleTypes lists the supported types of task schedule
+	ScheduleTypes []string `json:"schedule_types"`
+	// PluginRPCTypes lists the RPC protocols plugins can use
+	PluginRPCTypes []string `json:"plugin_rpc_types"`
+}
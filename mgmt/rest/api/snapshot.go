@@ -0,0 +1,12 @@
+package api
+
+import (
+	"github.com/intelsdi-x/snap/mgmt/rest/snapshot"
+)
+
+type Snapshots interface {
+	Take() (*snapshot.Snapshot, error)
+	List() ([]snapshot.Info, error)
+	Get(string) (*snapshot.Snapshot, error)
+	Restore(string) (*snapshot.RestoreResult, error)
+}
@@ -1,8 +1,11 @@
 package api
 
 import (
+	"net/http"
+
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest/auth"
 	"github.com/intelsdi-x/snap/pkg/schedule"
 	"github.com/intelsdi-x/snap/scheduler/wmap"
 )
@@ -17,3 +20,34 @@ type Tasks interface {
 	WatchTask(string, core.TaskWatcherHandler) (core.TaskWatcherCloser, error)
 	EnableTask(string) (core.Task, error)
 }
+
+// TaskCreator creates a task like Tasks.CreateTask
+type TaskCreator func(schedule.Schedule, *wmap.WorkflowMap, bool, ...core.TaskOption) (core.Task, core.TaskErrors)
+
+// OwnedTaskCreator returns a creator of the tasks owned by the caller of the
+// request and its tenant.  The tasks created without an authenticated
+// caller have no owner.
+func OwnedTaskCreator(r *http.Request, create TaskCreator) TaskCreator {
+	id := auth.IdentityFrom(r)
+	if id == nil {
+		return create
+	}
+	owner := &core.TaskOwner{Name: id.Name, Tenant: id.Tenant}
+	return func(sch schedule.Schedule, wfMap *wmap.WorkflowMap, startOnCreate bool, opts ...core.TaskOption) (core.Task, core.TaskErrors) {
+		return create(sch, wfMap, startOnCreate, append(opts, core.OptionOwner(owner))...)
+	}
+}
+
+// CanAccessTask returns true when the caller of the request may see and
+// change the task; any caller may when the caller is not authenticated.
+func CanAccessTask(r *http.Request, t core.Task) bool {
+	id := auth.IdentityFrom(r)
+	return id == nil || id.CanAccess(core.TenantOf(t))
+}
+
+// CanAccessAllTasks returns true when the caller of the request may see and
+// change the tasks of every tenant: admins and callers not authenticated.
+func CanAccessAllTasks(r *http.Request) bool {
+	id := auth.IdentityFrom(r)
+	return id == nil || id.Role == auth.RoleAdmin
+}
@@ -0,0 +1,346 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit records who created, started, stopped and removed tasks and
+// who loaded and unloaded plugins through the REST API, and when.  Records
+// are appended to a file, kept in memory to be queried and optionally shipped
+// to a publisher plugin.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+const (
+	defaultRetention = 1000
+	// subscriptionID is the ID the audit publisher is subscribed under
+	subscriptionID = "audit"
+	// maxRecordSize bounds the size of a record read back from the file
+	maxRecordSize = 1024 * 1024
+)
+
+// The audited actions
+const (
+	ActionCreateTask   = "create-task"
+	ActionStartTask    = "start-task"
+	ActionStopTask     = "stop-task"
+	ActionEnableTask   = "enable-task"
+	ActionRemoveTask   = "remove-task"
+	ActionLoadPlugin   = "load-plugin"
+	ActionUnloadPlugin = "unload-plugin"
+)
+
+var auditLogger = log.WithField("_module", "_mgmt-rest-audit")
+
+// ErrPublisherNotBound - The error message for an audit record shipped before the metric manager is bound
+var ErrPublisherNotBound = errors.New("Audit log is not bound to a metric manager able to publish")
+
+// Config holds the audit configuration of the REST API.
+//   Note: if this struct is modified, then the constraints in the rest
+//         package need to be modified to match the field mapping that is
+//         defined here
+type Config struct {
+	// Path is the file audit records are appended to, one JSON record per
+	// line; records are only kept in memory when it is not set
+	Path string `json:"path"yaml:"path"`
+	// Retention is the number of records kept in memory to be queried; it
+	// defaults to 1000
+	Retention int `json:"retention"yaml:"retention"`
+	// Publisher ships every record to a publisher plugin when set; only its
+	// plugin name, version and config are used
+	Publisher *wmap.PublishWorkflowMapNode `json:"publisher,omitempty"yaml:"publisher,omitempty"`
+}
+
+// Record tells who did what, and when.
+type Record struct {
+	Timestamp time.Time `json:"timestamp"`
+	// User is the user, API key or token subject the caller authenticated
+	// with, if any
+	User       string `json:"user,omitempty"`
+	RemoteAddr string `json:"remote_addr"`
+	Action     string `json:"action"`
+	// Target is the task ID or the plugin (type:name:version) acted upon,
+	// when known
+	Target string `json:"target,omitempty"`
+	// Status is the HTTP status code of the response
+	Status int `json:"status"`
+}
+
+// Succeeded returns true when the audited action succeeded
+func (r Record) Succeeded() bool {
+	return r.Status >= 200 && r.Status < 300
+}
+
+// Filter selects audit records; empty fields match all the records.
+type Filter struct {
+	User   string
+	Action string
+	Target string
+	Since  time.Time
+	// Limit keeps the most recent records only when greater than 0
+	Limit int
+}
+
+func (f Filter) matches(r Record) bool {
+	return (f.User == "" || f.User == r.User) &&
+		(f.Action == "" || f.Action == r.Action) &&
+		(f.Target == "" || f.Target == r.Target) &&
+		!r.Timestamp.Before(f.Since)
+}
+
+type publishesMetrics interface {
+	SubscribeDeps(string, []core.RequestedMetric, []core.SubscribedPlugin, *cdata.ConfigDataTree) []serror.SnapError
+	UnsubscribeDeps(string) []serror.SnapError
+	PublishMetrics([]core.Metric, map[string]ctypes.ConfigValue, string, string, int) []error
+}
+
+// Log is the append-only log of the audit records.
+type Log struct {
+	retention int
+
+	sync.Mutex
+	file    *os.File
+	records []Record
+
+	publisher     *subscribedPublisher
+	subscribed    bool
+	metricManager publishesMetrics
+}
+
+// New returns a Log configured from the given config.  The records already
+// in the audit file are read back so they can be queried.
+func New(cfg *Config) (*Log, error) {
+	l := &Log{retention: cfg.Retention}
+	if l.retention <= 0 {
+		l.retention = defaultRetention
+	}
+	if cfg.Publisher != nil {
+		if cfg.Publisher.PluginName == "" {
+			return nil, errors.New("Audit publisher name is not set (while parsing 'restapi::audit::publisher::plugin_name')")
+		}
+		cn, err := cfg.Publisher.GetConfigNode()
+		if err != nil {
+			return nil, fmt.Errorf("%v (while parsing 'restapi::audit::publisher::config')", err)
+		}
+		l.publisher = &subscribedPublisher{
+			name:    cfg.Publisher.PluginName,
+			version: cfg.Publisher.PluginVersion,
+			config:  cn,
+		}
+	}
+	if cfg.Path == "" {
+		return l, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0700); err != nil {
+		return nil, fmt.Errorf("%v (while parsing 'restapi::audit::path')", err)
+	}
+	if err := l.readBack(cfg.Path); err != nil {
+		return nil, fmt.Errorf("%v (while reading the audit file %s)", err, cfg.Path)
+	}
+	f, err := os.OpenFile(cfg.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("%v (while parsing 'restapi::audit::path')", err)
+	}
+	l.file = f
+	return l, nil
+}
+
+// readBack keeps the most recent records of the audit file in memory
+func (l *Log) readBack(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 4096), maxRecordSize)
+	for sc.Scan() {
+		var r Record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			// a record partially written when snapteld stopped
+			continue
+		}
+		l.keep(r)
+	}
+	return sc.Err()
+}
+
+// BindMetricManager sets the manager of the publisher plugin the records
+// are shipped to.
+func (l *Log) BindMetricManager(m interface{}) {
+	l.Lock()
+	defer l.Unlock()
+	if p, ok := m.(publishesMetrics); ok {
+		l.metricManager = p
+	}
+}
+
+// Stop unsubscribes from the publisher plugin and closes the audit file.
+func (l *Log) Stop() {
+	l.Lock()
+	defer l.Unlock()
+	if l.subscribed {
+		l.metricManager.UnsubscribeDeps(subscriptionID)
+		l.subscribed = false
+	}
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+}
+
+// Append adds a record to the log.  The record is shipped to the publisher
+// plugin, when there is one, in the background.
+func (l *Log) Append(r Record) {
+	logger := auditLogger.WithFields(log.Fields{
+		"_block":      "append",
+		"user":        r.User,
+		"remote-addr": r.RemoteAddr,
+		"action":      r.Action,
+		"target":      r.Target,
+		"status":      r.Status,
+	})
+	logger.Info("audit record")
+
+	l.Lock()
+	defer l.Unlock()
+	l.keep(r)
+	if l.file != nil {
+		b, err := json.Marshal(r)
+		if err == nil {
+			_, err = l.file.Write(append(b, '\n'))
+		}
+		if err != nil {
+			logger.WithField("_error", err.Error()).Error("unable to write audit record")
+		}
+	}
+	if l.publisher != nil {
+		go l.ship(r)
+	}
+}
+
+func (l *Log) keep(r Record) {
+	l.records = append(l.records, r)
+	if len(l.records) > l.retention {
+		l.records = append([]Record{}, l.records[len(l.records)-l.retention:]...)
+	}
+}
+
+// Query returns the records matching the given filter, oldest first.
+func (l *Log) Query(f Filter) []Record {
+	l.Lock()
+	defer l.Unlock()
+	records := []Record{}
+	for _, r := range l.records {
+		if f.matches(r) {
+			records = append(records, r)
+		}
+	}
+	if f.Limit > 0 && len(records) > f.Limit {
+		records = records[len(records)-f.Limit:]
+	}
+	return records
+}
+
+// ship publishes the record with the publisher plugin, subscribing to the
+// plugin first so that it is started
+func (l *Log) ship(r Record) {
+	logger := auditLogger.WithFields(log.Fields{
+		"_block":            "ship",
+		"publisher-name":    l.publisher.name,
+		"publisher-version": l.publisher.version,
+	})
+	l.Lock()
+	mm := l.metricManager
+	if mm == nil {
+		l.Unlock()
+		logger.WithField("_error", ErrPublisherNotBound.Error()).Warn("unable to ship audit record")
+		return
+	}
+	if !l.subscribed {
+		if errs := mm.SubscribeDeps(subscriptionID, nil, []core.SubscribedPlugin{l.publisher}, cdata.NewTree()); len(errs) > 0 {
+			l.Unlock()
+			logger.WithField("_error", errs[0].Error()).Warn("unable to subscribe to the audit publisher")
+			return
+		}
+		l.subscribed = true
+	}
+	l.Unlock()
+
+	if errs := mm.PublishMetrics([]core.Metric{recordMetric(r)}, l.publisher.config.Table(), subscriptionID, l.publisher.name, l.publisher.version); len(errs) > 0 {
+		logger.WithField("_error", errs[0].Error()).Warn("unable to ship audit record")
+	}
+}
+
+// recordMetric turns a record into the metric /snap/audit/<action> whose
+// value is the status code of the response
+func recordMetric(r Record) core.Metric {
+	return plugin.MetricType{
+		Namespace_: core.NewNamespace("snap", "audit", r.Action),
+		Data_:      r.Status,
+		Tags_: map[string]string{
+			"user":        r.User,
+			"remote_addr": r.RemoteAddr,
+			"target":      r.Target,
+		},
+		Timestamp_: r.Timestamp,
+	}
+}
+
+// subscribedPublisher is the publisher plugin the records are shipped to
+type subscribedPublisher struct {
+	name    string
+	version int
+	config  *cdata.ConfigDataNode
+}
+
+func (p *subscribedPublisher) TypeName() string {
+	return core.PublisherPluginType.String()
+}
+
+func (p *subscribedPublisher) Name() string {
+	return p.name
+}
+
+func (p *subscribedPublisher) Version() int {
+	return p.version
+}
+
+func (p *subscribedPublisher) Config() *cdata.ConfigDataNode {
+	return p.config
+}
@@ -0,0 +1,180 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/urfave/negroni"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// metricManager records the metrics published
+type metricManager struct {
+	sync.Mutex
+	subscribed []core.SubscribedPlugin
+	published  chan core.Metric
+}
+
+func (m *metricManager) SubscribeDeps(id string, _ []core.RequestedMetric, plugins []core.SubscribedPlugin, _ *cdata.ConfigDataTree) []serror.SnapError {
+	m.Lock()
+	defer m.Unlock()
+	m.subscribed = append(m.subscribed, plugins...)
+	return nil
+}
+
+func (m *metricManager) UnsubscribeDeps(string) []serror.SnapError {
+	return nil
+}
+
+func (m *metricManager) PublishMetrics(mts []core.Metric, _ map[string]ctypes.ConfigValue, _, _ string, _ int) []error {
+	for _, mt := range mts {
+		m.published <- mt
+	}
+	return nil
+}
+
+func TestClassify(t *testing.T) {
+	Convey("Classify", t, func() {
+		cases := []struct {
+			method, path, taskAction string
+			action, target           string
+		}{
+			{"POST", "/v2/tasks", "", ActionCreateTask, ""},
+			{"POST", "/v2/tasks/import", "", ActionCreateTask, ""},
+			{"PUT", "/v2/tasks/abc", "start", ActionStartTask, "abc"},
+			{"PUT", "/v1/tasks/abc/stop", "", ActionStopTask, "abc"},
+			{"PUT", "/v1/tasks/abc/enable", "", ActionEnableTask, "abc"},
+			{"DELETE", "/v1/tasks/abc", "", ActionRemoveTask, "abc"},
+			{"POST", "/v2/plugins", "", ActionLoadPlugin, ""},
+			{"DELETE", "/v2/plugins/collector/mock/1", "", ActionUnloadPlugin, "collector:mock:1"},
+			{"GET", "/v2/tasks/abc", "", "", ""},
+			{"PUT", "/v2/tasks/abc", "", "", ""},
+			{"POST", "/v2/tasks/simulate", "", "", ""},
+			{"DELETE", "/v2/plugins/collector/mock/1/config", "", "", ""},
+		}
+		for _, c := range cases {
+			action, target := Classify(c.method, c.path, c.taskAction)
+			So(action, ShouldEqual, c.action)
+			So(target, ShouldEqual, c.target)
+		}
+	})
+}
+
+func TestLog(t *testing.T) {
+	Convey("Given an audit log appending to a file", t, func() {
+		dir, err := ioutil.TempDir("", "snap-audit")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "audit.log")
+		l, err := New(&Config{Path: path, Retention: 2})
+		So(err, ShouldBeNil)
+
+		Convey("the audited requests are recorded with the caller", func() {
+			n := negroni.New(negroni.HandlerFunc(l.Middleware))
+			n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "POST" {
+					w.WriteHeader(201)
+					w.Write([]byte(`{"id": "abc", "name": "task-abc"}`))
+					return
+				}
+				w.WriteHeader(404)
+			})
+			req := httptest.NewRequest("POST", "/v2/tasks", nil)
+			req.SetBasicAuth("admin", "secret")
+			n.ServeHTTP(httptest.NewRecorder(), req)
+			n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/v2/tasks/xyz", nil))
+			n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v2/tasks", nil))
+
+			records := l.Query(Filter{})
+			So(len(records), ShouldEqual, 2)
+			So(records[0].User, ShouldEqual, "admin")
+			So(records[0].Action, ShouldEqual, ActionCreateTask)
+			So(records[0].Target, ShouldEqual, "abc")
+			So(records[0].Succeeded(), ShouldBeTrue)
+			So(records[1].Action, ShouldEqual, ActionRemoveTask)
+			So(records[1].Target, ShouldEqual, "xyz")
+			So(records[1].Status, ShouldEqual, 404)
+			So(l.Query(Filter{User: "admin"}), ShouldHaveLength, 1)
+		})
+		Convey("the records are filtered and the most recent ones are kept", func() {
+			start := time.Now()
+			l.Append(Record{Timestamp: start.Add(-time.Hour), Action: ActionLoadPlugin, Status: 201})
+			l.Append(Record{Timestamp: start, Action: ActionStartTask, Target: "a", Status: 200})
+			l.Append(Record{Timestamp: start, Action: ActionStopTask, Target: "a", Status: 200})
+			So(l.Query(Filter{}), ShouldHaveLength, 2)
+			So(l.Query(Filter{Action: ActionStopTask}), ShouldHaveLength, 1)
+			So(l.Query(Filter{Target: "a", Limit: 1})[0].Action, ShouldEqual, ActionStopTask)
+			So(l.Query(Filter{Since: start.Add(time.Minute)}), ShouldBeEmpty)
+
+			Convey("and read back from the file", func() {
+				l.Stop()
+				l, err := New(&Config{Path: path})
+				So(err, ShouldBeNil)
+				defer l.Stop()
+				So(l.Query(Filter{}), ShouldHaveLength, 3)
+			})
+		})
+
+		l.Stop()
+	})
+	Convey("Given an audit log shipping to a publisher", t, func() {
+		pub := wmap.NewPublishNode("file", 2)
+		pub.AddConfigItem("file", "/tmp/audit")
+		l, err := New(&Config{Publisher: pub})
+		So(err, ShouldBeNil)
+		mm := &metricManager{published: make(chan core.Metric, 1)}
+		l.BindMetricManager(mm)
+
+		Convey("the records are published as metrics", func() {
+			l.Append(Record{Timestamp: time.Now(), User: "admin", Action: ActionUnloadPlugin, Target: "collector:mock:1", Status: 200})
+			var m core.Metric
+			So(func() {
+				select {
+				case m = <-mm.published:
+				case <-time.After(5 * time.Second):
+				}
+			}, ShouldNotPanic)
+			So(m, ShouldNotBeNil)
+			So(m.Namespace().String(), ShouldEqual, "/snap/audit/unload-plugin")
+			So(m.Data(), ShouldEqual, 200)
+			So(m.Tags()["user"], ShouldEqual, "admin")
+			So(mm.subscribed, ShouldHaveLength, 1)
+			So(mm.subscribed[0].Name(), ShouldEqual, "file")
+		})
+
+		l.Stop()
+	})
+}
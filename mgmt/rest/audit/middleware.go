@@ -0,0 +1,173 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/urfave/negroni"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/auth"
+)
+
+// maxCapturedBody bounds the part of a response read to find the created
+// task or the loaded plugin
+const maxCapturedBody = 64 * 1024
+
+// Middleware records the audited requests handled by next.  It has to run
+// after the authentication of the caller.
+func (l *Log) Middleware(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	action, target := Classify(r.Method, r.URL.Path, r.URL.Query().Get("action"))
+	if action == "" {
+		next(rw, r)
+		return
+	}
+	w := &capturingWriter{ResponseWriter: rw.(negroni.ResponseWriter)}
+	next(w, r)
+
+	if target == "" && w.Succeeded() {
+		target = createdTarget(w.body.Bytes())
+	}
+	user, _, _ := r.BasicAuth()
+	if id := auth.IdentityFrom(r); id != nil {
+		user = id.Name
+	} else if user == "" && r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		// the caller authenticated with a client certificate only
+		user = r.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	l.Append(Record{
+		Timestamp:  time.Now(),
+		User:       user,
+		RemoteAddr: r.RemoteAddr,
+		Action:     action,
+		Target:     target,
+		Status:     w.Status(),
+	})
+}
+
+// Classify returns the audited action of a REST API request and its target,
+// when the target is part of the request.  The action is empty for the
+// requests which are not audited.  taskAction is the action query parameter
+// of the v2 task state updates.
+func Classify(method, path, taskAction string) (action, target string) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 2 || (parts[0] != "v1" && parts[0] != "v2") {
+		return "", ""
+	}
+	version, parts := parts[0], parts[1:]
+	switch {
+	case parts[0] == "tasks" && method == "POST" && len(parts) == 1:
+		return ActionCreateTask, ""
+	case parts[0] == "tasks" && method == "POST" && len(parts) == 2 && parts[1] == "import":
+		return ActionCreateTask, ""
+	case parts[0] == "tasks" && method == "DELETE" && len(parts) == 2:
+		return ActionRemoveTask, parts[1]
+	case parts[0] == "tasks" && method == "PUT" && len(parts) == 2 && version == "v2":
+		if a := taskStateAction(taskAction); a != "" {
+			return a, parts[1]
+		}
+	case parts[0] == "tasks" && method == "PUT" && len(parts) == 3 && version == "v1":
+		if a := taskStateAction(parts[2]); a != "" {
+			return a, parts[1]
+		}
+	case parts[0] == "plugins" && method == "POST" && len(parts) == 1:
+		return ActionLoadPlugin, ""
+	case parts[0] == "plugins" && method == "DELETE" && len(parts) == 4:
+		return ActionUnloadPlugin, strings.Join(parts[1:], ":")
+	}
+	return "", ""
+}
+
+func taskStateAction(a string) string {
+	switch a {
+	case "start":
+		return ActionStartTask
+	case "stop":
+		return ActionStopTask
+	case "enable":
+		return ActionEnableTask
+	}
+	return ""
+}
+
+// createdTarget returns the ID of the task or the plugin described in the
+// response of the v1 or v2 API to a task creation or a plugin load
+func createdTarget(body []byte) string {
+	var resp struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		Version int    `json:"version"`
+		// v1 wraps the response
+		Body *struct {
+			ID            string `json:"id"`
+			LoadedPlugins []struct {
+				Name    string `json:"name"`
+				Type    string `json:"type"`
+				Version int    `json:"version"`
+			} `json:"loaded_plugins"`
+		} `json:"body"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return ""
+	}
+	switch {
+	case resp.ID != "":
+		return resp.ID
+	case resp.Name != "" && resp.Type != "":
+		return pluginKey(resp.Type, resp.Name, resp.Version)
+	case resp.Body != nil && resp.Body.ID != "":
+		return resp.Body.ID
+	case resp.Body != nil && len(resp.Body.LoadedPlugins) > 0:
+		p := resp.Body.LoadedPlugins[0]
+		return pluginKey(p.Type, p.Name, p.Version)
+	}
+	return ""
+}
+
+func pluginKey(typ, name string, version int) string {
+	return fmt.Sprintf("%s:%s:%d", typ, name, version)
+}
+
+// capturingWriter keeps the beginning of the response body
+type capturingWriter struct {
+	negroni.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	if room := maxCapturedBody - w.body.Len(); room > 0 {
+		if len(b) < room {
+			room = len(b)
+		}
+		w.body.Write(b[:room])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Succeeded returns true when the response has a 2xx status code
+func (w *capturingWriter) Succeeded() bool {
+	return w.Status() >= 200 && w.Status() < 300
+}
@@ -0,0 +1,204 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package auth authenticates the callers of the REST API with a user name
+// and password, an API key or a JSON Web Token, and authorizes their
+// requests according to the role they are given.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// APIKeyHeader is the request header holding an API key
+const APIKeyHeader = "X-Api-Key"
+
+// The methods a caller is authenticated with
+const (
+	MethodBasic  = "basic"
+	MethodAPIKey = "api-key"
+	MethodJWT    = "jwt"
+)
+
+var (
+	// ErrNoCredentials - The error message for a request without credentials
+	ErrNoCredentials = errors.New("No credentials given")
+	// ErrInvalidCredentials - The error message for a request with wrong credentials
+	ErrInvalidCredentials = errors.New("Invalid credentials")
+)
+
+// Config holds the authentication configuration of the REST API.
+//   Note: if this struct is modified, then the constraints in the rest
+//         package need to be modified to match the field mapping that is
+//         defined here
+type Config struct {
+	// Users authenticate with HTTP basic authentication
+	Users []User `json:"users"yaml:"users"`
+	// APIKeys are given in the X-Api-Key header
+	APIKeys []APIKey `json:"api_keys"yaml:"api_keys"`
+	// JWT enables the JSON Web Tokens given as bearer tokens when set
+	JWT *JWTConfig `json:"jwt,omitempty"yaml:"jwt,omitempty"`
+}
+
+// User is a user name and password given a role.  Either the password or
+// its bcrypt hash is set.  The tasks of the user belong to its tenant, its
+// name when no tenant is set.
+type User struct {
+	Name         string `json:"name"yaml:"name"`
+	Password     string `json:"password"yaml:"password"`
+	PasswordHash string `json:"password_hash"yaml:"password_hash"`
+	Role         Role   `json:"role"yaml:"role"`
+	Tenant       string `json:"tenant"yaml:"tenant"`
+}
+
+// APIKey is a key given a role.  The name identifies the holder of the key.
+type APIKey struct {
+	Name   string `json:"name"yaml:"name"`
+	Key    string `json:"key"yaml:"key"`
+	Role   Role   `json:"role"yaml:"role"`
+	Tenant string `json:"tenant"yaml:"tenant"`
+}
+
+// Identity is an authenticated caller.
+type Identity struct {
+	Name   string
+	Role   Role
+	Method string
+	// Tenant owns the tasks created by the caller
+	Tenant string
+}
+
+// newIdentity returns an identity whose tenant defaults to its name
+func newIdentity(name string, role Role, method, tenant string) *Identity {
+	if tenant == "" {
+		tenant = name
+	}
+	return &Identity{Name: name, Role: role, Method: method, Tenant: tenant}
+}
+
+// CanAccess returns true when the caller may see and change the tasks of
+// the given tenant: admins access the tasks of every tenant, the other
+// callers only the ones of their tenant.
+func (id *Identity) CanAccess(tenant string) bool {
+	return id.Role == RoleAdmin || id.Tenant == tenant
+}
+
+// Authenticator authenticates the callers of the REST API.
+type Authenticator struct {
+	users map[string]User
+	keys  []APIKey
+	jwt   *jwtVerifier
+}
+
+// New returns an Authenticator configured from the given config.
+func New(cfg *Config) (*Authenticator, error) {
+	a := &Authenticator{users: map[string]User{}}
+	for i, u := range cfg.Users {
+		field := fmt.Sprintf("restapi::auth::users[%d]", i)
+		if u.Name == "" {
+			return nil, fmt.Errorf("User name is not set (while parsing '%s::name')", field)
+		}
+		if _, ok := a.users[u.Name]; ok {
+			return nil, fmt.Errorf("Duplicate user %s (while parsing '%s::name')", u.Name, field)
+		}
+		if (u.Password == "") == (u.PasswordHash == "") {
+			return nil, fmt.Errorf("Either a password or a password hash is required (while parsing '%s')", field)
+		}
+		if !u.Role.valid() {
+			return nil, fmt.Errorf("Unknown role '%s' (while parsing '%s::role')", u.Role, field)
+		}
+		a.users[u.Name] = u
+	}
+	for i, k := range cfg.APIKeys {
+		field := fmt.Sprintf("restapi::auth::api_keys[%d]", i)
+		if k.Key == "" {
+			return nil, fmt.Errorf("API key is not set (while parsing '%s::key')", field)
+		}
+		if !k.Role.valid() {
+			return nil, fmt.Errorf("Unknown role '%s' (while parsing '%s::role')", k.Role, field)
+		}
+		a.keys = append(a.keys, k)
+	}
+	if cfg.JWT != nil {
+		v, err := newJWTVerifier(cfg.JWT)
+		if err != nil {
+			return nil, err
+		}
+		a.jwt = v
+	}
+	return a, nil
+}
+
+// Authenticate returns the identity of the caller of the request.  The API
+// key is checked first, then the bearer token and then the user name and
+// password.
+func (a *Authenticator) Authenticate(r *http.Request) (*Identity, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		for _, k := range a.keys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(k.Key)) == 1 {
+				return newIdentity(k.Name, k.Role, MethodAPIKey, k.Tenant), nil
+			}
+		}
+		return nil, ErrInvalidCredentials
+	}
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		if a.jwt == nil {
+			return nil, ErrInvalidCredentials
+		}
+		return a.jwt.verify(strings.TrimPrefix(h, "Bearer "))
+	}
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	u, ok := a.users[name]
+	if !ok || !u.matches(password) {
+		return nil, ErrInvalidCredentials
+	}
+	return newIdentity(u.Name, u.Role, MethodBasic, u.Tenant), nil
+}
+
+func (u User) matches(password string) bool {
+	if u.PasswordHash != "" {
+		return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(password), []byte(u.Password)) == 1
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of the request carrying the identity of its
+// caller.
+func WithIdentity(r *http.Request, id *Identity) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), identityKey{}, id))
+}
+
+// IdentityFrom returns the identity of the caller of the request, nil when
+// the caller is not authenticated.
+func IdentityFrom(r *http.Request) *Identity {
+	id, _ := r.Context().Value(identityKey{}).(*Identity)
+	return id
+}
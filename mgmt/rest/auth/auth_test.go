@@ -0,0 +1,179 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func token(secret string, header, claims map[string]interface{}) string {
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	t := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	return t + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthenticate(t *testing.T) {
+	Convey("Given an authenticator with users, API keys and tokens", t, func() {
+		hash, err := bcrypt.GenerateFromPassword([]byte("hashed"), bcrypt.MinCost)
+		So(err, ShouldBeNil)
+		a, err := New(&Config{
+			Users: []User{
+				{Name: "ro", Password: "secret", Role: RoleReadOnly},
+				{Name: "ops", PasswordHash: string(hash), Role: RoleTaskAdmin, Tenant: "team-a"},
+			},
+			APIKeys: []APIKey{{Name: "ci", Key: "k3y", Role: RolePluginAdmin}},
+			JWT:     &JWTConfig{Secret: "jwt-secret", Issuer: "sso"},
+		})
+		So(err, ShouldBeNil)
+		authenticate := func(set func(*http.Request)) (*Identity, error) {
+			r := httptest.NewRequest("GET", "/v2/tasks", nil)
+			set(r)
+			return a.Authenticate(r)
+		}
+
+		Convey("users authenticate with their password or its hash", func() {
+			id, err := authenticate(func(r *http.Request) { r.SetBasicAuth("ro", "secret") })
+			So(err, ShouldBeNil)
+			So(*id, ShouldResemble, Identity{Name: "ro", Role: RoleReadOnly, Method: MethodBasic, Tenant: "ro"})
+			id, err = authenticate(func(r *http.Request) { r.SetBasicAuth("ops", "hashed") })
+			So(err, ShouldBeNil)
+			So(id.Role, ShouldEqual, RoleTaskAdmin)
+			So(id.Tenant, ShouldEqual, "team-a")
+			_, err = authenticate(func(r *http.Request) { r.SetBasicAuth("ro", "wrong") })
+			So(err, ShouldEqual, ErrInvalidCredentials)
+			_, err = authenticate(func(r *http.Request) {})
+			So(err, ShouldEqual, ErrNoCredentials)
+		})
+		Convey("API keys are given in a header", func() {
+			id, err := authenticate(func(r *http.Request) { r.Header.Set(APIKeyHeader, "k3y") })
+			So(err, ShouldBeNil)
+			So(*id, ShouldResemble, Identity{Name: "ci", Role: RolePluginAdmin, Method: MethodAPIKey, Tenant: "ci"})
+			_, err = authenticate(func(r *http.Request) { r.Header.Set(APIKeyHeader, "other") })
+			So(err, ShouldEqual, ErrInvalidCredentials)
+		})
+		Convey("tokens are checked for their signature and claims", func() {
+			hs256 := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+			claims := map[string]interface{}{"sub": "alice", "role": "admin", "iss": "sso", "exp": time.Now().Add(time.Hour).Unix()}
+			bearer := func(tk string) func(*http.Request) {
+				return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+tk) }
+			}
+			id, err := authenticate(bearer(token("jwt-secret", hs256, claims)))
+			So(err, ShouldBeNil)
+			So(*id, ShouldResemble, Identity{Name: "alice", Role: RoleAdmin, Method: MethodJWT, Tenant: "alice"})
+			claims["tenant"] = "team-b"
+			id, err = authenticate(bearer(token("jwt-secret", hs256, claims)))
+			So(err, ShouldBeNil)
+			So(id.Tenant, ShouldEqual, "team-b")
+
+			_, err = authenticate(bearer(token("other-secret", hs256, claims)))
+			So(err, ShouldEqual, ErrInvalidCredentials)
+			_, err = authenticate(bearer(token("jwt-secret", map[string]interface{}{"alg": "none"}, claims)))
+			So(err, ShouldEqual, ErrInvalidCredentials)
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			_, err = authenticate(bearer(token("jwt-secret", hs256, claims)))
+			So(err, ShouldEqual, ErrInvalidCredentials)
+			claims["exp"] = time.Now().Add(time.Hour).Unix()
+			claims["iss"] = "elsewhere"
+			_, err = authenticate(bearer(token("jwt-secret", hs256, claims)))
+			So(err, ShouldEqual, ErrInvalidCredentials)
+		})
+	})
+	Convey("Invalid configurations are rejected", t, func() {
+		_, err := New(&Config{Users: []User{{Name: "u", Password: "p", Role: "root"}}})
+		So(err, ShouldNotBeNil)
+		_, err = New(&Config{Users: []User{{Name: "u", Role: RoleAdmin}}})
+		So(err, ShouldNotBeNil)
+		_, err = New(&Config{APIKeys: []APIKey{{Role: RoleAdmin}}})
+		So(err, ShouldNotBeNil)
+		_, err = New(&Config{JWT: &JWTConfig{}})
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestAuthorize(t *testing.T) {
+	Convey("Roles allow the requests they are given permission for", t, func() {
+		cases := []struct {
+			method, path string
+			allowed      []Role
+		}{
+			{"GET", "/v2/plugins", []Role{RoleReadOnly, RoleTaskAdmin, RolePluginAdmin, RoleAdmin}},
+			{"GET", "/v2/events", []Role{RoleReadOnly, RoleTaskAdmin, RolePluginAdmin, RoleAdmin}},
+			{"GET", "/v2/audit", []Role{RoleAdmin}},
+			{"GET", "/v2/cardinality", []Role{RoleAdmin}},
+			{"GET", "/v2/subscriptions", []Role{RoleAdmin}},
+			{"GET", "/v2/snapshots", []Role{RoleAdmin}},
+			{"GET", "/v2/snapshots/s", []Role{RoleAdmin}},
+			{"GET", "/v2/advisor", []Role{RoleReadOnly, RoleTaskAdmin, RolePluginAdmin, RoleAdmin}},
+			{"POST", "/v2/tasks", []Role{RoleTaskAdmin, RoleAdmin}},
+			{"PUT", "/v1/tasks/abc/start", []Role{RoleTaskAdmin, RoleAdmin}},
+			{"POST", "/v2/maintenance", []Role{RoleTaskAdmin, RoleAdmin}},
+			{"POST", "/v2/plugins", []Role{RolePluginAdmin, RoleAdmin}},
+			{"PUT", "/v2/plugins/collector/mock/1/config", []Role{RolePluginAdmin, RoleAdmin}},
+			{"POST", "/v2/snapshots/s/restore", []Role{RoleAdmin}},
+			{"POST", "/v2/templates", []Role{RoleAdmin}},
+			{"DELETE", "/v2/templates/t", []Role{RoleAdmin}},
+			{"POST", "/v2/templates/t/tasks", []Role{RoleTaskAdmin, RoleAdmin}},
+			{"POST", "/v1/tribe/agreements", []Role{RoleAdmin}},
+		}
+		for _, c := range cases {
+			p := RequiredPermission(c.method, c.path)
+			for _, r := range []Role{RoleReadOnly, RoleTaskAdmin, RolePluginAdmin, RoleAdmin} {
+				So(r.Allows(p), ShouldEqual, containsRole(c.allowed, r))
+			}
+		}
+	})
+	Convey("Callers only access the tasks of their tenant unless they are admins", t, func() {
+		So(TaskID("/v2/tasks/abc"), ShouldEqual, "abc")
+		So(TaskID("/v1/tasks/abc/start"), ShouldEqual, "abc")
+		So(TaskID("/v2/tasks"), ShouldEqual, "")
+		So(TaskID("/v2/tasks/import"), ShouldEqual, "")
+		So(TaskID("/v2/plugins/collector/abc"), ShouldEqual, "")
+		ops := &Identity{Name: "ops", Role: RoleTaskAdmin, Tenant: "team-a"}
+		So(ops.CanAccess("team-a"), ShouldBeTrue)
+		So(ops.CanAccess("team-b"), ShouldBeFalse)
+		So(ops.CanAccess(""), ShouldBeFalse)
+		admin := &Identity{Name: "root", Role: RoleAdmin, Tenant: "root"}
+		So(admin.CanAccess("team-b"), ShouldBeTrue)
+	})
+}
+
+func containsRole(roles []Role, r Role) bool {
+	for _, role := range roles {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRoleClaim   = "role"
+	defaultTenantClaim = "tenant"
+)
+
+// JWTConfig configures the JSON Web Tokens accepted by the REST API.  Tokens
+// are signed with HMAC SHA-256 (HS256).  The subject of a token names the
+// caller, its role is given in the role claim and its tenant, the subject by
+// default, in the tenant claim.
+type JWTConfig struct {
+	// Secret is the key tokens are signed with
+	Secret string `json:"secret"yaml:"secret"`
+	// Issuer is the issuer tokens must have when set
+	Issuer string `json:"issuer"yaml:"issuer"`
+	// RoleClaim is the claim holding the role; it defaults to "role"
+	RoleClaim string `json:"role_claim"yaml:"role_claim"`
+	// TenantClaim is the claim holding the tenant; it defaults to "tenant"
+	TenantClaim string `json:"tenant_claim"yaml:"tenant_claim"`
+}
+
+type jwtVerifier struct {
+	secret      []byte
+	issuer      string
+	roleClaim   string
+	tenantClaim string
+}
+
+func newJWTVerifier(cfg *JWTConfig) (*jwtVerifier, error) {
+	if cfg.Secret == "" {
+		return nil, errors.New("JWT secret is not set (while parsing 'restapi::auth::jwt::secret')")
+	}
+	v := &jwtVerifier{
+		secret:      []byte(cfg.Secret),
+		issuer:      cfg.Issuer,
+		roleClaim:   cfg.RoleClaim,
+		tenantClaim: cfg.TenantClaim,
+	}
+	if v.roleClaim == "" {
+		v.roleClaim = defaultRoleClaim
+	}
+	if v.tenantClaim == "" {
+		v.tenantClaim = defaultTenantClaim
+	}
+	return v, nil
+}
+
+// verify checks the signature and the claims of the token and returns the
+// identity it holds
+func (v *jwtVerifier) verify(token string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCredentials
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return nil, ErrInvalidCredentials
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, ErrInvalidCredentials
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	now := float64(time.Now().Unix())
+	if exp, ok := claims["exp"].(float64); ok && now >= exp {
+		return nil, ErrInvalidCredentials
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now < nbf {
+		return nil, ErrInvalidCredentials
+	}
+	if v.issuer != "" && claims["iss"] != v.issuer {
+		return nil, ErrInvalidCredentials
+	}
+	sub, _ := claims["sub"].(string)
+	role, _ := claims[v.roleClaim].(string)
+	if !Role(role).valid() {
+		return nil, ErrInvalidCredentials
+	}
+	tenant, _ := claims[v.tenantClaim].(string)
+	return newIdentity(sub, Role(role), MethodJWT, tenant), nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,128 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"strings"
+)
+
+// Role tells which requests a caller may make.
+type Role string
+
+// The roles given to the callers
+const (
+	// RoleReadOnly may only read
+	RoleReadOnly Role = "read-only"
+	// RoleTaskAdmin may also create, change and remove tasks
+	RoleTaskAdmin Role = "task-admin"
+	// RolePluginAdmin may also load, configure and unload plugins
+	RolePluginAdmin Role = "plugin-admin"
+	// RoleAdmin may make any request
+	RoleAdmin Role = "admin"
+)
+
+// Permission is what a request needs to be allowed.
+type Permission int
+
+// The permissions of the requests
+const (
+	// PermissionRead is needed to read anything but the reports over the
+	// tasks of every tenant
+	PermissionRead Permission = iota
+	// PermissionTasks is needed to change tasks and maintenance windows and
+	// to create tasks from templates
+	PermissionTasks
+	// PermissionPlugins is needed to change plugins and their config
+	PermissionPlugins
+	// PermissionAdmin is needed for any other change, e.g. restoring a
+	// snapshot, registering a task template or joining a tribe agreement,
+	// and to read the audit log, the cardinality and subscription reports
+	PermissionAdmin
+)
+
+func (r Role) valid() bool {
+	switch r {
+	case RoleReadOnly, RoleTaskAdmin, RolePluginAdmin, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// Allows returns true when the role grants the given permission
+func (r Role) Allows(p Permission) bool {
+	switch r {
+	case RoleAdmin:
+		return true
+	case RoleTaskAdmin:
+		return p == PermissionRead || p == PermissionTasks
+	case RolePluginAdmin:
+		return p == PermissionRead || p == PermissionPlugins
+	case RoleReadOnly:
+		return p == PermissionRead
+	}
+	return false
+}
+
+// RequiredPermission returns the permission needed by a request to the
+// given path with the given method
+func RequiredPermission(method, path string) Permission {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		// the reports over the tasks of every tenant, and the snapshots of
+		// them, are only read by admins
+		if len(parts) >= 2 && parts[0] == "v2" {
+			switch parts[1] {
+			case "audit", "cardinality", "subscriptions", "snapshots":
+				return PermissionAdmin
+			}
+		}
+		return PermissionRead
+	}
+	if len(parts) < 2 || (parts[0] != "v1" && parts[0] != "v2") {
+		return PermissionAdmin
+	}
+	switch parts[1] {
+	case "tasks", "maintenance":
+		return PermissionTasks
+	case "plugins":
+		return PermissionPlugins
+	case "templates":
+		// creating a task from a template
+		if len(parts) == 4 && parts[3] == "tasks" {
+			return PermissionTasks
+		}
+	}
+	return PermissionAdmin
+}
+
+// TaskID returns the id of the task a request to the given path is about,
+// empty when the path is not the one of a task
+func TaskID(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 3 || (parts[0] != "v1" && parts[0] != "v2") || parts[1] != "tasks" {
+		return ""
+	}
+	switch parts[2] {
+	case "import", "simulate":
+		return ""
+	}
+	return parts[2]
+}
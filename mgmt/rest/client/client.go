@@ -112,6 +112,20 @@ func Timeout(t time.Duration) metaOp {
 	}
 }
 
+//ClientCertificate is an option that can be provided to the func client.New in order to authenticate with a TLS client certificate.
+func ClientCertificate(cert tls.Certificate) metaOp {
+	return func(c *Client) {
+		t := c.http.Transport.(*http.Transport)
+		c.http.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: t.TLSClientConfig.InsecureSkipVerify,
+				Certificates:       []tls.Certificate{cert},
+			},
+			IdleConnTimeout: t.IdleConnTimeout,
+		}
+	}
+}
+
 var (
 	secureTransport = &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: false},
@@ -34,7 +34,7 @@ import (
 )
 
 type Schedule struct {
-	// Type specifies the type of the schedule. Currently, the type of "simple", "windowed" and "cron" are supported.
+	// Type specifies the type of the schedule. Currently, the type of "simple", "windowed", "cron" and "composite" are supported.
 	Type string `json:"type,omitempty"`
 	// Interval specifies the time duration.
 	Interval string `json:"interval,omitempty"`
@@ -45,6 +45,27 @@ type Schedule struct {
 	// Count specifies the number of expected runs (defaults to 0 what means no limit, set to 1 means single run task).
 	// Count is supported by "simple" and "windowed" schedules
 	Count uint `json:"count,omitempty"`
+	// Aligned fires the schedule on the multiples of its interval.
+	// Aligned is supported by "simple" and "windowed" schedules
+	Aligned bool `json:"aligned,omitempty"`
+	// Schedules specifies the schedules of a "composite" schedule, which fires whenever one of them fires.
+	Schedules []Schedule `json:"schedules,omitempty"`
+}
+
+// coreSchedule returns the schedule as sent to snapteld
+func (s *Schedule) coreSchedule() *core.Schedule {
+	cs := &core.Schedule{
+		Type:           s.Type,
+		Interval:       s.Interval,
+		StartTimestamp: s.StartTimestamp,
+		StopTimestamp:  s.StopTimestamp,
+		Count:          s.Count,
+		Aligned:        s.Aligned,
+	}
+	for i := range s.Schedules {
+		cs.Schedules = append(cs.Schedules, *s.Schedules[i].coreSchedule())
+	}
+	return cs
 }
 
 // CreateTask creates a task given the schedule, workflow, task name, and task state.
@@ -53,13 +74,7 @@ type Schedule struct {
 // A ScheduledTask is returned if it succeeds, otherwise an error is returned.
 func (c *Client) CreateTask(s *Schedule, wf *wmap.WorkflowMap, name string, deadline string, startTask bool, maxFailures int) *CreateTaskResult {
 	t := core.TaskCreationRequest{
-		Schedule: &core.Schedule{
-			Type:           s.Type,
-			Interval:       s.Interval,
-			StartTimestamp: s.StartTimestamp,
-			StopTimestamp:  s.StopTimestamp,
-			Count:          s.Count,
-		},
+		Schedule:    s.coreSchedule(),
 		Workflow:    wf,
 		Start:       startTask,
 		MaxFailures: maxFailures,
@@ -1,5 +1,12 @@
 package rest
 
+import (
+	"github.com/intelsdi-x/snap/mgmt/rest/admission"
+	"github.com/intelsdi-x/snap/mgmt/rest/audit"
+	"github.com/intelsdi-x/snap/mgmt/rest/auth"
+	"github.com/intelsdi-x/snap/mgmt/rest/snapshot"
+)
+
 // default configuration values
 const (
 	defaultEnable          bool   = true
@@ -8,11 +15,14 @@ const (
 	defaultHTTPS           bool   = false
 	defaultRestCertificate string = ""
 	defaultRestKey         string = ""
+	defaultRestClientCA    string = ""
 	defaultAuth            bool   = false
 	defaultAuthPassword    string = ""
 	defaultPortSetByConfig bool   = false
 	defaultPprof           bool   = false
+	defaultMetrics         bool   = false
 	defaultCorsd           string = ""
+	defaultSocketMode      string = ""
 )
 
 // holds the configuration passed in through the SNAP config file
@@ -26,11 +36,26 @@ type Config struct {
 	HTTPS            bool   `json:"https"yaml:"https"`
 	RestCertificate  string `json:"rest_certificate"yaml:"rest_certificate"`
 	RestKey          string `json:"rest_key"yaml:"rest_key"`
+	RestClientCA     string `json:"rest_client_ca"yaml:"rest_client_ca"`
 	RestAuth         bool   `json:"rest_auth"yaml:"rest_auth"`
 	RestAuthPassword string `json:"rest_auth_password"yaml:"rest_auth_password"`
 	portSetByConfig  bool   ``
 	Pprof            bool   `json:"pprof"yaml:"pprof"`
+	Metrics          bool   `json:"metrics"yaml:"metrics"`
 	Corsd            string `json:"allowed_origins"yaml:"allowed_origins"`
+	SocketMode       string `json:"socket_mode"yaml:"socket_mode"`
+	// Admission configures the review of task creation requests; nil
+	// disables admission control
+	Admission *admission.Config `json:"admission,omitempty"yaml:"admission,omitempty"`
+	// Snapshots configures the configuration snapshots; nil disables them
+	Snapshots *snapshot.Config `json:"snapshots,omitempty"yaml:"snapshots,omitempty"`
+	// Audit configures the audit log of the task and plugin operations; nil
+	// disables it
+	Audit *audit.Config `json:"audit,omitempty"yaml:"audit,omitempty"`
+	// Auth configures the users, API keys and JSON Web Tokens authenticating
+	// the callers of the REST API and their roles; nil leaves rest_auth as
+	// the only authentication
+	Auth *auth.Config `json:"auth,omitempty"yaml:"auth,omitempty"`
 }
 
 const (
@@ -56,6 +81,9 @@ const (
 					"rest_key" : {
 						"type": "string"
 					},
+					"rest_client_ca" : {
+						"type": "string"
+					},
 					"port" : {
 						"type": "integer",
 						"minimum": 1,
@@ -67,8 +95,160 @@ const (
 					"pprof": {
 						"type": "boolean"
 					},
+					"metrics": {
+						"type": "boolean"
+					},
 					"allowed_origins" : {
 						"type": "string"
+					},
+					"socket_mode" : {
+						"type": "string",
+						"pattern": "^(0?[0-7]{3})?$"
+					},
+					"admission" : {
+						"type": ["object", "null"],
+						"properties" : {
+							"webhook_url" : {
+								"type": "string"
+							},
+							"webhook_timeout" : {
+								"type": "string"
+							},
+							"name_pattern" : {
+								"type": "string"
+							},
+							"required_tags" : {
+								"type": "array",
+								"items": { "type": "string" }
+							},
+							"default_tags" : {
+								"type": "object",
+								"additionalProperties": { "type": "string" }
+							},
+							"forbidden_publishers" : {
+								"type": "array",
+								"items": { "type": "string" }
+							}
+						},
+						"additionalProperties": false
+					},
+					"snapshots" : {
+						"type": ["object", "null"],
+						"properties" : {
+							"path" : {
+								"type": "string"
+							},
+							"interval" : {
+								"type": "string"
+							},
+							"retention" : {
+								"type": "integer"
+							}
+						},
+						"additionalProperties": false
+					},
+					"audit" : {
+						"type": ["object", "null"],
+						"properties" : {
+							"path" : {
+								"type": "string"
+							},
+							"retention" : {
+								"type": "integer",
+								"minimum": 0
+							},
+							"publisher" : {
+								"type": ["object", "null"],
+								"properties" : {
+									"plugin_name" : {
+										"type": "string"
+									},
+									"plugin_version" : {
+										"type": "integer"
+									},
+									"config" : {
+										"type": "object"
+									}
+								},
+								"required": ["plugin_name"],
+								"additionalProperties": false
+							}
+						},
+						"additionalProperties": false
+					},
+					"auth" : {
+						"type": ["object", "null"],
+						"properties" : {
+							"users" : {
+								"type": "array",
+								"items": {
+									"type": "object",
+									"properties" : {
+										"name" : {
+											"type": "string"
+										},
+										"password" : {
+											"type": "string"
+										},
+										"password_hash" : {
+											"type": "string"
+										},
+										"role" : {
+											"type": "string",
+											"enum": ["read-only", "task-admin", "plugin-admin", "admin"]
+										},
+										"tenant" : {
+											"type": "string"
+										}
+									},
+									"required": ["name", "role"],
+									"additionalProperties": false
+								}
+							},
+							"api_keys" : {
+								"type": "array",
+								"items": {
+									"type": "object",
+									"properties" : {
+										"name" : {
+											"type": "string"
+										},
+										"key" : {
+											"type": "string"
+										},
+										"role" : {
+											"type": "string",
+											"enum": ["read-only", "task-admin", "plugin-admin", "admin"]
+										},
+										"tenant" : {
+											"type": "string"
+										}
+									},
+									"required": ["key", "role"],
+									"additionalProperties": false
+								}
+							},
+							"jwt" : {
+								"type": ["object", "null"],
+								"properties" : {
+									"secret" : {
+										"type": "string"
+									},
+									"issuer" : {
+										"type": "string"
+									},
+									"role_claim" : {
+										"type": "string"
+									},
+									"tenant_claim" : {
+										"type": "string"
+									}
+								},
+								"required": ["secret"],
+								"additionalProperties": false
+							}
+						},
+						"additionalProperties": false
 					}
 				},
 				"additionalProperties": false
@@ -85,11 +265,14 @@ func GetDefaultConfig() *Config {
 		HTTPS:            defaultHTTPS,
 		RestCertificate:  defaultRestCertificate,
 		RestKey:          defaultRestKey,
+		RestClientCA:     defaultRestClientCA,
 		RestAuth:         defaultAuth,
 		RestAuthPassword: defaultAuthPassword,
 		portSetByConfig:  defaultPortSetByConfig,
 		Pprof:            defaultPprof,
+		Metrics:          defaultMetrics,
 		Corsd:            defaultCorsd,
+		SocketMode:       defaultSocketMode,
 	}
 }
 
@@ -32,7 +32,7 @@ var (
 	}
 	flAPIAddr = cli.StringFlag{
 		Name:   "api-addr, b",
-		Usage:  "API Address[:port] to bind to/listen on. Default: empty string => listen on all interfaces",
+		Usage:  "API Address[:port] or unix:///path/to/socket to bind to/listen on. Default: empty string => listen on all interfaces",
 		EnvVar: "SNAP_ADDR",
 	}
 	flAPIPort = cli.StringFlag{
@@ -52,6 +52,10 @@ var (
 		Name:  "rest-key",
 		Usage: "A path to a key file to use for HTTPS deployment of Snap's REST API",
 	}
+	flRestClientCA = cli.StringFlag{
+		Name:  "rest-client-ca",
+		Usage: "A path to the CA certificates verifying the client certificates required by Snap's REST API over HTTPS",
+	}
 	flRestAuth = cli.BoolFlag{
 		Name:  "rest-auth",
 		Usage: "Enables Snap's REST API authentication",
@@ -60,11 +64,15 @@ var (
 		Name:  "pprof",
 		Usage: "Enables profiling tools",
 	}
+	flRestMetrics = cli.BoolFlag{
+		Name:  "rest-metrics",
+		Usage: "Enables the /metrics endpoint of Snap's REST API exposing the internals of snapteld in the Prometheus format",
+	}
 	flCorsd = cli.StringFlag{
 		Name:  "allowed_origins",
 		Usage: "Define Cors allowed origins",
 	}
 
 	// Flags consumed by snapteld
-	Flags = []cli.Flag{flAPIDisabled, flAPIAddr, flAPIPort, flRestHTTPS, flRestCert, flRestKey, flRestAuth, flPProf, flCorsd}
+	Flags = []cli.Flag{flAPIDisabled, flAPIAddr, flAPIPort, flRestHTTPS, flRestCert, flRestKey, flRestClientCA, flRestAuth, flPProf, flRestMetrics, flCorsd}
 )
@@ -0,0 +1,271 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// metricsContentType is the content type of the Prometheus text format
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// measuresRuns is implemented by a task manager keeping the histogram of
+// the durations of the task runs
+type measuresRuns interface {
+	RunDurationHistogram() core.Histogram
+}
+
+// measuresWorkQueues is implemented by a task manager reporting the depth of
+// its work queues
+type measuresWorkQueues interface {
+	WorkQueueDepths() []core.WorkQueueDepth
+}
+
+// boundsInflightMetrics is implemented by a task manager bounding the memory
+// of the metrics being published
+type boundsInflightMetrics interface {
+	InflightMetrics() core.InflightMetrics
+}
+
+// countsPluginRestarts is implemented by a metric manager counting the
+// restarts of the plugins
+type countsPluginRestarts interface {
+	PluginRestarts() []core.PluginRestarts
+}
+
+// accountsResources is implemented by the tasks accounting for the
+// resources their runs use
+type accountsResources interface {
+	ResourceUsage() core.TaskResourceUsage
+}
+
+// countsDeliveries is implemented by the tasks counting the deliveries of
+// their metrics to their publishers
+type countsDeliveries interface {
+	DeliveryStats() core.TaskDeliveryStats
+}
+
+func (s *Server) addMetricsRoute() {
+	if s.metrics {
+		s.r.GET("/metrics", s.getMetrics)
+	}
+}
+
+// getMetrics exposes the internals of snapteld in the Prometheus text format
+func (s *Server) getMetrics(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var buf bytes.Buffer
+	if s.taskManager != nil {
+		tasks := s.taskManager.GetTasks()
+		writeTaskStates(&buf, tasks)
+		writeTaskUsage(&buf, tasks)
+		writeTaskDeliveries(&buf, tasks)
+	}
+	if m, ok := s.taskManager.(measuresRuns); ok {
+		writeHistogram(&buf, "snap_task_run_duration_seconds", "Durations of the task runs in seconds.", m.RunDurationHistogram())
+	}
+	if m, ok := s.taskManager.(measuresWorkQueues); ok {
+		writeHeader(&buf, "snap_work_queue_depth", "Number of jobs waiting for a worker in the work queues of the scheduler.", "gauge")
+		for _, d := range m.WorkQueueDepths() {
+			queue := d.Queue
+			if queue == "" {
+				queue = "default"
+			}
+			writeSample(&buf, "snap_work_queue_depth", labels("queue", queue, "phase", d.Phase), float64(d.Depth))
+		}
+	}
+	if m, ok := s.taskManager.(boundsInflightMetrics); ok {
+		if in := m.InflightMetrics(); in.Budget > 0 {
+			writeInflightMetrics(&buf, in)
+		}
+	}
+	if m, ok := s.metricManager.(countsPluginRestarts); ok {
+		restarts := m.PluginRestarts()
+		sort.Sort(byPlugin(restarts))
+		writeHeader(&buf, "snap_plugin_restarts_total", "Number of restarts of the running plugins after they failed.", "counter")
+		for _, p := range restarts {
+			writeSample(&buf, "snap_plugin_restarts_total", labels("type", p.Type, "name", p.Name, "version", strconv.Itoa(p.Version)), float64(p.Restarts))
+		}
+	}
+	w.Header().Set("Content-Type", metricsContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// writeInflightMetrics writes the state of the memory budget of the metrics
+// being published
+func writeInflightMetrics(buf *bytes.Buffer, in core.InflightMetrics) {
+	writeHeader(buf, "snap_inflight_budget_bytes", "Memory budget of the metrics being published in bytes.", "gauge")
+	writeSample(buf, "snap_inflight_budget_bytes", "", float64(in.Budget))
+	writeHeader(buf, "snap_inflight_bytes", "Estimated memory used by the metrics being published in bytes.", "gauge")
+	writeSample(buf, "snap_inflight_bytes", "", float64(in.Used))
+	writeHeader(buf, "snap_inflight_spilled_batches", "Number of batches of metrics spilled to disk waiting to be published.", "gauge")
+	writeSample(buf, "snap_inflight_spilled_batches", "", float64(in.SpilledBatches))
+	writeHeader(buf, "snap_inflight_spilled_bytes", "Estimated memory of the batches of metrics spilled to disk in bytes.", "gauge")
+	writeSample(buf, "snap_inflight_spilled_bytes", "", float64(in.SpilledBytes))
+	writeHeader(buf, "snap_inflight_spills_total", "Number of batches of metrics spilled to disk.", "counter")
+	writeSample(buf, "snap_inflight_spills_total", "", float64(in.SpillsTotal))
+	writeHeader(buf, "snap_inflight_dropped_metrics_total", "Number of metrics dropped because they did not fit in the memory budget.", "counter")
+	writeSample(buf, "snap_inflight_dropped_metrics_total", "", float64(in.DroppedMetrics))
+}
+
+type byPlugin []core.PluginRestarts
+
+func (p byPlugin) Len() int      { return len(p) }
+func (p byPlugin) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p byPlugin) Less(i, j int) bool {
+	if p[i].Type != p[j].Type {
+		return p[i].Type < p[j].Type
+	}
+	if p[i].Name != p[j].Name {
+		return p[i].Name < p[j].Name
+	}
+	return p[i].Version < p[j].Version
+}
+
+// writeTaskStates writes the number of tasks in each state, including the
+// states no task is in
+func writeTaskStates(buf *bytes.Buffer, tasks map[string]core.Task) {
+	counts := map[string]int{}
+	for _, state := range core.TaskStateLookup {
+		counts[state] = 0
+	}
+	for _, t := range tasks {
+		counts[t.State().String()]++
+	}
+	states := make([]string, 0, len(counts))
+	for state := range counts {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	writeHeader(buf, "snap_tasks", "Number of tasks by state.", "gauge")
+	for _, state := range states {
+		writeSample(buf, "snap_tasks", labels("state", state), float64(counts[state]))
+	}
+}
+
+// writeTaskUsage writes the resources used by the tasks accounting for them
+func writeTaskUsage(buf *bytes.Buffer, tasks map[string]core.Task) {
+	ids := make([]string, 0, len(tasks))
+	usages := map[string]core.TaskResourceUsage{}
+	for id, t := range tasks {
+		if u, ok := t.(accountsResources); ok {
+			ids = append(ids, id)
+			usages[id] = u.ResourceUsage()
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	sort.Strings(ids)
+	taskLabels := func(id string) string {
+		return labels("task_id", id, "task_name", tasks[id].GetName())
+	}
+	writeHeader(buf, "snap_task_workflow_seconds_total", "Time the workers spent running the jobs of the tasks in seconds.", "counter")
+	for _, id := range ids {
+		writeSample(buf, "snap_task_workflow_seconds_total", taskLabels(id), usages[id].WorkflowSeconds)
+	}
+	writeHeader(buf, "snap_task_metrics_processed_total", "Number of metrics handled by the jobs of the tasks.", "counter")
+	for _, id := range ids {
+		writeSample(buf, "snap_task_metrics_processed_total", taskLabels(id), float64(usages[id].MetricsProcessed))
+	}
+	writeHeader(buf, "snap_task_processed_bytes_total", "Estimated size of the metrics handled by the jobs of the tasks in bytes.", "counter")
+	for _, id := range ids {
+		writeSample(buf, "snap_task_processed_bytes_total", taskLabels(id), float64(usages[id].BytesProcessed))
+	}
+}
+
+// writeTaskDeliveries writes the deliveries of the metrics of the tasks
+// counting them
+func writeTaskDeliveries(buf *bytes.Buffer, tasks map[string]core.Task) {
+	ids := make([]string, 0, len(tasks))
+	stats := map[string]core.TaskDeliveryStats{}
+	for id, t := range tasks {
+		if d, ok := t.(countsDeliveries); ok {
+			ids = append(ids, id)
+			stats[id] = d.DeliveryStats()
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	sort.Strings(ids)
+	taskLabels := func(id string) string {
+		return labels("task_id", id, "task_name", tasks[id].GetName())
+	}
+	writeHeader(buf, "snap_task_published_metrics_total", "Number of metrics of the tasks acknowledged by their publishers.", "counter")
+	for _, id := range ids {
+		writeSample(buf, "snap_task_published_metrics_total", taskLabels(id), float64(stats[id].Published))
+	}
+	writeHeader(buf, "snap_task_publish_retries_total", "Number of batches of the tasks published again after a retryable failure.", "counter")
+	for _, id := range ids {
+		writeSample(buf, "snap_task_publish_retries_total", taskLabels(id), float64(stats[id].Retries))
+	}
+	writeHeader(buf, "snap_task_publish_failures_total", "Number of failed publish calls of the tasks by kind of error.", "counter")
+	for _, id := range ids {
+		writeSample(buf, "snap_task_publish_failures_total", labels("task_id", id, "task_name", tasks[id].GetName(), "kind", core.RetryablePublishError), float64(stats[id].RetryableFailures))
+		writeSample(buf, "snap_task_publish_failures_total", labels("task_id", id, "task_name", tasks[id].GetName(), "kind", core.FatalPublishError), float64(stats[id].FatalFailures))
+	}
+	writeHeader(buf, "snap_task_undelivered_metrics_total", "Number of metrics of the tasks given up after a fatal failure or the deadline of the publish.", "counter")
+	for _, id := range ids {
+		writeSample(buf, "snap_task_undelivered_metrics_total", taskLabels(id), float64(stats[id].Undelivered))
+	}
+	writeHeader(buf, "snap_task_deduplicated_metrics_total", "Number of metrics of the tasks not published again in a batch acknowledged already.", "counter")
+	for _, id := range ids {
+		writeSample(buf, "snap_task_deduplicated_metrics_total", taskLabels(id), float64(stats[id].Deduplicated))
+	}
+}
+
+func writeHistogram(buf *bytes.Buffer, name, help string, h core.Histogram) {
+	writeHeader(buf, name, help, "histogram")
+	var cumulative uint64
+	for i, bound := range h.Bounds {
+		cumulative += h.Counts[i]
+		writeSample(buf, name+"_bucket", labels("le", strconv.FormatFloat(bound, 'g', -1, 64)), float64(cumulative))
+	}
+	writeSample(buf, name+"_bucket", labels("le", "+Inf"), float64(h.Count))
+	writeSample(buf, name+"_sum", "", h.Sum)
+	writeSample(buf, name+"_count", "", float64(h.Count))
+}
+
+func writeHeader(buf *bytes.Buffer, name, help, typ string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func writeSample(buf *bytes.Buffer, name, labels string, value float64) {
+	fmt.Fprintf(buf, "%s%s %s\n", name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// labels formats the given label names and values, e.g. {state="Running"}
+func labels(nameValues ...string) string {
+	pairs := make([]string, 0, len(nameValues)/2)
+	for i := 0; i+1 < len(nameValues); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", nameValues[i], strconv.Quote(nameValues[i+1])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
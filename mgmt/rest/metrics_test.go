@@ -0,0 +1,53 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+func TestMetricsFormat(t *testing.T) {
+	Convey("Histograms are written with cumulative buckets", t, func() {
+		h := core.NewHistogram([]float64{0.5, 1})
+		for _, v := range []float64{0.25, 0.75, 2} {
+			h.Observe(v)
+		}
+		var buf bytes.Buffer
+		writeHistogram(&buf, "run_seconds", "Run durations.", h.Copy())
+		So(buf.String(), ShouldEqual, `# HELP run_seconds Run durations.
+# TYPE run_seconds histogram
+run_seconds_bucket{le="0.5"} 1
+run_seconds_bucket{le="1"} 2
+run_seconds_bucket{le="+Inf"} 3
+run_seconds_sum 3
+run_seconds_count 3
+`)
+	})
+	Convey("Label values are quoted", t, func() {
+		So(labels("name", `say "hi"`, "version", "1"), ShouldEqual, `{name="say \"hi\"",version="1"}`)
+	})
+}
@@ -37,6 +37,7 @@ import (
 
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/mgmt/rest/api"
 	"github.com/intelsdi-x/snap/mgmt/rest/v2/mock"
 	log "github.com/sirupsen/logrus"
 	. "github.com/smartystreets/goconvey/convey"
@@ -57,6 +58,13 @@ func startV2API(cfg *mockConfig, testType string) *restAPIInstance {
 	case "task":
 		mockTaskManager := &mock.MockTaskManager{}
 		r.BindTaskManager(mockTaskManager)
+	case "info":
+		r.BindDaemonInfo(&api.DaemonInfo{
+			Version:        "test",
+			Subsystems:     map[string]bool{"tribe": false},
+			ScheduleTypes:  []string{"simple"},
+			PluginRPCTypes: []string{"grpc"},
+		})
 	}
 	go func(ch <-chan error) {
 		// Block on the error channel. Will return exit status 1 for an error or
@@ -464,3 +472,28 @@ func TestV2Metric(t *testing.T) {
 		})
 	})
 }
+
+func TestV2Info(t *testing.T) {
+	Convey("Test Info REST API V2", t, func() {
+		Convey("Get info - v2/info", func() {
+			r := startV2API(getDefaultMockConfig(), "info")
+			resp, err := http.Get(
+				fmt.Sprintf("http://localhost:%d/v2/info", r.port))
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 200)
+			info := api.DaemonInfo{}
+			So(json.NewDecoder(resp.Body).Decode(&info), ShouldBeNil)
+			So(info.Version, ShouldEqual, "test")
+			So(info.Subsystems, ShouldResemble, map[string]bool{"tribe": false})
+			So(info.ScheduleTypes, ShouldResemble, []string{"simple"})
+			So(info.PluginRPCTypes, ShouldResemble, []string{"grpc"})
+		})
+		Convey("Get info without daemon info - v2/info", func() {
+			r := startV2API(getDefaultMockConfig(), "metric")
+			resp, err := http.Get(
+				fmt.Sprintf("http://localhost:%d/v2/info", r.port))
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 404)
+		})
+	})
+}
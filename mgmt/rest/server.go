@@ -26,6 +26,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"strconv"
 	"sync"
 	"time"
@@ -37,9 +38,15 @@ import (
 
 	"strings"
 
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/admission"
 	"github.com/intelsdi-x/snap/mgmt/rest/api"
+	"github.com/intelsdi-x/snap/mgmt/rest/audit"
+	"github.com/intelsdi-x/snap/mgmt/rest/auth"
+	"github.com/intelsdi-x/snap/mgmt/rest/snapshot"
 	"github.com/intelsdi-x/snap/mgmt/rest/v1"
 	"github.com/intelsdi-x/snap/mgmt/rest/v2"
+	"github.com/intelsdi-x/snap/pkg/netutil"
 )
 
 const (
@@ -50,6 +57,8 @@ const (
 
 var (
 	ErrBadCert = errors.New("Invalid certificate given")
+	// ErrBadClientCA - The error message for a client CA file without any certificate
+	ErrBadClientCA = errors.New("Invalid client CA certificates given")
 
 	restLogger     = log.WithField("_module", "_mgmt-rest")
 	protocolPrefix = "http"
@@ -62,6 +71,7 @@ type Server struct {
 	snapTLS        *snapTLS
 	auth           bool
 	pprof          bool
+	metrics        bool
 	authpwd        string
 	addrString     string
 	addr           net.Addr
@@ -72,6 +82,18 @@ type Server struct {
 	// the following instance variables are used to cleanly shutdown the server
 	serverListener net.Listener
 	closingChan    chan bool
+	// socketMode is the permissions given to the Unix domain socket the
+	// server listens on
+	socketMode os.FileMode
+	// snapshots is nil when configuration snapshots are disabled
+	snapshots *snapshot.Manager
+	// auditLog is nil when the audit log is disabled
+	auditLog *audit.Log
+	// authenticator is nil when no user, API key or JWT is configured
+	authenticator *auth.Authenticator
+	// the managers the /metrics endpoint measures
+	metricManager api.Metrics
+	taskManager   api.Tasks
 }
 
 // New creates a REST API server with a given config
@@ -82,27 +104,65 @@ func New(cfg *Config) (*Server, error) {
 		killChan:   make(chan struct{}),
 		addrString: cfg.Address,
 		pprof:      cfg.Pprof,
+		metrics:    cfg.Metrics,
+	}
+	var err error
+	if s.socketMode, err = netutil.ParseFileMode(cfg.SocketMode); err != nil {
+		return nil, err
+	}
+	if cfg.RestClientCA != "" && !cfg.HTTPS {
+		return nil, errors.New("Client certificates require HTTPS (while parsing 'restapi::rest_client_ca')")
 	}
 	if cfg.HTTPS {
-		var err error
-		s.snapTLS, err = newtls(cfg.RestCertificate, cfg.RestKey)
+		s.snapTLS, err = newtls(cfg.RestCertificate, cfg.RestKey, cfg.RestClientCA)
 		if err != nil {
 			return nil, err
 		}
 		protocolPrefix = "https"
 	}
 	restLogger.Info(fmt.Sprintf("Configuring REST API with HTTPS set to: %v", cfg.HTTPS))
+	if cfg.RestClientCA != "" {
+		restLogger.Info("REST API requires client certificates")
+	}
 
 	s.apis = []api.API{
 		v1.New(&s.wg, s.killChan, protocolPrefix),
 		v2.New(&s.wg, s.killChan, protocolPrefix),
 	}
+	if cfg.Admission != nil {
+		a, err := admission.New(cfg.Admission)
+		if err != nil {
+			return nil, err
+		}
+		s.BindTaskAdmitter(a)
+	}
+	if cfg.Snapshots != nil {
+		if s.snapshots, err = snapshot.New(cfg.Snapshots); err != nil {
+			return nil, err
+		}
+		s.bindSnapshotManager(s.snapshots)
+	}
+	if cfg.Auth != nil {
+		if s.authenticator, err = auth.New(cfg.Auth); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Audit != nil {
+		if s.auditLog, err = audit.New(cfg.Audit); err != nil {
+			return nil, err
+		}
+		s.bindAuditLog(s.auditLog)
+	}
 
 	s.n = negroni.New(
 		NewLogger(),
 		negroni.NewRecovery(),
 		negroni.HandlerFunc(s.authMiddleware),
 	)
+	// the caller is audited once authenticated
+	if s.auditLog != nil {
+		s.n.Use(negroni.HandlerFunc(s.auditLog.Middleware))
+	}
 	s.r = httprouter.New()
 
 	// CORS has to be turned on explicitly in the global config.
@@ -127,15 +187,26 @@ func New(cfg *Config) (*Server, error) {
 }
 
 func (s *Server) BindMetricManager(m api.Metrics) {
+	s.metricManager = m
 	for _, apiInstance := range s.apis {
 		apiInstance.BindMetricManager(m)
 	}
+	if s.snapshots != nil {
+		s.snapshots.BindMetricManager(m)
+	}
+	if s.auditLog != nil {
+		s.auditLog.BindMetricManager(m)
+	}
 }
 
 func (s *Server) BindTaskManager(t api.Tasks) {
+	s.taskManager = t
 	for _, apiInstance := range s.apis {
 		apiInstance.BindTaskManager(t)
 	}
+	if s.snapshots != nil {
+		s.snapshots.BindTaskManager(t)
+	}
 }
 
 func (s *Server) BindTribeManager(t api.Tribe) {
@@ -150,6 +221,85 @@ func (s *Server) BindConfigManager(c api.Config) {
 	}
 }
 
+// BindTaskAdmitter adds an admitter reviewing the task creation requests
+// received by the APIs
+func (s *Server) BindTaskAdmitter(a core.TaskAdmitter) {
+	for _, apiInstance := range s.apis {
+		apiInstance.BindTaskAdmitter(a)
+	}
+}
+
+// bindsDaemonInfo is implemented by the APIs describing the daemon
+type bindsDaemonInfo interface {
+	BindDaemonInfo(*api.DaemonInfo)
+}
+
+// BindDaemonInfo sets the description of the daemon served by the APIs
+func (s *Server) BindDaemonInfo(info *api.DaemonInfo) {
+	for _, apiInstance := range s.apis {
+		if b, ok := apiInstance.(bindsDaemonInfo); ok {
+			b.BindDaemonInfo(info)
+		}
+	}
+}
+
+// bindsSnapshotManager is implemented by the APIs serving the configuration
+// snapshots
+type bindsSnapshotManager interface {
+	BindSnapshotManager(api.Snapshots)
+}
+
+func (s *Server) bindSnapshotManager(m api.Snapshots) {
+	for _, apiInstance := range s.apis {
+		if b, ok := apiInstance.(bindsSnapshotManager); ok {
+			b.BindSnapshotManager(m)
+		}
+	}
+}
+
+// bindsLogging is implemented by the APIs changing the log levels
+type bindsLogging interface {
+	BindLogging(api.Logging)
+}
+
+// BindLogging sets the logging subsystem whose levels are changed through
+// the APIs
+func (s *Server) BindLogging(l api.Logging) {
+	for _, apiInstance := range s.apis {
+		if b, ok := apiInstance.(bindsLogging); ok {
+			b.BindLogging(l)
+		}
+	}
+}
+
+// bindsReloader is implemented by the APIs reloading the configuration
+type bindsReloader interface {
+	BindReloader(api.Reloader)
+}
+
+// BindReloader sets the reloader of the configuration of the daemon
+// triggered through the APIs
+func (s *Server) BindReloader(r api.Reloader) {
+	for _, apiInstance := range s.apis {
+		if b, ok := apiInstance.(bindsReloader); ok {
+			b.BindReloader(r)
+		}
+	}
+}
+
+// bindsAuditLog is implemented by the APIs serving the audit log
+type bindsAuditLog interface {
+	BindAuditLog(api.AuditLog)
+}
+
+func (s *Server) bindAuditLog(l api.AuditLog) {
+	for _, apiInstance := range s.apis {
+		if b, ok := apiInstance.(bindsAuditLog); ok {
+			b.BindAuditLog(l)
+		}
+	}
+}
+
 // SetAPIAuth sets API authentication to enabled or disabled
 func (s *Server) SetAPIAuth(auth bool) {
 	s.auth = auth
@@ -166,19 +316,69 @@ func (s *Server) authMiddleware(rw http.ResponseWriter, r *http.Request, next ht
 	s.setAllowedOrigins(rw, reqOrigin)
 
 	defer r.Body.Close()
+	if !s.auth && s.authenticator == nil {
+		next(rw, r)
+		return
+	}
+	id, err := s.authenticate(r)
+	if err != nil {
+		v2.Write(401, v2.UnauthError{Code: 401, Message: "Not authorized. Please specify the same password that used to start snapteld, or a configured user, API key or token. E.g: [snaptel -p plugin list] or [curl http://localhost:8181/v2/plugins -u snap]"}, rw)
+		return
+	}
+	if !id.Role.Allows(auth.RequiredPermission(r.Method, r.URL.Path)) {
+		restLogger.WithFields(log.Fields{
+			"_block": "authorize",
+			"user":   id.Name,
+			"role":   id.Role,
+			"method": r.Method,
+			"path":   r.URL.Path,
+		}).Warn("request forbidden")
+		v2.Write(403, v2.UnauthError{Code: 403, Message: fmt.Sprintf("Forbidden. The role %s does not allow %s %s", id.Role, r.Method, r.URL.Path)}, rw)
+		return
+	}
+	if tenant, ok := s.taskTenant(auth.TaskID(r.URL.Path)); ok && !id.CanAccess(tenant) {
+		restLogger.WithFields(log.Fields{
+			"_block": "authorize",
+			"user":   id.Name,
+			"tenant": id.Tenant,
+			"method": r.Method,
+			"path":   r.URL.Path,
+		}).Warn("request to a task of another tenant forbidden")
+		v2.Write(403, v2.UnauthError{Code: 403, Message: fmt.Sprintf("Forbidden. The task %s belongs to another tenant", auth.TaskID(r.URL.Path))}, rw)
+		return
+	}
+	next(rw, auth.WithIdentity(r, id))
+}
+
+// taskTenant returns the tenant of the task with the given id; false when
+// there is no such task
+func (s *Server) taskTenant(id string) (string, bool) {
+	if id == "" || s.taskManager == nil {
+		return "", false
+	}
+	t, err := s.taskManager.GetTask(id)
+	if err != nil {
+		return "", false
+	}
+	return core.TenantOf(t), true
+}
+
+// authenticate returns the identity of the caller of the request.  The
+// password snapteld was started with authenticates any user as an admin.
+func (s *Server) authenticate(r *http.Request) (*auth.Identity, error) {
+	var err error = auth.ErrNoCredentials
+	if s.authenticator != nil {
+		var id *auth.Identity
+		if id, err = s.authenticator.Authenticate(r); err == nil {
+			return id, nil
+		}
+	}
 	if s.auth {
-		_, password, ok := r.BasicAuth()
-		// If we have valid password or going to tribe/agreements endpoint
-		// go to next. tribe/agreements endpoint used for populating
-		// snaptel help page when tribe mode is turned on.
-		if ok && password == s.authpwd {
-			next(rw, r)
-		} else {
-			v2.Write(401, v2.UnauthError{Code: 401, Message: "Not authorized. Please specify the same password that used to start snapteld. E.g: [snaptel -p plugin list] or [curl http://localhost:8181/v2/plugins -u snap]"}, rw)
+		if user, password, ok := r.BasicAuth(); ok && password == s.authpwd {
+			return &auth.Identity{Name: user, Role: auth.RoleAdmin, Method: auth.MethodBasic, Tenant: user}, nil
 		}
-	} else {
-		next(rw, r)
 	}
+	return nil, err
 }
 
 // CORS origins have to be turned on explicitly in the global config.
@@ -211,6 +411,9 @@ func (s *Server) Start() error {
 	s.closingChan = make(chan bool, 1)
 	s.addRoutes()
 	s.run(s.addrString)
+	if s.snapshots != nil {
+		s.snapshots.Start()
+	}
 	restLogger.WithFields(log.Fields{
 		"_block": "start",
 	}).Info("REST started")
@@ -218,6 +421,9 @@ func (s *Server) Start() error {
 }
 
 func (s *Server) Stop() {
+	if s.snapshots != nil {
+		s.snapshots.Stop()
+	}
 	// add a boolean to the s.closingChan (used for error handling in the
 	// goroutine that is listening for connections)
 	s.closingChan <- true
@@ -227,6 +433,9 @@ func (s *Server) Stop() {
 	s.serverListener.Close()
 	// wait for the server goroutines to complete (serve and watch)
 	s.wg.Wait()
+	if s.auditLog != nil {
+		s.auditLog.Stop()
+	}
 	// finally log the result
 	restLogger.WithFields(log.Fields{
 		"_block": "stop",
@@ -237,28 +446,33 @@ func (s *Server) Err() <-chan error {
 	return s.err
 }
 
+// Port returns the port the server listens on, or 0 when it listens on a
+// Unix domain socket
 func (s *Server) Port() int {
-	return s.addr.(*net.TCPAddr).Port
+	if addr, ok := s.addr.(*net.TCPAddr); ok {
+		return addr.Port
+	}
+	return 0
 }
 
 func (s *Server) run(addrString string) {
 	restLogger.Info("Starting REST API on ", addrString)
 	if s.snapTLS != nil {
-		cer, err := tls.LoadX509KeyPair(s.snapTLS.cert, s.snapTLS.key)
+		config, err := s.snapTLS.config()
 		if err != nil {
 			s.err <- err
 			return
 		}
-		config := &tls.Config{Certificates: []tls.Certificate{cer}}
-		ln, err := tls.Listen("tcp", addrString, config)
+		ln, err := netutil.Listen(addrString, s.socketMode)
 		if err != nil {
 			log.Fatal(err)
 		}
-		s.serverListener = ln
+		s.serverListener = tls.NewListener(ln, config)
+		s.addr = ln.Addr()
 		s.wg.Add(1)
-		go s.serveTLS(ln)
+		go s.serveTLS(s.serverListener)
 	} else {
-		ln, err := net.Listen("tcp", addrString)
+		ln, err := netutil.Listen(addrString, s.socketMode)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -286,7 +500,10 @@ func (s *Server) serveTLS(ln net.Listener) {
 
 func (s *Server) serve(ln net.Listener) {
 	defer s.wg.Done()
-	err := http.Serve(tcpKeepAliveListener{ln.(*net.TCPListener)}, s.n)
+	if tcpLn, ok := ln.(*net.TCPListener); ok {
+		ln = tcpKeepAliveListener{tcpLn}
+	}
+	err := http.Serve(ln, s.n)
 	if err != nil {
 		select {
 		case <-s.closingChan:
@@ -306,6 +523,7 @@ func (s *Server) addRoutes() {
 		}
 	}
 	s.addPprofRoutes()
+	s.addMetricsRoute()
 }
 
 func (s *Server) getAllowedOrigins(corsd string) ([]string, error) {
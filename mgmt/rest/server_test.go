@@ -23,11 +23,19 @@ package rest
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync"
 	"testing"
 
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/api"
+	"github.com/intelsdi-x/snap/mgmt/rest/auth"
+	"github.com/intelsdi-x/snap/mgmt/rest/v2"
 	"github.com/intelsdi-x/snap/pkg/cfgfile"
+	"github.com/julienschmidt/httprouter"
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/urfave/negroni"
 )
@@ -263,3 +271,242 @@ func TestRestAPICorsd(t *testing.T) {
 		})
 	})
 }
+
+func TestRestAPIAuthorization(t *testing.T) {
+	Convey("Given a REST API with users and the rest_auth password", t, func() {
+		a, err := auth.New(&auth.Config{Users: []auth.User{
+			{Name: "viewer", Password: "view", Role: auth.RoleReadOnly},
+			{Name: "ops", Password: "ops", Role: auth.RoleTaskAdmin},
+		}})
+		So(err, ShouldBeNil)
+		s := &Server{auth: true, authpwd: "legacy", authenticator: a}
+		serve := func(method, path, user, password string) (int, *auth.Identity) {
+			var id *auth.Identity
+			n := negroni.New(negroni.HandlerFunc(s.authMiddleware))
+			n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id = auth.IdentityFrom(r)
+			})
+			req := httptest.NewRequest(method, path, nil)
+			if user != "" {
+				req.SetBasicAuth(user, password)
+			}
+			rec := httptest.NewRecorder()
+			n.ServeHTTP(rec, req)
+			return rec.Code, id
+		}
+
+		Convey("callers without valid credentials are not authorized", func() {
+			code, _ := serve("GET", "/v2/plugins", "", "")
+			So(code, ShouldEqual, 401)
+			code, _ = serve("GET", "/v2/plugins", "viewer", "wrong")
+			So(code, ShouldEqual, 401)
+		})
+		Convey("requests are allowed according to the role of the caller", func() {
+			code, id := serve("GET", "/v2/plugins", "viewer", "view")
+			So(code, ShouldEqual, 200)
+			So(id.Name, ShouldEqual, "viewer")
+			code, _ = serve("POST", "/v2/plugins", "viewer", "view")
+			So(code, ShouldEqual, 403)
+			code, _ = serve("POST", "/v2/tasks", "ops", "ops")
+			So(code, ShouldEqual, 200)
+			code, _ = serve("DELETE", "/v2/plugins/collector/mock/1", "ops", "ops")
+			So(code, ShouldEqual, 403)
+		})
+		Convey("the rest_auth password is an admin", func() {
+			code, id := serve("POST", "/v2/plugins", "snap", "legacy")
+			So(code, ShouldEqual, 200)
+			So(id.Role, ShouldEqual, auth.RoleAdmin)
+		})
+		Convey("callers only access the tasks of their tenant unless they are admins", func() {
+			s.taskManager = &tenantTasks{tenants: map[string]string{"t1": "ops", "t2": "team-b"}}
+			code, _ := serve("GET", "/v2/tasks/t1", "ops", "ops")
+			So(code, ShouldEqual, 200)
+			code, _ = serve("PUT", "/v2/tasks/t2?action=stop", "ops", "ops")
+			So(code, ShouldEqual, 403)
+			code, _ = serve("GET", "/v1/tasks/t2", "viewer", "view")
+			So(code, ShouldEqual, 403)
+			code, _ = serve("DELETE", "/v2/tasks/t2", "snap", "legacy")
+			So(code, ShouldEqual, 200)
+		})
+		Convey("callers only pause and see the tasks of their tenant unless they are admins", func() {
+			tm := &tenantMaintenance{tenantTasks: tenantTasks{tenants: map[string]string{"t1": "ops", "t2": "team-b"}}}
+			s.taskManager = tm
+			v := v2.New(&sync.WaitGroup{}, make(chan struct{}), "http")
+			v.BindTaskManager(tm)
+			router := httprouter.New()
+			for _, route := range v.GetRoutes() {
+				router.Handle(route.Method, route.Path, route.Handle)
+			}
+			n := negroni.New(negroni.HandlerFunc(s.authMiddleware))
+			n.UseHandler(router)
+			do := func(method, path, user, password, body string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(method, path, strings.NewReader(body))
+				req.SetBasicAuth(user, password)
+				rec := httptest.NewRecorder()
+				n.ServeHTTP(rec, req)
+				return rec
+			}
+
+			So(do("POST", "/v2/maintenance", "ops", "ops", "").Code, ShouldEqual, 403)
+			So(do("POST", "/v2/maintenance", "ops", "ops", `{"task_ids":["t1","t2"]}`).Code, ShouldEqual, 403)
+			So(tm.current, ShouldBeNil)
+			So(do("POST", "/v2/maintenance", "ops", "ops", `{"task_ids":["t1"]}`).Code, ShouldEqual, 201)
+			So(tm.current.TaskIDs, ShouldResemble, []string{"t1"})
+			So(do("DELETE", "/v2/maintenance", "ops", "ops", "").Code, ShouldEqual, 200)
+			So(do("POST", "/v2/maintenance", "ops", "ops", `{"task_ids":["t1"],"labels":{"env":"prod"}}`).Code, ShouldEqual, 400)
+			So(do("POST", "/v2/maintenance", "ops", "ops", `{"labels":{"env":"prod"}}`).Code, ShouldEqual, 201)
+			So(tm.current.TaskIDs, ShouldResemble, []string{"t1"})
+			So(do("DELETE", "/v2/maintenance", "ops", "ops", "").Code, ShouldEqual, 200)
+			So(do("POST", "/v2/maintenance", "snap", "legacy", `{"labels":{"env":"prod"}}`).Code, ShouldEqual, 201)
+			So(tm.current.TaskIDs, ShouldResemble, []string{"t1", "t2"})
+			So(do("DELETE", "/v2/maintenance", "snap", "legacy", "").Code, ShouldEqual, 200)
+
+			tm.current = &core.MaintenanceWindow{}
+			So(do("DELETE", "/v2/maintenance", "ops", "ops", "").Code, ShouldEqual, 403)
+			So(do("DELETE", "/v2/maintenance", "snap", "legacy", "").Code, ShouldEqual, 200)
+			So(do("POST", "/v2/maintenance", "snap", "legacy", "").Code, ShouldEqual, 201)
+
+			rec := do("GET", "/v2/alerts", "viewer", "view", "")
+			So(rec.Code, ShouldEqual, 200)
+			So(rec.Body.String(), ShouldNotContainSubstring, `"t1"`)
+			So(rec.Body.String(), ShouldNotContainSubstring, `"t2"`)
+			rec = do("GET", "/v2/alerts", "ops", "ops", "")
+			So(rec.Body.String(), ShouldContainSubstring, `"t1"`)
+			So(rec.Body.String(), ShouldNotContainSubstring, `"t2"`)
+			rec = do("GET", "/v2/alerts", "snap", "legacy", "")
+			So(rec.Body.String(), ShouldContainSubstring, `"t2"`)
+
+			rec = do("GET", "/v2/events", "ops", "ops", "")
+			So(rec.Body.String(), ShouldContainSubstring, `"t1"`)
+			So(rec.Body.String(), ShouldNotContainSubstring, `"t2"`)
+			So(rec.Body.String(), ShouldNotContainSubstring, `"removed"`)
+			So(do("GET", "/v2/events?task_id=t2", "ops", "ops", "").Code, ShouldEqual, 403)
+			So(do("GET", "/v2/audit", "ops", "ops", "").Code, ShouldEqual, 403)
+			So(do("GET", "/v2/cardinality", "viewer", "view", "").Code, ShouldEqual, 403)
+			So(do("GET", "/v2/subscriptions", "ops", "ops", "").Code, ShouldEqual, 403)
+		})
+		Convey("callers only see the snapshots, advice and simulations of the tasks of their tenant unless they are admins", func() {
+			tm := &tenantMaintenance{tenantTasks: tenantTasks{tenants: map[string]string{"t1": "ops", "t2": "team-b"}}}
+			s.taskManager = tm
+			v := v2.New(&sync.WaitGroup{}, make(chan struct{}), "http")
+			v.BindTaskManager(tm)
+			router := httprouter.New()
+			for _, route := range v.GetRoutes() {
+				router.Handle(route.Method, route.Path, route.Handle)
+			}
+			n := negroni.New(negroni.HandlerFunc(s.authMiddleware))
+			n.UseHandler(router)
+			do := func(method, path, user, password, body string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(method, path, strings.NewReader(body))
+				req.SetBasicAuth(user, password)
+				rec := httptest.NewRecorder()
+				n.ServeHTTP(rec, req)
+				return rec
+			}
+
+			So(do("GET", "/v2/snapshots", "viewer", "view", "").Code, ShouldEqual, 403)
+			So(do("GET", "/v2/snapshots/daily", "ops", "ops", "").Code, ShouldEqual, 403)
+			So(do("GET", "/v2/snapshots", "snap", "legacy", "").Code, ShouldEqual, 404)
+
+			rec := do("GET", "/v2/advisor", "ops", "ops", "")
+			So(rec.Code, ShouldEqual, 200)
+			So(rec.Body.String(), ShouldContainSubstring, `"t1"`)
+			So(rec.Body.String(), ShouldNotContainSubstring, `"t2"`)
+			So(rec.Body.String(), ShouldContainSubstring, `"tasks_scanned": 1`)
+			rec = do("GET", "/v2/advisor", "viewer", "view", "")
+			So(rec.Body.String(), ShouldNotContainSubstring, `"t1"`)
+			So(rec.Body.String(), ShouldContainSubstring, `"tasks_scanned": 0`)
+			rec = do("GET", "/v2/advisor", "snap", "legacy", "")
+			So(rec.Body.String(), ShouldContainSubstring, `"t2"`)
+
+			So(do("POST", "/v2/tasks/simulate", "ops", "ops", `{"task_id":"t1"}`).Code, ShouldEqual, 200)
+			So(do("POST", "/v2/tasks/simulate", "ops", "ops", `{"task_id":"t2"}`).Code, ShouldEqual, 403)
+			So(do("POST", "/v2/tasks/simulate/run", "ops", "ops", `{"tasks":[{"task_id":"t1"},{"task_id":"t2"}]}`).Code, ShouldEqual, 403)
+			So(do("POST", "/v2/tasks/simulate/run", "snap", "legacy", `{"tasks":[{"task_id":"t1"},{"task_id":"t2"}]}`).Code, ShouldEqual, 200)
+		})
+	})
+}
+
+// tenantTasks is a task manager whose tasks only have an owner
+type tenantTasks struct {
+	api.Tasks
+	tenants map[string]string
+}
+
+func (m *tenantTasks) GetTask(id string) (core.Task, error) {
+	tenant, ok := m.tenants[id]
+	if !ok {
+		return nil, fmt.Errorf("task not found")
+	}
+	return &ownedTask{owner: &core.TaskOwner{Name: tenant, Tenant: tenant}}, nil
+}
+
+func (m *tenantTasks) GetTasks() map[string]core.Task {
+	tasks := map[string]core.Task{}
+	for id := range m.tenants {
+		tasks[id], _ = m.GetTask(id)
+	}
+	return tasks
+}
+
+type ownedTask struct {
+	core.Task
+	owner *core.TaskOwner
+}
+
+func (t *ownedTask) GetOwner() *core.TaskOwner {
+	return t.owner
+}
+
+func (t *ownedTask) State() core.TaskState {
+	return core.TaskSpinning
+}
+
+// tenantMaintenance pauses the tasks of several tenants and tracks their
+// alerts and events
+type tenantMaintenance struct {
+	tenantTasks
+	current *core.MaintenanceWindow
+}
+
+func (m *tenantMaintenance) StartMaintenance(reason string, ids []string, sel core.TaskListOptions) (*core.MaintenanceWindow, error) {
+	if len(sel.Labels) > 0 {
+		for _, id := range []string{"t1", "t2"} {
+			if t, _ := m.GetTask(id); sel.Visible == nil || sel.Visible(t) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	m.current = &core.MaintenanceWindow{Reason: reason, TaskIDs: ids, Labels: sel.Labels}
+	return m.current, nil
+}
+
+func (m *tenantMaintenance) EndMaintenance() (*core.MaintenanceWindow, error) {
+	mw := m.current
+	m.current = nil
+	return mw, nil
+}
+
+func (m *tenantMaintenance) Maintenance() (*core.MaintenanceWindow, []core.MaintenanceWindow) {
+	return m.current, nil
+}
+
+func (m *tenantMaintenance) Alerts() []core.Alert {
+	return []core.Alert{{TaskID: "t1", Name: "high"}, {TaskID: "t2", Name: "high"}}
+}
+
+func (m *tenantMaintenance) QueryEvents(q core.EventQuery) []core.EventRecord {
+	return []core.EventRecord{{TaskID: "t1"}, {TaskID: "t2"}, {TaskID: "removed"}}
+}
+
+func (m *tenantMaintenance) AdviseTasks() *core.AdvisorReport {
+	return &core.AdvisorReport{TasksScanned: 2, Findings: []core.TaskAdvisory{{TaskID: "t1"}, {TaskID: "t2"}}}
+}
+
+func (m *tenantMaintenance) SimulateSchedule(*core.Schedule, string) (*core.ScheduleSimulation, error) {
+	return &core.ScheduleSimulation{}, nil
+}
+
+func (m *tenantMaintenance) SimulateRun(*core.RunSimulationRequest) (*core.RunSimulation, error) {
+	return &core.RunSimulation{}, nil
+}
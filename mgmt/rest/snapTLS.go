@@ -22,6 +22,7 @@ package rest
 import (
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
@@ -33,10 +34,23 @@ import (
 
 type snapTLS struct {
 	cert, key string
+	// clientCAs verify the client certificates; nil when clients are not
+	// required to present one
+	clientCAs *x509.CertPool
 }
 
-func newtls(certPath, keyPath string) (*snapTLS, error) {
+func newtls(certPath, keyPath, clientCAPath string) (*snapTLS, error) {
 	t := &snapTLS{}
+	if clientCAPath != "" {
+		b, err := ioutil.ReadFile(clientCAPath)
+		if err != nil {
+			return nil, err
+		}
+		t.clientCAs = x509.NewCertPool()
+		if !t.clientCAs.AppendCertsFromPEM(b) {
+			return nil, ErrBadClientCA
+		}
+	}
 	if certPath != "" && keyPath != "" {
 		cert, err := os.Open(certPath)
 		if err != nil {
@@ -78,6 +92,23 @@ func newtls(certPath, keyPath string) (*snapTLS, error) {
 	return t, nil
 }
 
+// config returns the TLS configuration of the REST API server
+func (t *snapTLS) config() (*tls.Config, error) {
+	cer, err := tls.LoadX509KeyPair(t.cert, t.key)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cer},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if t.clientCAs != nil {
+		config.ClientCAs = t.clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
 func generateCert(t *snapTLS) error {
 	// good for 1 year
 	notBefore := time.Now()
@@ -0,0 +1,701 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package snapshot periodically exports the configuration of snapteld, its
+// tasks and its loaded plugins, into timestamped snapshot files and restores
+// the configuration saved in a snapshot.  Older snapshots are removed
+// according to a retention count.
+package snapshot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vrischmann/jsonutil"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+const (
+	defaultRetention = 10
+	// how long a removed task is given to stop
+	stopTimeout = 30 * time.Second
+
+	snapshotPrefix = "snapshot-"
+	snapshotExt    = ".json"
+	// pluginsDir is the directory of the snapshot directory the plugins
+	// saved with the snapshots are copied to, by checksum
+	pluginsDir = "plugins"
+	// signatureExt is the extension of the signature of a saved plugin
+	signatureExt = ".asc"
+	// timestampFormat sorts lexically in chronological order
+	timestampFormat = "20060102T150405.000000000Z"
+)
+
+var (
+	snapshotLogger = log.WithField("_module", "_mgmt-rest-snapshot")
+
+	snapshotName = regexp.MustCompile(`^` + snapshotPrefix + `[0-9]{8}T[0-9]{6}\.[0-9]{9}Z$`)
+)
+
+var (
+	// ErrSnapshotNotFound - The error message for a snapshot which does not exist
+	ErrSnapshotNotFound = errors.New("Snapshot not found")
+	// ErrManagersNotBound - The error message for a snapshot taken before the plugin and task managers are bound
+	ErrManagersNotBound = errors.New("Snapshot manager is not bound to the plugin and task managers")
+)
+
+// Config holds the snapshot configuration of the REST API.
+//   Note: if this struct is modified, then the constraints in the rest
+//         package need to be modified to match the field mapping that is
+//         defined here
+type Config struct {
+	// Path is the directory snapshot files are written to
+	Path string `json:"path"yaml:"path"`
+	// Interval between two automatic snapshots; snapshots are only taken
+	// on demand when it is not set
+	Interval jsonutil.Duration `json:"interval"yaml:"interval"`
+	// Retention is the number of snapshots kept, the oldest ones being
+	// removed first; it defaults to 10 and a negative value keeps them all
+	Retention int `json:"retention"yaml:"retention"`
+}
+
+// Snapshot is the configuration of snapteld at a point in time.
+type Snapshot struct {
+	Name      string    `json:"name"`
+	Timestamp time.Time `json:"timestamp"`
+	Plugins   []Plugin  `json:"plugins"`
+	Tasks     []Task    `json:"tasks"`
+}
+
+// Plugin is a plugin loaded when a snapshot was taken.
+type Plugin struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	Path    string `json:"path"`
+	Signed  bool   `json:"signed"`
+	// File is the copy of the plugin saved with the snapshot, relative to
+	// the snapshot directory; its signature, if any, is saved next to it
+	// with the .asc extension
+	File string `json:"file,omitempty"`
+}
+
+// Task is a task existing when a snapshot was taken, described by the
+// manifest it is recreated from.
+type Task struct {
+	ID       string                    `json:"id"`
+	State    string                    `json:"state"`
+	Manifest *core.TaskCreationRequest `json:"manifest"`
+	// Owner is the owner of the task, which is not part of its manifest
+	Owner *core.TaskOwner `json:"owner,omitempty"`
+}
+
+// Info describes a snapshot file.
+type Info struct {
+	Name      string    `json:"name"`
+	Timestamp time.Time `json:"timestamp"`
+	Size      int64     `json:"size"`
+}
+
+// RestoreResult reports the changes made by restoring a snapshot.
+type RestoreResult struct {
+	// Backup is the snapshot taken right before the restore
+	Backup        string   `json:"backup"`
+	LoadedPlugins []string `json:"loaded_plugins"`
+	CreatedTasks  []string `json:"created_tasks"`
+	RemovedTasks  []string `json:"removed_tasks"`
+	// ReplacedTasks were created again from the snapshot since their
+	// manifest changed, StartedTasks and StoppedTasks were started or
+	// stopped back to their state in the snapshot
+	ReplacedTasks []string `json:"replaced_tasks"`
+	StartedTasks  []string `json:"started_tasks"`
+	StoppedTasks  []string `json:"stopped_tasks"`
+	Errors        []string `json:"errors,omitempty"`
+}
+
+type managesPlugins interface {
+	PluginCatalog() core.PluginCatalog
+	Load(*core.RequestedPlugin) (core.CatalogedPlugin, serror.SnapError)
+	GetTempDir() string
+}
+
+// signedPlugin is implemented by a loaded plugin which keeps the signature
+// it was loaded with
+type signedPlugin interface {
+	Signature() []byte
+}
+
+type managesTasks interface {
+	CreateTask(schedule.Schedule, *wmap.WorkflowMap, bool, ...core.TaskOption) (core.Task, core.TaskErrors)
+	GetTasks() map[string]core.Task
+	GetTask(string) (core.Task, error)
+	StartTask(string) []serror.SnapError
+	StopTask(string) []serror.SnapError
+	RemoveTask(string) error
+}
+
+// Manager takes, lists and restores the snapshots of a snapteld instance.
+type Manager struct {
+	path      string
+	interval  time.Duration
+	retention int
+
+	// serializes the changes to the snapshot directory
+	sync.Mutex
+	// restoring serializes the restores
+	restoring sync.Mutex
+	// restored is the snapshot being restored, whose saved plugins are
+	// kept even once it is pruned
+	restored      *Snapshot
+	pluginManager managesPlugins
+	taskManager   managesTasks
+	quit          chan struct{}
+}
+
+// New returns a Manager configured from the given config.  The snapshot
+// directory is created when it does not exist.
+func New(cfg *Config) (*Manager, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("Snapshot path is not set (while parsing 'restapi::snapshots::path')")
+	}
+	if err := os.MkdirAll(cfg.Path, 0700); err != nil {
+		return nil, fmt.Errorf("%v (while parsing 'restapi::snapshots::path')", err)
+	}
+	m := &Manager{
+		path:      cfg.Path,
+		interval:  cfg.Interval.Duration,
+		retention: cfg.Retention,
+	}
+	if m.retention == 0 {
+		m.retention = defaultRetention
+	}
+	return m, nil
+}
+
+// BindMetricManager sets the manager of the plugins saved in the snapshots.
+func (m *Manager) BindMetricManager(p managesPlugins) {
+	m.pluginManager = p
+}
+
+// BindTaskManager sets the manager of the tasks saved in the snapshots.
+func (m *Manager) BindTaskManager(t managesTasks) {
+	m.taskManager = t
+}
+
+// Start takes a snapshot on every interval until Stop is called.
+func (m *Manager) Start() {
+	if m.interval <= 0 {
+		return
+	}
+	m.quit = make(chan struct{})
+	go func(quit chan struct{}) {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := m.Take(); err != nil {
+					snapshotLogger.WithFields(log.Fields{
+						"_block": "periodic-snapshot",
+						"_error": err.Error(),
+					}).Error("unable to take snapshot")
+				}
+			case <-quit:
+				return
+			}
+		}
+	}(m.quit)
+}
+
+// Stop stops taking periodic snapshots.
+func (m *Manager) Stop() {
+	if m.quit != nil {
+		close(m.quit)
+		m.quit = nil
+	}
+}
+
+// Take writes a snapshot of the current configuration and removes the
+// snapshots exceeding the retention count.  The plugins are copied along
+// with their signature so that they can be loaded again once unloaded, the
+// binaries uploaded through the REST API being removed on unload.
+func (m *Manager) Take() (*Snapshot, error) {
+	if m.pluginManager == nil || m.taskManager == nil {
+		return nil, ErrManagersNotBound
+	}
+	m.Lock()
+	defer m.Unlock()
+	now := time.Now().UTC()
+	s := &Snapshot{
+		Name:      snapshotPrefix + now.Format(timestampFormat),
+		Timestamp: now,
+		Plugins:   []Plugin{},
+		Tasks:     []Task{},
+	}
+	for _, p := range m.pluginManager.PluginCatalog() {
+		file, err := m.savePlugin(p)
+		if err != nil {
+			// the plugin is only restored from its path
+			snapshotLogger.WithFields(log.Fields{
+				"_block": "take-snapshot",
+				"plugin": p.Key(),
+				"path":   p.PluginPath(),
+				"_error": err.Error(),
+			}).Warn("unable to save plugin")
+		}
+		s.Plugins = append(s.Plugins, Plugin{
+			Type:    p.TypeName(),
+			Name:    p.Name(),
+			Version: p.Version(),
+			Path:    p.PluginPath(),
+			Signed:  p.IsSigned(),
+			File:    file,
+		})
+	}
+	for id, t := range m.taskManager.GetTasks() {
+		manifest, err := core.TaskManifest(t)
+		if err != nil {
+			return nil, fmt.Errorf("%v (while saving task %s)", err, id)
+		}
+		s.Tasks = append(s.Tasks, Task{
+			ID:       id,
+			State:    t.State().String(),
+			Manifest: manifest,
+			Owner:    t.GetOwner(),
+		})
+	}
+	sort.Sort(byID(s.Tasks))
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	file := m.file(s.Name)
+	// write the snapshot under a temporary name so a partially written
+	// snapshot is never listed
+	if err := ioutil.WriteFile(file+".tmp", b, 0600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(file+".tmp", file); err != nil {
+		return nil, err
+	}
+	snapshotLogger.WithFields(log.Fields{
+		"_block":   "take-snapshot",
+		"snapshot": s.Name,
+		"plugins":  len(s.Plugins),
+		"tasks":    len(s.Tasks),
+	}).Info("snapshot taken")
+	m.prune()
+	return s, nil
+}
+
+// savePlugin copies the plugin, and its signature, to the plugins directory
+// under its checksum, unless it was already saved, and returns the path of
+// the copy relative to the snapshot directory; remote plugins and the ones
+// without a file are not saved.
+func (m *Manager) savePlugin(p core.CatalogedPlugin) (string, error) {
+	path := p.PluginPath()
+	if path == "" || core.IsUri(path) {
+		return "", nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	file := filepath.Join(pluginsDir, hex.EncodeToString(sum[:]), filepath.Base(path))
+	dest := filepath.Join(m.path, file)
+	if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(dest); os.IsNotExist(err) {
+		if err := writeFile(dest, b, 0700); err != nil {
+			return "", err
+		}
+	}
+	if sp, ok := p.(signedPlugin); ok && len(sp.Signature()) > 0 {
+		if err := writeFile(dest+signatureExt, sp.Signature(), 0600); err != nil {
+			return "", err
+		}
+	}
+	return file, nil
+}
+
+// writeFile writes the file under a temporary name first so that a
+// partially written file is never read
+func writeFile(file string, b []byte, perm os.FileMode) error {
+	if err := ioutil.WriteFile(file+".tmp", b, perm); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+// List returns the snapshots from the oldest to the most recent.
+func (m *Manager) List() ([]Info, error) {
+	files, err := ioutil.ReadDir(m.path)
+	if err != nil {
+		return nil, err
+	}
+	infos := []Info{}
+	for _, f := range files {
+		name := strings.TrimSuffix(f.Name(), snapshotExt)
+		if f.IsDir() || !strings.HasSuffix(f.Name(), snapshotExt) || !snapshotName.MatchString(name) {
+			continue
+		}
+		ts, err := time.Parse(timestampFormat, strings.TrimPrefix(name, snapshotPrefix))
+		if err != nil {
+			continue
+		}
+		infos = append(infos, Info{Name: name, Timestamp: ts, Size: f.Size()})
+	}
+	sort.Sort(byName(infos))
+	return infos, nil
+}
+
+// Get returns the snapshot with the given name.
+func (m *Manager) Get(name string) (*Snapshot, error) {
+	if !snapshotName.MatchString(name) {
+		return nil, ErrSnapshotNotFound
+	}
+	b, err := ioutil.ReadFile(m.file(name))
+	if os.IsNotExist(err) {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Restore brings the configuration back to the one saved in the snapshot
+// with the given name.  A snapshot of the current configuration is taken
+// first so the restore can be undone.  The plugins of the snapshot which are
+// not loaded anymore are loaded again from their path, the tasks created
+// since the snapshot are stopped and removed and the tasks removed since the
+// snapshot are created again with their ID, and started if they were
+// running.  Tasks existing both in the snapshot and now are created again
+// when their manifest changed, else started or stopped back to their state
+// in the snapshot.  Plugins loaded since the snapshot are left loaded.
+// Restoring continues past individual failures which are reported in the
+// result.  Restores are serialized.
+func (m *Manager) Restore(name string) (*RestoreResult, error) {
+	m.restoring.Lock()
+	defer m.restoring.Unlock()
+	s, err := m.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	m.setRestored(s)
+	defer m.setRestored(nil)
+	backup, err := m.Take()
+	if err != nil {
+		return nil, err
+	}
+	res := &RestoreResult{
+		Backup:        backup.Name,
+		LoadedPlugins: []string{},
+		CreatedTasks:  []string{},
+		RemovedTasks:  []string{},
+		ReplacedTasks: []string{},
+		StartedTasks:  []string{},
+		StoppedTasks:  []string{},
+	}
+	logger := snapshotLogger.WithFields(log.Fields{
+		"_block":   "restore-snapshot",
+		"snapshot": name,
+	})
+
+	m.restorePlugins(s, res)
+
+	tasks := m.taskManager.GetTasks()
+	saved := map[string]bool{}
+	for _, t := range s.Tasks {
+		saved[t.ID] = true
+	}
+	for id := range tasks {
+		if saved[id] {
+			continue
+		}
+		if err := m.removeTask(id); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("unable to remove task %s: %v", id, err))
+			continue
+		}
+		res.RemovedTasks = append(res.RemovedTasks, id)
+	}
+	for _, t := range s.Tasks {
+		if current, ok := tasks[t.ID]; ok {
+			m.reconcileTask(t, current, res)
+			continue
+		}
+		if err := m.restoreTask(t); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("unable to create task %s: %v", t.ID, err))
+			continue
+		}
+		res.CreatedTasks = append(res.CreatedTasks, t.ID)
+	}
+
+	logger.WithFields(log.Fields{
+		"backup":         res.Backup,
+		"loaded-plugins": len(res.LoadedPlugins),
+		"created-tasks":  len(res.CreatedTasks),
+		"removed-tasks":  len(res.RemovedTasks),
+		"replaced-tasks": len(res.ReplacedTasks),
+		"started-tasks":  len(res.StartedTasks),
+		"stopped-tasks":  len(res.StoppedTasks),
+		"errors":         len(res.Errors),
+	}).Info("snapshot restored")
+	return res, nil
+}
+
+func (m *Manager) restorePlugins(s *Snapshot, res *RestoreResult) {
+	loaded := map[string]bool{}
+	for _, p := range m.pluginManager.PluginCatalog() {
+		loaded[pluginKey(p.TypeName(), p.Name(), p.Version())] = true
+	}
+	for _, p := range s.Plugins {
+		key := pluginKey(p.Type, p.Name, p.Version)
+		if loaded[key] {
+			continue
+		}
+		rp, err := m.requestPlugin(p)
+		if err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("unable to load plugin %s: %v", key, err))
+			continue
+		}
+		if _, serr := m.pluginManager.Load(rp); serr != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("unable to load plugin %s: %v", key, serr))
+			continue
+		}
+		res.LoadedPlugins = append(res.LoadedPlugins, key)
+	}
+}
+
+func (m *Manager) setRestored(s *Snapshot) {
+	m.Lock()
+	defer m.Unlock()
+	m.restored = s
+}
+
+// requestPlugin returns the request to load the plugin from its copy saved
+// with the snapshot, signed with its saved signature, else from its path
+func (m *Manager) requestPlugin(p Plugin) (*core.RequestedPlugin, error) {
+	if p.File == "" {
+		return core.NewRequestedPlugin(p.Path, m.pluginManager.GetTempDir(), nil)
+	}
+	file := filepath.Join(m.path, p.File)
+	rp, err := core.NewRequestedPlugin(file, m.pluginManager.GetTempDir(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(file + signatureExt); err == nil {
+		if err := rp.ReadSignatureFile(file + signatureExt); err != nil {
+			return nil, err
+		}
+	}
+	return rp, nil
+}
+
+// reconcileTask brings a task existing both in the snapshot and now back to
+// the snapshot: it is created again when its manifest changed, else started
+// or stopped as it was.
+func (m *Manager) reconcileTask(t Task, current core.Task, res *RestoreResult) {
+	manifest, err := core.TaskManifest(current)
+	if err != nil {
+		res.Errors = append(res.Errors, fmt.Sprintf("unable to compare task %s: %v", t.ID, err))
+		return
+	}
+	if !sameManifest(manifest, t.Manifest) {
+		if err := m.removeTask(t.ID); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("unable to replace task %s: %v", t.ID, err))
+			return
+		}
+		if err := m.restoreTask(t); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("unable to replace task %s: %v", t.ID, err))
+			return
+		}
+		res.ReplacedTasks = append(res.ReplacedTasks, t.ID)
+		return
+	}
+	wasRunning := t.State == core.TaskSpinning.String() || t.State == core.TaskFiring.String()
+	state := current.State()
+	running := state == core.TaskSpinning || state == core.TaskFiring
+	switch {
+	case wasRunning && !running:
+		if errs := m.taskManager.StartTask(t.ID); len(errs) > 0 {
+			res.Errors = append(res.Errors, fmt.Sprintf("unable to start task %s: %v", t.ID, errs[0]))
+			return
+		}
+		res.StartedTasks = append(res.StartedTasks, t.ID)
+	case !wasRunning && running:
+		if errs := m.taskManager.StopTask(t.ID); len(errs) > 0 {
+			res.Errors = append(res.Errors, fmt.Sprintf("unable to stop task %s: %v", t.ID, errs[0]))
+			return
+		}
+		res.StoppedTasks = append(res.StoppedTasks, t.ID)
+	}
+}
+
+// sameManifest returns true when the manifests create the same task,
+// whether they start it or not
+func sameManifest(a, b *core.TaskCreationRequest) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ca, cb := *a, *b
+	ca.Start, cb.Start = false, false
+	ja, err := json.Marshal(ca)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(cb)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ja, jb)
+}
+
+// removeTask stops and removes a task; a stopped task cannot be stopped
+// again, only removing it matters
+func (m *Manager) removeTask(id string) error {
+	m.taskManager.StopTask(id)
+	m.waitStopped(id)
+	return m.taskManager.RemoveTask(id)
+}
+
+// waitStopped waits for a task being stopped to reach the stopped state.
+func (m *Manager) waitStopped(id string) {
+	deadline := time.Now().Add(stopTimeout)
+	for time.Now().Before(deadline) {
+		t, err := m.taskManager.GetTask(id)
+		if err != nil || t.State() != core.TaskStopping {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func (m *Manager) restoreTask(t Task) error {
+	manifest := *t.Manifest
+	manifest.Start = t.State == core.TaskSpinning.String() || t.State == core.TaskFiring.String()
+	b, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	create := func(sch schedule.Schedule, wfMap *wmap.WorkflowMap, startOnCreate bool, opts ...core.TaskOption) (core.Task, core.TaskErrors) {
+		return m.taskManager.CreateTask(sch, wfMap, startOnCreate, append(opts, core.SetTaskID(t.ID), core.OptionOwner(t.Owner))...)
+	}
+	_, err = core.ImportTask(b, nil, create)
+	return err
+}
+
+// prune removes the oldest snapshots exceeding the retention count.
+func (m *Manager) prune() {
+	if m.retention < 0 {
+		return
+	}
+	infos, err := m.List()
+	if err != nil {
+		return
+	}
+	for i := 0; i < len(infos)-m.retention; i++ {
+		if err := os.Remove(m.file(infos[i].Name)); err != nil {
+			snapshotLogger.WithFields(log.Fields{
+				"_block":   "prune-snapshots",
+				"snapshot": infos[i].Name,
+				"_error":   err.Error(),
+			}).Warn("unable to remove snapshot")
+		}
+	}
+	m.prunePlugins()
+}
+
+// prunePlugins removes the saved plugins no snapshot refers to anymore
+func (m *Manager) prunePlugins() {
+	infos, err := m.List()
+	if err != nil {
+		return
+	}
+	snapshots := []*Snapshot{}
+	if m.restored != nil {
+		snapshots = append(snapshots, m.restored)
+	}
+	for _, info := range infos {
+		s, err := m.Get(info.Name)
+		if err != nil {
+			// the plugins of a snapshot which can not be read are kept
+			return
+		}
+		snapshots = append(snapshots, s)
+	}
+	used := map[string]bool{}
+	for _, s := range snapshots {
+		for _, p := range s.Plugins {
+			if p.File != "" {
+				used[filepath.Dir(filepath.Join(m.path, p.File))] = true
+			}
+		}
+	}
+	dirs, _ := filepath.Glob(filepath.Join(m.path, pluginsDir, "*"))
+	for _, dir := range dirs {
+		if !used[dir] {
+			os.RemoveAll(dir)
+		}
+	}
+}
+
+func (m *Manager) file(name string) string {
+	return filepath.Join(m.path, name+snapshotExt)
+}
+
+func pluginKey(typ, name string, version int) string {
+	return fmt.Sprintf("%s"+core.Separator+"%s"+core.Separator+"%d", typ, name, version)
+}
+
+type byID []Task
+
+func (t byID) Len() int           { return len(t) }
+func (t byID) Less(i, j int) bool { return t[i].ID < t[j].ID }
+func (t byID) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+
+type byName []Info
+
+func (in byName) Len() int           { return len(in) }
+func (in byName) Less(i, j int) bool { return in[i].Name < in[j].Name }
+func (in byName) Swap(i, j int)      { in[i], in[j] = in[j], in[i] }
@@ -0,0 +1,308 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest/v2/mock"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// idTask records the ID given to a created task
+type idTask struct {
+	core.Task
+	id string
+}
+
+func (t *idTask) ID() string      { return t.id }
+func (t *idTask) SetID(id string) { t.id = id }
+
+// stateTask is a task in a given state
+type stateTask struct {
+	core.Task
+	state core.TaskState
+}
+
+func (t *stateTask) State() core.TaskState { return t.state }
+
+// taskManager keeps its tasks in a map and records the changes made to it
+type taskManager struct {
+	*mock.MockTaskManager
+	tasks   map[string]core.Task
+	created []string
+	removed []string
+	started []string
+	stopped []string
+}
+
+func newTaskManager() *taskManager {
+	m := &taskManager{MockTaskManager: &mock.MockTaskManager{}, tasks: map[string]core.Task{}}
+	for id, t := range m.MockTaskManager.GetTasks() {
+		m.tasks[id] = t
+	}
+	return m
+}
+
+func (m *taskManager) CreateTask(sch schedule.Schedule, wf *wmap.WorkflowMap, start bool, opts ...core.TaskOption) (core.Task, core.TaskErrors) {
+	t, errs := m.MockTaskManager.CreateTask(sch, wf, start, opts...)
+	it := &idTask{Task: t}
+	for _, opt := range opts {
+		opt(it)
+	}
+	m.tasks[it.id] = it
+	m.created = append(m.created, it.id)
+	return it, errs
+}
+
+func (m *taskManager) GetTasks() map[string]core.Task {
+	tasks := map[string]core.Task{}
+	for id, t := range m.tasks {
+		tasks[id] = t
+	}
+	return tasks
+}
+
+func (m *taskManager) RemoveTask(id string) error {
+	delete(m.tasks, id)
+	m.removed = append(m.removed, id)
+	return nil
+}
+
+func (m *taskManager) StartTask(id string) []serror.SnapError {
+	m.started = append(m.started, id)
+	return nil
+}
+
+func (m *taskManager) StopTask(id string) []serror.SnapError {
+	m.stopped = append(m.stopped, id)
+	return nil
+}
+
+// signedPluginFile is a plugin loaded from a file with a signature
+type signedPluginFile struct {
+	mock.MockLoadedPlugin
+	path      string
+	signature []byte
+}
+
+func (p signedPluginFile) PluginPath() string { return p.path }
+func (p signedPluginFile) IsSigned() bool     { return true }
+func (p signedPluginFile) Signature() []byte  { return p.signature }
+
+// pluginManager has the given plugins loaded and records the plugins it is
+// requested to load
+type pluginManager struct {
+	mock.MockManagesMetrics
+	plugins   core.PluginCatalog
+	requested []*core.RequestedPlugin
+	tempDir   string
+}
+
+func (m *pluginManager) PluginCatalog() core.PluginCatalog { return m.plugins }
+func (m *pluginManager) GetTempDir() string                { return m.tempDir }
+
+func (m *pluginManager) Load(rp *core.RequestedPlugin) (core.CatalogedPlugin, serror.SnapError) {
+	m.requested = append(m.requested, rp)
+	return nil, nil
+}
+
+func TestSnapshotPlugins(t *testing.T) {
+	Convey("Given a snapshot manager and a plugin uploaded to a temporary directory", t, func() {
+		dir, err := ioutil.TempDir("", "snapshots")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		upload := filepath.Join(dir, "upload")
+		So(os.MkdirAll(upload, 0700), ShouldBeNil)
+		path := filepath.Join(upload, "snap-plugin-collector-foo")
+		So(ioutil.WriteFile(path, []byte("plugin"), 0700), ShouldBeNil)
+		So(os.MkdirAll(filepath.Join(dir, "temp"), 0700), ShouldBeNil)
+		m, err := New(&Config{Path: filepath.Join(dir, "snapshots"), Retention: 1})
+		So(err, ShouldBeNil)
+		m.BindTaskManager(newTaskManager())
+		pm := &pluginManager{
+			plugins: core.PluginCatalog{signedPluginFile{
+				MockLoadedPlugin: mock.MockLoadedPlugin{MyName: "foo", MyType: "collector", MyVersion: 1},
+				path:             path,
+				signature:        []byte("signature"),
+			}},
+			tempDir: filepath.Join(dir, "temp"),
+		}
+		m.BindMetricManager(pm)
+
+		Convey("the plugin and its signature are saved with the snapshot", func() {
+			s, err := m.Take()
+			So(err, ShouldBeNil)
+			So(s.Plugins, ShouldHaveLength, 1)
+			So(s.Plugins[0].File, ShouldStartWith, pluginsDir+string(filepath.Separator))
+			b, err := ioutil.ReadFile(filepath.Join(m.path, s.Plugins[0].File))
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "plugin")
+
+			Convey("and restored from the copy once the upload is removed", func() {
+				So(os.RemoveAll(upload), ShouldBeNil)
+				pm.plugins = core.PluginCatalog{}
+				res, err := m.Restore(s.Name)
+				So(err, ShouldBeNil)
+				So(res.Errors, ShouldBeEmpty)
+				So(res.LoadedPlugins, ShouldResemble, []string{pluginKey("collector", "foo", 1)})
+				So(pm.requested, ShouldHaveLength, 1)
+				So(string(pm.requested[0].Signature()), ShouldEqual, "signature")
+				b, err := ioutil.ReadFile(pm.requested[0].Path())
+				So(err, ShouldBeNil)
+				So(string(b), ShouldEqual, "plugin")
+			})
+			Convey("and removed with the last snapshot referring to it", func() {
+				pm.plugins = core.PluginCatalog{}
+				_, err := m.Take()
+				So(err, ShouldBeNil)
+				dirs, _ := filepath.Glob(filepath.Join(m.path, pluginsDir, "*"))
+				So(dirs, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestSnapshots(t *testing.T) {
+	Convey("Given a snapshot manager", t, func() {
+		dir, err := ioutil.TempDir("", "snapshots")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		m, err := New(&Config{Path: dir, Retention: 2})
+		So(err, ShouldBeNil)
+		tm := newTaskManager()
+		m.BindTaskManager(tm)
+		m.BindMetricManager(mock.MockManagesMetrics{})
+
+		Convey("a snapshot holds the loaded plugins and the tasks", func() {
+			s, err := m.Take()
+			So(err, ShouldBeNil)
+			infos, err := m.List()
+			So(err, ShouldBeNil)
+			So(infos, ShouldHaveLength, 1)
+			So(infos[0].Name, ShouldEqual, s.Name)
+
+			saved, err := m.Get(s.Name)
+			So(err, ShouldBeNil)
+			So(saved.Plugins, ShouldHaveLength, len(mock.MockManagesMetrics{}.PluginCatalog()))
+			So(saved.Tasks, ShouldHaveLength, 2)
+			So(saved.Tasks[0].ID, ShouldEqual, "Task1")
+			So(saved.Tasks[0].Manifest, ShouldNotBeNil)
+		})
+		Convey("the oldest snapshots exceeding the retention are removed", func() {
+			var names []string
+			for i := 0; i < 3; i++ {
+				s, err := m.Take()
+				So(err, ShouldBeNil)
+				names = append(names, s.Name)
+			}
+			infos, err := m.List()
+			So(err, ShouldBeNil)
+			So(infos, ShouldHaveLength, 2)
+			So(infos[0].Name, ShouldEqual, names[1])
+			So(infos[1].Name, ShouldEqual, names[2])
+		})
+		Convey("restoring a snapshot brings the tasks back", func() {
+			s, err := m.Take()
+			So(err, ShouldBeNil)
+			delete(tm.tasks, "Task2")
+			tm.tasks["Task3"] = tm.MockTaskManager.GetTasks()["Task1"]
+
+			res, err := m.Restore(s.Name)
+			So(err, ShouldBeNil)
+			So(res.Backup, ShouldNotEqual, s.Name)
+			So(res.Errors, ShouldBeEmpty)
+			So(res.LoadedPlugins, ShouldBeEmpty)
+			So(res.RemovedTasks, ShouldResemble, []string{"Task3"})
+			So(res.CreatedTasks, ShouldResemble, []string{"Task2"})
+			So(tm.created, ShouldResemble, []string{"Task2"})
+			So(tm.tasks, ShouldContainKey, "Task2")
+			So(tm.tasks, ShouldNotContainKey, "Task3")
+		})
+		Convey("restoring a snapshot brings the tasks back to their state", func() {
+			tm.tasks["Task1"] = &stateTask{Task: tm.tasks["Task1"], state: core.TaskStopped}
+			s, err := m.Take()
+			So(err, ShouldBeNil)
+			tm.tasks["Task1"] = tm.MockTaskManager.GetTasks()["Task1"]
+			tm.tasks["Task2"] = &stateTask{Task: tm.tasks["Task2"], state: core.TaskStopped}
+
+			res, err := m.Restore(s.Name)
+			So(err, ShouldBeNil)
+			So(res.Errors, ShouldBeEmpty)
+			So(res.StoppedTasks, ShouldResemble, []string{"Task1"})
+			So(res.StartedTasks, ShouldResemble, []string{"Task2"})
+			So(res.ReplacedTasks, ShouldBeEmpty)
+			So(tm.stopped, ShouldResemble, []string{"Task1"})
+			So(tm.started, ShouldResemble, []string{"Task2"})
+		})
+		Convey("restoring a snapshot replaces the tasks whose manifest changed", func() {
+			s, err := m.Take()
+			So(err, ShouldBeNil)
+			saved, err := m.Get(s.Name)
+			So(err, ShouldBeNil)
+			saved.Tasks[0].Manifest.Name = "renamed"
+			b, err := json.Marshal(saved)
+			So(err, ShouldBeNil)
+			So(ioutil.WriteFile(m.file(s.Name), b, 0600), ShouldBeNil)
+
+			res, err := m.Restore(s.Name)
+			So(err, ShouldBeNil)
+			So(res.Errors, ShouldBeEmpty)
+			So(res.ReplacedTasks, ShouldResemble, []string{"Task1"})
+			So(tm.removed, ShouldResemble, []string{"Task1"})
+			So(tm.created, ShouldResemble, []string{"Task1"})
+		})
+		Convey("restores are serialized", func() {
+			s, err := m.Take()
+			So(err, ShouldBeNil)
+			m.restoring.Lock()
+			done := make(chan struct{})
+			go func() {
+				m.Restore(s.Name)
+				close(done)
+			}()
+			select {
+			case <-done:
+				t.Fatal("a restore ran while another was going")
+			case <-time.After(100 * time.Millisecond):
+			}
+			m.restoring.Unlock()
+			<-done
+		})
+		Convey("unknown snapshots are not found", func() {
+			_, err := m.Get("../snapshot-20170101T000000.000000000Z")
+			So(err, ShouldEqual, ErrSnapshotNotFound)
+			_, err = m.Restore("snapshot-20170101T000000.000000000Z")
+			So(err, ShouldEqual, ErrSnapshotNotFound)
+		})
+	})
+}
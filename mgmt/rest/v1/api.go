@@ -3,6 +3,7 @@ package v1
 import (
 	"sync"
 
+	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/mgmt/rest/api"
 	log "github.com/sirupsen/logrus"
 )
@@ -29,6 +30,8 @@ type apiV1 struct {
 	taskManager   api.Tasks
 	tribeManager  api.Tribe
 	configManager api.Config
+	// taskAdmitters review task creation requests before tasks are created
+	taskAdmitters []core.TaskAdmitter
 
 	wg       *sync.WaitGroup
 	killChan chan struct{}
@@ -88,6 +91,11 @@ func (s *apiV1) BindMetricManager(metricManager api.Metrics) {
 
 func (s *apiV1) BindTaskManager(taskManager api.Tasks) {
 	s.taskManager = taskManager
+	// a task manager expanding task presets reviews the requests before the
+	// other admitters so that they see the options of the preset
+	if a, ok := taskManager.(core.TaskAdmitter); ok {
+		s.taskAdmitters = append([]core.TaskAdmitter{a}, s.taskAdmitters...)
+	}
 }
 
 func (s *apiV1) BindTribeManager(tribeManager api.Tribe) {
@@ -97,3 +105,7 @@ func (s *apiV1) BindTribeManager(tribeManager api.Tribe) {
 func (s *apiV1) BindConfigManager(configManager api.Config) {
 	s.configManager = configManager
 }
+
+func (s *apiV1) BindTaskAdmitter(taskAdmitter core.TaskAdmitter) {
+	s.taskAdmitters = append(s.taskAdmitters, taskAdmitter)
+}
@@ -109,7 +109,15 @@ func AddSchedulerTaskFromTask(t core.Task) *AddScheduledTask {
 		LastFailureMessage: t.LastFailureMessage(),
 		State:              t.State().String(),
 		Workflow:           t.WMap(),
+		Singleton:          t.IsSingleton(),
 	}
+	if name, size := t.GetDedicatedQueue(); name != "" {
+		st.Queue = &core.TaskQueue{Name: name, Size: size}
+	}
+	st.PinnedThread = t.GetPinnedThread()
+	st.PublishRateLimit = t.GetPublishRateLimit()
+	st.CardinalityLimit = t.GetCardinalityLimit()
+	st.Owner = t.GetOwner()
 	assertSchedule(t.Schedule(), st)
 	if st.LastRunTimestamp < 0 {
 		st.LastRunTimestamp = -1
@@ -118,19 +126,25 @@ func AddSchedulerTaskFromTask(t core.Task) *AddScheduledTask {
 }
 
 type ScheduledTask struct {
-	ID                 string            `json:"id"`
-	Name               string            `json:"name"`
-	Deadline           string            `json:"deadline"`
-	Workflow           *wmap.WorkflowMap `json:"workflow,omitempty"`
-	Schedule           *core.Schedule    `json:"schedule,omitempty"`
-	CreationTimestamp  int64             `json:"creation_timestamp,omitempty"`
-	LastRunTimestamp   int64             `json:"last_run_timestamp,omitempty"`
-	HitCount           int               `json:"hit_count,omitempty"`
-	MissCount          int               `json:"miss_count,omitempty"`
-	FailedCount        int               `json:"failed_count,omitempty"`
-	LastFailureMessage string            `json:"last_failure_message,omitempty"`
-	State              string            `json:"task_state"`
-	Href               string            `json:"href"`
+	ID                 string                     `json:"id"`
+	Name               string                     `json:"name"`
+	Deadline           string                     `json:"deadline"`
+	Workflow           *wmap.WorkflowMap          `json:"workflow,omitempty"`
+	Schedule           *core.Schedule             `json:"schedule,omitempty"`
+	CreationTimestamp  int64                      `json:"creation_timestamp,omitempty"`
+	LastRunTimestamp   int64                      `json:"last_run_timestamp,omitempty"`
+	HitCount           int                        `json:"hit_count,omitempty"`
+	MissCount          int                        `json:"miss_count,omitempty"`
+	FailedCount        int                        `json:"failed_count,omitempty"`
+	LastFailureMessage string                     `json:"last_failure_message,omitempty"`
+	State              string                     `json:"task_state"`
+	Href               string                     `json:"href"`
+	Singleton          bool                       `json:"singleton,omitempty"`
+	Queue              *core.TaskQueue            `json:"queue,omitempty"`
+	PinnedThread       *core.TaskPinnedThread     `json:"pinned-thread,omitempty"`
+	PublishRateLimit   *core.TaskPublishRateLimit `json:"publish-rate-limit,omitempty"`
+	CardinalityLimit   *core.TaskCardinalityLimit `json:"cardinality-limit,omitempty"`
+	Owner              *core.TaskOwner            `json:"owner,omitempty"`
 }
 
 func (s *ScheduledTask) CreationTime() time.Time {
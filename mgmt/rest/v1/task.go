@@ -28,6 +28,8 @@ import (
 	"time"
 
 	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/admission"
+	"github.com/intelsdi-x/snap/mgmt/rest/api"
 	"github.com/intelsdi-x/snap/mgmt/rest/v1/rbody"
 	"github.com/julienschmidt/httprouter"
 	log "github.com/sirupsen/logrus"
@@ -45,9 +47,13 @@ var (
 )
 
 func (s *apiV1) addTask(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	task, err := core.CreateTaskFromContent(r.Body, nil, s.taskManager.CreateTask)
+	task, err := core.CreateTaskFromContent(r.Body, nil, api.OwnedTaskCreator(r, s.taskManager.CreateTask), s.taskAdmitters...)
 	if err != nil {
-		rbody.Write(500, rbody.FromError(err), w)
+		code := 500
+		if _, ok := err.(*admission.RejectedError); ok {
+			code = 403
+		}
+		rbody.Write(code, rbody.FromError(err), w)
 		return
 	}
 	taskB := rbody.AddSchedulerTaskFromTask(task)
@@ -59,13 +65,16 @@ func (s *apiV1) getTasks(w http.ResponseWriter, r *http.Request, _ httprouter.Pa
 	sts := s.taskManager.GetTasks()
 
 	tasks := &rbody.ScheduledTaskListReturned{}
-	tasks.ScheduledTasks = make([]rbody.ScheduledTask, len(sts))
+	tasks.ScheduledTasks = make([]rbody.ScheduledTask, 0, len(sts))
 
-	i := 0
 	for _, t := range sts {
-		tasks.ScheduledTasks[i] = *rbody.SchedulerTaskFromTask(t)
-		tasks.ScheduledTasks[i].Href = taskURI(r.Host, version, t)
-		i++
+		// the callers only list the tasks of their tenant
+		if !api.CanAccessTask(r, t) {
+			continue
+		}
+		task := *rbody.SchedulerTaskFromTask(t)
+		task.Href = taskURI(r.Host, version, t)
+		tasks.ScheduledTasks = append(tasks.ScheduledTasks, task)
 	}
 	sort.Sort(tasks)
 	rbody.Write(200, tasks, w)
@@ -0,0 +1,77 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"net/http"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// AdvisorReportResponse returns the findings of the task advisor.
+//
+// swagger:response AdvisorReportResponse
+type AdvisorReportResponse struct {
+	// in: body
+	Report core.AdvisorReport
+}
+
+// advisesTasks is implemented by a task manager which scans its running
+// tasks for actionable findings.
+type advisesTasks interface {
+	AdviseTasks() *core.AdvisorReport
+}
+
+func (s *apiV2) getAdvisorReport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	adv, ok := s.taskManager.(advisesTasks)
+	if !ok {
+		Write(501, FromError(ErrAdvisorUnsupported), w)
+		return
+	}
+	report := adv.AdviseTasks()
+	// the callers only see the findings about the tasks of their tenant
+	// unless they are admins
+	if !api.CanAccessAllTasks(r) {
+		access := s.taskAccess(r)
+		findings := []core.TaskAdvisory{}
+		scanned := 0
+		for _, f := range report.Findings {
+			if access(f.TaskID) {
+				findings = append(findings, f)
+			}
+		}
+		for id, t := range s.taskManager.GetTasks() {
+			switch t.State() {
+			case core.TaskSpinning, core.TaskFiring, core.TaskSuspended:
+				if access(id) {
+					scanned++
+				}
+			}
+		}
+		report = &core.AdvisorReport{
+			Timestamp:    report.Timestamp,
+			TasksScanned: scanned,
+			Findings:     findings,
+		}
+	}
+	Write(200, report, w)
+}
@@ -0,0 +1,64 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"net/http"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// AlertsResponse returns the state of the alerts of the tasks.
+//
+// swagger:response AlertsResponse
+type AlertsResponse struct {
+	// in: body
+	Alerts []core.Alert `json:"alerts"`
+}
+
+// listsAlerts is implemented by a task manager which tracks the state of
+// the alert nodes of its tasks.
+type listsAlerts interface {
+	Alerts() []core.Alert
+}
+
+func (s *apiV2) getAlerts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	al, ok := s.taskManager.(listsAlerts)
+	if !ok {
+		Write(501, FromError(ErrAlertsUnsupported), w)
+		return
+	}
+	// the callers only see the alerts of the tasks of their tenant unless
+	// they are admins
+	access := func(string) bool { return true }
+	if !api.CanAccessAllTasks(r) {
+		access = s.taskAccess(r)
+	}
+	state := r.URL.Query().Get("state")
+	alerts := []core.Alert{}
+	for _, a := range al.Alerts() {
+		if (state == "" || a.State == state) && access(a.TaskID) {
+			alerts = append(alerts, a)
+		}
+	}
+	Write(200, AlertsResponse{Alerts: alerts}, w)
+}
@@ -25,6 +25,7 @@ import (
 
 	"net/http"
 
+	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/mgmt/rest/api"
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/negroni"
@@ -44,6 +45,15 @@ type apiV2 struct {
 	metricManager api.Metrics
 	taskManager   api.Tasks
 	configManager api.Config
+	// taskAdmitters review task creation requests before tasks are created
+	taskAdmitters []core.TaskAdmitter
+	daemonInfo    *api.DaemonInfo
+	// snapshotManager is nil when configuration snapshots are disabled
+	snapshotManager api.Snapshots
+	// auditLog is nil when the audit log is disabled
+	auditLog api.AuditLog
+	logging  api.Logging
+	reloader api.Reloader
 
 	wg       *sync.WaitGroup
 	killChan chan struct{}
@@ -56,6 +66,23 @@ func New(wg *sync.WaitGroup, killChan chan struct{}, protocol string) *apiV2 {
 
 func (s *apiV2) GetRoutes() []api.Route {
 	routes := []api.Route{
+		// swagger:route GET /info info getInfo
+		//
+		// Get Info
+		//
+		// Describes the daemon: its version, build, enabled subsystems, supported
+		// schedule types and plugin RPC protocols.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: DaemonInfoResponse
+		// 404: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/info", Handle: s.getInfo},
 		// swagger:route GET /plugins plugins getPlugins
 		//
 		// Get All
@@ -93,7 +120,9 @@ func (s *apiV2) GetRoutes() []api.Route {
 		//
 		// Load
 		//
-		// A plugin binary is required.
+		// A plugin binary is required. With upgrade=true the plugin replaces the loaded
+		// versions of the plugin with the same type and name; running tasks are moved
+		// to it and the previous versions are unloaded once unused.
 		//
 		// Consumes:
 		// multipart/form-data
@@ -109,6 +138,7 @@ func (s *apiV2) GetRoutes() []api.Route {
 		// 409: ErrorResponse
 		// 415: ErrorResponse
 		// 500: ErrorResponse
+		// 501: ErrorResponse
 		// 401: UnauthResponse
 		api.Route{Method: "POST", Path: prefix + "/plugins", Handle: s.loadPlugin},
 		// swagger:route DELETE /plugins/{ptype}/{pname}/{pversion} plugins unloadPlugin
@@ -146,6 +176,25 @@ func (s *apiV2) GetRoutes() []api.Route {
 		// 400: ErrorResponse
 		// 401: UnauthResponse
 		api.Route{Method: "GET", Path: prefix + "/plugins/:type/:name/:version/config", Handle: s.getPluginConfigItem},
+		// swagger:route GET /plugins/{ptype}/{pname}/{pversion}/policy plugins getPluginPolicy
+		//
+		// Get Config Policy
+		//
+		// Lists the rules of the config policy of the plugin by namespace, with
+		// the type, the default, the minimum, the maximum and the allowed values
+		// of each config item, e.g. to generate the form of the config.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: PluginPolicyResponse
+		// 400: ErrorResponse
+		// 401: UnauthResponse
+		// 404: ErrorResponse
+		api.Route{Method: "GET", Path: prefix + "/plugins/:type/:name/:version/policy", Handle: s.getPluginPolicy},
 		// swagger:route PUT /plugins/{ptype}/{pname}/{pversion}/config plugins setPluginConfigItem
 		//
 		// Set Config
@@ -201,6 +250,24 @@ func (s *apiV2) GetRoutes() []api.Route {
 		// 500: ErrorResponse
 		// 401: UnauthResponse
 		api.Route{Method: "GET", Path: prefix + "/metrics", Handle: s.getMetrics},
+		// swagger:route GET /metrics/watch plugins watchMetricCatalog
+		//
+		// Watch Metric Catalog
+		//
+		// Streams the changes of the metric catalog and of the metrics
+		// resolved for the tasks after one.
+		//
+		// Produces:
+		// text/event-stream
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: CatalogWatchResponse
+		// 500: ErrorResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/metrics/watch", Handle: s.watchMetricCatalog},
 		// swagger:route GET /tasks tasks getTasks
 		//
 		// Get All
@@ -249,6 +316,172 @@ func (s *apiV2) GetRoutes() []api.Route {
 		// 500: ErrorResponse
 		// 401: UnauthResponse
 		api.Route{Method: "GET", Path: prefix + "/tasks/:id/watch", Handle: s.watchTask},
+		// swagger:route GET /tasks/{id}/export tasks exportTask
+		//
+		// Export
+		//
+		// Returns a portable manifest of the task which can be imported on
+		// any Snap instance, as the body of the 200 response. The task ID is
+		// required.
+		//
+		// Produces:
+		// application/json
+		// application/x-yaml
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 400: ErrorResponse
+		// 404: ErrorResponse
+		// 500: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/tasks/:id/export", Handle: s.exportTask},
+		// swagger:route GET /tasks/{id}/history tasks getTaskHistory
+		//
+		// History
+		//
+		// Returns when and why the state of the task changed, oldest first.
+		// The task ID is required.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: TaskHistoryResponse
+		// 404: ErrorResponse
+		// 500: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/tasks/:id/history", Handle: s.getTaskHistory},
+		// swagger:route GET /tasks/{id}/backfill tasks getBackfill
+		//
+		// Get Backfill
+		//
+		// Returns the status of the last backfill of the task.
+		// The task ID is required.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: BackfillResponse
+		// 404: ErrorResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/tasks/:id/backfill", Handle: s.getBackfill},
+		// swagger:route PUT /tasks/{id}/backfill tasks startBackfill
+		//
+		// Start Backfill
+		//
+		// Runs the workflow of a running task for the past timestamps between start and end,
+		// spaced by the interval of its schedule, speed times faster than its schedule.
+		// The collectors of the task must support collecting past metrics.
+		//
+		// Consumes:
+		// application/json
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 202: BackfillResponse
+		// 400: ErrorResponse
+		// 404: ErrorResponse
+		// 409: ErrorResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "PUT", Path: prefix + "/tasks/:id/backfill", Handle: s.startBackfill},
+		// swagger:route DELETE /tasks/{id}/backfill tasks cancelBackfill
+		//
+		// Cancel Backfill
+		//
+		// Cancels the backfill running for the task; the run in progress completes.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: BackfillResponse
+		// 404: ErrorResponse
+		// 409: ErrorResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "DELETE", Path: prefix + "/tasks/:id/backfill", Handle: s.cancelBackfill},
+		// swagger:route POST /tasks/import tasks importTask
+		//
+		// Import
+		//
+		// A JSON or YAML task manifest produced by export is required.
+		// The imported task is given a new ID.
+		//
+		// Consumes:
+		// application/json
+		// application/x-yaml
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 201: TaskResponse
+		// 403: ErrorResponse
+		// 500: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "POST", Path: prefix + "/tasks/import", Handle: s.importTask},
+		// swagger:route POST /tasks/simulate tasks simulateSchedule
+		//
+		// Simulate
+		//
+		// Runs a schedule over a day against the recorded run durations of a task, or
+		// of all the tasks of the daemon, and returns the expected missed fires along
+		// with a recommended interval.
+		//
+		// Consumes:
+		// application/json
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: ScheduleSimulationResponse
+		// 400: ErrorResponse
+		// 404: ErrorResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "POST", Path: prefix + "/tasks/simulate", Handle: s.simulateSchedule},
+		// swagger:route POST /tasks/simulate/run tasks simulateRun
+		//
+		// Simulate Run
+		//
+		// Simulates the schedules of several tasks together over a virtual time range,
+		// without running anything, and returns when each task would fire, the fires of
+		// different tasks colliding and the load of the runs.
+		//
+		// Consumes:
+		// application/json
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: RunSimulationResponse
+		// 400: ErrorResponse
+		// 404: ErrorResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "POST", Path: prefix + "/tasks/simulate/run", Handle: s.simulateRun},
 		// swagger:route POST /tasks tasks addTask
 		//
 		// Add
@@ -265,6 +498,7 @@ func (s *apiV2) GetRoutes() []api.Route {
 		//
 		// Responses:
 		// 201: TaskResponse
+		// 403: ErrorResponse
 		// 500: ErrorResponse
 		// 401: UnauthResponse
 		api.Route{Method: "POST", Path: prefix + "/tasks", Handle: s.addTask},
@@ -306,6 +540,401 @@ func (s *apiV2) GetRoutes() []api.Route {
 		// 500: TaskErrorResponse
 		// 401: UnauthResponse
 		api.Route{Method: "DELETE", Path: prefix + "/tasks/:id", Handle: s.removeTask},
+		// swagger:route GET /templates templates getTemplates
+		//
+		// Get Templates
+		//
+		// Lists the task templates, sorted by name.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: TemplatesResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/templates", Handle: s.getTemplates},
+		// swagger:route POST /templates templates addTemplate
+		//
+		// Add Template
+		//
+		// Registers a task template: a task manifest whose strings refer to the variables
+		// of the template as {{name}}.
+		//
+		// Consumes:
+		// application/json
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 201: TemplateResponse
+		// 400: ErrorResponse
+		// 409: ErrorResponse
+		// 500: ErrorResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "POST", Path: prefix + "/templates", Handle: s.addTemplate},
+		// swagger:route GET /templates/{name} templates getTemplate
+		//
+		// Get Template
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: TemplateResponse
+		// 404: ErrorResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/templates/:name", Handle: s.getTemplate},
+		// swagger:route DELETE /templates/{name} templates removeTemplate
+		//
+		// Remove Template
+		//
+		// The tasks created from the template are not affected.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 204: TemplateResponse
+		// 404: ErrorResponse
+		// 500: ErrorResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "DELETE", Path: prefix + "/templates/:name", Handle: s.removeTemplate},
+		// swagger:route POST /templates/{name}/tasks templates addTemplateTask
+		//
+		// Add Task From Template
+		//
+		// Creates a task from a template given the values of its variables. The body is a
+		// task manifest with the "variables" of the template; the options it sets take
+		// precedence over the ones of the template.
+		//
+		// Consumes:
+		// application/json
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 201: TaskResponse
+		// 400: ErrorResponse
+		// 403: ErrorResponse
+		// 404: ErrorResponse
+		// 500: ErrorResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "POST", Path: prefix + "/templates/:name/tasks", Handle: s.addTemplateTask},
+		// swagger:route GET /advisor tasks getAdvisorReport
+		//
+		// Advisor
+		//
+		// Scans the running tasks and reports the deprecated metrics they collect, the
+		// plugins they use which have a newer version loaded, the schedules which miss
+		// too many fires and the publishers which keep failing.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: AdvisorReportResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/advisor", Handle: s.getAdvisorReport},
+		// swagger:route GET /cardinality tasks getCardinality
+		//
+		// Cardinality
+		//
+		// Returns the number of unique metric namespaces collected by the latest run of the
+		// tasks against the global and per-task cardinality limits, with the namespace
+		// patterns having the most of them.  The top query parameter sets the number of
+		// patterns returned, 10 by default.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: CardinalityResponse
+		// 400: ErrorResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/cardinality", Handle: s.getCardinality},
+		// swagger:route GET /subscriptions plugins getSubscriptions
+		//
+		// Subscriptions
+		//
+		// Lists the tasks subscribed to every loaded plugin.  The subscriptions of the
+		// tasks which do not exist anymore or are not running were left behind, and are
+		// reported as leaked; a plugin without subscribers can be unloaded.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: SubscriptionsResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/subscriptions", Handle: s.getSubscriptions},
+		// swagger:route GET /alerts tasks getAlerts
+		//
+		// Alerts
+		//
+		// Lists the state, firing or resolved, of the alert nodes of the tasks for every
+		// series they evaluated.  The state query parameter restricts the list to the
+		// alerts in that state.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: AlertsResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/alerts", Handle: s.getAlerts},
+		// swagger:route GET /maintenance tasks getMaintenance
+		//
+		// Get Maintenance
+		//
+		// Returns the maintenance window the scheduler is in, if any, and the past ones.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: MaintenanceResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/maintenance", Handle: s.getMaintenance},
+		// swagger:route POST /maintenance tasks startMaintenance
+		//
+		// Start Maintenance
+		//
+		// Pauses the given tasks, or all the tasks, at once until the maintenance ends.
+		// The fires the tasks skip are neither missed nor failed.
+		//
+		// Consumes:
+		// application/json
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 201: MaintenanceWindowResponse
+		// 400: ErrorResponse
+		// 404: ErrorResponse
+		// 409: ErrorResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "POST", Path: prefix + "/maintenance", Handle: s.startMaintenance},
+		// swagger:route DELETE /maintenance tasks endMaintenance
+		//
+		// End Maintenance
+		//
+		// Resumes the tasks paused by the current maintenance window.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: MaintenanceWindowResponse
+		// 409: ErrorResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "DELETE", Path: prefix + "/maintenance", Handle: s.endMaintenance},
+		// swagger:route GET /events tasks getEvents
+		//
+		// Get Events
+		//
+		// Lists the task lifecycle events logged by the scheduler (created, started, stopped,
+		// disabled, fired...), oldest first. The events can be filtered by task, namespace
+		// and time range.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: EventsResponse
+		// 400: ErrorResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/events", Handle: s.getEvents},
+		// swagger:route GET /audit audit getAudit
+		//
+		// Get Audit
+		//
+		// Lists who created, started, stopped, enabled and removed tasks and who loaded
+		// and unloaded plugins, and when, oldest first. The records can be filtered by
+		// user, action, target and time.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: AuditResponse
+		// 400: ErrorResponse
+		// 404: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/audit", Handle: s.getAudit},
+		// swagger:route GET /log/levels logging getLogLevels
+		//
+		// Get Log Levels
+		//
+		// Returns the default log level of the daemon and the levels of the modules
+		// which have their own.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: LogLevelsResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/log/levels", Handle: s.getLogLevels},
+		// swagger:route PUT /log/levels logging setLogLevels
+		//
+		// Set Log Levels
+		//
+		// Replaces the default log level and the levels of the modules at runtime,
+		// e.g. {"default":"info","modules":{"scheduler":"debug"}}. The changes are
+		// not saved to the configuration file.
+		//
+		// Consumes:
+		// application/json
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: LogLevelsResponse
+		// 400: ErrorResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "PUT", Path: prefix + "/log/levels", Handle: s.setLogLevels},
+		// swagger:route POST /config/reload config reloadConfig
+		//
+		// Reload Configuration
+		//
+		// Reads the configuration file of the daemon again and applies the settings
+		// which change without a restart: the log levels, the size of the worker
+		// pools, the default timeouts of the tasks and the plugin trust settings.
+		// The running tasks are not interrupted. The settings are applied all
+		// together or, when the configuration is invalid, none is.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: ConfigReloadedResponse
+		// 400: ErrorResponse
+		// 501: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "POST", Path: prefix + "/config/reload", Handle: s.reloadConfig},
+		// swagger:route GET /snapshots snapshots getSnapshots
+		//
+		// Get All
+		//
+		// Lists the configuration snapshots from the oldest to the most recent.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: SnapshotsResponse
+		// 404: ErrorResponse
+		// 500: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/snapshots", Handle: s.getSnapshots},
+		// swagger:route POST /snapshots snapshots takeSnapshot
+		//
+		// Take
+		//
+		// Saves the loaded plugins and the tasks into a new snapshot.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 201: SnapshotResponse
+		// 404: ErrorResponse
+		// 500: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "POST", Path: prefix + "/snapshots", Handle: s.takeSnapshot},
+		// swagger:route GET /snapshots/{name} snapshots getSnapshot
+		//
+		// Get
+		//
+		// The snapshot name is required.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: SnapshotResponse
+		// 404: ErrorResponse
+		// 500: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "GET", Path: prefix + "/snapshots/:name", Handle: s.getSnapshot},
+		// swagger:route POST /snapshots/{name}/restore snapshots restoreSnapshot
+		//
+		// Restore
+		//
+		// Loads the plugins of the snapshot which are not loaded anymore, removes
+		// the tasks created since the snapshot and creates again the tasks removed
+		// since. A snapshot is taken before restoring. The snapshot name is required.
+		//
+		// Produces:
+		// application/json
+		//
+		// Schemes: http, https
+		//
+		// Responses:
+		// 200: SnapshotRestoreResponse
+		// 404: ErrorResponse
+		// 500: ErrorResponse
+		// 401: UnauthResponse
+		api.Route{Method: "POST", Path: prefix + "/snapshots/:name/restore", Handle: s.restoreSnapshot},
 	}
 	return routes
 }
@@ -316,6 +945,11 @@ func (s *apiV2) BindMetricManager(metricManager api.Metrics) {
 
 func (s *apiV2) BindTaskManager(taskManager api.Tasks) {
 	s.taskManager = taskManager
+	// a task manager expanding task presets reviews the requests before the
+	// other admitters so that they see the options of the preset
+	if a, ok := taskManager.(core.TaskAdmitter); ok {
+		s.taskAdmitters = append([]core.TaskAdmitter{a}, s.taskAdmitters...)
+	}
 }
 
 func (s *apiV2) BindTribeManager(tribeManager api.Tribe) {}
@@ -324,6 +958,38 @@ func (s *apiV2) BindConfigManager(configManager api.Config) {
 	s.configManager = configManager
 }
 
+func (s *apiV2) BindTaskAdmitter(taskAdmitter core.TaskAdmitter) {
+	s.taskAdmitters = append(s.taskAdmitters, taskAdmitter)
+}
+
+// BindDaemonInfo sets the description of the daemon returned by GET /v2/info
+func (s *apiV2) BindDaemonInfo(info *api.DaemonInfo) {
+	s.daemonInfo = info
+}
+
+// BindSnapshotManager sets the manager of the configuration snapshots served
+// by /v2/snapshots
+func (s *apiV2) BindSnapshotManager(m api.Snapshots) {
+	s.snapshotManager = m
+}
+
+// BindAuditLog sets the audit log served by /v2/audit
+func (s *apiV2) BindAuditLog(l api.AuditLog) {
+	s.auditLog = l
+}
+
+// BindLogging sets the logging subsystem whose levels are served by
+// /v2/log/levels
+func (s *apiV2) BindLogging(l api.Logging) {
+	s.logging = l
+}
+
+// BindReloader sets the reloader of the configuration triggered by
+// /v2/config/reload
+func (s *apiV2) BindReloader(r api.Reloader) {
+	s.reloader = r
+}
+
 func Write(code int, body interface{}, w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json; version=2; charset=utf-8")
 	w.Header().Set("Version", "beta")
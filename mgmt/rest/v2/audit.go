@@ -0,0 +1,86 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/audit"
+	"github.com/julienschmidt/httprouter"
+)
+
+// AuditResponse returns the audit records of the task and plugin operations.
+//
+// swagger:response AuditResponse
+type AuditResponse struct {
+	// in: body
+	Records []audit.Record `json:"records"`
+}
+
+// AuditParams defines the filters of the audit records.
+//
+// swagger:parameters getAudit
+type AuditParams struct {
+	// in: query
+	User string `json:"user"`
+	// in: query
+	Action string `json:"action"`
+	// in: query
+	Target string `json:"target"`
+	// Unix timestamp of the oldest record returned.
+	// in: query
+	Since int64 `json:"since"`
+	// Number of most recent records returned.
+	// in: query
+	Limit int `json:"limit"`
+}
+
+func (s *apiV2) getAudit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if s.auditLog == nil {
+		Write(404, FromError(ErrAuditDisabled), w)
+		return
+	}
+	q := r.URL.Query()
+	f := audit.Filter{
+		User:   q.Get("user"),
+		Action: q.Get("action"),
+		Target: q.Get("target"),
+	}
+	if v := q.Get("since"); v != "" {
+		since, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			Write(400, FromError(fmt.Errorf("invalid since: %v", err)), w)
+			return
+		}
+		f.Since = time.Unix(since, 0)
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			Write(400, FromError(fmt.Errorf("invalid limit: %s", v)), w)
+			return
+		}
+		f.Limit = limit
+	}
+	Write(200, AuditResponse{Records: s.auditLog.Query(f)}, w)
+}
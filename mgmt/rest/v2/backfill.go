@@ -0,0 +1,117 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/julienschmidt/httprouter"
+)
+
+// BackfillResponse returns the status of the backfill of a task.
+//
+// swagger:response BackfillResponse
+type BackfillResponse struct {
+	// in: body
+	Backfill core.BackfillStatus
+}
+
+// BackfillParams defines the range of past timestamps to run the task for.
+//
+// swagger:parameters startBackfill
+type BackfillParams struct {
+	// in: body
+	//
+	// required: true
+	Backfill core.Backfill
+}
+
+// backfillsTasks is implemented by a task manager which runs the workflow of
+// the tasks for past timestamps.
+type backfillsTasks interface {
+	BackfillTask(string, core.Backfill) (*core.BackfillStatus, error)
+	GetBackfill(string) (*core.BackfillStatus, error)
+	CancelBackfill(string) (*core.BackfillStatus, error)
+}
+
+func (s *apiV2) startBackfill(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	bm, ok := s.taskManager.(backfillsTasks)
+	if !ok {
+		Write(501, FromError(ErrBackfillUnsupported), w)
+		return
+	}
+	id := p.ByName("id")
+	if _, err := s.taskManager.GetTask(id); err != nil {
+		Write(404, FromError(err), w)
+		return
+	}
+	var bf core.Backfill
+	err := json.NewDecoder(r.Body).Decode(&bf)
+	r.Body.Close()
+	if err != nil {
+		Write(400, FromError(err), w)
+		return
+	}
+	if bf.Start >= bf.End || bf.Speed < 0 {
+		Write(400, FromError(ErrInvalidBackfill), w)
+		return
+	}
+	status, err := bm.BackfillTask(id, bf)
+	if err != nil {
+		Write(409, FromError(err), w)
+		return
+	}
+	Write(202, status, w)
+}
+
+func (s *apiV2) getBackfill(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	bm, ok := s.taskManager.(backfillsTasks)
+	if !ok {
+		Write(501, FromError(ErrBackfillUnsupported), w)
+		return
+	}
+	status, err := bm.GetBackfill(p.ByName("id"))
+	if err != nil {
+		Write(404, FromError(err), w)
+		return
+	}
+	Write(200, status, w)
+}
+
+func (s *apiV2) cancelBackfill(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	bm, ok := s.taskManager.(backfillsTasks)
+	if !ok {
+		Write(501, FromError(ErrBackfillUnsupported), w)
+		return
+	}
+	id := p.ByName("id")
+	if _, err := s.taskManager.GetTask(id); err != nil {
+		Write(404, FromError(err), w)
+		return
+	}
+	status, err := bm.CancelBackfill(id)
+	if err != nil {
+		Write(409, FromError(err), w)
+		return
+	}
+	Write(200, status, w)
+}
@@ -0,0 +1,62 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CardinalityResponse returns the unique metric namespaces collected by the
+// tasks.
+//
+// swagger:response CardinalityResponse
+type CardinalityResponse struct {
+	// in: body
+	Report core.CardinalityReport
+}
+
+// reportsCardinality is implemented by a task manager which tracks the
+// unique metric namespaces collected by its tasks.
+type reportsCardinality interface {
+	Cardinality(top int) core.CardinalityReport
+}
+
+func (s *apiV2) getCardinality(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	rc, ok := s.taskManager.(reportsCardinality)
+	if !ok {
+		Write(501, FromError(ErrCardinalityUnsupported), w)
+		return
+	}
+	top := 0
+	if v := r.URL.Query().Get("top"); v != "" {
+		var err error
+		top, err = strconv.Atoi(v)
+		if err != nil || top <= 0 {
+			Write(400, FromError(fmt.Errorf("invalid top: %s", v)), w)
+			return
+		}
+	}
+	Write(200, rc.Cardinality(top), w)
+}
@@ -0,0 +1,131 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// CatalogWatchChange is the event type of a change of the metric
+	// catalog, or of the metrics resolved for a task after one
+	CatalogWatchChange = "catalog-change"
+	// catalogWatchBuffer is the number of changes buffered for a watcher;
+	// the changes are dropped when a slow client lets the buffer fill up
+	catalogWatchBuffer = 64
+)
+
+// catalogWatchers numbers the watchers of the metric catalog
+var catalogWatchers uint64
+
+// watchesCatalog is implemented by a metric manager which hands the changes
+// of its metric catalog to watchers.
+type watchesCatalog interface {
+	WatchMetricCatalog(string, func(core.CatalogChange)) error
+	UnwatchMetricCatalog(string) error
+}
+
+// CatalogWatchResponse defines the response of the metric catalog watching
+// stream.
+//
+// swagger:response CatalogWatchResponse
+type CatalogWatchResponse struct {
+	// in: body
+	Body struct {
+		CatalogWatch StreamedCatalogEvent `json:"catalog_watch"`
+	}
+}
+
+// StreamedCatalogEvent defines the metric catalog watching data type.
+type StreamedCatalogEvent struct {
+	EventType string              `json:"type"`
+	Message   string              `json:"message,omitempty"`
+	Change    *core.CatalogChange `json:"change,omitempty"`
+}
+
+func (e *StreamedCatalogEvent) ToJSON() string {
+	j, _ := json.Marshal(e)
+	return string(j)
+}
+
+func (s *apiV2) watchMetricCatalog(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	wc, ok := s.metricManager.(watchesCatalog)
+	if !ok {
+		Write(501, FromError(ErrWatchUnsupported), w)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		// This only works on ResponseWriters that support streaming
+		Write(500, FromError(ErrStreamingUnsupported), w)
+		return
+	}
+	changes := make(chan core.CatalogChange, catalogWatchBuffer)
+	name := strconv.FormatUint(atomic.AddUint64(&catalogWatchers, 1), 10)
+	err := wc.WatchMetricCatalog(name, func(c core.CatalogChange) {
+		select {
+		case changes <- c:
+		default:
+			restLogger.WithField("_block", "watch-metric-catalog").Warn("catalog change dropped for a slow watcher")
+		}
+	})
+	if err != nil {
+		Write(500, FromError(err), w)
+		return
+	}
+	defer wc.UnwatchMetricCatalog(name)
+
+	// Make this Server Sent Events compatible
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	so := StreamedCatalogEvent{
+		EventType: TaskWatchStreamOpen,
+		Message:   "Stream opened",
+	}
+	fmt.Fprintf(w, "data: %s\n\n", so.ToJSON())
+	flusher.Flush()
+
+	n := w.(http.CloseNotifier).CloseNotify()
+	for {
+		select {
+		case c := <-changes:
+			e := StreamedCatalogEvent{EventType: CatalogWatchChange, Change: &c}
+			fmt.Fprintf(w, "data: %s\n\n", e.ToJSON())
+			flusher.Flush()
+		case <-n:
+			return
+		case <-s.killChan:
+			return
+		}
+	}
+}
@@ -29,15 +29,43 @@ import (
 
 const (
 	ErrPluginAlreadyLoaded     = "plugin is already loaded"
+	ErrNoPluginToUpgrade       = "No loaded plugin with the same type and name to upgrade"
+	ErrUpgradeNotNewer         = "Upgraded plugin version must be newer than the loaded versions"
 	ErrTaskNotFound            = "task not found"
 	ErrTaskDisabledNotRunnable = "task is disabled"
+	ErrQuotaExceeded           = "quota exceeded"
+	ErrNoMaintenanceTasks      = "No task holds the labels of the maintenance"
 )
 
 var (
-	ErrPluginNotFound       = errors.New("plugin not found")
-	ErrStreamingUnsupported = errors.New("streaming unsupported")
-	ErrNoActionSpecified    = errors.New("no action was specified in the request")
-	ErrWrongAction          = errors.New("wrong action requested")
+	ErrPluginNotFound         = errors.New("plugin not found")
+	ErrStreamingUnsupported   = errors.New("streaming unsupported")
+	ErrNoActionSpecified      = errors.New("no action was specified in the request")
+	ErrWrongAction            = errors.New("wrong action requested")
+	ErrUpgradeUnsupported     = errors.New("plugin upgrade unsupported")
+	ErrDaemonInfoUnavailable  = errors.New("daemon info unavailable")
+	ErrSnapshotsDisabled      = errors.New("configuration snapshots are disabled")
+	ErrInvalidResourceLimit   = errors.New("resource limits must be non-negative numbers")
+	ErrSimulationUnsupported  = errors.New("schedule simulation unsupported")
+	ErrAdvisorUnsupported     = errors.New("task advisor unsupported")
+	ErrAlertsUnsupported      = errors.New("alerts unsupported")
+	ErrMaintenanceUnsupported = errors.New("maintenance mode unsupported")
+	ErrAuditDisabled          = errors.New("audit log is disabled")
+	ErrEventLogUnsupported    = errors.New("scheduler event log unsupported")
+	ErrLoggingUnsupported     = errors.New("log levels cannot be changed")
+	ErrReloadUnsupported      = errors.New("configuration reload unsupported")
+	ErrBackfillUnsupported    = errors.New("task backfill unsupported")
+	ErrCardinalityUnsupported = errors.New("metric cardinality tracking unsupported")
+	ErrInvalidBackfill        = errors.New("backfill start must be before its end and its speed positive")
+	ErrNoPluginToLoad         = errors.New("a plugin file, plugin_uri, plugin_url or registry_plugin is required")
+	ErrTaskListUnsupported    = errors.New("task filtering and pagination unsupported")
+	ErrTemplatesUnsupported   = errors.New("task templates unsupported")
+	ErrSubscriptionsUnknown   = errors.New("plugin subscriptions unavailable")
+	ErrSearchUnsupported      = errors.New("metric catalog search unsupported")
+	ErrWatchUnsupported       = errors.New("metric catalog watch unsupported")
+	ErrPauseAllForbidden      = errors.New("only admins may pause all the tasks")
+	ErrEndPauseForbidden      = errors.New("the maintenance window pauses the tasks of other tenants")
+	ErrMaintenanceSelection   = errors.New("a maintenance selects its tasks by task_ids or by labels, not both")
 )
 
 // ErrorResponse represents the Snap error response type.
@@ -0,0 +1,121 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// EventsResponse returns the events logged by the scheduler.
+//
+// swagger:response EventsResponse
+type EventsResponse struct {
+	// in: body
+	Events []core.EventRecord `json:"events"`
+}
+
+// EventsParams defines the filters of the logged events.
+//
+// swagger:parameters getEvents
+type EventsParams struct {
+	// in: query
+	TaskID string `json:"task_id"`
+	// Namespace of the events, e.g. Scheduler.TaskDisabled.
+	// in: query
+	Namespace string `json:"namespace"`
+	// Unix timestamp of the oldest event returned.
+	// in: query
+	Since int64 `json:"since"`
+	// Unix timestamp of the most recent event returned.
+	// in: query
+	Until int64 `json:"until"`
+	// Number of most recent events returned.
+	// in: query
+	Limit int `json:"limit"`
+}
+
+// queriesEvents is implemented by a task manager which logs the events of
+// its tasks.
+type queriesEvents interface {
+	QueryEvents(core.EventQuery) []core.EventRecord
+}
+
+func (s *apiV2) getEvents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	qe, ok := s.taskManager.(queriesEvents)
+	if !ok {
+		Write(501, FromError(ErrEventLogUnsupported), w)
+		return
+	}
+	q := r.URL.Query()
+	query := core.EventQuery{
+		TaskID:    q.Get("task_id"),
+		Namespace: q.Get("namespace"),
+	}
+	for _, bound := range []struct {
+		name string
+		t    *time.Time
+	}{{"since", &query.Since}, {"until", &query.Until}} {
+		v := q.Get(bound.name)
+		if v == "" {
+			continue
+		}
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			Write(400, FromError(fmt.Errorf("invalid %s: %v", bound.name, err)), w)
+			return
+		}
+		*bound.t = time.Unix(ts, 0)
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			Write(400, FromError(fmt.Errorf("invalid limit: %s", v)), w)
+			return
+		}
+		query.Limit = limit
+	}
+	if query.TaskID != "" {
+		if t, err := s.taskManager.GetTask(query.TaskID); err == nil && !api.CanAccessTask(r, t) {
+			Write(403, FromError(fmt.Errorf("task %s belongs to another tenant", query.TaskID)), w)
+			return
+		}
+	}
+	events := qe.QueryEvents(query)
+	// the callers only see the events of the tasks of their tenant unless
+	// they are admins
+	if !api.CanAccessAllTasks(r) {
+		access := s.taskAccess(r)
+		filtered := []core.EventRecord{}
+		for _, e := range events {
+			if access(e.TaskID) {
+				filtered = append(filtered, e)
+			}
+		}
+		events = filtered
+	}
+	Write(200, EventsResponse{Events: events}, w)
+}
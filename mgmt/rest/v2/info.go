@@ -0,0 +1,43 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"net/http"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// DaemonInfoResponse represents the response describing the daemon.
+//
+// swagger:response DaemonInfoResponse
+type DaemonInfoResponse struct {
+	// in: body
+	Body api.DaemonInfo
+}
+
+func (s *apiV2) getInfo(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if s.daemonInfo == nil {
+		Write(404, FromError(ErrDaemonInfoUnavailable), w)
+		return
+	}
+	Write(200, s.daemonInfo, w)
+}
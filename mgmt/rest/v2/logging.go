@@ -0,0 +1,76 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/pkg/logging"
+)
+
+// LogLevelsResponse returns the log levels of the modules of the daemon.
+//
+// swagger:response LogLevelsResponse
+type LogLevelsResponse struct {
+	// in: body
+	Body logging.Levels
+}
+
+// LogLevelsParams defines the log levels set.
+//
+// swagger:parameters setLogLevels
+type LogLevelsParams struct {
+	// in: body
+	Body logging.Levels
+}
+
+func (s *apiV2) getLogLevels(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if s.logging == nil {
+		Write(501, FromError(ErrLoggingUnsupported), w)
+		return
+	}
+	Write(200, s.logging.Levels(), w)
+}
+
+func (s *apiV2) setLogLevels(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if s.logging == nil {
+		Write(501, FromError(ErrLoggingUnsupported), w)
+		return
+	}
+	var levels logging.Levels
+	if err := json.NewDecoder(r.Body).Decode(&levels); err != nil {
+		Write(400, FromError(err), w)
+		return
+	}
+	if err := s.logging.SetLevels(levels); err != nil {
+		Write(400, FromError(err), w)
+		return
+	}
+	restLogger.WithFields(log.Fields{
+		"_block":  "set-log-levels",
+		"default": levels.Default,
+		"modules": levels.Modules,
+	}).Info("log levels changed")
+	Write(200, s.logging.Levels(), w)
+}
@@ -0,0 +1,205 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// MaintenanceResponse returns the current maintenance window, if any, and the
+// past ones.
+//
+// swagger:response MaintenanceResponse
+type MaintenanceResponse struct {
+	// in: body
+	Maintenance Maintenance `json:"maintenance"`
+}
+
+// Maintenance is the current maintenance window, absent when the scheduler is
+// not in maintenance mode, and the past ones, oldest first.
+type Maintenance struct {
+	Current *core.MaintenanceWindow  `json:"current,omitempty"`
+	History []core.MaintenanceWindow `json:"history"`
+}
+
+// MaintenanceWindowResponse returns the maintenance window which started or
+// ended.
+//
+// swagger:response MaintenanceWindowResponse
+type MaintenanceWindowResponse struct {
+	// in: body
+	Window core.MaintenanceWindow
+}
+
+// MaintenanceParams defines the tasks to pause.
+//
+// swagger:parameters startMaintenance
+type MaintenanceParams struct {
+	// in: body
+	Request MaintenanceRequest
+}
+
+// MaintenanceRequest gives the reason of a maintenance window and the IDs of
+// the tasks it pauses, or the labels selecting them; all the tasks are
+// paused when neither is given.
+type MaintenanceRequest struct {
+	Reason  string            `json:"reason,omitempty"`
+	TaskIDs []string          `json:"task_ids,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// managesMaintenance is implemented by a task manager which pauses its tasks
+// during maintenance windows.
+type managesMaintenance interface {
+	StartMaintenance(string, []string, core.TaskListOptions) (*core.MaintenanceWindow, error)
+	EndMaintenance() (*core.MaintenanceWindow, error)
+	Maintenance() (*core.MaintenanceWindow, []core.MaintenanceWindow)
+}
+
+func (s *apiV2) getMaintenance(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	mm, ok := s.taskManager.(managesMaintenance)
+	if !ok {
+		Write(501, FromError(ErrMaintenanceUnsupported), w)
+		return
+	}
+	current, history := mm.Maintenance()
+	Write(200, MaintenanceResponse{Maintenance: Maintenance{Current: current, History: history}}, w)
+}
+
+func (s *apiV2) startMaintenance(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	mm, ok := s.taskManager.(managesMaintenance)
+	if !ok {
+		Write(501, FromError(ErrMaintenanceUnsupported), w)
+		return
+	}
+	var req MaintenanceRequest
+	// an empty body pauses all the tasks
+	if r.ContentLength != 0 {
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			r.Body.Close()
+			Write(400, FromError(err), w)
+			return
+		}
+	}
+	r.Body.Close()
+	if len(req.TaskIDs) > 0 && len(req.Labels) > 0 {
+		Write(400, FromError(ErrMaintenanceSelection), w)
+		return
+	}
+	if err := core.ValidateTaskLabels(req.Labels); err != nil {
+		Write(400, FromError(err), w)
+		return
+	}
+	// the callers only pause the tasks of their tenant unless they are admins
+	var selector core.TaskListOptions
+	if len(req.Labels) > 0 {
+		selector.Labels = req.Labels
+		if !api.CanAccessAllTasks(r) {
+			selector.Visible = func(t core.Task) bool { return api.CanAccessTask(r, t) }
+		}
+	} else if len(req.TaskIDs) == 0 && !api.CanAccessAllTasks(r) {
+		Write(403, FromError(ErrPauseAllForbidden), w)
+		return
+	}
+	for _, id := range req.TaskIDs {
+		t, err := s.taskManager.GetTask(id)
+		if err != nil {
+			Write(404, FromError(err), w)
+			return
+		}
+		if !api.CanAccessTask(r, t) {
+			Write(403, FromError(fmt.Errorf("task %s belongs to another tenant", id)), w)
+			return
+		}
+	}
+	mw, err := mm.StartMaintenance(req.Reason, req.TaskIDs, selector)
+	if err != nil {
+		if err.Error() == ErrNoMaintenanceTasks {
+			Write(404, FromError(err), w)
+			return
+		}
+		Write(409, FromError(err), w)
+		return
+	}
+	Write(201, mw, w)
+}
+
+func (s *apiV2) endMaintenance(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	mm, ok := s.taskManager.(managesMaintenance)
+	if !ok {
+		Write(501, FromError(ErrMaintenanceUnsupported), w)
+		return
+	}
+	if current, _ := mm.Maintenance(); current != nil && !s.canAccessTasks(r, current.TaskIDs) {
+		Write(403, FromError(ErrEndPauseForbidden), w)
+		return
+	}
+	mw, err := mm.EndMaintenance()
+	if err != nil {
+		Write(409, FromError(err), w)
+		return
+	}
+	Write(200, mw, w)
+}
+
+// canAccessTasks returns true when the caller of the request may change all
+// the given tasks, all the tasks when none is given; only admins may change
+// the tasks which were removed
+func (s *apiV2) canAccessTasks(r *http.Request, ids []string) bool {
+	if api.CanAccessAllTasks(r) {
+		return true
+	}
+	if len(ids) == 0 {
+		return false
+	}
+	access := s.taskAccess(r)
+	for _, id := range ids {
+		if !access(id) {
+			return false
+		}
+	}
+	return true
+}
+
+// taskAccess returns whether the caller of the request may see the task
+// with a given id, looking every task up once; the tasks which were removed
+// and the records of no task are only seen by admins
+func (s *apiV2) taskAccess(r *http.Request) func(string) bool {
+	seen := map[string]bool{}
+	return func(id string) bool {
+		if id == "" {
+			return api.CanAccessAllTasks(r)
+		}
+		ok, found := seen[id]
+		if !found {
+			t, err := s.taskManager.GetTask(id)
+			ok = err == nil && api.CanAccessTask(r, t)
+			seen[id] = ok
+		}
+		return ok
+	}
+}
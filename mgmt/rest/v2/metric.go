@@ -25,6 +25,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"net/url"
 
@@ -55,6 +56,9 @@ type MetricParams struct {
 
 type MetricsResonse struct {
 	Metrics Metrics `json:"metrics,omitempty"`
+	// Next is the key to search the next page of metrics after, empty on the
+	// last page
+	Next string `json:"next,omitempty"`
 }
 
 type Metrics []Metric
@@ -96,11 +100,38 @@ func (m Metrics) Swap(i, j int) {
 	m[i], m[j] = m[j], m[i]
 }
 
+// searchesMetrics is implemented by a metric manager which searches its
+// catalog by namespace pattern, plugin, version and last advertised time,
+// a page at a time.
+type searchesMetrics interface {
+	SearchMetrics(core.MetricSearchOptions) ([]core.CatalogedMetric, string, error)
+}
+
+// searchParams are the query parameters of a search of the metric catalog
+var searchParams = []string{"plugin", "since", "after", "limit"}
+
+// isMetricSearch returns true when the query searches the catalog rather
+// than fetching the metrics under a namespace
+func isMetricSearch(q url.Values) bool {
+	for _, param := range searchParams {
+		if _, ok := q[param]; ok {
+			return true
+		}
+	}
+	// a trailing * is handled by FetchMetrics
+	ns := strings.TrimSuffix(q.Get("ns"), "*")
+	return strings.ContainsAny(ns, "*?[\\")
+}
+
 func (s *apiV2) getMetrics(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 
 	// If we are provided a parameter with the name 'ns' we need to
 	// perform a query
 	q := r.URL.Query()
+	if isMetricSearch(q) {
+		s.searchMetrics(w, r)
+		return
+	}
 	v := q.Get("ver")
 	ns_query := q.Get("ns")
 	if ns_query != "" {
@@ -137,7 +168,62 @@ func (s *apiV2) getMetrics(w http.ResponseWriter, r *http.Request, _ httprouter.
 	respondWithMetrics(r.Host, mts, w)
 }
 
+// searchMetrics lists the metrics of the catalog selected by namespace
+// pattern, plugin, version and last advertised time, in the order of their
+// namespaces and versions, a page at a time
+func (s *apiV2) searchMetrics(w http.ResponseWriter, r *http.Request) {
+	sm, ok := s.metricManager.(searchesMetrics)
+	if !ok {
+		Write(501, FromError(ErrSearchUnsupported), w)
+		return
+	}
+	q := r.URL.Query()
+	opts := core.MetricSearchOptions{
+		Namespace: q.Get("ns"),
+		Plugin:    q.Get("plugin"),
+		After:     q.Get("after"),
+	}
+	if v := q.Get("ver"); v != "" {
+		ver, err := strconv.Atoi(v)
+		if err != nil {
+			Write(400, FromError(err), w)
+			return
+		}
+		opts.Version = ver
+	}
+	if v := q.Get("since"); v != "" {
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			Write(400, FromError(fmt.Errorf("invalid since: %v", err)), w)
+			return
+		}
+		opts.Since = time.Unix(ts, 0)
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			Write(400, FromError(fmt.Errorf("invalid limit: %s", v)), w)
+			return
+		}
+		opts.Limit = limit
+	}
+	mts, next, err := sm.SearchMetrics(opts)
+	if err != nil {
+		Write(400, FromError(err), w)
+		return
+	}
+	b := metricsBody(r.Host, mts)
+	b.Next = next
+	Write(200, b, w)
+}
+
 func respondWithMetrics(host string, mts []core.CatalogedMetric, w http.ResponseWriter) {
+	Write(200, metricsBody(host, mts), w)
+}
+
+// metricsBody returns the response listing the metrics in the order of their
+// namespaces and versions
+func metricsBody(host string, mts []core.CatalogedMetric) MetricsResonse {
 	b := MetricsResonse{Metrics: make(Metrics, 0)}
 	for _, m := range mts {
 		policies := PolicyTableSlice(m.Policy().RulesAsTable())
@@ -155,7 +241,7 @@ func respondWithMetrics(host string, mts []core.CatalogedMetric, w http.Response
 		})
 	}
 	sort.Sort(b.Metrics)
-	Write(200, b, w)
+	return b
 }
 
 func catalogedMetricURI(host string, mt core.CatalogedMetric) string {
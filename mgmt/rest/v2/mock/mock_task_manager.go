@@ -67,26 +67,50 @@ type mockTask struct {
 	MyHref               string            `json:"href"`
 }
 
-func (t *mockTask) ID() string                          { return t.MyID }
-func (t *mockTask) State() core.TaskState               { return core.TaskSpinning }
-func (t *mockTask) HitCount() uint                      { return 0 }
-func (t *mockTask) GetName() string                     { return t.MyName }
-func (t *mockTask) SetName(string)                      { return }
-func (t *mockTask) SetID(string)                        { return }
-func (t *mockTask) MissedCount() uint                   { return 0 }
-func (t *mockTask) FailedCount() uint                   { return 0 }
-func (t *mockTask) LastFailureMessage() string          { return "" }
-func (t *mockTask) LastRunTime() *time.Time             { return &time.Time{} }
-func (t *mockTask) CreationTime() *time.Time            { return &time.Time{} }
-func (t *mockTask) DeadlineDuration() time.Duration     { return 4 }
-func (t *mockTask) SetDeadlineDuration(time.Duration)   { return }
-func (t *mockTask) SetTaskID(id string)                 { return }
-func (t *mockTask) SetStopOnFailure(int)                { return }
-func (t *mockTask) GetStopOnFailure() int               { return 0 }
-func (t *mockTask) MaxCollectDuration() time.Duration   { return time.Second }
-func (t *mockTask) SetMaxCollectDuration(time.Duration) {}
-func (t *mockTask) MaxMetricsBuffer() int64             { return 0 }
-func (t *mockTask) SetMaxMetricsBuffer(int64)           {}
+func (t *mockTask) ID() string                                      { return t.MyID }
+func (t *mockTask) State() core.TaskState                           { return core.TaskSpinning }
+func (t *mockTask) HitCount() uint                                  { return 0 }
+func (t *mockTask) GetName() string                                 { return t.MyName }
+func (t *mockTask) SetName(string)                                  { return }
+func (t *mockTask) SetID(string)                                    { return }
+func (t *mockTask) MissedCount() uint                               { return 0 }
+func (t *mockTask) FailedCount() uint                               { return 0 }
+func (t *mockTask) LastFailureMessage() string                      { return "" }
+func (t *mockTask) LastRunTime() *time.Time                         { return &time.Time{} }
+func (t *mockTask) CreationTime() *time.Time                        { return &time.Time{} }
+func (t *mockTask) DeadlineDuration() time.Duration                 { return 4 }
+func (t *mockTask) SetDeadlineDuration(time.Duration)               { return }
+func (t *mockTask) SetTaskID(id string)                             { return }
+func (t *mockTask) SetStopOnFailure(int)                            { return }
+func (t *mockTask) GetStopOnFailure() int                           { return 0 }
+func (t *mockTask) MaxCollectDuration() time.Duration               { return time.Second }
+func (t *mockTask) SetMaxCollectDuration(time.Duration)             {}
+func (t *mockTask) SetFailurePolicy(core.FailurePolicy)             {}
+func (t *mockTask) GetFailurePolicy() core.FailurePolicy            { return core.FailurePolicyDisable }
+func (t *mockTask) SetFailureCooldown(time.Duration)                {}
+func (t *mockTask) GetFailureCooldown() time.Duration               { return 0 }
+func (t *mockTask) SetSingleton(bool)                               { return }
+func (t *mockTask) IsSingleton() bool                               { return false }
+func (t *mockTask) SetDedicatedQueue(string, int)                   { return }
+func (t *mockTask) GetDedicatedQueue() (string, int)                { return "", 0 }
+func (t *mockTask) SetPinnedThread(*core.TaskPinnedThread)          { return }
+func (t *mockTask) GetPinnedThread() *core.TaskPinnedThread         { return nil }
+func (t *mockTask) SetPublishRateLimit(*core.TaskPublishRateLimit)  { return }
+func (t *mockTask) GetPublishRateLimit() *core.TaskPublishRateLimit { return nil }
+func (t *mockTask) SetCardinalityLimit(*core.TaskCardinalityLimit)  { return }
+func (t *mockTask) GetCardinalityLimit() *core.TaskCardinalityLimit { return nil }
+func (t *mockTask) SetBlackouts([]core.BlackoutPeriod)              {}
+func (t *mockTask) GetBlackouts() []core.BlackoutPeriod             { return nil }
+func (t *mockTask) BlackedOutCount() uint                           { return 0 }
+func (t *mockTask) SetOwner(*core.TaskOwner)                        { return }
+func (t *mockTask) GetOwner() *core.TaskOwner                       { return nil }
+func (t *mockTask) SetTimeouts(core.TaskTimeouts)                   {}
+func (t *mockTask) GetTimeouts() core.TaskTimeouts                  { return core.TaskTimeouts{} }
+func (t *mockTask) SetLabels(map[string]string)                     {}
+func (t *mockTask) GetLabels() map[string]string                    { return nil }
+func (t *mockTask) StateHistory() []core.TaskStateTransition        { return nil }
+func (t *mockTask) MaxMetricsBuffer() int64                         { return 0 }
+func (t *mockTask) SetMaxMetricsBuffer(int64)                       {}
 func (t *mockTask) Option(...core.TaskOption) core.TaskOption {
 	return core.TaskDeadlineDuration(0)
 }
@@ -91,11 +91,18 @@ type Plugin struct {
 	LastHitTimestamp int64         `json:"last_hit_timestamp,omitempty"`
 	ID               uint32        `json:"id,omitempty"`
 	PprofPort        string        `json:"pprof_port,omitempty"`
+	// ResourceLimits holds the resource limits the plugin was loaded with
+	ResourceLimits *core.PluginResourceLimits `json:"resource_limits,omitempty"`
+	// ResourceUsage holds the last sampled resource usage of a running plugin
+	ResourceUsage *core.PluginResourceUsage `json:"resource_usage,omitempty"`
+	// Subscribers is the number of tasks subscribed to a loaded plugin; the
+	// plugins without subscribers can be unloaded
+	Subscribers *int `json:"subscribers,omitempty"`
 }
 
 // PluginParams represents the request path plugin name, version and type.
 //
-// swagger:parameters getPlugin unloadPlugin getPluginConfigItem setPluginConfigItem
+// swagger:parameters getPlugin unloadPlugin getPluginConfigItem setPluginConfigItem getPluginPolicy
 type PluginParams struct {
 	// required: true
 	// in: path
@@ -152,6 +159,59 @@ type PluginPostParams struct {
 	// in: formData
 	//
 	PluginURI string `json:"plugin_uri"`
+	// URL (http or https) to download the plugin from
+	//
+	// in: formData
+	//
+	PluginURL string `json:"plugin_url"`
+	// Hex encoded SHA-256 checksum the plugin downloaded from plugin_url must match
+	//
+	// in: formData
+	//
+	PluginSHA256 string `json:"plugin_sha256"`
+	// URL to download the signature of the plugin from
+	//
+	// in: formData
+	//
+	PluginSignatureURL string `json:"plugin_signature_url"`
+	// Plugin of the plugin registry to download, as <type>:<name>[:<version>]
+	//
+	// in: formData
+	//
+	RegistryPlugin string `json:"registry_plugin"`
+	// Maximum resident memory of each running instance of the plugin, in bytes
+	//
+	// in: formData
+	//
+	MemoryLimit int64 `json:"memory_limit"`
+	// Maximum CPU usage of each running instance of the plugin, 100 being one core
+	//
+	// in: formData
+	//
+	CPULimit float64 `json:"cpu_limit"`
+	// Upgrade the loaded plugin with the same type and name
+	//
+	// in: query
+	//
+	Upgrade bool `json:"upgrade"`
+}
+
+// upgradesPlugins is implemented by a metric manager which can load a new
+// version of a plugin in place of the loaded ones without stopping the tasks
+// using them.
+type upgradesPlugins interface {
+	UpgradePlugin(*core.RequestedPlugin) (core.CatalogedPlugin, serror.SnapError)
+}
+
+// limitsResources is implemented by the plugins loaded with resource limits.
+type limitsResources interface {
+	ResourceLimits() core.PluginResourceLimits
+}
+
+// reportsResourceUsage is implemented by the running plugins whose resource
+// usage is sampled.
+type reportsResourceUsage interface {
+	ResourceUsage() core.PluginResourceUsage
 }
 
 // Map for collecting HTTP form field data
@@ -187,8 +247,11 @@ func (s *apiV2) loadPlugin(w http.ResponseWriter, r *http.Request, _ httprouter.
 
 	if strings.HasPrefix(mediaType, "multipart/") {
 		var certPath, keyPath, caCertPaths string
+		var source core.PluginSource
+		var registryPlugin string
 		var signature []byte
 		var checkSum [sha256.Size]byte
+		var limits core.PluginResourceLimits
 
 		// Go OpenAPI sends URL-encoded forms (without boundary data) if no file fields were passed.
 		// In standalone plugin mode, only plugin address is passed, so because of this behavior,
@@ -267,6 +330,26 @@ func (s *apiV2) loadPlugin(w http.ResponseWriter, r *http.Request, _ httprouter.
 					return
 				}
 				checkSum = sha256.Sum256(field.data)
+			case "memory_limit":
+				limits.MemoryBytes, err = strconv.ParseInt(string(field.data), 10, 64)
+				if err != nil || limits.MemoryBytes < 0 {
+					Write(400, FromError(ErrInvalidResourceLimit), w)
+					return
+				}
+			case "cpu_limit":
+				limits.CPUPercent, err = strconv.ParseFloat(string(field.data), 64)
+				if err != nil || limits.CPUPercent < 0 {
+					Write(400, FromError(ErrInvalidResourceLimit), w)
+					return
+				}
+			case "plugin_url":
+				source.URL = string(field.data)
+			case "plugin_sha256":
+				source.SHA256 = string(field.data)
+			case "plugin_signature_url":
+				source.SignatureURL = string(field.data)
+			case "registry_plugin":
+				registryPlugin = string(field.data)
 			case "plugin_uri":
 				pluginURI := string(field.data)
 				rp, err = core.NewRequestedPlugin(pluginURI, "", nil)
@@ -285,6 +368,27 @@ func (s *apiV2) loadPlugin(w http.ResponseWriter, r *http.Request, _ httprouter.
 			}
 		}
 
+		// A plugin may be downloaded from a URL or from the plugin registry
+		// of the daemon when it is loaded
+		if source.URL != "" {
+			rp, err = core.NewRemoteRequestedPlugin(source)
+			if err != nil {
+				Write(400, FromError(err), w)
+				return
+			}
+		} else if registryPlugin != "" {
+			ref, err := core.ParsePluginRegistryRef(registryPlugin)
+			if err != nil {
+				Write(400, FromError(err), w)
+				return
+			}
+			rp = core.NewRegistryRequestedPlugin(ref)
+		}
+		if rp == nil {
+			Write(400, FromError(ErrNoPluginToLoad), w)
+			return
+		}
+
 		// Sanity check, verify the checkSum on the file sent is the same
 		// as after it is written to disk.
 		if rp.CheckSum() != checkSum {
@@ -309,20 +413,34 @@ func (s *apiV2) loadPlugin(w http.ResponseWriter, r *http.Request, _ httprouter.
 			}
 		}
 		rp.SetSignature(signature)
+		rp.SetResourceLimits(limits)
+
+		load := s.metricManager.Load
+		if r.URL.Query().Get("upgrade") == "true" {
+			u, ok := s.metricManager.(upgradesPlugins)
+			if !ok {
+				Write(501, FromError(ErrUpgradeUnsupported), w)
+				return
+			}
+			load = u.UpgradePlugin
+		}
 
 		restLogger.Info("Loading plugin: ", rp.Path())
-		pl, err := s.metricManager.Load(rp)
+		pl, err := load(rp)
 		if err != nil {
 			var ec int
 			restLogger.Error(err)
-			restLogger.Debugf("Removing file (%s)", rp.Path())
-			err2 := os.RemoveAll(filepath.Dir(rp.Path()))
-			if err2 != nil {
-				restLogger.Error(err2)
+			// a plugin which failed to download has no file
+			if rp.Path() != "" {
+				restLogger.Debugf("Removing file (%s)", rp.Path())
+				err2 := os.RemoveAll(filepath.Dir(rp.Path()))
+				if err2 != nil {
+					restLogger.Error(err2)
+				}
 			}
 			rb := FromError(err)
 			switch rb.ErrorMessage {
-			case ErrPluginAlreadyLoaded:
+			case ErrPluginAlreadyLoaded, ErrNoPluginToUpgrade, ErrUpgradeNotNewer:
 				ec = 409
 			default:
 				ec = 500
@@ -419,6 +537,12 @@ func (s *apiV2) getPlugins(w http.ResponseWriter, r *http.Request, params httpro
 	} else {
 		// get plugins from the plugin catalog
 		plugins = pluginCatalogBody(r.Host, s.metricManager.PluginCatalog())
+		if subscribers := s.subscribers(); subscribers != nil {
+			for i, p := range plugins {
+				n := subscribers[subscriptionKey(p.Type, p.Name, p.Version)]
+				plugins[i].Subscribers = &n
+			}
+		}
 	}
 
 	filteredPlugins := []Plugin{}
@@ -450,7 +574,7 @@ func pluginCatalogBody(host string, c []core.CatalogedPlugin) []Plugin {
 }
 
 func catalogedPluginBody(host string, c core.CatalogedPlugin) Plugin {
-	return Plugin{
+	p := Plugin{
 		Name:            c.Name(),
 		Version:         c.Version(),
 		Type:            c.TypeName(),
@@ -459,6 +583,8 @@ func catalogedPluginBody(host string, c core.CatalogedPlugin) Plugin {
 		LoadedTimestamp: c.LoadedTimestamp().Unix(),
 		Href:            pluginURI(host, c),
 	}
+	p.ResourceLimits = resourceLimits(c)
+	return p
 }
 
 func runningPluginsBody(host string, c []core.AvailablePlugin) []Plugin {
@@ -473,11 +599,30 @@ func runningPluginsBody(host string, c []core.AvailablePlugin) []Plugin {
 			ID:               p.ID(),
 			Href:             pluginURI(host, p),
 			PprofPort:        p.Port(),
+			ResourceLimits:   resourceLimits(p),
+		}
+		if u, ok := p.(reportsResourceUsage); ok {
+			usage := u.ResourceUsage()
+			plugins[i].ResourceUsage = &usage
 		}
 	}
 	return plugins
 }
 
+// resourceLimits returns the resource limits of the plugin, or nil when it
+// has none
+func resourceLimits(p core.Plugin) *core.PluginResourceLimits {
+	l, ok := p.(limitsResources)
+	if !ok {
+		return nil
+	}
+	limits := l.ResourceLimits()
+	if limits.IsZero() {
+		return nil
+	}
+	return &limits
+}
+
 func pluginURI(host string, c core.Plugin) string {
 	return fmt.Sprintf("%s://%s/%s/plugins/%s/%s/%d", protocolPrefix, host, version, c.TypeName(), c.Name(), c.Version())
 }
@@ -519,6 +664,7 @@ func (s *apiV2) getPlugin(w http.ResponseWriter, r *http.Request, p httprouter.P
 				Required: r.Required,
 				Minimum:  r.Minimum,
 				Maximum:  r.Maximum,
+				Allowed:  r.Allowed,
 			})
 		}
 
@@ -557,5 +703,9 @@ func (s *apiV2) getPlugin(w http.ResponseWriter, r *http.Request, p httprouter.P
 		Href:            pluginURI(r.Host, plugin),
 		ConfigPolicy:    configPolicy,
 	}
+	if subscribers := s.subscribers(); subscribers != nil {
+		n := subscribers[subscriptionKey(plugin.TypeName(), plugin.Name(), plugin.Version())]
+		pluginRet.Subscribers = &n
+	}
 	Write(200, pluginRet, w)
 }
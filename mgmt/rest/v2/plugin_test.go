@@ -0,0 +1,71 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/urfave/negroni"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest/v2/mock"
+)
+
+// upgradingManager fails the upgrades of the plugins with the given error
+type upgradingManager struct {
+	mock.MockManagesMetrics
+	err error
+}
+
+func (m upgradingManager) UpgradePlugin(*core.RequestedPlugin) (core.CatalogedPlugin, serror.SnapError) {
+	return nil, serror.New(m.err)
+}
+
+func TestUpgradePlugin(t *testing.T) {
+	Convey("Given a REST API upgrading the plugins", t, func() {
+		s := New(&sync.WaitGroup{}, make(chan struct{}), "http")
+		upgrade := func(err error) int {
+			s.BindMetricManager(upgradingManager{err: err})
+			req := httptest.NewRequest("POST", "/v2/plugins?upgrade=true", strings.NewReader("plugin_uri=http://localhost:8182"))
+			req.Header.Set("Content-Type", "multipart/form-data")
+			rec := httptest.NewRecorder()
+			s.loadPlugin(negroni.NewResponseWriter(rec), req, nil)
+			return rec.Code
+		}
+
+		Convey("an upgrade to a version which is not newer is a conflict", func() {
+			So(upgrade(errors.New(ErrUpgradeNotNewer)), ShouldEqual, 409)
+		})
+		Convey("an upgrade of a plugin which is not loaded is a conflict", func() {
+			So(upgrade(errors.New(ErrNoPluginToUpgrade)), ShouldEqual, 409)
+		})
+		Convey("the other errors are internal errors", func() {
+			So(upgrade(errors.New("plugin crashed")), ShouldEqual, 500)
+		})
+	})
+}
@@ -0,0 +1,94 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PluginPolicyResponse represents the config policy of a plugin.
+//
+// swagger:response PluginPolicyResponse
+type PluginPolicyResponse struct {
+	// in: body
+	Body PluginPolicies
+}
+
+// PluginPolicies represents the nodes of the config policy of a plugin.
+type PluginPolicies struct {
+	Policies []PluginPolicy `json:"policies"`
+}
+
+// PluginPolicy represents the rules of the config policy of a plugin at
+// a namespace, "/" for the rules of the plugin itself, e.g. to generate
+// the form of the config.
+type PluginPolicy struct {
+	Namespace string           `json:"namespace"`
+	Rules     PolicyTableSlice `json:"rules"`
+}
+
+type pluginPolicies []PluginPolicy
+
+func (p pluginPolicies) Len() int           { return len(p) }
+func (p pluginPolicies) Less(i, j int) bool { return p[i].Namespace < p[j].Namespace }
+func (p pluginPolicies) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+func (s *apiV2) getPluginPolicy(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	plType, plName, plVersion, f, se := pluginParameters(p)
+	if se != nil {
+		Write(400, FromSnapError(se), w)
+		return
+	}
+
+	var plugin core.CatalogedPlugin
+	for _, item := range s.metricManager.PluginCatalog() {
+		if item.Name() == plName &&
+			item.Version() == plVersion &&
+			item.TypeName() == plType {
+			plugin = item
+			break
+		}
+	}
+	if plugin == nil {
+		Write(404, FromSnapError(serror.New(ErrPluginNotFound, f)), w)
+		return
+	}
+
+	policies := pluginPolicies{}
+	if plugin.Policy() != nil {
+		for _, node := range plugin.Policy().GetAll() {
+			if !node.HasRules() {
+				continue
+			}
+			policies = append(policies, PluginPolicy{
+				Namespace: "/" + strings.Join(node.Key, "/"),
+				Rules:     PolicyTableSlice(node.RulesAsTable()),
+			})
+		}
+	}
+	sort.Sort(policies)
+	Write(200, PluginPolicies{Policies: policies}, w)
+}
@@ -0,0 +1,58 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	log "github.com/sirupsen/logrus"
+)
+
+// ConfigReloadedResponse returns the settings changed by a reload of the
+// configuration.
+//
+// swagger:response ConfigReloadedResponse
+type ConfigReloadedResponse struct {
+	// in: body
+	Body ConfigReloaded
+}
+
+// ConfigReloaded lists the settings changed by a reload of the configuration.
+type ConfigReloaded struct {
+	Changed []string `json:"changed"`
+}
+
+func (s *apiV2) reloadConfig(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if s.reloader == nil {
+		Write(501, FromError(ErrReloadUnsupported), w)
+		return
+	}
+	changed, err := s.reloader.Reload()
+	if err != nil {
+		Write(400, FromError(err), w)
+		return
+	}
+	restLogger.WithFields(log.Fields{
+		"_block":  "reload-config",
+		"changed": changed,
+	}).Info("configuration reloaded")
+	Write(200, ConfigReloaded{Changed: changed}, w)
+}
@@ -0,0 +1,153 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ScheduleSimulationResponse returns the outcome of a schedule simulation.
+//
+// swagger:response ScheduleSimulationResponse
+type ScheduleSimulationResponse struct {
+	// in: body
+	Simulation core.ScheduleSimulation
+}
+
+// ScheduleSimulationParams defines the schedule to simulate.
+//
+// swagger:parameters simulateSchedule
+type ScheduleSimulationParams struct {
+	// in: body
+	//
+	// required: true
+	Request ScheduleSimulationRequest
+}
+
+// ScheduleSimulationRequest is the schedule to simulate and the task whose
+// recorded run durations it is simulated against.  The run durations of all
+// the tasks of the daemon are used when no task is given.
+type ScheduleSimulationRequest struct {
+	Schedule *core.Schedule `json:"schedule"`
+	TaskID   string         `json:"task_id,omitempty"`
+}
+
+// simulatesSchedules is implemented by a task manager which records the
+// durations of the task runs to simulate schedules against them.
+type simulatesSchedules interface {
+	SimulateSchedule(*core.Schedule, string) (*core.ScheduleSimulation, error)
+}
+
+func (s *apiV2) simulateSchedule(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	sim, ok := s.taskManager.(simulatesSchedules)
+	if !ok {
+		Write(501, FromError(ErrSimulationUnsupported), w)
+		return
+	}
+	var req ScheduleSimulationRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	r.Body.Close()
+	if err != nil {
+		Write(400, FromError(err), w)
+		return
+	}
+	if req.TaskID != "" {
+		t, err := s.taskManager.GetTask(req.TaskID)
+		if err != nil {
+			Write(404, FromError(err), w)
+			return
+		}
+		if !api.CanAccessTask(r, t) {
+			Write(403, FromError(fmt.Errorf("task %s belongs to another tenant", req.TaskID)), w)
+			return
+		}
+	}
+	res, err := sim.SimulateSchedule(req.Schedule, req.TaskID)
+	if err != nil {
+		Write(400, FromError(err), w)
+		return
+	}
+	Write(200, res, w)
+}
+
+// RunSimulationResponse returns the outcome of a run simulation.
+//
+// swagger:response RunSimulationResponse
+type RunSimulationResponse struct {
+	// in: body
+	Simulation core.RunSimulation
+}
+
+// RunSimulationParams defines the tasks to simulate and the time range to
+// simulate them over.
+//
+// swagger:parameters simulateRun
+type RunSimulationParams struct {
+	// in: body
+	//
+	// required: true
+	Request core.RunSimulationRequest
+}
+
+// simulatesRuns is implemented by a task manager which simulates the
+// schedules of several tasks together.
+type simulatesRuns interface {
+	SimulateRun(*core.RunSimulationRequest) (*core.RunSimulation, error)
+}
+
+func (s *apiV2) simulateRun(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	sim, ok := s.taskManager.(simulatesRuns)
+	if !ok {
+		Write(501, FromError(ErrSimulationUnsupported), w)
+		return
+	}
+	var req core.RunSimulationRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	r.Body.Close()
+	if err != nil {
+		Write(400, FromError(err), w)
+		return
+	}
+	for _, t := range req.Tasks {
+		if t.TaskID != "" {
+			tk, err := s.taskManager.GetTask(t.TaskID)
+			if err != nil {
+				Write(404, FromError(err), w)
+				return
+			}
+			if !api.CanAccessTask(r, tk) {
+				Write(403, FromError(fmt.Errorf("task %s belongs to another tenant", t.TaskID)), w)
+				return
+			}
+		}
+	}
+	res, err := sim.SimulateRun(&req)
+	if err != nil {
+		Write(400, FromError(err), w)
+		return
+	}
+	Write(200, res, w)
+}
@@ -0,0 +1,124 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"net/http"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/snapshot"
+	"github.com/julienschmidt/httprouter"
+)
+
+// SnapshotsResponse returns a list of configuration snapshots.
+//
+// swagger:response SnapshotsResponse
+type SnapshotsResponse struct {
+	// in: body
+	Body Snapshots
+}
+
+// Snapshots represents a list of configuration snapshots.
+type Snapshots struct {
+	Snapshots []snapshot.Info `json:"snapshots"`
+}
+
+// SnapshotResponse returns a configuration snapshot.
+//
+// swagger:response SnapshotResponse
+type SnapshotResponse struct {
+	// in: body
+	Snapshot snapshot.Snapshot
+}
+
+// SnapshotRestoreResponse returns the changes made by restoring a snapshot.
+//
+// swagger:response SnapshotRestoreResponse
+type SnapshotRestoreResponse struct {
+	// in: body
+	Result snapshot.RestoreResult
+}
+
+// SnapshotParam defines the API path snapshot name.
+//
+// swagger:parameters getSnapshot restoreSnapshot
+type SnapshotParam struct {
+	// in: path
+	// required: true
+	Name string `json:"name"`
+}
+
+func (s *apiV2) getSnapshots(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if s.snapshotManager == nil {
+		Write(404, FromError(ErrSnapshotsDisabled), w)
+		return
+	}
+	infos, err := s.snapshotManager.List()
+	if err != nil {
+		Write(500, FromError(err), w)
+		return
+	}
+	Write(200, Snapshots{Snapshots: infos}, w)
+}
+
+func (s *apiV2) takeSnapshot(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if s.snapshotManager == nil {
+		Write(404, FromError(ErrSnapshotsDisabled), w)
+		return
+	}
+	sn, err := s.snapshotManager.Take()
+	if err != nil {
+		Write(500, FromError(err), w)
+		return
+	}
+	Write(201, sn, w)
+}
+
+func (s *apiV2) getSnapshot(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	if s.snapshotManager == nil {
+		Write(404, FromError(ErrSnapshotsDisabled), w)
+		return
+	}
+	sn, err := s.snapshotManager.Get(p.ByName("name"))
+	if err != nil {
+		Write(snapshotErrorCode(err), FromError(err), w)
+		return
+	}
+	Write(200, sn, w)
+}
+
+func (s *apiV2) restoreSnapshot(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	if s.snapshotManager == nil {
+		Write(404, FromError(ErrSnapshotsDisabled), w)
+		return
+	}
+	res, err := s.snapshotManager.Restore(p.ByName("name"))
+	if err != nil {
+		Write(snapshotErrorCode(err), FromError(err), w)
+		return
+	}
+	Write(200, res, w)
+}
+
+func snapshotErrorCode(err error) int {
+	if err == snapshot.ErrSnapshotNotFound {
+		return 404
+	}
+	return 500
+}
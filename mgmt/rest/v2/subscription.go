@@ -0,0 +1,115 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/julienschmidt/httprouter"
+)
+
+// SubscriptionsResponse returns the tasks subscribed to the loaded plugins.
+//
+// swagger:response SubscriptionsResponse
+type SubscriptionsResponse struct {
+	// in: body
+	Body SubscriptionsBody
+}
+
+// SubscriptionsBody lists the subscriptions of the plugins and the number of
+// subscriptions leaked.
+type SubscriptionsBody struct {
+	Subscriptions []PluginSubscription `json:"subscriptions"`
+	// Leaked is the number of subscriptions left behind
+	Leaked int `json:"leaked"`
+}
+
+// PluginSubscription holds the tasks subscribed to a plugin and the ones
+// whose subscription was left behind: the tasks which do not exist or are
+// not running.
+type PluginSubscription struct {
+	core.PluginSubscription
+	LeakedTasks []string `json:"leaked_tasks,omitempty"`
+}
+
+// reportsSubscriptions is implemented by a metric manager which counts the
+// tasks subscribed to its plugins.
+type reportsSubscriptions interface {
+	PluginSubscriptions() []core.PluginSubscription
+}
+
+func (s *apiV2) getSubscriptions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	rs, ok := s.metricManager.(reportsSubscriptions)
+	if !ok {
+		Write(501, FromError(ErrSubscriptionsUnknown), w)
+		return
+	}
+	body := SubscriptionsBody{Subscriptions: []PluginSubscription{}}
+	for _, sub := range rs.PluginSubscriptions() {
+		ps := PluginSubscription{PluginSubscription: sub}
+		for _, id := range sub.Tasks {
+			if !s.holdsSubscriptions(id) {
+				ps.LeakedTasks = append(ps.LeakedTasks, id)
+			}
+		}
+		body.Leaked += len(ps.LeakedTasks)
+		// the pool counts the subscriptions of the tasks without dependencies
+		// on the plugin anymore as well
+		if sub.Subscribers > len(sub.Tasks) {
+			body.Leaked += sub.Subscribers - len(sub.Tasks)
+		}
+		body.Subscriptions = append(body.Subscriptions, ps)
+	}
+	Write(200, body, w)
+}
+
+// holdsSubscriptions returns true when the task exists and is running, so
+// that it is subscribed to its plugins
+func (s *apiV2) holdsSubscriptions(id string) bool {
+	t, err := s.taskManager.GetTask(id)
+	if err != nil {
+		return false
+	}
+	switch t.State() {
+	case core.TaskSpinning, core.TaskFiring, core.TaskStopping, core.TaskSuspended:
+		return true
+	}
+	return false
+}
+
+// subscribers returns the number of tasks subscribed to every plugin by the
+// key of the plugin, nil when the metric manager does not count them
+func (s *apiV2) subscribers() map[string]int {
+	rs, ok := s.metricManager.(reportsSubscriptions)
+	if !ok {
+		return nil
+	}
+	subscribers := map[string]int{}
+	for _, sub := range rs.PluginSubscriptions() {
+		subscribers[subscriptionKey(sub.Type, sub.Name, sub.Version)] = len(sub.Tasks)
+	}
+	return subscribers
+}
+
+func subscriptionKey(typeName, name string, version int) string {
+	return fmt.Sprintf("%s"+core.Separator+"%s"+core.Separator+"%d", typeName, name, version)
+}
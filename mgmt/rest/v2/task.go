@@ -21,13 +21,17 @@ package v2
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest/admission"
+	"github.com/intelsdi-x/snap/mgmt/rest/api"
 	"github.com/intelsdi-x/snap/pkg/schedule"
 	"github.com/intelsdi-x/snap/scheduler/wmap"
 	"github.com/julienschmidt/httprouter"
@@ -39,7 +43,8 @@ import (
 type TasksResp struct {
 	// in: body
 	Body struct {
-		Tasks Tasks `json:"tasks"`
+		Tasks Tasks  `json:"tasks"`
+		Next  string `json:"next,omitempty"`
 	}
 }
 
@@ -51,6 +56,14 @@ type TaskResp struct {
 	Task Task `json:"task"`
 }
 
+// TaskHistoryResponse returns the state transitions of a task.
+//
+// swagger:response TaskHistoryResponse
+type TaskHistoryResp struct {
+	// in: body
+	History TaskHistory `json:"history"`
+}
+
 // TaskErrorResponse returns removing a task error.
 //
 // swagger:response TaskErrorResponse
@@ -61,17 +74,42 @@ type RemoveTaskError struct {
 
 type TasksResponse struct {
 	Tasks Tasks `json:"tasks"`
+	// Next is the id to list the next page of tasks after, empty on the
+	// last page
+	Next string `json:"next,omitempty"`
 }
 
 // TaskParam defines the API path task id.
 //
-// swagger:parameters getTask watchTask updateTaskState removeTask
+// swagger:parameters getTask watchTask updateTaskState removeTask exportTask getTaskHistory startBackfill getBackfill cancelBackfill
 type TaskParam struct {
 	// in: path
 	// required: true
 	ID string `json:"id"`
 }
 
+// TaskListParams select and paginate the listed tasks.
+//
+// swagger:parameters getTasks
+type TaskListParams struct {
+	// Name of the tasks
+	//
+	// in: query
+	Name string `json:"name"`
+	// Labels of the tasks, formatted as key=value
+	//
+	// in: query
+	Label []string `json:"label"`
+	// Maximum number of tasks listed
+	//
+	// in: query
+	Limit int `json:"limit"`
+	// Id of the task to list the tasks after
+	//
+	// in: query
+	After string `json:"after"`
+}
+
 // TaskPostParams defines task POST and PUT string representation content.
 //
 // swagger:parameters addTask
@@ -84,6 +122,16 @@ type TaskPostParams struct {
 	Task Task `json:"task"yaml:"task"`
 }
 
+// TaskExportParams defines the format of an exported task manifest.
+//
+// swagger:parameters exportTask
+type TaskExportParams struct {
+	// Format of the manifest, json (default) or yaml
+	//
+	// in: query
+	Format string `json:"format"`
+}
+
 // TaskPutParams defines a task state
 //
 // swagger:parameters updateTaskState
@@ -98,26 +146,55 @@ type TaskPutParams struct {
 
 // Task represents Snap task definition.
 type Task struct {
-	ID                 string            `json:"id,omitempty"`
-	Name               string            `json:"name,omitempty"`
-	Version            int               `json:"version,omitempty"`
-	Deadline           string            `json:"deadline,omitempty"`
-	Workflow           *wmap.WorkflowMap `json:"workflow,omitempty"`
-	Schedule           *core.Schedule    `json:"schedule,omitempty"`
-	CreationTimestamp  int64             `json:"creation_timestamp,omitempty"`
-	LastRunTimestamp   int64             `json:"last_run_timestamp,omitempty"`
-	HitCount           int               `json:"hit_count,omitempty"`
-	MissCount          int               `json:"miss_count,omitempty"`
-	FailedCount        int               `json:"failed_count,omitempty"`
-	LastFailureMessage string            `json:"last_failure_message,omitempty"`
-	TaskState          string            `json:"task_state,omitempty"`
-	Href               string            `json:"href,omitempty"`
-	Start              bool              `json:"start,omitempty"`
-	MaxFailures        int               `json:"max-failures,omitempty"`
+	ID                 string                     `json:"id,omitempty"`
+	Name               string                     `json:"name,omitempty"`
+	Version            int                        `json:"version,omitempty"`
+	Deadline           string                     `json:"deadline,omitempty"`
+	Workflow           *wmap.WorkflowMap          `json:"workflow,omitempty"`
+	Schedule           *core.Schedule             `json:"schedule,omitempty"`
+	CreationTimestamp  int64                      `json:"creation_timestamp,omitempty"`
+	LastRunTimestamp   int64                      `json:"last_run_timestamp,omitempty"`
+	HitCount           int                        `json:"hit_count,omitempty"`
+	MissCount          int                        `json:"miss_count,omitempty"`
+	BlackoutCount      int                        `json:"blackout_count,omitempty"`
+	FailedCount        int                        `json:"failed_count,omitempty"`
+	LastFailureMessage string                     `json:"last_failure_message,omitempty"`
+	TaskState          string                     `json:"task_state,omitempty"`
+	Href               string                     `json:"href,omitempty"`
+	Start              bool                       `json:"start,omitempty"`
+	MaxFailures        int                        `json:"max-failures,omitempty"`
+	Singleton          bool                       `json:"singleton,omitempty"`
+	Queue              *core.TaskQueue            `json:"queue,omitempty"`
+	PinnedThread       *core.TaskPinnedThread     `json:"pinned-thread,omitempty"`
+	PublishRateLimit   *core.TaskPublishRateLimit `json:"publish-rate-limit,omitempty"`
+	CardinalityLimit   *core.TaskCardinalityLimit `json:"cardinality-limit,omitempty"`
+	// Blackouts are the periods the fires of the task are suppressed in
+	Blackouts []core.BlackoutPeriod `json:"blackouts,omitempty"`
+	// Owner created the task; its tenant sees and changes it
+	Owner *core.TaskOwner `json:"owner,omitempty"`
+	// Labels select the task in the listings of the tasks
+	Labels map[string]string `json:"labels,omitempty"`
+	// ResourceUsage holds the approximate resources used by the runs of the task
+	ResourceUsage *core.TaskResourceUsage `json:"resource_usage,omitempty"`
+	// Delivery counts the deliveries of the metrics of the task to its publishers
+	Delivery *core.TaskDeliveryStats `json:"delivery,omitempty"`
 }
 
 type Tasks []Task
 
+// TaskHistory is the timeline of the state transitions of a task, oldest first.
+type TaskHistory struct {
+	ID          string                `json:"id"`
+	Transitions []TaskStateTransition `json:"transitions"`
+}
+
+// TaskStateTransition tells when and why a task entered a state.
+type TaskStateTransition struct {
+	Timestamp int64  `json:"timestamp"`
+	TaskState string `json:"task_state"`
+	Reason    string `json:"reason"`
+}
+
 func (s Tasks) Len() int {
 	return len(s)
 }
@@ -135,33 +212,146 @@ func (s *Task) CreationTime() time.Time {
 }
 
 func (s *apiV2) addTask(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	task, err := core.CreateTaskFromContent(r.Body, nil, s.taskManager.CreateTask)
+	task, err := core.CreateTaskFromContent(r.Body, nil, api.OwnedTaskCreator(r, s.taskManager.CreateTask), s.taskAdmitters...)
+	if err != nil {
+		Write(createTaskErrorCode(err), FromError(err), w)
+		return
+	}
+	taskB := AddSchedulerTaskFromTask(task)
+	taskB.Href = taskURI(r.Host, task)
+	Write(201, taskB, w)
+}
+
+func (s *apiV2) exportTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	t, err := s.taskManager.GetTask(id)
+	if err != nil {
+		Write(404, FromError(err), w)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "json":
+		manifest, err := core.TaskManifest(t)
+		if err != nil {
+			Write(500, FromError(err), w)
+			return
+		}
+		Write(200, manifest, w)
+	case "yaml", "yml":
+		b, err := core.ExportTask(t, format)
+		if err != nil {
+			Write(500, FromError(err), w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-yaml; version=2; charset=utf-8")
+		w.Header().Set("Version", "beta")
+		w.WriteHeader(200)
+		w.Write(b)
+	default:
+		Write(400, FromError(core.ErrUnknownManifestFormat), w)
+	}
+}
+
+func (s *apiV2) importTask(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	content, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
 	if err != nil {
 		Write(500, FromError(err), w)
 		return
 	}
+	task, err := core.ImportTask(content, nil, api.OwnedTaskCreator(r, s.taskManager.CreateTask), s.taskAdmitters...)
+	if err != nil {
+		Write(createTaskErrorCode(err), FromError(err), w)
+		return
+	}
 	taskB := AddSchedulerTaskFromTask(task)
 	taskB.Href = taskURI(r.Host, task)
 	Write(201, taskB, w)
 }
 
+// listsTasks is implemented by a task manager which indexes its tasks by
+// name and label and lists them by pages.
+type listsTasks interface {
+	ListTasks(core.TaskListOptions) ([]core.Task, string)
+}
+
 func (s *apiV2) getTasks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	q := r.URL.Query()
+	if len(q) > 0 {
+		s.listTasks(w, r)
+		return
+	}
+
 	// get tasks from the task manager
 	sts := s.taskManager.GetTasks()
 
-	// create the task list response
-	tasks := make(Tasks, len(sts))
-	i := 0
+	// create the task list response, the callers only list the tasks of
+	// their tenant
+	tasks := make(Tasks, 0, len(sts))
 	for _, t := range sts {
-		tasks[i] = SchedulerTaskFromTask(t)
-		tasks[i].Href = taskURI(r.Host, t)
-		i++
+		if !api.CanAccessTask(r, t) {
+			continue
+		}
+		task := SchedulerTaskFromTask(t)
+		task.Href = taskURI(r.Host, t)
+		tasks = append(tasks, task)
 	}
 	sort.Sort(tasks)
 
 	Write(200, TasksResponse{Tasks: tasks}, w)
 }
 
+// listTasks lists the tasks selected by name and label, in the order of
+// their ids, a page at a time
+func (s *apiV2) listTasks(w http.ResponseWriter, r *http.Request) {
+	lt, ok := s.taskManager.(listsTasks)
+	if !ok {
+		Write(501, FromError(ErrTaskListUnsupported), w)
+		return
+	}
+	q := r.URL.Query()
+	opts := core.TaskListOptions{
+		Name:  q.Get("name"),
+		After: q.Get("after"),
+	}
+	for _, selector := range q["label"] {
+		k, v, err := core.ParseTaskLabel(selector)
+		if err != nil {
+			Write(400, FromError(err), w)
+			return
+		}
+		if opts.Labels == nil {
+			opts.Labels = map[string]string{}
+		}
+		opts.Labels[k] = v
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			Write(400, FromError(fmt.Errorf("invalid limit: %s", v)), w)
+			return
+		}
+		opts.Limit = limit
+	}
+	// the callers only list the tasks of their tenant unless they are
+	// admins
+	if !api.CanAccessAllTasks(r) {
+		opts.Visible = func(t core.Task) bool {
+			return api.CanAccessTask(r, t)
+		}
+	}
+	sts, next := lt.ListTasks(opts)
+
+	tasks := make(Tasks, 0, len(sts))
+	for _, t := range sts {
+		task := SchedulerTaskFromTask(t)
+		task.Href = taskURI(r.Host, t)
+		tasks = append(tasks, task)
+	}
+	Write(200, TasksResponse{Tasks: tasks, Next: next}, w)
+}
+
 func (s *apiV2) getTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	id := p.ByName("id")
 	t, err := s.taskManager.GetTask(id)
@@ -174,6 +364,27 @@ func (s *apiV2) getTask(w http.ResponseWriter, r *http.Request, p httprouter.Par
 	Write(200, task, w)
 }
 
+func (s *apiV2) getTaskHistory(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	t, err := s.taskManager.GetTask(id)
+	if err != nil {
+		Write(404, FromError(err), w)
+		return
+	}
+	history := TaskHistory{
+		ID:          t.ID(),
+		Transitions: []TaskStateTransition{},
+	}
+	for _, st := range t.StateHistory() {
+		history.Transitions = append(history.Transitions, TaskStateTransition{
+			Timestamp: st.Time.Unix(),
+			TaskState: st.State.String(),
+			Reason:    st.Reason,
+		})
+	}
+	Write(200, history, w)
+}
+
 func (s *apiV2) updateTaskState(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	errs := make([]serror.SnapError, 0, 1)
 	id := p.ByName("id")
@@ -228,10 +439,38 @@ func (s *apiV2) removeTask(w http.ResponseWriter, r *http.Request, p httprouter.
 	Write(204, nil, w)
 }
 
+// createTaskErrorCode returns the status code of a failed task creation
+func createTaskErrorCode(err error) int {
+	if _, ok := err.(*admission.RejectedError); ok {
+		return 403
+	}
+	if strings.Contains(err.Error(), ErrQuotaExceeded) {
+		return 403
+	}
+	for _, e := range []error{core.ErrInvalidTaskManifest, core.ErrUnknownTaskTemplate, core.ErrUnknownTemplateVariable, core.ErrMissingTemplateVariable} {
+		if strings.Contains(err.Error(), e.Error()) {
+			return 400
+		}
+	}
+	return 500
+}
+
 func taskURI(host string, t core.Task) string {
 	return fmt.Sprintf("%s://%s/%s/tasks/%s", protocolPrefix, host, version, t.ID())
 }
 
+// accountsResources is implemented by the tasks accounting for the resources
+// their runs use.
+type accountsResources interface {
+	ResourceUsage() core.TaskResourceUsage
+}
+
+// countsDeliveries is implemented by the tasks counting the deliveries of
+// their metrics to their publishers.
+type countsDeliveries interface {
+	DeliveryStats() core.TaskDeliveryStats
+}
+
 // functions to convert a core.Task to a Task
 func AddSchedulerTaskFromTask(t core.Task) Task {
 	st := SchedulerTaskFromTask(t)
@@ -249,9 +488,28 @@ func SchedulerTaskFromTask(t core.Task) Task {
 		LastRunTimestamp:   t.LastRunTime().Unix(),
 		HitCount:           int(t.HitCount()),
 		MissCount:          int(t.MissedCount()),
+		BlackoutCount:      int(t.BlackedOutCount()),
 		FailedCount:        int(t.FailedCount()),
 		LastFailureMessage: t.LastFailureMessage(),
 		TaskState:          t.State().String(),
+		Singleton:          t.IsSingleton(),
+	}
+	if name, size := t.GetDedicatedQueue(); name != "" {
+		st.Queue = &core.TaskQueue{Name: name, Size: size}
+	}
+	st.PinnedThread = t.GetPinnedThread()
+	st.PublishRateLimit = t.GetPublishRateLimit()
+	st.CardinalityLimit = t.GetCardinalityLimit()
+	st.Blackouts = t.GetBlackouts()
+	st.Owner = t.GetOwner()
+	st.Labels = t.GetLabels()
+	if u, ok := t.(accountsResources); ok {
+		usage := u.ResourceUsage()
+		st.ResourceUsage = &usage
+	}
+	if d, ok := t.(countsDeliveries); ok {
+		delivery := d.DeliveryStats()
+		st.Delivery = &delivery
 	}
 	if st.LastRunTimestamp < 0 {
 		st.LastRunTimestamp = -1
@@ -267,6 +525,7 @@ func (t *Task) assertSchedule(s schedule.Schedule) {
 			Interval:       v.Interval.String(),
 			StartTimestamp: v.StartTime,
 			StopTimestamp:  v.StopTime,
+			Aligned:        v.Aligned,
 		}
 		return
 	case *schedule.CronSchedule:
@@ -275,5 +534,8 @@ func (t *Task) assertSchedule(s schedule.Schedule) {
 			Interval: v.Entry(),
 		}
 		return
+	case *schedule.CompositeSchedule:
+		t.Schedule, _ = core.ScheduleFromSchedule(v)
+		return
 	}
 }
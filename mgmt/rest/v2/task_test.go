@@ -0,0 +1,54 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/urfave/negroni"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/v2/mock"
+)
+
+func TestImportTask(t *testing.T) {
+	Convey("Given a REST API importing the task manifests", t, func() {
+		s := New(&sync.WaitGroup{}, make(chan struct{}), "http")
+		s.BindTaskManager(&mock.MockTaskManager{})
+		importTask := func(manifest string) int {
+			req := httptest.NewRequest("POST", "/v2/tasks/import", strings.NewReader(manifest))
+			rec := httptest.NewRecorder()
+			s.importTask(negroni.NewResponseWriter(rec), req, nil)
+			return rec.Code
+		}
+
+		Convey("a manifest which is not YAML is a bad request", func() {
+			So(importTask("version: 1\n\tname: [broken"), ShouldEqual, 400)
+		})
+		Convey("a manifest with fields of the wrong type is a bad request", func() {
+			So(importTask(`{"version": 1, "deadline": 5}`), ShouldEqual, 400)
+		})
+	})
+}
@@ -0,0 +1,179 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/api"
+)
+
+// TemplatesResponse returns the task templates.
+//
+// swagger:response TemplatesResponse
+type TemplatesResponse struct {
+	// in: body
+	Body Templates
+}
+
+// Templates is a list of task templates sorted by name.
+type Templates struct {
+	Templates []*core.TaskTemplate `json:"templates"`
+}
+
+// TemplateResponse returns a task template.
+//
+// swagger:response TemplateResponse
+type TemplateResponse struct {
+	// in: body
+	Template core.TaskTemplate
+}
+
+// TemplateParam defines the API path template name.
+//
+// swagger:parameters getTemplate removeTemplate addTemplateTask
+type TemplateParam struct {
+	// in: path
+	// required: true
+	Name string `json:"name"`
+}
+
+// TemplatePostParams defines the template to register.
+//
+// swagger:parameters addTemplate
+type TemplatePostParams struct {
+	// in: body
+	//
+	// required: true
+	Template core.TaskTemplate `json:"template"`
+}
+
+// managesTemplates is implemented by a task manager which creates tasks from
+// task templates.
+type managesTemplates interface {
+	AddTaskTemplate(*core.TaskTemplate) error
+	RemoveTaskTemplate(string) error
+	GetTaskTemplate(string) (*core.TaskTemplate, error)
+	GetTaskTemplates() []*core.TaskTemplate
+}
+
+// templateAdmitter sets the template the requests are to create a task from
+type templateAdmitter string
+
+func (a templateAdmitter) AdmitTask(tr *core.TaskCreationRequest) error {
+	tr.Template = string(a)
+	return nil
+}
+
+func (s *apiV2) getTemplates(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	mt, ok := s.taskManager.(managesTemplates)
+	if !ok {
+		Write(501, FromError(ErrTemplatesUnsupported), w)
+		return
+	}
+	Write(200, Templates{Templates: mt.GetTaskTemplates()}, w)
+}
+
+func (s *apiV2) addTemplate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	mt, ok := s.taskManager.(managesTemplates)
+	if !ok {
+		Write(501, FromError(ErrTemplatesUnsupported), w)
+		return
+	}
+	t := &core.TaskTemplate{}
+	err := json.NewDecoder(r.Body).Decode(t)
+	r.Body.Close()
+	if err != nil {
+		Write(400, FromError(err), w)
+		return
+	}
+	if err := t.Validate(); err != nil {
+		Write(400, FromError(err), w)
+		return
+	}
+	if err := mt.AddTaskTemplate(t); err != nil {
+		if strings.Contains(err.Error(), core.ErrTaskTemplateExists.Error()) {
+			Write(409, FromError(err), w)
+			return
+		}
+		Write(500, FromError(err), w)
+		return
+	}
+	Write(201, t, w)
+}
+
+func (s *apiV2) getTemplate(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	mt, ok := s.taskManager.(managesTemplates)
+	if !ok {
+		Write(501, FromError(ErrTemplatesUnsupported), w)
+		return
+	}
+	t, err := mt.GetTaskTemplate(p.ByName("name"))
+	if err != nil {
+		Write(404, FromError(err), w)
+		return
+	}
+	Write(200, t, w)
+}
+
+func (s *apiV2) removeTemplate(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	mt, ok := s.taskManager.(managesTemplates)
+	if !ok {
+		Write(501, FromError(ErrTemplatesUnsupported), w)
+		return
+	}
+	name := p.ByName("name")
+	if _, err := mt.GetTaskTemplate(name); err != nil {
+		Write(404, FromError(err), w)
+		return
+	}
+	if err := mt.RemoveTaskTemplate(name); err != nil {
+		Write(500, FromError(err), w)
+		return
+	}
+	Write(204, nil, w)
+}
+
+func (s *apiV2) addTemplateTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	mt, ok := s.taskManager.(managesTemplates)
+	if !ok {
+		Write(501, FromError(ErrTemplatesUnsupported), w)
+		return
+	}
+	name := p.ByName("name")
+	if _, err := mt.GetTaskTemplate(name); err != nil {
+		Write(404, FromError(err), w)
+		return
+	}
+	admitters := append([]core.TaskAdmitter{templateAdmitter(name)}, s.taskAdmitters...)
+	task, err := core.CreateTaskFromContent(r.Body, nil, api.OwnedTaskCreator(r, s.taskManager.CreateTask), admitters...)
+	if err != nil {
+		Write(createTaskErrorCode(err), FromError(err), w)
+		return
+	}
+	taskB := AddSchedulerTaskFromTask(task)
+	taskB.Href = taskURI(r.Host, task)
+	Write(201, taskB, w)
+}
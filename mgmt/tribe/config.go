@@ -40,6 +40,7 @@ const (
 	defaultRestAPIPassword           string        = ""
 	defaultRestAPIPort               int           = 8181
 	defaultRestAPIInsecureSkipVerify string        = "true"
+	defaultExclusiveTasks            bool          = false
 )
 
 // holds the configuration passed in through the SNAP config file
@@ -52,6 +53,7 @@ type Config struct {
 	BindAddr                  string             `json:"bind_addr"yaml:"bind_addr"`
 	BindPort                  int                `json:"bind_port"yaml:"bind_port"`
 	Seed                      string             `json:"seed"yaml:"seed"`
+	ExclusiveTasks            bool               `json:"exclusive_tasks"yaml:"exclusive_tasks"`
 	MemberlistConfig          *memberlist.Config `json:"-"yaml:"-"`
 	RestAPIProto              string             `json:"-"yaml:"-"`
 	RestAPIPassword           string             `json:"-"yaml:"-"`
@@ -80,6 +82,9 @@ const (
 					},
 					"seed": {
 						"type" : "string"
+					},
+					"exclusive_tasks": {
+						"type": "boolean"
 					}
 				},
 				"additionalProperties": false
@@ -98,6 +103,7 @@ func GetDefaultConfig() *Config {
 		BindAddr:                  netutil.GetIP(),
 		BindPort:                  defaultBindPort,
 		Seed:                      defaultSeed,
+		ExclusiveTasks:            defaultExclusiveTasks,
 		MemberlistConfig:          mlCfg,
 		RestAPIProto:              defaultRestAPIProto,
 		RestAPIPassword:           defaultRestAPIPassword,
@@ -140,6 +146,10 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 			if err := json.Unmarshal(v, &(c.Seed)); err != nil {
 				return fmt.Errorf("%v (while parsing 'tribe::seed')", err)
 			}
+		case "exclusive_tasks":
+			if err := json.Unmarshal(v, &(c.ExclusiveTasks)); err != nil {
+				return fmt.Errorf("%v (while parsing 'tribe::exclusive_tasks')", err)
+			}
 		default:
 			return fmt.Errorf("Unrecognized key '%v' in global config file while parsing 'tribe'", k)
 		}
@@ -81,6 +81,9 @@ func TestTribeConfigJSON(t *testing.T) {
 		Convey("Seed should be 1.1.1.1:16000", func() {
 			So(cfg.Seed, ShouldEqual, "1.1.1.1:16000")
 		})
+		Convey("ExclusiveTasks should be true", func() {
+			So(cfg.ExclusiveTasks, ShouldEqual, true)
+		})
 	})
 
 }
@@ -114,6 +117,9 @@ func TestTribeConfigYaml(t *testing.T) {
 		Convey("Seed should be 1.1.1.1:16000", func() {
 			So(cfg.Seed, ShouldEqual, "1.1.1.1:16000")
 		})
+		Convey("ExclusiveTasks should be true", func() {
+			So(cfg.ExclusiveTasks, ShouldEqual, true)
+		})
 	})
 
 }
@@ -136,6 +142,9 @@ func TestTribeDefaultConfig(t *testing.T) {
 		Convey("Seed should be empty", func() {
 			So(cfg.Seed, ShouldEqual, "")
 		})
+		Convey("ExclusiveTasks should be false", func() {
+			So(cfg.ExclusiveTasks, ShouldEqual, false)
+		})
 		Convey("MemberlistConfig.PushPullInterval should be 300s", func() {
 			So(cfg.MemberlistConfig.PushPullInterval, ShouldEqual, 300*time.Second)
 		})
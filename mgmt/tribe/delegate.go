@@ -112,6 +112,12 @@ func (t *delegate) NotifyMsg(buf []byte) {
 			panic(err)
 		}
 		rebroadcast = t.tribe.handleTaskStateQuery(msg)
+	case claimLeaderMsgType:
+		msg := &leaderMsg{}
+		if err := decodeMessage(buf[1:], msg); err != nil {
+			panic(err)
+		}
+		rebroadcast = t.tribe.handleClaimLeader(msg)
 	case taskStateQueryResponseMsgType:
 		msg := &taskStateQueryResponseMsg{}
 		if err := decodeMessage(buf[1:], msg); err != nil {
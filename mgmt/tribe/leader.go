@@ -0,0 +1,105 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tribe
+
+import (
+	"github.com/pborman/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// Leader returns true when this member leads the singleton task along with
+// the term of the latest leadership claim for the task.  The leader of a task
+// is the member of its agreement chosen by taskOwner.  The elected member
+// claims the task with a gossiped message whose Lamport time is the term of
+// its leadership and fires the task once its claim is the latest one it knows
+// of; a former leader stops firing the task as soon as it learns of a newer
+// claim or of its loss of leadership.  Tasks which are not shared through an
+// agreement are led by this member.
+func (t *tribe) Leader(taskID string) (bool, uint64) {
+	t.mutex.RLock()
+	name, agr := t.taskAgreement(taskID)
+	if agr == nil {
+		t.mutex.RUnlock()
+		return true, 0
+	}
+	local := t.memberlist.LocalNode().Name
+	elected := taskOwner(taskID, agr.Members) == local
+	claim := t.leaderClaims[taskID]
+	t.mutex.RUnlock()
+
+	var term uint64
+	if claim != nil {
+		term = uint64(claim.LTime)
+	}
+	if !elected {
+		return false, term
+	}
+	if claim != nil && claim.MemberName == local {
+		return true, term
+	}
+	// the task is fired once the claim had a chance to reach the other members
+	t.claimLeader(name, taskID)
+	return false, term
+}
+
+func (t *tribe) claimLeader(agreementName, taskID string) {
+	msg := &leaderMsg{
+		LTime:         t.clock.Increment(),
+		UUID:          uuid.New(),
+		TaskID:        taskID,
+		AgreementName: agreementName,
+		MemberName:    t.memberlist.LocalNode().Name,
+		Type:          claimLeaderMsgType,
+	}
+	t.logger.WithFields(log.Fields{
+		"_block":    "claim-leader",
+		"task-id":   taskID,
+		"agreement": agreementName,
+		"term":      msg.LTime,
+	}).Debug("claiming leadership of task")
+	if t.handleClaimLeader(msg) {
+		t.broadcast(claimLeaderMsgType, msg, nil)
+	}
+}
+
+// handleClaimLeader records a leadership claim newer than the one known for
+// the task.  Claims which are not newer are not broadcast again.
+func (t *tribe) handleClaimLeader(msg *leaderMsg) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	// update the clock if newer
+	t.clock.Update(msg.LTime)
+
+	if claim, ok := t.leaderClaims[msg.TaskID]; ok && !newerClaim(msg, claim) {
+		return false
+	}
+	t.leaderClaims[msg.TaskID] = msg
+	return true
+}
+
+// newerClaim returns true if the claim a supersedes the claim b.  Concurrent
+// claims are ordered by member name so all the members agree on the latest.
+func newerClaim(a, b *leaderMsg) bool {
+	if a.LTime != b.LTime {
+		return a.LTime > b.LTime
+	}
+	return a.MemberName > b.MemberName
+}
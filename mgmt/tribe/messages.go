@@ -45,6 +45,7 @@ const (
 	startTaskMsgType
 	getTaskStateMsgType
 	taskStateQueryResponseMsgType
+	claimLeaderMsgType
 )
 
 var msgTypes = []string{
@@ -61,6 +62,7 @@ var msgTypes = []string{
 	"Start task",
 	"Get task state",
 	"Get task state response",
+	"Claim leader",
 }
 
 func (m msgType) String() string {
@@ -164,6 +166,36 @@ func (t *taskMsg) String() string {
 		t.GetType(), t.Agreement(), t.ID(), t.TaskID)
 }
 
+type leaderMsg struct {
+	LTime         LTime
+	UUID          string
+	TaskID        string
+	AgreementName string
+	MemberName    string
+	Type          msgType
+}
+
+func (l *leaderMsg) ID() string {
+	return l.UUID
+}
+
+func (l *leaderMsg) Time() LTime {
+	return l.LTime
+}
+
+func (l *leaderMsg) GetType() msgType {
+	return l.Type
+}
+
+func (l *leaderMsg) Agreement() string {
+	return l.AgreementName
+}
+
+func (l *leaderMsg) String() string {
+	return fmt.Sprintf("msg type='%v' agreementName='%v' uuid='%v' task='%v' member='%v'",
+		l.GetType(), l.Agreement(), l.ID(), l.TaskID, l.MemberName)
+}
+
 type taskStateQueryMsg struct {
 	LTime         LTime
 	UUID          string
@@ -0,0 +1,98 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tribe
+
+import (
+	"hash/fnv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
+)
+
+// placementSource is the source of the scheduler events caused by moving
+// tasks between the members of an agreement
+const placementSource = "placement"
+
+// Owns returns true when the task has to run on this member.  When exclusive
+// tasks are enabled a task shared through an agreement runs on a single member
+// of the agreement chosen with rendezvous hashing, so that only the tasks of a
+// member leaving the agreement are moved to the remaining members.
+func (t *tribe) Owns(taskID string) bool {
+	if !t.config.ExclusiveTasks {
+		return true
+	}
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	_, agr := t.taskAgreement(taskID)
+	if agr == nil {
+		return true
+	}
+	return taskOwner(taskID, agr.Members) == t.memberlist.LocalNode().Name
+}
+
+// taskAgreement returns the agreement through which this member shares the
+// task or nil when the task is not shared.  The tribe must be locked.
+func (t *tribe) taskAgreement(taskID string) (string, *agreement.Agreement) {
+	m, ok := t.members[t.memberlist.LocalNode().Name]
+	if !ok {
+		return "", nil
+	}
+	task := agreement.Task{ID: taskID}
+	for name, a := range m.TaskAgreements {
+		if ok, _ := a.Tasks.Contains(task); !ok {
+			continue
+		}
+		if agr, ok := t.agreements[name]; ok {
+			return name, agr
+		}
+	}
+	return "", nil
+}
+
+// taskOwner returns the name of the member running the task
+func taskOwner(taskID string, members map[string]*agreement.Member) string {
+	var owner string
+	var max uint64
+	for name := range members {
+		h := fnv.New64a()
+		h.Write([]byte(taskID))
+		h.Write([]byte(name))
+		score := h.Sum64()
+		if owner == "" || score > max || (score == max && name < owner) {
+			owner = name
+			max = score
+		}
+	}
+	return owner
+}
+
+// rebalanceTasks asks the task manager to move the tasks whose owner changed
+// after the membership of an agreement or its tasks changed.  It is called
+// with the tribe mutex held so the rebalance runs in its own goroutine.
+func (t *tribe) rebalanceTasks() {
+	if !t.config.ExclusiveTasks || t.taskManager == nil {
+		return
+	}
+	t.logger.WithFields(log.Fields{
+		"_block": "rebalance-tasks",
+	}).Debug("rebalancing tasks")
+	go t.taskManager.Rebalance()
+}
@@ -77,6 +77,7 @@ type tribe struct {
 	logger             *log.Entry
 	taskStartStopCache *cache
 	taskStateResponses map[string]*taskStateQueryResponse
+	leaderClaims       map[string]*leaderMsg
 	members            map[string]*agreement.Member
 	tags               map[string]string
 	EventManager       *gomit.EventController
@@ -105,6 +106,7 @@ func New(cfg *Config) (*tribe, error) {
 		agreements:         map[string]*agreement.Agreement{},
 		members:            map[string]*agreement.Member{},
 		taskStateResponses: map[string]*taskStateQueryResponse{},
+		leaderClaims:       map[string]*leaderMsg{},
 		taskStartStopCache: newCache(),
 		msgBuffer:          make([]msg, 512),
 		intentBuffer:       []msg{},
@@ -370,7 +372,7 @@ func (t *tribe) HandleGomitEvent(e gomit.Event) {
 			}
 		}
 	case *scheduler_event.TaskStoppedEvent:
-		if v.Source != "tribe" {
+		if v.Source != "tribe" && v.Source != placementSource {
 			logger.WithFields(log.Fields{
 				"event":   e.Namespace(),
 				"task-id": v.TaskID,
@@ -389,7 +391,7 @@ func (t *tribe) HandleGomitEvent(e gomit.Event) {
 			}
 		}
 	case *scheduler_event.TaskStartedEvent:
-		if v.Source != "tribe" {
+		if v.Source != "tribe" && v.Source != placementSource {
 			logger.WithFields(log.Fields{
 				"event":   e.Namespace(),
 				"task-id": v.TaskID,
@@ -953,6 +955,7 @@ func (t *tribe) handleAddTask(msg *taskMsg) bool {
 			t.taskWorkQueue <- work
 
 			t.processIntents()
+			t.rebalanceTasks()
 			return true
 		}
 	}
@@ -976,6 +979,7 @@ func (t *tribe) handleRemoveTask(msg *taskMsg) bool {
 
 	if _, ok := t.agreements[msg.Agreement()]; ok {
 		if t.agreements[msg.AgreementName].TaskAgreement.Remove(agreement.Task{ID: msg.TaskID}) {
+			delete(t.leaderClaims, msg.TaskID)
 
 			work := worker.TaskRequest{
 				Task: worker.Task{
@@ -986,6 +990,7 @@ func (t *tribe) handleRemoveTask(msg *taskMsg) bool {
 			t.taskWorkQueue <- work
 
 			t.processIntents()
+			t.rebalanceTasks()
 			return true
 		}
 	}
@@ -1084,6 +1089,7 @@ func (t *tribe) handleMemberLeave(n *memberlist.Node) {
 			delete(t.agreements[k].Members, n.Name)
 		}
 		delete(t.members, n.Name)
+		t.rebalanceTasks()
 	}
 }
 
@@ -1298,6 +1304,7 @@ func (t *tribe) joinAgreement(msg *agreementMsg) serror.SnapError {
 
 	// update the agreements membership
 	t.agreements[msg.Agreement()].Members[msg.MemberName] = t.members[msg.MemberName]
+	t.rebalanceTasks()
 
 	// get plugins and tasks if this is the node joining
 	if msg.MemberName == t.memberlist.LocalNode().Name {
@@ -1318,7 +1325,7 @@ func (t *tribe) joinAgreement(msg *agreementMsg) serror.SnapError {
 			for _, tsk := range a.TaskAgreement.Tasks {
 				state := t.TaskStateQuery(msg.Agreement(), tsk.ID)
 				startOnCreate := false
-				if state == core.TaskSpinning || state == core.TaskFiring {
+				if state == core.TaskSpinning || state == core.TaskFiring || state == core.TaskSuspended {
 					startOnCreate = true
 				}
 				work := worker.TaskRequest{
@@ -1345,6 +1352,7 @@ func (t *tribe) leaveAgreement(msg *agreementMsg) serror.SnapError {
 	if _, ok := t.members[msg.MemberName].TaskAgreements[msg.Agreement()]; ok {
 		delete(t.members[msg.MemberName].TaskAgreements, msg.Agreement())
 	}
+	t.rebalanceTasks()
 
 	return nil
 }
@@ -50,33 +50,58 @@ func (m *mockTaskManager) CreateTaskTribe(sch schedule.Schedule, wfMap *wmap.Wor
 func (m *mockTaskManager) StopTaskTribe(id string) []serror.SnapError  { return nil }
 func (m *mockTaskManager) StartTaskTribe(id string) []serror.SnapError { return nil }
 func (m *mockTaskManager) RemoveTaskTribe(id string) error             { return nil }
+func (m *mockTaskManager) Rebalance()                                  {}
 
 type mockTask struct{}
 
-func (t *mockTask) ID() string                                { return "" }
-func (t *mockTask) State() core.TaskState                     { return core.TaskSpinning }
-func (t *mockTask) HitCount() uint                            { return 0 }
-func (t *mockTask) GetName() string                           { return "" }
-func (t *mockTask) SetName(string)                            { return }
-func (t *mockTask) SetID(string)                              { return }
-func (t *mockTask) MissedCount() uint                         { return 0 }
-func (t *mockTask) FailedCount() uint                         { return 0 }
-func (t *mockTask) LastFailureMessage() string                { return "" }
-func (t *mockTask) LastRunTime() *time.Time                   { return nil }
-func (t *mockTask) CreationTime() *time.Time                  { return nil }
-func (t *mockTask) DeadlineDuration() time.Duration           { return 0 }
-func (t *mockTask) SetDeadlineDuration(time.Duration)         { return }
-func (t *mockTask) SetTaskID(id string)                       { return }
-func (t *mockTask) SetStopOnFailure(int)                      { return }
-func (t *mockTask) GetStopOnFailure() int                     { return 0 }
-func (t *mockTask) Option(...core.TaskOption) core.TaskOption { return core.TaskDeadlineDuration(0) }
-func (t *mockTask) WMap() *wmap.WorkflowMap                   { return nil }
-func (t *mockTask) Schedule() schedule.Schedule               { return nil }
-func (t *mockTask) MaxFailures() int                          { return 10 }
-func (t *mockTask) MaxMetricsBuffer() int64                   { return 0 }
-func (t *mockTask) SetMaxMetricsBuffer(int64)                 {}
-func (t *mockTask) MaxCollectDuration() time.Duration         { return time.Second }
-func (t *mockTask) SetMaxCollectDuration(time.Duration)       {}
+func (t *mockTask) ID() string                                      { return "" }
+func (t *mockTask) State() core.TaskState                           { return core.TaskSpinning }
+func (t *mockTask) HitCount() uint                                  { return 0 }
+func (t *mockTask) GetName() string                                 { return "" }
+func (t *mockTask) SetName(string)                                  { return }
+func (t *mockTask) SetID(string)                                    { return }
+func (t *mockTask) MissedCount() uint                               { return 0 }
+func (t *mockTask) FailedCount() uint                               { return 0 }
+func (t *mockTask) LastFailureMessage() string                      { return "" }
+func (t *mockTask) LastRunTime() *time.Time                         { return nil }
+func (t *mockTask) CreationTime() *time.Time                        { return nil }
+func (t *mockTask) DeadlineDuration() time.Duration                 { return 0 }
+func (t *mockTask) SetDeadlineDuration(time.Duration)               { return }
+func (t *mockTask) SetTaskID(id string)                             { return }
+func (t *mockTask) SetStopOnFailure(int)                            { return }
+func (t *mockTask) GetStopOnFailure() int                           { return 0 }
+func (t *mockTask) Option(...core.TaskOption) core.TaskOption       { return core.TaskDeadlineDuration(0) }
+func (t *mockTask) WMap() *wmap.WorkflowMap                         { return nil }
+func (t *mockTask) Schedule() schedule.Schedule                     { return nil }
+func (t *mockTask) MaxFailures() int                                { return 10 }
+func (t *mockTask) MaxMetricsBuffer() int64                         { return 0 }
+func (t *mockTask) SetMaxMetricsBuffer(int64)                       {}
+func (t *mockTask) MaxCollectDuration() time.Duration               { return time.Second }
+func (t *mockTask) SetMaxCollectDuration(time.Duration)             {}
+func (t *mockTask) SetFailurePolicy(core.FailurePolicy)             {}
+func (t *mockTask) GetFailurePolicy() core.FailurePolicy            { return core.FailurePolicyDisable }
+func (t *mockTask) SetFailureCooldown(time.Duration)                {}
+func (t *mockTask) GetFailureCooldown() time.Duration               { return 0 }
+func (t *mockTask) SetSingleton(bool)                               { return }
+func (t *mockTask) IsSingleton() bool                               { return false }
+func (t *mockTask) SetDedicatedQueue(string, int)                   { return }
+func (t *mockTask) GetDedicatedQueue() (string, int)                { return "", 0 }
+func (t *mockTask) SetPinnedThread(*core.TaskPinnedThread)          { return }
+func (t *mockTask) GetPinnedThread() *core.TaskPinnedThread         { return nil }
+func (t *mockTask) SetPublishRateLimit(*core.TaskPublishRateLimit)  { return }
+func (t *mockTask) GetPublishRateLimit() *core.TaskPublishRateLimit { return nil }
+func (t *mockTask) SetCardinalityLimit(*core.TaskCardinalityLimit)  { return }
+func (t *mockTask) GetCardinalityLimit() *core.TaskCardinalityLimit { return nil }
+func (t *mockTask) SetBlackouts([]core.BlackoutPeriod)              {}
+func (t *mockTask) GetBlackouts() []core.BlackoutPeriod             { return nil }
+func (t *mockTask) BlackedOutCount() uint                           { return 0 }
+func (t *mockTask) SetOwner(*core.TaskOwner)                        { return }
+func (t *mockTask) GetOwner() *core.TaskOwner                       { return nil }
+func (t *mockTask) SetTimeouts(core.TaskTimeouts)                   {}
+func (t *mockTask) GetTimeouts() core.TaskTimeouts                  { return core.TaskTimeouts{} }
+func (t *mockTask) SetLabels(map[string]string)                     {}
+func (t *mockTask) GetLabels() map[string]string                    { return nil }
+func (t *mockTask) StateHistory() []core.TaskStateTransition        { return nil }
 
 func getTestConfig() *Config {
 	cfg := GetDefaultConfig()
@@ -442,6 +467,78 @@ func TestTribeTaskAgreements(t *testing.T) {
 	})
 }
 
+func TestTribeLeaderElection(t *testing.T) {
+	numOfTribes := 3
+	tribes := getTribes(numOfTribes, nil)
+	Convey(fmt.Sprintf("%d tribes share a task through an agreement", numOfTribes), t, func() {
+		agreementName := "agreement1"
+		task := agreement.Task{ID: uuid.New()}
+		t := tribes[0]
+		So(t.AddAgreement(agreementName), ShouldBeNil)
+		for _, tr := range tribes {
+			So(t.JoinAgreement(agreementName, tr.memberlist.LocalNode().Name), ShouldBeNil)
+		}
+		So(t.AddTask(agreementName, task), ShouldBeNil)
+		var wg sync.WaitGroup
+		for _, tr := range tribes {
+			wg.Add(1)
+			go func(tr *tribe) {
+				defer wg.Done()
+				for {
+					tr.mutex.RLock()
+					_, a := tr.taskAgreement(task.ID)
+					ready := a != nil && len(a.Members) == numOfTribes
+					tr.mutex.RUnlock()
+					if ready {
+						return
+					}
+					time.Sleep(50 * time.Millisecond)
+				}
+			}(tr)
+		}
+		wg.Wait()
+
+		Convey("a single member leads the task", func() {
+			leaders := map[string]uint64{}
+			terms := map[uint64]struct{}{}
+			for i := 0; i < 100; i++ {
+				leaders = map[string]uint64{}
+				terms = map[uint64]struct{}{}
+				for _, tr := range tribes {
+					ok, term := tr.Leader(task.ID)
+					if ok {
+						leaders[tr.memberlist.LocalNode().Name] = term
+					}
+					terms[term] = struct{}{}
+				}
+				if len(leaders) == 1 && len(terms) == 1 {
+					break
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			So(leaders, ShouldHaveLength, 1)
+			So(terms, ShouldHaveLength, 1)
+			for name := range leaders {
+				t.mutex.RLock()
+				_, a := t.taskAgreement(task.ID)
+				So(name, ShouldEqual, taskOwner(task.ID, a.Members))
+				t.mutex.RUnlock()
+			}
+		})
+	})
+	Convey("A tribe handling leadership claims", t, func() {
+		tr := &tribe{leaderClaims: map[string]*leaderMsg{}}
+		Convey("does not replace a newer claim with an older one", func() {
+			newer := &leaderMsg{LTime: 10, TaskID: "task", MemberName: "member-0"}
+			older := &leaderMsg{LTime: 9, TaskID: "task", MemberName: "member-1"}
+			So(tr.handleClaimLeader(newer), ShouldBeTrue)
+			So(tr.handleClaimLeader(older), ShouldBeFalse)
+			So(tr.handleClaimLeader(newer), ShouldBeFalse)
+			So(newerClaim(&leaderMsg{LTime: 10, MemberName: "member-1"}, newer), ShouldBeTrue)
+		})
+	})
+}
+
 func TestTribeAgreements(t *testing.T) {
 	numOfTribes := 5
 	tribes := getTribes(numOfTribes, nil)
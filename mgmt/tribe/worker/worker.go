@@ -27,7 +27,7 @@ import (
 	"math/rand"
 	"net"
 	"os"
-	"path"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -114,6 +114,7 @@ type ManagesTasks interface {
 	StopTaskTribe(id string) []serror.SnapError
 	StartTaskTribe(id string) []serror.SnapError
 	RemoveTaskTribe(id string) error
+	Rebalance()
 }
 
 type getsMembers interface {
@@ -375,7 +376,7 @@ func (w worker) downloadPlugin(c *client.Client, plugin core.Plugin) (*os.File,
 			logger.Error(err)
 			return nil, err
 		}
-		fpath := path.Join(dir, fmt.Sprintf("%s-%s-%d", plugin.TypeName(), plugin.Name(), plugin.Version()))
+		fpath := filepath.Join(dir, fmt.Sprintf("%s-%s-%d", plugin.TypeName(), plugin.Name(), plugin.Version()))
 		f, err := os.OpenFile(fpath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0700)
 		if err != nil {
 			logger.Error(err)
@@ -436,12 +437,15 @@ func (w worker) createTask(taskID string, startOnCreate bool) {
 				}
 			}
 			logger.Debug("creating task")
-			opt := core.SetTaskID(taskID)
+			opts := []core.TaskOption{
+				core.SetTaskID(taskID),
+				core.OptionSingleton(taskResult.Singleton),
+			}
 			_, errs := w.taskManager.CreateTaskTribe(
 				getSchedule(taskResult.ScheduledTaskReturned.Schedule),
 				taskResult.Workflow,
 				startOnCreate,
-				opt)
+				opts...)
 			if errs != nil && len(errs.Errors()) > 0 {
 				fields := log.Fields{}
 				for idx, e := range errs.Errors() {
@@ -566,6 +570,7 @@ func getSchedule(s *core.Schedule) schedule.Schedule {
 			s.StopTimestamp,
 			s.Count,
 		)
+		sch.Aligned = s.Aligned
 		if err = sch.Validate(); err != nil {
 			logger.Error(err)
 			return nil
@@ -582,6 +587,23 @@ func getSchedule(s *core.Schedule) schedule.Schedule {
 			return nil
 		}
 		return sch
+	case "composite":
+		if len(s.Schedules) == 0 {
+			logger.Error(core.ErrMissingSchedules)
+			return nil
+		}
+		schedules := make([]schedule.Schedule, len(s.Schedules))
+		for i := range s.Schedules {
+			if schedules[i] = getSchedule(&s.Schedules[i]); schedules[i] == nil {
+				return nil
+			}
+		}
+		sch := schedule.NewCompositeSchedule(schedules...)
+		if err := sch.Validate(); err != nil {
+			logger.Error(err)
+			return nil
+		}
+		return sch
 	case "streaming":
 		logger.Error("streaming is not yet available for tribe")
 		//todo
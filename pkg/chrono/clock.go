@@ -0,0 +1,200 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chrono
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits for it.  The code waiting on a Clock
+// instead of the time package is tested deterministically with a FakeClock.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// After returns a channel receiving the time once d elapsed
+	After(d time.Duration) <-chan time.Time
+	// NewTimer returns a timer firing once d elapsed
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer of a Clock, like time.Timer
+type Timer interface {
+	// C returns the channel receiving the time when the timer fires
+	C() <-chan time.Time
+	// Stop prevents the timer from firing; it returns false if the timer
+	// already fired or was stopped
+	Stop() bool
+	// Reset changes the timer to fire once d elapsed; it returns true if
+	// the timer was active
+	Reset(d time.Duration) bool
+}
+
+// Real is the clock of the time package
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t *realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// FakeClock is a Clock whose time only moves when it is advanced.  The
+// timers whose time is reached fire, in order, as the clock advances.
+type FakeClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	// waiting is signaled every time a timer is added
+	waiting *sync.Cond
+}
+
+// NewFakeClock returns a fake clock telling the given time
+func NewFakeClock(now time.Time) *FakeClock {
+	f := &FakeClock{now: now}
+	f.waiting = sync.NewCond(&f.mutex)
+	return f
+}
+
+// Now returns the time of the clock
+func (f *FakeClock) Now() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.now
+}
+
+// After returns a channel receiving the time once the clock advanced by d
+func (f *FakeClock) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// NewTimer returns a timer firing once the clock advanced by d; it fires at
+// once when d is not positive
+func (f *FakeClock) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the time of the clock forward by d, firing the timers whose
+// time is reached
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.now = f.now.Add(d)
+	sort.Stable(byDeadline(f.timers))
+	for len(f.timers) > 0 && !f.timers[0].deadline.After(f.now) {
+		t := f.timers[0]
+		f.timers = f.timers[1:]
+		t.fire()
+	}
+}
+
+// Waiters returns the number of timers which did not fire yet
+func (f *FakeClock) Waiters() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return len(f.timers)
+}
+
+// BlockUntil blocks until at least n timers wait on the clock, so that the
+// clock is advanced once the code under test waits on it
+func (f *FakeClock) BlockUntil(n int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for len(f.timers) < n {
+		f.waiting.Wait()
+	}
+}
+
+// remove removes the timer and returns true if it was waiting; the clock
+// must be locked
+func (f *FakeClock) remove(t *fakeTimer) bool {
+	for i, w := range f.timers {
+		if w == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	c        chan time.Time
+	deadline time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	return t.clock.remove(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	f := t.clock
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	active := f.remove(t)
+	t.deadline = f.now.Add(d)
+	if d <= 0 {
+		t.fire()
+		return active
+	}
+	f.timers = append(f.timers, t)
+	f.waiting.Broadcast()
+	return active
+}
+
+// fire sends the time to the channel of the timer, unless a time it was
+// sent was not received
+func (t *fakeTimer) fire() {
+	select {
+	case t.c <- t.deadline:
+	default:
+	}
+}
+
+type byDeadline []*fakeTimer
+
+func (b byDeadline) Len() int           { return len(b) }
+func (b byDeadline) Less(i, j int) bool { return b[i].deadline.Before(b[j].deadline) }
+func (b byDeadline) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
@@ -0,0 +1,84 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chrono
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func received(c <-chan time.Time) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	Convey("Given a fake clock", t, func() {
+		start := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+		c := NewFakeClock(start)
+
+		Convey("its time only moves when advanced", func() {
+			So(c.Now(), ShouldResemble, start)
+			c.Advance(time.Minute)
+			So(c.Now(), ShouldResemble, start.Add(time.Minute))
+		})
+		Convey("its timers fire once their time is reached", func() {
+			after := c.After(time.Second)
+			timer := c.NewTimer(2 * time.Second)
+			So(c.Waiters(), ShouldEqual, 2)
+			c.Advance(999 * time.Millisecond)
+			So(received(after), ShouldBeFalse)
+			c.Advance(time.Millisecond)
+			So(received(after), ShouldBeTrue)
+			So(received(timer.C()), ShouldBeFalse)
+			c.Advance(time.Second)
+			So(received(timer.C()), ShouldBeTrue)
+			So(c.Waiters(), ShouldEqual, 0)
+		})
+		Convey("its timers can be stopped and reset", func() {
+			timer := c.NewTimer(time.Second)
+			So(timer.Stop(), ShouldBeTrue)
+			So(timer.Stop(), ShouldBeFalse)
+			c.Advance(time.Second)
+			So(received(timer.C()), ShouldBeFalse)
+			So(timer.Reset(time.Second), ShouldBeFalse)
+			c.Advance(time.Second)
+			So(received(timer.C()), ShouldBeTrue)
+		})
+		Convey("a timer without duration fires at once", func() {
+			So(received(c.After(0)), ShouldBeTrue)
+		})
+		Convey("BlockUntil returns once the timers wait", func() {
+			go func() {
+				<-c.After(time.Hour)
+			}()
+			c.BlockUntil(1)
+			So(c.Waiters(), ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,361 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package eventbus dispatches gomit events to their subscribers through
+// buffered queues, so that emitting an event does not wait for the handlers.
+// Each subscriber has its own queue, delivery goroutine and overflow policy,
+// and may filter the events it receives.
+package eventbus
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/gomit"
+)
+
+// Policy tells what Emit does when the queue of a subscriber is full.
+type Policy int
+
+const (
+	// Block waits for room in the queue, up to the block timeout of the
+	// bus; the emitter is slowed down to the pace of the subscriber
+	Block Policy = iota
+	// DropNewest discards the event being emitted
+	DropNewest
+	// DropOldest discards the oldest queued event to make room
+	DropOldest
+)
+
+var (
+	// ErrUnknownPolicy - The error message for an unknown overflow policy
+	ErrUnknownPolicy = errors.New("Unknown event overflow policy")
+)
+
+var busLogger = log.WithField("_module", "eventbus")
+
+// ParsePolicy returns the policy with the given name: "block", "drop-newest"
+// or "drop-oldest".
+func ParsePolicy(name string) (Policy, error) {
+	switch name {
+	case "", "block":
+		return Block, nil
+	case "drop-newest":
+		return DropNewest, nil
+	case "drop-oldest":
+		return DropOldest, nil
+	}
+	return Block, ErrUnknownPolicy
+}
+
+func (p Policy) String() string {
+	switch p {
+	case DropNewest:
+		return "drop-newest"
+	case DropOldest:
+		return "drop-oldest"
+	}
+	return "block"
+}
+
+// Filter returns the part of an event a subscriber receives, nil when the
+// subscriber is not interested in the event.
+type Filter func(gomit.EventBody) gomit.EventBody
+
+// SubscribeOption sets an option of a subscription.
+type SubscribeOption func(*subscriber)
+
+// WithFilter hands the subscriber only the events returned by the filter.
+func WithFilter(f Filter) SubscribeOption {
+	return func(s *subscriber) {
+		s.filter = f
+	}
+}
+
+// WithPolicy sets the overflow policy of the subscriber, overriding the
+// default policy of the bus.
+func WithPolicy(p Policy) SubscribeOption {
+	return func(s *subscriber) {
+		s.policy = p
+	}
+}
+
+// Synchronously hands the events to the subscriber within Emit, without
+// queue nor overflow policy, whatever the buffer size of the bus, so that
+// no event is dropped; meant for the handlers keeping the state of the
+// emitter, which must not block.
+func Synchronously() SubscribeOption {
+	return func(s *subscriber) {
+		s.synchronous = true
+	}
+}
+
+// Bus is a gomit.Emitter and gomit.Delegator delivering events to the
+// registered handlers.  When its buffer size is zero the handlers are called
+// synchronously by Emit, as gomit.EventController does.
+type Bus struct {
+	buffer       int
+	policy       Policy
+	blockTimeout time.Duration
+
+	mutex       sync.RWMutex
+	subscribers map[string]*subscriber
+}
+
+// New returns a bus queuing up to buffer events per subscriber, which uses
+// the given policy when a queue is full.  With the Block policy an event is
+// dropped when there is still no room after blockTimeout, so that a stuck
+// handler cannot stall the emitters; zero waits forever.
+func New(buffer int, policy Policy, blockTimeout time.Duration) *Bus {
+	if buffer < 0 {
+		buffer = 0
+	}
+	return &Bus{
+		buffer:       buffer,
+		policy:       policy,
+		blockTimeout: blockTimeout,
+		subscribers:  map[string]*subscriber{},
+	}
+}
+
+// Emit hands the event to the subscribers and returns the number of
+// subscribers it was handed to.
+func (b *Bus) Emit(body gomit.EventBody) (int, error) {
+	e := gomit.Event{
+		Header: gomit.Header{Time: time.Now()},
+		Body:   body,
+	}
+	b.mutex.RLock()
+	subs := make([]*subscriber, 0, len(b.subscribers))
+	for _, s := range b.subscribers {
+		subs = append(subs, s)
+	}
+	b.mutex.RUnlock()
+
+	n := 0
+	for _, s := range subs {
+		if s.deliver(e) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// RegisterHandler subscribes the handler to all events with the defaults of
+// the bus.
+func (b *Bus) RegisterHandler(name string, h gomit.Handler) error {
+	return b.Subscribe(name, h)
+}
+
+// Subscribe registers the handler under the given name, replacing the
+// handler already registered under that name.
+func (b *Bus) Subscribe(name string, h gomit.Handler, opts ...SubscribeOption) error {
+	s := &subscriber{
+		name:         name,
+		handler:      h,
+		policy:       b.policy,
+		blockTimeout: b.blockTimeout,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if b.buffer > 0 && !s.synchronous {
+		s.queue = make(chan gomit.Event, b.buffer)
+		s.quit = make(chan struct{})
+		s.done = make(chan struct{})
+		go s.run()
+	}
+	b.mutex.Lock()
+	old := b.subscribers[name]
+	b.subscribers[name] = s
+	b.mutex.Unlock()
+	if old != nil {
+		old.stop()
+	}
+	return nil
+}
+
+// UnregisterHandler removes the handler registered under the given name
+// after the events queued for it are delivered.  It must not be called by
+// the handler itself.
+func (b *Bus) UnregisterHandler(name string) error {
+	b.mutex.Lock()
+	s := b.subscribers[name]
+	delete(b.subscribers, name)
+	b.mutex.Unlock()
+	if s != nil {
+		s.stop()
+	}
+	return nil
+}
+
+// HandlerCount returns the number of registered handlers.
+func (b *Bus) HandlerCount() int {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return len(b.subscribers)
+}
+
+// IsHandlerRegistered returns true when a handler is registered under the
+// given name.
+func (b *Bus) IsHandlerRegistered(name string) bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	_, ok := b.subscribers[name]
+	return ok
+}
+
+// Dropped returns the number of events discarded because the queue of the
+// handler registered under the given name was full.
+func (b *Bus) Dropped(name string) uint64 {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if s, ok := b.subscribers[name]; ok {
+		return atomic.LoadUint64(&s.dropped)
+	}
+	return 0
+}
+
+// Stop delivers the queued events and removes all handlers.
+func (b *Bus) Stop() {
+	b.mutex.Lock()
+	subs := b.subscribers
+	b.subscribers = map[string]*subscriber{}
+	b.mutex.Unlock()
+	for _, s := range subs {
+		s.stop()
+	}
+}
+
+type subscriber struct {
+	// dropped is first to be 64-bit aligned for the atomic operations
+	dropped uint64
+
+	name         string
+	handler      gomit.Handler
+	filter       Filter
+	policy       Policy
+	blockTimeout time.Duration
+	synchronous  bool
+
+	// queue is nil when events are delivered synchronously
+	queue chan gomit.Event
+	quit  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// deliver queues the event or hands it to the handler right away.  It
+// returns false when the event is filtered out or dropped.
+func (s *subscriber) deliver(e gomit.Event) bool {
+	if s.filter != nil {
+		if e.Body = s.filter(e.Body); e.Body == nil {
+			return false
+		}
+	}
+	if s.queue == nil {
+		s.handler.HandleGomitEvent(e)
+		return true
+	}
+	select {
+	case s.queue <- e:
+		return true
+	case <-s.quit:
+		return false
+	default:
+	}
+	switch s.policy {
+	case DropNewest:
+		s.drop(e)
+		return false
+	case DropOldest:
+		select {
+		case old := <-s.queue:
+			s.drop(old)
+		default:
+		}
+		select {
+		case s.queue <- e:
+			return true
+		default:
+			s.drop(e)
+			return false
+		}
+	}
+	var timeout <-chan time.Time
+	if s.blockTimeout > 0 {
+		timer := time.NewTimer(s.blockTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case s.queue <- e:
+		return true
+	case <-s.quit:
+		return false
+	case <-timeout:
+		s.drop(e)
+		return false
+	}
+}
+
+func (s *subscriber) drop(e gomit.Event) {
+	dropped := atomic.AddUint64(&s.dropped, 1)
+	busLogger.WithFields(log.Fields{
+		"_block":          "deliver",
+		"subscriber":      s.name,
+		"event-namespace": e.Namespace(),
+		"dropped-events":  dropped,
+	}).Debug("subscriber queue is full, event dropped")
+}
+
+// run hands the queued events to the handler until the subscriber is
+// stopped, then delivers the events left in the queue.
+func (s *subscriber) run() {
+	defer close(s.done)
+	for {
+		select {
+		case e := <-s.queue:
+			s.handler.HandleGomitEvent(e)
+		case <-s.quit:
+			for {
+				select {
+				case e := <-s.queue:
+					s.handler.HandleGomitEvent(e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *subscriber) stop() {
+	if s.queue == nil {
+		return
+	}
+	s.once.Do(func() {
+		close(s.quit)
+	})
+	<-s.done
+}
@@ -0,0 +1,165 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventbus
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/gomit"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testEvent int
+
+func (e testEvent) Namespace() string {
+	return "test"
+}
+
+// gatedHandler records the events it handles once its gate is opened
+type gatedHandler struct {
+	sync.Mutex
+	gate   chan struct{}
+	events []int
+}
+
+func newGatedHandler() *gatedHandler {
+	return &gatedHandler{gate: make(chan struct{})}
+}
+
+func (h *gatedHandler) HandleGomitEvent(e gomit.Event) {
+	<-h.gate
+	h.Lock()
+	defer h.Unlock()
+	h.events = append(h.events, int(e.Body.(testEvent)))
+}
+
+func (h *gatedHandler) received() []int {
+	h.Lock()
+	defer h.Unlock()
+	return append([]int{}, h.events...)
+}
+
+func TestBus(t *testing.T) {
+	Convey("Given a bus without buffer", t, func() {
+		bus := New(0, Block, 0)
+		h := newGatedHandler()
+		close(h.gate)
+		bus.RegisterHandler("h", h)
+		Convey("events are handled by Emit", func() {
+			n, err := bus.Emit(testEvent(1))
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 1)
+			So(h.received(), ShouldResemble, []int{1})
+		})
+	})
+	Convey("Given a bus with buffers", t, func() {
+		Convey("Emit does not wait for the handlers", func() {
+			bus := New(2, Block, 0)
+			h := newGatedHandler()
+			bus.RegisterHandler("h", h)
+			bus.Emit(testEvent(1))
+			bus.Emit(testEvent(2))
+			So(h.received(), ShouldBeEmpty)
+			close(h.gate)
+			bus.UnregisterHandler("h")
+			So(h.received(), ShouldResemble, []int{1, 2})
+			So(bus.IsHandlerRegistered("h"), ShouldBeFalse)
+		})
+		Convey("a full queue drops the newest events", func() {
+			bus := New(1, DropNewest, 0)
+			h := newGatedHandler()
+			bus.Subscribe("h", h)
+			for i := 1; i <= 4; i++ {
+				bus.Emit(testEvent(i))
+			}
+			So(bus.Dropped("h"), ShouldBeGreaterThanOrEqualTo, 2)
+			close(h.gate)
+			bus.Stop()
+			So(h.received()[0], ShouldEqual, 1)
+			So(h.received(), ShouldNotContain, 4)
+		})
+		Convey("a full queue drops the oldest events", func() {
+			bus := New(1, DropOldest, 0)
+			h := newGatedHandler()
+			bus.Subscribe("h", h)
+			for i := 1; i <= 4; i++ {
+				bus.Emit(testEvent(i))
+			}
+			So(bus.Dropped("h"), ShouldBeGreaterThanOrEqualTo, 2)
+			close(h.gate)
+			bus.Stop()
+			received := h.received()
+			So(received[len(received)-1], ShouldEqual, 4)
+		})
+		Convey("a blocked emitter gives up after the block timeout", func() {
+			bus := New(1, Block, 10*time.Millisecond)
+			h := newGatedHandler()
+			bus.Subscribe("h", h)
+			for i := 1; i <= 3; i++ {
+				bus.Emit(testEvent(i))
+			}
+			So(bus.Dropped("h"), ShouldBeGreaterThanOrEqualTo, 1)
+			close(h.gate)
+			bus.Stop()
+		})
+		Convey("synchronous subscribers are handed every event by Emit", func() {
+			bus := New(1, DropNewest, 0)
+			h := newGatedHandler()
+			close(h.gate)
+			bus.Subscribe("h", h, Synchronously())
+			for i := 1; i <= 3; i++ {
+				bus.Emit(testEvent(i))
+			}
+			So(h.received(), ShouldResemble, []int{1, 2, 3})
+			So(bus.Dropped("h"), ShouldEqual, 0)
+			bus.Stop()
+		})
+		Convey("filtered out events are not queued", func() {
+			bus := New(1, DropNewest, 0)
+			h := newGatedHandler()
+			odd := func(b gomit.EventBody) gomit.EventBody {
+				if int(b.(testEvent))%2 == 1 {
+					return b
+				}
+				return nil
+			}
+			bus.Subscribe("h", h, WithFilter(odd))
+			n, _ := bus.Emit(testEvent(2))
+			So(n, ShouldEqual, 0)
+			bus.Emit(testEvent(3))
+			close(h.gate)
+			bus.Stop()
+			So(h.received(), ShouldResemble, []int{3})
+		})
+	})
+	Convey("Overflow policies are parsed from their names", t, func() {
+		for _, p := range []Policy{Block, DropNewest, DropOldest} {
+			parsed, err := ParsePolicy(p.String())
+			So(err, ShouldBeNil)
+			So(parsed, ShouldEqual, p)
+		}
+		_, err := ParsePolicy("drop-all")
+		So(err, ShouldEqual, ErrUnknownPolicy)
+	})
+}
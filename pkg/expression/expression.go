@@ -0,0 +1,404 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package expression parses and evaluates the boolean expressions selecting
+// metrics, e.g. `value > 90 && ns =~ "/intel/psutil/cpu/.*"`.
+//
+// An expression compares operands with ==, !=, <, <=, >, >=, =~ (matches
+// the regular expression) and !~ (does not match it), and combines the
+// comparisons with &&, || and !, grouped with parentheses.  An operand is a
+// number, a string between double quotes, true, false or an identifier whose
+// value is given when the expression is evaluated.  Identifiers are made of
+// letters, digits, '_' and '.', e.g. tags.host.
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrEmptyExpression - The error message for an empty expression
+	ErrEmptyExpression = errors.New("Expression is empty")
+)
+
+// Resolver returns the value of an identifier of an expression: a float64,
+// a string or a bool.  A missing identifier is nil, which only equals nil.
+type Resolver func(identifier string) interface{}
+
+// Expression is a parsed boolean expression
+type Expression struct {
+	text        string
+	root        node
+	identifiers []string
+}
+
+// Parse parses the given expression
+func Parse(s string) (*Expression, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, ErrEmptyExpression
+	}
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid expression '%s': %v", s, err)
+	}
+	p := &parser{tokens: tokens, identifiers: map[string]struct{}{}}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected '%s'", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid expression '%s': %v", s, err)
+	}
+	e := &Expression{text: s, root: root}
+	for id := range p.identifiers {
+		e.identifiers = append(e.identifiers, id)
+	}
+	return e, nil
+}
+
+// String returns the text of the expression
+func (e *Expression) String() string {
+	return e.text
+}
+
+// Identifiers returns the identifiers the expression refers to
+func (e *Expression) Identifiers() []string {
+	return append([]string{}, e.identifiers...)
+}
+
+// Match evaluates the expression with the values of the resolver.  An
+// operand which is not a bool is false, and values of different types are
+// only ever different.
+func (e *Expression) Match(r Resolver) bool {
+	return truth(e.root.eval(r))
+}
+
+func truth(v interface{}) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
+
+type tokenKind int
+
+const (
+	tokenOperator tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdentifier
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// value is the number or unquoted string of a literal
+	value interface{}
+}
+
+// operators are matched longest first
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "(", ")"}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(s) && s[end] != '"'; end++ {
+				if s[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(s) {
+				return nil, errors.New("unterminated string")
+			}
+			v, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", s[i:end+1])
+			}
+			tokens = append(tokens, token{kind: tokenString, text: s[i : end+1], value: v})
+			i = end + 1
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(s) && unicode.IsDigit(rune(s[i+1]))) || (c == '.' && i+1 < len(s) && unicode.IsDigit(rune(s[i+1]))):
+			end := i + 1
+			for end < len(s) && (unicode.IsDigit(rune(s[end])) || strings.ContainsRune(".eE", rune(s[end])) || ((s[end] == '-' || s[end] == '+') && (s[end-1] == 'e' || s[end-1] == 'E'))) {
+				end++
+			}
+			v, err := strconv.ParseFloat(s[i:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %s", s[i:end])
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: s[i:end], value: v})
+			i = end
+		case unicode.IsLetter(c) || c == '_':
+			end := i + 1
+			for end < len(s) && (unicode.IsLetter(rune(s[end])) || unicode.IsDigit(rune(s[end])) || s[end] == '_' || s[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdentifier, text: s[i:end]})
+			i = end
+		default:
+			found := false
+			for _, op := range operators {
+				if strings.HasPrefix(s[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op})
+					i += len(op)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unexpected '%c'", c)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens      []token
+	pos         int
+	identifiers map[string]struct{}
+}
+
+// accept consumes the next token if it is the given operator
+func (p *parser) accept(op string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOperator && p.tokens[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept("!") {
+		n, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{n: n}, nil
+	}
+	return p.parseComparison()
+}
+
+var comparisons = []string{"==", "!=", "<=", ">=", "<", ">", "=~", "!~"}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range comparisons {
+		if !p.accept(op) {
+			continue
+		}
+		if op == "=~" || op == "!~" {
+			if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenString {
+				return nil, fmt.Errorf("expected a string after '%s'", op)
+			}
+			expr := p.tokens[p.pos].value.(string)
+			p.pos++
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q: %v", expr, err)
+			}
+			return &matchNode{left: left, re: re, negate: op == "!~"}, nil
+		}
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, errors.New("unexpected end of expression")
+	}
+	if p.accept("(") {
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, errors.New("expected ')'")
+		}
+		return n, nil
+	}
+	t := p.tokens[p.pos]
+	switch t.kind {
+	case tokenNumber, tokenString:
+		p.pos++
+		return &literalNode{value: t.value}, nil
+	case tokenIdentifier:
+		p.pos++
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		}
+		p.identifiers[t.text] = struct{}{}
+		return &identifierNode{name: t.text}, nil
+	}
+	return nil, fmt.Errorf("unexpected '%s'", t.text)
+}
+
+type node interface {
+	eval(r Resolver) interface{}
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(Resolver) interface{} {
+	return n.value
+}
+
+type identifierNode struct {
+	name string
+}
+
+func (n *identifierNode) eval(r Resolver) interface{} {
+	return r(n.name)
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n *orNode) eval(r Resolver) interface{} {
+	return truth(n.left.eval(r)) || truth(n.right.eval(r))
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n *andNode) eval(r Resolver) interface{} {
+	return truth(n.left.eval(r)) && truth(n.right.eval(r))
+}
+
+type notNode struct {
+	n node
+}
+
+func (n *notNode) eval(r Resolver) interface{} {
+	return !truth(n.n.eval(r))
+}
+
+type matchNode struct {
+	left   node
+	re     *regexp.Regexp
+	negate bool
+}
+
+func (n *matchNode) eval(r Resolver) interface{} {
+	s, ok := n.left.eval(r).(string)
+	if !ok {
+		return false
+	}
+	return n.re.MatchString(s) != n.negate
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(r Resolver) interface{} {
+	left, right := n.left.eval(r), n.right.eval(r)
+	switch l := left.(type) {
+	case float64:
+		if r, ok := right.(float64); ok {
+			return compare(n.op, l < r, l == r)
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return compare(n.op, l < r, l == r)
+		}
+	case bool:
+		if r, ok := right.(bool); ok && (n.op == "==" || n.op == "!=") {
+			return compare(n.op, false, l == r)
+		}
+	case nil:
+		if right == nil && (n.op == "==" || n.op == "!=") {
+			return compare(n.op, false, true)
+		}
+	}
+	// values of different types are only different
+	return n.op == "!="
+}
+
+func compare(op string, less, equal bool) bool {
+	switch op {
+	case "==":
+		return equal
+	case "!=":
+		return !equal
+	case "<":
+		return less
+	case "<=":
+		return less || equal
+	case ">":
+		return !less && !equal
+	case ">=":
+		return !less
+	}
+	return false
+}
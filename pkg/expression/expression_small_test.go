@@ -0,0 +1,77 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expression
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExpression(t *testing.T) {
+	values := map[string]interface{}{
+		"value":     95.5,
+		"ns":        "/intel/psutil/cpu/cpu0/user",
+		"tags.host": "node1",
+		"up":        true,
+	}
+	resolver := func(id string) interface{} {
+		return values[id]
+	}
+	match := func(s string) bool {
+		e, err := Parse(s)
+		So(err, ShouldBeNil)
+		return e.Match(resolver)
+	}
+
+	Convey("Given metric values", t, func() {
+		Convey("comparisons and regular expressions are combined", func() {
+			So(match(`value > 90 && ns =~ "/intel/psutil/cpu/.*"`), ShouldBeTrue)
+			So(match(`value > 90 && ns !~ "/intel/psutil/cpu/.*"`), ShouldBeFalse)
+			So(match(`value <= 90 || tags.host == "node1"`), ShouldBeTrue)
+			So(match(`!(value >= 95.5) || up == false`), ShouldBeFalse)
+			So(match(`value != -1e3 && up`), ShouldBeTrue)
+		})
+		Convey("|| has a lower precedence than &&", func() {
+			So(match(`value < 0 && up || tags.host == "node1"`), ShouldBeTrue)
+			So(match(`value < 0 && (up || tags.host == "node1")`), ShouldBeFalse)
+		})
+		Convey("values of different types and missing values are different", func() {
+			So(match(`value == "95.5"`), ShouldBeFalse)
+			So(match(`value != "95.5"`), ShouldBeTrue)
+			So(match(`tags.missing == "node1"`), ShouldBeFalse)
+			So(match(`tags.missing > 1`), ShouldBeFalse)
+		})
+		Convey("the identifiers are listed", func() {
+			e, err := Parse(`value > 90 && (ns =~ "cpu" || value < 0)`)
+			So(err, ShouldBeNil)
+			So(e.Identifiers(), ShouldHaveLength, 2)
+			So(e.String(), ShouldEqual, `value > 90 && (ns =~ "cpu" || value < 0)`)
+		})
+		Convey("invalid expressions are rejected", func() {
+			for _, s := range []string{"", "value >", `ns =~ value`, `ns =~ "("`, "(value > 1", "value > 1)", `"unterminated`, "value # 1", "value 1"} {
+				_, err := Parse(s)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
@@ -5,10 +5,30 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// windowsExecutableExts are the extensions of the files Windows executes
+var windowsExecutableExts = []string{".exe", ".com", ".bat", ".cmd"}
+
+// IsExecutable returns true when the file is executable by someone (even if
+// it isn't you).  Windows has no execute permission so a file is executable
+// there when its extension is one of an executable.
+func IsExecutable(fi os.FileInfo) bool {
+	if runtime.GOOS == "windows" {
+		ext := strings.ToLower(filepath.Ext(fi.Name()))
+		for _, e := range windowsExecutableExts {
+			if ext == e {
+				return true
+			}
+		}
+		return false
+	}
+	return fi.Mode()&0111 != 0
+}
+
 // WriteFile creates a temporary directory for loading plugins
 // Plugins loaded by the cli and from the auto-load directory go through this route of copying the plugin binaries to the temp dir and executing from temp
 // WriteFile takes the name of the original file (fileName), path to the original file (filePath) and the content of the file (b)
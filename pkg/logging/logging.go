@@ -0,0 +1,157 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logging sets the level of the log entries written by each module of
+// snapteld.  The module of an entry is its "_module" field, e.g. "scheduler"
+// or "control-plugin-mgr".  The level set for a module also applies to its
+// sub-modules, whose names start with the name of the module and a dash, so
+// that "control" covers "control-runner" and "rest" covers "rest-v2".
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Levels are the levels of the log entries written, by module, named as
+// logrus levels: "debug", "info", "warning", "error" or "fatal".  The modules
+// without a level of their own use the default level.
+type Levels struct {
+	Default string            `json:"default"`
+	Modules map[string]string `json:"modules,omitempty"`
+}
+
+// Module returns the module of a log entry, without the leading underscore
+// and "mgmt-" prefix of the modules of the REST API, e.g. "rest-v2" for
+// "_mgmt-rest-v2".  It is empty when the entry has no module.
+func Module(e *log.Entry) string {
+	m, _ := e.Data["_module"].(string)
+	m = strings.TrimPrefix(m, "_")
+	return strings.TrimPrefix(m, "mgmt-")
+}
+
+// Filter is a logrus formatter writing the entries of each module from the
+// level of the module on, with the formatter it wraps.
+type Filter struct {
+	formatter log.Formatter
+
+	mutex   sync.RWMutex
+	levels  Levels
+	dflt    log.Level
+	modules map[string]log.Level
+	// names are the modules with a level, longest first
+	names []string
+}
+
+// New returns a filter wrapping the formatter, which it installs as the
+// formatter of the standard logger along with the levels.
+func New(formatter log.Formatter, levels Levels) (*Filter, error) {
+	f := &Filter{formatter: formatter}
+	if err := f.SetLevels(levels); err != nil {
+		return nil, err
+	}
+	log.SetFormatter(f)
+	return f, nil
+}
+
+// Format formats the entry with the wrapped formatter, or returns nothing
+// when the entry is below the level of its module.
+func (f *Filter) Format(e *log.Entry) ([]byte, error) {
+	if e.Level > f.level(Module(e)) {
+		return nil, nil
+	}
+	return f.formatter.Format(e)
+}
+
+// Levels returns the levels of the modules
+func (f *Filter) Levels() Levels {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	levels := Levels{Default: f.levels.Default, Modules: map[string]string{}}
+	for m, l := range f.levels.Modules {
+		levels.Modules[m] = l
+	}
+	return levels
+}
+
+// SetLevels replaces the levels of the modules.  The level of the standard
+// logger is set to the most verbose of them so that the entries reach the
+// filter.
+func (f *Filter) SetLevels(levels Levels) error {
+	dflt, err := log.ParseLevel(levels.Default)
+	if err != nil {
+		return fmt.Errorf("%v (while parsing the default log level)", err)
+	}
+	levels.Default = dflt.String()
+	modules := map[string]log.Level{}
+	names := []string{}
+	verbose := dflt
+	normalized := map[string]string{}
+	for m, l := range levels.Modules {
+		lvl, err := log.ParseLevel(l)
+		if err != nil {
+			return fmt.Errorf("%v (while parsing the log level of module '%s')", err, m)
+		}
+		modules[m] = lvl
+		normalized[m] = lvl.String()
+		names = append(names, m)
+		if lvl > verbose {
+			verbose = lvl
+		}
+	}
+	levels.Modules = normalized
+	sort.Sort(byLength(names))
+
+	f.mutex.Lock()
+	f.levels = levels
+	f.dflt = dflt
+	f.modules = modules
+	f.names = names
+	f.mutex.Unlock()
+	log.SetLevel(verbose)
+	return nil
+}
+
+// level returns the level of the module, which is the one of the longest
+// module name it starts with
+func (f *Filter) level(module string) log.Level {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	for _, name := range f.names {
+		if module == name || strings.HasPrefix(module, name+"-") {
+			return f.modules[name]
+		}
+	}
+	return f.dflt
+}
+
+type byLength []string
+
+func (b byLength) Len() int      { return len(b) }
+func (b byLength) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byLength) Less(i, j int) bool {
+	if len(b[i]) != len(b[j]) {
+		return len(b[i]) > len(b[j])
+	}
+	return b[i] < b[j]
+}
@@ -0,0 +1,117 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func entry(module string, level log.Level) *log.Entry {
+	e := log.WithField("_module", module)
+	e.Level = level
+	return e
+}
+
+func TestFilter(t *testing.T) {
+	formatter := log.StandardLogger().Formatter
+	level := log.GetLevel()
+	defer func() {
+		log.SetFormatter(formatter)
+		log.SetLevel(level)
+	}()
+
+	Convey("Given a filter with levels by module", t, func() {
+		f, err := New(&log.TextFormatter{}, Levels{
+			Default: "warning",
+			Modules: map[string]string{"control": "info", "control-plugin-runner": "debug", "rest": "error"},
+		})
+		So(err, ShouldBeNil)
+		Convey("the standard logger is set to the most verbose level", func() {
+			So(log.GetLevel(), ShouldEqual, log.DebugLevel)
+		})
+		Convey("entries are filtered by the level of their module", func() {
+			cases := []struct {
+				module  string
+				level   log.Level
+				written bool
+			}{
+				{"scheduler", log.InfoLevel, false},
+				{"scheduler", log.WarnLevel, true},
+				{"control", log.InfoLevel, true},
+				{"control", log.DebugLevel, false},
+				{"control-plugin-mgr", log.InfoLevel, true},
+				{"control-plugin-runner", log.DebugLevel, true},
+				{"controller", log.InfoLevel, false},
+				{"_mgmt-rest-v2", log.WarnLevel, false},
+				{"_mgmt-rest-v2", log.ErrorLevel, true},
+			}
+			for _, c := range cases {
+				b, err := f.Format(entry(c.module, c.level))
+				So(err, ShouldBeNil)
+				So(len(b) > 0, ShouldEqual, c.written)
+			}
+		})
+		Convey("levels are normalized", func() {
+			So(f.SetLevels(Levels{Default: "WARN", Modules: map[string]string{"scheduler": "Debug"}}), ShouldBeNil)
+			So(f.Levels(), ShouldResemble, Levels{Default: "warning", Modules: map[string]string{"scheduler": "debug"}})
+		})
+		Convey("invalid levels are rejected and the levels kept", func() {
+			So(f.SetLevels(Levels{Default: "verbose"}), ShouldNotBeNil)
+			So(f.SetLevels(Levels{Default: "info", Modules: map[string]string{"scheduler": "loud"}}), ShouldNotBeNil)
+			So(f.Levels().Default, ShouldEqual, "warning")
+			So(f.Levels().Modules, ShouldContainKey, "rest")
+		})
+	})
+}
+
+func TestRotatingFile(t *testing.T) {
+	Convey("Given a rotating file", t, func() {
+		dir, err := ioutil.TempDir("", "snap-logging")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "snapteld.log")
+		r, err := NewRotatingFile(path, 10, 2, false)
+		So(err, ShouldBeNil)
+		defer r.Close()
+
+		Convey("it is rotated when full and keeps the given number of backups", func() {
+			for _, s := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+				_, err := r.Write([]byte(s))
+				So(err, ShouldBeNil)
+			}
+			b, _ := ioutil.ReadFile(path)
+			So(string(b), ShouldEqual, "dddddd\n")
+			b, _ = ioutil.ReadFile(path + ".1")
+			So(string(b), ShouldEqual, "cccccc\n")
+			b, _ = ioutil.ReadFile(path + ".2")
+			So(string(b), ShouldEqual, "bbbbbb\n")
+			_, err := os.Stat(path + ".3")
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,126 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingFile is a log file which is rotated once it reaches its maximum
+// size: the file is renamed with the suffix .1, the previous .1 to .2 and
+// so on, and the oldest file beyond the number of backups is removed.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mutex sync.Mutex
+	file  *os.File
+	size  int64
+}
+
+// NewRotatingFile opens the log file at path, which is rotated when it
+// reaches maxSize bytes, keeping maxBackups rotated files.  The file is not
+// rotated when maxSize is zero.  The file is truncated first if asked to,
+// otherwise the entries are appended.
+func NewRotatingFile(path string, maxSize int64, maxBackups int, truncate bool) (*RotatingFile, error) {
+	mode := os.O_APPEND
+	if truncate {
+		mode = os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|mode, 0666)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+		file:       f,
+		size:       info.Size(),
+	}, nil
+}
+
+// Write writes an entry to the file, rotating the file first when the
+// entry would make it exceed its maximum size
+func (r *RotatingFile) Write(b []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(b)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			// keep on writing to the current file rather than losing entries
+			fmt.Fprintf(os.Stderr, "unable to rotate the log file %s: %v\n", r.path, err)
+		}
+	}
+	n, err := r.file.Write(b)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the file
+func (r *RotatingFile) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.file.Close()
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	if r.maxBackups > 0 {
+		os.Remove(r.backup(r.maxBackups))
+		for i := r.maxBackups - 1; i > 0; i-- {
+			os.Rename(r.backup(i), r.backup(i+1))
+		}
+		if err := os.Rename(r.path, r.backup(1)); err != nil {
+			return r.reopen(os.O_APPEND, err)
+		}
+	}
+	return r.reopen(os.O_TRUNC, nil)
+}
+
+// reopen opens the file again after a rotation, returning the error of the
+// rotation if any
+func (r *RotatingFile) reopen(mode int, rotateErr error) error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|mode, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	return rotateErr
+}
+
+func (r *RotatingFile) backup(i int) string {
+	return fmt.Sprintf("%s.%d", r.path, i)
+}
@@ -0,0 +1,117 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netutil
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UnixScheme is the prefix of addresses which refer to Unix domain sockets,
+// e.g. unix:///var/run/snap/snapteld.sock
+const UnixScheme = "unix://"
+
+// ErrUnixSocketUnsupported - The error message for a Unix domain socket address used on Windows
+var ErrUnixSocketUnsupported = errors.New("Unix domain sockets are not supported on Windows, use a TCP address")
+
+// UnixSocketsSupported returns false on Windows, where the daemon and the
+// plugins communicate over TCP only
+func UnixSocketsSupported() bool {
+	return runtime.GOOS != "windows"
+}
+
+// UnixSocketPath returns the path of the Unix domain socket the given address
+// refers to and true, or false if the address is not a Unix socket address.
+func UnixSocketPath(addr string) (string, bool) {
+	if !strings.HasPrefix(addr, UnixScheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(addr, UnixScheme), true
+}
+
+// JoinHostPort combines host and port into a TCP address.  IPv6 hosts are
+// enclosed in square brackets, which the host may already be.
+func JoinHostPort(host string, port int) string {
+	return net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(port))
+}
+
+// ParseFileMode parses the octal permissions given to Unix domain sockets,
+// e.g. "0660".  An empty string is parsed as 0, which leaves the
+// permissions of created sockets unchanged.
+func ParseFileMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return 0, nil
+	}
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || m > 0777 {
+		return 0, fmt.Errorf("invalid socket permissions '%s', expected an octal mode like 0660", mode)
+	}
+	return os.FileMode(m), nil
+}
+
+// Listen announces on the given address, either a TCP address (host:port,
+// IPv6 hosts being enclosed in square brackets) or a Unix domain socket
+// address.  A stale socket left at the path of a Unix domain socket is
+// removed and the socket is given the permissions in mode, unless mode is 0.
+func Listen(addr string, mode os.FileMode) (net.Listener, error) {
+	path, ok := UnixSocketPath(addr)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if !UnixSocketsSupported() {
+		return nil, ErrUnixSocketUnsupported
+	}
+	if fi, err := os.Stat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("cannot listen on %s: file exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
+
+// Dial connects to the given TCP or Unix domain socket address.
+func Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	if path, ok := UnixSocketPath(addr); ok {
+		if !UnixSocketsSupported() {
+			return nil, ErrUnixSocketUnsupported
+		}
+		return net.DialTimeout("unix", path, timeout)
+	}
+	return net.DialTimeout("tcp", addr, timeout)
+}
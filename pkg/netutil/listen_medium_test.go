@@ -0,0 +1,114 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netutil
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestListen(t *testing.T) {
+	Convey("Given a temporary directory", t, func() {
+		dir, err := ioutil.TempDir("", "netutil")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "test.sock")
+
+		Convey("Unix domain sockets are given the requested permissions", func() {
+			mode, err := ParseFileMode("0600")
+			So(err, ShouldBeNil)
+			ln, err := Listen(UnixScheme+path, mode)
+			So(err, ShouldBeNil)
+			defer ln.Close()
+			fi, err := os.Stat(path)
+			So(err, ShouldBeNil)
+			So(fi.Mode()&os.ModeSocket, ShouldNotEqual, 0)
+			So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+
+			go func() {
+				if c, err := ln.Accept(); err == nil {
+					c.Write([]byte("ok"))
+					c.Close()
+				}
+			}()
+			conn, err := Dial(UnixScheme+path, time.Second)
+			So(err, ShouldBeNil)
+			b, err := ioutil.ReadAll(conn)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "ok")
+		})
+		Convey("a stale Unix domain socket is replaced", func() {
+			l, err := net.Listen("unix", path)
+			So(err, ShouldBeNil)
+			// keep the socket file in place as a crashed process would
+			l.(*net.UnixListener).SetUnlinkOnClose(false)
+			l.Close()
+			ln, err := Listen(UnixScheme+path, 0)
+			So(err, ShouldBeNil)
+			ln.Close()
+		})
+		Convey("files which are not sockets are not replaced", func() {
+			So(ioutil.WriteFile(path, []byte{}, 0644), ShouldBeNil)
+			_, err := Listen(UnixScheme+path, 0)
+			So(err, ShouldNotBeNil)
+		})
+	})
+	Convey("TCP addresses are listened on", t, func() {
+		ln, err := Listen(JoinHostPort("127.0.0.1", 0), 0)
+		So(err, ShouldBeNil)
+		So(ln.Addr().Network(), ShouldEqual, "tcp")
+		ln.Close()
+	})
+}
+
+func TestAddresses(t *testing.T) {
+	Convey("IPv6 hosts are enclosed in square brackets", t, func() {
+		So(JoinHostPort("::1", 8181), ShouldEqual, "[::1]:8181")
+		So(JoinHostPort("[::1]", 8181), ShouldEqual, "[::1]:8181")
+		So(JoinHostPort("127.0.0.1", 8181), ShouldEqual, "127.0.0.1:8181")
+	})
+	Convey("Unix domain socket addresses are recognized", t, func() {
+		path, ok := UnixSocketPath("unix:///var/run/snap.sock")
+		So(ok, ShouldBeTrue)
+		So(path, ShouldEqual, "/var/run/snap.sock")
+		_, ok = UnixSocketPath("127.0.0.1:8181")
+		So(ok, ShouldBeFalse)
+	})
+	Convey("Socket permissions are parsed as octal", t, func() {
+		mode, err := ParseFileMode("660")
+		So(err, ShouldBeNil)
+		So(mode, ShouldEqual, os.FileMode(0660))
+		mode, err = ParseFileMode("")
+		So(err, ShouldBeNil)
+		So(mode, ShouldEqual, 0)
+		_, err = ParseFileMode("0980")
+		So(err, ShouldNotBeNil)
+		_, err = ParseFileMode("1777")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -45,12 +45,30 @@ var (
 	ErrCheckSignature = errors.New("Error checking signature")
 )
 
-//ValidateSignature is exported for plugin authoring
+// ValidateSignature checks the detached signature of the signed file against
+// the given keyring files.  An armored OpenPGP signature is checked against
+// the OpenPGP keyrings and a PEM encoded x509 signature (see
+// validateX509Signature) against the certificate files (.pem, .crt).
 func (s *SigningManager) ValidateSignature(keyringFiles []string, signedFile string, signature []byte) error {
 	var signedby string
 	var e error
 	var checked *openpgp.Entity
 
+	var certFiles, pgpFiles []string
+	for _, f := range keyringFiles {
+		if IsCertificateFile(f) {
+			certFiles = append(certFiles, f)
+		} else {
+			pgpFiles = append(pgpFiles, f)
+		}
+	}
+	if isX509Signature(signature) {
+		return validateX509Signature(certFiles, signedFile, signature)
+	}
+	if len(pgpFiles) == 0 {
+		return ErrKeyringFileNotFound
+	}
+
 	signed, err := os.Open(signedFile)
 	if err != nil {
 		return fmt.Errorf("%v: %v\n%v", ErrSignedFileNotFound, signedFile, err)
@@ -58,7 +76,7 @@ func (s *SigningManager) ValidateSignature(keyringFiles []string, signedFile str
 	defer signed.Close()
 
 	//Go through all the keyrings til either signature is valid or end of keyrings
-	for _, keyringFile := range keyringFiles {
+	for _, keyringFile := range pgpFiles {
 		keyringf, err := os.Open(keyringFile)
 		if err != nil {
 			return fmt.Errorf("%v: %v\n%v", ErrKeyringFileNotFound, keyringFile, err)
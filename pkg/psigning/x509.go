@@ -0,0 +1,172 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package psigning
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// SignatureBlockType - The type of the PEM block holding an x509 plugin signature
+	SignatureBlockType = "SIGNATURE"
+	// CertificateBlockType - The type of the PEM blocks holding certificates
+	CertificateBlockType = "CERTIFICATE"
+)
+
+var (
+	// ErrNoTrustedCertificate - Error message for an x509 signature checked without any trusted certificate
+	ErrNoTrustedCertificate = errors.New("No trusted certificate (.pem, .crt) in the keyring paths")
+	// ErrUnsupportedSigningKey - Error message for a certificate whose key type can not check signatures
+	ErrUnsupportedSigningKey = errors.New("Unsupported signing key, expected RSA or ECDSA")
+)
+
+// IsCertificateFile returns true when the given keyring file holds x509
+// certificates rather than an OpenPGP keyring
+func IsCertificateFile(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".pem", ".crt", ".cer":
+		return true
+	}
+	return false
+}
+
+// isX509Signature returns true when the signature is a PEM encoded x509
+// signature rather than an armored OpenPGP one
+func isX509Signature(signature []byte) bool {
+	block, _ := pem.Decode(signature)
+	return block != nil && block.Type == SignatureBlockType
+}
+
+// validateX509Signature checks a detached signature made with the key of an
+// x509 certificate.  The signature file holds a SIGNATURE PEM block with the
+// SHA-256 signature of the plugin, optionally followed by the certificate of
+// the signer and its intermediates.  The signer is either one of the trusted
+// certificates or a certificate issued by one of them.
+func validateX509Signature(certFiles []string, signedFile string, signature []byte) error {
+	var sig []byte
+	var chain []*x509.Certificate
+	for rest := signature; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case SignatureBlockType:
+			sig = block.Bytes
+		case CertificateBlockType:
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return fmt.Errorf("%v\n%v", ErrCheckSignature, err)
+			}
+			chain = append(chain, cert)
+		}
+	}
+
+	trusted, err := readCertificates(certFiles)
+	if err != nil {
+		return err
+	}
+	if len(trusted) == 0 {
+		return ErrNoTrustedCertificate
+	}
+	signed, err := ioutil.ReadFile(signedFile)
+	if err != nil {
+		return fmt.Errorf("%v: %v\n%v", ErrSignedFileNotFound, signedFile, err)
+	}
+
+	if len(chain) > 0 {
+		roots := x509.NewCertPool()
+		for _, cert := range trusted {
+			roots.AddCert(cert)
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range chain[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := chain[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		if err != nil {
+			return fmt.Errorf("%v\n%v", ErrCheckSignature, err)
+		}
+		if err := checkX509Signature(chain[0], signed, sig); err != nil {
+			return fmt.Errorf("%v\n%v", ErrCheckSignature, err)
+		}
+		return nil
+	}
+
+	for _, cert := range trusted {
+		if err = checkX509Signature(cert, signed, sig); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%v\n%v", ErrCheckSignature, err)
+}
+
+func checkX509Signature(cert *x509.Certificate, signed, sig []byte) error {
+	var algo x509.SignatureAlgorithm
+	switch cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		algo = x509.SHA256WithRSA
+	case *ecdsa.PublicKey:
+		algo = x509.ECDSAWithSHA256
+	default:
+		return ErrUnsupportedSigningKey
+	}
+	return cert.CheckSignature(algo, signed, sig)
+}
+
+// readCertificates returns all the certificates of the given PEM files
+func readCertificates(files []string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v\n%v", ErrKeyringFileNotFound, file, err)
+		}
+		for {
+			var block *pem.Block
+			block, b = pem.Decode(b)
+			if block == nil {
+				break
+			}
+			if block.Type != CertificateBlockType {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v\n%v", ErrUnableToReadKeyring, file, err)
+			}
+			certs = append(certs, cert)
+		}
+	}
+	return certs, nil
+}
@@ -0,0 +1,119 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package psigning
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testSigner struct {
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+	der  []byte
+}
+
+func newTestSigner(cn string, parent *testSigner) *testSigner {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	So(err, ShouldBeNil)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	issuer, issuerKey := tmpl, key
+	if parent != nil {
+		issuer, issuerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, issuer, &key.PublicKey, issuerKey)
+	So(err, ShouldBeNil)
+	cert, err := x509.ParseCertificate(der)
+	So(err, ShouldBeNil)
+	return &testSigner{key: key, cert: cert, der: der}
+}
+
+func (s *testSigner) sign(data []byte, withCert bool) []byte {
+	h := sha256.Sum256(data)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, h[:])
+	So(err, ShouldBeNil)
+	out := pem.EncodeToMemory(&pem.Block{Type: SignatureBlockType, Bytes: sig})
+	if withCert {
+		out = append(out, pem.EncodeToMemory(&pem.Block{Type: CertificateBlockType, Bytes: s.der})...)
+	}
+	return out
+}
+
+func TestValidateX509Signature(t *testing.T) {
+	Convey("Given a plugin signed with an x509 certificate", t, func() {
+		dir, err := ioutil.TempDir("", "psigning")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		plugin := filepath.Join(dir, "snap-plugin-collector-mock")
+		data := []byte("plugin binary")
+		So(ioutil.WriteFile(plugin, data, 0755), ShouldBeNil)
+
+		ca := newTestSigner("snap ca", nil)
+		caFile := filepath.Join(dir, "ca.pem")
+		So(ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: CertificateBlockType, Bytes: ca.der}), 0644), ShouldBeNil)
+		s := SigningManager{}
+
+		Convey("a signature made by a trusted certificate is valid", func() {
+			So(s.ValidateSignature([]string{caFile}, plugin, ca.sign(data, false)), ShouldBeNil)
+		})
+		Convey("a signature made by a certificate issued by a trusted one is valid", func() {
+			leaf := newTestSigner("plugin signer", ca)
+			So(s.ValidateSignature([]string{caFile}, plugin, leaf.sign(data, true)), ShouldBeNil)
+		})
+		Convey("a signature of another file is invalid", func() {
+			err := s.ValidateSignature([]string{caFile}, plugin, ca.sign([]byte("another binary"), false))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, ErrCheckSignature.Error())
+		})
+		Convey("a signature made by an untrusted certificate is invalid", func() {
+			other := newTestSigner("other ca", nil)
+			So(s.ValidateSignature([]string{caFile}, plugin, other.sign(data, false)), ShouldNotBeNil)
+			So(s.ValidateSignature([]string{caFile}, plugin, other.sign(data, true)), ShouldNotBeNil)
+		})
+		Convey("a signature checked without trusted certificate is invalid", func() {
+			err := s.ValidateSignature([]string{"pubring.gpg"}, plugin, ca.sign(data, false))
+			So(err, ShouldEqual, ErrNoTrustedCertificate)
+		})
+	})
+}
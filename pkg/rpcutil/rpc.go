@@ -20,12 +20,14 @@ limitations under the License.
 package rpcutil
 
 import (
-	"fmt"
+	"net"
 	"time"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+
+	"github.com/intelsdi-x/snap/pkg/netutil"
 )
 
 // grpcDialDefaultTimeout is the default timeout for initial gRPC dial
@@ -37,18 +39,31 @@ func GetClientConnection(ctx context.Context, addr string, port int) (*grpc.Clie
 }
 
 // GetClientConnectionWithCreds returns a grcp.ClientConn with optional TLS
-// security (if creds != nil)
-func GetClientConnectionWithCreds(ctx context.Context, addr string, port int, creds credentials.TransportCredentials) (*grpc.ClientConn, error) {
+// security (if creds != nil).  The address may be a host (IPv4, IPv6 or name)
+// combined with the port or a Unix domain socket address, e.g.
+// unix:///var/run/snap/snapteld.sock, in which case the port is not used.
+// The options are added to the ones dialing the connection.
+func GetClientConnectionWithCreds(ctx context.Context, addr string, port int, creds credentials.TransportCredentials, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
 	grpcDialOpts := []grpc.DialOption{
 		grpc.WithTimeout(grpcDialDefaultTimeout),
 	}
+	grpcDialOpts = append(grpcDialOpts, opts...)
 	if creds != nil {
 		grpcDialOpts = append(grpcDialOpts, grpc.WithTransportCredentials(creds))
 	} else {
 		grpcDialOpts = append(grpcDialOpts, grpc.WithInsecure())
 	}
 
-	conn, err := grpc.DialContext(ctx, fmt.Sprintf("%v:%v", addr, port), grpcDialOpts...)
+	target := netutil.JoinHostPort(addr, port)
+	if _, ok := netutil.UnixSocketPath(addr); ok {
+		// the port is not used with Unix domain sockets
+		target = addr
+		grpcDialOpts = append(grpcDialOpts, grpc.WithDialer(func(_ string, timeout time.Duration) (net.Conn, error) {
+			return netutil.Dial(addr, timeout)
+		}))
+	}
+
+	conn, err := grpc.DialContext(ctx, target, grpcDialOpts...)
 	if err != nil {
 		return nil, err
 	}
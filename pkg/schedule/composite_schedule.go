@@ -0,0 +1,216 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schedule
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/pkg/chrono"
+)
+
+// ErrMissingSchedules - The error message for a composite schedule without schedules
+var ErrMissingSchedules = errors.New("Composite schedule has no schedules")
+
+// coincidentFires is how close the fires of several schedules are to be a
+// single fire of the composite schedule
+const coincidentFires = 5 * time.Millisecond
+
+// CompositeSchedule is a schedule firing whenever one of its schedules fires,
+// e.g. every 5 minutes and at midnight.  Each schedule is waited on from its
+// own last fire; the fires of several schedules due at once are a single fire.
+// The schedule ends when all of its schedules have ended.
+type CompositeSchedule struct {
+	Schedules []Schedule
+	state     ScheduleState
+	clock     chrono.Clock
+
+	sync.Mutex
+	// fires receives the responses of the schedules waited on
+	fires   chan compositeFire
+	waiting []bool
+	ended   []bool
+	last    []time.Time
+	// fired is the time the composite schedule last fired at
+	fired time.Time
+}
+
+type compositeFire struct {
+	index    int
+	response Response
+}
+
+// NewCompositeSchedule returns a schedule firing whenever one of the given
+// schedules fires
+func NewCompositeSchedule(schedules ...Schedule) *CompositeSchedule {
+	return &CompositeSchedule{
+		Schedules: schedules,
+	}
+}
+
+// SetClock sets the clock the schedule and its schedules tell the time with
+func (c *CompositeSchedule) SetClock(clock chrono.Clock) {
+	c.clock = clock
+	for _, s := range c.Schedules {
+		if cs, ok := s.(Clocked); ok {
+			cs.SetClock(clock)
+		}
+	}
+}
+
+// GetState returns the state of the CompositeSchedule
+func (c *CompositeSchedule) GetState() ScheduleState {
+	return c.state
+}
+
+// Validate validates each schedule of the CompositeSchedule
+func (c *CompositeSchedule) Validate() error {
+	if len(c.Schedules) == 0 {
+		return ErrMissingSchedules
+	}
+	for _, s := range c.Schedules {
+		if err := s.Validate(); err != nil {
+			return err
+		}
+	}
+	c.state = Active
+	return nil
+}
+
+// Wait waits until one of the schedules fires.  The schedules are waited on
+// until they fire, across the calls; last is ignored since each schedule is
+// waited on from its own last fire.
+func (c *CompositeSchedule) Wait(last time.Time) Response {
+	for {
+		if !c.waitAll() {
+			c.state = Ended
+			return &CompositeScheduleResponse{
+				state:    c.state,
+				lastTime: clockOrReal(c.clock).Now(),
+			}
+		}
+		f := <-c.fires
+		r := &CompositeScheduleResponse{}
+		c.record(f, r)
+		// the schedules which fired meanwhile fire along, once
+	drain:
+		for {
+			select {
+			case f = <-c.fires:
+				c.record(f, r)
+			default:
+				break drain
+			}
+		}
+		if r.err != nil {
+			c.state = Error
+		}
+		if r.err != nil || r.fired {
+			r.state = c.state
+			c.Lock()
+			c.fired = r.lastTime
+			c.Unlock()
+			return r
+		}
+	}
+}
+
+// waitAll waits on the schedules which have not ended and are not waited on
+// yet, and returns false when all of the schedules have ended
+func (c *CompositeSchedule) waitAll() bool {
+	c.Lock()
+	defer c.Unlock()
+	if c.fires == nil {
+		c.fires = make(chan compositeFire, len(c.Schedules))
+		c.waiting = make([]bool, len(c.Schedules))
+		c.ended = make([]bool, len(c.Schedules))
+		c.last = make([]time.Time, len(c.Schedules))
+	}
+	active := false
+	for i, s := range c.Schedules {
+		if c.ended[i] {
+			continue
+		}
+		active = true
+		if c.waiting[i] {
+			continue
+		}
+		c.waiting[i] = true
+		go func(i int, s Schedule, last time.Time) {
+			c.fires <- compositeFire{index: i, response: s.Wait(last)}
+		}(i, s, c.last[i])
+	}
+	return active
+}
+
+// record adds the response of a schedule to the response of the composite
+func (c *CompositeSchedule) record(f compositeFire, r *CompositeScheduleResponse) {
+	c.Lock()
+	defer c.Unlock()
+	c.waiting[f.index] = false
+	switch f.response.State() {
+	case Error:
+		c.ended[f.index] = true
+		r.err = f.response.Error()
+	case Ended:
+		c.ended[f.index] = true
+	default:
+		c.last[f.index] = f.response.LastTime()
+		// a fire along with the last one was part of it
+		if !c.fired.IsZero() && f.response.LastTime().Sub(c.fired) < coincidentFires {
+			return
+		}
+		r.fired = true
+		r.missed += f.response.Missed()
+		if f.response.LastTime().After(r.lastTime) {
+			r.lastTime = f.response.LastTime()
+		}
+	}
+}
+
+// CompositeScheduleResponse is the response from CompositeSchedule
+type CompositeScheduleResponse struct {
+	state    ScheduleState
+	err      error
+	fired    bool
+	missed   uint
+	lastTime time.Time
+}
+
+// State returns the state of the Schedule
+func (c *CompositeScheduleResponse) State() ScheduleState {
+	return c.state
+}
+
+// Error returns last error
+func (c *CompositeScheduleResponse) Error() error {
+	return c.err
+}
+
+// Missed returns any missed intervals
+func (c *CompositeScheduleResponse) Missed() uint {
+	return c.missed
+}
+
+// LastTime returns the last response time
+func (c *CompositeScheduleResponse) LastTime() time.Time {
+	return c.lastTime
+}
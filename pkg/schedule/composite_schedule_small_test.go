@@ -0,0 +1,102 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/pkg/chrono"
+)
+
+type erroredSchedule struct{}
+
+func (erroredSchedule) GetState() ScheduleState { return Active }
+func (erroredSchedule) Validate() error         { return nil }
+func (erroredSchedule) Wait(time.Time) Response {
+	return &CronScheduleResponse{state: Error, err: errors.New("schedule failed")}
+}
+
+func TestCompositeSchedule(t *testing.T) {
+	Convey("A composite schedule without schedules is invalid", t, func() {
+		So(NewCompositeSchedule().Validate(), ShouldEqual, ErrMissingSchedules)
+	})
+	Convey("A composite schedule holding an invalid schedule is invalid", t, func() {
+		c := NewCompositeSchedule(NewWindowedSchedule(time.Minute, nil, nil, 0), NewWindowedSchedule(0, nil, nil, 0))
+		So(c.Validate(), ShouldEqual, ErrInvalidInterval)
+	})
+	Convey("Given a composite schedule on a fake clock", t, func() {
+		start := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := chrono.NewFakeClock(start)
+		c := NewCompositeSchedule(
+			NewWindowedSchedule(time.Minute, nil, nil, 0),
+			NewWindowedSchedule(90*time.Second, nil, nil, 0),
+		)
+		c.SetClock(clock)
+		So(c.Validate(), ShouldBeNil)
+
+		Convey("it fires whenever one of its schedules fires", func() {
+			wait := func(d time.Duration) Response {
+				responses := make(chan Response)
+				go func() { responses <- c.Wait(clock.Now()) }()
+				clock.BlockUntil(2)
+				clock.Advance(d)
+				select {
+				case r := <-responses:
+					return r
+				case <-time.After(time.Second):
+					t.Fatal("the schedule did not fire")
+				}
+				return nil
+			}
+			// both schedules fire at once on the first run
+			r := c.Wait(time.Time{})
+			So(r.State(), ShouldEqual, Active)
+			So(r.LastTime(), ShouldResemble, start)
+
+			r = wait(time.Minute)
+			So(r.State(), ShouldEqual, Active)
+			So(r.LastTime(), ShouldResemble, start.Add(time.Minute))
+			r = wait(30 * time.Second)
+			So(r.LastTime(), ShouldResemble, start.Add(90*time.Second))
+			r = wait(30 * time.Second)
+			So(r.LastTime(), ShouldResemble, start.Add(2*time.Minute))
+			So(r.Missed(), ShouldEqual, 0)
+		})
+	})
+	Convey("A composite schedule errors when one of its schedules errors", t, func() {
+		c := NewCompositeSchedule(NewWindowedSchedule(time.Hour, nil, nil, 0), erroredSchedule{})
+		So(c.Validate(), ShouldBeNil)
+		var r Response
+		for i := 0; i < 3; i++ {
+			if r = c.Wait(time.Time{}); r.State() == Error {
+				break
+			}
+		}
+		So(r.State(), ShouldEqual, Error)
+		So(r.Error(), ShouldNotBeNil)
+		So(c.GetState(), ShouldEqual, Error)
+	})
+}
@@ -23,6 +23,8 @@ import (
 	"time"
 
 	"github.com/robfig/cron"
+
+	"github.com/intelsdi-x/snap/pkg/chrono"
 )
 
 // ErrMissingCronEntry indicates missing cron entry
@@ -34,6 +36,7 @@ type CronSchedule struct {
 	enabled  bool
 	state    ScheduleState
 	schedule *cron.Cron
+	clock    chrono.Clock
 }
 
 // NewCronSchedule creates and starts new cron schedule and returns an instance of CronSchedule
@@ -51,6 +54,11 @@ func (c *CronSchedule) Entry() string {
 	return c.entry
 }
 
+// SetClock sets the clock the schedule tells the time with
+func (c *CronSchedule) SetClock(clock chrono.Clock) {
+	c.clock = clock
+}
+
 // GetState returns state of CronSchedule
 func (c *CronSchedule) GetState() ScheduleState {
 	return c.state
@@ -71,7 +79,8 @@ func (c *CronSchedule) Validate() error {
 // Wait waits as long as specified in cron entry
 func (c *CronSchedule) Wait(last time.Time) Response {
 	var err error
-	now := time.Now()
+	clock := clockOrReal(c.clock)
+	now := clock.Now()
 
 	// first run
 	if (last == time.Time{}) {
@@ -102,14 +111,14 @@ func (c *CronSchedule) Wait(last time.Time) Response {
 
 		// wait
 		waitTime := s.Next(now)
-		time.Sleep(waitTime.Sub(now))
+		<-clock.After(waitTime.Sub(now))
 	}
 
 	return &CronScheduleResponse{
 		state:    c.GetState(),
 		err:      err,
 		missed:   misses,
-		lastTime: time.Now(),
+		lastTime: clock.Now(),
 	}
 }
 
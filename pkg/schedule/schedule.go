@@ -22,6 +22,8 @@ package schedule
 import (
 	"errors"
 	"time"
+
+	"github.com/intelsdi-x/snap/pkg/chrono"
 )
 
 var (
@@ -67,15 +69,31 @@ type Response interface {
 	LastTime() time.Time
 }
 
-func waitOnInterval(last time.Time, i time.Duration) (uint, time.Time) {
+// Clocked is implemented by the schedules which tell the time, and wait for
+// it, with a clock other than the real one
+type Clocked interface {
+	SetClock(chrono.Clock)
+}
+
+// clockOrReal returns the clock, or the real clock when none is set
+func clockOrReal(c chrono.Clock) chrono.Clock {
+	if c == nil {
+		return chrono.Real
+	}
+	return c
+}
+
+// waitOnInterval waits for the first interval after last to fire and returns
+// the intervals missed since last and the time the interval fired at
+func waitOnInterval(c chrono.Clock, last time.Time, i time.Duration) (uint, time.Time) {
 	// first run
 	if (last == time.Time{}) {
 		// for the first run, do not wait on interval
 		// and schedule workflow execution immediately
-		return uint(0), time.Now()
+		return uint(0), c.Now()
 	}
 	// Get the difference in time.Duration since last in nanoseconds (int64)
-	timeDiff := time.Since(last).Nanoseconds()
+	timeDiff := c.Now().Sub(last).Nanoseconds()
 	// cache our schedule interval in nanoseconds
 	nanoInterval := i.Nanoseconds()
 	// use modulo operation to obtain the remainder of time over last interval
@@ -84,6 +102,12 @@ func waitOnInterval(last time.Time, i time.Duration) (uint, time.Time) {
 	missed := (timeDiff - remainder) / nanoInterval // timeDiff.Nanoseconds() % s.Interval.Nanoseconds()
 	waitDuration := nanoInterval - remainder
 	// Wait until predicted interval fires
-	time.Sleep(time.Duration(waitDuration))
-	return uint(missed), time.Now()
+	<-c.After(time.Duration(waitDuration))
+	return uint(missed), last.Add(time.Duration((missed + 1) * nanoInterval))
+}
+
+// alignedBefore returns the last multiple of the interval since the Unix
+// epoch at or before t
+func alignedBefore(t time.Time, i time.Duration) time.Time {
+	return t.Add(-time.Duration(t.UnixNano() % i.Nanoseconds()))
 }
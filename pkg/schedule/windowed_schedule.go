@@ -23,6 +23,8 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/pkg/chrono"
 )
 
 var (
@@ -31,12 +33,20 @@ var (
 
 // WindowedSchedule is a schedule that waits on an interval within a specific time window
 type WindowedSchedule struct {
-	Interval   time.Duration
-	StartTime  *time.Time
-	StopTime   *time.Time
-	Count      uint
+	Interval  time.Duration
+	StartTime *time.Time
+	StopTime  *time.Time
+	Count     uint
+	// Aligned fires the schedule on the multiples of the interval since the
+	// Unix epoch, e.g. a schedule of a minute at the start of every minute
+	Aligned    bool
 	state      ScheduleState
 	stopOnTime *time.Time
+	clock      chrono.Clock
+	// fired is the time the last interval waited on fired at.  The next
+	// interval is timed from it rather than from the time the task fired,
+	// which is later, so that the fires do not drift from the intervals.
+	fired time.Time
 }
 
 // NewWindowedSchedule returns an instance of WindowedSchedule with given interval, start and stop timestamp
@@ -60,20 +70,26 @@ func NewWindowedSchedule(i time.Duration, start *time.Time, stop *time.Time, cou
 	}
 }
 
+// SetClock sets the clock the schedule tells the time with
+func (w *WindowedSchedule) SetClock(c chrono.Clock) {
+	w.clock = c
+}
+
 // setStopOnTime calculates and set the value of the windowed `stopOnTime` which is the right window boundary.
 // `stopOnTime` is determined by `StopTime` or, if it is not provided, calculated based on count and interval.
 func (w *WindowedSchedule) setStopOnTime() {
 	if w.StopTime == nil && w.Count != 0 {
 		// determine the window stop based on the `count` and `interval`
 		var newStop time.Time
+		now := clockOrReal(w.clock).Now()
 
 		// if start is not set or points in the past,
 		// use the current time to calculate stopOnTime
-		if w.StartTime != nil && time.Now().Before(*w.StartTime) {
-			newStop = w.StartTime.Add(time.Duration(w.Count) * w.Interval)
+		if w.StartTime != nil && now.Before(*w.StartTime) {
+			newStop = w.firstFire(*w.StartTime).Add(time.Duration(w.Count) * w.Interval)
 		} else {
 			// set a new stop timestamp from this point in time
-			newStop = time.Now().Add(time.Duration(w.Count) * w.Interval)
+			newStop = w.firstFire(now).Add(time.Duration(w.Count) * w.Interval)
 		}
 		// set calculated new stop
 		w.stopOnTime = &newStop
@@ -92,7 +108,7 @@ func (w *WindowedSchedule) GetState() ScheduleState {
 // Validate validates the start, stop and duration interval of WindowedSchedule
 func (w *WindowedSchedule) Validate() error {
 	// if the stop time was set but it is in the past, return an error
-	if w.StopTime != nil && time.Now().After(*w.StopTime) {
+	if w.StopTime != nil && clockOrReal(w.clock).Now().After(*w.StopTime) {
 		return ErrInvalidStopTime
 	}
 
@@ -117,6 +133,7 @@ func (w *WindowedSchedule) Wait(last time.Time) Response {
 	// If within the window we wait our interval and return
 	// otherwise we exit with a completed state.
 	var m uint
+	clock := clockOrReal(w.clock)
 
 	if (last == time.Time{}) {
 		// the first waiting in cycles, so
@@ -127,28 +144,28 @@ func (w *WindowedSchedule) Wait(last time.Time) Response {
 	// Do we even have a specific start time?
 	if w.StartTime != nil {
 		// Wait till it is time to start if before the window start
-		if time.Now().Before(*w.StartTime) {
-			wait := w.StartTime.Sub(time.Now())
+		if clock.Now().Before(*w.StartTime) {
+			wait := w.StartTime.Sub(clock.Now())
 			logger.WithFields(log.Fields{
 				"_block":         "windowed-wait",
 				"sleep-duration": wait,
 			}).Debug("Waiting for window to start")
-			time.Sleep(wait)
+			<-clock.After(wait)
 		}
 	}
 
 	// Do we even have a stop time?
 	if w.stopOnTime != nil {
-		if time.Now().Before(*w.stopOnTime) {
+		if clock.Now().Before(*w.stopOnTime) {
 			logger.WithFields(log.Fields{
 				"_block":           "windowed-wait",
-				"time-before-stop": w.stopOnTime.Sub(time.Now()),
+				"time-before-stop": w.stopOnTime.Sub(clock.Now()),
 			}).Debug("Within window, calling interval")
 
-			m, _ = waitOnInterval(last, w.Interval)
+			m = w.waitOnInterval(clock, last)
 
 			// check if the schedule should be ended after waiting on interval
-			if time.Now().After(*w.stopOnTime) {
+			if clock.Now().After(*w.stopOnTime) {
 				logger.WithFields(log.Fields{
 					"_block": "windowed-wait",
 				}).Debug("schedule has ended")
@@ -163,14 +180,50 @@ func (w *WindowedSchedule) Wait(last time.Time) Response {
 		}
 	} else {
 		// This has no end like a simple schedule
-		m, _ = waitOnInterval(last, w.Interval)
+		m = w.waitOnInterval(clock, last)
 
 	}
 	return &WindowedScheduleResponse{
 		state:    w.GetState(),
 		missed:   m,
-		lastTime: time.Now(),
+		lastTime: clock.Now(),
+	}
+}
+
+// firstFire returns the time the schedule first fires at when started at t
+func (w *WindowedSchedule) firstFire(t time.Time) time.Time {
+	if !w.Aligned {
+		return t
+	}
+	b := alignedBefore(t, w.Interval)
+	if b.Before(t) {
+		b = b.Add(w.Interval)
+	}
+	return b
+}
+
+// waitOnInterval waits for the next interval to fire.  The intervals are
+// timed from the interval the last fire was for, unless the task fired once
+// the interval after it had passed, e.g. when it was paused.  An aligned
+// schedule waits for the next multiple of the interval instead.
+func (w *WindowedSchedule) waitOnInterval(clock chrono.Clock, last time.Time) uint {
+	if w.Aligned {
+		if (last == time.Time{}) {
+			now := clock.Now()
+			if first := w.firstFire(now); first.After(now) {
+				<-clock.After(first.Sub(now))
+			}
+			return 0
+		}
+		m, _ := waitOnInterval(clock, alignedBefore(last, w.Interval), w.Interval)
+		return m
+	}
+	if (last != time.Time{}) && !w.fired.IsZero() && !last.Before(w.fired) && last.Sub(w.fired) < w.Interval {
+		last = w.fired
 	}
+	m, fired := waitOnInterval(clock, last, w.Interval)
+	w.fired = fired
+	return m
 }
 
 // WindowedScheduleResponse is the response from SimpleSchedule
@@ -28,6 +28,8 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/pkg/chrono"
 )
 
 func TestWindowedScheduleValidation(t *testing.T) {
@@ -91,3 +93,101 @@ func TestWindowedScheduleValidation(t *testing.T) {
 		So(afterMS, ShouldBeLessThan, shouldWait+10)
 	})
 }
+
+func TestWindowedScheduleClock(t *testing.T) {
+	Convey("Given a windowed schedule on a fake clock", t, func() {
+		clock := chrono.NewFakeClock(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+		w := NewWindowedSchedule(time.Minute, nil, nil, 2)
+		w.SetClock(clock)
+		So(w.Validate(), ShouldBeNil)
+
+		Convey("it fires at once then on every interval until its count is reached", func() {
+			r := w.Wait(time.Time{})
+			So(r.State(), ShouldEqual, Active)
+			So(r.LastTime(), ShouldResemble, clock.Now())
+			last := r.LastTime()
+
+			// the schedule is waited on 90s late: one fire was missed and
+			// the next one is 30s away
+			clock.Advance(90 * time.Second)
+			responses := make(chan Response)
+			go func() { responses <- w.Wait(last) }()
+			clock.BlockUntil(1)
+			clock.Advance(30 * time.Second)
+			r = <-responses
+			So(r.State(), ShouldEqual, Active)
+			So(r.Missed(), ShouldEqual, 1)
+			So(r.LastTime(), ShouldResemble, last.Add(2*time.Minute))
+
+			r = w.Wait(r.LastTime())
+			So(r.State(), ShouldEqual, Ended)
+		})
+	})
+	Convey("Given a simple schedule on a fake clock", t, func() {
+		start := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := chrono.NewFakeClock(start)
+		w := NewWindowedSchedule(time.Second, nil, nil, 0)
+		w.SetClock(clock)
+		So(w.Validate(), ShouldBeNil)
+
+		Convey("the fires do not drift when the task fires late", func() {
+			r := w.Wait(time.Time{})
+			So(r.LastTime(), ShouldResemble, start)
+			for i := 1; i <= 3; i++ {
+				// the task fires 5ms after the interval
+				clock.Advance(5 * time.Millisecond)
+				last := clock.Now()
+				responses := make(chan Response)
+				go func() { responses <- w.Wait(last) }()
+				clock.BlockUntil(1)
+				clock.Advance(995 * time.Millisecond)
+				select {
+				case r = <-responses:
+				case <-time.After(time.Second):
+					t.Fatal("the schedule did not fire on the interval")
+				}
+				So(r.Missed(), ShouldEqual, 0)
+				So(r.LastTime(), ShouldResemble, start.Add(time.Duration(i)*time.Second))
+			}
+		})
+		Convey("the fires are timed from the last fire when it is past the next interval", func() {
+			r := w.Wait(time.Time{})
+			clock.Advance(2500 * time.Millisecond)
+			last := clock.Now()
+			responses := make(chan Response)
+			go func() { responses <- w.Wait(last) }()
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+			r = <-responses
+			So(r.Missed(), ShouldEqual, 0)
+			So(r.LastTime(), ShouldResemble, last.Add(time.Second))
+		})
+	})
+	Convey("Given an aligned schedule on a fake clock", t, func() {
+		clock := chrono.NewFakeClock(time.Date(2017, 1, 1, 0, 0, 42, 0, time.UTC))
+		w := NewWindowedSchedule(10*time.Second, nil, nil, 0)
+		w.Aligned = true
+		w.SetClock(clock)
+		So(w.Validate(), ShouldBeNil)
+
+		Convey("it fires on the multiples of the interval", func() {
+			wait := func(last time.Time, d time.Duration) Response {
+				responses := make(chan Response)
+				go func() { responses <- w.Wait(last) }()
+				clock.BlockUntil(1)
+				clock.Advance(d)
+				return <-responses
+			}
+			r := wait(time.Time{}, 8*time.Second)
+			So(r.State(), ShouldEqual, Active)
+			So(r.LastTime(), ShouldResemble, time.Date(2017, 1, 1, 0, 0, 50, 0, time.UTC))
+
+			// the task fires late, the schedule still fires on the boundary
+			clock.Advance(3 * time.Second)
+			r = wait(clock.Now(), 7*time.Second)
+			So(r.State(), ShouldEqual, Active)
+			So(r.Missed(), ShouldEqual, 0)
+			So(r.LastTime(), ShouldResemble, time.Date(2017, 1, 1, 0, 1, 0, 0, time.UTC))
+		})
+	})
+}
@@ -0,0 +1,160 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package secrets resolves the references to secrets found in the config of
+// the plugins, e.g. vault:secret/db#password, with the stores holding them.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// Provider reads the secrets of a secrets store.
+type Provider interface {
+	// Secret returns the value of the key of the secret at the path.
+	Secret(path, key string) (string, error)
+}
+
+// Reference is a reference to a secret, written <provider>:<path>#<key>,
+// e.g. vault:secret/db#password for the key password of the secret at
+// secret/db in Vault.
+type Reference struct {
+	Provider string
+	Path     string
+	Key      string
+}
+
+func (r Reference) String() string {
+	return fmt.Sprintf("%s:%s#%s", r.Provider, r.Path, r.Key)
+}
+
+var referenceRegexp = regexp.MustCompile(`^([a-z][a-z0-9_]*):([^/#:][^#]*)#([^#]+)$`)
+
+// ParseReference returns the reference to a secret written in the value.
+// The values which are not references, e.g. URLs, are reported as such.
+func ParseReference(value string) (Reference, bool) {
+	m := referenceRegexp.FindStringSubmatch(value)
+	if m == nil {
+		return Reference{}, false
+	}
+	return Reference{Provider: m[1], Path: m[2], Key: m[3]}, true
+}
+
+// Check returns an error when the secret a reference refers to must not be
+// read, e.g. when its path is not allowed.
+type Check func(Reference) error
+
+// Resolver resolves the references to secrets with the providers registered
+// under their name.  The values referencing another provider are left as
+// they are.
+type Resolver struct {
+	mutex     sync.RWMutex
+	providers map[string]Provider
+}
+
+// NewResolver returns a resolver without providers.
+func NewResolver() *Resolver {
+	return &Resolver{providers: map[string]Provider{}}
+}
+
+// Register adds the provider of the references starting with name.
+func (r *Resolver) Register(name string, p Provider) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.providers[name] = p
+}
+
+func (r *Resolver) provider(ref Reference) Provider {
+	if r == nil {
+		return nil
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.providers[ref.Provider]
+}
+
+// Resolve returns the value of the secret referenced by the value, and
+// whether the value is a reference.  The reference is checked, when a check
+// is given, before the secret is read.
+func (r *Resolver) Resolve(value string, check Check) (string, bool, error) {
+	ref, ok := ParseReference(value)
+	if !ok {
+		return value, false, nil
+	}
+	p := r.provider(ref)
+	if p == nil {
+		return value, false, nil
+	}
+	if check != nil {
+		if err := check(ref); err != nil {
+			return "", true, fmt.Errorf("cannot resolve %s: %v", ref, err)
+		}
+	}
+	secret, err := p.Secret(ref.Path, ref.Key)
+	if err != nil {
+		return "", true, fmt.Errorf("cannot resolve %s: %v", ref, err)
+	}
+	return secret, true, nil
+}
+
+// ResolveTable returns the config items with the references to secrets
+// replaced by their values, and whether the config holds references.  The
+// table given is left unchanged, so that the references rather than the
+// secrets are kept, e.g. in the manifest of a task.  The references are
+// checked as by Resolve.
+func (r *Resolver) ResolveTable(table map[string]ctypes.ConfigValue, check Check) (map[string]ctypes.ConfigValue, bool, error) {
+	var resolved map[string]ctypes.ConfigValue
+	var failed []string
+	for k, v := range table {
+		s, ok := v.(ctypes.ConfigValueStr)
+		if !ok {
+			continue
+		}
+		secret, isRef, err := r.Resolve(s.Value, check)
+		if !isRef {
+			continue
+		}
+		if resolved == nil {
+			resolved = make(map[string]ctypes.ConfigValue, len(table))
+			for k, v := range table {
+				resolved[k] = v
+			}
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", k, err))
+			continue
+		}
+		resolved[k] = ctypes.ConfigValueStr{Value: secret}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return nil, true, errors.New(strings.Join(failed, "; "))
+	}
+	if resolved == nil {
+		return table, false, nil
+	}
+	return resolved, true, nil
+}
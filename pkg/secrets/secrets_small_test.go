@@ -0,0 +1,181 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+type mockProvider map[string]string
+
+func (m mockProvider) Secret(path, key string) (string, error) {
+	if v, ok := m[path+"#"+key]; ok {
+		return v, nil
+	}
+	return "", errors.New("not found")
+}
+
+func TestParseReference(t *testing.T) {
+	Convey("Parsing references to secrets", t, func() {
+		ref, ok := ParseReference("vault:secret/db#password")
+		So(ok, ShouldBeTrue)
+		So(ref, ShouldResemble, Reference{Provider: "vault", Path: "secret/db", Key: "password"})
+		So(ref.String(), ShouldEqual, "vault:secret/db#password")
+
+		for _, v := range []string{"password", "vault:secret/db", "http://host/path#anchor", "vault:#password", "vault:secret/db#"} {
+			_, ok := ParseReference(v)
+			So(ok, ShouldBeFalse)
+		}
+	})
+}
+
+func TestResolveTable(t *testing.T) {
+	Convey("Given a resolver with a provider", t, func() {
+		r := NewResolver()
+		r.Register("mock", mockProvider{"secret/db#password": "s3cr3t"})
+
+		Convey("the references are replaced in a copy of the config", func() {
+			table := map[string]ctypes.ConfigValue{
+				"user":     ctypes.ConfigValueStr{Value: "snap"},
+				"password": ctypes.ConfigValueStr{Value: "mock:secret/db#password"},
+				"url":      ctypes.ConfigValueStr{Value: "other:secret/db#password"},
+				"port":     ctypes.ConfigValueInt{Value: 5432},
+			}
+			resolved, isRef, err := r.ResolveTable(table, nil)
+			So(err, ShouldBeNil)
+			So(isRef, ShouldBeTrue)
+			So(resolved["password"], ShouldResemble, ctypes.ConfigValueStr{Value: "s3cr3t"})
+			So(resolved["url"], ShouldResemble, ctypes.ConfigValueStr{Value: "other:secret/db#password"})
+			So(resolved["port"], ShouldResemble, ctypes.ConfigValueInt{Value: 5432})
+			So(table["password"], ShouldResemble, ctypes.ConfigValueStr{Value: "mock:secret/db#password"})
+		})
+		Convey("a config without references is returned as it is", func() {
+			table := map[string]ctypes.ConfigValue{"user": ctypes.ConfigValueStr{Value: "snap"}}
+			resolved, isRef, err := r.ResolveTable(table, nil)
+			So(err, ShouldBeNil)
+			So(isRef, ShouldBeFalse)
+			So(resolved, ShouldResemble, table)
+		})
+		Convey("the secrets which cannot be read are reported by config item", func() {
+			_, _, err := r.ResolveTable(map[string]ctypes.ConfigValue{"password": ctypes.ConfigValueStr{Value: "mock:secret/db#passwd"}}, nil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "password: cannot resolve mock:secret/db#passwd: not found")
+		})
+		Convey("the references failing the check are not read", func() {
+			check := func(ref Reference) error {
+				return errors.New("path not allowed")
+			}
+			_, isRef, err := r.ResolveTable(map[string]ctypes.ConfigValue{"password": ctypes.ConfigValueStr{Value: "mock:secret/db#password"}}, check)
+			So(isRef, ShouldBeTrue)
+			So(err.Error(), ShouldEqual, "password: cannot resolve mock:secret/db#password: path not allowed")
+		})
+	})
+}
+
+func TestVault(t *testing.T) {
+	Convey("Given a Vault server", t, func() {
+		reads := map[string]int{}
+		renewed := []interface{}{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Vault-Token") != "token" {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"errors": ["permission denied"]}`))
+				return
+			}
+			reads[r.URL.Path]++
+			switch r.URL.Path {
+			case "/v1/secret/db":
+				w.Write([]byte(`{"lease_duration": 2764800, "data": {"password": "s3cr3t", "port": 5432}}`))
+			case "/v1/kv/data/app":
+				w.Write([]byte(`{"data": {"data": {"api_key": "k3y"}, "metadata": {"version": 2}}}`))
+			case "/v1/database/creds/ro":
+				w.Write([]byte(`{"lease_id": "database/creds/ro/1", "lease_duration": 60, "renewable": true, "data": {"username": "ro-1"}}`))
+			case "/v1/sys/leases/renew":
+				body := map[string]interface{}{}
+				json.NewDecoder(r.Body).Decode(&body)
+				renewed = append(renewed, body["lease_id"])
+				w.Write([]byte(`{"lease_id": "database/creds/ro/1", "lease_duration": 60, "renewable": true}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"errors": []}`))
+			}
+		}))
+		defer server.Close()
+		now := time.Now()
+		v := NewVault(server.URL, "token", time.Minute)
+		v.now = func() time.Time { return now }
+
+		Convey("the secrets are read and cached", func() {
+			s, err := v.Secret("secret/db", "password")
+			So(err, ShouldBeNil)
+			So(s, ShouldEqual, "s3cr3t")
+			s, err = v.Secret("secret/db", "port")
+			So(err, ShouldBeNil)
+			So(s, ShouldEqual, "5432")
+			So(reads["/v1/secret/db"], ShouldEqual, 1)
+
+			Convey("until their TTL when they have no lease", func() {
+				now = now.Add(2 * time.Minute)
+				v.Secret("secret/db", "password")
+				So(reads["/v1/secret/db"], ShouldEqual, 2)
+			})
+		})
+		Convey("the secrets of the key/value engine version 2 are unwrapped", func() {
+			s, err := v.Secret("kv/data/app", "api_key")
+			So(err, ShouldBeNil)
+			So(s, ShouldEqual, "k3y")
+		})
+		Convey("the renewable leases are renewed before they expire", func() {
+			s, err := v.Secret("database/creds/ro", "username")
+			So(err, ShouldBeNil)
+			So(s, ShouldEqual, "ro-1")
+			now = now.Add(30 * time.Second)
+			v.renew()
+			So(renewed, ShouldBeEmpty)
+			now = now.Add(15 * time.Second)
+			v.renew()
+			So(renewed, ShouldResemble, []interface{}{"database/creds/ro/1"})
+			now = now.Add(45 * time.Second)
+			v.Secret("database/creds/ro", "username")
+			So(reads["/v1/database/creds/ro"], ShouldEqual, 1)
+		})
+		Convey("the errors of Vault are reported", func() {
+			_, err := v.Secret("secret/db", "user")
+			So(err.Error(), ShouldEqual, "secret secret/db has no key user")
+			_, err = v.Secret("secret/missing", "user")
+			So(err.Error(), ShouldEqual, "vault responded 404")
+			_, err = NewVault(server.URL, "wrong", time.Minute).Secret("secret/db", "password")
+			So(err.Error(), ShouldEqual, "vault responded 403: permission denied")
+			_, err = NewVault("", "token", time.Minute).Secret("secret/db", "password")
+			So(err, ShouldEqual, ErrVaultNotConfigured)
+		})
+	})
+}
@@ -0,0 +1,301 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// VaultProvider is the name of the references to the secrets held by Vault
+const VaultProvider = "vault"
+
+const (
+	vaultRequestTimeout = 10 * time.Second
+	// how often the leases are checked for renewal
+	vaultRenewInterval = 10 * time.Second
+)
+
+var (
+	// ErrVaultNotConfigured - The error message for a secret read from Vault without its address
+	ErrVaultNotConfigured = errors.New("vault address is not configured")
+
+	vaultLogger = log.WithField("_module", "secrets-vault")
+)
+
+// lease holds a secret read from Vault until it expires
+type lease struct {
+	id        string
+	renewable bool
+	duration  time.Duration
+	expires   time.Time
+	data      map[string]interface{}
+}
+
+// vaultSecret is the response of Vault to the read of a secret or to the
+// renewal of its lease
+type vaultSecret struct {
+	LeaseID       string                 `json:"lease_id"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Renewable     bool                   `json:"renewable"`
+	Data          map[string]interface{} `json:"data"`
+	Errors        []string               `json:"errors"`
+}
+
+// Vault reads the secrets of a HashiCorp Vault server with its HTTP API.
+// The secrets are cached until their lease expires, or for the TTL when
+// they have none, e.g. the secrets of a key/value engine.  Once started, the
+// renewable leases, e.g. of dynamic database credentials, are renewed
+// before they expire so that the secrets given to the plugins stay valid.
+type Vault struct {
+	address string
+	token   string
+	ttl     time.Duration
+	client  *http.Client
+
+	mutex  sync.Mutex
+	leases map[string]*lease
+	stopCh chan struct{}
+	now    func() time.Time
+}
+
+// NewVault returns the provider of the secrets of the Vault server at the
+// address, authenticating with the token.  The secrets without a lease are
+// cached for the ttl.
+func NewVault(address, token string, ttl time.Duration) *Vault {
+	return &Vault{
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+		ttl:     ttl,
+		client:  &http.Client{Timeout: vaultRequestTimeout},
+		leases:  map[string]*lease{},
+		now:     time.Now,
+	}
+}
+
+// Secret returns the value of the key of the secret at the path, read from
+// the cache while its lease is valid.
+func (v *Vault) Secret(path, key string) (string, error) {
+	if v.address == "" {
+		return "", ErrVaultNotConfigured
+	}
+	v.mutex.Lock()
+	l, ok := v.leases[path]
+	v.mutex.Unlock()
+	if !ok || !v.now().Before(l.expires) {
+		var err error
+		if l, err = v.read(path); err != nil {
+			return "", err
+		}
+		v.mutex.Lock()
+		v.leases[path] = l
+		v.mutex.Unlock()
+	}
+	value, ok := l.data[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %s", path, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// read reads the secret at the path
+func (v *Vault) read(path string) (*lease, error) {
+	secret, err := v.do("GET", "/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	data := secret.Data
+	// the key/value engine version 2 wraps the secret with its metadata
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+	l := &lease{
+		id:        secret.LeaseID,
+		renewable: secret.Renewable && secret.LeaseID != "",
+		duration:  time.Duration(secret.LeaseDuration) * time.Second,
+		data:      data,
+	}
+	// the secrets without a lease, e.g. of the key/value engine, are read
+	// again after the TTL to pick up their new versions
+	if l.id == "" || l.duration <= 0 {
+		l.duration = v.ttl
+	}
+	l.expires = v.now().Add(l.duration)
+	return l, nil
+}
+
+// renew renews the leases which expire within a third of their duration,
+// and forgets the secrets whose lease expired or cannot be renewed so that
+// they are read again the next time they are resolved
+func (v *Vault) renew() {
+	v.mutex.Lock()
+	leases := make(map[string]*lease, len(v.leases))
+	for path, l := range v.leases {
+		leases[path] = l
+	}
+	v.mutex.Unlock()
+
+	now := v.now()
+	for path, l := range leases {
+		if !l.renewable {
+			if !now.Before(l.expires) {
+				v.forget(path, l)
+			}
+			continue
+		}
+		if l.expires.Sub(now) > l.duration/3 {
+			continue
+		}
+		body := map[string]interface{}{
+			"lease_id":  l.id,
+			"increment": int(l.duration.Seconds()),
+		}
+		secret, err := v.do("PUT", "/v1/sys/leases/renew", body)
+		if err != nil {
+			vaultLogger.WithFields(log.Fields{
+				"_block": "renew",
+				"path":   path,
+				"error":  err,
+			}).Warn("the lease of the secret cannot be renewed, it will be read again")
+			v.forget(path, l)
+			continue
+		}
+		renewed := *l
+		if secret.LeaseDuration > 0 {
+			renewed.duration = time.Duration(secret.LeaseDuration) * time.Second
+		}
+		renewed.expires = now.Add(renewed.duration)
+		v.mutex.Lock()
+		if v.leases[path] == l {
+			v.leases[path] = &renewed
+		}
+		v.mutex.Unlock()
+		vaultLogger.WithFields(log.Fields{
+			"_block":   "renew",
+			"path":     path,
+			"duration": renewed.duration,
+		}).Debug("lease of the secret renewed")
+	}
+}
+
+// forget removes the lease of the path from the cache, unless it was
+// replaced in the meantime
+func (v *Vault) forget(path string, l *lease) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.leases[path] == l {
+		delete(v.leases, path)
+	}
+}
+
+// do sends the request to Vault and decodes its response
+func (v *Vault) do(method, path string, body interface{}) (*vaultSecret, error) {
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, v.address+path, rd)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	secret := &vaultSecret{}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, secret); err != nil && resp.StatusCode == http.StatusOK {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		if len(secret.Errors) > 0 {
+			return nil, fmt.Errorf("vault responded %d: %s", resp.StatusCode, strings.Join(secret.Errors, ", "))
+		}
+		return nil, fmt.Errorf("vault responded %d", resp.StatusCode)
+	}
+	return secret, nil
+}
+
+// Start renews the leases of the cached secrets until Stop is called.
+func (v *Vault) Start() {
+	if v == nil || v.address == "" {
+		return
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.stopCh != nil {
+		return
+	}
+	v.stopCh = make(chan struct{})
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(vaultRenewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				v.renew()
+			case <-stop:
+				return
+			}
+		}
+	}(v.stopCh)
+}
+
+// Stop stops renewing the leases.
+func (v *Vault) Stop() {
+	if v == nil {
+		return
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.stopCh != nil {
+		close(v.stopCh)
+		v.stopCh = nil
+	}
+}
@@ -0,0 +1,148 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snaptest
+
+import (
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// FromCollectorPlugin runs a collector of the control/plugin package in the
+// test process.  The metrics it exposes are the metric types it returns for
+// the given config, which may be nil.
+func FromCollectorPlugin(name string, version int, p plugin.CollectorPlugin, config *cdata.ConfigDataNode) (Collector, error) {
+	if config == nil {
+		config = cdata.NewNode()
+	}
+	mts, err := p.GetMetricTypes(plugin.ConfigType{ConfigDataNode: config})
+	if err != nil {
+		return nil, err
+	}
+	c := &collectorPlugin{pluginMeta: pluginMeta{name, version}, plugin: p}
+	for _, mt := range mts {
+		c.metrics = append(c.metrics, mt.Namespace())
+	}
+	return c, nil
+}
+
+type collectorPlugin struct {
+	pluginMeta
+	plugin  plugin.CollectorPlugin
+	metrics []core.Namespace
+}
+
+func (c *collectorPlugin) Metrics() []core.Namespace {
+	return c.metrics
+}
+
+func (c *collectorPlugin) CollectMetrics(mts []core.Metric) ([]core.Metric, error) {
+	collected, err := c.plugin.CollectMetrics(metricTypes(mts))
+	if err != nil {
+		return nil, err
+	}
+	return coreMetrics(collected), nil
+}
+
+// FromProcessorPlugin runs a processor of the control/plugin package in the
+// test process; the metrics are handed to it GOB encoded, as by snapteld
+func FromProcessorPlugin(name string, version int, p plugin.ProcessorPlugin) Processor {
+	return &processorPlugin{pluginMeta: pluginMeta{name, version}, plugin: p}
+}
+
+type processorPlugin struct {
+	pluginMeta
+	plugin plugin.ProcessorPlugin
+}
+
+func (p *processorPlugin) ProcessMetrics(mts []core.Metric, config map[string]ctypes.ConfigValue) ([]core.Metric, error) {
+	if len(mts) == 0 {
+		return mts, nil
+	}
+	content, contentType, err := plugin.MarshalMetricTypes(plugin.SnapGOBContentType, metricTypes(mts))
+	if err != nil {
+		return nil, err
+	}
+	contentType, content, err = p.plugin.Process(contentType, content, config)
+	if err != nil {
+		return nil, err
+	}
+	processed, err := plugin.UnmarshallMetricTypes(contentType, content)
+	if err != nil {
+		return nil, err
+	}
+	return coreMetrics(processed), nil
+}
+
+// FromPublisherPlugin runs a publisher of the control/plugin package in the
+// test process; the metrics are handed to it GOB encoded, as by snapteld
+func FromPublisherPlugin(name string, version int, p plugin.PublisherPlugin) Publisher {
+	return &publisherPlugin{pluginMeta: pluginMeta{name, version}, plugin: p}
+}
+
+type publisherPlugin struct {
+	pluginMeta
+	plugin plugin.PublisherPlugin
+}
+
+func (p *publisherPlugin) PublishMetrics(mts []core.Metric, config map[string]ctypes.ConfigValue) error {
+	if len(mts) == 0 {
+		return nil
+	}
+	content, contentType, err := plugin.MarshalMetricTypes(plugin.SnapGOBContentType, metricTypes(mts))
+	if err != nil {
+		return err
+	}
+	return p.plugin.Publish(contentType, content, config)
+}
+
+func metricTypes(mts []core.Metric) []plugin.MetricType {
+	types := make([]plugin.MetricType, len(mts))
+	for i, m := range mts {
+		types[i] = metricType(m)
+	}
+	return types
+}
+
+func metricType(m core.Metric) plugin.MetricType {
+	if mt, ok := m.(plugin.MetricType); ok {
+		return mt
+	}
+	return plugin.MetricType{
+		Namespace_:          m.Namespace(),
+		Version_:            m.Version(),
+		LastAdvertisedTime_: m.LastAdvertisedTime(),
+		Config_:             m.Config(),
+		Data_:               m.Data(),
+		Tags_:               m.Tags(),
+		Description_:        m.Description(),
+		Unit_:               m.Unit(),
+		Timestamp_:          m.Timestamp(),
+	}
+}
+
+func coreMetrics(mts []plugin.MetricType) []core.Metric {
+	metrics := make([]core.Metric, len(mts))
+	for i, m := range mts {
+		metrics[i] = m
+	}
+	return metrics
+}
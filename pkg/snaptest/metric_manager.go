@@ -0,0 +1,325 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snaptest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/core/serror"
+)
+
+var (
+	// ErrUnknownPluginType - The error message for a plugin which is neither a collector, a processor nor a publisher
+	ErrUnknownPluginType = errors.New("Plugin is neither a Collector, a Processor nor a Publisher")
+	// ErrStreamingUnsupported - The error message for the streaming tasks, which are not run in the test process
+	ErrStreamingUnsupported = errors.New("Streaming collectors are not supported")
+)
+
+// MetricManager collects, processes and publishes the metrics of the tasks
+// of a scheduler with the plugins it holds.  A metric requested by a task is
+// collected by the collector of the highest version, or of the requested
+// version, exposing it.  The wildcards of the requested namespaces match any
+// element and the dynamic elements of the exposed namespaces any value.
+type MetricManager struct {
+	mutex      sync.Mutex
+	collectors []Collector
+	processors []Processor
+	publishers []Publisher
+	// subscriptions are the metrics collected for each task
+	subscriptions map[string][]subscription
+}
+
+type subscription struct {
+	collector Collector
+	metrics   []core.Metric
+}
+
+// NewMetricManager returns a metric manager holding the given plugins
+func NewMetricManager(plugins ...Plugin) (*MetricManager, error) {
+	m := &MetricManager{subscriptions: map[string][]subscription{}}
+	for _, p := range plugins {
+		if err := m.Add(p); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Add adds a collector, processor or publisher to the plugins the tasks
+// created afterwards run
+func (m *MetricManager) Add(p Plugin) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	switch v := p.(type) {
+	case Collector:
+		m.collectors = append(m.collectors, v)
+	case Processor:
+		m.processors = append(m.processors, v)
+	case Publisher:
+		m.publishers = append(m.publishers, v)
+	default:
+		return fmt.Errorf("%v: %s", ErrUnknownPluginType, p.Name())
+	}
+	return nil
+}
+
+// CollectMetrics collects the metrics subscribed by the task and adds the
+// tags of the workflow to them
+func (m *MetricManager) CollectMetrics(taskID string, allTags map[string]map[string]string) ([]core.Metric, []error) {
+	m.mutex.Lock()
+	subs := m.subscriptions[taskID]
+	m.mutex.Unlock()
+	var metrics []core.Metric
+	var errs []error
+	for _, s := range subs {
+		mts, err := s.collector.CollectMetrics(s.metrics)
+		if err != nil {
+			errs = append(errs, serror.New(err, map[string]interface{}{
+				"plugin-type":    core.CollectorPluginType.String(),
+				"plugin-name":    s.collector.Name(),
+				"plugin-version": s.collector.Version(),
+			}))
+			continue
+		}
+		for _, mt := range mts {
+			metrics = append(metrics, withTags(mt, allTags))
+		}
+	}
+	return metrics, errs
+}
+
+// StreamMetrics fails, the streaming collectors are not supported
+func (m *MetricManager) StreamMetrics(string, map[string]map[string]string, time.Duration, int64) (chan []core.Metric, chan error, []error) {
+	return nil, nil, []error{ErrStreamingUnsupported}
+}
+
+// ProcessMetrics processes the metrics with the named processor
+func (m *MetricManager) ProcessMetrics(mts []core.Metric, config map[string]ctypes.ConfigValue, taskID, name string, version int) ([]core.Metric, []error) {
+	p := m.processor(name, version)
+	if p == nil {
+		return nil, []error{pluginNotFound(core.ProcessorPluginType, name, version)}
+	}
+	processed, err := p.ProcessMetrics(mts, config)
+	if err != nil {
+		return nil, []error{err}
+	}
+	return processed, nil
+}
+
+// PublishMetrics publishes the metrics with the named publisher
+func (m *MetricManager) PublishMetrics(mts []core.Metric, config map[string]ctypes.ConfigValue, taskID, name string, version int) []error {
+	p := m.publisher(name, version)
+	if p == nil {
+		return []error{pluginNotFound(core.PublisherPluginType, name, version)}
+	}
+	if err := p.PublishMetrics(mts, config); err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+// GetAutodiscoverPaths returns no path, the tasks are not auto discovered
+func (m *MetricManager) GetAutodiscoverPaths() []string {
+	return nil
+}
+
+// ValidateDeps checks that the requested metrics are exposed by a collector
+// and that the processors and publishers of the workflow are held
+func (m *MetricManager) ValidateDeps(mts []core.RequestedMetric, prs []core.SubscribedPlugin, cdt *cdata.ConfigDataTree, _ ...core.SubscribedPluginAssert) []serror.SnapError {
+	_, errs := m.subscribe(mts, prs, cdt)
+	return errs
+}
+
+// SubscribeDeps selects the collectors of the metrics requested by the task
+func (m *MetricManager) SubscribeDeps(taskID string, mts []core.RequestedMetric, prs []core.SubscribedPlugin, cdt *cdata.ConfigDataTree) []serror.SnapError {
+	subs, errs := m.subscribe(mts, prs, cdt)
+	if len(errs) > 0 {
+		return errs
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.subscriptions[taskID] = subs
+	return nil
+}
+
+// UnsubscribeDeps forgets the metrics collected for the task
+func (m *MetricManager) UnsubscribeDeps(taskID string) []serror.SnapError {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.subscriptions, taskID)
+	return nil
+}
+
+func (m *MetricManager) subscribe(mts []core.RequestedMetric, prs []core.SubscribedPlugin, cdt *cdata.ConfigDataTree) ([]subscription, []serror.SnapError) {
+	var errs []serror.SnapError
+	for _, pr := range prs {
+		switch pr.TypeName() {
+		case core.ProcessorPluginType.String():
+			if m.processor(pr.Name(), pr.Version()) == nil {
+				errs = append(errs, serror.New(pluginNotFound(core.ProcessorPluginType, pr.Name(), pr.Version())))
+			}
+		case core.PublisherPluginType.String():
+			if m.publisher(pr.Name(), pr.Version()) == nil {
+				errs = append(errs, serror.New(pluginNotFound(core.PublisherPluginType, pr.Name(), pr.Version())))
+			}
+		}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var subs []subscription
+	byCollector := map[Collector]int{}
+	for _, rm := range mts {
+		var config *cdata.ConfigDataNode
+		if cdt != nil {
+			config = cdt.Get(rm.Namespace().Strings())
+		}
+		found := false
+		for _, c := range m.collectorsOf(rm.Version()) {
+			for _, ns := range c.Metrics() {
+				matched, ok := match(rm.Namespace(), ns)
+				if !ok {
+					continue
+				}
+				found = true
+				i, ok := byCollector[c]
+				if !ok {
+					i = len(subs)
+					byCollector[c] = i
+					subs = append(subs, subscription{collector: c})
+				}
+				subs[i].metrics = append(subs[i].metrics, plugin.MetricType{
+					Namespace_: matched,
+					Version_:   c.Version(),
+					Config_:    config,
+				})
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, serror.New(fmt.Errorf("Metric not found: %s (version: %d)", rm.Namespace(), rm.Version())))
+		}
+	}
+	return subs, errs
+}
+
+// collectorsOf returns the collectors of the given version, or all of them
+// by decreasing version when the version is not set; the manager must be
+// locked
+func (m *MetricManager) collectorsOf(version int) []Collector {
+	var collectors []Collector
+	for _, c := range m.collectors {
+		if version > 0 && c.Version() != version {
+			continue
+		}
+		i := len(collectors)
+		for i > 0 && collectors[i-1].Version() < c.Version() {
+			i--
+		}
+		collectors = append(collectors, nil)
+		copy(collectors[i+1:], collectors[i:])
+		collectors[i] = c
+	}
+	return collectors
+}
+
+func (m *MetricManager) processor(name string, version int) Processor {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var found Processor
+	for _, p := range m.processors {
+		if p.Name() == name && (p.Version() == version || version < 1 && (found == nil || p.Version() > found.Version())) {
+			found = p
+		}
+	}
+	return found
+}
+
+func (m *MetricManager) publisher(name string, version int) Publisher {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var found Publisher
+	for _, p := range m.publishers {
+		if p.Name() == name && (p.Version() == version || version < 1 && (found == nil || p.Version() > found.Version())) {
+			found = p
+		}
+	}
+	return found
+}
+
+func pluginNotFound(t core.PluginType, name string, version int) error {
+	return fmt.Errorf("%s plugin not found: %s (version: %d)", t, name, version)
+}
+
+// match returns the exposed namespace matched by the requested one, with its
+// dynamic elements set to the requested values.  A trailing wildcard of the
+// requested namespace matches any number of elements.
+func match(requested, exposed core.Namespace) (core.Namespace, bool) {
+	n := len(requested)
+	if n > 0 && requested[n-1].Value == "*" && len(exposed) > n {
+		requested, n = requested[:n-1], n-1
+	} else if n != len(exposed) {
+		return nil, false
+	}
+	matched := make(core.Namespace, len(exposed))
+	copy(matched, exposed)
+	for i := 0; i < n; i++ {
+		r, e := requested[i].Value, exposed[i]
+		switch {
+		case e.IsDynamic():
+			if r != "*" {
+				matched[i].Value = r
+			}
+		case r != "*" && r != e.Value:
+			return nil, false
+		}
+	}
+	return matched, true
+}
+
+// withTags adds the tags of the workflow for the namespace of the metric
+func withTags(m core.Metric, allTags map[string]map[string]string) core.Metric {
+	mt := metricType(m)
+	tags := map[string]string{}
+	for k, v := range mt.Tags_ {
+		tags[k] = v
+	}
+	ns := mt.Namespace().String()
+	for prefix, nsTags := range allTags {
+		if ns != prefix && !strings.HasPrefix(ns, strings.TrimSuffix(prefix, "/")+"/") {
+			continue
+		}
+		for k, v := range nsTags {
+			tags[k] = v
+		}
+	}
+	mt.Tags_ = tags
+	return mt
+}
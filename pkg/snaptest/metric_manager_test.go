@@ -0,0 +1,64 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snaptest
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+func TestMatch(t *testing.T) {
+	Convey("Matching requested namespaces with exposed ones", t, func() {
+		foo := core.NewNamespace("intel", "mock", "foo")
+		baz := core.NewNamespace("intel", "mock").AddDynamicElement("host", "name of the host").AddStaticElement("baz")
+		cases := []struct {
+			requested core.Namespace
+			exposed   core.Namespace
+			matched   string
+			ok        bool
+		}{
+			{core.NewNamespace("intel", "mock", "foo"), foo, "/intel/mock/foo", true},
+			{core.NewNamespace("intel", "mock", "bar"), foo, "", false},
+			{core.NewNamespace("intel", "mock", "*"), foo, "/intel/mock/foo", true},
+			{core.NewNamespace("intel", "*"), foo, "/intel/mock/foo", true},
+			{core.NewNamespace("intel", "mock"), foo, "", false},
+			{core.NewNamespace("intel", "mock", "host1", "baz"), baz, "/intel/mock/host1/baz", true},
+			{core.NewNamespace("intel", "mock", "*", "baz"), baz, "/intel/mock/*/baz", true},
+			{core.NewNamespace("intel", "mock", "host1", "qux"), baz, "", false},
+		}
+		for _, c := range cases {
+			matched, ok := match(c.requested, c.exposed)
+			So(ok, ShouldEqual, c.ok)
+			if ok {
+				So(matched.String(), ShouldEqual, c.matched)
+			}
+		}
+		Convey("the dynamic elements are kept", func() {
+			matched, _ := match(core.NewNamespace("intel", "mock", "host1", "baz"), baz)
+			So(matched[2].IsDynamic(), ShouldBeTrue)
+			So(baz[2].Value, ShouldEqual, "*")
+		})
+	})
+}
@@ -0,0 +1,234 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package snaptest runs Snap tasks with plugins running in the test process,
+// so that plugins and workflows are tested together without compiled plugin
+// binaries nor PULSE_PATH.  The tasks of a Scheduler collect, process and
+// publish with the given collectors, processors and publishers: the mock
+// plugins of this package, plugins written for this package or the plugins
+// of the control/plugin package wrapped by FromCollectorPlugin,
+// FromProcessorPlugin and FromPublisherPlugin.
+package snaptest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// Plugin is a plugin run in the test process
+type Plugin interface {
+	Name() string
+	Version() int
+}
+
+// Collector is a collector plugin run in the test process
+type Collector interface {
+	Plugin
+	// Metrics returns the namespaces of the metrics the collector exposes
+	Metrics() []core.Namespace
+	// CollectMetrics collects the requested metrics, whose config is the
+	// config of the task for their namespace
+	CollectMetrics([]core.Metric) ([]core.Metric, error)
+}
+
+// Processor is a processor plugin run in the test process
+type Processor interface {
+	Plugin
+	ProcessMetrics([]core.Metric, map[string]ctypes.ConfigValue) ([]core.Metric, error)
+}
+
+// Publisher is a publisher plugin run in the test process
+type Publisher interface {
+	Plugin
+	PublishMetrics([]core.Metric, map[string]ctypes.ConfigValue) error
+}
+
+type pluginMeta struct {
+	name    string
+	version int
+}
+
+func (p pluginMeta) Name() string {
+	return p.name
+}
+
+func (p pluginMeta) Version() int {
+	return p.version
+}
+
+// MockCollector is a collector exposing the given metrics, whose data is the
+// number of collections done so far
+type MockCollector struct {
+	pluginMeta
+	metrics []core.Namespace
+
+	mutex       sync.Mutex
+	collections int
+	err         error
+}
+
+// NewMockCollector returns a mock collector exposing the metrics of the given
+// namespaces, e.g. "/intel/mock/foo"
+func NewMockCollector(name string, version int, namespaces ...string) *MockCollector {
+	c := &MockCollector{pluginMeta: pluginMeta{name, version}}
+	for _, ns := range namespaces {
+		c.metrics = append(c.metrics, core.NewNamespace(strings.Split(strings.Trim(ns, "/"), "/")...))
+	}
+	return c
+}
+
+// Metrics returns the namespaces of the metrics the collector exposes
+func (c *MockCollector) Metrics() []core.Namespace {
+	return c.metrics
+}
+
+// CollectMetrics returns the requested metrics with the number of
+// collections as data, or the error the collector fails with
+func (c *MockCollector) CollectMetrics(mts []core.Metric) ([]core.Metric, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.collections++
+	metrics := make([]core.Metric, len(mts))
+	for i, m := range mts {
+		metrics[i] = plugin.MetricType{
+			Namespace_: m.Namespace(),
+			Version_:   c.version,
+			Config_:    m.Config(),
+			Data_:      c.collections,
+			Timestamp_: time.Now(),
+		}
+	}
+	return metrics, nil
+}
+
+// Fail makes the collections fail with the given error; nil ends the
+// failures
+func (c *MockCollector) Fail(err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.err = err
+}
+
+// Collections returns the number of collections done
+func (c *MockCollector) Collections() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.collections
+}
+
+// Passthru is a processor returning the metrics unchanged
+type Passthru struct {
+	pluginMeta
+}
+
+// NewPassthru returns a processor returning the metrics unchanged
+func NewPassthru(name string, version int) *Passthru {
+	return &Passthru{pluginMeta{name, version}}
+}
+
+// ProcessMetrics returns the metrics unchanged
+func (p *Passthru) ProcessMetrics(mts []core.Metric, _ map[string]ctypes.ConfigValue) ([]core.Metric, error) {
+	return mts, nil
+}
+
+// Recorder is a publisher recording the metrics published, for the tests to
+// check them
+type Recorder struct {
+	pluginMeta
+
+	mutex     sync.Mutex
+	published []core.Metric
+	configs   []map[string]ctypes.ConfigValue
+	err       error
+	// recorded is signaled every time metrics are published
+	recorded *sync.Cond
+}
+
+// NewRecorder returns a publisher recording the metrics published
+func NewRecorder(name string, version int) *Recorder {
+	r := &Recorder{pluginMeta: pluginMeta{name, version}}
+	r.recorded = sync.NewCond(&r.mutex)
+	return r
+}
+
+// PublishMetrics records the metrics, or fails with the error set by Fail
+func (r *Recorder) PublishMetrics(mts []core.Metric, config map[string]ctypes.ConfigValue) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.published = append(r.published, mts...)
+	r.configs = append(r.configs, config)
+	r.recorded.Broadcast()
+	return nil
+}
+
+// Fail makes the publications fail with the given error; nil ends the
+// failures
+func (r *Recorder) Fail(err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.err = err
+}
+
+// Published returns the metrics published so far, in order
+func (r *Recorder) Published() []core.Metric {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]core.Metric{}, r.published...)
+}
+
+// Configs returns the config of every publication so far, in order
+func (r *Recorder) Configs() []map[string]ctypes.ConfigValue {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]map[string]ctypes.ConfigValue{}, r.configs...)
+}
+
+// WaitFor waits until at least n metrics were published and returns them,
+// or returns an error once the timeout elapsed
+func (r *Recorder) WaitFor(n int, timeout time.Duration) ([]core.Metric, error) {
+	timedOut := false
+	timer := time.AfterFunc(timeout, func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		timedOut = true
+		r.recorded.Broadcast()
+	})
+	defer timer.Stop()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for len(r.published) < n && !timedOut {
+		r.recorded.Wait()
+	}
+	if len(r.published) < n {
+		return nil, fmt.Errorf("%d metrics published in %v, expected %d", len(r.published), timeout, n)
+	}
+	return append([]core.Metric{}, r.published...), nil
+}
@@ -0,0 +1,105 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snaptest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest/api"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// Scheduler is a started scheduler whose tasks collect, process and publish
+// with the plugins of its metric manager
+type Scheduler struct {
+	api.Tasks
+	// Plugins holds the plugins the tasks run
+	Plugins *MetricManager
+	stop    func()
+}
+
+// NewScheduler starts a scheduler with the default configuration, whose
+// tasks run the given plugins
+func NewScheduler(plugins ...Plugin) (*Scheduler, error) {
+	return NewSchedulerWithConfig(scheduler.GetDefaultConfig(), plugins...)
+}
+
+// NewSchedulerWithConfig starts a scheduler with the given configuration,
+// whose tasks run the given plugins
+func NewSchedulerWithConfig(cfg *scheduler.Config, plugins ...Plugin) (*Scheduler, error) {
+	mm, err := NewMetricManager(plugins...)
+	if err != nil {
+		return nil, err
+	}
+	s := scheduler.New(cfg)
+	s.SetMetricManager(mm)
+	if err := s.Start(); err != nil {
+		return nil, err
+	}
+	return &Scheduler{Tasks: s, Plugins: mm, stop: s.Stop}, nil
+}
+
+// Stop stops the tasks, the workers running their jobs and the scheduler
+func (s *Scheduler) Stop() {
+	s.stop()
+}
+
+// RunTask creates a task with the given schedule and workflow and starts it
+func (s *Scheduler) RunTask(sch schedule.Schedule, w *wmap.WorkflowMap, opts ...core.TaskOption) (core.Task, error) {
+	t, errs := s.CreateTask(sch, w, true, opts...)
+	if errs != nil && len(errs.Errors()) > 0 {
+		return nil, snapErrors(errs.Errors())
+	}
+	return t, nil
+}
+
+// WaitForRuns waits until the task ran at least n times, or returns an
+// error once the timeout elapsed
+func (s *Scheduler) WaitForRuns(id string, n uint, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		t, err := s.GetTask(id)
+		if err != nil {
+			return err
+		}
+		if t.HitCount() >= n {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("task %s ran %d times in %v, expected %d", id, t.HitCount(), timeout, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func snapErrors(errs []serror.SnapError) error {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
@@ -0,0 +1,125 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snaptest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/plugin/collector/snap-plugin-collector-mock1/mock"
+	"github.com/intelsdi-x/snap/plugin/processor/snap-plugin-processor-passthru/passthru"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func TestScheduler(t *testing.T) {
+	Convey("Given a scheduler running mock plugins", t, func() {
+		collector := NewMockCollector("mock", 1, "/intel/mock/foo", "/intel/mock/bar")
+		recorder := NewRecorder("recorder", 1)
+		s, err := NewScheduler(collector, NewPassthru("passthru", 1), recorder)
+		So(err, ShouldBeNil)
+		defer s.Stop()
+
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/intel/mock/*", 0)
+		w.Collect.AddConfigItem("/intel/mock", "user", "root")
+		w.Collect.Tags = map[string]map[string]string{"/intel/mock": {"env": "test"}}
+		pr := wmap.NewProcessNode("passthru", 1)
+		pu := wmap.NewPublishNode("recorder", 1)
+		pu.AddConfigItem("file", "/tmp/published")
+		pr.Add(pu)
+		w.Collect.Add(pr)
+
+		Convey("the metrics of a task go through its workflow", func() {
+			tsk, err := s.RunTask(schedule.NewWindowedSchedule(10*time.Millisecond, nil, nil, 0), w)
+			So(err, ShouldBeNil)
+			So(s.WaitForRuns(tsk.ID(), 2, 5*time.Second), ShouldBeNil)
+			mts, err := recorder.WaitFor(4, 5*time.Second)
+			So(err, ShouldBeNil)
+			So(mts[0].Namespace().String(), ShouldEqual, "/intel/mock/foo")
+			So(mts[1].Namespace().String(), ShouldEqual, "/intel/mock/bar")
+			So(mts[2].Data(), ShouldEqual, 2)
+			So(mts[0].Tags()["env"], ShouldEqual, "test")
+			So(mts[0].Config().Table()["user"], ShouldResemble, ctypes.ConfigValueStr{Value: "root"})
+			So(recorder.Configs()[0]["file"], ShouldResemble, ctypes.ConfigValueStr{Value: "/tmp/published"})
+		})
+		Convey("the failures of the plugins fail the runs", func() {
+			collector.Fail(errors.New("collector down"))
+			tsk, err := s.RunTask(schedule.NewWindowedSchedule(10*time.Millisecond, nil, nil, 0), w)
+			So(err, ShouldBeNil)
+			So(s.WaitForRuns(tsk.ID(), 2, 5*time.Second), ShouldBeNil)
+			So(tsk.FailedCount(), ShouldBeGreaterThan, 0)
+			So(tsk.LastFailureMessage(), ShouldContainSubstring, "collector down")
+			So(recorder.Published(), ShouldBeEmpty)
+		})
+		Convey("a task requesting metrics no collector exposes is not created", func() {
+			w := wmap.NewWorkflowMap()
+			w.Collect.AddMetric("/intel/other/foo", 0)
+			w.Collect.Add(wmap.NewPublishNode("recorder", 1))
+			_, err := s.RunTask(schedule.NewWindowedSchedule(time.Second, nil, nil, 0), w)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "/intel/other/foo")
+		})
+		Convey("a task using a missing publisher is not created", func() {
+			w := wmap.NewWorkflowMap()
+			w.Collect.AddMetric("/intel/mock/foo", 0)
+			w.Collect.Add(wmap.NewPublishNode("influxdb", 0))
+			_, err := s.RunTask(schedule.NewWindowedSchedule(time.Second, nil, nil, 0), w)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "influxdb")
+		})
+	})
+	Convey("Given a scheduler running plugins of the control/plugin package", t, func() {
+		collector, err := FromCollectorPlugin("mock", 1, &mock.Mock{}, nil)
+		So(err, ShouldBeNil)
+		recorder := NewRecorder("recorder", 1)
+		s, err := NewScheduler(collector, FromProcessorPlugin("passthru", 1, passthru.NewPassthruProcessor()), recorder)
+		So(err, ShouldBeNil)
+		defer s.Stop()
+
+		Convey("the metrics of a task go through its workflow", func() {
+			w := wmap.NewWorkflowMap()
+			w.Collect.AddMetric("/intel/mock/foo", 0)
+			w.Collect.AddMetric("/intel/mock/host0/baz", 0)
+			w.Collect.AddConfigItem("/intel/mock", "password", "secret")
+			pr := wmap.NewProcessNode("passthru", 0)
+			pr.Add(wmap.NewPublishNode("recorder", 0))
+			w.Collect.Add(pr)
+			_, err := s.RunTask(schedule.NewWindowedSchedule(time.Second, nil, nil, 1), w)
+			So(err, ShouldBeNil)
+			mts, err := recorder.WaitFor(2, 5*time.Second)
+			So(err, ShouldBeNil)
+			byNamespace := map[string]core.Metric{}
+			for _, m := range mts {
+				byNamespace[m.Namespace().String()] = m
+			}
+			So(byNamespace, ShouldContainKey, "/intel/mock/foo")
+			So(byNamespace["/intel/mock/foo"].Data(), ShouldContainSubstring, "password={secret}")
+			So(byNamespace, ShouldContainKey, "/intel/mock/host0/baz")
+		})
+	})
+}
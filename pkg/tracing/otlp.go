@@ -0,0 +1,180 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// otlpTracesPath is the path the traces are posted to when the URL of
+	// the collector has none
+	otlpTracesPath = "/v1/traces"
+	// instrumentationScope names the code creating the spans
+	instrumentationScope = "github.com/intelsdi-x/snap"
+
+	spanKindInternal = 1
+	statusCodeOK     = 1
+	statusCodeError  = 2
+)
+
+// OTLPExporter posts the spans to an OpenTelemetry collector with the OTLP
+// protocol over HTTP, encoded in JSON.
+type OTLPExporter struct {
+	url     string
+	service string
+	client  *http.Client
+}
+
+// NewOTLPExporter returns an exporter posting the spans to the collector at
+// the given URL, e.g. http://localhost:4318, on behalf of the named service.
+func NewOTLPExporter(endpoint, service string, timeout time.Duration) (*OTLPExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("Unsupported OTLP endpoint scheme '%s', expected http or https", u.Scheme)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = otlpTracesPath
+	}
+	return &OTLPExporter{
+		url:     u.String(),
+		service: service,
+		client:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Export posts the spans to the collector
+func (e *OTLPExporter) Export(spans []*Span) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("OTLP collector answered %s", resp.Status)
+	}
+	return nil
+}
+
+// The OTLP/JSON encoding of ExportTraceServiceRequest
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+func (e *OTLPExporter) request(spans []*Span) otlpRequest {
+	encoded := make([]otlpSpan, len(spans))
+	for i, s := range spans {
+		encoded[i] = otlpSpan{
+			TraceID:           s.Context.TraceID.String(),
+			SpanID:            s.Context.SpanID.String(),
+			Name:              s.Name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        attributes(s.Attributes),
+			Status:            otlpStatus{Code: statusCodeOK},
+		}
+		if s.Parent.IsValid() {
+			encoded[i].ParentSpanID = s.Parent.String()
+		}
+		if s.Error != "" {
+			encoded[i].Status = otlpStatus{Code: statusCodeError, Message: s.Error}
+		}
+	}
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: attributes(map[string]string{"service.name": e.service}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: instrumentationScope},
+				Spans: encoded,
+			}},
+		}},
+	}
+}
+
+func attributes(m map[string]string) []otlpAttribute {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]otlpAttribute, len(keys))
+	for i, k := range keys {
+		attrs[i] = otlpAttribute{Key: k, Value: otlpValue{StringValue: m[k]}}
+	}
+	return attrs
+}
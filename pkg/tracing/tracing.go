@@ -0,0 +1,342 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing records the spans of the task runs of snapteld and exports
+// them in batches, e.g. to an OpenTelemetry collector with OTLPExporter.  The
+// trace context is propagated to the plugins in the W3C traceparent format.
+//
+// A nil *Tracer and a nil *Span are valid and record nothing, so that the
+// instrumented code does not need to check whether tracing is enabled.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mrand "math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// batchSize is the number of spans exported at once
+	batchSize = 512
+	// queueSize is the number of finished spans waiting to be exported;
+	// the spans finished when the queue is full are dropped
+	queueSize = 4096
+	// exportInterval is the longest time a finished span waits to be exported
+	exportInterval = 5 * time.Second
+)
+
+var (
+	// ErrInvalidTraceparent - The error message for a malformed traceparent
+	ErrInvalidTraceparent = errors.New("Invalid traceparent")
+)
+
+var tracingLogger = log.WithField("_module", "tracing")
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+// IsValid returns true when the trace ID is not all zeros
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+// IsValid returns true when the span ID is not all zeros
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// SpanContext is the part of a span propagated to its children, including
+// the ones created by the plugins.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+// IsValid returns true when the context identifies a span
+func (c SpanContext) IsValid() bool {
+	return c.TraceID.IsValid() && c.SpanID.IsValid()
+}
+
+// Traceparent returns the context in the W3C traceparent format; only the
+// sampled spans are recorded, so the sampled flag is always set.
+func (c SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", c.TraceID, c.SpanID)
+}
+
+// ParseTraceparent returns the span context of a W3C traceparent
+func ParseTraceparent(s string) (SpanContext, error) {
+	var c SpanContext
+	parts := strings.Split(s, "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[3]) != 2 {
+		return c, ErrInvalidTraceparent
+	}
+	if !decodeID(c.TraceID[:], parts[1]) || !decodeID(c.SpanID[:], parts[2]) || !c.IsValid() {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	return c, nil
+}
+
+func decodeID(id []byte, s string) bool {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(id) {
+		return false
+	}
+	copy(id, b)
+	return true
+}
+
+// Exporter sends finished spans to a tracing backend.
+type Exporter interface {
+	Export(spans []*Span) error
+}
+
+// Tracer creates spans and hands them to its exporter in batches once they
+// are finished.
+type Tracer struct {
+	// dropped is first to be 64-bit aligned for the atomic operations
+	dropped uint64
+
+	sampleRatio float64
+	exporter    Exporter
+
+	queue chan *Span
+	quit  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewTracer returns a tracer recording the given ratio of the traces, from
+// 0 to 1, and exporting their spans with the exporter.
+func NewTracer(sampleRatio float64, exporter Exporter) *Tracer {
+	t := &Tracer{
+		sampleRatio: sampleRatio,
+		exporter:    exporter,
+		queue:       make(chan *Span, queueSize),
+		quit:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// Start starts the root span of a new trace.  It returns nil when the trace
+// is not sampled.
+func (t *Tracer) Start(name string) *Span {
+	if t == nil || t.sampleRatio <= 0 || (t.sampleRatio < 1 && mrand.Float64() >= t.sampleRatio) {
+		return nil
+	}
+	var c SpanContext
+	rand.Read(c.TraceID[:])
+	rand.Read(c.SpanID[:])
+	return newSpan(t, name, c, SpanID{})
+}
+
+// StartChild starts a span of the trace of the given parent, which may be
+// the context of a span created by another process.  It returns nil when the
+// parent is not valid.
+func (t *Tracer) StartChild(name string, parent SpanContext) *Span {
+	if t == nil || !parent.IsValid() {
+		return nil
+	}
+	c := SpanContext{TraceID: parent.TraceID}
+	rand.Read(c.SpanID[:])
+	return newSpan(t, name, c, parent.SpanID)
+}
+
+// Dropped returns the number of finished spans dropped because they could
+// not be exported fast enough.
+func (t *Tracer) Dropped() uint64 {
+	if t == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&t.dropped)
+}
+
+// Stop exports the finished spans and stops the tracer.
+func (t *Tracer) Stop() {
+	if t == nil {
+		return
+	}
+	t.once.Do(func() {
+		close(t.quit)
+	})
+	<-t.done
+}
+
+func (t *Tracer) finished(s *Span) {
+	select {
+	case t.queue <- s:
+	default:
+		atomic.AddUint64(&t.dropped, 1)
+	}
+}
+
+// run exports the finished spans in batches until the tracer is stopped
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	batch := make([]*Span, 0, batchSize)
+	for {
+		select {
+		case s := <-t.queue:
+			if batch = append(batch, s); len(batch) == batchSize {
+				batch = t.export(batch)
+			}
+		case <-ticker.C:
+			batch = t.export(batch)
+		case <-t.quit:
+			for {
+				select {
+				case s := <-t.queue:
+					batch = append(batch, s)
+				default:
+					t.export(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (t *Tracer) export(batch []*Span) []*Span {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := t.exporter.Export(batch); err != nil {
+		tracingLogger.WithFields(log.Fields{
+			"_block": "export",
+			"_error": err.Error(),
+			"spans":  len(batch),
+		}).Warn("unable to export spans")
+	}
+	return make([]*Span, 0, batchSize)
+}
+
+// Span is a timed operation of a trace.  Its fields must not be changed
+// once it is finished.
+type Span struct {
+	tracer *Tracer
+
+	Name       string
+	Context    SpanContext
+	Parent     SpanID
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+	// Error is the error the operation failed with, if any
+	Error string
+
+	mutex sync.Mutex
+}
+
+func newSpan(t *Tracer, name string, c SpanContext, parent SpanID) *Span {
+	return &Span{
+		tracer:     t,
+		Name:       name,
+		Context:    c,
+		Parent:     parent,
+		Start:      time.Now(),
+		Attributes: map[string]string{},
+	}
+}
+
+// SpanContext returns the context of the span, which is not valid for a nil
+// span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.Context
+}
+
+// Child starts a span of the same trace, child of this span.
+func (s *Span) Child(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	return s.tracer.StartChild(name, s.Context)
+}
+
+// SetAttribute describes the operation of the span.
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Attributes[key] = value
+}
+
+// SetErrors marks the operation of the span as failed with the errors.
+func (s *Span) SetErrors(errs ...error) {
+	if s == nil || len(errs) == 0 {
+		return
+	}
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.Error != "" {
+		msgs = append([]string{s.Error}, msgs...)
+	}
+	s.Error = strings.Join(msgs, "; ")
+}
+
+// Finish ends the span and queues it to be exported.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.End = time.Now()
+	s.mutex.Unlock()
+	s.tracer.finished(s)
+}
+
+type spanKey struct{}
+
+// NewContext returns a copy of the context carrying the span, so that the
+// calls made for the operation of the span can record their own spans as its
+// children.
+func NewContext(ctx context.Context, s *Span) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// FromContext returns the span carried by the context, nil if none.
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
@@ -0,0 +1,147 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type recorder struct {
+	sync.Mutex
+	spans []*Span
+}
+
+func (r *recorder) Export(spans []*Span) error {
+	r.Lock()
+	defer r.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func TestTraceparent(t *testing.T) {
+	Convey("Span contexts round trip through the traceparent format", t, func() {
+		tr := NewTracer(1, &recorder{})
+		defer tr.Stop()
+		c := tr.Start("root").SpanContext()
+		parsed, err := ParseTraceparent(c.Traceparent())
+		So(err, ShouldBeNil)
+		So(parsed, ShouldResemble, c)
+	})
+	Convey("Malformed traceparents are rejected", t, func() {
+		for _, s := range []string{
+			"",
+			"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331",
+			"ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+			"00-0af7651916cd43dd8448eb211c80319c-b7ad6b71692033-01",
+		} {
+			_, err := ParseTraceparent(s)
+			So(err, ShouldEqual, ErrInvalidTraceparent)
+		}
+	})
+}
+
+func TestTracer(t *testing.T) {
+	Convey("Given a tracer", t, func() {
+		rec := &recorder{}
+		tr := NewTracer(1, rec)
+
+		Convey("finished spans are exported when it stops", func() {
+			root := tr.Start("root")
+			child := root.Child("child")
+			child.SetAttribute("k", "v")
+			child.SetErrors(errors.New("failed"))
+			child.Finish()
+			root.Finish()
+			tr.Stop()
+			So(len(rec.spans), ShouldEqual, 2)
+			So(rec.spans[0].Name, ShouldEqual, "child")
+			So(rec.spans[0].Parent, ShouldEqual, root.Context.SpanID)
+			So(rec.spans[0].Context.TraceID, ShouldEqual, root.Context.TraceID)
+			So(rec.spans[0].Attributes["k"], ShouldEqual, "v")
+			So(rec.spans[0].Error, ShouldEqual, "failed")
+		})
+		Convey("spans are only started for valid parents", func() {
+			So(tr.StartChild("orphan", SpanContext{}), ShouldBeNil)
+			tr.Stop()
+		})
+	})
+	Convey("A tracer sampling nothing starts no span", t, func() {
+		tr := NewTracer(0, &recorder{})
+		defer tr.Stop()
+		So(tr.Start("root"), ShouldBeNil)
+	})
+	Convey("Nil tracers and spans record nothing", t, func() {
+		var tr *Tracer
+		span := tr.Start("root")
+		So(span, ShouldBeNil)
+		So(span.Child("child"), ShouldBeNil)
+		So(span.SpanContext().IsValid(), ShouldBeFalse)
+		span.SetAttribute("k", "v")
+		span.Finish()
+		tr.Stop()
+	})
+}
+
+func TestOTLPExporter(t *testing.T) {
+	Convey("Given an OTLP collector", t, func() {
+		var path string
+		var body map[string]interface{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			b, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(b, &body)
+		}))
+		defer srv.Close()
+
+		Convey("spans are posted to the traces path", func() {
+			e, err := NewOTLPExporter(srv.URL, "snapteld", time.Second)
+			So(err, ShouldBeNil)
+			tr := NewTracer(1, e)
+			span := tr.Start("task.run")
+			span.SetErrors(errors.New("failed"))
+			span.Finish()
+			tr.Stop()
+			So(path, ShouldEqual, "/v1/traces")
+			rs := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+			spans := rs["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+			So(len(spans), ShouldEqual, 1)
+			s := spans[0].(map[string]interface{})
+			So(s["name"], ShouldEqual, "task.run")
+			So(s["traceId"], ShouldEqual, span.Context.TraceID.String())
+			So(s["status"].(map[string]interface{})["code"], ShouldEqual, statusCodeError)
+		})
+		Convey("endpoints which are not HTTP are rejected", func() {
+			_, err := NewOTLPExporter("grpc://localhost:4317", "snapteld", time.Second)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
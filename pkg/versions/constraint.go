@@ -0,0 +1,150 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package versions resolves plugin versions against version constraints.
+package versions
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrEmptyConstraint - The error message for an empty version constraint
+	ErrEmptyConstraint = errors.New("Version constraint is empty")
+)
+
+// Latest is the version constraint matching any version of a plugin,
+// which resolves to the latest loaded one.
+const Latest = "latest"
+
+var versionOperators = []string{">=", "<=", "!=", ">", "<", "="}
+
+type versionTerm struct {
+	op      string
+	version int
+}
+
+func (t versionTerm) match(v int) bool {
+	switch t.op {
+	case ">=":
+		return v >= t.version
+	case "<=":
+		return v <= t.version
+	case "!=":
+		return v != t.version
+	case ">":
+		return v > t.version
+	case "<":
+		return v < t.version
+	default:
+		return v == t.version
+	}
+}
+
+// Constraint restricts the plugin versions a metric can be collected
+// from.  A constraint is a list of alternatives separated by "||"; each
+// alternative is a list of terms separated by spaces or commas which must
+// all match (e.g. ">=2 <4 || 6").  The constraint "latest" matches any
+// version.
+type Constraint struct {
+	text         string
+	alternatives [][]versionTerm
+}
+
+// ParseConstraint parses the given version constraint
+func ParseConstraint(s string) (*Constraint, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, ErrEmptyConstraint
+	}
+	vc := &Constraint{text: s}
+	if s == Latest || s == "*" {
+		return vc, nil
+	}
+	for _, alt := range strings.Split(s, "||") {
+		var terms []versionTerm
+		fields := strings.FieldsFunc(alt, func(r rune) bool { return r == ' ' || r == ',' })
+		for i := 0; i < len(fields); i++ {
+			f := fields[i]
+			op := ""
+			for _, o := range versionOperators {
+				if strings.HasPrefix(f, o) {
+					op = o
+					break
+				}
+			}
+			f = strings.TrimPrefix(f, op)
+			// allow a space between the operator and the version
+			if f == "" && i+1 < len(fields) {
+				i++
+				f = fields[i]
+			}
+			v, err := strconv.Atoi(f)
+			if err != nil || v < 1 {
+				return nil, fmt.Errorf("Invalid version constraint '%s': '%s' is not a valid version", s, f)
+			}
+			terms = append(terms, versionTerm{op: op, version: v})
+		}
+		if len(terms) == 0 {
+			return nil, fmt.Errorf("Invalid version constraint '%s': empty alternative", s)
+		}
+		vc.alternatives = append(vc.alternatives, terms)
+	}
+	return vc, nil
+}
+
+// Match returns true if the given version satisfies the constraint
+func (vc *Constraint) Match(v int) bool {
+	if len(vc.alternatives) == 0 {
+		return true
+	}
+	for _, terms := range vc.alternatives {
+		matched := true
+		for _, t := range terms {
+			if !t.match(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the highest of the given versions satisfying the
+// constraint.  The second return value is false when none of them does.
+func (vc *Constraint) Resolve(versions []int) (int, bool) {
+	best, found := 0, false
+	for _, v := range versions {
+		if vc.Match(v) && (!found || v > best) {
+			best, found = v, true
+		}
+	}
+	return best, found
+}
+
+// String returns the constraint as it was given
+func (vc *Constraint) String() string {
+	return vc.text
+}
@@ -0,0 +1,78 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package versions
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConstraint(t *testing.T) {
+	Convey("Parsing version constraints", t, func() {
+		Convey("a range matches the versions inside it", func() {
+			vc, err := ParseConstraint(">=2 <4")
+			So(err, ShouldBeNil)
+			So(vc.Match(1), ShouldBeFalse)
+			So(vc.Match(2), ShouldBeTrue)
+			So(vc.Match(3), ShouldBeTrue)
+			So(vc.Match(4), ShouldBeFalse)
+			So(vc.String(), ShouldEqual, ">=2 <4")
+		})
+		Convey("alternatives and operators separated from the version are supported", func() {
+			vc, err := ParseConstraint("< 3, != 1 || 5")
+			So(err, ShouldBeNil)
+			So(vc.Match(1), ShouldBeFalse)
+			So(vc.Match(2), ShouldBeTrue)
+			So(vc.Match(3), ShouldBeFalse)
+			So(vc.Match(5), ShouldBeTrue)
+		})
+		Convey("latest matches any version", func() {
+			vc, err := ParseConstraint("latest")
+			So(err, ShouldBeNil)
+			So(vc.Match(1), ShouldBeTrue)
+			So(vc.Match(42), ShouldBeTrue)
+		})
+		Convey("invalid constraints are rejected", func() {
+			_, err := ParseConstraint("")
+			So(err, ShouldEqual, ErrEmptyConstraint)
+			_, err = ParseConstraint(">=two")
+			So(err, ShouldNotBeNil)
+			_, err = ParseConstraint(">=2 ||")
+			So(err, ShouldNotBeNil)
+			_, err = ParseConstraint("~>0")
+			So(err, ShouldNotBeNil)
+		})
+	})
+	Convey("Resolving a version constraint", t, func() {
+		vc, _ := ParseConstraint(">=2 <4")
+		Convey("returns the highest matching version", func() {
+			v, ok := vc.Resolve([]int{1, 3, 2, 4})
+			So(ok, ShouldBeTrue)
+			So(v, ShouldEqual, 3)
+		})
+		Convey("fails when no version matches", func() {
+			_, ok := vc.Resolve([]int{1, 4, 5})
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
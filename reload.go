@@ -0,0 +1,159 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control"
+	"github.com/intelsdi-x/snap/pkg/logging"
+	"github.com/intelsdi-x/snap/scheduler"
+)
+
+type reloadsScheduler interface {
+	Reload(*scheduler.Config) error
+}
+
+type reloadsTrust interface {
+	SetPluginTrustLevel(int)
+	SetKeyringFiles([]string)
+}
+
+type setsLogLevels interface {
+	SetLevels(logging.Levels) error
+}
+
+// reloader reloads the configuration of snapteld, on SIGHUP or through the
+// REST API, and applies the settings which can change while snapteld runs:
+// the log levels, the size of the worker pools, the default timeouts of the
+// tasks and the plugin trust settings.  The other settings require a restart.
+type reloader struct {
+	ctx       runtimeFlagsContext
+	control   reloadsTrust
+	scheduler reloadsScheduler
+	logging   setsLogLevels
+
+	mutex sync.Mutex
+	// cfg is the configuration applied
+	cfg *Config
+}
+
+// Reload reads the configuration file again, applies the command-line
+// flags and applies the settings changed since the configuration was last
+// applied; it returns the names of these settings.  The settings are checked
+// before any is applied so that an invalid configuration changes nothing.
+func (r *reloader) Reload() ([]string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cfg := getDefaultConfig()
+	if err := readConfigFile(cfg, r.ctx.String("config")); err != nil {
+		return nil, err
+	}
+	applyCmdLineFlags(cfg, r.ctx)
+	if err := validateConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+	logLevelsChanged := cfg.LogLevel != r.cfg.LogLevel || !reflect.DeepEqual(cfg.LogLevels, r.cfg.LogLevels)
+	if cfg.LogLevel != r.cfg.LogLevel {
+		changed = append(changed, "log_level")
+	}
+	if !reflect.DeepEqual(cfg.LogLevels, r.cfg.LogLevels) {
+		changed = append(changed, "log_levels")
+	}
+
+	schedulerChanged := false
+	for _, c := range []struct {
+		name    string
+		changed bool
+	}{
+		{"scheduler::work_manager_pool_size", cfg.Scheduler.WorkManagerPoolSize != r.cfg.Scheduler.WorkManagerPoolSize},
+		{"scheduler::collect_timeout", cfg.Scheduler.CollectTimeout != r.cfg.Scheduler.CollectTimeout},
+		{"scheduler::process_timeout", cfg.Scheduler.ProcessTimeout != r.cfg.Scheduler.ProcessTimeout},
+		{"scheduler::publish_timeout", cfg.Scheduler.PublishTimeout != r.cfg.Scheduler.PublishTimeout},
+		{"scheduler::task_defaults", !reflect.DeepEqual(cfg.Scheduler.TaskDefaults, r.cfg.Scheduler.TaskDefaults)},
+	} {
+		if c.changed {
+			changed = append(changed, c.name)
+			schedulerChanged = true
+		}
+	}
+
+	trustChanged := cfg.Control.PluginTrust != r.cfg.Control.PluginTrust
+	keyringsChanged := cfg.Control.KeyringPaths != r.cfg.Control.KeyringPaths
+	if trustChanged {
+		changed = append(changed, "control::plugin_trust_level")
+	}
+	if keyringsChanged {
+		changed = append(changed, "control::keyring_paths")
+	}
+	var keyrings []string
+	if (trustChanged || keyringsChanged) && cfg.Control.PluginTrust > control.PluginTrustDisabled {
+		var err error
+		if keyrings, err = keyringFiles(cfg.Control.KeyringPaths); err != nil {
+			return nil, err
+		}
+	}
+
+	if schedulerChanged {
+		if err := r.scheduler.Reload(cfg.Scheduler); err != nil {
+			return nil, err
+		}
+		r.cfg.Scheduler.WorkManagerPoolSize = cfg.Scheduler.WorkManagerPoolSize
+		r.cfg.Scheduler.CollectTimeout = cfg.Scheduler.CollectTimeout
+		r.cfg.Scheduler.ProcessTimeout = cfg.Scheduler.ProcessTimeout
+		r.cfg.Scheduler.PublishTimeout = cfg.Scheduler.PublishTimeout
+		r.cfg.Scheduler.TaskDefaults = cfg.Scheduler.TaskDefaults
+	}
+	if logLevelsChanged {
+		// the levels passed the constraints of the configuration
+		r.logging.SetLevels(logLevels(cfg))
+		r.cfg.LogLevel = cfg.LogLevel
+		r.cfg.LogLevels = cfg.LogLevels
+	}
+	if trustChanged || keyringsChanged {
+		if keyrings != nil {
+			r.control.SetKeyringFiles(keyrings)
+		}
+		r.control.SetPluginTrustLevel(cfg.Control.PluginTrust)
+		r.cfg.Control.PluginTrust = cfg.Control.PluginTrust
+		r.cfg.Control.KeyringPaths = cfg.Control.KeyringPaths
+		if cfg.Control.PluginTrust == control.PluginTrustDisabled {
+			log.WithFields(
+				log.Fields{
+					"block":   "main",
+					"_module": logModule,
+				}).Warning("plugin signatures are not verified, any executable can be loaded as a plugin")
+		}
+	}
+
+	log.WithFields(
+		log.Fields{
+			"block":   "main",
+			"_module": logModule,
+			"changed": changed,
+		}).Info("configuration reloaded")
+	return changed, nil
+}
@@ -0,0 +1,133 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/pkg/logging"
+	"github.com/intelsdi-x/snap/scheduler"
+)
+
+type mockReloaded struct {
+	scheduler *scheduler.Config
+	levels    *logging.Levels
+	trust     *int
+	keyrings  []string
+}
+
+func (m *mockReloaded) Reload(cfg *scheduler.Config) error {
+	m.scheduler = cfg
+	return nil
+}
+
+func (m *mockReloaded) SetLevels(levels logging.Levels) error {
+	m.levels = &levels
+	return nil
+}
+
+func (m *mockReloaded) SetPluginTrustLevel(trust int) {
+	m.trust = &trust
+}
+
+func (m *mockReloaded) SetKeyringFiles(keyrings []string) {
+	m.keyrings = keyrings
+}
+
+func TestReloader(t *testing.T) {
+	Convey("Given a reloader of a configuration file", t, func() {
+		dir, err := ioutil.TempDir("", "snap-reload")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "snapteld.yaml")
+		write := func(content string) {
+			So(ioutil.WriteFile(path, []byte(content), 0600), ShouldBeNil)
+		}
+		m := &mockReloaded{}
+		r := &reloader{
+			ctx:       mockFlags{"config": path},
+			cfg:       getDefaultConfig(),
+			control:   m,
+			scheduler: m,
+			logging:   m,
+		}
+
+		Convey("the changed settings are applied", func() {
+			write("log_level: 1\nlog_levels:\n  scheduler: 4\nscheduler:\n  work_manager_pool_size: 8\n  collect_timeout: 5s\n")
+			changed, err := r.Reload()
+			So(err, ShouldBeNil)
+			So(changed, ShouldResemble, []string{"log_level", "log_levels", "scheduler::work_manager_pool_size", "scheduler::collect_timeout"})
+			So(m.scheduler.WorkManagerPoolSize, ShouldEqual, 8)
+			So(m.scheduler.CollectTimeout.Duration, ShouldEqual, 5*time.Second)
+			So(*m.levels, ShouldResemble, logging.Levels{Default: "debug", Modules: map[string]string{"scheduler": "error"}})
+			So(m.trust, ShouldBeNil)
+
+			Convey("and are not applied again by the next reload", func() {
+				m.scheduler, m.levels = nil, nil
+				changed, err := r.Reload()
+				So(err, ShouldBeNil)
+				So(changed, ShouldBeEmpty)
+				So(m.scheduler, ShouldBeNil)
+				So(m.levels, ShouldBeNil)
+			})
+		})
+		Convey("the default task options are applied", func() {
+			write("scheduler:\n  task_defaults:\n    deadline: 10s\n    labels:\n      env: prod\n")
+			changed, err := r.Reload()
+			So(err, ShouldBeNil)
+			So(changed, ShouldResemble, []string{"scheduler::task_defaults"})
+			So(m.scheduler.TaskDefaults.Deadline, ShouldEqual, "10s")
+			So(m.scheduler.TaskDefaults.Labels, ShouldResemble, map[string]string{"env": "prod"})
+		})
+		Convey("the plugin trust settings are applied", func() {
+			keyring := filepath.Join(dir, "keyring.gpg")
+			So(ioutil.WriteFile(keyring, []byte{}, 0600), ShouldBeNil)
+			write("control:\n  plugin_trust_level: 2\n  keyring_paths: " + keyring + "\n")
+			changed, err := r.Reload()
+			So(err, ShouldBeNil)
+			So(changed, ShouldResemble, []string{"control::plugin_trust_level", "control::keyring_paths"})
+			So(*m.trust, ShouldEqual, 2)
+			So(m.keyrings, ShouldResemble, []string{keyring})
+		})
+		Convey("nothing is applied from an invalid configuration", func() {
+			write("log_level: 2\nscheduler:\n  work_manager_pool_size: 0\n")
+			_, err := r.Reload()
+			So(err, ShouldNotBeNil)
+			So(m.scheduler, ShouldBeNil)
+			So(m.levels, ShouldBeNil)
+		})
+		Convey("nothing is applied when a keyring is missing", func() {
+			write("log_level: 1\ncontrol:\n  keyring_paths: " + filepath.Join(dir, "missing.gpg") + "\n")
+			_, err := r.Reload()
+			So(err, ShouldNotBeNil)
+			So(m.levels, ShouldBeNil)
+			So(m.trust, ShouldBeNil)
+			So(r.cfg.LogLevel, ShouldEqual, defaultLogLevel)
+		})
+	})
+}
@@ -0,0 +1,262 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+const (
+	// repeatedPublishFailures is the number of consecutive failures after
+	// which the advisor reports a publisher
+	repeatedPublishFailures = 3
+)
+
+// DeprecatedMetric marks the metrics whose namespace starts with the given
+// pattern as deprecated: the task advisor reports the tasks collecting them
+// along with the replacement, if any.  The "*" elements of the pattern match
+// any element.
+type DeprecatedMetric struct {
+	Namespace   string `json:"namespace"yaml:"namespace"`
+	Replacement string `json:"replacement,omitempty"yaml:"replacement"`
+	Reason      string `json:"reason,omitempty"yaml:"reason"`
+}
+
+// deprecatedMetric is a DeprecatedMetric with its namespace split in elements
+type deprecatedMetric struct {
+	DeprecatedMetric
+	pattern namespacePattern
+}
+
+// newDeprecatedMetrics validates the deprecated metrics of the configuration
+func newDeprecatedMetrics(dms []DeprecatedMetric) ([]deprecatedMetric, error) {
+	var out []deprecatedMetric
+	for _, dm := range dms {
+		pattern, err := parseNamespacePattern(dm.Namespace)
+		if err != nil {
+			return nil, fmt.Errorf("%v in deprecated metric", err)
+		}
+		out = append(out, deprecatedMetric{DeprecatedMetric: dm, pattern: pattern})
+	}
+	return out, nil
+}
+
+// publisherFailures counts the consecutive failures of the publish nodes of
+// a task by plugin
+type publisherFailures struct {
+	sync.Mutex
+	counts map[string]uint
+	errors map[string]string
+}
+
+// record counts a failed publish, or resets the count of the plugin when
+// the publish succeeded
+func (p *publisherFailures) record(plugin string, errs []error) {
+	p.Lock()
+	defer p.Unlock()
+	if len(errs) == 0 {
+		delete(p.counts, plugin)
+		delete(p.errors, plugin)
+		return
+	}
+	if p.counts == nil {
+		p.counts = map[string]uint{}
+		p.errors = map[string]string{}
+	}
+	p.counts[plugin]++
+	p.errors[plugin] = errs[0].Error()
+}
+
+// failing returns the plugins which failed at least the given number of
+// consecutive times with their count and last error
+func (p *publisherFailures) failing(threshold uint) map[string]string {
+	p.Lock()
+	defer p.Unlock()
+	out := map[string]string{}
+	for plugin, n := range p.counts {
+		if n >= threshold {
+			out[plugin] = fmt.Sprintf("%d consecutive failures, last: %s", n, p.errors[plugin])
+		}
+	}
+	return out
+}
+
+// catalogsPlugins is optionally implemented by a metric manager which can
+// list its loaded plugins.
+type catalogsPlugins interface {
+	PluginCatalog() core.PluginCatalog
+}
+
+// pluginSubject names a plugin as type:name:version
+func pluginSubject(typ, name string, version int) string {
+	return fmt.Sprintf("%s:%s:%d", typ, name, version)
+}
+
+// AdviseTasks scans the running tasks and reports the deprecated metrics
+// they collect, the plugins they use which have a newer version loaded, the
+// schedules which miss too many fires given the recorded run durations and
+// the publishers which keep failing.
+func (s *scheduler) AdviseTasks() *core.AdvisorReport {
+	report := &core.AdvisorReport{
+		Timestamp: time.Now().Unix(),
+		Findings:  []core.TaskAdvisory{},
+	}
+	for _, t := range s.tasks.Table() {
+		switch t.State() {
+		case core.TaskSpinning, core.TaskFiring, core.TaskSuspended:
+		default:
+			continue
+		}
+		report.TasksScanned++
+		report.Findings = append(report.Findings, s.adviseTask(t)...)
+	}
+	sort.Sort(advisories(report.Findings))
+	return report
+}
+
+func (s *scheduler) adviseTask(t *task) []core.TaskAdvisory {
+	var out []core.TaskAdvisory
+	add := func(kind, severity, subject, message, suggestion string) {
+		out = append(out, core.TaskAdvisory{
+			TaskID:     t.ID(),
+			TaskName:   t.GetName(),
+			Kind:       kind,
+			Severity:   severity,
+			Subject:    subject,
+			Message:    message,
+			Suggestion: suggestion,
+		})
+	}
+
+	for _, rm := range t.workflow.metrics {
+		ns := rm.Namespace()
+		for _, dm := range s.deprecatedMetrics {
+			if !dm.pattern.matches(ns) {
+				continue
+			}
+			msg := fmt.Sprintf("metric %s is deprecated", ns)
+			if dm.Reason != "" {
+				msg += ": " + dm.Reason
+			}
+			add(core.AdvisoryDeprecatedMetric, core.AdvisorySeverityWarning, ns.String(), msg, dm.Replacement)
+			break
+		}
+	}
+
+	// the collectors are only known by the metrics they expose
+	if vr, ok := t.metricsManager.(versionsResolver); ok {
+		for _, rm := range t.workflow.metrics {
+			if m, ok := rm.(*metric); !ok || m.constraint != nil || m.version < 1 {
+				continue
+			}
+			cmts, err := vr.GetMetricVersions(rm.Namespace())
+			if err != nil {
+				continue
+			}
+			latest := 0
+			for _, cmt := range cmts {
+				if cmt.Version() > latest {
+					latest = cmt.Version()
+				}
+			}
+			if latest > rm.Version() {
+				add(core.AdvisoryNewerPlugin, core.AdvisorySeverityInfo, rm.Namespace().String(),
+					fmt.Sprintf("metric %s is collected from version %d of its collector while version %d is loaded", rm.Namespace(), rm.Version(), latest),
+					strconv.Itoa(latest))
+			}
+		}
+	}
+	if pc, ok := t.metricsManager.(catalogsPlugins); ok {
+		latest := map[string]int{}
+		for _, p := range pc.PluginCatalog() {
+			key := p.TypeName() + ":" + p.Name()
+			if p.Version() > latest[key] {
+				latest[key] = p.Version()
+			}
+		}
+		t.workflow.walkNodes(func(typ, name string, version int) {
+			if version < 1 {
+				return
+			}
+			if v := latest[typ+":"+name]; v > version {
+				add(core.AdvisoryNewerPlugin, core.AdvisorySeverityInfo, pluginSubject(typ, name, version),
+					fmt.Sprintf("%s %s version %d is used while version %d is loaded", typ, name, version, v),
+					strconv.Itoa(v))
+			}
+		})
+	}
+
+	if ws, ok := t.schedule.(*schedule.WindowedSchedule); ok {
+		if durations := s.latencies.get(t.ID()); len(durations) > 0 {
+			sim, err := simulateSchedule(&core.Schedule{Type: "simple", Interval: ws.Interval.String()}, durations, time.Now())
+			if err == nil && sim.MissRatio > acceptableMissRatio {
+				add(core.AdvisoryShortInterval, core.AdvisorySeverityWarning, ws.Interval.String(), sim.Recommendation, sim.SuggestedInterval)
+			}
+		}
+	}
+
+	for plugin, msg := range t.publishFailures.failing(repeatedPublishFailures) {
+		add(core.AdvisoryFailingPublisher, core.AdvisorySeverityWarning, plugin, msg, "")
+	}
+	return out
+}
+
+// walkNodes calls the given function with the type, name and version of the
+// plugin of every process and publish node of the workflow; the built-in
+// processors and publishers are skipped
+func (s *schedulerWorkflow) walkNodes(f func(typ, name string, version int)) {
+	var walk func([]*processNode, []*publishNode)
+	walk = func(prs []*processNode, pus []*publishNode) {
+		for _, pr := range prs {
+			if pr.transform == nil {
+				f(core.ProcessorPluginType.String(), pr.Name(), pr.Version())
+			}
+			walk(pr.ProcessNodes, pr.PublishNodes)
+		}
+		for _, pu := range pus {
+			if pu.sink == nil {
+				f(core.PublisherPluginType.String(), pu.Name(), pu.Version())
+			}
+		}
+	}
+	walk(s.processNodes, s.publishNodes)
+}
+
+// advisories sorts the findings by task, kind and subject
+type advisories []core.TaskAdvisory
+
+func (a advisories) Len() int      { return len(a) }
+func (a advisories) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a advisories) Less(i, j int) bool {
+	if a[i].TaskID != a[j].TaskID {
+		return a[i].TaskID < a[j].TaskID
+	}
+	if a[i].Kind != a[j].Kind {
+		return a[i].Kind < a[j].Kind
+	}
+	return a[i].Subject < a[j].Subject
+}
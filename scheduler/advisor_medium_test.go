@@ -0,0 +1,146 @@
+//go:build medium
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+type catalogedPlugin struct {
+	typeName string
+	name     string
+	version  int
+}
+
+func (p catalogedPlugin) TypeName() string              { return p.typeName }
+func (p catalogedPlugin) Name() string                  { return p.name }
+func (p catalogedPlugin) Version() int                  { return p.version }
+func (p catalogedPlugin) IsSigned() bool                { return false }
+func (p catalogedPlugin) Status() string                { return "loaded" }
+func (p catalogedPlugin) PluginPath() string            { return "" }
+func (p catalogedPlugin) LoadedTimestamp() *time.Time   { return nil }
+func (p catalogedPlugin) Policy() *cpolicy.ConfigPolicy { return nil }
+func (p catalogedPlugin) Key() string                   { return pluginSubject(p.typeName, p.name, p.version) }
+
+// advisedMetricManager loads the versions 1 and 2 of the collector, the
+// versions 1 and 3 of the publisher and fails the publishes when told to
+type advisedMetricManager struct {
+	*versionedMetricManager
+	publishErr error
+}
+
+func (m *advisedMetricManager) PluginCatalog() core.PluginCatalog {
+	return core.PluginCatalog{
+		catalogedPlugin{"publisher", "file", 1},
+		catalogedPlugin{"publisher", "file", 3},
+	}
+}
+
+func (m *advisedMetricManager) PublishMetrics([]core.Metric, map[string]ctypes.ConfigValue, string, string, int) []error {
+	if m.publishErr != nil {
+		return []error{m.publishErr}
+	}
+	return nil
+}
+
+func TestAdviseTasks(t *testing.T) {
+	Convey("Given a scheduler with deprecated metrics", t, func() {
+		cfg := GetDefaultConfig()
+		cfg.DeprecatedMetrics = []DeprecatedMetric{
+			{Namespace: "/foo/*", Replacement: "/foo/qux", Reason: "renamed"},
+		}
+		s := New(cfg)
+		mm := &advisedMetricManager{versionedMetricManager: newVersionedMetricManager(1, 2)}
+		s.SetMetricManager(mm)
+		s.Start()
+
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		w.Collect.Add(wmap.NewPublishNode("file", 1))
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+		tk.killChan = make(chan struct{})
+
+		kinds := func(r *core.AdvisorReport) []string {
+			var out []string
+			for _, f := range r.Findings {
+				out = append(out, f.Kind+" "+f.Subject+" "+f.Suggestion)
+			}
+			return out
+		}
+
+		Convey("stopped tasks are not scanned", func() {
+			r := s.AdviseTasks()
+			So(r.TasksScanned, ShouldEqual, 0)
+			So(r.Findings, ShouldBeEmpty)
+		})
+		Convey("a running task", func() {
+			tk.state = core.TaskSpinning
+
+			Convey("is reported for its deprecated metrics and older plugins", func() {
+				r := s.AdviseTasks()
+				So(r.TasksScanned, ShouldEqual, 1)
+				So(kinds(r), ShouldResemble, []string{
+					"deprecated-metric /foo/bar /foo/qux",
+					"newer-plugin /foo/bar 2",
+					"newer-plugin publisher:file:1 3",
+				})
+				So(r.Findings[0].Message, ShouldEqual, "metric /foo/bar is deprecated: renamed")
+			})
+			Convey("is reported when its runs outlast its interval", func() {
+				for i := 0; i < 10; i++ {
+					s.latencies.record(tk.ID(), time.Second)
+				}
+				r := s.AdviseTasks()
+				So(kinds(r), ShouldContain, "short-interval 10ms 1s")
+			})
+			Convey("is reported when its publisher keeps failing", func() {
+				mm.publishErr = errors.New("disk full")
+				for i := 0; i < repeatedPublishFailures; i++ {
+					tk.fire()
+				}
+				r := s.AdviseTasks()
+				So(kinds(r), ShouldContain, "failing-publisher publisher:file:1 ")
+
+				Convey("until it publishes again", func() {
+					mm.publishErr = nil
+					tk.fire()
+					So(kinds(s.AdviseTasks()), ShouldNotContain, "failing-publisher publisher:file:1 ")
+				})
+			})
+		})
+
+		s.Stop()
+	})
+}
@@ -0,0 +1,226 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+// The built-in aggregation functions
+const (
+	aggregateMin   = "min"
+	aggregateMax   = "max"
+	aggregateAvg   = "avg"
+	aggregateSum   = "sum"
+	aggregateCount = "count"
+)
+
+var aggregateFuncs = map[string]struct{}{
+	aggregateMin:   {},
+	aggregateMax:   {},
+	aggregateAvg:   {},
+	aggregateSum:   {},
+	aggregateCount: {},
+}
+
+// aggregator reduces the metrics handed to a publish node to one metric per
+// namespace and aggregation function.
+type aggregator struct {
+	funcs []string
+}
+
+func newAggregator(funcs []string) (*aggregator, error) {
+	if len(funcs) == 0 {
+		return nil, nil
+	}
+	for _, f := range funcs {
+		if _, ok := aggregateFuncs[f]; !ok {
+			return nil, fmt.Errorf("Unknown aggregation function '%s' (expected one of min, max, avg, sum or count)", f)
+		}
+	}
+	return &aggregator{funcs: funcs}, nil
+}
+
+// aggregation holds the running values of a namespace
+type aggregation struct {
+	first     core.Metric
+	namespace core.Namespace
+	timestamp int64
+	min       float64
+	max       float64
+	sum       float64
+	count     int
+	// rule is the aggregation-only rule of the namespace, if any; the
+	// aggregation then counts its distinct series and keeps the tags common
+	// to all of them
+	rule   *exportRule
+	series map[string]struct{}
+	tags   map[string]string
+}
+
+// aggregate returns the aggregated metrics in the order their namespace first
+// appears.  Metrics whose data is not numeric are dropped.  The metrics
+// restricted by the export policy are aggregated over their group and only
+// the groups of enough distinct series are returned.
+func (a *aggregator) aggregate(mts []core.Metric, policy exportPolicy) []core.Metric {
+	var keys []string
+	aggs := map[string]*aggregation{}
+	for _, m := range mts {
+		v, ok := toFloat64(m.Data())
+		if !ok {
+			workflowLogger.WithFields(log.Fields{
+				"_block":    "aggregate",
+				"namespace": m.Namespace().String(),
+			}).Debug("Metric data is not numeric and is not aggregated")
+			continue
+		}
+		ns := m.Namespace()
+		rule := policy.rule(ns)
+		if rule != nil {
+			ns = rule.group(ns)
+		}
+		key := ns.String()
+		agg, ok := aggs[key]
+		if !ok {
+			agg = &aggregation{first: m, namespace: ns, min: v, max: v, rule: rule}
+			if rule != nil {
+				agg.series = map[string]struct{}{}
+				agg.tags = copyTags(m.Tags())
+			}
+			aggs[key] = agg
+			keys = append(keys, key)
+		}
+		agg.min = math.Min(agg.min, v)
+		agg.max = math.Max(agg.max, v)
+		agg.sum += v
+		agg.count++
+		if ts := m.Timestamp().UnixNano(); ts > agg.timestamp {
+			agg.timestamp = ts
+		}
+		if rule != nil {
+			agg.series[seriesKey(m)] = struct{}{}
+			tags := m.Tags()
+			for k, v := range agg.tags {
+				if tv, ok := tags[k]; !ok || tv != v {
+					delete(agg.tags, k)
+				}
+			}
+		}
+	}
+
+	out := make([]core.Metric, 0, len(keys)*len(a.funcs))
+	for _, key := range keys {
+		agg := aggs[key]
+		if agg.rule != nil && len(agg.series) < agg.rule.minGroupSize {
+			workflowLogger.WithFields(log.Fields{
+				"_block":         "aggregate",
+				"namespace":      key,
+				"group-size":     len(agg.series),
+				"min-group-size": agg.rule.minGroupSize,
+			}).Debug("Group too small to be published")
+			continue
+		}
+		for _, f := range a.funcs {
+			out = append(out, agg.metric(f))
+		}
+	}
+	return out
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	c := make(map[string]string, len(tags))
+	for k, v := range tags {
+		c[k] = v
+	}
+	return c
+}
+
+// metric returns the metric of the given aggregation function.  Its
+// namespace is the namespace of the aggregated metrics followed by the name
+// of the function, e.g. /intel/mock/foo/avg or /intel/procfs/*/cpu/avg for
+// the group of an aggregation-only rule.
+func (agg *aggregation) metric(f string) core.Metric {
+	var data interface{}
+	switch f {
+	case aggregateMin:
+		data = agg.min
+	case aggregateMax:
+		data = agg.max
+	case aggregateAvg:
+		data = agg.sum / float64(agg.count)
+	case aggregateSum:
+		data = agg.sum
+	case aggregateCount:
+		data = agg.count
+	}
+	ns := append(core.Namespace{}, agg.namespace...)
+	tags := agg.first.Tags()
+	if agg.rule != nil {
+		tags = agg.tags
+	}
+	return plugin.MetricType{
+		Namespace_:          ns.AddStaticElement(f),
+		Version_:            agg.first.Version(),
+		Tags_:               tags,
+		Timestamp_:          time.Unix(0, agg.timestamp),
+		LastAdvertisedTime_: agg.first.LastAdvertisedTime(),
+		Unit_:               agg.first.Unit(),
+		Description_:        agg.first.Description(),
+		Data_:               data,
+	}
+}
+
+// toFloat64 converts numeric metric data to a float64
+func toFloat64(data interface{}) (float64, bool) {
+	switch v := data.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,144 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func TestAggregator(t *testing.T) {
+	Convey("Given an aggregator", t, func() {
+		a, err := newAggregator([]string{"min", "max", "avg", "sum", "count"})
+		So(err, ShouldBeNil)
+		now := time.Now()
+		mt := func(ts time.Time, data interface{}, ns ...string) core.Metric {
+			return plugin.MetricType{Namespace_: core.NewNamespace(ns...), Timestamp_: ts, Data_: data}
+		}
+
+		Convey("metrics are aggregated per namespace", func() {
+			out := a.aggregate([]core.Metric{
+				mt(now.Add(-time.Second), 2, "intel", "mock", "foo"),
+				mt(now, float32(1.5), "intel", "mock", "bar"),
+				mt(now, int64(6), "intel", "mock", "foo"),
+				mt(now, "text", "intel", "mock", "baz"),
+			}, nil)
+			So(len(out), ShouldEqual, 10)
+			data := map[string]interface{}{}
+			for _, m := range out {
+				data[m.Namespace().String()] = m.Data()
+			}
+			So(data["/intel/mock/foo/min"], ShouldEqual, 2)
+			So(data["/intel/mock/foo/max"], ShouldEqual, 6)
+			So(data["/intel/mock/foo/avg"], ShouldEqual, 4)
+			So(data["/intel/mock/foo/sum"], ShouldEqual, 8)
+			So(data["/intel/mock/foo/count"], ShouldEqual, 2)
+			So(data["/intel/mock/bar/avg"], ShouldEqual, 1.5)
+			So(data, ShouldNotContainKey, "/intel/mock/baz/count")
+			So(out[0].Namespace().String(), ShouldEqual, "/intel/mock/foo/min")
+			So(out[0].Timestamp().Equal(now), ShouldBeTrue)
+		})
+		Convey("metrics restricted to aggregates are aggregated over their group", func() {
+			policy, err := newExportPolicy([]AggregationOnlyRule{{Namespace: "/intel/procfs/*", MinGroupSize: 3}})
+			So(err, ShouldBeNil)
+			proc := func(pid, user string, data int) core.Metric {
+				return plugin.MetricType{
+					Namespace_: core.NewNamespace("intel", "procfs", pid, "cpu"),
+					Tags_:      map[string]string{"user": user, "host": "node1"},
+					Timestamp_: now,
+					Data_:      data,
+				}
+			}
+			mts := []core.Metric{
+				proc("1", "alice", 1),
+				proc("2", "bob", 2),
+				proc("2", "bob", 4),
+				mt(now, 5, "intel", "mock", "foo"),
+			}
+			Convey("groups too small are not published", func() {
+				out := a.aggregate(mts, policy)
+				So(len(out), ShouldEqual, 5)
+				for _, m := range out {
+					So(m.Namespace().String(), ShouldStartWith, "/intel/mock/foo/")
+				}
+			})
+			Convey("groups large enough keep the tags common to their series", func() {
+				out := a.aggregate(append(mts, proc("3", "carol", 3)), policy)
+				So(len(out), ShouldEqual, 10)
+				data := map[string]interface{}{}
+				for _, m := range out {
+					data[m.Namespace().String()] = m.Data()
+					if strings.HasPrefix(m.Namespace().String(), "/intel/procfs/") {
+						So(m.Tags(), ShouldResemble, map[string]string{"host": "node1"})
+					}
+				}
+				So(data["/intel/procfs/*/cpu/count"], ShouldEqual, 4)
+				So(data["/intel/procfs/*/cpu/max"], ShouldEqual, 4)
+			})
+			Convey("raw values are not published", func() {
+				out := policy.unrestricted(mts)
+				So(len(out), ShouldEqual, 1)
+				So(out[0].Namespace().String(), ShouldEqual, "/intel/mock/foo")
+			})
+		})
+		Convey("an invalid aggregation-only rule is rejected", func() {
+			_, err := newExportPolicy([]AggregationOnlyRule{{Namespace: "/intel/procfs", MinGroupSize: 0}})
+			So(err, ShouldNotBeNil)
+			_, err = newExportPolicy([]AggregationOnlyRule{{Namespace: "intel", MinGroupSize: 2}})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("an unknown function is rejected", func() {
+			a, err := newAggregator([]string{"median"})
+			So(a, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given a task whose publisher aggregates metrics", t, func() {
+		s := New(GetDefaultConfig())
+		mm := &publishRecorder{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		w := newBufferedWorkflowMap(&wmap.BufferWorkflowMapNode{Intervals: 2})
+		w.Collect.Publish[0].Aggregate = []string{"count"}
+
+		Convey("a batch is reduced to one metric per namespace and function", func() {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+			So(errs.Errors(), ShouldBeEmpty)
+			tk := tsk.(*task)
+			tk.killChan = make(chan struct{})
+			tk.fire()
+			tk.fire()
+			So(mm.published(), ShouldResemble, []int{2})
+		})
+
+		s.Stop()
+	})
+}
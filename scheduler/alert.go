@@ -0,0 +1,307 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+	"github.com/intelsdi-x/snap/pkg/expression"
+)
+
+const (
+	// transformAlert is the built-in processor evaluating an alert
+	transformAlert = "alert"
+	// alertWebhookTimeout bounds the post of an alert to its webhook
+	alertWebhookTimeout = 10 * time.Second
+	// The tags added to the metrics of the alerts changing state
+	alertNameTag  = "alert"
+	alertStateTag = "alert_state"
+)
+
+var alertWebhookClient = &http.Client{Timeout: alertWebhookTimeout}
+
+// alertSeries is the state of an alert for one series
+type alertSeries struct {
+	alert  core.Alert
+	window []float64
+}
+
+// alertTransform evaluates the condition of an alert against every series
+// (namespace and tags) of the metrics it receives.  With a window of more
+// than one value, the condition is evaluated against the average of the
+// last values of the series, and a series does not fire before its window
+// is full.  The alert of a series fires when the
+// condition holds and resolves when it no longer does; only the metrics of
+// the series changing state go down the workflow, tagged with the name and
+// the new state of the alert.
+type alertTransform struct {
+	sync.Mutex
+	name    string
+	expr    *expression.Expression
+	window  int
+	webhook string
+	series  map[string]*alertSeries
+}
+
+func newAlertTransform(config map[string]interface{}) (*alertTransform, error) {
+	name, err := transformString(transformAlert, config, "name", true)
+	if err != nil {
+		return nil, err
+	}
+	text, err := transformString(transformAlert, config, "condition", true)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := parseMetricExpression(transformAlert, text)
+	if err != nil {
+		return nil, err
+	}
+	window, err := transformFloat(transformAlert, config, "window", 1)
+	if err != nil {
+		return nil, err
+	}
+	if window < 1 || window != float64(int(window)) {
+		return nil, fmt.Errorf("Invalid window %v in built-in processor 'alert' (expected a number of values, 1 or more)", window)
+	}
+	webhook, err := transformString(transformAlert, config, "webhook", false)
+	if err != nil {
+		return nil, err
+	}
+	if webhook != "" {
+		if u, err := url.Parse(webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("Invalid webhook '%s' in built-in processor 'alert' (expected an http(s) URL)", webhook)
+		}
+	}
+	return &alertTransform{
+		name:    name,
+		expr:    expr,
+		window:  int(window),
+		webhook: webhook,
+		series:  map[string]*alertSeries{},
+	}, nil
+}
+
+func (a *alertTransform) apply(mts []core.Metric) []core.Metric {
+	_, out := a.evaluate(mts)
+	return out
+}
+
+// evaluate returns the alerts changing state and their metrics.  Metrics
+// whose data is not numeric are ignored.
+func (a *alertTransform) evaluate(mts []core.Metric) ([]core.Alert, []core.Metric) {
+	a.Lock()
+	defer a.Unlock()
+	var changes []core.Alert
+	var out []core.Metric
+	for _, m := range mts {
+		v, ok := toFloat64(m.Data())
+		if !ok {
+			continue
+		}
+		key := seriesKey(m)
+		s, ok := a.series[key]
+		if !ok {
+			s = &alertSeries{alert: core.Alert{
+				Name:      a.name,
+				Condition: a.expr.String(),
+				Namespace: m.Namespace().String(),
+				Tags:      m.Tags(),
+				State:     core.AlertResolved,
+			}}
+			a.series[key] = s
+		}
+		s.window = append(s.window, v)
+		if len(s.window) > a.window {
+			s.window = s.window[len(s.window)-a.window:]
+		}
+		var sum float64
+		for _, w := range s.window {
+			sum += w
+		}
+		s.alert.Value = sum / float64(len(s.window))
+		resolve := metricResolver(m)
+		firing := len(s.window) == a.window && a.expr.Match(func(id string) interface{} {
+			if id == "value" {
+				return s.alert.Value
+			}
+			return resolve(id)
+		})
+		state := core.AlertResolved
+		if firing {
+			state = core.AlertFiring
+		}
+		// a series starts resolved: its first firing is its first change
+		if state == s.alert.State {
+			continue
+		}
+		s.alert.State = state
+		s.alert.Since = m.Timestamp().Unix()
+		changes = append(changes, s.alert)
+		tags := copyTags(m.Tags())
+		tags[alertNameTag] = a.name
+		tags[alertStateTag] = state
+		out = append(out, plugin.MetricType{
+			Namespace_:          m.Namespace(),
+			Version_:            m.Version(),
+			Tags_:               tags,
+			Timestamp_:          m.Timestamp(),
+			LastAdvertisedTime_: m.LastAdvertisedTime(),
+			Unit_:               m.Unit(),
+			Description_:        m.Description(),
+			Data_:               s.alert.Value,
+		})
+	}
+	return changes, out
+}
+
+// alerts returns the state of the alert for every series seen so far
+func (a *alertTransform) alerts() []core.Alert {
+	a.Lock()
+	defer a.Unlock()
+	out := make([]core.Alert, 0, len(a.series))
+	for _, s := range a.series {
+		out = append(out, s.alert)
+	}
+	return out
+}
+
+// notifyAlerts emits an event for every alert changing state and posts it to
+// the webhook of the alert, if any
+func (t *task) notifyAlerts(a *alertTransform, changes []core.Alert) {
+	for _, alert := range changes {
+		alert.TaskID = t.id
+		alert.TaskName = t.name
+		f := log.Fields{
+			"_block":    "alert",
+			"task-id":   t.id,
+			"task-name": t.name,
+			"alert":     alert.Name,
+			"namespace": alert.Namespace,
+			"value":     alert.Value,
+		}
+		if alert.State == core.AlertFiring {
+			workflowLogger.WithFields(f).Warn("Alert firing")
+			t.eventEmitter.Emit(&scheduler_event.AlertFiredEvent{TaskID: t.id, Alert: alert})
+		} else {
+			workflowLogger.WithFields(f).Info("Alert resolved")
+			t.eventEmitter.Emit(&scheduler_event.AlertResolvedEvent{TaskID: t.id, Alert: alert})
+		}
+		if a.webhook != "" {
+			go postAlert(a.webhook, alert)
+		}
+	}
+}
+
+// postAlert posts an alert to a webhook as JSON
+func postAlert(webhook string, alert core.Alert) {
+	f := log.Fields{
+		"_block":  "alert",
+		"task-id": alert.TaskID,
+		"alert":   alert.Name,
+		"webhook": webhook,
+	}
+	b, err := json.Marshal(alert)
+	if err != nil {
+		workflowLogger.WithFields(f).Error(err)
+		return
+	}
+	resp, err := alertWebhookClient.Post(webhook, "application/json", bytes.NewReader(b))
+	if err != nil {
+		workflowLogger.WithFields(f).Error(err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		workflowLogger.WithFields(f).Errorf("Alert webhook returned %s", resp.Status)
+	}
+}
+
+// Alerts returns the state of the alerts of every task, by task, alert and
+// series
+func (s *scheduler) Alerts() []core.Alert {
+	out := []core.Alert{}
+	for _, t := range s.tasks.Table() {
+		t.workflow.walkAlerts(func(a *alertTransform) {
+			for _, alert := range a.alerts() {
+				alert.TaskID = t.ID()
+				alert.TaskName = t.GetName()
+				out = append(out, alert)
+			}
+		})
+	}
+	sort.Sort(alerts(out))
+	return out
+}
+
+// walkAlerts calls the given function with the alert of every alert node of
+// the workflow
+func (s *schedulerWorkflow) walkAlerts(f func(a *alertTransform)) {
+	var walk func([]*processNode)
+	walk = func(prs []*processNode) {
+		for _, pr := range prs {
+			if a, ok := pr.transform.(*alertTransform); ok {
+				f(a)
+			}
+			walk(pr.ProcessNodes)
+		}
+	}
+	walk(s.processNodes)
+}
+
+// alerts sorts the alerts by task, name and series
+type alerts []core.Alert
+
+func (a alerts) Len() int      { return len(a) }
+func (a alerts) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a alerts) Less(i, j int) bool {
+	if a[i].TaskID != a[j].TaskID {
+		return a[i].TaskID < a[j].TaskID
+	}
+	if a[i].Name != a[j].Name {
+		return a[i].Name < a[j].Name
+	}
+	if a[i].Namespace != a[j].Namespace {
+		return a[i].Namespace < a[j].Namespace
+	}
+	return tagString(a[i].Tags) < tagString(a[j].Tags)
+}
+
+// tagString returns the tags as sorted key=value pairs
+func tagString(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,143 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func TestAlertTransform(t *testing.T) {
+	Convey("Given an alert over a rolling window", t, func() {
+		a, err := newAlertTransform(map[string]interface{}{
+			"name":      "cpu-high",
+			"condition": "value > 90",
+			"window":    3,
+		})
+		So(err, ShouldBeNil)
+		now := time.Now()
+		mt := func(i int, data interface{}, host string) core.Metric {
+			return plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "psutil", "cpu", "user"),
+				Tags_:      map[string]string{"host": host},
+				Timestamp_: now.Add(time.Duration(i) * time.Second),
+				Data_:      data,
+			}
+		}
+
+		Convey("a series fires once the average of its window holds the condition", func() {
+			changes, _ := a.evaluate([]core.Metric{mt(0, 100, "node1"), mt(0, 10, "node2")})
+			So(changes, ShouldBeEmpty)
+			changes, _ = a.evaluate([]core.Metric{mt(1, 100, "node1")})
+			So(changes, ShouldBeEmpty)
+			changes, out := a.evaluate([]core.Metric{mt(2, 85, "node1")})
+			So(len(changes), ShouldEqual, 1)
+			So(changes[0].State, ShouldEqual, core.AlertFiring)
+			So(changes[0].Value, ShouldEqual, 95)
+			So(changes[0].Tags, ShouldResemble, map[string]string{"host": "node1"})
+			So(len(out), ShouldEqual, 1)
+			So(out[0].Tags()["alert"], ShouldEqual, "cpu-high")
+			So(out[0].Tags()["alert_state"], ShouldEqual, core.AlertFiring)
+
+			Convey("and resolves when it no longer does", func() {
+				changes, _ := a.evaluate([]core.Metric{mt(3, 95, "node1")})
+				So(changes, ShouldBeEmpty)
+				changes, _ = a.evaluate([]core.Metric{mt(4, 20, "node1")})
+				So(len(changes), ShouldEqual, 1)
+				So(changes[0].State, ShouldEqual, core.AlertResolved)
+				So(changes[0].Since, ShouldEqual, now.Add(4*time.Second).Unix())
+				So(len(a.alerts()), ShouldEqual, 2)
+			})
+		})
+		Convey("metrics whose data is not numeric are ignored", func() {
+			changes, out := a.evaluate([]core.Metric{mt(0, "text", "node1")})
+			So(changes, ShouldBeEmpty)
+			So(out, ShouldBeEmpty)
+			So(a.alerts(), ShouldBeEmpty)
+		})
+		Convey("invalid settings are rejected", func() {
+			_, err := newAlertTransform(map[string]interface{}{"condition": "value > 90"})
+			So(err, ShouldNotBeNil)
+			_, err = newAlertTransform(map[string]interface{}{"name": "a", "condition": "value >"})
+			So(err, ShouldNotBeNil)
+			_, err = newAlertTransform(map[string]interface{}{"name": "a", "condition": "value > 1", "window": 0})
+			So(err, ShouldNotBeNil)
+			_, err = newAlertTransform(map[string]interface{}{"name": "a", "condition": "value > 1", "webhook": "ftp://example.com"})
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given a task with an alert node", t, func() {
+		posted := make(chan core.Alert, 2)
+		hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var a core.Alert
+			json.NewDecoder(r.Body).Decode(&a)
+			posted <- a
+		}))
+		defer hook.Close()
+		s := New(GetDefaultConfig())
+		mm := &publishRecorder{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		pr := &wmap.ProcessWorkflowMapNode{Builtin: "alert"}
+		pr.AddConfigItem("name", "bar-high")
+		pr.AddConfigItem("condition", `value > 1 || ns == "/foo/bar"`)
+		pr.AddConfigItem("webhook", hook.URL)
+		pr.Add(wmap.NewPublishNode("file", -1))
+		w.Collect.Add(pr)
+
+		Convey("the alerts firing are published, posted and listed", func() {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+			So(errs.Errors(), ShouldBeEmpty)
+			tk := tsk.(*task)
+			tk.killChan = make(chan struct{})
+			tk.fire()
+			So(mm.published(), ShouldResemble, []int{2})
+			select {
+			case a := <-posted:
+				So(a.TaskID, ShouldEqual, tk.ID())
+				So(a.State, ShouldEqual, core.AlertFiring)
+			case <-time.After(5 * time.Second):
+				So("no alert posted", ShouldBeEmpty)
+			}
+			alerts := s.Alerts()
+			So(len(alerts), ShouldEqual, 2)
+			So(alerts[0].Namespace, ShouldEqual, "/foo/bar")
+			So(alerts[1].Namespace, ShouldEqual, "/foo/baz")
+			So(alerts[1].TaskName, ShouldEqual, tk.GetName())
+		})
+
+		s.Stop()
+	})
+}
@@ -0,0 +1,225 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	// transformAnomaly is the built-in processor detecting anomalies
+	transformAnomaly = "anomaly"
+
+	// The methods of the anomaly detection
+	anomalySigma = "sigma"
+	anomalyEWMA  = "ewma"
+
+	// The actions on the anomalous metrics
+	anomalyAnnotate = "annotate"
+	anomalyFilter   = "filter"
+
+	// The tags of the anomalous metrics
+	anomalyTag      = "anomaly"
+	anomalyScoreTag = "anomaly_score"
+
+	defaultAnomalyWindow    = 30
+	defaultAnomalyThreshold = 3
+	defaultAnomalyAlpha     = 0.3
+)
+
+// anomalyState is the history of a series seen by an anomaly transform
+type anomalyState struct {
+	// window holds the last values of the series (sigma)
+	window []float64
+	// mean, variance and count are the moving statistics of the series
+	// (ewma)
+	mean     float64
+	variance float64
+	count    int
+}
+
+// anomalyTransform flags the values of the series (namespace and tags) which
+// deviate from the history of the series by more than threshold standard
+// deviations.  The history is either the last window values of the series
+// (sigma) or its exponentially weighted moving average and variance (ewma),
+// which flags values once window values were seen.  The flagged metrics are
+// tagged with anomaly=true and their score (the deviation in standard
+// deviations); with the filter action, only the flagged metrics are kept.
+// Metrics outside of the namespace of the transform are passed unchanged.
+type anomalyTransform struct {
+	sync.Mutex
+	method    string
+	action    string
+	namespace namespacePattern
+	window    int
+	threshold float64
+	alpha     float64
+	series    map[string]*anomalyState
+}
+
+func newAnomalyTransform(config map[string]interface{}) (*anomalyTransform, error) {
+	a := &anomalyTransform{series: map[string]*anomalyState{}}
+	var err error
+	if a.method, err = transformString(transformAnomaly, config, "method", false); err != nil {
+		return nil, err
+	}
+	switch a.method {
+	case "":
+		a.method = anomalySigma
+	case anomalySigma, anomalyEWMA:
+	default:
+		return nil, fmt.Errorf("Unknown method '%s' in built-in processor 'anomaly' (expected sigma or ewma)", a.method)
+	}
+	if a.action, err = transformString(transformAnomaly, config, "action", false); err != nil {
+		return nil, err
+	}
+	switch a.action {
+	case "":
+		a.action = anomalyAnnotate
+	case anomalyAnnotate, anomalyFilter:
+	default:
+		return nil, fmt.Errorf("Unknown action '%s' in built-in processor 'anomaly' (expected annotate or filter)", a.action)
+	}
+	ns, err := transformString(transformAnomaly, config, "namespace", false)
+	if err != nil {
+		return nil, err
+	}
+	if ns != "" {
+		if a.namespace, err = parseNamespacePattern(ns); err != nil {
+			return nil, fmt.Errorf("%v in built-in processor 'anomaly'", err)
+		}
+	}
+	window, err := transformFloat(transformAnomaly, config, "window", defaultAnomalyWindow)
+	if err != nil {
+		return nil, err
+	}
+	if window < 2 || window != float64(int(window)) {
+		return nil, fmt.Errorf("Invalid window %v in built-in processor 'anomaly' (expected a number of values, 2 or more)", window)
+	}
+	a.window = int(window)
+	if a.threshold, err = transformFloat(transformAnomaly, config, "threshold", defaultAnomalyThreshold); err != nil {
+		return nil, err
+	}
+	if a.threshold <= 0 {
+		return nil, fmt.Errorf("Invalid threshold %v in built-in processor 'anomaly' (expected a number of standard deviations above 0)", a.threshold)
+	}
+	if a.alpha, err = transformFloat(transformAnomaly, config, "alpha", defaultAnomalyAlpha); err != nil {
+		return nil, err
+	}
+	if a.alpha <= 0 || a.alpha > 1 {
+		return nil, fmt.Errorf("Invalid alpha %v in built-in processor 'anomaly' (expected a smoothing factor in ]0, 1])", a.alpha)
+	}
+	return a, nil
+}
+
+func (a *anomalyTransform) apply(mts []core.Metric) []core.Metric {
+	a.Lock()
+	defer a.Unlock()
+	out := make([]core.Metric, 0, len(mts))
+	for _, m := range mts {
+		v, ok := toFloat64(m.Data())
+		if !ok || !a.namespace.matches(m.Namespace()) {
+			if a.action == anomalyAnnotate {
+				out = append(out, m)
+			}
+			continue
+		}
+		key := seriesKey(m)
+		s, ok := a.series[key]
+		if !ok {
+			s = &anomalyState{}
+			a.series[key] = s
+		}
+		score, anomalous := a.score(s, v)
+		if !anomalous {
+			if a.action == anomalyAnnotate {
+				out = append(out, m)
+			}
+			continue
+		}
+		tags := copyTags(m.Tags())
+		tags[anomalyTag] = "true"
+		tags[anomalyScoreTag] = strconv.FormatFloat(score, 'f', 2, 64)
+		out = append(out, plugin.MetricType{
+			Namespace_:          m.Namespace(),
+			Version_:            m.Version(),
+			Tags_:               tags,
+			Timestamp_:          m.Timestamp(),
+			LastAdvertisedTime_: m.LastAdvertisedTime(),
+			Unit_:               m.Unit(),
+			Description_:        m.Description(),
+			Data_:               m.Data(),
+		})
+	}
+	return out
+}
+
+// score returns the deviation of the value from the history of the series,
+// in standard deviations, and whether it is anomalous, then adds the value
+// to the history.  A value is not anomalous until the history holds window
+// values; a value differing from a constant history is.
+func (a *anomalyTransform) score(s *anomalyState, v float64) (float64, bool) {
+	var mean, stddev float64
+	ready := false
+	switch a.method {
+	case anomalySigma:
+		if ready = len(s.window) == a.window; ready {
+			for _, w := range s.window {
+				mean += w
+			}
+			mean /= float64(len(s.window))
+			for _, w := range s.window {
+				stddev += (w - mean) * (w - mean)
+			}
+			stddev = math.Sqrt(stddev / float64(len(s.window)))
+		}
+		s.window = append(s.window, v)
+		if len(s.window) > a.window {
+			s.window = s.window[len(s.window)-a.window:]
+		}
+	case anomalyEWMA:
+		ready = s.count >= a.window
+		mean, stddev = s.mean, math.Sqrt(s.variance)
+		if s.count == 0 {
+			s.mean = v
+		} else {
+			diff := v - s.mean
+			incr := a.alpha * diff
+			s.mean += incr
+			s.variance = (1 - a.alpha) * (s.variance + diff*incr)
+		}
+		s.count++
+	}
+	if !ready {
+		return 0, false
+	}
+	deviation := math.Abs(v - mean)
+	if stddev == 0 {
+		return math.Inf(1), deviation > 0
+	}
+	score := deviation / stddev
+	return score, score > a.threshold
+}
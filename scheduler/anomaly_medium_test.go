@@ -0,0 +1,97 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+func TestAnomalyTransform(t *testing.T) {
+	mt := func(data interface{}, ns ...string) core.Metric {
+		return plugin.MetricType{Namespace_: core.NewNamespace(ns...), Data_: data}
+	}
+	// history alternates between 9 and 11: a mean of 10 and a standard
+	// deviation of 1
+	history := func(tr transform, n int) {
+		for i := 0; i < n; i++ {
+			v := 9
+			if i%2 == 1 {
+				v = 11
+			}
+			So(tr.apply([]core.Metric{mt(v, "intel", "cpu")})[0].Tags(), ShouldNotContainKey, "anomaly")
+		}
+	}
+
+	Convey("Given an anomaly detection over a sliding window", t, func() {
+		tr, err := newTransform("anomaly", map[string]interface{}{"window": 10, "namespace": "/intel/cpu"})
+		So(err, ShouldBeNil)
+		history(tr, 10)
+
+		Convey("values within 3 standard deviations are unchanged", func() {
+			out := tr.apply([]core.Metric{mt(12.5, "intel", "cpu")})
+			So(len(out), ShouldEqual, 1)
+			So(out[0].Tags(), ShouldNotContainKey, "anomaly")
+		})
+		Convey("values beyond 3 standard deviations are annotated", func() {
+			out := tr.apply([]core.Metric{mt(14, "intel", "cpu"), mt(1000, "intel", "mem")})
+			So(len(out), ShouldEqual, 2)
+			So(out[0].Tags()["anomaly"], ShouldEqual, "true")
+			So(out[0].Tags()["anomaly_score"], ShouldEqual, "4.00")
+			So(out[0].Data(), ShouldEqual, 14)
+			So(out[1].Tags(), ShouldNotContainKey, "anomaly")
+		})
+	})
+
+	Convey("Given an anomaly detection with an EWMA keeping only anomalies", t, func() {
+		tr, err := newTransform("anomaly", map[string]interface{}{"method": "ewma", "window": 5, "alpha": 0.5, "action": "filter"})
+		So(err, ShouldBeNil)
+		for i := 0; i < 5; i++ {
+			So(tr.apply([]core.Metric{mt(10, "intel", "cpu")}), ShouldBeEmpty)
+		}
+
+		Convey("only the anomalous metrics are kept", func() {
+			So(tr.apply([]core.Metric{mt(10, "intel", "cpu")}), ShouldBeEmpty)
+			out := tr.apply([]core.Metric{mt(50, "intel", "cpu"), mt("text", "intel", "cpu")})
+			So(len(out), ShouldEqual, 1)
+			So(out[0].Tags()["anomaly_score"], ShouldEqual, "+Inf")
+		})
+	})
+
+	Convey("Invalid anomaly detections are rejected", t, func() {
+		for _, config := range []map[string]interface{}{
+			{"method": "median"},
+			{"action": "drop"},
+			{"window": 1},
+			{"threshold": 0},
+			{"alpha": 1.5},
+			{"namespace": "intel"},
+		} {
+			_, err := newTransform("anomaly", config)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
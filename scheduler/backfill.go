@@ -0,0 +1,288 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+// maxBackfillRuns bounds the number of runs of a backfill
+const maxBackfillRuns = 100000
+
+var (
+	// ErrBackfillRunning - The error message for a backfill started while another one runs for the task
+	ErrBackfillRunning = errors.New("Task is already being backfilled")
+	// ErrNoBackfill - The error message for the backfill of a task which was never backfilled
+	ErrNoBackfill = errors.New("Task has no backfill running")
+	// ErrInvalidBackfillRange - The error message for a backfill whose range is empty or in the future
+	ErrInvalidBackfillRange = errors.New("Backfill start must be before its end, which must be in the past")
+	// ErrInvalidBackfillSpeed - The error message for a backfill with a negative speed
+	ErrInvalidBackfillSpeed = errors.New("Backfill speed must be positive")
+	// ErrBackfillSchedule - The error message for the backfill of a task without an interval schedule
+	ErrBackfillSchedule = errors.New("Only the tasks with a simple or windowed schedule can be backfilled")
+	// ErrBackfillUnsupported - The error message for a backfill the metric manager cannot collect past metrics for
+	ErrBackfillUnsupported = errors.New("Metric manager can not collect past metrics")
+	// ErrBackfillTaskNotRunning - The error message for the backfill of a task which is not running
+	ErrBackfillTaskNotRunning = errors.New("Task must be running to be backfilled")
+)
+
+type collectsMetricsAt interface {
+	CollectMetricsAt(string, map[string]map[string]string, time.Time) ([]core.Metric, []error)
+}
+
+// backfill is the backfill of a task; it is kept, once done, until the next
+// one so that its outcome can be queried
+type backfill struct {
+	sync.Mutex
+	status core.BackfillStatus
+	cancel chan struct{}
+}
+
+func (b *backfill) get() core.BackfillStatus {
+	b.Lock()
+	defer b.Unlock()
+	return b.status
+}
+
+func (b *backfill) ran(at time.Time, errs []error) {
+	b.Lock()
+	defer b.Unlock()
+	b.status.Runs++
+	b.status.Current = at.Unix()
+	if len(errs) > 0 {
+		b.status.FailedRuns++
+		b.status.LastError = errs[len(errs)-1].Error()
+	}
+}
+
+func (b *backfill) finish(state string, err error) {
+	b.Lock()
+	defer b.Unlock()
+	b.status.State = state
+	if err != nil {
+		b.status.LastError = err.Error()
+	}
+}
+
+// backfills holds the backfill of each task
+type backfills struct {
+	sync.Mutex
+	table map[string]*backfill
+}
+
+func newBackfills() *backfills {
+	return &backfills{table: map[string]*backfill{}}
+}
+
+func (b *backfills) get(id string) (*backfill, bool) {
+	b.Lock()
+	defer b.Unlock()
+	bf, ok := b.table[id]
+	return bf, ok
+}
+
+func (b *backfills) forget(id string) {
+	b.Lock()
+	defer b.Unlock()
+	if bf, ok := b.table[id]; ok && bf.get().State == core.BackfillRunning {
+		close(bf.cancel)
+	}
+	delete(b.table, id)
+}
+
+// BackfillTask runs the workflow of a running task for the past timestamps
+// between the start and the end of the backfill, spaced by the interval of
+// its schedule, alongside its scheduled runs.  The collectors are asked for
+// the metrics at each timestamp and the metrics are timestamped with it.
+// The runs are done Speed times faster than the schedule, or as fast as the
+// workers allow when Speed is zero.  It returns the status of the backfill
+// started.
+func (s *scheduler) BackfillTask(id string, bf core.Backfill) (*core.BackfillStatus, error) {
+	t, err := s.getTask(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := t.metricsManager.(collectsMetricsAt); !ok {
+		return nil, ErrBackfillUnsupported
+	}
+	w, ok := t.schedule.(*schedule.WindowedSchedule)
+	if !ok {
+		return nil, ErrBackfillSchedule
+	}
+	if bf.Start >= bf.End || bf.End > s.clock.Now().Unix() {
+		return nil, ErrInvalidBackfillRange
+	}
+	if bf.Speed < 0 {
+		return nil, ErrInvalidBackfillSpeed
+	}
+	start, end := time.Unix(bf.Start, 0), time.Unix(bf.End, 0)
+	total := end.Sub(start)/w.Interval + 1
+	if total > maxBackfillRuns {
+		return nil, fmt.Errorf("Backfill would run the task %d times, at most %d runs are allowed", total, maxBackfillRuns)
+	}
+	if st := t.State(); st != core.TaskSpinning && st != core.TaskFiring {
+		return nil, ErrBackfillTaskNotRunning
+	}
+
+	s.backfills.Lock()
+	if b, ok := s.backfills.table[id]; ok && b.get().State == core.BackfillRunning {
+		s.backfills.Unlock()
+		return nil, ErrBackfillRunning
+	}
+	b := &backfill{
+		status: core.BackfillStatus{
+			Backfill: bf,
+			State:    core.BackfillRunning,
+			Total:    int(total),
+		},
+		cancel: make(chan struct{}),
+	}
+	s.backfills.table[id] = b
+	s.backfills.Unlock()
+
+	schedulerLogger.WithFields(log.Fields{
+		"_block":    "backfill-task",
+		"task-id":   t.id,
+		"task-name": t.name,
+		"start":     start,
+		"end":       end,
+		"runs":      total,
+	}).Info("task backfill started")
+	go backfillTask(t, b, start, end, w.Interval)
+	status := b.get()
+	return &status, nil
+}
+
+// GetBackfill returns the status of the last backfill of a task
+func (s *scheduler) GetBackfill(id string) (*core.BackfillStatus, error) {
+	if _, err := s.getTask(id); err != nil {
+		return nil, err
+	}
+	b, ok := s.backfills.get(id)
+	if !ok {
+		return nil, ErrNoBackfill
+	}
+	status := b.get()
+	return &status, nil
+}
+
+// CancelBackfill cancels the backfill running for a task.  The run in
+// progress completes.
+func (s *scheduler) CancelBackfill(id string) (*core.BackfillStatus, error) {
+	if _, err := s.getTask(id); err != nil {
+		return nil, err
+	}
+	s.backfills.Lock()
+	b, ok := s.backfills.table[id]
+	if !ok || b.get().State != core.BackfillRunning {
+		s.backfills.Unlock()
+		return nil, ErrNoBackfill
+	}
+	close(b.cancel)
+	b.finish(core.BackfillCanceled, nil)
+	s.backfills.Unlock()
+	status := b.get()
+	return &status, nil
+}
+
+// backfillTask runs the workflow of the task for every timestamp of the
+// backfill until it is done, canceled or the task stops running
+func backfillTask(t *task, b *backfill, start, end time.Time, interval time.Duration) {
+	logger := schedulerLogger.WithFields(log.Fields{
+		"_block":    "backfill-task",
+		"task-id":   t.id,
+		"task-name": t.name,
+	})
+	speed := b.get().Speed
+	for at := start; !at.After(end); at = at.Add(interval) {
+		select {
+		case <-b.cancel:
+			logger.Info("task backfill canceled")
+			return
+		default:
+		}
+		if st := t.State(); st != core.TaskSpinning && st != core.TaskFiring {
+			b.finish(core.BackfillCanceled, ErrBackfillTaskNotRunning)
+			logger.Warn("task backfill canceled, the task stopped running")
+			return
+		}
+		runStart := t.clock.Now()
+		b.ran(at, t.backfillRun(at))
+		if speed == 0 {
+			continue
+		}
+		wait := time.Duration(float64(interval)/speed) - t.clock.Now().Sub(runStart)
+		select {
+		case <-b.cancel:
+			logger.Info("task backfill canceled")
+			return
+		case <-t.clock.After(wait):
+		}
+	}
+	b.finish(core.BackfillCompleted, nil)
+	status := b.get()
+	logger.WithFields(log.Fields{
+		"runs":        status.Runs,
+		"failed-runs": status.FailedRuns,
+	}).Info("task backfill completed")
+}
+
+// backfillRun runs the workflow of the task once for the metrics at the
+// given past time and returns the errors of the run.  The run does not count
+// as a run of the task.
+func (t *task) backfillRun(at time.Time) []error {
+	t.Lock()
+	defer t.Unlock()
+
+	t.failureMutex.Lock()
+	t.run = newRunResult(t.clock.Now())
+	t.failureMutex.Unlock()
+
+	ctx := t.runContext(0, nil)
+	j := withContext(ctx, newCollectorJob(t.workflow.metrics, t.collectTimeout(), t.metricsManager, t.workflow.configTree, t.id, t.workflow.tags))
+	j.(*collectorJob).at = at
+	var errs []error
+	if t.pinnedThread != nil {
+		errs = t.pinnedThread.Work(j).Promise().Await()
+	} else {
+		errs = t.manager.Work(j).Promise().Await()
+	}
+	t.usage.record(j, j.Metrics())
+	if len(errs) > 0 {
+		t.RecordFailure(errs)
+	}
+	if len(j.Metrics()) > 0 {
+		t.workflow.work(ctx, t, j)
+	}
+
+	t.failureMutex.Lock()
+	defer t.failureMutex.Unlock()
+	r := t.run
+	t.run = nil
+	return r.Errors
+}
@@ -0,0 +1,146 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// backfillRecorder records the past times the metrics are collected at
+type backfillRecorder struct {
+	*publishRecorder
+	mutex sync.Mutex
+	times []time.Time
+}
+
+func (m *backfillRecorder) CollectMetricsAt(_ string, _ map[string]map[string]string, at time.Time) ([]core.Metric, []error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.times = append(m.times, at)
+	return []core.Metric{
+		plugin.MetricType{Namespace_: core.NewNamespace("foo", "bar"), Data_: 1, Timestamp_: at},
+	}, nil
+}
+
+func (m *backfillRecorder) collectedAt() []time.Time {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]time.Time{}, m.times...)
+}
+
+func waitForBackfill(s *scheduler, id string) *core.BackfillStatus {
+	for i := 0; i < 200; i++ {
+		status, err := s.GetBackfill(id)
+		So(err, ShouldBeNil)
+		if status.State != core.BackfillRunning {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+func TestBackfillTask(t *testing.T) {
+	Convey("Given a running task whose collectors support past metrics", t, func() {
+		s := New(GetDefaultConfig())
+		mm := &backfillRecorder{publishRecorder: &publishRecorder{mockMetricManager: newMockMetricManager()}}
+		s.SetMetricManager(mm)
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		w.Collect.Add(wmap.NewPublishNode("file", -1))
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(time.Minute, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+		tk.state = core.TaskSpinning
+		end := time.Now().Add(-time.Hour).Truncate(time.Second)
+		start := end.Add(-5 * time.Minute)
+
+		Convey("the workflow runs for every interval of the range", func() {
+			status, err := s.BackfillTask(tsk.ID(), core.Backfill{Start: start.Unix(), End: end.Unix()})
+			So(err, ShouldBeNil)
+			So(status.State, ShouldEqual, core.BackfillRunning)
+			So(status.Total, ShouldEqual, 6)
+
+			status = waitForBackfill(s, tsk.ID())
+			So(status, ShouldNotBeNil)
+			So(status.State, ShouldEqual, core.BackfillCompleted)
+			So(status.Runs, ShouldEqual, 6)
+			So(status.FailedRuns, ShouldEqual, 0)
+			So(status.Current, ShouldEqual, end.Unix())
+			times := mm.collectedAt()
+			So(times, ShouldHaveLength, 6)
+			for i, at := range times {
+				So(at.Unix(), ShouldEqual, start.Add(time.Duration(i)*time.Minute).Unix())
+			}
+			So(mm.published(), ShouldContain, 1)
+		})
+		Convey("a single backfill runs at a time and can be canceled", func() {
+			_, err := s.BackfillTask(tsk.ID(), core.Backfill{Start: start.Unix(), End: end.Unix(), Speed: 0.001})
+			So(err, ShouldBeNil)
+			_, err = s.BackfillTask(tsk.ID(), core.Backfill{Start: start.Unix(), End: end.Unix()})
+			So(err, ShouldEqual, ErrBackfillRunning)
+			status, err := s.CancelBackfill(tsk.ID())
+			So(err, ShouldBeNil)
+			So(status.State, ShouldEqual, core.BackfillCanceled)
+			So(status.Runs, ShouldBeLessThan, 6)
+			_, err = s.CancelBackfill(tsk.ID())
+			So(err, ShouldEqual, ErrNoBackfill)
+		})
+		Convey("the range must be in the past and not empty", func() {
+			_, err := s.BackfillTask(tsk.ID(), core.Backfill{Start: end.Unix(), End: start.Unix()})
+			So(err, ShouldEqual, ErrInvalidBackfillRange)
+			_, err = s.BackfillTask(tsk.ID(), core.Backfill{Start: start.Unix(), End: time.Now().Add(time.Hour).Unix()})
+			So(err, ShouldEqual, ErrInvalidBackfillRange)
+		})
+		Convey("a stopped task is not backfilled", func() {
+			tk.state = core.TaskStopped
+			_, err := s.BackfillTask(tsk.ID(), core.Backfill{Start: start.Unix(), End: end.Unix()})
+			So(err, ShouldEqual, ErrBackfillTaskNotRunning)
+		})
+		Convey("no backfill is reported before the first one", func() {
+			_, err := s.GetBackfill(tsk.ID())
+			So(err, ShouldEqual, ErrNoBackfill)
+		})
+	})
+	Convey("Given a metric manager which can not collect past metrics", t, func() {
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(&publishRecorder{mockMetricManager: newMockMetricManager()})
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		w.Collect.Add(wmap.NewPublishNode("file", -1))
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(time.Minute, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		_, err := s.BackfillTask(tsk.ID(), core.Backfill{Start: 1, End: 2})
+		So(err, ShouldEqual, ErrBackfillUnsupported)
+	})
+}
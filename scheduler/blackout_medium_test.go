@@ -0,0 +1,60 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+func TestBlackouts(t *testing.T) {
+	Convey("Given a running task with a blackout period", t, func() {
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(newMockMetricManager())
+		s.Start()
+		now := time.Now()
+		blackouts := []core.BlackoutPeriod{{Start: now, End: now.Add(10 * interval), Reason: "database upgrade"}}
+		tk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newMockWorkflowMap(), false, core.OptionBlackouts(blackouts))
+		So(errs.Errors(), ShouldBeEmpty)
+		So(tk.GetBlackouts(), ShouldResemble, blackouts)
+		tk.(*task).Spin()
+
+		Convey("its fires are suppressed until the period ends and counted apart from the missed ones", func() {
+			time.Sleep(5 * interval)
+			So(tk.HitCount(), ShouldEqual, 0)
+			So(tk.BlackedOutCount(), ShouldBeGreaterThan, 0)
+			time.Sleep(15 * interval)
+			So(tk.HitCount(), ShouldBeGreaterThan, 0)
+			So(tk.MissedCount(), ShouldBeLessThan, 5)
+			blackedOut := tk.BlackedOutCount()
+			time.Sleep(5 * interval)
+			So(tk.BlackedOutCount(), ShouldEqual, blackedOut)
+		})
+
+		s.Stop()
+	})
+}
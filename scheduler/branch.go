@@ -0,0 +1,158 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// metricCondition is the condition of a branch of a workflow, see
+// wmap.MetricCondition
+type metricCondition struct {
+	namespaces []namespacePattern
+	tags       map[string][]string
+}
+
+func newMetricCondition(c *wmap.MetricCondition) (*metricCondition, error) {
+	if len(c.Namespaces) == 0 && len(c.Tags) == 0 {
+		return nil, fmt.Errorf("Invalid condition (expected namespaces or tags)")
+	}
+	mc := &metricCondition{tags: c.Tags}
+	for _, ns := range c.Namespaces {
+		p, err := parseNamespacePattern(ns)
+		if err != nil {
+			return nil, fmt.Errorf("%v in condition", err)
+		}
+		mc.namespaces = append(mc.namespaces, p)
+	}
+	return mc, nil
+}
+
+// matches returns true when the metric meets the condition
+func (c *metricCondition) matches(m core.Metric) bool {
+	if len(c.namespaces) > 0 {
+		ns := m.Namespace()
+		matched := false
+		for _, p := range c.namespaces {
+			if p.matches(ns) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	tags := m.Tags()
+	for k, values := range c.tags {
+		tv, ok := tags[k]
+		if !ok || !(contains(values, tagFilterAny) || contains(values, tv)) {
+			return false
+		}
+	}
+	return true
+}
+
+// branch routes the metrics of a workflow to a process or publish node: the
+// metrics meeting the condition of the node, or for an otherwise branch the
+// metrics meeting none of the conditions of its siblings
+type branch struct {
+	when      *metricCondition
+	otherwise bool
+	// siblings are the conditions of the siblings of an otherwise branch
+	siblings []*metricCondition
+}
+
+// newBranch returns the branch of a node; nil when the node receives all the
+// metrics of its parent
+func newBranch(when *wmap.MetricCondition, otherwise bool) (*branch, error) {
+	if when != nil && otherwise {
+		return nil, fmt.Errorf("Invalid branch (expected a when condition or otherwise, not both)")
+	}
+	if otherwise {
+		return &branch{otherwise: true}, nil
+	}
+	if when == nil {
+		return nil, nil
+	}
+	c, err := newMetricCondition(when)
+	if err != nil {
+		return nil, err
+	}
+	return &branch{when: c}, nil
+}
+
+// selects returns true when the metric goes down the branch
+func (b *branch) selects(m core.Metric) bool {
+	if b == nil {
+		return true
+	}
+	if !b.otherwise {
+		return b.when.matches(m)
+	}
+	for _, c := range b.siblings {
+		if c.matches(m) {
+			return false
+		}
+	}
+	return true
+}
+
+// filter returns the metrics going down the branch
+func (b *branch) filter(mts []core.Metric) []core.Metric {
+	if b == nil {
+		return mts
+	}
+	out := make([]core.Metric, 0, len(mts))
+	for _, m := range mts {
+		if b.selects(m) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// linkBranches hands the otherwise branches among the children of a node the
+// conditions of their siblings
+func linkBranches(prs []*processNode, pus []*publishNode) {
+	var conditions []*metricCondition
+	var otherwise []*branch
+	add := func(b *branch) {
+		switch {
+		case b == nil:
+		case b.otherwise:
+			otherwise = append(otherwise, b)
+		default:
+			conditions = append(conditions, b.when)
+		}
+	}
+	for _, pr := range prs {
+		add(pr.branch)
+	}
+	for _, pu := range pus {
+		add(pu.branch)
+	}
+	for _, b := range otherwise {
+		b.siblings = conditions
+	}
+}
@@ -0,0 +1,152 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// branchRecorder records the namespaces of the metrics published by every
+// publisher
+type branchRecorder struct {
+	*mockMetricManager
+	sync.Mutex
+	published map[string][]string
+}
+
+func (m *branchRecorder) CollectMetrics(string, map[string]map[string]string) ([]core.Metric, []error) {
+	return []core.Metric{
+		plugin.MetricType{Namespace_: core.NewNamespace("foo", "bar"), Tags_: map[string]string{"env": "prod"}, Data_: 1},
+		plugin.MetricType{Namespace_: core.NewNamespace("foo", "baz"), Tags_: map[string]string{"env": "dev"}, Data_: 2},
+		plugin.MetricType{Namespace_: core.NewNamespace("foo", "qux"), Data_: 3},
+	}, nil
+}
+
+func (m *branchRecorder) PublishMetrics(mts []core.Metric, _ map[string]ctypes.ConfigValue, _ string, name string, _ int) []error {
+	m.Lock()
+	defer m.Unlock()
+	for _, mt := range mts {
+		m.published[name] = append(m.published[name], mt.Namespace().String())
+	}
+	return nil
+}
+
+func (m *branchRecorder) namespaces(name string) []string {
+	m.Lock()
+	defer m.Unlock()
+	return append([]string{}, m.published[name]...)
+}
+
+func TestMetricCondition(t *testing.T) {
+	Convey("Given metrics with namespaces and tags", t, func() {
+		prod := plugin.MetricType{Namespace_: core.NewNamespace("intel", "cpu", "load"), Tags_: map[string]string{"env": "prod"}}
+		dev := plugin.MetricType{Namespace_: core.NewNamespace("intel", "mem", "free"), Tags_: map[string]string{"env": "dev"}}
+		untagged := plugin.MetricType{Namespace_: core.NewNamespace("intel", "cpu", "idle")}
+
+		Convey("a namespace condition matches the namespaces under its patterns", func() {
+			c, err := newMetricCondition(&wmap.MetricCondition{Namespaces: []string{"/intel/cpu"}})
+			So(err, ShouldBeNil)
+			So(c.matches(prod), ShouldBeTrue)
+			So(c.matches(untagged), ShouldBeTrue)
+			So(c.matches(dev), ShouldBeFalse)
+			c, err = newMetricCondition(&wmap.MetricCondition{Namespaces: []string{"/intel/*/free"}})
+			So(err, ShouldBeNil)
+			So(c.matches(dev), ShouldBeTrue)
+			So(c.matches(prod), ShouldBeFalse)
+		})
+		Convey("a tag condition matches the metrics with one of the values of every tag", func() {
+			c, err := newMetricCondition(&wmap.MetricCondition{Tags: map[string][]string{"env": {"prod", "staging"}}})
+			So(err, ShouldBeNil)
+			So(c.matches(prod), ShouldBeTrue)
+			So(c.matches(dev), ShouldBeFalse)
+			So(c.matches(untagged), ShouldBeFalse)
+			c, err = newMetricCondition(&wmap.MetricCondition{Tags: map[string][]string{"env": {"*"}}})
+			So(err, ShouldBeNil)
+			So(c.matches(dev), ShouldBeTrue)
+			So(c.matches(untagged), ShouldBeFalse)
+		})
+		Convey("namespaces and tags must both match", func() {
+			c, err := newMetricCondition(&wmap.MetricCondition{Namespaces: []string{"/intel/cpu"}, Tags: map[string][]string{"env": {"prod"}}})
+			So(err, ShouldBeNil)
+			So(c.matches(prod), ShouldBeTrue)
+			So(c.matches(untagged), ShouldBeFalse)
+		})
+		Convey("an empty condition is invalid", func() {
+			_, err := newMetricCondition(&wmap.MetricCondition{})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("a node takes a condition or otherwise, not both", func() {
+			_, err := newBranch(&wmap.MetricCondition{Namespaces: []string{"/intel"}}, true)
+			So(err, ShouldNotBeNil)
+			b, err := newBranch(nil, false)
+			So(err, ShouldBeNil)
+			So(b.selects(dev), ShouldBeTrue)
+		})
+	})
+}
+
+func TestWorkflowBranches(t *testing.T) {
+	Convey("Given a task whose publishers are branches of the workflow", t, func() {
+		s := New(GetDefaultConfig())
+		mm := &branchRecorder{mockMetricManager: newMockMetricManager(), published: map[string][]string{}}
+		s.SetMetricManager(mm)
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/*", 1)
+		prod := wmap.NewPublishNode("influxdb", -1)
+		prod.When = &wmap.MetricCondition{Tags: map[string][]string{"env": {"prod"}}}
+		w.Collect.Add(prod)
+		baz := wmap.NewPublishNode("kafka", -1)
+		baz.When = &wmap.MetricCondition{Namespaces: []string{"/foo/baz"}}
+		w.Collect.Add(baz)
+		rest := wmap.NewPublishNode("file", -1)
+		rest.Otherwise = true
+		w.Collect.Add(rest)
+		all := wmap.NewPublishNode("mock", -1)
+		w.Collect.Add(all)
+
+		Convey("every metric is routed to the branches whose condition it meets", func() {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+			So(errs.Errors(), ShouldBeEmpty)
+			tk := tsk.(*task)
+			tk.killChan = make(chan struct{})
+			tk.fire()
+			So(mm.namespaces("influxdb"), ShouldResemble, []string{"/foo/bar"})
+			So(mm.namespaces("kafka"), ShouldResemble, []string{"/foo/baz"})
+			So(mm.namespaces("file"), ShouldResemble, []string{"/foo/qux"})
+			So(mm.namespaces("mock"), ShouldHaveLength, 3)
+		})
+		Convey("a task with an invalid condition is not created", func() {
+			baz.When.Namespaces = []string{"foo"}
+			_, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+			So(errs.Errors(), ShouldNotBeEmpty)
+		})
+	})
+}
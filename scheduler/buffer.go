@@ -0,0 +1,85 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+var (
+	// ErrInvalidBuffer - The error message for a publish buffer without a valid limit
+	ErrInvalidBuffer = errors.New("Publish buffer requires a positive number of intervals or metrics")
+)
+
+// metricBuffer holds the metrics received by a publish node until the
+// configured number of runs or metrics is reached.
+type metricBuffer struct {
+	sync.Mutex
+	intervals  int
+	maxMetrics int
+	runs       int
+	metrics    []core.Metric
+}
+
+func newMetricBuffer(b *wmap.BufferWorkflowMapNode) (*metricBuffer, error) {
+	if b == nil {
+		return nil, nil
+	}
+	if b.Intervals < 0 || b.Metrics < 0 || (b.Intervals == 0 && b.Metrics == 0) {
+		return nil, ErrInvalidBuffer
+	}
+	return &metricBuffer{
+		intervals:  b.Intervals,
+		maxMetrics: b.Metrics,
+	}, nil
+}
+
+// add buffers the metrics of a run and returns the batch to publish, or nil
+// when the limits of the buffer are not reached yet.
+func (b *metricBuffer) add(mts []core.Metric) []core.Metric {
+	b.Lock()
+	defer b.Unlock()
+	b.runs++
+	b.metrics = append(b.metrics, mts...)
+	if (b.intervals > 0 && b.runs >= b.intervals) ||
+		(b.maxMetrics > 0 && len(b.metrics) >= b.maxMetrics) {
+		return b.take()
+	}
+	return nil
+}
+
+// flush empties the buffer and returns the metrics it held
+func (b *metricBuffer) flush() []core.Metric {
+	b.Lock()
+	defer b.Unlock()
+	return b.take()
+}
+
+// take must be called with the buffer locked
+func (b *metricBuffer) take() []core.Metric {
+	mts := b.metrics
+	b.metrics = nil
+	b.runs = 0
+	return mts
+}
@@ -0,0 +1,118 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// publishRecorder records the size of every batch of metrics published
+type publishRecorder struct {
+	*mockMetricManager
+	sync.Mutex
+	batches []int
+}
+
+func (m *publishRecorder) CollectMetrics(string, map[string]map[string]string) ([]core.Metric, []error) {
+	return []core.Metric{
+		plugin.MetricType{Namespace_: core.NewNamespace("foo", "bar"), Data_: 1},
+		plugin.MetricType{Namespace_: core.NewNamespace("foo", "baz"), Data_: 2},
+	}, nil
+}
+
+func (m *publishRecorder) PublishMetrics(mts []core.Metric, _ map[string]ctypes.ConfigValue, _ string, _ string, _ int) []error {
+	m.Lock()
+	defer m.Unlock()
+	m.batches = append(m.batches, len(mts))
+	return nil
+}
+
+func (m *publishRecorder) published() []int {
+	m.Lock()
+	defer m.Unlock()
+	return append([]int{}, m.batches...)
+}
+
+func newBufferedWorkflowMap(b *wmap.BufferWorkflowMapNode) *wmap.WorkflowMap {
+	w := wmap.NewWorkflowMap()
+	w.Collect.AddMetric("/foo/bar", 1)
+	pu := wmap.NewPublishNode("file", -1)
+	pu.Buffer = b
+	w.Collect.Add(pu)
+	return w
+}
+
+func TestPublishBuffer(t *testing.T) {
+	Convey("Given a task whose publisher buffers metrics", t, func() {
+		s := New(GetDefaultConfig())
+		mm := &publishRecorder{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+
+		Convey("metrics are published every configured number of intervals", func() {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newBufferedWorkflowMap(&wmap.BufferWorkflowMapNode{Intervals: 3}), false)
+			So(errs.Errors(), ShouldBeEmpty)
+			tk := tsk.(*task)
+			tk.killChan = make(chan struct{})
+			tk.fire()
+			tk.fire()
+			So(mm.published(), ShouldBeEmpty)
+			tk.fire()
+			So(mm.published(), ShouldResemble, []int{6})
+
+			Convey("and the remaining metrics are published when the buffer is flushed", func() {
+				tk.fire()
+				tk.workflow.FlushBuffers(tk)
+				So(mm.published(), ShouldResemble, []int{6, 2})
+				tk.workflow.FlushBuffers(tk)
+				So(mm.published(), ShouldResemble, []int{6, 2})
+			})
+		})
+		Convey("metrics are published once the buffer holds enough metrics", func() {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newBufferedWorkflowMap(&wmap.BufferWorkflowMapNode{Metrics: 4}), false)
+			So(errs.Errors(), ShouldBeEmpty)
+			tk := tsk.(*task)
+			tk.killChan = make(chan struct{})
+			tk.fire()
+			So(mm.published(), ShouldBeEmpty)
+			tk.fire()
+			So(mm.published(), ShouldResemble, []int{4})
+		})
+		Convey("a buffer without limits is rejected", func() {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newBufferedWorkflowMap(&wmap.BufferWorkflowMapNode{}), false)
+			So(tsk, ShouldBeNil)
+			So(errs.Errors(), ShouldNotBeEmpty)
+			So(errs.Errors()[0].Error(), ShouldEqual, ErrInvalidBuffer.Error())
+		})
+
+		s.Stop()
+	})
+}
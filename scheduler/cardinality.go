@@ -0,0 +1,343 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	// defaultCardinalityTop is the number of namespace patterns reported by
+	// default
+	defaultCardinalityTop = 10
+)
+
+var (
+	// ErrInvalidCardinalityLimit - The error message for a cardinality limit which is not a positive number of namespaces
+	ErrInvalidCardinalityLimit = errors.New("Invalid cardinality limit (expected a number of namespaces above 0)")
+)
+
+var cardinalityLogger = schedulerLogger.WithField("_block", "cardinality")
+
+// cardinalityGuard tracks the unique metric namespaces produced by the
+// latest run of every task and applies the cardinality limits of the tasks
+// and the global one.
+type cardinalityGuard struct {
+	limit   int
+	mode    string
+	enforce bool
+
+	sync.Mutex
+	// tasks holds the namespaces of the latest run of the tasks by task id
+	tasks map[string]*taskNamespaces
+	// refs counts the tasks whose latest run kept each namespace
+	refs map[string]int
+}
+
+// taskNamespaces are the namespaces produced by the latest run of a task
+type taskNamespaces struct {
+	name  string
+	limit *core.TaskCardinalityLimit
+	// kept are the namespaces whose metrics were not dropped
+	kept     map[string]struct{}
+	produced int
+	// patterns counts the namespaces produced by namespace pattern
+	patterns map[string]int
+}
+
+// parseCardinalityMode validates the mode of a cardinality limit and returns
+// true when the limit is enforced
+func parseCardinalityMode(mode string) (bool, error) {
+	switch mode {
+	case "", core.CardinalityWarn:
+		return false, nil
+	case core.CardinalityEnforce:
+		return true, nil
+	default:
+		return false, fmt.Errorf("Unknown cardinality mode '%s' (expected warn or enforce)", mode)
+	}
+}
+
+// validateCardinalityLimit returns an error when the cardinality limit of a
+// task is invalid
+func validateCardinalityLimit(l *core.TaskCardinalityLimit) error {
+	if l.MaxNamespaces <= 0 {
+		return ErrInvalidCardinalityLimit
+	}
+	_, err := parseCardinalityMode(l.Mode)
+	return err
+}
+
+// newCardinalityGuard returns the guard of the configuration.  The
+// namespaces are tracked even when there is no global limit, for the
+// report.
+func newCardinalityGuard(cfg *Config) (*cardinalityGuard, error) {
+	g := &cardinalityGuard{
+		tasks: map[string]*taskNamespaces{},
+		refs:  map[string]int{},
+	}
+	if cfg.MaxMetricCardinality <= 0 {
+		return g, nil
+	}
+	enforce, err := parseCardinalityMode(cfg.CardinalityMode)
+	if err != nil {
+		return g, err
+	}
+	g.limit = cfg.MaxMetricCardinality
+	g.mode = cardinalityMode(cfg.CardinalityMode)
+	g.enforce = enforce
+	return g, nil
+}
+
+func cardinalityMode(mode string) string {
+	if mode == "" {
+		return core.CardinalityWarn
+	}
+	return mode
+}
+
+// guard records the namespaces of the metrics collected by a run of the
+// task and returns the metrics within the limits.  The namespaces kept by
+// the previous run of the task come first so that the series published go
+// on when new namespaces show up.  The namespaces of a run finishing after
+// the task is removed are not recorded.
+func (g *cardinalityGuard) guard(t *task, mts []core.Metric) []core.Metric {
+	keys := make([]string, len(mts))
+	order := []string{}
+	seen := map[string]struct{}{}
+	patterns := map[string]int{}
+	for i, m := range mts {
+		keys[i] = m.Namespace().String()
+		if _, ok := seen[keys[i]]; ok {
+			continue
+		}
+		seen[keys[i]] = struct{}{}
+		order = append(order, keys[i])
+		patterns[cardinalityPattern(m.Namespace())]++
+	}
+	limit := t.GetCardinalityLimit()
+
+	g.Lock()
+	if t.isRemoved() {
+		g.Unlock()
+		return mts
+	}
+	previous := g.tasks[t.id]
+	candidates := order
+	if previous != nil {
+		candidates = make([]string, 0, len(order))
+		for _, ns := range order {
+			if _, ok := previous.kept[ns]; ok {
+				candidates = append(candidates, ns)
+			}
+		}
+		for _, ns := range order {
+			if _, ok := previous.kept[ns]; !ok {
+				candidates = append(candidates, ns)
+			}
+		}
+		for ns := range previous.kept {
+			g.release(ns)
+		}
+	}
+	taskOver := limit != nil && len(candidates) > limit.MaxNamespaces
+	if taskOver {
+		if enforce, _ := parseCardinalityMode(limit.Mode); enforce {
+			candidates = candidates[:limit.MaxNamespaces]
+		}
+	}
+	globalOver := 0
+	kept := make(map[string]struct{}, len(candidates))
+	for _, ns := range candidates {
+		if _, ok := g.refs[ns]; !ok && g.limit > 0 && len(g.refs) >= g.limit {
+			globalOver++
+			if g.enforce {
+				continue
+			}
+		}
+		kept[ns] = struct{}{}
+		g.refs[ns]++
+	}
+	g.tasks[t.id] = &taskNamespaces{
+		name:     t.name,
+		limit:    limit,
+		kept:     kept,
+		produced: len(order),
+		patterns: patterns,
+	}
+	global := len(g.refs)
+	g.Unlock()
+
+	dropped := len(order) - len(kept)
+	if taskOver {
+		cardinalityLogger.WithFields(log.Fields{
+			"task-id":    t.id,
+			"task-name":  t.name,
+			"namespaces": len(order),
+			"limit":      limit.MaxNamespaces,
+			"mode":       cardinalityMode(limit.Mode),
+		}).Warn("Task produced more namespaces than its cardinality limit")
+	}
+	if globalOver > 0 {
+		cardinalityLogger.WithFields(log.Fields{
+			"task-id":        t.id,
+			"task-name":      t.name,
+			"new-namespaces": globalOver,
+			"namespaces":     global,
+			"limit":          g.limit,
+			"mode":           g.mode,
+		}).Warn("Global cardinality limit exceeded")
+	}
+	if dropped == 0 {
+		return mts
+	}
+	out := make([]core.Metric, 0, len(mts))
+	for i, m := range mts {
+		if _, ok := kept[keys[i]]; ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// release forgets a task keeping the namespace; the lock must be held
+func (g *cardinalityGuard) release(ns string) {
+	if g.refs[ns] <= 1 {
+		delete(g.refs, ns)
+		return
+	}
+	g.refs[ns]--
+}
+
+// forget removes the namespaces of a removed task; the task must be marked
+// removed first
+func (g *cardinalityGuard) forget(id string) {
+	g.Lock()
+	defer g.Unlock()
+	if tn, ok := g.tasks[id]; ok {
+		for ns := range tn.kept {
+			g.release(ns)
+		}
+		delete(g.tasks, id)
+	}
+}
+
+// report returns the unique namespaces of the tasks with the given number of
+// namespace patterns having the most of them, overall and by task.  The
+// patterns overall sum the namespaces of the tasks.
+func (g *cardinalityGuard) report(top int) core.CardinalityReport {
+	if top <= 0 {
+		top = defaultCardinalityTop
+	}
+	g.Lock()
+	defer g.Unlock()
+	r := core.CardinalityReport{
+		Limit:      g.limit,
+		Mode:       g.mode,
+		Namespaces: len(g.refs),
+		Tasks:      []core.TaskCardinality{},
+	}
+	all := map[string]int{}
+	for id, tn := range g.tasks {
+		tc := core.TaskCardinality{
+			TaskID:     id,
+			TaskName:   tn.name,
+			Namespaces: tn.produced,
+			Dropped:    tn.produced - len(tn.kept),
+			Top:        topPatterns(tn.patterns, top),
+		}
+		if tn.limit != nil {
+			tc.Limit = tn.limit.MaxNamespaces
+			tc.Mode = cardinalityMode(tn.limit.Mode)
+		}
+		for p, n := range tn.patterns {
+			all[p] += n
+		}
+		r.Tasks = append(r.Tasks, tc)
+	}
+	r.Top = topPatterns(all, top)
+	sort.Sort(taskCardinalities(r.Tasks))
+	return r
+}
+
+// cardinalityPattern returns the namespace with its dynamic elements replaced
+// by "*"
+func cardinalityPattern(ns core.Namespace) string {
+	p := make(core.Namespace, len(ns))
+	copy(p, ns)
+	for i := range p {
+		if p[i].IsDynamic() {
+			p[i].Value = namespaceAny
+		}
+	}
+	return p.String()
+}
+
+// topPatterns returns the given number of patterns with the most namespaces
+func topPatterns(patterns map[string]int, top int) []core.NamespaceCardinality {
+	nc := make([]core.NamespaceCardinality, 0, len(patterns))
+	for p, n := range patterns {
+		nc = append(nc, core.NamespaceCardinality{Namespace: p, Namespaces: n})
+	}
+	sort.Sort(namespaceCardinalities(nc))
+	if len(nc) > top {
+		nc = nc[:top]
+	}
+	return nc
+}
+
+// namespaceCardinalities sorts the patterns by decreasing number of
+// namespaces
+type namespaceCardinalities []core.NamespaceCardinality
+
+func (n namespaceCardinalities) Len() int      { return len(n) }
+func (n namespaceCardinalities) Swap(i, j int) { n[i], n[j] = n[j], n[i] }
+func (n namespaceCardinalities) Less(i, j int) bool {
+	if n[i].Namespaces != n[j].Namespaces {
+		return n[i].Namespaces > n[j].Namespaces
+	}
+	return n[i].Namespace < n[j].Namespace
+}
+
+// taskCardinalities sorts the tasks by decreasing number of namespaces
+type taskCardinalities []core.TaskCardinality
+
+func (t taskCardinalities) Len() int      { return len(t) }
+func (t taskCardinalities) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t taskCardinalities) Less(i, j int) bool {
+	if t[i].Namespaces != t[j].Namespaces {
+		return t[i].Namespaces > t[j].Namespaces
+	}
+	return t[i].TaskID < t[j].TaskID
+}
+
+// Cardinality returns the unique metric namespaces produced by the latest
+// run of the tasks, with the given number of namespace patterns having the
+// most of them
+func (s *scheduler) Cardinality(top int) core.CardinalityReport {
+	return s.cardinality.report(top)
+}
@@ -0,0 +1,168 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func TestCardinalityGuard(t *testing.T) {
+	// disks returns the metrics of the given disks, read by a wildcard
+	// collector
+	disks := func(ids ...int) []core.Metric {
+		mts := []core.Metric{}
+		for _, id := range ids {
+			ns := core.NewNamespace("intel", "disk").AddDynamicElement("disk", "disk id").AddStaticElement("read")
+			ns[2].Value = fmt.Sprintf("sd%d", id)
+			mts = append(mts, plugin.MetricType{Namespace_: ns, Data_: id})
+		}
+		return mts
+	}
+	names := func(mts []core.Metric) []string {
+		out := make([]string, len(mts))
+		for i, m := range mts {
+			out[i] = m.Namespace().String()
+		}
+		return out
+	}
+
+	Convey("Given a task enforcing its cardinality limit", t, func() {
+		g, err := newCardinalityGuard(GetDefaultConfig())
+		So(err, ShouldBeNil)
+		tk := &task{id: "t1", name: "disks"}
+		tk.SetCardinalityLimit(&core.TaskCardinalityLimit{MaxNamespaces: 2, Mode: core.CardinalityEnforce})
+
+		Convey("the metrics of the namespaces beyond the limit are dropped", func() {
+			out := g.guard(tk, disks(1, 2, 3, 1))
+			So(names(out), ShouldResemble, []string{"/intel/disk/sd1/read", "/intel/disk/sd2/read", "/intel/disk/sd1/read"})
+		})
+		Convey("the namespaces of the previous run are kept first", func() {
+			g.guard(tk, disks(2, 3))
+			out := g.guard(tk, disks(1, 2, 3))
+			So(names(out), ShouldResemble, []string{"/intel/disk/sd2/read", "/intel/disk/sd3/read"})
+		})
+		Convey("the report gives the namespaces by pattern", func() {
+			g.guard(tk, disks(1, 2, 3))
+			r := g.report(0)
+			So(r.Namespaces, ShouldEqual, 2)
+			So(r.Tasks, ShouldHaveLength, 1)
+			So(r.Tasks[0].Namespaces, ShouldEqual, 3)
+			So(r.Tasks[0].Dropped, ShouldEqual, 1)
+			So(r.Tasks[0].Limit, ShouldEqual, 2)
+			So(r.Top, ShouldResemble, []core.NamespaceCardinality{{Namespace: "/intel/disk/*/read", Namespaces: 3}})
+			tk.markRemoved()
+			g.forget(tk.id)
+			So(g.report(0).Namespaces, ShouldEqual, 0)
+		})
+		Convey("a run finishing after the task is removed is not recorded", func() {
+			g.guard(tk, disks(1, 2))
+			tk.markRemoved()
+			g.forget(tk.id)
+			So(g.guard(tk, disks(1, 2)), ShouldHaveLength, 2)
+			r := g.report(0)
+			So(r.Namespaces, ShouldEqual, 0)
+			So(r.Tasks, ShouldBeEmpty)
+
+			Convey("while a new task of the same id is", func() {
+				again := &task{id: tk.id, name: "disks"}
+				g.guard(again, disks(1))
+				So(g.report(0).Tasks, ShouldHaveLength, 1)
+			})
+		})
+	})
+
+	Convey("Given a task warning about its cardinality limit", t, func() {
+		g, _ := newCardinalityGuard(GetDefaultConfig())
+		tk := &task{id: "t1", name: "disks"}
+		tk.SetCardinalityLimit(&core.TaskCardinalityLimit{MaxNamespaces: 2})
+
+		Convey("no metric is dropped", func() {
+			So(g.guard(tk, disks(1, 2, 3)), ShouldHaveLength, 3)
+			So(g.report(0).Tasks[0].Mode, ShouldEqual, core.CardinalityWarn)
+		})
+	})
+
+	Convey("Given a global cardinality limit", t, func() {
+		cfg := GetDefaultConfig()
+		cfg.MaxMetricCardinality = 3
+		cfg.CardinalityMode = core.CardinalityEnforce
+		g, err := newCardinalityGuard(cfg)
+		So(err, ShouldBeNil)
+		t1 := &task{id: "t1", name: "one"}
+		t2 := &task{id: "t2", name: "two"}
+
+		Convey("the namespaces shared by the tasks are counted once", func() {
+			So(g.guard(t1, disks(1, 2)), ShouldHaveLength, 2)
+			So(g.guard(t2, disks(2, 3, 4)), ShouldHaveLength, 2)
+			r := g.report(1)
+			So(r.Namespaces, ShouldEqual, 3)
+			So(r.Tasks[0].TaskID, ShouldEqual, "t2")
+			So(r.Tasks[0].Dropped, ShouldEqual, 1)
+			So(r.Top, ShouldHaveLength, 1)
+		})
+		Convey("the namespaces of a removed task are released", func() {
+			g.guard(t1, disks(1, 2, 3))
+			t1.markRemoved()
+			g.forget(t1.id)
+			So(g.guard(t2, disks(4, 5, 6)), ShouldHaveLength, 3)
+		})
+	})
+
+	Convey("Invalid cardinality limits are rejected", t, func() {
+		So(validateCardinalityLimit(&core.TaskCardinalityLimit{}), ShouldEqual, ErrInvalidCardinalityLimit)
+		So(validateCardinalityLimit(&core.TaskCardinalityLimit{MaxNamespaces: 1, Mode: "drop"}), ShouldNotBeNil)
+		_, err := parseCardinalityMode("strict")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Given a scheduler", t, func() {
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(newMockMetricManager())
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		w.Collect.Add(wmap.NewPublishNode("file", -1))
+
+		Convey("a task is created with its cardinality limit", func() {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false,
+				core.OptionCardinalityLimit(&core.TaskCardinalityLimit{MaxNamespaces: 10, Mode: core.CardinalityEnforce}))
+			So(errs.Errors(), ShouldBeEmpty)
+			So(tsk.(*task).GetCardinalityLimit().MaxNamespaces, ShouldEqual, 10)
+			So(s.Cardinality(0).Limit, ShouldEqual, 0)
+		})
+		Convey("an invalid cardinality limit fails the creation of the task", func() {
+			_, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false,
+				core.OptionCardinalityLimit(&core.TaskCardinalityLimit{MaxNamespaces: 0}))
+			So(errs.Errors(), ShouldNotBeEmpty)
+		})
+
+		s.Stop()
+	})
+}
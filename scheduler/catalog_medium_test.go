@@ -0,0 +1,183 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+var errMockPluginNotLoaded = errors.New("plugin not loaded")
+
+// churningMetricManager simulates a plugin catalog which is continuously
+// changing while tasks are created.
+type churningMetricManager struct {
+	*mockMetricManager
+	// loaded is 1 when the plugin needed by the tasks is loaded
+	loaded int32
+	// generation is bumped every time the plugin is loaded or unloaded
+	generation uint64
+	// changesLeft is the number of validations during which the catalog
+	// changes; -1 means the catalog is changed on every validation
+	changesLeft  int32
+	validateRuns int32
+	subscribed   map[string]struct{}
+	sync.Mutex
+}
+
+func newChurningMetricManager() *churningMetricManager {
+	return &churningMetricManager{
+		mockMetricManager: newMockMetricManager(),
+		loaded:            1,
+		subscribed:        map[string]struct{}{},
+	}
+}
+
+func (m *churningMetricManager) CatalogGeneration() uint64 {
+	return atomic.LoadUint64(&m.generation)
+}
+
+func (m *churningMetricManager) toggle() {
+	if atomic.LoadInt32(&m.loaded) == 1 {
+		atomic.StoreInt32(&m.loaded, 0)
+	} else {
+		atomic.StoreInt32(&m.loaded, 1)
+	}
+	atomic.AddUint64(&m.generation, 1)
+}
+
+func (m *churningMetricManager) ValidateDeps(mts []core.RequestedMetric, prs []core.SubscribedPlugin, ctree *cdata.ConfigDataTree, asserts ...core.SubscribedPluginAssert) []serror.SnapError {
+	atomic.AddInt32(&m.validateRuns, 1)
+	loaded := atomic.LoadInt32(&m.loaded) == 1
+	if left := atomic.LoadInt32(&m.changesLeft); left != 0 {
+		atomic.AddUint64(&m.generation, 1)
+		if left > 0 {
+			atomic.AddInt32(&m.changesLeft, -1)
+		}
+	}
+	if !loaded {
+		return []serror.SnapError{serror.New(errMockPluginNotLoaded)}
+	}
+	return nil
+}
+
+func (m *churningMetricManager) SubscribeDeps(taskID string, reqs []core.RequestedMetric, prs []core.SubscribedPlugin, ctree *cdata.ConfigDataTree) []serror.SnapError {
+	if atomic.LoadInt32(&m.loaded) != 1 {
+		return []serror.SnapError{serror.New(errMockPluginNotLoaded)}
+	}
+	m.Lock()
+	defer m.Unlock()
+	m.subscribed[taskID] = struct{}{}
+	return nil
+}
+
+func (m *churningMetricManager) UnsubscribeDeps(taskID string) []serror.SnapError {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.subscribed, taskID)
+	return nil
+}
+
+func TestCreateTaskCatalogChurn(t *testing.T) {
+	Convey("Given a scheduler whose plugin catalog changes during validation", t, func() {
+		s := New(GetDefaultConfig())
+		mm := newChurningMetricManager()
+		s.SetMetricManager(mm)
+		s.Start()
+		w := newMockWorkflowMap()
+
+		Convey("validation is retried until the catalog is stable", func() {
+			atomic.StoreInt32(&mm.changesLeft, 2)
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+			So(errs.Errors(), ShouldBeEmpty)
+			So(tsk, ShouldNotBeNil)
+			So(atomic.LoadInt32(&mm.validateRuns), ShouldEqual, 3)
+		})
+		Convey("an error is returned when the catalog never settles", func() {
+			atomic.StoreInt32(&mm.changesLeft, -1)
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+			So(tsk, ShouldBeNil)
+			So(errs.Errors(), ShouldNotBeEmpty)
+			So(errs.Errors()[len(errs.Errors())-1].Error(), ShouldEqual, ErrCatalogChanged.Error())
+			So(atomic.LoadInt32(&mm.validateRuns), ShouldEqual, maxCatalogRetries+1)
+		})
+	})
+}
+
+func TestCreateTaskCatalogChurnStress(t *testing.T) {
+	Convey("Given plugins which are continuously loaded and unloaded", t, func() {
+		s := New(GetDefaultConfig())
+		mm := newChurningMetricManager()
+		s.SetMetricManager(mm)
+		s.Start()
+
+		done := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					mm.toggle()
+					time.Sleep(time.Millisecond)
+				}
+			}
+		}()
+
+		Convey("tasks can be created and started concurrently", func() {
+			var created, failed int32
+			wg := sync.WaitGroup{}
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 25; j++ {
+						tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(time.Second, nil, nil, 0), newMockWorkflowMap(), true)
+						if tsk == nil {
+							atomic.AddInt32(&failed, 1)
+							continue
+						}
+						atomic.AddInt32(&created, 1)
+						if len(errs.Errors()) == 0 {
+							s.StopTask(tsk.ID())
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			close(done)
+
+			So(created+failed, ShouldEqual, 200)
+			So(len(s.GetTasks()), ShouldEqual, int(created))
+		})
+	})
+}
@@ -0,0 +1,71 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/chrono"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func TestTaskClock(t *testing.T) {
+	Convey("Given a task spinning on a fake clock", t, func() {
+		start := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := chrono.NewFakeClock(start)
+		s := New(GetDefaultConfig())
+		s.clock = clock
+		s.SetMetricManager(&publishRecorder{mockMetricManager: newMockMetricManager()})
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		w.Collect.Add(wmap.NewPublishNode("file", -1))
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(time.Minute, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+		tk.Spin()
+		defer tk.Stop()
+
+		Convey("it fires at once then once per interval of the clock", func() {
+			// the task waits on its schedule once it fired
+			clock.BlockUntil(1)
+			So(tk.HitCount(), ShouldEqual, 1)
+			So(*tk.LastRunTime(), ShouldResemble, start)
+
+			clock.Advance(59 * time.Second)
+			So(clock.Waiters(), ShouldEqual, 1)
+			So(tk.HitCount(), ShouldEqual, 1)
+
+			clock.Advance(time.Second)
+			clock.BlockUntil(1)
+			So(tk.HitCount(), ShouldEqual, 2)
+			So(*tk.LastRunTime(), ShouldResemble, start.Add(time.Minute))
+			So(tk.LastRunResult().Duration, ShouldEqual, 0)
+			So(tk.State(), ShouldEqual, core.TaskSpinning)
+		})
+	})
+}
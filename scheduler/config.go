@@ -22,12 +22,26 @@ package scheduler
 import (
 	"encoding/json"
 	"fmt"
+	"time"
+
+	"github.com/vrischmann/jsonutil"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/eventbus"
 )
 
 // default configuration values
 const (
 	defaultWorkManagerQueueSize uint = 25
 	defaultWorkManagerPoolSize  uint = 4
+	defaultEventBatchInterval        = 0 * time.Second
+	defaultEventBufferSize           = 0
+	defaultEventOverflowPolicy       = "block"
+	defaultEventLogRetention         = 10000
+	defaultTraceSampleRatio          = 1.0
+	defaultInflightPolicy            = inflightSpill
+	defaultCardinalityMode           = core.CardinalityWarn
+	defaultDedupWindow               = 1024
 )
 
 // holds the configuration passed in through the SNAP config file
@@ -37,6 +51,83 @@ const (
 type Config struct {
 	WorkManagerQueueSize uint `json:"work_manager_queue_size"yaml:"work_manager_queue_size"`
 	WorkManagerPoolSize  uint `json:"work_manager_pool_size"yaml:"work_manager_pool_size"`
+	// EventBatchInterval is the period at which the events emitted on every
+	// task fire are coalesced; zero disables batching
+	EventBatchInterval jsonutil.Duration `json:"event_batch_interval"yaml:"event_batch_interval"`
+	// EventBufferSize is the number of events queued for each handler of the
+	// scheduler events, which are then delivered asynchronously; zero
+	// delivers the events synchronously
+	EventBufferSize int `json:"event_buffer_size"yaml:"event_buffer_size"`
+	// EventOverflowPolicy tells what happens to an event when the queue of
+	// a handler is full: "block", "drop-newest" or "drop-oldest"
+	EventOverflowPolicy string `json:"event_overflow_policy"yaml:"event_overflow_policy"`
+	// EventLogPath is the file the task lifecycle events are appended to;
+	// the events are only kept in memory when it is not set
+	EventLogPath string `json:"event_log_path"yaml:"event_log_path"`
+	// EventLogRetention is the number of events kept in the event log
+	EventLogRetention int `json:"event_log_retention"yaml:"event_log_retention"`
+	// TraceEndpoint is the URL of the OpenTelemetry collector the spans of
+	// the task runs are exported to with OTLP over HTTP; empty disables
+	// tracing
+	TraceEndpoint string `json:"trace_endpoint"yaml:"trace_endpoint"`
+	// TraceSampleRatio is the ratio of the task runs traced, from 0 to 1
+	TraceSampleRatio float64 `json:"trace_sample_ratio"yaml:"trace_sample_ratio"`
+	// CollectTimeout, ProcessTimeout and PublishTimeout are the timeouts of
+	// the phases of the tasks which do not set their own; zero falls back to
+	// the deadline of the task
+	CollectTimeout jsonutil.Duration `json:"collect_timeout"yaml:"collect_timeout"`
+	ProcessTimeout jsonutil.Duration `json:"process_timeout"yaml:"process_timeout"`
+	PublishTimeout jsonutil.Duration `json:"publish_timeout"yaml:"publish_timeout"`
+	// TaskPresets are the named sets of task options task manifests may
+	// refer to with their "preset" field
+	TaskPresets core.TaskPresets `json:"task_presets,omitempty"yaml:"task_presets"`
+	// TaskDefaults are the task options applied to the tasks created without
+	// their own
+	TaskDefaults *core.TaskPreset `json:"task_defaults,omitempty"yaml:"task_defaults"`
+	// TaskTemplatePath is the directory the task templates are stored in;
+	// the templates are only kept in memory when it is not set
+	TaskTemplatePath string `json:"task_template_path"yaml:"task_template_path"`
+	// AggregationOnly lists the namespaces whose metrics are only published
+	// as aggregates over a minimum number of series
+	AggregationOnly []AggregationOnlyRule `json:"aggregation_only,omitempty"yaml:"aggregation_only"`
+	// DeprecatedMetrics lists the namespaces the task advisor reports the
+	// tasks collecting
+	DeprecatedMetrics []DeprecatedMetric `json:"deprecated_metrics,omitempty"yaml:"deprecated_metrics"`
+	// MaxInflightMemory bounds the estimated size, in bytes, of the metrics
+	// being published; zero does not bound it
+	MaxInflightMemory int64 `json:"max_inflight_memory"yaml:"max_inflight_memory"`
+	// InflightOverflowPolicy tells what happens to a batch of metrics which
+	// does not fit in MaxInflightMemory: "spill", the default, or "drop"
+	InflightOverflowPolicy string `json:"inflight_overflow_policy"yaml:"inflight_overflow_policy"`
+	// InflightSpillPath is the directory the batches are spilled to, the
+	// snap-spill directory of the temporary directory by default
+	InflightSpillPath string `json:"inflight_spill_path"yaml:"inflight_spill_path"`
+	// DedupPath is the directory the batches acknowledged by the publish
+	// nodes deduplicating them are recorded in; they are only kept in memory
+	// when it is not set
+	DedupPath string `json:"dedup_path"yaml:"dedup_path"`
+	// DedupWindow is the number of batches recorded for each publish node
+	// deduplicating them
+	DedupWindow int `json:"dedup_window"yaml:"dedup_window"`
+	// FilePublisherPath is the directory the built-in file publisher writes
+	// into, the paths of its publish nodes being relative to it; the
+	// snap-published directory of the temporary directory by default
+	FilePublisherPath string `json:"file_publisher_path"yaml:"file_publisher_path"`
+	// MaxMetricCardinality bounds the unique metric namespaces collected by
+	// the latest run of all the tasks; zero does not bound them
+	MaxMetricCardinality int `json:"max_metric_cardinality"yaml:"max_metric_cardinality"`
+	// CardinalityMode tells what happens to the namespaces beyond
+	// MaxMetricCardinality: "warn", the default, logs them and "enforce"
+	// drops their metrics
+	CardinalityMode string `json:"cardinality_mode"yaml:"cardinality_mode"`
+	// MaxTasks caps the number of tasks, MaxTasksPerPlugin the number of
+	// tasks using the same plugin and MaxSubscriptionsPerMetric the number of
+	// tasks collecting the same metric; zero does not cap them
+	MaxTasks                  int `json:"max_tasks"yaml:"max_tasks"`
+	MaxTasksPerPlugin         int `json:"max_tasks_per_plugin"yaml:"max_tasks_per_plugin"`
+	MaxSubscriptionsPerMetric int `json:"max_subscriptions_per_metric"yaml:"max_subscriptions_per_metric"`
+	// TenantQuotas are the quotas of the tasks of the tenants, by tenant
+	TenantQuotas map[string]TenantQuota `json:"tenant_quotas,omitempty"yaml:"tenant_quotas"`
 }
 
 const (
@@ -51,6 +142,178 @@ const (
 					"work_manager_pool_size" : {
 						"type": "integer",
 						"minimum": 1
+					},
+					"event_batch_interval" : {
+						"type": "string"
+					},
+					"event_buffer_size" : {
+						"type": "integer",
+						"minimum": 0
+					},
+					"event_overflow_policy" : {
+						"type": "string",
+						"enum": ["block", "drop-newest", "drop-oldest"]
+					},
+					"event_log_path" : {
+						"type": "string"
+					},
+					"task_template_path" : {
+						"type": "string"
+					},
+					"event_log_retention" : {
+						"type": "integer",
+						"minimum": 1
+					},
+					"trace_endpoint" : {
+						"type": "string"
+					},
+					"trace_sample_ratio" : {
+						"type": "number",
+						"minimum": 0,
+						"maximum": 1
+					},
+					"collect_timeout" : {
+						"type": "string"
+					},
+					"process_timeout" : {
+						"type": "string"
+					},
+					"publish_timeout" : {
+						"type": "string"
+					},
+					"task_presets" : {
+						"type": ["object", "null"],
+						"properties" : {},
+						"additionalProperties": {
+							"type": ["object", "null"],
+							"properties" : {
+								"deadline" : { "type": "string" },
+								"max-failures" : { "type": "integer" },
+								"max-collect-duration" : { "type": "string" },
+								"max-metrics-buffer" : { "type": "integer" },
+								"failure-policy" : { "type": "string" },
+								"failure-cooldown" : { "type": "string" },
+								"singleton" : { "type": "boolean" },
+								"queue" : { "type": "object" },
+								"pinned-thread" : { "type": "object" },
+								"publish-rate-limit" : { "type": "object" },
+								"cardinality-limit" : { "type": "object" },
+								"blackouts" : { "type": "array" },
+								"collect-timeout" : { "type": "string" },
+								"process-timeout" : { "type": "string" },
+								"publish-timeout" : { "type": "string" },
+								"labels" : { "type": "object" }
+							},
+							"additionalProperties": false
+						}
+					},
+					"task_defaults" : {
+						"type": ["object", "null"],
+						"properties" : {
+							"deadline" : { "type": "string" },
+							"max-failures" : { "type": "integer" },
+							"max-collect-duration" : { "type": "string" },
+							"max-metrics-buffer" : { "type": "integer" },
+							"failure-policy" : { "type": "string" },
+							"failure-cooldown" : { "type": "string" },
+							"singleton" : { "type": "boolean" },
+							"queue" : { "type": "object" },
+							"pinned-thread" : { "type": "object" },
+							"publish-rate-limit" : { "type": "object" },
+							"cardinality-limit" : { "type": "object" },
+							"blackouts" : { "type": "array" },
+							"collect-timeout" : { "type": "string" },
+							"process-timeout" : { "type": "string" },
+							"publish-timeout" : { "type": "string" },
+							"labels" : { "type": "object" }
+						},
+						"additionalProperties": false
+					},
+					"aggregation_only" : {
+						"type": ["array", "null"],
+						"items": {
+							"type": "object",
+							"properties" : {
+								"namespace" : { "type": "string" },
+								"min_group_size" : {
+									"type": "integer",
+									"minimum": 1
+								}
+							},
+							"required": ["namespace", "min_group_size"],
+							"additionalProperties": false
+						}
+					},
+					"deprecated_metrics" : {
+						"type": ["array", "null"],
+						"items": {
+							"type": "object",
+							"properties" : {
+								"namespace" : { "type": "string" },
+								"replacement" : { "type": "string" },
+								"reason" : { "type": "string" }
+							},
+							"required": ["namespace"],
+							"additionalProperties": false
+						}
+					},
+					"max_inflight_memory" : {
+						"type": "integer",
+						"minimum": 0
+					},
+					"inflight_overflow_policy" : {
+						"type": "string",
+						"enum": ["spill", "drop"]
+					},
+					"inflight_spill_path" : {
+						"type": "string"
+					},
+					"dedup_path" : {
+						"type": "string"
+					},
+					"file_publisher_path" : {
+						"type": "string"
+					},
+					"dedup_window" : {
+						"type": "integer",
+						"minimum": 1
+					},
+					"max_metric_cardinality" : {
+						"type": "integer",
+						"minimum": 0
+					},
+					"cardinality_mode" : {
+						"type": "string",
+						"enum": ["warn", "enforce"]
+					},
+					"max_tasks" : {
+						"type": "integer",
+						"minimum": 0
+					},
+					"max_tasks_per_plugin" : {
+						"type": "integer",
+						"minimum": 0
+					},
+					"max_subscriptions_per_metric" : {
+						"type": "integer",
+						"minimum": 0
+					},
+					"tenant_quotas" : {
+						"type": ["object", "null"],
+						"additionalProperties": {
+							"type": "object",
+							"properties" : {
+								"max_tasks" : {
+									"type": "integer",
+									"minimum": 0
+								},
+								"max_metrics_per_second" : {
+									"type": "number",
+									"minimum": 0
+								}
+							},
+							"additionalProperties": false
+						}
 					}
 				},
 				"additionalProperties": false
@@ -61,8 +324,16 @@ const (
 // get the default snapteld configuration
 func GetDefaultConfig() *Config {
 	return &Config{
-		WorkManagerQueueSize: defaultWorkManagerQueueSize,
-		WorkManagerPoolSize:  defaultWorkManagerPoolSize,
+		WorkManagerQueueSize:   defaultWorkManagerQueueSize,
+		WorkManagerPoolSize:    defaultWorkManagerPoolSize,
+		EventBatchInterval:     jsonutil.Duration{Duration: defaultEventBatchInterval},
+		EventBufferSize:        defaultEventBufferSize,
+		EventOverflowPolicy:    defaultEventOverflowPolicy,
+		EventLogRetention:      defaultEventLogRetention,
+		TraceSampleRatio:       defaultTraceSampleRatio,
+		InflightOverflowPolicy: defaultInflightPolicy,
+		CardinalityMode:        defaultCardinalityMode,
+		DedupWindow:            defaultDedupWindow,
 	}
 }
 
@@ -88,6 +359,140 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 			if err := json.Unmarshal(v, &(c.WorkManagerPoolSize)); err != nil {
 				return fmt.Errorf("%v (while parsing 'scheduler::work_manager_pool_size')", err)
 			}
+		case "event_batch_interval":
+			if err := json.Unmarshal(v, &(c.EventBatchInterval)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::event_batch_interval')", err)
+			}
+		case "event_buffer_size":
+			if err := json.Unmarshal(v, &(c.EventBufferSize)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::event_buffer_size')", err)
+			}
+		case "event_overflow_policy":
+			if err := json.Unmarshal(v, &(c.EventOverflowPolicy)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::event_overflow_policy')", err)
+			}
+			if _, err := eventbus.ParsePolicy(c.EventOverflowPolicy); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::event_overflow_policy')", err)
+			}
+		case "event_log_path":
+			if err := json.Unmarshal(v, &(c.EventLogPath)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::event_log_path')", err)
+			}
+		case "task_template_path":
+			if err := json.Unmarshal(v, &(c.TaskTemplatePath)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::task_template_path')", err)
+			}
+		case "event_log_retention":
+			if err := json.Unmarshal(v, &(c.EventLogRetention)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::event_log_retention')", err)
+			}
+		case "trace_endpoint":
+			if err := json.Unmarshal(v, &(c.TraceEndpoint)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::trace_endpoint')", err)
+			}
+		case "trace_sample_ratio":
+			if err := json.Unmarshal(v, &(c.TraceSampleRatio)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::trace_sample_ratio')", err)
+			}
+		case "collect_timeout":
+			if err := json.Unmarshal(v, &(c.CollectTimeout)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::collect_timeout')", err)
+			}
+		case "process_timeout":
+			if err := json.Unmarshal(v, &(c.ProcessTimeout)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::process_timeout')", err)
+			}
+		case "publish_timeout":
+			if err := json.Unmarshal(v, &(c.PublishTimeout)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::publish_timeout')", err)
+			}
+		case "task_presets":
+			if err := json.Unmarshal(v, &(c.TaskPresets)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::task_presets')", err)
+			}
+			if err := c.TaskPresets.Validate(); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::task_presets')", err)
+			}
+		case "task_defaults":
+			if err := json.Unmarshal(v, &(c.TaskDefaults)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::task_defaults')", err)
+			}
+			if c.TaskDefaults != nil {
+				if err := c.TaskDefaults.Validate(); err != nil {
+					return fmt.Errorf("%v (while parsing 'scheduler::task_defaults')", err)
+				}
+			}
+		case "aggregation_only":
+			if err := json.Unmarshal(v, &(c.AggregationOnly)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::aggregation_only')", err)
+			}
+			if _, err := newExportPolicy(c.AggregationOnly); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::aggregation_only')", err)
+			}
+		case "deprecated_metrics":
+			if err := json.Unmarshal(v, &(c.DeprecatedMetrics)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::deprecated_metrics')", err)
+			}
+			if _, err := newDeprecatedMetrics(c.DeprecatedMetrics); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::deprecated_metrics')", err)
+			}
+		case "max_inflight_memory":
+			if err := json.Unmarshal(v, &(c.MaxInflightMemory)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::max_inflight_memory')", err)
+			}
+		case "inflight_overflow_policy":
+			if err := json.Unmarshal(v, &(c.InflightOverflowPolicy)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::inflight_overflow_policy')", err)
+			}
+			if _, err := parseInflightPolicy(c.InflightOverflowPolicy); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::inflight_overflow_policy')", err)
+			}
+		case "inflight_spill_path":
+			if err := json.Unmarshal(v, &(c.InflightSpillPath)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::inflight_spill_path')", err)
+			}
+		case "dedup_path":
+			if err := json.Unmarshal(v, &(c.DedupPath)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::dedup_path')", err)
+			}
+		case "file_publisher_path":
+			if err := json.Unmarshal(v, &(c.FilePublisherPath)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::file_publisher_path')", err)
+			}
+		case "dedup_window":
+			if err := json.Unmarshal(v, &(c.DedupWindow)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::dedup_window')", err)
+			}
+		case "max_metric_cardinality":
+			if err := json.Unmarshal(v, &(c.MaxMetricCardinality)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::max_metric_cardinality')", err)
+			}
+		case "cardinality_mode":
+			if err := json.Unmarshal(v, &(c.CardinalityMode)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::cardinality_mode')", err)
+			}
+			if _, err := parseCardinalityMode(c.CardinalityMode); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::cardinality_mode')", err)
+			}
+		case "max_tasks":
+			if err := json.Unmarshal(v, &(c.MaxTasks)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::max_tasks')", err)
+			}
+		case "max_tasks_per_plugin":
+			if err := json.Unmarshal(v, &(c.MaxTasksPerPlugin)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::max_tasks_per_plugin')", err)
+			}
+		case "max_subscriptions_per_metric":
+			if err := json.Unmarshal(v, &(c.MaxSubscriptionsPerMetric)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::max_subscriptions_per_metric')", err)
+			}
+		case "tenant_quotas":
+			if err := json.Unmarshal(v, &(c.TenantQuotas)); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::tenant_quotas')", err)
+			}
+			if _, err := newTenantQuotas(c.TenantQuotas); err != nil {
+				return fmt.Errorf("%v (while parsing 'scheduler::tenant_quotas')", err)
+			}
 		default:
 			return fmt.Errorf("Unrecognized key '%v' in global config file while parsing 'scheduler'", k)
 		}
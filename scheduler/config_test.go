@@ -23,6 +23,7 @@ package scheduler
 
 import (
 	"testing"
+	"time"
 
 	"github.com/intelsdi-x/snap/pkg/cfgfile"
 	. "github.com/smartystreets/goconvey/convey"
@@ -70,6 +71,15 @@ func TestSchedulerConfigJSON(t *testing.T) {
 		Convey("WorkManagerPoolSize should equal 2", func() {
 			So(cfg.WorkManagerPoolSize, ShouldEqual, 2)
 		})
+		Convey("EventBatchInterval should equal 1s", func() {
+			So(cfg.EventBatchInterval.Duration, ShouldEqual, time.Second)
+		})
+		Convey("EventBufferSize should equal 1000", func() {
+			So(cfg.EventBufferSize, ShouldEqual, 1000)
+		})
+		Convey("EventOverflowPolicy should equal drop-oldest", func() {
+			So(cfg.EventOverflowPolicy, ShouldEqual, "drop-oldest")
+		})
 	})
 
 }
@@ -94,6 +104,15 @@ func TestSchedulerConfigYaml(t *testing.T) {
 		Convey("WorkManagerPoolSize should equal 2", func() {
 			So(cfg.WorkManagerPoolSize, ShouldEqual, 2)
 		})
+		Convey("EventBatchInterval should equal 1s", func() {
+			So(cfg.EventBatchInterval.Duration, ShouldEqual, time.Second)
+		})
+		Convey("EventBufferSize should equal 1000", func() {
+			So(cfg.EventBufferSize, ShouldEqual, 1000)
+		})
+		Convey("EventOverflowPolicy should equal drop-oldest", func() {
+			So(cfg.EventOverflowPolicy, ShouldEqual, "drop-oldest")
+		})
 	})
 
 }
@@ -107,5 +126,11 @@ func TestSchedulerDefaultConfig(t *testing.T) {
 		Convey("WorkManagerPoolSize should equal 4", func() {
 			So(cfg.WorkManagerPoolSize, ShouldEqual, 4)
 		})
+		Convey("EventBatchInterval should equal 0", func() {
+			So(cfg.EventBatchInterval.Duration, ShouldEqual, 0)
+		})
+		Convey("EventBufferSize should equal 0", func() {
+			So(cfg.EventBufferSize, ShouldEqual, 0)
+		})
 	})
 }
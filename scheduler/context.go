@@ -0,0 +1,162 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/tracing"
+)
+
+// ErrJobCancelled - The error message for a job refused as the run it was submitted for is cancelled
+var ErrJobCancelled = errors.New("Worker refused to run cancelled job.")
+
+// collectsMetricsContext, processesMetricsContext and publishesMetricsContext
+// are implemented by a metric manager taking the context of the job it is
+// called for.  The context is cancelled when the task is stopped or killed,
+// carries the run of the task (see core.TaskRunFromContext) and the span of
+// the job when the run is traced (see tracing.FromContext).
+type collectsMetricsContext interface {
+	CollectMetricsContext(context.Context, string, map[string]map[string]string) ([]core.Metric, []error)
+}
+
+type processesMetricsContext interface {
+	ProcessMetricsContext(context.Context, []core.Metric, map[string]ctypes.ConfigValue, string, string, int) ([]core.Metric, []error)
+}
+
+type publishesMetricsContext interface {
+	PublishMetricsContext(context.Context, []core.Metric, map[string]ctypes.ConfigValue, string, string, int) []error
+}
+
+// startContext sets the context of the runs of a task being started
+func (t *task) startContext() {
+	t.ctxMutex.Lock()
+	defer t.ctxMutex.Unlock()
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+}
+
+// cancelRuns cancels the context of the runs of the task so that a run in
+// progress gives up its jobs and plugin calls instead of completing
+func (t *task) cancelRuns() {
+	t.ctxMutex.Lock()
+	defer t.ctxMutex.Unlock()
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
+
+// runContext returns the context of the given run of the task, carrying the
+// run and its span.  A task fired while it is not started runs with a
+// context which is never cancelled.
+func (t *task) runContext(run uint, span *tracing.Span) context.Context {
+	t.ctxMutex.Lock()
+	ctx := t.ctx
+	t.ctxMutex.Unlock()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = core.NewTaskRunContext(ctx, core.TaskRun{
+		TaskID:   t.id,
+		TaskName: t.name,
+		Run:      run,
+	})
+	return tracing.NewContext(ctx, span)
+}
+
+// detachedContext returns a context carrying the task which is not
+// cancelled with its runs, for the metrics published once the task stops,
+// e.g. flushed from the buffers
+func (t *task) detachedContext() context.Context {
+	return core.NewTaskRunContext(context.Background(), core.TaskRun{
+		TaskID:   t.id,
+		TaskName: t.name,
+	})
+}
+
+// cancelled returns true when the runs of the task are cancelled
+func (t *task) cancelled() bool {
+	t.ctxMutex.Lock()
+	defer t.ctxMutex.Unlock()
+	return t.ctx != nil && t.ctx.Err() != nil
+}
+
+// contextJob is implemented by the jobs, see coreJob
+type contextJob interface {
+	setContext(context.Context)
+}
+
+// withContext sets the context of the job to the context of the run it is
+// submitted for
+func withContext(ctx context.Context, j job) job {
+	if cj, ok := j.(contextJob); ok {
+		cj.setContext(ctx)
+	}
+	return j
+}
+
+func (c *coreJob) setContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// Context returns the context of the run the job is submitted for
+func (c *coreJob) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// callContext returns the context handed to the metric manager, carrying
+// the span of the job
+func (c *coreJob) callContext() context.Context {
+	return tracing.NewContext(c.Context(), c.span)
+}
+
+// collect collects the metrics, handing the context of the job to the
+// metric manager when it takes one
+func (c *collectorJob) collect() ([]core.Metric, []error) {
+	c.traceRunStart()
+	if !c.at.IsZero() {
+		return c.collector.(collectsMetricsAt).CollectMetricsAt(c.TaskID(), c.tags, c.at)
+	}
+	if cc, ok := c.collector.(collectsMetricsContext); ok {
+		return cc.CollectMetricsContext(c.callContext(), c.TaskID(), c.tags)
+	}
+	return c.collector.CollectMetrics(c.TaskID(), c.tags)
+}
+
+func (p *processJob) process() ([]core.Metric, []error) {
+	p.traceRunStart()
+	if pc, ok := p.processor.(processesMetricsContext); ok {
+		return pc.ProcessMetricsContext(p.callContext(), p.parentJob.Metrics(), p.config, p.taskID, p.name, p.version)
+	}
+	return p.processor.ProcessMetrics(p.parentJob.Metrics(), p.config, p.taskID, p.name, p.version)
+}
+
+func (p *publisherJob) publish() []error {
+	p.traceRunStart()
+	if pc, ok := p.publisher.(publishesMetricsContext); ok {
+		return pc.PublishMetricsContext(p.callContext(), p.parentJob.Metrics(), p.config, p.taskID, p.name, p.version)
+	}
+	return p.publisher.PublishMetrics(p.parentJob.Metrics(), p.config, p.taskID, p.name, p.version)
+}
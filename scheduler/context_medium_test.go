@@ -0,0 +1,106 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+// blockingMetricManager collects until the context of the job is cancelled
+// and hands the run of the context to the test
+type blockingMetricManager struct {
+	*churningMetricManager
+	runs chan core.TaskRun
+}
+
+func (m *blockingMetricManager) CollectMetricsContext(ctx context.Context, taskID string, tags map[string]map[string]string) ([]core.Metric, []error) {
+	r, _ := core.TaskRunFromContext(ctx)
+	m.runs <- r
+	<-ctx.Done()
+	return nil, []error{ctx.Err()}
+}
+
+func TestTaskRunContext(t *testing.T) {
+	Convey("Given a task whose collection blocks until it is cancelled", t, func() {
+		mm := &blockingMetricManager{churningMetricManager: newChurningMetricManager(), runs: make(chan core.TaskRun, 1)}
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(mm)
+		s.Start()
+		defer s.Stop()
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newMockWorkflowMap(), false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+
+		So(s.StartTask(tk.ID()), ShouldBeEmpty)
+		var r core.TaskRun
+		select {
+		case r = <-mm.runs:
+		case <-time.After(time.Second):
+		}
+		Convey("the context of the collection carries the run of the task", func() {
+			So(r.TaskID, ShouldEqual, tk.ID())
+			So(r.TaskName, ShouldEqual, tk.GetName())
+			So(r.Run, ShouldEqual, 1)
+			tk.Kill()
+		})
+		Convey("stopping the task cancels the run in progress", func() {
+			stopped := make(chan struct{})
+			go func() {
+				s.StopTask(tk.ID())
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-time.After(time.Second):
+			}
+			So(waitForState(tk, core.TaskStopped), ShouldEqual, core.TaskStopped)
+			So(tk.LastRunResult(), ShouldNotBeNil)
+			So(tk.LastRunResult().LastError(), ShouldEqual, context.Canceled.Error())
+
+			Convey("and a task started again runs with a new context", func() {
+				So(s.StartTask(tk.ID()), ShouldBeEmpty)
+				select {
+				case r = <-mm.runs:
+				case <-time.After(time.Second):
+				}
+				So(r.Run, ShouldEqual, 2)
+				So(tk.cancelled(), ShouldBeFalse)
+				tk.Kill()
+				So(waitForState(tk, core.TaskDisabled), ShouldEqual, core.TaskDisabled)
+			})
+		})
+		Convey("a job of a cancelled run is refused by the workers", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			j := withContext(ctx, newCollectorJob(nil, time.Second, mm, nil, tk.ID(), nil))
+			So(s.workManager.Work(j).Promise().Await(), ShouldResemble, []error{ErrJobCancelled})
+			tk.Kill()
+		})
+	})
+}
@@ -0,0 +1,126 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrInvalidQueueSize - The error message for a dedicated queue with a negative size
+	ErrInvalidQueueSize = errors.New("Dedicated queue size must not be negative")
+	// ErrQueueSizeMismatch - The error message for a dedicated queue requested with another size than the one it has
+	ErrQueueSizeMismatch = errors.New("Dedicated queue already exists with a different size")
+)
+
+// dedicatedQueues holds the work managers dedicated to some tasks.  A work
+// manager is created for the first task naming its queue and stopped once
+// the last task using it is removed.
+type dedicatedQueues struct {
+	sync.Mutex
+	// queueSize is the length of the queues of the work managers
+	queueSize uint
+	// poolSize is the default number of workers of each pool
+	poolSize uint
+	queues   map[string]*dedicatedQueue
+}
+
+type dedicatedQueue struct {
+	manager *workManager
+	size    uint
+	tasks   int
+}
+
+func newDedicatedQueues(queueSize, poolSize uint) *dedicatedQueues {
+	return &dedicatedQueues{
+		queueSize: queueSize,
+		poolSize:  poolSize,
+		queues:    map[string]*dedicatedQueue{},
+	}
+}
+
+// setPoolSize changes the default number of workers of the dedicated work
+// managers created afterwards
+func (d *dedicatedQueues) setPoolSize(size uint) {
+	d.Lock()
+	defer d.Unlock()
+	d.poolSize = size
+}
+
+// acquire returns the work manager of the named queue for a task, creating it
+// if needed.
+func (d *dedicatedQueues) acquire(name string, size int) (*workManager, error) {
+	if size < 0 {
+		return nil, ErrInvalidQueueSize
+	}
+	wkrs := uint(size)
+	if wkrs == 0 {
+		wkrs = d.poolSize
+	}
+	d.Lock()
+	defer d.Unlock()
+	q, ok := d.queues[name]
+	if ok {
+		if size != 0 && q.size != wkrs {
+			return nil, ErrQueueSizeMismatch
+		}
+		q.tasks++
+		return q.manager, nil
+	}
+	schedulerLogger.WithFields(log.Fields{
+		"_block":     "acquire-dedicated-queue",
+		"queue-name": name,
+		"queue-size": wkrs,
+	}).Info("Creating dedicated work queue")
+	m := newWorkManager(
+		CollectQSizeOption(d.queueSize),
+		CollectWkrSizeOption(wkrs),
+		PublishQSizeOption(d.queueSize),
+		PublishWkrSizeOption(wkrs),
+		ProcessQSizeOption(d.queueSize),
+		ProcessWkrSizeOption(wkrs),
+	)
+	m.Start()
+	d.queues[name] = &dedicatedQueue{manager: m, size: wkrs, tasks: 1}
+	return m, nil
+}
+
+// release gives back the named queue of a task which is removed
+func (d *dedicatedQueues) release(name string) {
+	d.Lock()
+	defer d.Unlock()
+	q, ok := d.queues[name]
+	if !ok {
+		return
+	}
+	q.tasks--
+	if q.tasks > 0 {
+		return
+	}
+	schedulerLogger.WithFields(log.Fields{
+		"_block":     "release-dedicated-queue",
+		"queue-name": name,
+	}).Info("Stopping dedicated work queue")
+	q.manager.shutdown()
+	delete(d.queues, name)
+}
@@ -0,0 +1,85 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+func TestDedicatedQueue(t *testing.T) {
+	Convey("Given a scheduler", t, func() {
+		s := New(GetDefaultConfig())
+		mm := &publishRecorder{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		create := func(opts ...core.TaskOption) (*task, core.TaskErrors) {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newBufferedWorkflowMap(nil), false, opts...)
+			if tsk == nil {
+				return nil, errs
+			}
+			tk := tsk.(*task)
+			tk.killChan = make(chan struct{})
+			return tk, errs
+		}
+
+		Convey("a task with a dedicated queue does not use the shared queues", func() {
+			tk, errs := create(core.DedicatedQueue("critical", 2))
+			So(errs.Errors(), ShouldBeEmpty)
+			So(tk.manager, ShouldNotEqual, s.workManager)
+			name, size := tk.GetDedicatedQueue()
+			So(name, ShouldEqual, "critical")
+			So(size, ShouldEqual, 2)
+
+			Convey("and its jobs are worked by the dedicated queue", func() {
+				So(tk.fire().Success(), ShouldBeTrue)
+				So(mm.published(), ShouldResemble, []int{2})
+			})
+
+			Convey("tasks naming the same queue share it", func() {
+				other, errs := create(core.DedicatedQueue("critical", 0))
+				So(errs.Errors(), ShouldBeEmpty)
+				So(other.manager, ShouldEqual, tk.manager)
+			})
+			Convey("a queue cannot be requested with another size", func() {
+				other, errs := create(core.DedicatedQueue("critical", 3))
+				So(other, ShouldBeNil)
+				So(errs.Errors()[0].Error(), ShouldEqual, ErrQueueSizeMismatch.Error())
+			})
+			Convey("the queue is stopped once its last task is removed", func() {
+				So(s.RemoveTask(tk.ID()), ShouldBeNil)
+				So(s.dedicatedQueues.queues, ShouldNotContainKey, "critical")
+			})
+		})
+		Convey("a task without a dedicated queue uses the shared queues", func() {
+			tk, errs := create()
+			So(errs.Errors(), ShouldBeEmpty)
+			So(tk.manager, ShouldEqual, s.workManager)
+		})
+
+		s.Stop()
+	})
+}
@@ -0,0 +1,314 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// dedupSuffix is the suffix of the files the windows are recorded in
+const dedupSuffix = ".dedup"
+
+var dedupLogger = schedulerLogger.WithField("_block", "dedup")
+
+// dedupWindows holds the windows of the publish nodes deduplicating their
+// batches, recorded in a file per task and publish node under dir, or only
+// kept in memory when dir is not set.  The windows are keyed by task ID, then
+// by publish node.
+type dedupWindows struct {
+	dir  string
+	size int
+
+	sync.Mutex
+	windows map[string]map[string]*dedupWindow
+}
+
+// newDedupWindows returns the windows of the configuration; they are kept in
+// memory, with an error, when the directory cannot be created
+func newDedupWindows(cfg *Config) (*dedupWindows, error) {
+	d := &dedupWindows{
+		dir:     cfg.DedupPath,
+		size:    cfg.DedupWindow,
+		windows: map[string]map[string]*dedupWindow{},
+	}
+	if d.size <= 0 {
+		d.size = defaultDedupWindow
+	}
+	if d.dir == "" {
+		return d, nil
+	}
+	if err := os.MkdirAll(d.dir, 0700); err != nil {
+		d.dir = ""
+		return d, fmt.Errorf("%v (while parsing 'scheduler::dedup_path')", err)
+	}
+	return d, nil
+}
+
+// window returns the window of a publish node of a task, read back from its
+// file the first time, or nil once the task is removed.  The task is marked
+// removed before its windows are forgotten, so checking it under the lock
+// ensures a publish in flight does not create a window again after forget.
+func (d *dedupWindows) window(t *task, pu *publishNode) *dedupWindow {
+	key := nodeKey(pu)
+	d.Lock()
+	defer d.Unlock()
+	if t.isRemoved() {
+		return nil
+	}
+	windows, ok := d.windows[t.id]
+	if !ok {
+		windows = map[string]*dedupWindow{}
+		d.windows[t.id] = windows
+	}
+	if w, ok := windows[key]; ok {
+		return w
+	}
+	w := &dedupWindow{size: d.size, index: map[string]bool{}}
+	if d.dir != "" {
+		w.path = filepath.Join(d.dir, dedupFile(t.id, key))
+		if err := w.readBack(); err != nil {
+			dedupLogger.WithField("_error", err.Error()).Warn("unable to read the dedup window back")
+		}
+	}
+	windows[key] = w
+	return w
+}
+
+// forget removes the windows of a task, along with the files of its windows
+// which were not read back since the start
+func (d *dedupWindows) forget(taskID string) {
+	d.Lock()
+	defer d.Unlock()
+	for _, w := range d.windows[taskID] {
+		w.remove()
+	}
+	delete(d.windows, taskID)
+	if d.dir == "" {
+		return
+	}
+	files, err := ioutil.ReadDir(d.dir)
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		if isDedupFile(f.Name(), taskID) {
+			os.Remove(filepath.Join(d.dir, f.Name()))
+		}
+	}
+}
+
+// dedupFile returns the name of the file of the window of a publish node of a
+// task
+func dedupFile(taskID, key string) string {
+	return taskID + "-" + key + dedupSuffix
+}
+
+// isDedupFile returns true when the file holds a window of the task and not
+// one of another task whose ID starts with the same characters
+func isDedupFile(name, taskID string) bool {
+	if !strings.HasPrefix(name, taskID+"-") || !strings.HasSuffix(name, dedupSuffix) {
+		return false
+	}
+	key := strings.TrimSuffix(strings.TrimPrefix(name, taskID+"-"), dedupSuffix)
+	if len(key) != nodeKeyLen {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
+
+// dedupWindow holds the IDs of the latest batches acknowledged by a publish
+// node, appended to a file when a path is set.  The file is compacted to the
+// IDs of the window when it holds twice as many.
+type dedupWindow struct {
+	path string
+	size int
+
+	sync.Mutex
+	ids     []string
+	index   map[string]bool
+	file    *os.File
+	written int
+	// closed is set once the window is removed, so that a publish in flight
+	// does not create its file again
+	closed bool
+}
+
+func (w *dedupWindow) readBack() error {
+	f, err := os.Open(w.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if id := sc.Text(); id != "" {
+			w.written++
+			w.keep(id)
+		}
+	}
+	return sc.Err()
+}
+
+// seen returns true when the batch was acknowledged already
+func (w *dedupWindow) seen(id string) bool {
+	w.Lock()
+	defer w.Unlock()
+	return w.index[id]
+}
+
+// add records a batch acknowledged by the publisher, unless the window was
+// removed
+func (w *dedupWindow) add(id string) {
+	w.Lock()
+	defer w.Unlock()
+	if w.closed || w.index[id] {
+		return
+	}
+	w.keep(id)
+	if w.path == "" {
+		return
+	}
+	if err := w.write(id); err != nil {
+		dedupLogger.WithField("_error", err.Error()).Error("unable to record the batch in the dedup window")
+	}
+}
+
+func (w *dedupWindow) keep(id string) {
+	w.ids = append(w.ids, id)
+	w.index[id] = true
+	if len(w.ids) > w.size {
+		delete(w.index, w.ids[0])
+		w.ids = append([]string{}, w.ids[1:]...)
+	}
+}
+
+func (w *dedupWindow) write(id string) error {
+	if w.file == nil {
+		f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		w.file = f
+	}
+	if _, err := w.file.WriteString(id + "\n"); err != nil {
+		return err
+	}
+	w.written++
+	if w.written < 2*w.size {
+		return nil
+	}
+	return w.compact()
+}
+
+// compact replaces the file with one holding the IDs of the window only
+func (w *dedupWindow) compact() error {
+	tmp := w.path + ".tmp"
+	if err := writeLines(tmp, w.ids); err != nil {
+		return err
+	}
+	w.file.Close()
+	w.file = nil
+	if err := os.Rename(tmp, w.path); err != nil {
+		return err
+	}
+	w.written = len(w.ids)
+	return nil
+}
+
+func (w *dedupWindow) remove() {
+	w.Lock()
+	defer w.Unlock()
+	w.closed = true
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+}
+
+func writeLines(path string, lines []string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	for _, l := range lines {
+		bw.WriteString(l + "\n")
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// nodeKeyLen is the length of the keys of the publish nodes
+const nodeKeyLen = 16
+
+// nodeKey identifies a publish node of a task by its plugin and config
+func nodeKey(pu *publishNode) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s:%d", pu.name, pu.version)
+	table := pu.config.Table()
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "\n%s=%v", k, table[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:nodeKeyLen]
+}
+
+// batchID identifies a batch of metrics by its content: the namespace, tags,
+// timestamp and data of its metrics, in any order
+func batchID(mts []core.Metric) string {
+	lines := make([]string, len(mts))
+	for i, m := range mts {
+		tags := make([]string, 0, len(m.Tags()))
+		for k, v := range m.Tags() {
+			tags = append(tags, k+"="+v)
+		}
+		sort.Strings(tags)
+		lines[i] = fmt.Sprintf("%s|%s|%d|%v", m.Namespace().String(), strings.Join(tags, ","), m.Timestamp().UnixNano(), m.Data())
+	}
+	sort.Strings(lines)
+	h := sha256.New()
+	for _, l := range lines {
+		h.Write([]byte(l))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,118 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPublishDedup(t *testing.T) {
+	Convey("Given a task whose publisher deduplicates its batches", t, func() {
+		dir, err := ioutil.TempDir("", "snap-dedup")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		cfg := GetDefaultConfig()
+		cfg.DedupPath = dir
+		cfg.DedupWindow = 2
+		s := New(cfg)
+		mm := &publishRecorder{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		w := newBufferedWorkflowMap(nil)
+		w.Collect.Publish[0].Dedup = true
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+		tk.killChan = make(chan struct{})
+
+		Convey("a batch acknowledged already is not published again", func() {
+			tk.fire()
+			tk.fire()
+			So(mm.published(), ShouldResemble, []int{2})
+			So(tk.DeliveryStats(), ShouldResemble, core.TaskDeliveryStats{Published: 2, Deduplicated: 2})
+
+			Convey("the window is read back from its file", func() {
+				d, err := newDedupWindows(cfg)
+				So(err, ShouldBeNil)
+				pu := tk.workflow.publishNodes[0]
+				So(d.window(tk, pu).ids, ShouldResemble, tk.dedup.window(tk, pu).ids)
+				So(d.window(tk, pu).ids, ShouldHaveLength, 1)
+			})
+			Convey("the windows of a removed task are removed", func() {
+				So(s.RemoveTask(tk.ID()), ShouldBeNil)
+				files, _ := filepath.Glob(filepath.Join(dir, "*"+dedupSuffix))
+				So(files, ShouldBeEmpty)
+
+				Convey("and are not created again by a publish in flight", func() {
+					So(tk.dedup.window(tk, tk.workflow.publishNodes[0]), ShouldBeNil)
+					So(tk.dedup.windows, ShouldNotContainKey, tk.ID())
+				})
+			})
+			Convey("the windows of a task whose ID starts with the ID of a removed task are kept", func() {
+				other, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false, core.SetTaskID(tk.ID()+"-copy"))
+				So(errs.Errors(), ShouldBeEmpty)
+				ot := other.(*task)
+				ot.killChan = make(chan struct{})
+				ot.fire()
+				So(s.RemoveTask(tk.ID()), ShouldBeNil)
+				So(tk.dedup.windows, ShouldContainKey, ot.ID())
+				files, _ := filepath.Glob(filepath.Join(dir, "*"+dedupSuffix))
+				So(files, ShouldHaveLength, 1)
+				So(filepath.Base(files[0]), ShouldStartWith, ot.ID()+"-")
+			})
+		})
+	})
+	Convey("A dedup window", t, func() {
+		dir, err := ioutil.TempDir("", "snap-dedup")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "window"+dedupSuffix)
+		w := &dedupWindow{path: path, size: 2, index: map[string]bool{}}
+
+		Convey("keeps the latest batches and compacts its file", func() {
+			for _, id := range []string{"a", "b", "c", "d"} {
+				w.add(id)
+			}
+			So(w.seen("a"), ShouldBeFalse)
+			So(w.seen("d"), ShouldBeTrue)
+			b, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "c\nd\n")
+			w.remove()
+		})
+		Convey("does not record batches once removed", func() {
+			w.remove()
+			os.Remove(path)
+			w.add("a")
+			So(w.seen("a"), ShouldBeFalse)
+			_, err := os.Stat(path)
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+	})
+}
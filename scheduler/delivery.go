@@ -0,0 +1,93 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	// publishRetryInterval is the delay before the first retry of a batch
+	// whose publisher failed with a retryable error; it doubles on every
+	// retry up to maxPublishRetryInterval
+	publishRetryInterval    = 100 * time.Millisecond
+	maxPublishRetryInterval = 5 * time.Second
+)
+
+// taskDelivery counts the deliveries of the metrics of a task to its
+// publishers
+type taskDelivery struct {
+	sync.Mutex
+	stats core.TaskDeliveryStats
+}
+
+// record counts the outcome of a publish call for a batch of the given size
+// and returns the number of metrics the publisher acknowledged, with the
+// error of the publisher when the rest of the batch can be published again
+func (d *taskDelivery) record(size int, errs []error) (int, *core.PublishError) {
+	d.Lock()
+	defer d.Unlock()
+	if len(errs) == 0 {
+		d.stats.Published += uint64(size)
+		return size, nil
+	}
+	for _, err := range errs {
+		if pe, ok := err.(*core.PublishError); ok {
+			d.stats.Published += uint64(pe.Published)
+			if pe.Retryable {
+				d.stats.RetryableFailures++
+				return pe.Published, pe
+			}
+			d.stats.FatalFailures++
+			return pe.Published, nil
+		}
+	}
+	d.stats.FatalFailures++
+	return 0, nil
+}
+
+func (d *taskDelivery) retried() {
+	d.Lock()
+	defer d.Unlock()
+	d.stats.Retries++
+}
+
+// undelivered counts the metrics given up
+func (d *taskDelivery) undelivered(n int) {
+	d.Lock()
+	defer d.Unlock()
+	d.stats.Undelivered += uint64(n)
+}
+
+// deduplicated counts the metrics of a batch not published again
+func (d *taskDelivery) deduplicated(n int) {
+	d.Lock()
+	defer d.Unlock()
+	d.stats.Deduplicated += uint64(n)
+}
+
+func (d *taskDelivery) get() core.TaskDeliveryStats {
+	d.Lock()
+	defer d.Unlock()
+	return d.stats
+}
@@ -0,0 +1,98 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// ackingPublisher fails the publish calls with the given errors, in turn,
+// then succeeds, recording the size of every batch it is given
+type ackingPublisher struct {
+	publishRecorder
+	failures []error
+}
+
+func (m *ackingPublisher) PublishMetrics(mts []core.Metric, _ map[string]ctypes.ConfigValue, _ string, _ string, _ int) []error {
+	m.Lock()
+	defer m.Unlock()
+	m.batches = append(m.batches, len(mts))
+	if len(m.failures) == 0 {
+		return nil
+	}
+	err := m.failures[0]
+	m.failures = m.failures[1:]
+	return []error{err}
+}
+
+func TestPublishDelivery(t *testing.T) {
+	Convey("Given a task whose publisher acknowledges its batches", t, func() {
+		s := New(GetDefaultConfig())
+		mm := &ackingPublisher{publishRecorder: publishRecorder{mockMetricManager: newMockMetricManager()}}
+		s.SetMetricManager(mm)
+		s.Start()
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newBufferedWorkflowMap(nil), false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+		tk.killChan = make(chan struct{})
+		backend := errors.New("backend unreachable")
+
+		Convey("the metrics not acknowledged are published again after a retryable failure", func() {
+			mm.failures = []error{&core.PublishError{Err: backend, Published: 1, Retryable: true}}
+			tk.fire()
+			So(mm.published(), ShouldResemble, []int{2, 1})
+			So(tk.DeliveryStats(), ShouldResemble, core.TaskDeliveryStats{Published: 2, Retries: 1, RetryableFailures: 1})
+			So(tk.FailedCount(), ShouldEqual, 0)
+		})
+		Convey("a fatal failure is not retried", func() {
+			mm.failures = []error{&core.PublishError{Err: backend, Published: 1}}
+			tk.fire()
+			So(mm.published(), ShouldResemble, []int{2})
+			So(tk.DeliveryStats(), ShouldResemble, core.TaskDeliveryStats{Published: 1, FatalFailures: 1, Undelivered: 1})
+			So(tk.FailedCount(), ShouldEqual, 1)
+		})
+		Convey("the errors of the publishers without acknowledgement are fatal", func() {
+			mm.failures = []error{backend}
+			tk.fire()
+			So(mm.published(), ShouldResemble, []int{2})
+			So(tk.DeliveryStats(), ShouldResemble, core.TaskDeliveryStats{FatalFailures: 1, Undelivered: 2})
+		})
+		Convey("the retries stop at the deadline of the publish", func() {
+			tk.timeouts.Publish = 250 * time.Millisecond
+			for i := 0; i < 10; i++ {
+				mm.failures = append(mm.failures, &core.PublishError{Err: backend, Retryable: true})
+			}
+			tk.fire()
+			// the retries wait 100ms, then 200ms which is past the deadline
+			So(mm.published(), ShouldResemble, []int{2, 2})
+			So(tk.DeliveryStats(), ShouldResemble, core.TaskDeliveryStats{Retries: 1, RetryableFailures: 2, Undelivered: 2})
+			So(tk.FailedCount(), ShouldEqual, 1)
+		})
+	})
+}
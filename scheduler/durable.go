@@ -0,0 +1,58 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+)
+
+// The policies applied to the failures of a publish node
+const (
+	// publishFailureFail records the failure against the task
+	publishFailureFail = "fail"
+	// publishFailureIgnore only logs the failure
+	publishFailureIgnore = "ignore"
+)
+
+// parsePublishFailurePolicy returns true when the failures of a publish node
+// are to be ignored.
+func parsePublishFailurePolicy(policy string) (bool, error) {
+	switch policy {
+	case "", publishFailureFail:
+		return false, nil
+	case publishFailureIgnore:
+		return true, nil
+	default:
+		return false, fmt.Errorf("Unknown publish failure policy '%s' (expected fail or ignore)", policy)
+	}
+}
+
+// splitDurable separates the durable publish nodes, which are published to
+// before their siblings, from the other publish nodes.
+func splitDurable(pus []*publishNode) (durable []*publishNode, others []*publishNode) {
+	for _, pu := range pus {
+		if pu.durable {
+			durable = append(durable, pu)
+		} else {
+			others = append(others, pu)
+		}
+	}
+	return durable, others
+}
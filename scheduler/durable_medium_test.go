@@ -0,0 +1,117 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// destinationRecorder records the order in which publishers are called;
+// the "local" publisher is slow to answer and the "remote" one fails.
+type destinationRecorder struct {
+	*mockMetricManager
+	sync.Mutex
+	calls []string
+}
+
+func (m *destinationRecorder) CollectMetrics(string, map[string]map[string]string) ([]core.Metric, []error) {
+	return []core.Metric{plugin.MetricType{Namespace_: core.NewNamespace("foo", "bar"), Data_: 1}}, nil
+}
+
+func (m *destinationRecorder) PublishMetrics(_ []core.Metric, _ map[string]ctypes.ConfigValue, _ string, name string, _ int) []error {
+	if name == "local" {
+		time.Sleep(10 * time.Millisecond)
+	}
+	m.Lock()
+	m.calls = append(m.calls, name)
+	m.Unlock()
+	if name == "remote" {
+		return []error{errors.New("network is unreachable")}
+	}
+	return nil
+}
+
+func (m *destinationRecorder) published() []string {
+	m.Lock()
+	defer m.Unlock()
+	return append([]string{}, m.calls...)
+}
+
+func newFailureDomainWorkflowMap(onFailure string) *wmap.WorkflowMap {
+	w := wmap.NewWorkflowMap()
+	w.Collect.AddMetric("/foo/bar", 1)
+	remote := wmap.NewPublishNode("remote", -1)
+	remote.OnFailure = onFailure
+	w.Collect.Add(remote)
+	local := wmap.NewPublishNode("local", -1)
+	local.Durable = true
+	w.Collect.Add(local)
+	return w
+}
+
+func TestDurablePublish(t *testing.T) {
+	Convey("Given a task publishing to a durable and a remote destination", t, func() {
+		s := New(GetDefaultConfig())
+		mm := &destinationRecorder{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		sch := schedule.NewWindowedSchedule(interval, nil, nil, 0)
+
+		Convey("the durable destination is published to first", func() {
+			tsk, errs := s.CreateTask(sch, newFailureDomainWorkflowMap(""), false)
+			So(errs.Errors(), ShouldBeEmpty)
+			tk := tsk.(*task)
+			tk.killChan = make(chan struct{})
+			tk.fire()
+			So(mm.published(), ShouldResemble, []string{"local", "remote"})
+			Convey("and the failures of the remote destination are recorded by default", func() {
+				So(tk.FailedCount(), ShouldEqual, 1)
+			})
+		})
+		Convey("the failures of a destination can be ignored", func() {
+			tsk, errs := s.CreateTask(sch, newFailureDomainWorkflowMap("ignore"), false)
+			So(errs.Errors(), ShouldBeEmpty)
+			tk := tsk.(*task)
+			tk.killChan = make(chan struct{})
+			tk.fire()
+			So(mm.published(), ShouldResemble, []string{"local", "remote"})
+			So(tk.FailedCount(), ShouldEqual, 0)
+		})
+		Convey("an unknown failure policy is rejected", func() {
+			_, errs := s.CreateTask(sch, newFailureDomainWorkflowMap("retry"), false)
+			So(errs.Errors(), ShouldNotBeEmpty)
+		})
+
+		s.Stop()
+	})
+}
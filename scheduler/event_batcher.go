@@ -0,0 +1,144 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/gomit"
+
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+)
+
+// maxEventBatchSize is the number of pending events which triggers a flush
+// before the batch interval elapsed.
+const maxEventBatchSize = 1000
+
+// batchedNamespaces are the events emitted on every task fire which are
+// coalesced when event batching is enabled.
+var batchedNamespaces = []string{
+	scheduler_event.MetricCollected,
+	scheduler_event.MetricCollectionFailed,
+	scheduler_event.TaskRunCompleted,
+}
+
+// eventBatcher is a gomit.Emitter which coalesces events of the given
+// namespaces into a scheduler_event.EventsBatchedEvent emitted once per
+// interval.  Events of other namespaces are emitted immediately.  When the
+// interval is not positive all events are emitted immediately.
+type eventBatcher struct {
+	emitter    gomit.Emitter
+	interval   time.Duration
+	namespaces map[string]struct{}
+
+	mutex   sync.Mutex
+	pending []gomit.EventBody
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func newEventBatcher(emitter gomit.Emitter, interval time.Duration, namespaces ...string) *eventBatcher {
+	b := &eventBatcher{
+		emitter:    emitter,
+		interval:   interval,
+		namespaces: make(map[string]struct{}, len(namespaces)),
+	}
+	for _, ns := range namespaces {
+		b.namespaces[ns] = struct{}{}
+	}
+	return b
+}
+
+// Emit queues the event when its namespace is batched, otherwise the event
+// is emitted right away.
+func (b *eventBatcher) Emit(body gomit.EventBody) (int, error) {
+	if b.interval <= 0 {
+		return b.emitter.Emit(body)
+	}
+	if _, ok := b.namespaces[body.Namespace()]; !ok {
+		return b.emitter.Emit(body)
+	}
+	b.mutex.Lock()
+	b.pending = append(b.pending, body)
+	full := len(b.pending) >= maxEventBatchSize
+	b.mutex.Unlock()
+	if full {
+		return b.flush()
+	}
+	return 0, nil
+}
+
+// start emits the pending events every interval until stopped.
+func (b *eventBatcher) start() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.interval <= 0 || b.stop != nil {
+		return
+	}
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+	go func(stop, done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.flush()
+			case <-stop:
+				b.flush()
+				return
+			}
+		}
+	}(b.stop, b.done)
+}
+
+// halt stops the periodic emission after the pending events are emitted.
+func (b *eventBatcher) halt() {
+	b.mutex.Lock()
+	stop, done := b.stop, b.done
+	b.stop, b.done = nil, nil
+	b.mutex.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// flush emits all pending events as a single batch.
+func (b *eventBatcher) flush() (int, error) {
+	b.mutex.Lock()
+	events := b.pending
+	b.pending = nil
+	b.mutex.Unlock()
+	if len(events) == 0 {
+		return 0, nil
+	}
+	log.WithFields(log.Fields{
+		"_module":     "scheduler-events",
+		"_block":      "flush-batch",
+		"event-count": len(events),
+	}).Debug("emitting batched events")
+	return b.emitter.Emit(&scheduler_event.EventsBatchedEvent{Events: events})
+}
@@ -0,0 +1,135 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/gomit"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+	"github.com/intelsdi-x/snap/pkg/eventbus"
+)
+
+type recordingHandler struct {
+	sync.Mutex
+	events []gomit.Event
+}
+
+func (r *recordingHandler) HandleGomitEvent(e gomit.Event) {
+	r.Lock()
+	defer r.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *recordingHandler) received() []gomit.Event {
+	r.Lock()
+	defer r.Unlock()
+	return append([]gomit.Event{}, r.events...)
+}
+
+func TestEventBatcher(t *testing.T) {
+	Convey("Given an event batcher", t, func() {
+		ec := gomit.NewEventController()
+		all := &recordingHandler{}
+		ec.RegisterHandler("all", all)
+
+		Convey("events are emitted right away when batching is disabled", func() {
+			b := newEventBatcher(ec, 0, batchedNamespaces...)
+			b.Emit(&scheduler_event.MetricCollectedEvent{TaskID: "1"})
+			So(all.received(), ShouldHaveLength, 1)
+		})
+		Convey("per-fire events are coalesced into a batch", func() {
+			b := newEventBatcher(ec, time.Hour, batchedNamespaces...)
+			b.start()
+			b.Emit(&scheduler_event.MetricCollectedEvent{TaskID: "1"})
+			b.Emit(&scheduler_event.MetricCollectionFailedEvent{TaskID: "2"})
+			b.Emit(&scheduler_event.TaskStoppedEvent{TaskID: "3"})
+			So(all.received(), ShouldHaveLength, 1)
+			So(all.received()[0].Namespace(), ShouldEqual, scheduler_event.TaskStopped)
+
+			b.halt()
+			events := all.received()
+			So(events, ShouldHaveLength, 2)
+			batch, ok := events[1].Body.(*scheduler_event.EventsBatchedEvent)
+			So(ok, ShouldBeTrue)
+			So(batch.Events, ShouldHaveLength, 2)
+			So(batch.Events[0].(*scheduler_event.MetricCollectedEvent).TaskID, ShouldEqual, "1")
+			So(batch.Events[1].(*scheduler_event.MetricCollectionFailedEvent).TaskID, ShouldEqual, "2")
+		})
+		Convey("a full batch is emitted before the interval elapses", func() {
+			b := newEventBatcher(ec, time.Hour, batchedNamespaces...)
+			for i := 0; i < maxEventBatchSize; i++ {
+				b.Emit(&scheduler_event.MetricCollectedEvent{TaskID: "1"})
+			}
+			So(all.received(), ShouldHaveLength, 1)
+		})
+		Convey("handlers only receive the namespaces they subscribed to", func() {
+			collected := &recordingHandler{}
+			bus := eventbus.New(0, eventbus.Block, 0)
+			filter := EventFilter{Namespaces: []string{scheduler_event.MetricCollected}}
+			bus.Subscribe("collected", collected, eventbus.WithFilter(filter.busFilter()))
+			b := newEventBatcher(bus, time.Hour, batchedNamespaces...)
+			b.Emit(&scheduler_event.TaskStartedEvent{TaskID: "1"})
+			b.Emit(&scheduler_event.MetricCollectionFailedEvent{TaskID: "1"})
+			b.flush()
+			So(collected.received(), ShouldBeEmpty)
+
+			b.Emit(&scheduler_event.MetricCollectedEvent{TaskID: "1"})
+			b.Emit(&scheduler_event.MetricCollectionFailedEvent{TaskID: "1"})
+			b.flush()
+			events := collected.received()
+			So(events, ShouldHaveLength, 1)
+			batch := events[0].Body.(*scheduler_event.EventsBatchedEvent)
+			So(batch.Events, ShouldHaveLength, 1)
+			So(batch.Events[0].Namespace(), ShouldEqual, scheduler_event.MetricCollected)
+		})
+	})
+}
+
+func TestEventFilter(t *testing.T) {
+	Convey("Given a handler of the events of a task", t, func() {
+		bus := eventbus.New(0, eventbus.Block, 0)
+		received := &recordingHandler{}
+		filter := EventFilter{TaskIDs: []string{"1"}}
+		bus.Subscribe("task-1", received, eventbus.WithFilter(filter.busFilter()))
+
+		Convey("only the events of the task are handed to it", func() {
+			bus.Emit(&scheduler_event.TaskStartedEvent{TaskID: "1"})
+			bus.Emit(&scheduler_event.TaskStartedEvent{TaskID: "2"})
+			bus.Emit(&scheduler_event.MaintenanceStartedEvent{})
+			bus.Emit(&scheduler_event.EventsBatchedEvent{Events: []gomit.EventBody{
+				&scheduler_event.MetricCollectedEvent{TaskID: "2"},
+				&scheduler_event.MetricCollectedEvent{TaskID: "1"},
+			}})
+			events := received.received()
+			So(events, ShouldHaveLength, 2)
+			So(events[0].Body.(*scheduler_event.TaskStartedEvent).TaskID, ShouldEqual, "1")
+			batch := events[1].Body.(*scheduler_event.EventsBatchedEvent)
+			So(batch.Events, ShouldHaveLength, 1)
+			So(batch.Events[0].(*scheduler_event.MetricCollectedEvent).TaskID, ShouldEqual, "1")
+		})
+	})
+}
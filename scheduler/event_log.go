@@ -0,0 +1,352 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/gomit"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+	"github.com/intelsdi-x/snap/pkg/eventbus"
+)
+
+const (
+	// EventLogRegistrationName registers the event log with the event manager
+	EventLogRegistrationName = "event-log"
+	// maxEventRecordSize bounds the size of a record read back from the file
+	maxEventRecordSize = 1024 * 1024
+)
+
+var eventLogLogger = schedulerLogger.WithField("_block", "event-log")
+
+// eventLog keeps the task lifecycle events emitted by the scheduler in a
+// ring buffer, appended to a file when a path is set.  The file is compacted
+// to the retained records when it holds twice as many.  Handlers subscribed
+// with a replay receive the past events from the log, then the live ones.
+type eventLog struct {
+	retention int
+	path      string
+
+	sync.Mutex
+	file    *os.File
+	written int
+	seq     uint64
+	records []core.EventRecord
+	// replayed are the handlers subscribed with a replay
+	replayed map[string]*replayedHandler
+}
+
+type replayedHandler struct {
+	handler gomit.Handler
+	accepts eventbus.Filter
+}
+
+// newEventLog returns an event log retaining the given number of records.
+// The records already in the file at path are read back.
+func newEventLog(path string, retention int) (*eventLog, error) {
+	l := &eventLog{
+		retention: retention,
+		path:      path,
+		replayed:  map[string]*replayedHandler{},
+	}
+	if l.retention <= 0 {
+		l.retention = defaultEventLogRetention
+	}
+	if path == "" {
+		return l, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("%v (while parsing 'scheduler::event_log_path')", err)
+	}
+	if err := l.readBack(); err != nil {
+		return nil, fmt.Errorf("%v (while reading the event log %s)", err, path)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("%v (while parsing 'scheduler::event_log_path')", err)
+	}
+	l.file = f
+	return l, nil
+}
+
+// readBack keeps the most recent records of the file in memory
+func (l *eventLog) readBack() error {
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 4096), maxEventRecordSize)
+	for sc.Scan() {
+		var r core.EventRecord
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			// a record partially written when snapteld stopped
+			continue
+		}
+		l.written++
+		l.keep(r)
+		if r.Seq > l.seq {
+			l.seq = r.Seq
+		}
+	}
+	return sc.Err()
+}
+
+func (l *eventLog) keep(r core.EventRecord) {
+	l.records = append(l.records, r)
+	if len(l.records) > l.retention {
+		l.records = append([]core.EventRecord{}, l.records[len(l.records)-l.retention:]...)
+	}
+}
+
+// HandleGomitEvent records the logged events and hands the event to the
+// handlers subscribed with a replay.
+func (l *eventLog) HandleGomitEvent(e gomit.Event) {
+	l.Lock()
+	defer l.Unlock()
+	bodies := []gomit.EventBody{e.Body}
+	if batch, ok := e.Body.(*scheduler_event.EventsBatchedEvent); ok {
+		bodies = batch.Events
+	}
+	for _, body := range bodies {
+		r, ok := eventRecord(body)
+		if !ok {
+			continue
+		}
+		l.seq++
+		r.Seq = l.seq
+		r.Timestamp = e.Header.Time
+		l.keep(r)
+		l.write(r)
+	}
+	for _, rh := range l.replayed {
+		if rh.accepts == nil {
+			rh.handler.HandleGomitEvent(e)
+		} else if body := rh.accepts(e.Body); body != nil {
+			rh.handler.HandleGomitEvent(gomit.Event{Header: e.Header, Body: body})
+		}
+	}
+}
+
+// write appends the record to the file, then compacts the file when it
+// holds twice the retained records
+func (l *eventLog) write(r core.EventRecord) {
+	if l.file == nil {
+		return
+	}
+	b, err := json.Marshal(r)
+	if err == nil {
+		_, err = l.file.Write(append(b, '\n'))
+	}
+	if err != nil {
+		eventLogLogger.WithField("_error", err.Error()).Error("unable to write event record")
+		return
+	}
+	l.written++
+	if l.written < 2*l.retention {
+		return
+	}
+	if err := l.compact(); err != nil {
+		eventLogLogger.WithField("_error", err.Error()).Error("unable to compact the event log")
+	}
+}
+
+// compact replaces the file with one holding the retained records only
+func (l *eventLog) compact() error {
+	tmp := l.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, r := range l.records {
+		b, err := json.Marshal(r)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		w.Write(append(b, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	f.Close()
+	if err := os.Rename(tmp, l.path); err != nil {
+		return err
+	}
+	l.file.Close()
+	l.file, err = os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	l.written = len(l.records)
+	return nil
+}
+
+// query returns the records selected by the query, oldest first
+func (l *eventLog) query(q core.EventQuery) []core.EventRecord {
+	l.Lock()
+	defer l.Unlock()
+	return l.selectRecords(q, nil)
+}
+
+func (l *eventLog) selectRecords(q core.EventQuery, f *EventFilter) []core.EventRecord {
+	records := []core.EventRecord{}
+	for _, r := range l.records {
+		if q.Matches(r) && (f == nil || f.matchesRecord(r)) {
+			records = append(records, r)
+		}
+	}
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[len(records)-q.Limit:]
+	}
+	return records
+}
+
+// subscribe hands the handler the records selected by the query and the
+// filter as scheduler_event.EventReplayedEvent, then the live events
+// selected by the filter.  No event is missed or handed twice between the
+// replay and the live events.
+func (l *eventLog) subscribe(name string, h gomit.Handler, filter EventFilter, q core.EventQuery) {
+	l.Lock()
+	defer l.Unlock()
+	records := l.selectRecords(q, &filter)
+	for _, r := range records {
+		h.HandleGomitEvent(gomit.Event{
+			Header: gomit.Header{Time: r.Timestamp},
+			Body:   &scheduler_event.EventReplayedEvent{Record: r},
+		})
+	}
+	l.replayed[name] = &replayedHandler{
+		handler: h,
+		accepts: filter.busFilter(),
+	}
+	eventLogLogger.WithFields(log.Fields{
+		"handler":         name,
+		"replayed-events": len(records),
+	}).Debug("handler subscribed with a replay")
+}
+
+// unsubscribe removes a handler subscribed with a replay
+func (l *eventLog) unsubscribe(name string) bool {
+	l.Lock()
+	defer l.Unlock()
+	_, ok := l.replayed[name]
+	delete(l.replayed, name)
+	return ok
+}
+
+// eventRecord returns the record of a logged event; the events emitted on
+// every collection are not logged, the completion of the run is
+func eventRecord(body gomit.EventBody) (core.EventRecord, bool) {
+	r := core.EventRecord{Namespace: body.Namespace()}
+	if te, ok := body.(scheduler_event.TaskEvent); ok {
+		r.TaskID = te.GetTaskID()
+	}
+	if te, ok := body.(scheduler_event.TaskEndEvent); ok {
+		r.TaskName = te.GetTaskName()
+		r.TaskLabels = te.GetTaskLabels()
+	}
+	switch v := body.(type) {
+	case *scheduler_event.TaskCreatedEvent:
+		r.Details = map[string]string{"source": v.Source, "start_on_create": strconv.FormatBool(v.StartOnCreate)}
+	case *scheduler_event.TaskStartedEvent:
+		r.Details = map[string]string{"source": v.Source}
+	case *scheduler_event.TaskStoppedEvent:
+		r.Details = map[string]string{"source": v.Source, "why": v.Why}
+	case *scheduler_event.TaskEndedEvent:
+		r.Details = map[string]string{"source": v.Source, "why": v.Why}
+	case *scheduler_event.TaskDeletedEvent:
+		r.Details = map[string]string{"source": v.Source}
+	case *scheduler_event.TaskDisabledEvent:
+		r.Details = map[string]string{"why": v.Why}
+	case *scheduler_event.TaskErroredEvent:
+		r.Details = map[string]string{"why": v.Why}
+	case *scheduler_event.TaskFailureLimitReachedEvent:
+		r.Details = map[string]string{
+			"consecutive_failures": strconv.Itoa(v.ConsecutiveFailures),
+			"policy":               v.Policy,
+			"why":                  v.Why,
+		}
+	case *scheduler_event.TaskSuspendedEvent:
+		r.Details = map[string]string{"why": v.Why}
+	case *scheduler_event.TaskResumedEvent:
+	case *scheduler_event.TaskRunCompletedEvent:
+		r.Details = map[string]string{
+			"duration": v.Duration.String(),
+			"success":  strconv.FormatBool(v.Success),
+			"partial":  strconv.FormatBool(v.Partial),
+		}
+		if len(v.Errors) > 0 {
+			errs := make([]string, len(v.Errors))
+			for i, err := range v.Errors {
+				errs[i] = err.Error()
+			}
+			r.Details["errors"] = strings.Join(errs, "; ")
+		}
+		if len(v.FailedPlugins) > 0 {
+			r.Details["failed_plugins"] = strings.Join(v.FailedPlugins, ",")
+		}
+	case *scheduler_event.TaskLeadershipChangedEvent:
+		r.Details = map[string]string{"leader": strconv.FormatBool(v.Leader), "term": strconv.FormatUint(v.Term, 10)}
+	case *scheduler_event.AlertFiredEvent:
+		r.Details = map[string]string{"alert": v.Alert.Name, "value": strconv.FormatFloat(v.Alert.Value, 'g', -1, 64)}
+	case *scheduler_event.AlertResolvedEvent:
+		r.Details = map[string]string{"alert": v.Alert.Name, "value": strconv.FormatFloat(v.Alert.Value, 'g', -1, 64)}
+	case *scheduler_event.MaintenanceStartedEvent:
+		r.Details = map[string]string{"reason": v.Window.Reason, "task_ids": strings.Join(v.Window.TaskIDs, ",")}
+	case *scheduler_event.MaintenanceEndedEvent:
+		r.Details = map[string]string{"reason": v.Window.Reason, "task_ids": strings.Join(v.Window.TaskIDs, ",")}
+	default:
+		return r, false
+	}
+	return r, true
+}
+
+// matchesRecord returns true when the filter selects the event of the record
+func (f EventFilter) matchesRecord(r core.EventRecord) bool {
+	return (len(f.Namespaces) == 0 || contains(f.Namespaces, r.Namespace)) &&
+		(len(f.TaskIDs) == 0 || contains(f.TaskIDs, r.TaskID))
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
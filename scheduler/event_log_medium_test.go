@@ -0,0 +1,115 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/gomit"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+)
+
+func TestEventLog(t *testing.T) {
+	Convey("Given an event log persisted to a file", t, func() {
+		dir, err := ioutil.TempDir("", "snap-event-log")
+		So(err, ShouldBeNil)
+		Reset(func() {
+			os.RemoveAll(dir)
+		})
+		path := filepath.Join(dir, "events.log")
+		l, err := newEventLog(path, 3)
+		So(err, ShouldBeNil)
+		emit := func(body gomit.EventBody) {
+			l.HandleGomitEvent(gomit.Event{Header: gomit.Header{Time: time.Now()}, Body: body})
+		}
+
+		Convey("task lifecycle events are logged, collections are not", func() {
+			emit(&scheduler_event.TaskCreatedEvent{TaskID: "1", Source: "user"})
+			emit(&scheduler_event.MetricCollectedEvent{TaskID: "1"})
+			emit(&scheduler_event.EventsBatchedEvent{Events: []gomit.EventBody{
+				&scheduler_event.MetricCollectedEvent{TaskID: "1"},
+				&scheduler_event.TaskRunCompletedEvent{TaskID: "1", Success: true},
+			}})
+			emit(&scheduler_event.TaskDisabledEvent{TaskID: "2", TaskName: "cpu", TaskLabels: map[string]string{"team": "compute"}, Why: "too many failures"})
+			records := l.query(core.EventQuery{})
+			So(records, ShouldHaveLength, 3)
+			So(records[0].Namespace, ShouldEqual, scheduler_event.TaskCreated)
+			So(records[0].Details["source"], ShouldEqual, "user")
+			So(records[1].Namespace, ShouldEqual, scheduler_event.TaskRunCompleted)
+			So(records[2].Seq, ShouldEqual, 3)
+
+			disabled := l.query(core.EventQuery{TaskID: "2"})
+			So(disabled, ShouldHaveLength, 1)
+			So(disabled[0].Details["why"], ShouldEqual, "too many failures")
+			So(disabled[0].TaskName, ShouldEqual, "cpu")
+			So(disabled[0].TaskLabels, ShouldResemble, map[string]string{"team": "compute"})
+			So(l.query(core.EventQuery{Until: records[0].Timestamp.Add(-time.Second)}), ShouldBeEmpty)
+
+			Convey("the errors of the schedules are logged", func() {
+				emit(&scheduler_event.TaskErroredEvent{TaskID: "3", TaskName: "disk", Why: "schedule is invalid"})
+				errored := l.query(core.EventQuery{Namespace: scheduler_event.TaskErrored})
+				So(errored, ShouldHaveLength, 1)
+				So(errored[0].TaskID, ShouldEqual, "3")
+				So(errored[0].TaskName, ShouldEqual, "disk")
+				So(errored[0].Details["why"], ShouldEqual, "schedule is invalid")
+			})
+			Convey("the retained events are read back", func() {
+				emit(&scheduler_event.TaskStartedEvent{TaskID: "1"})
+				emit(&scheduler_event.TaskStoppedEvent{TaskID: "1"})
+				emit(&scheduler_event.TaskEndedEvent{TaskID: "1"})
+				l2, err := newEventLog(path, 3)
+				So(err, ShouldBeNil)
+				records := l2.query(core.EventQuery{})
+				So(records, ShouldHaveLength, 3)
+				So(records[0].Namespace, ShouldEqual, scheduler_event.TaskStarted)
+				So(records[2].Seq, ShouldEqual, 6)
+				So(l2.written, ShouldBeLessThan, 6)
+			})
+		})
+		Convey("handlers subscribed with a replay receive the past then the live events", func() {
+			emit(&scheduler_event.TaskStartedEvent{TaskID: "1"})
+			emit(&scheduler_event.TaskStartedEvent{TaskID: "2"})
+			h := &recordingHandler{}
+			l.subscribe("replay", h, EventFilter{TaskIDs: []string{"1"}}, core.EventQuery{})
+			emit(&scheduler_event.TaskStoppedEvent{TaskID: "2"})
+			emit(&scheduler_event.TaskStoppedEvent{TaskID: "1"})
+			events := h.received()
+			So(events, ShouldHaveLength, 2)
+			replayed, ok := events[0].Body.(*scheduler_event.EventReplayedEvent)
+			So(ok, ShouldBeTrue)
+			So(replayed.Record.Namespace, ShouldEqual, scheduler_event.TaskStarted)
+			So(replayed.Record.TaskID, ShouldEqual, "1")
+			So(events[1].Namespace(), ShouldEqual, scheduler_event.TaskStopped)
+
+			So(l.unsubscribe("replay"), ShouldBeTrue)
+			emit(&scheduler_event.TaskStartedEvent{TaskID: "1"})
+			So(h.received(), ShouldHaveLength, 2)
+		})
+	})
+}
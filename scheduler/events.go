@@ -0,0 +1,121 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/gomit"
+
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+	"github.com/intelsdi-x/snap/pkg/eventbus"
+)
+
+// eventBlockTimeout is how long an event waits for room in the queue of a
+// handler with the block overflow policy before it is dropped
+const eventBlockTimeout = 5 * time.Second
+
+// newEventBus returns the bus delivering the scheduler events to their
+// handlers
+func newEventBus(cfg *Config) *eventbus.Bus {
+	policy, err := eventbus.ParsePolicy(cfg.EventOverflowPolicy)
+	if err != nil {
+		schedulerLogger.WithFields(log.Fields{
+			"_block": "new-event-bus",
+			"policy": cfg.EventOverflowPolicy,
+		}).Error(err)
+	}
+	if cfg.EventBufferSize > 0 {
+		schedulerLogger.WithFields(log.Fields{
+			"_block":      "new-event-bus",
+			"buffer-size": cfg.EventBufferSize,
+			"policy":      policy,
+		}).Info("Delivering scheduler events asynchronously")
+	}
+	return eventbus.New(cfg.EventBufferSize, policy, eventBlockTimeout)
+}
+
+// EventFilter selects the scheduler events handed to a handler.  An empty
+// field does not restrict the events.
+type EventFilter struct {
+	// Namespaces are the namespaces of the events, e.g.
+	// scheduler_event.TaskStopped
+	Namespaces []string
+	// TaskIDs are the tasks the events are about; events which are not
+	// about a task are not selected when set
+	TaskIDs []string
+}
+
+// busFilter returns the filter of the event bus selecting the events, nil
+// when all events are selected.  Events contained in a
+// scheduler_event.EventsBatchedEvent are filtered individually.
+func (f EventFilter) busFilter() eventbus.Filter {
+	if len(f.Namespaces) == 0 && len(f.TaskIDs) == 0 {
+		return nil
+	}
+	namespaces := toSet(f.Namespaces)
+	taskIDs := toSet(f.TaskIDs)
+	accepts := func(body gomit.EventBody) bool {
+		if len(namespaces) > 0 {
+			if _, ok := namespaces[body.Namespace()]; !ok {
+				return false
+			}
+		}
+		if len(taskIDs) > 0 {
+			te, ok := body.(scheduler_event.TaskEvent)
+			if !ok {
+				return false
+			}
+			if _, ok := taskIDs[te.GetTaskID()]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return func(body gomit.EventBody) gomit.EventBody {
+		if accepts(body) {
+			return body
+		}
+		batch, ok := body.(*scheduler_event.EventsBatchedEvent)
+		if !ok {
+			return nil
+		}
+		events := []gomit.EventBody{}
+		for _, e := range batch.Events {
+			if accepts(e) {
+				events = append(events, e)
+			}
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		return &scheduler_event.EventsBatchedEvent{Events: events}
+	}
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
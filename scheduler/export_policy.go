@@ -0,0 +1,152 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	// namespaceAny matches any element of a namespace pattern
+	namespaceAny = "*"
+	// namespaceSeparator separates the elements of a namespace pattern
+	namespaceSeparator = "/"
+)
+
+// AggregationOnlyRule restricts the metrics whose namespace starts with the
+// given pattern to aggregates over at least MinGroupSize distinct series:
+// raw values of the metrics are never published.  The "*" elements of the
+// pattern match any element and, like the dynamic elements of the
+// namespace, are aggregated over.
+type AggregationOnlyRule struct {
+	Namespace    string `json:"namespace"yaml:"namespace"`
+	MinGroupSize int    `json:"min_group_size"yaml:"min_group_size"`
+}
+
+// namespacePattern is a namespace of the scheduler configuration split in
+// elements, whose "*" elements match any element
+type namespacePattern []string
+
+// parseNamespacePattern splits a namespace given as /<element>/...
+func parseNamespacePattern(ns string) (namespacePattern, error) {
+	if !strings.HasPrefix(ns, namespaceSeparator) || len(ns) < 2 {
+		return nil, fmt.Errorf("Invalid namespace '%s' (expected /<element>/...)", ns)
+	}
+	return strings.Split(strings.TrimPrefix(ns, namespaceSeparator), namespaceSeparator), nil
+}
+
+// matches returns true when the namespace starts with the pattern
+func (p namespacePattern) matches(ns core.Namespace) bool {
+	if len(ns) < len(p) {
+		return false
+	}
+	for i, e := range p {
+		if e != namespaceAny && e != ns[i].Value {
+			return false
+		}
+	}
+	return true
+}
+
+// exportRule is an AggregationOnlyRule with its namespace split in elements
+type exportRule struct {
+	pattern      namespacePattern
+	minGroupSize int
+}
+
+// exportPolicy holds the aggregation-only rules of the scheduler
+type exportPolicy []*exportRule
+
+// newExportPolicy validates the aggregation-only rules and returns the
+// resulting policy, nil when there is no rule
+func newExportPolicy(rules []AggregationOnlyRule) (exportPolicy, error) {
+	var p exportPolicy
+	for _, r := range rules {
+		pattern, err := parseNamespacePattern(r.Namespace)
+		if err != nil {
+			return nil, fmt.Errorf("%v in aggregation-only rule", err)
+		}
+		if r.MinGroupSize < 1 {
+			return nil, fmt.Errorf("Invalid minimum group size %d in aggregation-only rule for '%s' (expected 1 or more)", r.MinGroupSize, r.Namespace)
+		}
+		p = append(p, &exportRule{
+			pattern:      pattern,
+			minGroupSize: r.MinGroupSize,
+		})
+	}
+	return p, nil
+}
+
+// rule returns the first rule restricting the given namespace or nil
+func (p exportPolicy) rule(ns core.Namespace) *exportRule {
+	for _, r := range p {
+		if r.pattern.matches(ns) {
+			return r
+		}
+	}
+	return nil
+}
+
+// unrestricted returns the metrics which may be published as they are
+func (p exportPolicy) unrestricted(mts []core.Metric) []core.Metric {
+	if len(p) == 0 {
+		return mts
+	}
+	out := make([]core.Metric, 0, len(mts))
+	for _, m := range mts {
+		if p.rule(m.Namespace()) == nil {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// group returns the namespace the metric is aggregated under: its dynamic
+// elements and the elements matched by a "*" of the pattern are replaced by
+// "*".
+func (r *exportRule) group(ns core.Namespace) core.Namespace {
+	g := append(core.Namespace{}, ns...)
+	for i := range g {
+		if g[i].IsDynamic() || (i < len(r.pattern) && r.pattern[i] == namespaceAny) {
+			g[i].Value = namespaceAny
+		}
+	}
+	return g
+}
+
+// seriesKey identifies the series of a metric, its namespace and tags, to
+// count the distinct series of a group
+func seriesKey(m core.Metric) string {
+	tags := m.Tags()
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	key := m.Namespace().String()
+	for _, k := range keys {
+		key += "\x00" + k + "=" + tags[k]
+	}
+	return key
+}
@@ -0,0 +1,456 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// The built-in publishers and the formats of the file publisher
+const (
+	sinkFile        = "file"
+	sinkHTTP        = "http"
+	sinkKafka       = "kafka"
+	sinkStatsd      = "statsd"
+	sinkRemoteWrite = "remote_write"
+
+	fileFormatJSON = "json"
+	fileFormatCSV  = "csv"
+	fileFormatGob  = "gob"
+)
+
+// fileTimeFormat names the rotated files after the time of their rotation
+const fileTimeFormat = "20060102T150405.000000000"
+
+// defaultFileSinkDir is the directory of the temporary directory the file
+// publisher writes into when the scheduler config does not set one
+const defaultFileSinkDir = "snap-published"
+
+var fileSinkLogger = schedulerLogger.WithField("_block", "file-publisher")
+
+// sink is a built-in publisher run by the workflow engine itself instead of
+// a publisher plugin
+type sink interface {
+	publish(ctx context.Context, mts []core.Metric, now time.Time) error
+	close()
+}
+
+// newSink returns the built-in publisher of the given name set up with the
+// config of its publish node
+func newSink(name string, config map[string]interface{}) (sink, error) {
+	switch name {
+	case sinkFile:
+		return newFileSink(config)
+	case sinkHTTP:
+		return newHTTPSink(config)
+	case sinkKafka:
+		return newKafkaSink(config)
+	case sinkStatsd:
+		return newStatsdSink(config)
+	case sinkRemoteWrite:
+		return newRemoteWriteSink(config)
+	}
+	return nil, fmt.Errorf("Unknown built-in publisher '%s' (expected file, http, kafka, statsd or remote_write)", name)
+}
+
+// metricRecord is a metric written by the built-in publishers
+type metricRecord struct {
+	Namespace string            `json:"namespace"`
+	Timestamp time.Time         `json:"timestamp"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Unit      string            `json:"unit,omitempty"`
+	Data      interface{}       `json:"data"`
+}
+
+// fileSink appends the metrics to a file, in JSON lines, CSV or gob, which
+// is rotated once it reaches its size or age.  The rotated files are named
+// after the time of the rotation, optionally compressed with gzip, and the
+// oldest ones are removed beyond the number kept.  The path of the file is
+// relative to the directory of the file publisher set in the scheduler
+// config, out of which the tasks can not write.
+type fileSink struct {
+	name     string
+	path     string
+	format   string
+	maxSize  int64
+	maxAge   time.Duration
+	maxFiles int
+	compress bool
+
+	sync.Mutex
+	file *os.File
+	// out counts the size of the file
+	out     *countingWriter
+	opened  time.Time
+	encoder *gob.Encoder
+}
+
+func newFileSink(config map[string]interface{}) (*fileSink, error) {
+	name, err := sinkString(sinkFile, config, "path", true)
+	if err != nil {
+		return nil, err
+	}
+	name = filepath.Clean(name)
+	if filepath.IsAbs(name) || name == "." || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("Invalid path '%s' in built-in publisher 'file' (expected a path relative to the directory of the file publisher)", config["path"])
+	}
+	format, err := sinkString(sinkFile, config, "format", false)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "":
+		format = fileFormatJSON
+	case fileFormatJSON, fileFormatCSV, fileFormatGob:
+	default:
+		return nil, fmt.Errorf("Invalid format '%s' in built-in publisher 'file' (expected json, csv or gob)", format)
+	}
+	maxSize, err := sinkFloat(sinkFile, config, "max_size")
+	if err != nil {
+		return nil, err
+	}
+	age, err := sinkString(sinkFile, config, "max_age", false)
+	if err != nil {
+		return nil, err
+	}
+	var maxAge time.Duration
+	if age != "" {
+		if maxAge, err = time.ParseDuration(age); err != nil || maxAge <= 0 {
+			return nil, fmt.Errorf("Invalid max_age '%s' in built-in publisher 'file' (expected a positive duration)", age)
+		}
+	}
+	maxFiles, err := sinkFloat(sinkFile, config, "max_files")
+	if err != nil {
+		return nil, err
+	}
+	compress, err := sinkBool(sinkFile, config, "compress")
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{
+		name:     name,
+		path:     filepath.Join(os.TempDir(), defaultFileSinkDir, name),
+		format:   format,
+		maxSize:  int64(maxSize),
+		maxAge:   maxAge,
+		maxFiles: int(maxFiles),
+		compress: compress,
+	}, nil
+}
+
+// sinkString returns a string setting of a built-in publisher
+func sinkString(name string, config map[string]interface{}, key string, required bool) (string, error) {
+	v, ok := config[key]
+	if !ok {
+		if required {
+			return "", fmt.Errorf("Missing '%s' in the config of built-in publisher '%s'", key, name)
+		}
+		return "", nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("Invalid '%s' in the config of built-in publisher '%s' (expected a string)", key, name)
+	}
+	return s, nil
+}
+
+// sinkBool returns a boolean setting of a built-in publisher, false when
+// not set
+func sinkBool(name string, config map[string]interface{}, key string) (bool, error) {
+	v, ok := config[key]
+	if !ok {
+		return false, nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("Invalid '%s' in the config of built-in publisher '%s' (expected a boolean)", key, name)
+	}
+	return b, nil
+}
+
+// sinkFloat returns a count setting of a built-in publisher, 0 when not set
+func sinkFloat(name string, config map[string]interface{}, key string) (float64, error) {
+	v, ok := config[key]
+	if !ok {
+		return 0, nil
+	}
+	f, ok := toFloat64(v)
+	if !ok || f < 0 || f != float64(int64(f)) {
+		return 0, fmt.Errorf("Invalid '%s' in the config of built-in publisher '%s' (expected an integer, 0 or more)", key, name)
+	}
+	return f, nil
+}
+
+// publish writes the metrics to the file, rotated first when due
+func (f *fileSink) publish(_ context.Context, mts []core.Metric, now time.Time) error {
+	f.Lock()
+	defer f.Unlock()
+	if f.file != nil && f.due(now) {
+		if err := f.rotate(now); err != nil {
+			return err
+		}
+	}
+	if f.file == nil {
+		if err := f.open(now); err != nil {
+			return err
+		}
+	}
+	var err error
+	switch f.format {
+	case fileFormatJSON:
+		enc := json.NewEncoder(f.out)
+		for _, m := range mts {
+			if err = enc.Encode(newMetricRecord(m)); err != nil {
+				break
+			}
+		}
+	case fileFormatCSV:
+		w := csv.NewWriter(f.out)
+		if f.out.n == 0 {
+			w.Write([]string{"timestamp", "namespace", "data", "unit", "tags"})
+		}
+		for _, m := range mts {
+			w.Write(csvRecord(m))
+		}
+		w.Flush()
+		err = w.Error()
+	case fileFormatGob:
+		for _, m := range mts {
+			if err = f.encoder.Encode(newMetricRecord(m)); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%v (while writing to %s)", err, f.path)
+	}
+	return nil
+}
+
+// due returns true when the file reached its size or age
+func (f *fileSink) due(now time.Time) bool {
+	return (f.maxSize > 0 && f.out.n >= f.maxSize) || (f.maxAge > 0 && now.Sub(f.opened) >= f.maxAge)
+}
+
+// open opens the file for appending; a gob file left by a previous run is
+// rotated first, since a gob stream can not be appended to
+func (f *fileSink) open(now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.out = &countingWriter{w: file, n: fi.Size()}
+	f.opened = now
+	if f.format == fileFormatGob && f.out.n > 0 {
+		return f.rotate(now)
+	}
+	f.encoder = gob.NewEncoder(f.out)
+	return nil
+}
+
+// rotate renames the file after the time of the rotation, compresses it
+// when set, removes the oldest rotated files beyond the number kept, then
+// opens a new file
+func (f *fileSink) rotate(now time.Time) error {
+	f.file.Close()
+	f.file = nil
+	rotated := f.path + "." + now.UTC().Format(fileTimeFormat)
+	if err := os.Rename(f.path, rotated); err != nil {
+		return err
+	}
+	if f.compress {
+		if err := gzipFile(rotated); err != nil {
+			fileSinkLogger.WithFields(log.Fields{
+				"path":   rotated,
+				"_error": err.Error(),
+			}).Warn("unable to compress the rotated file")
+		}
+	}
+	if f.maxFiles > 0 {
+		files, _ := filepath.Glob(f.path + ".*")
+		sort.Strings(files)
+		for len(files) > f.maxFiles {
+			os.Remove(files[0])
+			files = files[1:]
+		}
+	}
+	return f.open(now)
+}
+
+// setDir sets the directory of the file publisher the file is written into
+func (f *fileSink) setDir(dir string) {
+	f.Lock()
+	defer f.Unlock()
+	f.path = filepath.Join(dir, f.name)
+}
+
+func (f *fileSink) close() {
+	f.Lock()
+	defer f.Unlock()
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+}
+
+// gzipFile replaces the file with its compressed copy
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// countingWriter counts the bytes written, to tell the size of the file
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func newMetricRecord(m core.Metric) metricRecord {
+	return metricRecord{
+		Namespace: m.Namespace().String(),
+		Timestamp: m.Timestamp(),
+		Tags:      m.Tags(),
+		Unit:      m.Unit(),
+		Data:      m.Data(),
+	}
+}
+
+// csvRecord returns the timestamp, namespace, data, unit and tags of the
+// metric; the tags are joined as key=value pairs sorted by key
+func csvRecord(m core.Metric) []string {
+	tags := make([]string, 0, len(m.Tags()))
+	for k, v := range m.Tags() {
+		tags = append(tags, k+"="+v)
+	}
+	sort.Strings(tags)
+	return []string{
+		m.Timestamp().UTC().Format(time.RFC3339Nano),
+		m.Namespace().String(),
+		fmt.Sprint(m.Data()),
+		m.Unit(),
+		strings.Join(tags, ";"),
+	}
+}
+
+// setFileSinkDir sets the directory the file publishers of the workflow write
+// into
+func (s *schedulerWorkflow) setFileSinkDir(dir string) {
+	var walk func([]*processNode, []*publishNode)
+	walk = func(prs []*processNode, pus []*publishNode) {
+		for _, pr := range prs {
+			walk(pr.ProcessNodes, pr.PublishNodes)
+		}
+		for _, pu := range pus {
+			if f, ok := pu.sink.(*fileSink); ok {
+				f.setDir(dir)
+			}
+		}
+	}
+	walk(s.processNodes, s.publishNodes)
+}
+
+// setSinkSecrets has the built-in publishers of the workflow posting over
+// HTTP resolve the references to secrets of their settings with the given
+// function
+func (s *schedulerWorkflow) setSinkSecrets(resolve func(string) (string, error)) {
+	var walk func([]*processNode, []*publishNode)
+	walk = func(prs []*processNode, pus []*publishNode) {
+		for _, pr := range prs {
+			walk(pr.ProcessNodes, pr.PublishNodes)
+		}
+		for _, pu := range pus {
+			if t, ok := pu.sink.(interface {
+				setSecrets(func(string) (string, error))
+			}); ok {
+				t.setSecrets(resolve)
+			}
+		}
+	}
+	walk(s.processNodes, s.publishNodes)
+}
+
+// closeSinks closes the files of the built-in publishers of the workflow
+func (s *schedulerWorkflow) closeSinks() {
+	var walk func([]*processNode, []*publishNode)
+	walk = func(prs []*processNode, pus []*publishNode) {
+		for _, pr := range prs {
+			walk(pr.ProcessNodes, pr.PublishNodes)
+		}
+		for _, pu := range pus {
+			if pu.sink != nil {
+				pu.sink.close()
+			}
+		}
+	}
+	walk(s.processNodes, s.publishNodes)
+}
@@ -0,0 +1,240 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func readLines(path string) []string {
+	f, err := os.Open(path)
+	So(err, ShouldBeNil)
+	defer f.Close()
+	lines := []string{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	return lines
+}
+
+// newFileSinkIn returns a file publisher writing into the directory
+func newFileSinkIn(dir string, config map[string]interface{}) (sink, error) {
+	s, err := newSink(sinkFile, config)
+	if err != nil {
+		return nil, err
+	}
+	s.(*fileSink).setDir(dir)
+	return s, nil
+}
+
+func TestFileSink(t *testing.T) {
+	Convey("Given the built-in file publisher", t, func() {
+		dir, err := ioutil.TempDir("", "snap-file-publisher")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		name := filepath.Join("metrics", "published")
+		path := filepath.Join(dir, name)
+		now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+		mts := []core.Metric{
+			plugin.MetricType{Namespace_: core.NewNamespace("foo", "bar"), Timestamp_: now, Tags_: map[string]string{"b": "2", "a": "1"}, Unit_: "B", Data_: 1.5},
+			plugin.MetricType{Namespace_: core.NewNamespace("foo", "baz"), Timestamp_: now, Data_: "up"},
+		}
+
+		Convey("it writes the metrics as JSON lines by default", func() {
+			s, err := newFileSinkIn(dir, map[string]interface{}{"path": name})
+			So(err, ShouldBeNil)
+			defer s.close()
+			So(s.publish(context.Background(), mts, now), ShouldBeNil)
+			So(s.publish(context.Background(), mts[:1], now), ShouldBeNil)
+			lines := readLines(path)
+			So(lines, ShouldHaveLength, 3)
+			var r metricRecord
+			So(json.Unmarshal([]byte(lines[0]), &r), ShouldBeNil)
+			So(r.Namespace, ShouldEqual, "/foo/bar")
+			So(r.Timestamp.Equal(now), ShouldBeTrue)
+			So(r.Tags, ShouldResemble, map[string]string{"a": "1", "b": "2"})
+			So(r.Unit, ShouldEqual, "B")
+			So(r.Data, ShouldEqual, 1.5)
+		})
+		Convey("it writes the metrics as CSV under a header", func() {
+			s, err := newFileSinkIn(dir, map[string]interface{}{"path": name, "format": "csv"})
+			So(err, ShouldBeNil)
+			defer s.close()
+			So(s.publish(context.Background(), mts, now), ShouldBeNil)
+			So(readLines(path), ShouldResemble, []string{
+				"timestamp,namespace,data,unit,tags",
+				"2017-01-01T00:00:00Z,/foo/bar,1.5,B,a=1;b=2",
+				"2017-01-01T00:00:00Z,/foo/baz,up,,",
+			})
+		})
+		Convey("it writes the metrics as a gob stream", func() {
+			s, err := newFileSinkIn(dir, map[string]interface{}{"path": name, "format": "gob"})
+			So(err, ShouldBeNil)
+			So(s.publish(context.Background(), mts, now), ShouldBeNil)
+			So(s.publish(context.Background(), mts, now), ShouldBeNil)
+			s.close()
+			f, err := os.Open(path)
+			So(err, ShouldBeNil)
+			defer f.Close()
+			dec := gob.NewDecoder(f)
+			records := []metricRecord{}
+			for {
+				var r metricRecord
+				if err := dec.Decode(&r); err != nil {
+					So(err, ShouldEqual, io.EOF)
+					break
+				}
+				records = append(records, r)
+			}
+			So(records, ShouldHaveLength, 4)
+			So(records[3].Data, ShouldEqual, "up")
+
+			Convey("a gob stream left by a previous run is rotated, not appended to", func() {
+				s, err := newFileSinkIn(dir, map[string]interface{}{"path": name, "format": "gob"})
+				So(err, ShouldBeNil)
+				defer s.close()
+				So(s.publish(context.Background(), mts, now.Add(time.Second)), ShouldBeNil)
+				rotated, _ := filepath.Glob(path + ".*")
+				So(rotated, ShouldResemble, []string{path + ".20170101T000001.000000000"})
+			})
+		})
+		Convey("it rotates the file once it reaches its size", func() {
+			s, err := newFileSinkIn(dir, map[string]interface{}{"path": name, "max_size": 10, "max_files": 2, "compress": true})
+			So(err, ShouldBeNil)
+			defer s.close()
+			for i := 0; i < 4; i++ {
+				So(s.publish(context.Background(), mts[:1], now.Add(time.Duration(i)*time.Second)), ShouldBeNil)
+			}
+			So(readLines(path), ShouldHaveLength, 1)
+			rotated, _ := filepath.Glob(path + ".*")
+			So(rotated, ShouldResemble, []string{
+				path + ".20170101T000002.000000000.gz",
+				path + ".20170101T000003.000000000.gz",
+			})
+			f, err := os.Open(rotated[0])
+			So(err, ShouldBeNil)
+			defer f.Close()
+			zr, err := gzip.NewReader(f)
+			So(err, ShouldBeNil)
+			b, err := ioutil.ReadAll(zr)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldStartWith, `{"namespace":"/foo/bar"`)
+		})
+		Convey("it rotates the file once it reaches its age", func() {
+			s, err := newFileSinkIn(dir, map[string]interface{}{"path": name, "max_age": "1m"})
+			So(err, ShouldBeNil)
+			defer s.close()
+			So(s.publish(context.Background(), mts, now), ShouldBeNil)
+			So(s.publish(context.Background(), mts, now.Add(59*time.Second)), ShouldBeNil)
+			rotated, _ := filepath.Glob(path + ".*")
+			So(rotated, ShouldBeEmpty)
+			So(s.publish(context.Background(), mts, now.Add(time.Minute)), ShouldBeNil)
+			rotated, _ = filepath.Glob(path + ".*")
+			So(rotated, ShouldHaveLength, 1)
+			So(readLines(rotated[0]), ShouldHaveLength, 4)
+			So(readLines(path), ShouldHaveLength, 2)
+		})
+		Convey("it rejects invalid settings", func() {
+			_, err := newSink(sinkFile, map[string]interface{}{})
+			So(err.Error(), ShouldEqual, "Missing 'path' in the config of built-in publisher 'file'")
+			_, err = newSink(sinkFile, map[string]interface{}{"path": name, "format": "xml"})
+			So(err.Error(), ShouldEqual, "Invalid format 'xml' in built-in publisher 'file' (expected json, csv or gob)")
+			_, err = newSink(sinkFile, map[string]interface{}{"path": name, "max_age": "soon"})
+			So(err.Error(), ShouldStartWith, "Invalid max_age 'soon'")
+			_, err = newSink(sinkFile, map[string]interface{}{"path": name, "max_size": -1})
+			So(err.Error(), ShouldStartWith, "Invalid 'max_size'")
+			_, err = newSink(sinkFile, map[string]interface{}{"path": name, "compress": "yes"})
+			So(err.Error(), ShouldStartWith, "Invalid 'compress'")
+			_, err = newSink("influxdb", nil)
+			So(err.Error(), ShouldEqual, "Unknown built-in publisher 'influxdb' (expected file, http, kafka, statsd or remote_write)")
+		})
+		Convey("it does not write out of the directory of the file publisher", func() {
+			for _, p := range []string{path, "../published", "metrics/../../published", ".", ""} {
+				_, err := newSink(sinkFile, map[string]interface{}{"path": p})
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "Invalid path '"+p+"' in built-in publisher 'file'")
+			}
+			s, err := newFileSinkIn(dir, map[string]interface{}{"path": "metrics/../published"})
+			So(err, ShouldBeNil)
+			defer s.close()
+			So(s.publish(context.Background(), mts, now), ShouldBeNil)
+			So(readLines(filepath.Join(dir, "published")), ShouldHaveLength, 2)
+		})
+	})
+}
+
+func TestWorkflowFileSink(t *testing.T) {
+	Convey("Given a task publishing to the built-in file publisher", t, func() {
+		dir, err := ioutil.TempDir("", "snap-file-publisher")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "published")
+		cfg := GetDefaultConfig()
+		cfg.FilePublisherPath = dir
+		s := New(cfg)
+		mm := &publishRecorder{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		pu := &wmap.PublishWorkflowMapNode{Builtin: "file"}
+		pu.AddConfigItem("path", "published")
+		w.Collect.Add(pu)
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+		tk.killChan = make(chan struct{})
+
+		Convey("the metrics are written by the workflow engine, without a plugin", func() {
+			tk.fire()
+			So(mm.published(), ShouldBeEmpty)
+			lines := readLines(path)
+			So(lines, ShouldHaveLength, 2)
+			So(strings.Contains(lines[1], `"namespace":"/foo/baz"`), ShouldBeTrue)
+			So(tk.DeliveryStats().Published, ShouldEqual, 2)
+		})
+		Convey("a built-in publisher takes no plugin name", func() {
+			w.Collect.Publish[0].PluginName = "file"
+			_, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+			So(errs.Errors(), ShouldNotBeEmpty)
+		})
+	})
+}
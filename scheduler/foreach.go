@@ -0,0 +1,147 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// forEach fans a workflow out per instance of a dynamic metric, e.g. per
+// container of /intel/docker/*: the metrics whose namespace starts with the
+// pattern are grouped by the elements matched by its wildcards, and every
+// group goes down the process and publish nodes as a job of its own.  The
+// instances are discovered anew from the metrics of every run.
+type forEach struct {
+	pattern namespacePattern
+
+	sync.Mutex
+	// instances are the instances found in the previous run
+	instances []string
+}
+
+func newForEach(ns string) (*forEach, error) {
+	p, err := parseNamespacePattern(ns)
+	if err != nil {
+		return nil, fmt.Errorf("%v in for_each", err)
+	}
+	if !contains(p, namespaceAny) {
+		return nil, fmt.Errorf("Invalid for_each '%s' (expected a wildcard (*) element matching the instances)", ns)
+	}
+	return &forEach{pattern: p}, nil
+}
+
+// instance returns the instance of the metric: the elements of its namespace
+// matched by the wildcards of the pattern
+func (f *forEach) instance(m core.Metric) (string, bool) {
+	ns := m.Namespace()
+	if !f.pattern.matches(ns) {
+		return "", false
+	}
+	elems := []string{}
+	for i, e := range f.pattern {
+		if e == namespaceAny {
+			elems = append(elems, ns[i].Value)
+		}
+	}
+	return strings.Join(elems, namespaceSeparator), true
+}
+
+// split groups the metrics by instance, in the order the instances are
+// found; the metrics of no instance are grouped together, last
+func (f *forEach) split(mts []core.Metric) ([]string, [][]core.Metric) {
+	instances := []string{}
+	groups := [][]core.Metric{}
+	index := map[string]int{}
+	var others []core.Metric
+	for _, m := range mts {
+		inst, ok := f.instance(m)
+		if !ok {
+			others = append(others, m)
+			continue
+		}
+		i, ok := index[inst]
+		if !ok {
+			i = len(groups)
+			index[inst] = i
+			instances = append(instances, inst)
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], m)
+	}
+	if len(others) > 0 {
+		groups = append(groups, others)
+	}
+	return instances, groups
+}
+
+// discover records the instances found in a run and returns the instances
+// which appeared and disappeared since the previous run
+func (f *forEach) discover(instances []string) (added, removed []string) {
+	f.Lock()
+	defer f.Unlock()
+	current := append([]string{}, instances...)
+	sort.Strings(current)
+	for _, inst := range current {
+		if !contains(f.instances, inst) {
+			added = append(added, inst)
+		}
+	}
+	for _, inst := range f.instances {
+		if !contains(current, inst) {
+			removed = append(removed, inst)
+		}
+	}
+	f.instances = current
+	return added, removed
+}
+
+// workInstances runs the process and publish nodes of the workflow for the
+// metrics of every instance, concurrently
+func workInstances(ctx context.Context, f *forEach, prs []*processNode, pus []*publishNode, t *task, pj job) {
+	instances, groups := f.split(pj.Metrics())
+	if added, removed := f.discover(instances); len(added) > 0 || len(removed) > 0 {
+		workflowLogger.WithFields(log.Fields{
+			"_block":            "work-instances",
+			"task-id":           t.id,
+			"task-name":         t.name,
+			"for-each":          "/" + strings.Join(f.pattern, namespaceSeparator),
+			"instances":         len(instances),
+			"instances-added":   strings.Join(added, ","),
+			"instances-removed": strings.Join(removed, ","),
+		}).Info("instances of the task changed")
+	}
+	wg := &sync.WaitGroup{}
+	for _, mts := range groups {
+		wg.Add(1)
+		go func(mts []core.Metric) {
+			defer wg.Done()
+			workJobs(ctx, prs, pus, t, newBatchJob(pj.Type(), pj.Deadline(), t.id, mts))
+		}(mts)
+	}
+	wg.Wait()
+}
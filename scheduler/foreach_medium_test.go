@@ -0,0 +1,155 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// instancesRecorder collects the metrics of the containers it is given and
+// records the namespaces of every batch published
+type instancesRecorder struct {
+	*mockMetricManager
+	sync.Mutex
+	containers []string
+	batches    []string
+}
+
+func (m *instancesRecorder) CollectMetrics(string, map[string]map[string]string) ([]core.Metric, []error) {
+	m.Lock()
+	defer m.Unlock()
+	mts := []core.Metric{plugin.MetricType{Namespace_: core.NewNamespace("intel", "containers"), Data_: len(m.containers)}}
+	for _, c := range m.containers {
+		mts = append(mts,
+			plugin.MetricType{Namespace_: core.NewNamespace("intel", "docker", c, "cpu"), Data_: 1},
+			plugin.MetricType{Namespace_: core.NewNamespace("intel", "docker", c, "mem"), Data_: 2},
+		)
+	}
+	return mts, nil
+}
+
+func (m *instancesRecorder) PublishMetrics(mts []core.Metric, _ map[string]ctypes.ConfigValue, _ string, _ string, _ int) []error {
+	m.Lock()
+	defer m.Unlock()
+	nss := []string{}
+	for _, mt := range mts {
+		nss = append(nss, mt.Namespace().String())
+	}
+	m.batches = append(m.batches, strings.Join(nss, " "))
+	return nil
+}
+
+func (m *instancesRecorder) setContainers(containers ...string) {
+	m.Lock()
+	defer m.Unlock()
+	m.containers = containers
+	m.batches = nil
+}
+
+// published returns the batches published, sorted as the instances are
+// published concurrently
+func (m *instancesRecorder) published() []string {
+	m.Lock()
+	defer m.Unlock()
+	batches := append([]string{}, m.batches...)
+	sort.Strings(batches)
+	return batches
+}
+
+func TestForEach(t *testing.T) {
+	Convey("Given a for_each pattern", t, func() {
+		f, err := newForEach("/intel/docker/*")
+		So(err, ShouldBeNil)
+		a := plugin.MetricType{Namespace_: core.NewNamespace("intel", "docker", "a", "cpu")}
+		b := plugin.MetricType{Namespace_: core.NewNamespace("intel", "docker", "b", "cpu")}
+		a2 := plugin.MetricType{Namespace_: core.NewNamespace("intel", "docker", "a", "mem")}
+		other := plugin.MetricType{Namespace_: core.NewNamespace("intel", "cpu")}
+
+		Convey("the metrics are grouped by instance, the others last", func() {
+			instances, groups := f.split([]core.Metric{a, other, b, a2})
+			So(instances, ShouldResemble, []string{"a", "b"})
+			So(groups, ShouldResemble, [][]core.Metric{{a, a2}, {b}, {other}})
+		})
+		Convey("the instances appearing and disappearing are reported", func() {
+			added, removed := f.discover([]string{"b", "a"})
+			So(added, ShouldResemble, []string{"a", "b"})
+			So(removed, ShouldBeEmpty)
+			added, removed = f.discover([]string{"b", "c"})
+			So(added, ShouldResemble, []string{"c"})
+			So(removed, ShouldResemble, []string{"a"})
+		})
+		Convey("a pattern without a wildcard is invalid", func() {
+			_, err := newForEach("/intel/docker")
+			So(err, ShouldNotBeNil)
+			_, err = newForEach("intel/*")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestWorkflowForEach(t *testing.T) {
+	Convey("Given a task fanning out per container", t, func() {
+		s := New(GetDefaultConfig())
+		mm := &instancesRecorder{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/intel/*", 1)
+		w.Collect.ForEach = "/intel/docker/*"
+		w.Collect.Add(wmap.NewPublishNode("file", -1))
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+		tk.killChan = make(chan struct{})
+
+		Convey("the metrics of every instance are published as a batch", func() {
+			mm.setContainers("a", "b")
+			tk.fire()
+			So(mm.published(), ShouldResemble, []string{
+				"/intel/containers",
+				"/intel/docker/a/cpu /intel/docker/a/mem",
+				"/intel/docker/b/cpu /intel/docker/b/mem",
+			})
+
+			Convey("and the instances are discovered on every fire", func() {
+				mm.setContainers("b", "c")
+				tk.fire()
+				So(mm.published(), ShouldResemble, []string{
+					"/intel/containers",
+					"/intel/docker/b/cpu /intel/docker/b/mem",
+					"/intel/docker/c/cpu /intel/docker/c/mem",
+				})
+				So(tk.workflow.forEach.instances, ShouldResemble, []string{"b", "c"})
+			})
+		})
+	})
+}
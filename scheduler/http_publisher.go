@@ -0,0 +1,228 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	// defaultHTTPSinkTimeout bounds a request of the http publisher
+	defaultHTTPSinkTimeout = 10 * time.Second
+	// httpSinkHeaderPrefix prefixes the keys of the config setting headers
+	httpSinkHeaderPrefix = "header."
+)
+
+// httpTarget is the endpoint of a built-in publisher posting over HTTP,
+// with the headers and the authentication of its requests.  The headers, the
+// token and the password may refer to secrets, resolved for every request
+// so that the task keeps the references.
+type httpTarget struct {
+	url      string
+	headers  http.Header
+	username string
+	password string
+	token    string
+	client   *http.Client
+	secret   func(string) (string, error)
+}
+
+// newHTTPTarget returns the endpoint set by the url, header.<name>, token,
+// username, password and timeout settings of a built-in publisher
+func newHTTPTarget(name string, config map[string]interface{}) (*httpTarget, error) {
+	endpoint, err := sinkString(name, config, "url", true)
+	if err != nil {
+		return nil, err
+	}
+	if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("Invalid url '%s' in built-in publisher '%s' (expected an http(s) URL)", endpoint, name)
+	}
+	t := &httpTarget{url: endpoint, headers: http.Header{}}
+	for k := range config {
+		if !strings.HasPrefix(k, httpSinkHeaderPrefix) {
+			continue
+		}
+		v, err := sinkString(name, config, k, true)
+		if err != nil {
+			return nil, err
+		}
+		t.headers.Set(strings.TrimPrefix(k, httpSinkHeaderPrefix), v)
+	}
+	if t.username, err = sinkString(name, config, "username", false); err != nil {
+		return nil, err
+	}
+	if t.password, err = sinkString(name, config, "password", false); err != nil {
+		return nil, err
+	}
+	if t.token, err = sinkString(name, config, "token", false); err != nil {
+		return nil, err
+	}
+	if t.token != "" && t.username != "" {
+		return nil, fmt.Errorf("Invalid auth in built-in publisher '%s' (expected a token or a username, not both)", name)
+	}
+	timeout := defaultHTTPSinkTimeout
+	text, err := sinkString(name, config, "timeout", false)
+	if err != nil {
+		return nil, err
+	}
+	if text != "" {
+		if timeout, err = time.ParseDuration(text); err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("Invalid timeout '%s' in built-in publisher '%s' (expected a positive duration)", text, name)
+		}
+	}
+	t.client = &http.Client{Timeout: timeout}
+	return t, nil
+}
+
+// setSecrets sets the function resolving the references to secrets of the
+// settings of the target
+func (t *httpTarget) setSecrets(resolve func(string) (string, error)) {
+	t.secret = resolve
+}
+
+// resolve returns the value of the setting, the secret it refers to if any
+func (t *httpTarget) resolve(value string) (string, error) {
+	if t.secret == nil || value == "" {
+		return value, nil
+	}
+	return t.secret(value)
+}
+
+// post posts the body with the given headers.  The network errors and the
+// 408, 429 and 5xx responses are retryable failures.
+func (t *httpTarget) post(ctx context.Context, body []byte, headers http.Header) error {
+	req, err := http.NewRequest("POST", t.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range t.headers {
+		values := make([]string, len(v))
+		for i := range v {
+			if values[i], err = t.resolve(v[i]); err != nil {
+				return err
+			}
+		}
+		req.Header[k] = values
+	}
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	if t.token != "" {
+		token, err := t.resolve(t.token)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if t.username != "" {
+		password, err := t.resolve(t.password)
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(t.username, password)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return &core.PublishError{Err: err, Retryable: true}
+	}
+	// drain the body so that the connection is reused
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("%s returned %s", t.url, resp.Status)
+	retryable := resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return &core.PublishError{Err: err, Retryable: retryable}
+}
+
+// httpSink posts the metrics as a JSON array to an HTTP endpoint, in batches
+// of at most batchSize metrics.  The retryable failures of the requests are
+// retried by the workflow for the metrics of the batches not accepted, as
+// for a publisher plugin reporting a retryable failure.
+type httpSink struct {
+	*httpTarget
+	batchSize int
+	retry     bool
+}
+
+func newHTTPSink(config map[string]interface{}) (*httpSink, error) {
+	target, err := newHTTPTarget(sinkHTTP, config)
+	if err != nil {
+		return nil, err
+	}
+	s := &httpSink{httpTarget: target}
+	batchSize, err := sinkFloat(sinkHTTP, config, "batch_size")
+	if err != nil {
+		return nil, err
+	}
+	s.batchSize = int(batchSize)
+	s.retry = true
+	if _, ok := config["retry"]; ok {
+		if s.retry, err = sinkBool(sinkHTTP, config, "retry"); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// publish posts the metrics batch by batch; the error tells how many
+// metrics the endpoint accepted before the failure
+func (s *httpSink) publish(ctx context.Context, mts []core.Metric, _ time.Time) error {
+	size := s.batchSize
+	if size <= 0 {
+		size = len(mts)
+	}
+	for published := 0; published < len(mts); published += size {
+		end := published + size
+		if end > len(mts) {
+			end = len(mts)
+		}
+		records := make([]metricRecord, end-published)
+		for i, m := range mts[published:end] {
+			records[i] = newMetricRecord(m)
+		}
+		b, err := json.Marshal(records)
+		if err != nil {
+			return &core.PublishError{Err: err, Published: published}
+		}
+		if err := s.post(ctx, b, http.Header{"Content-Type": {"application/json"}}); err != nil {
+			if pe, ok := err.(*core.PublishError); ok {
+				pe.Published = published
+				pe.Retryable = pe.Retryable && s.retry
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *httpSink) close() {}
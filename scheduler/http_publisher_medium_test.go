@@ -0,0 +1,190 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// endpoint records the requests it receives and answers them with the given
+// statuses, in turn, then with 200
+type endpoint struct {
+	sync.Mutex
+	statuses []int
+	batches  [][]metricRecord
+	headers  []http.Header
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.Lock()
+	defer e.Unlock()
+	var records []metricRecord
+	json.NewDecoder(r.Body).Decode(&records)
+	e.batches = append(e.batches, records)
+	e.headers = append(e.headers, r.Header)
+	if len(e.statuses) > 0 {
+		w.WriteHeader(e.statuses[0])
+		e.statuses = e.statuses[1:]
+	}
+}
+
+func (e *endpoint) sizes() []int {
+	e.Lock()
+	defer e.Unlock()
+	sizes := []int{}
+	for _, b := range e.batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
+
+func TestHTTPSink(t *testing.T) {
+	Convey("Given the built-in http publisher", t, func() {
+		ep := &endpoint{}
+		srv := httptest.NewServer(ep)
+		defer srv.Close()
+		now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+		mts := []core.Metric{
+			plugin.MetricType{Namespace_: core.NewNamespace("foo", "bar"), Timestamp_: now, Data_: 1.5},
+			plugin.MetricType{Namespace_: core.NewNamespace("foo", "baz"), Timestamp_: now, Data_: 2.5},
+			plugin.MetricType{Namespace_: core.NewNamespace("foo", "qux"), Timestamp_: now, Data_: 3.5},
+		}
+
+		Convey("it posts the metrics in batches with the headers and auth", func() {
+			s, err := newSink(sinkHTTP, map[string]interface{}{"url": srv.URL, "batch_size": 2, "header.X-Scope": "lab", "token": "secret"})
+			So(err, ShouldBeNil)
+			So(s.publish(context.Background(), mts, now), ShouldBeNil)
+			So(ep.sizes(), ShouldResemble, []int{2, 1})
+			So(ep.batches[1][0].Namespace, ShouldEqual, "/foo/qux")
+			So(ep.batches[1][0].Data, ShouldEqual, 3.5)
+			So(ep.headers[0].Get("X-Scope"), ShouldEqual, "lab")
+			So(ep.headers[0].Get("Authorization"), ShouldEqual, "Bearer secret")
+			So(ep.headers[0].Get("Content-Type"), ShouldEqual, "application/json")
+		})
+		Convey("it uses basic auth with a username", func() {
+			s, err := newSink(sinkHTTP, map[string]interface{}{"url": srv.URL, "username": "snap", "password": "pass"})
+			So(err, ShouldBeNil)
+			So(s.publish(context.Background(), mts, now), ShouldBeNil)
+			So(ep.sizes(), ShouldResemble, []int{3})
+			So(ep.headers[0].Get("Authorization"), ShouldEqual, "Basic c25hcDpwYXNz")
+		})
+		Convey("it resolves the secrets its settings refer to for every request", func() {
+			s, err := newSink(sinkHTTP, map[string]interface{}{"url": srv.URL, "header.X-Key": "vault:secret/api#key", "token": "vault:secret/api#token"})
+			So(err, ShouldBeNil)
+			secrets := map[string]string{"vault:secret/api#key": "k1", "vault:secret/api#token": "t1"}
+			s.(*httpSink).setSecrets(func(v string) (string, error) {
+				if secret, ok := secrets[v]; ok {
+					return secret, nil
+				}
+				return "", errors.New("cannot resolve " + v)
+			})
+			So(s.publish(context.Background(), mts, now), ShouldBeNil)
+			So(ep.headers[0].Get("X-Key"), ShouldEqual, "k1")
+			So(ep.headers[0].Get("Authorization"), ShouldEqual, "Bearer t1")
+			secrets["vault:secret/api#token"] = "t2"
+			So(s.publish(context.Background(), mts, now), ShouldBeNil)
+			So(ep.headers[1].Get("Authorization"), ShouldEqual, "Bearer t2")
+			delete(secrets, "vault:secret/api#key")
+			So(s.publish(context.Background(), mts, now), ShouldNotBeNil)
+			So(ep.sizes(), ShouldHaveLength, 2)
+		})
+		Convey("a server error is retryable for the metrics not accepted", func() {
+			ep.statuses = []int{200, 503}
+			s, err := newSink(sinkHTTP, map[string]interface{}{"url": srv.URL, "batch_size": 2})
+			So(err, ShouldBeNil)
+			err = s.publish(context.Background(), mts, now)
+			So(err, ShouldHaveSameTypeAs, &core.PublishError{})
+			So(err.(*core.PublishError).Published, ShouldEqual, 2)
+			So(err.(*core.PublishError).Retryable, ShouldBeTrue)
+		})
+		Convey("a client error is fatal", func() {
+			ep.statuses = []int{400}
+			s, err := newSink(sinkHTTP, map[string]interface{}{"url": srv.URL})
+			So(err, ShouldBeNil)
+			err = s.publish(context.Background(), mts, now)
+			So(err.(*core.PublishError).Retryable, ShouldBeFalse)
+			So(err.Error(), ShouldEndWith, "returned 400 Bad Request")
+		})
+		Convey("no failure is retryable without retry", func() {
+			ep.statuses = []int{503}
+			s, err := newSink(sinkHTTP, map[string]interface{}{"url": srv.URL, "retry": false})
+			So(err, ShouldBeNil)
+			err = s.publish(context.Background(), mts, now)
+			So(err.(*core.PublishError).Retryable, ShouldBeFalse)
+		})
+		Convey("it rejects invalid settings", func() {
+			_, err := newSink(sinkHTTP, map[string]interface{}{})
+			So(err.Error(), ShouldEqual, "Missing 'url' in the config of built-in publisher 'http'")
+			_, err = newSink(sinkHTTP, map[string]interface{}{"url": "ftp://example.com"})
+			So(err.Error(), ShouldEqual, "Invalid url 'ftp://example.com' in built-in publisher 'http' (expected an http(s) URL)")
+			_, err = newSink(sinkHTTP, map[string]interface{}{"url": srv.URL, "token": "t", "username": "u"})
+			So(err, ShouldNotBeNil)
+			_, err = newSink(sinkHTTP, map[string]interface{}{"url": srv.URL, "timeout": "0s"})
+			So(err.Error(), ShouldStartWith, "Invalid timeout '0s'")
+			_, err = newSink(sinkHTTP, map[string]interface{}{"url": srv.URL, "header.X-Scope": 1.0})
+			So(err.Error(), ShouldEqual, "Invalid 'header.X-Scope' in the config of built-in publisher 'http' (expected a string)")
+		})
+	})
+}
+
+func TestWorkflowHTTPSink(t *testing.T) {
+	Convey("Given a task publishing to the built-in http publisher", t, func() {
+		ep := &endpoint{statuses: []int{200, 503}}
+		srv := httptest.NewServer(ep)
+		defer srv.Close()
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(&publishRecorder{mockMetricManager: newMockMetricManager()})
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		pu := &wmap.PublishWorkflowMapNode{Builtin: "http"}
+		pu.AddConfigItem("url", srv.URL)
+		pu.AddConfigItem("batch_size", 1)
+		w.Collect.Add(pu)
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+		tk.killChan = make(chan struct{})
+
+		Convey("the metrics not accepted after a server error are posted again", func() {
+			tk.fire()
+			So(ep.sizes(), ShouldResemble, []int{1, 1, 1})
+			So(ep.batches[2][0].Namespace, ShouldEqual, "/foo/baz")
+			So(tk.DeliveryStats(), ShouldResemble, core.TaskDeliveryStats{Published: 2, Retries: 1, RetryableFailures: 1})
+			So(tk.FailedCount(), ShouldEqual, 0)
+		})
+	})
+}
@@ -0,0 +1,296 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+// The policies applied to a batch of metrics which does not fit in the
+// memory budget of the metrics in flight
+const (
+	// inflightSpill writes the batch to disk until the memory is available
+	inflightSpill = "spill"
+	// inflightDrop drops the batch
+	inflightDrop = "drop"
+)
+
+const (
+	// spillSuffix is the suffix of the files the batches are spilled to
+	spillSuffix = ".spill"
+	// metricOverhead is the estimated size of a metric, its namespace, tags
+	// and data excluded
+	metricOverhead = 128
+)
+
+var inflightLogger = schedulerLogger.WithField("_block", "inflight")
+
+// inflightBudget bounds the memory used by the metrics being published.  A
+// batch of metrics is admitted when it fits in the budget, or when no other
+// batch is in flight so that a batch larger than the budget is not stuck.
+// A batch which does not fit is spilled to disk, then published when the
+// batches in flight release enough memory, or dropped.  The spilled batches
+// are published in order and, while some are waiting, the new batches are
+// spilled after them.
+type inflightBudget struct {
+	max    int64
+	policy string
+	dir    string
+
+	sync.Mutex
+	used    int64
+	seq     uint64
+	spilled []*spilledBatch
+	stats   core.InflightMetrics
+}
+
+// spilledBatch is a batch of metrics waiting on disk to be published to a
+// publish node of a task
+type spilledBatch struct {
+	path  string
+	size  int64
+	count int
+	task  *task
+	node  *publishNode
+}
+
+// parseInflightPolicy validates the policy applied to the batches exceeding
+// the memory budget
+func parseInflightPolicy(policy string) (string, error) {
+	switch policy {
+	case "":
+		return inflightSpill, nil
+	case inflightSpill, inflightDrop:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("Unknown in-flight overflow policy '%s' (expected spill or drop)", policy)
+	}
+}
+
+// newInflightBudget returns the budget of the configuration, or nil when the
+// metrics in flight are not limited.  The batches spilled by a previous run
+// of snapteld, whose tasks are gone, are removed.
+func newInflightBudget(cfg *Config) (*inflightBudget, error) {
+	if cfg.MaxInflightMemory <= 0 {
+		return nil, nil
+	}
+	policy, err := parseInflightPolicy(cfg.InflightOverflowPolicy)
+	if err != nil {
+		return nil, err
+	}
+	b := &inflightBudget{
+		max:    cfg.MaxInflightMemory,
+		policy: policy,
+		dir:    cfg.InflightSpillPath,
+	}
+	b.stats.Budget = b.max
+	if policy != inflightSpill {
+		return b, nil
+	}
+	if b.dir == "" {
+		b.dir = filepath.Join(os.TempDir(), "snap-spill")
+	}
+	if err := os.MkdirAll(b.dir, 0700); err != nil {
+		return nil, fmt.Errorf("%v (while parsing 'scheduler::inflight_spill_path')", err)
+	}
+	stale, _ := filepath.Glob(filepath.Join(b.dir, "*"+spillSuffix))
+	for _, path := range stale {
+		os.Remove(path)
+	}
+	return b, nil
+}
+
+// admit reserves the memory of the metrics published to a publish node and
+// returns their size, or returns false when they were spilled or dropped.
+// The size is given back to release once published.
+func (b *inflightBudget) admit(t *task, pu *publishNode, mts []core.Metric) (int64, bool) {
+	size := metricsSize(mts)
+	b.Lock()
+	defer b.Unlock()
+	if len(b.spilled) == 0 && b.fits(size) {
+		b.used += size
+		return size, true
+	}
+	fields := log.Fields{
+		"task-id":      t.id,
+		"task-name":    t.name,
+		"publish-name": pu.Name(),
+		"metrics":      len(mts),
+		"size":         size,
+		"used":         b.used,
+		"budget":       b.max,
+	}
+	if b.policy == inflightSpill {
+		err := b.spill(t, pu, mts, size)
+		if err == nil {
+			inflightLogger.WithFields(fields).Debug("In-flight memory budget exceeded, batch spilled to disk")
+			return 0, false
+		}
+		fields["_error"] = err.Error()
+	}
+	b.stats.DroppedBatches++
+	b.stats.DroppedMetrics += uint64(len(mts))
+	inflightLogger.WithFields(fields).Warn("In-flight memory budget exceeded, dropping metrics")
+	return 0, false
+}
+
+// fits must be called with the budget locked
+func (b *inflightBudget) fits(size int64) bool {
+	return b.used == 0 || b.used+size <= b.max
+}
+
+// spill must be called with the budget locked
+func (b *inflightBudget) spill(t *task, pu *publishNode, mts []core.Metric, size int64) error {
+	mtypes := make([]plugin.MetricType, len(mts))
+	for i, m := range mts {
+		mtypes[i] = transformedMetric(m, m.Namespace(), m.Data(), m.Unit()).(plugin.MetricType)
+	}
+	payload, _, err := plugin.MarshalMetricTypes(plugin.SnapGOBContentType, mtypes)
+	if err != nil {
+		return err
+	}
+	b.seq++
+	path := filepath.Join(b.dir, fmt.Sprintf("%020d%s", b.seq, spillSuffix))
+	if err := ioutil.WriteFile(path, payload, 0600); err != nil {
+		return err
+	}
+	b.spilled = append(b.spilled, &spilledBatch{path: path, size: size, count: len(mts), task: t, node: pu})
+	b.stats.SpilledBatches++
+	b.stats.SpilledBytes += size
+	b.stats.SpillsTotal++
+	return nil
+}
+
+// release gives back the memory of published metrics, then publishes the
+// spilled batches fitting in the budget
+func (b *inflightBudget) release(size int64) {
+	b.Lock()
+	b.used -= size
+	b.Unlock()
+	b.drain()
+}
+
+func (b *inflightBudget) drain() {
+	for {
+		b.Lock()
+		if len(b.spilled) == 0 || !b.fits(b.spilled[0].size) {
+			b.Unlock()
+			return
+		}
+		sb := b.spilled[0]
+		b.spilled = b.spilled[1:]
+		b.used += sb.size
+		b.stats.SpilledBatches--
+		b.stats.SpilledBytes -= sb.size
+		b.Unlock()
+		go b.replay(sb)
+	}
+}
+
+// replay publishes a spilled batch read back from disk
+func (b *inflightBudget) replay(sb *spilledBatch) {
+	defer b.release(sb.size)
+	mts, err := sb.read()
+	if err != nil {
+		b.Lock()
+		b.stats.DroppedBatches++
+		b.stats.DroppedMetrics += uint64(sb.count)
+		b.Unlock()
+		inflightLogger.WithFields(log.Fields{
+			"task-id":      sb.task.id,
+			"task-name":    sb.task.name,
+			"publish-name": sb.node.Name(),
+			"metrics":      sb.count,
+			"_error":       err.Error(),
+		}).Error("Unable to read back a spilled batch, dropping metrics")
+		return
+	}
+	t := sb.task
+	submitPublish(t.detachedContext(), newBatchJob(collectJobType, time.Now().Add(t.deadlineDuration), t.id, mts), t, sb.node)
+}
+
+// read returns the metrics of the batch and removes its file
+func (sb *spilledBatch) read() ([]core.Metric, error) {
+	defer os.Remove(sb.path)
+	payload, err := ioutil.ReadFile(sb.path)
+	if err != nil {
+		return nil, err
+	}
+	mtypes, err := plugin.UnmarshallMetricTypes(plugin.SnapGOBContentType, payload)
+	if err != nil {
+		return nil, err
+	}
+	mts := make([]core.Metric, len(mtypes))
+	for i, m := range mtypes {
+		mts[i] = m
+	}
+	return mts, nil
+}
+
+// metrics returns the state of the budget
+func (b *inflightBudget) metrics() core.InflightMetrics {
+	b.Lock()
+	defer b.Unlock()
+	stats := b.stats
+	stats.Used = b.used
+	return stats
+}
+
+// metricsSize estimates the memory used by the metrics
+func metricsSize(mts []core.Metric) int64 {
+	var size int64
+	for _, m := range mts {
+		size += metricOverhead
+		for _, e := range m.Namespace() {
+			size += int64(len(e.Value) + len(e.Name) + len(e.Description))
+		}
+		for k, v := range m.Tags() {
+			size += int64(len(k) + len(v))
+		}
+		switch d := m.Data().(type) {
+		case string:
+			size += int64(len(d))
+		case []byte:
+			size += int64(len(d))
+		default:
+			size += 16
+		}
+	}
+	return size
+}
+
+// InflightMetrics returns the state of the memory budget of the metrics being
+// published.
+func (s *scheduler) InflightMetrics() core.InflightMetrics {
+	if s.inflight == nil {
+		return core.InflightMetrics{}
+	}
+	return s.inflight.metrics()
+}
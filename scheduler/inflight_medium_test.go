@@ -0,0 +1,124 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+func spillFiles(dir string) []string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+spillSuffix))
+	return files
+}
+
+func TestInflightBudget(t *testing.T) {
+	Convey("Given a scheduler whose metrics in flight fit in a small budget", t, func() {
+		dir, err := ioutil.TempDir("", "snap-spill")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		cfg := GetDefaultConfig()
+		cfg.MaxInflightMemory = 1
+		cfg.InflightSpillPath = dir
+		s := New(cfg)
+		mm := &publishRecorder{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newBufferedWorkflowMap(nil), false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+		pu := tk.workflow.publishNodes[0]
+		mts := []core.Metric{
+			plugin.MetricType{Namespace_: core.NewNamespace("foo", "bar"), Data_: 1, Tags_: map[string]string{"host": "a"}},
+			plugin.MetricType{Namespace_: core.NewNamespace("foo", "baz"), Data_: "value"},
+		}
+
+		Convey("a batch is admitted while no other is in flight", func() {
+			size, ok := s.inflight.admit(tk, pu, mts)
+			So(ok, ShouldBeTrue)
+			So(size, ShouldEqual, metricsSize(mts))
+			So(s.InflightMetrics().Used, ShouldEqual, size)
+
+			Convey("the next ones are spilled, then published in order once it is released", func() {
+				_, ok := s.inflight.admit(tk, pu, mts[:1])
+				So(ok, ShouldBeFalse)
+				_, ok = s.inflight.admit(tk, pu, mts)
+				So(ok, ShouldBeFalse)
+				So(spillFiles(dir), ShouldHaveLength, 2)
+				stats := s.InflightMetrics()
+				So(stats.SpilledBatches, ShouldEqual, 2)
+				So(stats.SpilledBytes, ShouldEqual, metricsSize(mts[:1])+size)
+				So(stats.SpillsTotal, ShouldEqual, 2)
+
+				s.inflight.release(size)
+				for i := 0; i < 100 && len(mm.published()) < 2; i++ {
+					time.Sleep(10 * time.Millisecond)
+				}
+				So(mm.published(), ShouldResemble, []int{1, 2})
+				for i := 0; i < 100 && s.InflightMetrics().Used > 0; i++ {
+					time.Sleep(10 * time.Millisecond)
+				}
+				stats = s.InflightMetrics()
+				So(stats.Used, ShouldEqual, 0)
+				So(stats.SpilledBatches, ShouldEqual, 0)
+				So(stats.SpilledBytes, ShouldEqual, 0)
+				So(spillFiles(dir), ShouldBeEmpty)
+			})
+		})
+		Convey("the batches are dropped with the drop policy", func() {
+			s.inflight.policy = inflightDrop
+			_, ok := s.inflight.admit(tk, pu, mts)
+			So(ok, ShouldBeTrue)
+			_, ok = s.inflight.admit(tk, pu, mts)
+			So(ok, ShouldBeFalse)
+			So(spillFiles(dir), ShouldBeEmpty)
+			stats := s.InflightMetrics()
+			So(stats.DroppedBatches, ShouldEqual, 1)
+			So(stats.DroppedMetrics, ShouldEqual, 2)
+		})
+		Convey("the batches spilled by a previous run are removed", func() {
+			So(ioutil.WriteFile(filepath.Join(dir, "1"+spillSuffix), []byte{}, 0600), ShouldBeNil)
+			_, err := newInflightBudget(cfg)
+			So(err, ShouldBeNil)
+			So(spillFiles(dir), ShouldBeEmpty)
+		})
+	})
+	Convey("The metrics in flight are not bounded by default", t, func() {
+		b, err := newInflightBudget(GetDefaultConfig())
+		So(err, ShouldBeNil)
+		So(b, ShouldBeNil)
+		cfg := GetDefaultConfig()
+		cfg.MaxInflightMemory = 1
+		cfg.InflightOverflowPolicy = "block"
+		_, err = newInflightBudget(cfg)
+		So(err, ShouldNotBeNil)
+	})
+}
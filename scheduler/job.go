@@ -20,6 +20,7 @@ limitations under the License.
 package scheduler
 
 import (
+	"context"
 	"sync"
 	"time"
 
@@ -29,6 +30,8 @@ import (
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/ctypes"
 	. "github.com/intelsdi-x/snap/pkg/promise"
+	"github.com/intelsdi-x/snap/pkg/tracing"
+	"github.com/intelsdi-x/snap/pkg/versions"
 )
 
 const (
@@ -88,6 +91,7 @@ type job interface {
 	TaskID() string
 	Run()
 	Metrics() []core.Metric
+	Context() context.Context
 }
 
 type jobType int
@@ -101,6 +105,12 @@ type coreJob struct {
 	deadline  time.Time
 	starttime time.Time
 	errors    []error
+	// ctx is the context of the run the job is submitted for
+	ctx context.Context
+	// span records the job when the task run is traced
+	span *tracing.Span
+	// runTime is how long the worker spent running the job
+	runTime time.Duration
 }
 
 func newCoreJob(t jobType, deadline time.Time, taskID string, name string, version int) *coreJob {
@@ -115,6 +125,16 @@ func newCoreJob(t jobType, deadline time.Time, taskID string, name string, versi
 	}
 }
 
+// timeRun records how long the job ran since start
+func (c *coreJob) timeRun(start time.Time) {
+	c.runTime = time.Since(start)
+}
+
+// RunTime returns how long the worker spent running the job
+func (c *coreJob) RunTime() time.Duration {
+	return c.runTime
+}
+
 func (c *coreJob) StartTime() time.Time {
 	return c.starttime
 }
@@ -170,6 +190,8 @@ type collectorJob struct {
 	metrics        []core.Metric
 	configDataTree *cdata.ConfigDataTree
 	tags           map[string]map[string]string
+	// at, when set, is the past time the metrics are collected at
+	at time.Time
 }
 
 func newCollectorJob(
@@ -194,6 +216,8 @@ type metric struct {
 	namespace core.Namespace
 	version   int
 	config    *cdata.ConfigDataNode
+	// constraint, when set, selects the version of the metric
+	constraint *versions.Constraint
 }
 
 func (m *metric) Namespace() core.Namespace {
@@ -220,6 +244,7 @@ func (c *collectorJob) Metrics() []core.Metric {
 }
 
 func (c *collectorJob) Run() {
+	defer c.timeRun(time.Now())
 	log.WithFields(log.Fields{
 		"_module":      "scheduler-job",
 		"block":        "run",
@@ -240,7 +265,7 @@ func (c *collectorJob) Run() {
 		}
 	}
 
-	ret, errs := c.collector.CollectMetrics(c.TaskID(), c.tags)
+	ret, errs := c.collect()
 
 	log.WithFields(log.Fields{
 		"_module":      "scheduler-job",
@@ -275,17 +300,18 @@ func (pr *processJob) Metrics() []core.Metric {
 	return pr.metrics
 }
 
-func newProcessJob(parentJob job, pluginName string, pluginVersion int, contentType string, config map[string]ctypes.ConfigValue, processor processesMetrics, taskID string) job {
+func newProcessJob(parentJob job, deadline time.Time, pluginName string, pluginVersion int, contentType string, config map[string]ctypes.ConfigValue, processor processesMetrics, taskID string) job {
 	return &processJob{
 		parentJob: parentJob,
 		metrics:   []core.Metric{},
-		coreJob:   newCoreJob(processJobType, parentJob.Deadline(), taskID, pluginName, pluginVersion),
+		coreJob:   newCoreJob(processJobType, deadline, taskID, pluginName, pluginVersion),
 		config:    config,
 		processor: processor,
 	}
 }
 
 func (p *processJob) Run() {
+	defer p.timeRun(time.Now())
 	log.WithFields(log.Fields{
 		"_module":        "scheduler-job",
 		"block":          "run",
@@ -295,7 +321,7 @@ func (p *processJob) Run() {
 		"plugin-config":  p.config,
 	}).Debug("starting processor job")
 
-	mts, errs := p.processor.ProcessMetrics(p.parentJob.Metrics(), p.config, p.taskID, p.name, p.version)
+	mts, errs := p.process()
 	if errs != nil {
 		for _, e := range errs {
 			log.WithFields(log.Fields{
@@ -324,16 +350,17 @@ func (pu *publisherJob) Metrics() []core.Metric {
 	return []core.Metric{}
 }
 
-func newPublishJob(parentJob job, pluginName string, pluginVersion int, contentType string, config map[string]ctypes.ConfigValue, publisher publishesMetrics, taskID string) job {
+func newPublishJob(parentJob job, deadline time.Time, pluginName string, pluginVersion int, contentType string, config map[string]ctypes.ConfigValue, publisher publishesMetrics, taskID string) job {
 	return &publisherJob{
 		parentJob: parentJob,
 		publisher: publisher,
-		coreJob:   newCoreJob(publishJobType, parentJob.Deadline(), taskID, pluginName, pluginVersion),
+		coreJob:   newCoreJob(publishJobType, deadline, taskID, pluginName, pluginVersion),
 		config:    config,
 	}
 }
 
 func (p *publisherJob) Run() {
+	defer p.timeRun(time.Now())
 	log.WithFields(log.Fields{
 		"_module":        "scheduler-job",
 		"block":          "run",
@@ -343,7 +370,7 @@ func (p *publisherJob) Run() {
 		"plugin-config":  p.config,
 	}).Debug("starting publisher job")
 
-	errs := p.publisher.PublishMetrics(p.parentJob.Metrics(), p.config, p.taskID, p.name, p.version)
+	errs := p.publish()
 	if errs != nil {
 		for _, e := range errs {
 			log.WithFields(log.Fields{
@@ -359,3 +386,24 @@ func (p *publisherJob) Run() {
 		p.AddErrors(errs...)
 	}
 }
+
+// batchJob is the parent job of a publish job publishing metrics prepared by
+// the publish node, e.g. buffered or ordered metrics.
+type batchJob struct {
+	*coreJob
+	metrics []core.Metric
+}
+
+func newBatchJob(t jobType, deadline time.Time, taskID string, mts []core.Metric) *batchJob {
+	return &batchJob{
+		coreJob: newCoreJob(t, deadline, taskID, "", 0),
+		metrics: mts,
+	}
+}
+
+func (b *batchJob) Metrics() []core.Metric {
+	return b.metrics
+}
+
+// Run does nothing; the metrics of a batch job are already collected.
+func (b *batchJob) Run() {}
@@ -0,0 +1,448 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// kafkaOptionPrefix prefixes the keys of the config passed through to the
+// configuration of the sarama producer, e.g. sarama.producer.required_acks
+const kafkaOptionPrefix = "sarama."
+
+var (
+	// kafkaPlaceholder matches the placeholders of the templates of the
+	// topics and keys of the kafka publisher
+	kafkaPlaceholder = regexp.MustCompile(`\{([^{}]+)\}`)
+	// kafkaTopicInvalid matches the characters not allowed in a topic
+	kafkaTopicInvalid = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+)
+
+// kafkaSink produces a message per metric, holding the metric in JSON, to
+// the topic of the metric on the brokers.  The topic and the key of the
+// messages, which selects their partition, are templates expanded for every
+// metric.  The producer connects to the brokers on the first publish; the
+// lock is only held to swap it, neither while connecting nor while sending.
+type kafkaSink struct {
+	brokers []string
+	topic   kafkaTemplate
+	key     kafkaTemplate
+	config  *sarama.Config
+	// newProducer connects a producer to the brokers
+	newProducer func([]string, *sarama.Config) (sarama.SyncProducer, error)
+
+	sync.Mutex
+	producer *kafkaProducer
+	closed   bool
+}
+
+// kafkaProducer is a producer connected to the brokers with the count of the
+// sends in flight; a producer dropped by the sink is closed once they return
+type kafkaProducer struct {
+	sarama.SyncProducer
+	sends   int
+	dropped bool
+}
+
+// errKafkaSinkClosed is returned by a publish racing with the removal of the
+// task
+var errKafkaSinkClosed = errors.New("built-in publisher 'kafka' is closed")
+
+func newKafkaSink(config map[string]interface{}) (*kafkaSink, error) {
+	brokers, err := sinkString(sinkKafka, config, "brokers", true)
+	if err != nil {
+		return nil, err
+	}
+	topic, err := sinkString(sinkKafka, config, "topic", true)
+	if err != nil {
+		return nil, err
+	}
+	key, err := sinkString(sinkKafka, config, "key", false)
+	if err != nil {
+		return nil, err
+	}
+	k := &kafkaSink{
+		config:      sarama.NewConfig(),
+		newProducer: sarama.NewSyncProducer,
+	}
+	for _, b := range strings.Split(brokers, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			k.brokers = append(k.brokers, b)
+		}
+	}
+	if len(k.brokers) == 0 {
+		return nil, fmt.Errorf("Invalid brokers '%s' in built-in publisher 'kafka' (expected a list of host:port separated by commas)", brokers)
+	}
+	if k.topic, err = parseKafkaTemplate("topic", topic); err != nil {
+		return nil, err
+	}
+	if k.key, err = parseKafkaTemplate("key", key); err != nil {
+		return nil, err
+	}
+	k.config.ClientID = "snap"
+	for name, value := range config {
+		if !strings.HasPrefix(name, kafkaOptionPrefix) {
+			continue
+		}
+		if err := setKafkaOption(k.config, strings.TrimPrefix(name, kafkaOptionPrefix), value); err != nil {
+			return nil, err
+		}
+	}
+	// the sync producer waits for the acknowledgement of every message
+	k.config.Producer.Return.Successes = true
+	k.config.Producer.Return.Errors = true
+	if err := k.config.Validate(); err != nil {
+		return nil, fmt.Errorf("Invalid sarama config in built-in publisher 'kafka': %v", err)
+	}
+	return k, nil
+}
+
+// publish produces the messages of the metrics; the error tells how many
+// metrics, in order, the brokers acknowledged before the first failure.  A
+// publish which does not complete by the deadline of the context drops the
+// producer, so that the next one connects again.
+func (k *kafkaSink) publish(ctx context.Context, mts []core.Metric, _ time.Time) error {
+	msgs := make([]*sarama.ProducerMessage, len(mts))
+	for i, m := range mts {
+		b, err := json.Marshal(newMetricRecord(m))
+		if err != nil {
+			return &core.PublishError{Err: err, Published: i}
+		}
+		msgs[i] = &sarama.ProducerMessage{
+			Topic:    kafkaTopicInvalid.ReplaceAllString(k.topic.expand(m), "_"),
+			Value:    sarama.ByteEncoder(b),
+			Metadata: i,
+		}
+		if k.key != nil {
+			msgs[i].Key = sarama.StringEncoder(k.key.expand(m))
+		}
+	}
+	p, err := k.acquire(ctx)
+	if err != nil {
+		return &core.PublishError{Err: err, Retryable: err != errKafkaSinkClosed}
+	}
+	done := make(chan error, 1)
+	go func() {
+		err := p.SendMessages(msgs)
+		k.release(p)
+		done <- err
+	}()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		k.drop(p)
+		return &core.PublishError{Err: ctx.Err(), Retryable: true}
+	}
+	if err == nil {
+		return nil
+	}
+	errs, ok := err.(sarama.ProducerErrors)
+	if !ok || len(errs) == 0 {
+		// the producer is connected again on the next publish
+		k.drop(p)
+		return &core.PublishError{Err: err, Retryable: true}
+	}
+	published := len(mts)
+	retryable := true
+	for _, pe := range errs {
+		if i, ok := pe.Msg.Metadata.(int); ok && i < published {
+			published = i
+		}
+		if !retryableKafkaError(pe.Err) {
+			retryable = false
+		}
+	}
+	return &core.PublishError{
+		Err:       fmt.Errorf("%d of %d metrics not published to kafka: %v", len(errs), len(mts), errs[0].Err),
+		Published: published,
+		Retryable: retryable,
+	}
+}
+
+// acquire returns the producer, connected to the brokers until the deadline
+// of the context when there is none, and counts a send in flight on it
+func (k *kafkaSink) acquire(ctx context.Context) (*kafkaProducer, error) {
+	k.Lock()
+	if k.closed {
+		k.Unlock()
+		return nil, errKafkaSinkClosed
+	}
+	if p := k.producer; p != nil {
+		p.sends++
+		k.Unlock()
+		return p, nil
+	}
+	k.Unlock()
+
+	type connected struct {
+		producer sarama.SyncProducer
+		err      error
+	}
+	done := make(chan connected, 1)
+	go func() {
+		p, err := k.newProducer(k.brokers, k.config)
+		done <- connected{p, err}
+	}()
+	var c connected
+	select {
+	case c = <-done:
+	case <-ctx.Done():
+		// the producer connected past the deadline is not used
+		go func() {
+			if c := <-done; c.err == nil {
+				c.producer.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	k.Lock()
+	defer k.Unlock()
+	switch {
+	case k.closed:
+		c.producer.Close()
+		return nil, errKafkaSinkClosed
+	case k.producer != nil:
+		// another publish connected meanwhile
+		c.producer.Close()
+	default:
+		k.producer = &kafkaProducer{SyncProducer: c.producer}
+	}
+	k.producer.sends++
+	return k.producer, nil
+}
+
+// release counts a send on the producer as returned, closing the producer
+// when it was the last one of a dropped producer
+func (k *kafkaSink) release(p *kafkaProducer) {
+	k.Lock()
+	defer k.Unlock()
+	p.sends--
+	if p.dropped && p.sends == 0 {
+		p.Close()
+	}
+}
+
+// drop stops using the producer, which is closed once its sends return
+func (k *kafkaSink) drop(p *kafkaProducer) {
+	k.Lock()
+	defer k.Unlock()
+	if k.producer == p {
+		k.producer = nil
+	}
+	if p.dropped {
+		return
+	}
+	p.dropped = true
+	if p.sends == 0 {
+		p.Close()
+	}
+}
+
+func (k *kafkaSink) close() {
+	k.Lock()
+	p := k.producer
+	k.closed = true
+	k.Unlock()
+	if p != nil {
+		k.drop(p)
+	}
+}
+
+// retryableKafkaError returns false for the errors which a retry would not
+// fix: the messages or topics the brokers reject and the authorizations
+func retryableKafkaError(err error) bool {
+	switch err {
+	case sarama.ErrMessageSizeTooLarge, sarama.ErrInvalidMessage, sarama.ErrInvalidTopic,
+		sarama.ErrTopicAuthorizationFailed, sarama.ErrClusterAuthorizationFailed:
+		return false
+	}
+	return true
+}
+
+// kafkaTemplate is the template of the topic or the key of the messages of
+// the metrics; its elements are the text between the placeholders and the
+// placeholders: {namespace}, the namespace of the metric joined by dots,
+// {N}, the Nth element of the namespace from 0, {host}, the host the metric
+// was collected on, and {tag:NAME}, a tag of the metric.  A placeholder the
+// metric has no value for expands to nothing.
+type kafkaTemplate []kafkaTemplateElement
+
+type kafkaTemplateElement struct {
+	text  string
+	value func(m core.Metric) string
+}
+
+func parseKafkaTemplate(setting, text string) (kafkaTemplate, error) {
+	if text == "" {
+		return nil, nil
+	}
+	t := kafkaTemplate{}
+	last := 0
+	for _, loc := range kafkaPlaceholder.FindAllStringSubmatchIndex(text, -1) {
+		if loc[0] > last {
+			t = append(t, kafkaTemplateElement{text: text[last:loc[0]]})
+		}
+		value, err := kafkaPlaceholderValue(text[loc[2]:loc[3]])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s '%s' in built-in publisher 'kafka' (%v)", setting, text, err)
+		}
+		t = append(t, kafkaTemplateElement{value: value})
+		last = loc[1]
+	}
+	if last < len(text) {
+		t = append(t, kafkaTemplateElement{text: text[last:]})
+	}
+	return t, nil
+}
+
+func kafkaPlaceholderValue(p string) (func(m core.Metric) string, error) {
+	switch {
+	case p == "namespace":
+		return func(m core.Metric) string {
+			return strings.Join(m.Namespace().Strings(), ".")
+		}, nil
+	case p == "host":
+		return func(m core.Metric) string {
+			return m.Tags()[core.STD_TAG_PLUGIN_RUNNING_ON]
+		}, nil
+	case strings.HasPrefix(p, "tag:") && len(p) > len("tag:"):
+		tag := strings.TrimPrefix(p, "tag:")
+		return func(m core.Metric) string {
+			return m.Tags()[tag]
+		}, nil
+	}
+	i, err := strconv.Atoi(p)
+	if err != nil || i < 0 {
+		return nil, fmt.Errorf("unknown placeholder {%s}, expected {namespace}, {N}, {host} or {tag:NAME}", p)
+	}
+	return func(m core.Metric) string {
+		if ns := m.Namespace().Strings(); i < len(ns) {
+			return ns[i]
+		}
+		return ""
+	}, nil
+}
+
+func (t kafkaTemplate) expand(m core.Metric) string {
+	var out string
+	for _, e := range t {
+		if e.value != nil {
+			out += e.value(m)
+		} else {
+			out += e.text
+		}
+	}
+	return out
+}
+
+// setKafkaOption sets the field of the sarama config at the given path,
+// e.g. producer.required_acks for Producer.RequiredAcks; the names of the
+// fields are matched ignoring case and underscores
+func setKafkaOption(cfg *sarama.Config, path string, value interface{}) error {
+	v := reflect.ValueOf(cfg).Elem()
+	for _, name := range strings.Split(path, ".") {
+		name = strings.Replace(name, "_", "", -1)
+		if v.Kind() != reflect.Struct {
+			v = reflect.Value{}
+			break
+		}
+		v = v.FieldByNameFunc(func(n string) bool { return strings.EqualFold(n, name) })
+		if !v.IsValid() {
+			break
+		}
+	}
+	if !v.IsValid() || !v.CanSet() {
+		return fmt.Errorf("Unknown sarama option '%s%s' in built-in publisher 'kafka'", kafkaOptionPrefix, path)
+	}
+	invalid := fmt.Errorf("Invalid sarama option '%s%s' in built-in publisher 'kafka' (expected a %s)", kafkaOptionPrefix, path, v.Type())
+	switch v.Interface().(type) {
+	case time.Duration:
+		s, ok := value.(string)
+		d, err := time.ParseDuration(s)
+		if !ok || err != nil {
+			return invalid
+		}
+		v.SetInt(int64(d))
+		return nil
+	case sarama.KafkaVersion:
+		s, ok := value.(string)
+		version, err := sarama.ParseKafkaVersion(s)
+		if !ok || err != nil {
+			return invalid
+		}
+		v.Set(reflect.ValueOf(version))
+		return nil
+	case sarama.CompressionCodec:
+		if s, ok := value.(string); ok {
+			codecs := map[string]sarama.CompressionCodec{
+				"none":   sarama.CompressionNone,
+				"gzip":   sarama.CompressionGZIP,
+				"snappy": sarama.CompressionSnappy,
+				"lz4":    sarama.CompressionLZ4,
+			}
+			codec, ok := codecs[s]
+			if !ok {
+				return invalid
+			}
+			v.Set(reflect.ValueOf(codec))
+			return nil
+		}
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return invalid
+		}
+		v.SetBool(b)
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return invalid
+		}
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, ok := toFloat64(value)
+		if !ok || f != float64(int64(f)) || v.OverflowInt(int64(f)) {
+			return invalid
+		}
+		v.SetInt(int64(f))
+	default:
+		return fmt.Errorf("Unsupported sarama option '%s%s' in built-in publisher 'kafka'", kafkaOptionPrefix, path)
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// producerRecorder records the messages it is given and fails the messages
+// at the given indexes of the next call with the given error
+type producerRecorder struct {
+	sarama.SyncProducer
+	sync.Mutex
+	messages []*sarama.ProducerMessage
+	failing  map[int]error
+	closed   bool
+	// blocked, when set, holds the sends until it is closed
+	blocked chan struct{}
+}
+
+func (p *producerRecorder) SendMessages(msgs []*sarama.ProducerMessage) error {
+	if p.blocked != nil {
+		<-p.blocked
+	}
+	p.Lock()
+	defer p.Unlock()
+	var errs sarama.ProducerErrors
+	for i, m := range msgs {
+		if err, ok := p.failing[i]; ok {
+			errs = append(errs, &sarama.ProducerError{Msg: m, Err: err})
+			continue
+		}
+		p.messages = append(p.messages, m)
+	}
+	p.failing = nil
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (p *producerRecorder) Close() error {
+	p.Lock()
+	defer p.Unlock()
+	p.closed = true
+	return nil
+}
+
+func (p *producerRecorder) isClosed() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.closed
+}
+
+func (p *producerRecorder) waitForClose() bool {
+	for i := 0; i < 200 && !p.isClosed(); i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return p.isClosed()
+}
+
+func (p *producerRecorder) topics() []string {
+	p.Lock()
+	defer p.Unlock()
+	topics := []string{}
+	for _, m := range p.messages {
+		topics = append(topics, m.Topic)
+	}
+	return topics
+}
+
+func newTestKafkaSink(config map[string]interface{}, p *producerRecorder) (*kafkaSink, error) {
+	s, err := newSink(sinkKafka, config)
+	if err != nil {
+		return nil, err
+	}
+	k := s.(*kafkaSink)
+	k.newProducer = func(brokers []string, _ *sarama.Config) (sarama.SyncProducer, error) {
+		return p, nil
+	}
+	return k, nil
+}
+
+func TestKafkaSink(t *testing.T) {
+	Convey("Given the built-in kafka publisher", t, func() {
+		p := &producerRecorder{}
+		now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+		mts := []core.Metric{
+			plugin.MetricType{Namespace_: core.NewNamespace("intel", "procfs", "cpu"), Timestamp_: now, Tags_: map[string]string{core.STD_TAG_PLUGIN_RUNNING_ON: "node-1", "dc": "eu west"}, Data_: 1.5},
+			plugin.MetricType{Namespace_: core.NewNamespace("intel", "docker", "mem"), Timestamp_: now, Tags_: map[string]string{core.STD_TAG_PLUGIN_RUNNING_ON: "node-2"}, Data_: 2.5},
+		}
+
+		Convey("it produces a message per metric to the topic and key of the metric", func() {
+			k, err := newTestKafkaSink(map[string]interface{}{"brokers": "kafka-1:9092, kafka-2:9092", "topic": "snap.{1}.{tag:dc}", "key": "{host}"}, p)
+			So(err, ShouldBeNil)
+			So(k.brokers, ShouldResemble, []string{"kafka-1:9092", "kafka-2:9092"})
+			So(k.publish(context.Background(), mts, now), ShouldBeNil)
+			So(p.topics(), ShouldResemble, []string{"snap.procfs.eu_west", "snap.docker."})
+			So(p.messages[1].Key, ShouldEqual, sarama.StringEncoder("node-2"))
+			var r metricRecord
+			So(json.Unmarshal(p.messages[0].Value.(sarama.ByteEncoder), &r), ShouldBeNil)
+			So(r.Namespace, ShouldEqual, "/intel/procfs/cpu")
+			So(r.Data, ShouldEqual, 1.5)
+
+			Convey("the producer is closed with the publisher", func() {
+				k.close()
+				So(p.isClosed(), ShouldBeTrue)
+			})
+		})
+		Convey("the messages have no key without a key template", func() {
+			k, err := newTestKafkaSink(map[string]interface{}{"brokers": "kafka:9092", "topic": "{namespace}"}, p)
+			So(err, ShouldBeNil)
+			So(k.publish(context.Background(), mts, now), ShouldBeNil)
+			So(p.topics(), ShouldResemble, []string{"intel.procfs.cpu", "intel.docker.mem"})
+			So(p.messages[0].Key, ShouldBeNil)
+		})
+		Convey("the metrics from the first failed message are published again", func() {
+			k, err := newTestKafkaSink(map[string]interface{}{"brokers": "kafka:9092", "topic": "snap"}, p)
+			So(err, ShouldBeNil)
+			p.failing = map[int]error{1: sarama.ErrNotLeaderForPartition}
+			err = k.publish(context.Background(), mts, now)
+			So(err, ShouldHaveSameTypeAs, &core.PublishError{})
+			So(err.(*core.PublishError).Published, ShouldEqual, 1)
+			So(err.(*core.PublishError).Retryable, ShouldBeTrue)
+			p.failing = map[int]error{0: sarama.ErrMessageSizeTooLarge}
+			err = k.publish(context.Background(), mts, now)
+			So(err.(*core.PublishError).Published, ShouldEqual, 0)
+			So(err.(*core.PublishError).Retryable, ShouldBeFalse)
+		})
+		Convey("a producer failing to connect is retried", func() {
+			k, err := newSink(sinkKafka, map[string]interface{}{"brokers": "kafka:9092", "topic": "snap"})
+			So(err, ShouldBeNil)
+			k.(*kafkaSink).newProducer = func([]string, *sarama.Config) (sarama.SyncProducer, error) {
+				return nil, errors.New("kafka: client has run out of available brokers")
+			}
+			err = k.publish(context.Background(), mts, now)
+			So(err.(*core.PublishError).Retryable, ShouldBeTrue)
+		})
+		Convey("a send past the deadline fails and the producer is connected again", func() {
+			p.blocked = make(chan struct{})
+			k, err := newTestKafkaSink(map[string]interface{}{"brokers": "kafka:9092", "topic": "snap"}, p)
+			So(err, ShouldBeNil)
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			err = k.publish(ctx, mts, now)
+			So(err.(*core.PublishError).Err, ShouldEqual, context.DeadlineExceeded)
+			So(err.(*core.PublishError).Retryable, ShouldBeTrue)
+			So(p.isClosed(), ShouldBeFalse)
+			close(p.blocked)
+			So(p.waitForClose(), ShouldBeTrue)
+
+			connected := &producerRecorder{}
+			k.newProducer = func([]string, *sarama.Config) (sarama.SyncProducer, error) {
+				return connected, nil
+			}
+			So(k.publish(context.Background(), mts, now), ShouldBeNil)
+			So(connected.topics(), ShouldHaveLength, 2)
+		})
+		Convey("a connection past the deadline fails without holding the publisher", func() {
+			k, err := newSink(sinkKafka, map[string]interface{}{"brokers": "kafka:9092", "topic": "snap"})
+			So(err, ShouldBeNil)
+			connecting := make(chan struct{})
+			k.(*kafkaSink).newProducer = func([]string, *sarama.Config) (sarama.SyncProducer, error) {
+				<-connecting
+				return p, nil
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			err = k.publish(ctx, mts, now)
+			So(err.(*core.PublishError).Err, ShouldEqual, context.DeadlineExceeded)
+			So(err.(*core.PublishError).Retryable, ShouldBeTrue)
+			k.close()
+			close(connecting)
+			So(p.waitForClose(), ShouldBeTrue)
+			So(k.publish(context.Background(), mts, now).(*core.PublishError).Retryable, ShouldBeFalse)
+		})
+		Convey("the sarama options are passed through", func() {
+			k, err := newTestKafkaSink(map[string]interface{}{
+				"brokers":                           "kafka:9092",
+				"topic":                             "snap",
+				"sarama.client_id":                  "snap-lab",
+				"sarama.producer.required_acks":     -1,
+				"sarama.producer.compression":       "gzip",
+				"sarama.producer.flush.frequency":   "500ms",
+				"sarama.producer.max_message_bytes": 2000000,
+				"sarama.net.tls.enable":             true,
+			}, p)
+			So(err, ShouldBeNil)
+			So(k.config.ClientID, ShouldEqual, "snap-lab")
+			So(k.config.Producer.RequiredAcks, ShouldEqual, sarama.WaitForAll)
+			So(k.config.Producer.Compression, ShouldEqual, sarama.CompressionGZIP)
+			So(k.config.Producer.Flush.Frequency, ShouldEqual, 500*time.Millisecond)
+			So(k.config.Producer.MaxMessageBytes, ShouldEqual, 2000000)
+			So(k.config.Net.TLS.Enable, ShouldBeTrue)
+			So(k.config.Producer.Return.Successes, ShouldBeTrue)
+		})
+		Convey("it rejects invalid settings", func() {
+			_, err := newSink(sinkKafka, map[string]interface{}{"topic": "snap"})
+			So(err.Error(), ShouldEqual, "Missing 'brokers' in the config of built-in publisher 'kafka'")
+			_, err = newSink(sinkKafka, map[string]interface{}{"brokers": " , ", "topic": "snap"})
+			So(err.Error(), ShouldStartWith, "Invalid brokers")
+			_, err = newSink(sinkKafka, map[string]interface{}{"brokers": "kafka:9092", "topic": "snap.{plugin}"})
+			So(err.Error(), ShouldEqual, "Invalid topic 'snap.{plugin}' in built-in publisher 'kafka' (unknown placeholder {plugin}, expected {namespace}, {N}, {host} or {tag:NAME})")
+			_, err = newSink(sinkKafka, map[string]interface{}{"brokers": "kafka:9092", "topic": "snap", "sarama.producer.colour": "red"})
+			So(err.Error(), ShouldEqual, "Unknown sarama option 'sarama.producer.colour' in built-in publisher 'kafka'")
+			_, err = newSink(sinkKafka, map[string]interface{}{"brokers": "kafka:9092", "topic": "snap", "sarama.producer.retry.max": "3"})
+			So(err.Error(), ShouldStartWith, "Invalid sarama option 'sarama.producer.retry.max'")
+			_, err = newSink(sinkKafka, map[string]interface{}{"brokers": "kafka:9092", "topic": "snap", "sarama.producer.required_acks": 1000000})
+			So(err.Error(), ShouldStartWith, "Invalid sarama option 'sarama.producer.required_acks'")
+		})
+	})
+}
+
+func TestWorkflowKafkaSink(t *testing.T) {
+	Convey("Given a task publishing to the built-in kafka publisher", t, func() {
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(&publishRecorder{mockMetricManager: newMockMetricManager()})
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		pu := &wmap.PublishWorkflowMapNode{Builtin: "kafka"}
+		pu.AddConfigItem("brokers", "kafka:9092")
+		pu.AddConfigItem("topic", "snap.{1}")
+		w.Collect.Add(pu)
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+		tk.killChan = make(chan struct{})
+		p := &producerRecorder{failing: map[int]error{1: sarama.ErrLeaderNotAvailable}}
+		tk.workflow.publishNodes[0].sink.(*kafkaSink).newProducer = func([]string, *sarama.Config) (sarama.SyncProducer, error) {
+			return p, nil
+		}
+
+		Convey("the metrics not acknowledged by the brokers are produced again", func() {
+			tk.fire()
+			So(p.topics(), ShouldResemble, []string{"snap.bar", "snap.baz"})
+			So(tk.DeliveryStats(), ShouldResemble, core.TaskDeliveryStats{Published: 2, Retries: 1, RetryableFailures: 1})
+
+			Convey("the producer is closed when the task is removed", func() {
+				So(s.RemoveTask(tk.ID()), ShouldBeNil)
+				So(p.isClosed(), ShouldBeTrue)
+			})
+		})
+	})
+}
@@ -0,0 +1,98 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+)
+
+// electsLeaders elects the member of a cluster firing a singleton task
+type electsLeaders interface {
+	// Leader returns true when this node leads the task along with the term
+	// of the current leadership of the task.  The term grows every time the
+	// leadership of the task moves to another member; it is zero while no
+	// member claims the task, e.g. when the task is in no agreement.
+	Leader(taskID string) (bool, uint64)
+}
+
+// SetLeaderElection sets the component electing the member of a cluster
+// firing each singleton task.  Without it singleton tasks fire on this node.
+func (s *scheduler) SetLeaderElection(e electsLeaders) {
+	s.leaderElection = e
+	for _, t := range s.tasks.Table() {
+		t.setLeaderElection(e)
+	}
+	schedulerLogger.WithFields(log.Fields{
+		"_block": "set-leader-election",
+	}).Debug("leader election linked")
+}
+
+func (t *task) setLeaderElection(e electsLeaders) {
+	t.leaderMutex.Lock()
+	defer t.leaderMutex.Unlock()
+	t.leaderElection = e
+}
+
+// leads returns true when this node may fire the task.  Tasks which are not
+// singletons always fire.  A node fires a singleton task only while it is
+// elected and its term is not older than a term it saw before; this fences a
+// stale leadership from firing the task again.  The terms seen are forgotten
+// while no member claims the task.
+func (t *task) leads() bool {
+	if !t.singleton {
+		return true
+	}
+	t.leaderMutex.Lock()
+	defer t.leaderMutex.Unlock()
+	if t.leaderElection == nil {
+		return true
+	}
+	leader, term := t.leaderElection.Leader(t.id)
+	fenced := false
+	switch {
+	case term == 0:
+		// no claim to fence, e.g. the task left its agreement
+		t.leaderTerm = 0
+	case term < t.leaderTerm:
+		fenced = true
+	default:
+		t.leaderTerm = term
+	}
+	leads := leader && !fenced
+	if leads != t.leader {
+		t.leader = leads
+		taskLogger.WithFields(log.Fields{
+			"_block":    "leads",
+			"task-id":   t.id,
+			"task-name": t.name,
+			"leader":    leads,
+			"term":      term,
+		}).Info("task leadership changed")
+		event := &scheduler_event.TaskLeadershipChangedEvent{
+			TaskID: t.id,
+			Leader: leads,
+			Term:   term,
+		}
+		defer t.eventEmitter.Emit(event)
+	}
+	return leads
+}
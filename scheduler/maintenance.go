@@ -0,0 +1,170 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+)
+
+// maxMaintenanceHistory bounds the number of past maintenance windows kept
+const maxMaintenanceHistory = 100
+
+var (
+	// ErrMaintenanceInProgress - The error message for a maintenance started while another one is in progress
+	ErrMaintenanceInProgress = errors.New("Maintenance already in progress")
+	// ErrNoMaintenance - The error message for a maintenance ended while none is in progress
+	ErrNoMaintenance = errors.New("No maintenance in progress")
+	// ErrNoMaintenanceTasks - The error message for a maintenance selecting the tasks by labels no task holds
+	ErrNoMaintenanceTasks = errors.New("No task holds the labels of the maintenance")
+)
+
+// maintenance holds the maintenance window the scheduler is in, if any, and
+// the past ones.  It is shared by the tasks of the scheduler, which check
+// whether they are paused before every fire.
+type maintenance struct {
+	sync.RWMutex
+	current *core.MaintenanceWindow
+	// tasks are the IDs of the paused tasks; nil when all tasks are paused
+	tasks   map[string]bool
+	history []core.MaintenanceWindow
+}
+
+func newMaintenance() *maintenance {
+	return &maintenance{}
+}
+
+// covers returns true when the given task is paused by the current
+// maintenance window
+func (m *maintenance) covers(id string) bool {
+	if m == nil {
+		return false
+	}
+	m.RLock()
+	defer m.RUnlock()
+	if m.current == nil {
+		return false
+	}
+	return m.tasks == nil || m.tasks[id]
+}
+
+// StartMaintenance enters maintenance mode: the given tasks, or the tasks
+// holding all the labels of the selector and visible through it when it has
+// labels, or all the tasks otherwise, are paused until EndMaintenance is
+// called.  All the tasks are paused at once, and the fires they skip are
+// neither missed nor failed.  The tasks selected by their labels are the ones
+// holding them when the maintenance starts.
+func (s *scheduler) StartMaintenance(reason string, taskIDs []string, selector core.TaskListOptions) (*core.MaintenanceWindow, error) {
+	for _, id := range taskIDs {
+		if _, err := s.getTask(id); err != nil {
+			return nil, err
+		}
+	}
+	var labels map[string]string
+	if len(taskIDs) == 0 && len(selector.Labels) > 0 {
+		selected, _ := s.tasks.list(core.TaskListOptions{Labels: selector.Labels, Visible: selector.Visible})
+		if len(selected) == 0 {
+			return nil, ErrNoMaintenanceTasks
+		}
+		for _, t := range selected {
+			taskIDs = append(taskIDs, t.ID())
+		}
+		labels = selector.Labels
+	}
+	m := s.maintenance
+	m.Lock()
+	if m.current != nil {
+		m.Unlock()
+		return nil, ErrMaintenanceInProgress
+	}
+	w := core.MaintenanceWindow{
+		Reason:  reason,
+		TaskIDs: append([]string{}, taskIDs...),
+		Labels:  labels,
+		Start:   time.Now().Unix(),
+	}
+	var tasks map[string]bool
+	if len(taskIDs) > 0 {
+		tasks = make(map[string]bool, len(taskIDs))
+		for _, id := range taskIDs {
+			tasks[id] = true
+		}
+	}
+	m.current = &w
+	m.tasks = tasks
+	m.Unlock()
+
+	schedulerLogger.WithFields(log.Fields{
+		"_block": "start-maintenance",
+		"reason": reason,
+		"tasks":  taskIDs,
+		"labels": labels,
+	}).Info("Maintenance started, tasks paused")
+	s.eventManager.Emit(&scheduler_event.MaintenanceStartedEvent{Window: w})
+	return &w, nil
+}
+
+// EndMaintenance leaves maintenance mode and resumes the paused tasks.  It
+// returns the maintenance window which ended.
+func (s *scheduler) EndMaintenance() (*core.MaintenanceWindow, error) {
+	m := s.maintenance
+	m.Lock()
+	if m.current == nil {
+		m.Unlock()
+		return nil, ErrNoMaintenance
+	}
+	w := *m.current
+	w.End = time.Now().Unix()
+	m.current = nil
+	m.tasks = nil
+	m.history = append(m.history, w)
+	if len(m.history) > maxMaintenanceHistory {
+		m.history = m.history[len(m.history)-maxMaintenanceHistory:]
+	}
+	m.Unlock()
+
+	schedulerLogger.WithFields(log.Fields{
+		"_block": "end-maintenance",
+		"reason": w.Reason,
+		"tasks":  w.TaskIDs,
+	}).Info("Maintenance ended, tasks resumed")
+	s.eventManager.Emit(&scheduler_event.MaintenanceEndedEvent{Window: w})
+	return &w, nil
+}
+
+// Maintenance returns the current maintenance window, nil when the scheduler
+// is not in maintenance mode, and the past ones, oldest first.
+func (s *scheduler) Maintenance() (*core.MaintenanceWindow, []core.MaintenanceWindow) {
+	m := s.maintenance
+	m.RLock()
+	defer m.RUnlock()
+	var current *core.MaintenanceWindow
+	if m.current != nil {
+		w := *m.current
+		current = &w
+	}
+	return current, append([]core.MaintenanceWindow{}, m.history...)
+}
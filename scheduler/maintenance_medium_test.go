@@ -0,0 +1,116 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+func TestMaintenance(t *testing.T) {
+	Convey("Given two running tasks", t, func() {
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(newMockMetricManager())
+		s.Start()
+		paused, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newMockWorkflowMap(), false)
+		So(errs.Errors(), ShouldBeEmpty)
+		running, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newMockWorkflowMap(), false)
+		So(errs.Errors(), ShouldBeEmpty)
+		paused.(*task).Spin()
+		running.(*task).Spin()
+
+		Convey("a maintenance window pauses the given tasks until it ends", func() {
+			w, err := s.StartMaintenance("patching", []string{paused.ID()}, core.TaskListOptions{})
+			So(err, ShouldBeNil)
+			So(w.TaskIDs, ShouldResemble, []string{paused.ID()})
+			time.Sleep(5 * interval)
+			pausedHits, runningHits := paused.HitCount(), running.HitCount()
+			time.Sleep(10 * interval)
+			So(paused.HitCount(), ShouldEqual, pausedHits)
+			So(running.HitCount(), ShouldBeGreaterThan, runningHits)
+
+			_, err = s.StartMaintenance("again", nil, core.TaskListOptions{})
+			So(err, ShouldEqual, ErrMaintenanceInProgress)
+			current, _ := s.Maintenance()
+			So(current.Reason, ShouldEqual, "patching")
+
+			w, err = s.EndMaintenance()
+			So(err, ShouldBeNil)
+			So(w.End, ShouldBeGreaterThanOrEqualTo, w.Start)
+			time.Sleep(10 * interval)
+			So(paused.HitCount(), ShouldBeGreaterThan, pausedHits)
+			So(paused.MissedCount(), ShouldBeLessThan, 5)
+			current, history := s.Maintenance()
+			So(current, ShouldBeNil)
+			So(len(history), ShouldEqual, 1)
+		})
+		Convey("a maintenance window without tasks pauses all of them", func() {
+			_, err := s.StartMaintenance("", nil, core.TaskListOptions{})
+			So(err, ShouldBeNil)
+			So(s.maintenance.covers(paused.ID()), ShouldBeTrue)
+			So(s.maintenance.covers(running.ID()), ShouldBeTrue)
+			So(s.maintenance.covers("new-task"), ShouldBeTrue)
+		})
+		Convey("a maintenance window pauses the tasks holding its labels", func() {
+			prod, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newMockWorkflowMap(), false,
+				core.OptionLabels(map[string]string{"env": "prod", "team": "ops"}))
+			So(errs.Errors(), ShouldBeEmpty)
+			_, errs = s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newMockWorkflowMap(), false,
+				core.OptionLabels(map[string]string{"env": "dev"}))
+			So(errs.Errors(), ShouldBeEmpty)
+			w, err := s.StartMaintenance("", nil, core.TaskListOptions{Labels: map[string]string{"env": "prod"}})
+			So(err, ShouldBeNil)
+			So(w.TaskIDs, ShouldResemble, []string{prod.ID()})
+			So(w.Labels, ShouldResemble, map[string]string{"env": "prod"})
+			So(s.maintenance.covers(prod.ID()), ShouldBeTrue)
+			So(s.maintenance.covers(running.ID()), ShouldBeFalse)
+			So(s.maintenance.covers("new-task"), ShouldBeFalse)
+		})
+		Convey("a maintenance window of labels no visible task holds is rejected", func() {
+			_, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newMockWorkflowMap(), false,
+				core.OptionLabels(map[string]string{"env": "prod"}))
+			So(errs.Errors(), ShouldBeEmpty)
+			_, err := s.StartMaintenance("", nil, core.TaskListOptions{Labels: map[string]string{"env": "staging"}})
+			So(err, ShouldEqual, ErrNoMaintenanceTasks)
+			hidden := func(core.Task) bool { return false }
+			_, err = s.StartMaintenance("", nil, core.TaskListOptions{Labels: map[string]string{"env": "prod"}, Visible: hidden})
+			So(err, ShouldEqual, ErrNoMaintenanceTasks)
+			current, _ := s.Maintenance()
+			So(current, ShouldBeNil)
+		})
+		Convey("a maintenance window of an unknown task is rejected", func() {
+			_, err := s.StartMaintenance("", []string{"unknown"}, core.TaskListOptions{})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("a maintenance cannot end when none is in progress", func() {
+			_, err := s.EndMaintenance()
+			So(err, ShouldEqual, ErrNoMaintenance)
+		})
+
+		s.Stop()
+	})
+}
@@ -0,0 +1,59 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sort"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// orderedMetrics sorts metrics by namespace then timestamp
+type orderedMetrics []core.Metric
+
+func (m orderedMetrics) Len() int {
+	return len(m)
+}
+
+func (m orderedMetrics) Less(i, j int) bool {
+	a, b := m[i].Namespace(), m[j].Namespace()
+	for k := 0; k < len(a) && k < len(b); k++ {
+		if a[k].Value != b[k].Value {
+			return a[k].Value < b[k].Value
+		}
+	}
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return m[i].Timestamp().Before(m[j].Timestamp())
+}
+
+func (m orderedMetrics) Swap(i, j int) {
+	m[i], m[j] = m[j], m[i]
+}
+
+// sortMetrics returns a copy of the metrics sorted by namespace then
+// timestamp.  Metrics which compare equal keep their order.
+func sortMetrics(mts []core.Metric) []core.Metric {
+	sorted := make(orderedMetrics, len(mts))
+	copy(sorted, mts)
+	sort.Stable(sorted)
+	return sorted
+}
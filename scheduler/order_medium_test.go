@@ -0,0 +1,63 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+func TestSortMetrics(t *testing.T) {
+	Convey("Given metrics in no particular order", t, func() {
+		now := time.Now()
+		mt := func(ts time.Time, data int, ns ...string) core.Metric {
+			return plugin.MetricType{Namespace_: core.NewNamespace(ns...), Timestamp_: ts, Data_: data}
+		}
+		mts := []core.Metric{
+			mt(now, 0, "intel", "mock", "foo"),
+			mt(now.Add(-time.Second), 1, "intel", "mock", "bar"),
+			mt(now, 2, "intel", "mock"),
+			mt(now.Add(-time.Second), 3, "intel", "mock", "foo"),
+			mt(now, 4, "intel", "mock", "bar"),
+			mt(now, 5, "intel", "mock", "foo"),
+		}
+
+		Convey("they are sorted by namespace then timestamp", func() {
+			sorted := sortMetrics(mts)
+			data := make([]interface{}, len(sorted))
+			for i, m := range sorted {
+				data[i] = m.Data()
+			}
+			So(data, ShouldResemble, []interface{}{2, 1, 4, 3, 0, 5})
+		})
+		Convey("the given metrics are left untouched", func() {
+			sortMetrics(mts)
+			So(mts[0].Data(), ShouldEqual, 0)
+			So(mts[5].Data(), ShouldEqual, 5)
+		})
+	})
+}
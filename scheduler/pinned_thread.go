@@ -0,0 +1,127 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/pkg/chrono"
+)
+
+const (
+	// maxPinnedCPU bounds the CPUs a pinned thread is bound to, as the CPU set
+	// of sched_setaffinity does
+	maxPinnedCPU = 1024
+)
+
+var (
+	// ErrInvalidPinnedCPU - The error message for a pinned thread bound to a CPU out of range
+	ErrInvalidPinnedCPU = errors.New("Pinned thread CPU must be between 0 and 1023")
+	// ErrCPUAffinityUnsupported - The error message for CPUs given to a pinned thread on another OS than Linux
+	ErrCPUAffinityUnsupported = errors.New("CPU affinity of a pinned thread is only supported on Linux")
+)
+
+// pinnedThread runs the collect jobs of a task on a goroutine locked to its
+// OS thread, bound to the given CPUs if any, so that the collection does not
+// share a thread with the rest of the daemon.  Jobs run one at a time.
+type pinnedThread struct {
+	taskID string
+	cpus   []int
+	rcv    chan queuedJob
+	quit   chan struct{}
+}
+
+// newPinnedThread starts the thread of a task and returns once it is locked
+// and bound to its CPUs
+func newPinnedThread(taskID string, cpus []int) (*pinnedThread, error) {
+	for _, cpu := range cpus {
+		if cpu < 0 || cpu >= maxPinnedCPU {
+			return nil, fmt.Errorf("%v (got %d)", ErrInvalidPinnedCPU, cpu)
+		}
+	}
+	p := &pinnedThread{
+		taskID: taskID,
+		cpus:   cpus,
+		rcv:    make(chan queuedJob),
+		quit:   make(chan struct{}),
+	}
+	started := make(chan error)
+	go p.start(started)
+	if err := <-started; err != nil {
+		return nil, err
+	}
+	schedulerLogger.WithFields(log.Fields{
+		"_block":  "pinned-thread",
+		"task-id": taskID,
+		"cpus":    cpus,
+	}).Info("Started pinned thread for the collect jobs of the task")
+	return p, nil
+}
+
+func (p *pinnedThread) start(started chan<- error) {
+	// the thread is never unlocked: when the goroutine exits the thread,
+	// whose affinity was changed, exits with it
+	runtime.LockOSThread()
+	if len(p.cpus) > 0 {
+		if err := setThreadAffinity(p.cpus); err != nil {
+			started <- err
+			return
+		}
+	}
+	close(started)
+	for {
+		select {
+		case q := <-p.rcv:
+			// like a worker, refuse a job whose deadline is exceeded or
+			// whose run is cancelled
+			if !chrono.Chrono.Now().Before(q.Job().Deadline()) {
+				q.Job().AddErrors(errors.New("Pinned thread refused to run overdue job."))
+			} else if q.Job().Context().Err() != nil {
+				q.Job().AddErrors(ErrJobCancelled)
+			} else {
+				q.Job().Run()
+			}
+			q.Promise().Complete(q.Job().Errors())
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// Work runs the job on the pinned thread
+func (p *pinnedThread) Work(j job) queuedJob {
+	q := newQueuedJob(j)
+	select {
+	case p.rcv <- q:
+	case <-p.quit:
+		j.AddErrors(errors.New("Pinned thread is stopped."))
+		q.Promise().Complete(j.Errors())
+	}
+	return q
+}
+
+// stop stops the thread once the job it runs, if any, completes
+func (p *pinnedThread) stop() {
+	close(p.quit)
+}
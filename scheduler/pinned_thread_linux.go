@@ -0,0 +1,40 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// setThreadAffinity binds the calling thread to the given CPUs
+func setThreadAffinity(cpus []int) error {
+	var set [maxPinnedCPU / 64]uint64
+	for _, cpu := range cpus {
+		set[cpu/64] |= 1 << uint(cpu%64)
+	}
+	// a pid of 0 is the calling thread
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, uintptr(len(set)*8), uintptr(unsafe.Pointer(&set[0])))
+	if errno != 0 {
+		return fmt.Errorf("Unable to set the CPU affinity of the pinned thread to %v: %v", cpus, errno)
+	}
+	return nil
+}
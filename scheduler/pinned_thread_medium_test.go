@@ -0,0 +1,81 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+func TestPinnedThread(t *testing.T) {
+	Convey("Given a scheduler", t, func() {
+		s := New(GetDefaultConfig())
+		mm := &publishRecorder{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		create := func(opts ...core.TaskOption) (*task, core.TaskErrors) {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newBufferedWorkflowMap(nil), false, opts...)
+			if tsk == nil {
+				return nil, errs
+			}
+			tk := tsk.(*task)
+			tk.killChan = make(chan struct{})
+			return tk, errs
+		}
+
+		Convey("a task with a pinned thread collects on it", func() {
+			tk, errs := create(core.OptionPinnedThread(&core.TaskPinnedThread{}))
+			So(errs.Errors(), ShouldBeEmpty)
+			So(tk.pinnedThread, ShouldNotBeNil)
+			So(tk.GetPinnedThread(), ShouldNotBeNil)
+
+			Convey("and its jobs are worked by the pinned thread", func() {
+				So(tk.fire().Success(), ShouldBeTrue)
+				So(mm.published(), ShouldResemble, []int{2})
+			})
+
+			Convey("the thread is stopped once the task is removed", func() {
+				pt := tk.pinnedThread
+				So(s.RemoveTask(tk.ID()), ShouldBeNil)
+				j := newCollectorJob(nil, time.Second, mm, nil, tk.ID(), nil)
+				So(pt.Work(j).Promise().Await(), ShouldNotBeEmpty)
+			})
+		})
+		Convey("a task cannot be pinned to an invalid CPU", func() {
+			tk, errs := create(core.OptionPinnedThread(&core.TaskPinnedThread{CPUs: []int{-1}}))
+			So(tk, ShouldBeNil)
+			So(errs.Errors()[0].Error(), ShouldStartWith, ErrInvalidPinnedCPU.Error())
+		})
+		Convey("a task without a pinned thread collects on the workers", func() {
+			tk, errs := create()
+			So(errs.Errors(), ShouldBeEmpty)
+			So(tk.pinnedThread, ShouldBeNil)
+		})
+
+		s.Stop()
+	})
+}
@@ -0,0 +1,139 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// placementSource is the source of the task events caused by moving tasks
+// between the members of a cluster
+const placementSource = "placement"
+
+// placesTasks decides which member of a cluster runs a task
+type placesTasks interface {
+	// Owns returns true when the task has to run on this node. Tasks which
+	// are not shared with a cluster are always owned.
+	Owns(taskID string) bool
+}
+
+// standbyTasks holds the ids of the tasks which were started but are run by
+// another member of the cluster
+type standbyTasks struct {
+	sync.Mutex
+	ids map[string]struct{}
+}
+
+func newStandbyTasks() *standbyTasks {
+	return &standbyTasks{
+		ids: map[string]struct{}{},
+	}
+}
+
+func (s *standbyTasks) add(id string) {
+	s.Lock()
+	defer s.Unlock()
+	s.ids[id] = struct{}{}
+}
+
+// remove returns true if the task was in standby
+func (s *standbyTasks) remove(id string) bool {
+	s.Lock()
+	defer s.Unlock()
+	_, ok := s.ids[id]
+	delete(s.ids, id)
+	return ok
+}
+
+func (s *standbyTasks) contains(id string) bool {
+	s.Lock()
+	defer s.Unlock()
+	_, ok := s.ids[id]
+	return ok
+}
+
+// SetTaskPlacement sets the component deciding which member of a cluster
+// runs a task. Without it every task runs on this node.
+func (s *scheduler) SetTaskPlacement(p placesTasks) {
+	s.placement = p
+	schedulerLogger.WithFields(log.Fields{
+		"_block": "set-task-placement",
+	}).Debug("task placement linked")
+}
+
+// owns returns true if the task has to run on this node
+func (s *scheduler) owns(id string) bool {
+	if s.placement == nil {
+		return true
+	}
+	return s.placement.Owns(id)
+}
+
+// StandbyTasks returns the ids of the started tasks which are run by
+// another member of the cluster.
+func (s *scheduler) StandbyTasks() []string {
+	s.standby.Lock()
+	defer s.standby.Unlock()
+	ids := make([]string, 0, len(s.standby.ids))
+	for id := range s.standby.ids {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Rebalance starts the tasks in standby which are now owned by this node and
+// moves the running tasks owned by another member of the cluster to standby.
+// It is called whenever the membership of the cluster changes.
+func (s *scheduler) Rebalance() {
+	logger := schedulerLogger.WithFields(log.Fields{
+		"_block": "rebalance",
+	})
+	for id, t := range s.tasks.Table() {
+		if t.singleton {
+			continue
+		}
+		owned := s.owns(id)
+		switch {
+		case owned && s.standby.contains(id):
+			logger.WithFields(log.Fields{
+				"task-id": id,
+			}).Info("task moved to this node")
+			s.standby.remove(id)
+			if errs := s.startTask(id, placementSource); len(errs) > 0 {
+				f := buildErrorsLog(errs, logger)
+				f.Error("error starting task moved to this node")
+			}
+		case !owned && (t.State() == core.TaskSpinning || t.State() == core.TaskFiring || t.State() == core.TaskSuspended):
+			logger.WithFields(log.Fields{
+				"task-id": id,
+			}).Info("task moved to another node")
+			if errs := s.stopTask(id, placementSource); len(errs) > 0 {
+				f := buildErrorsLog(errs, logger)
+				f.Error("error stopping task moved to another node")
+				continue
+			}
+			s.standby.add(id)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	// defaultMaxBufferedSeconds is the number of seconds worth of metrics
+	// buffered per publish node by default under the buffer policy
+	defaultMaxBufferedSeconds = 10
+)
+
+var (
+	// ErrInvalidPublishRate - The error message for a publish rate limit which is not a positive number of metrics
+	ErrInvalidPublishRate = errors.New("Invalid publish rate limit (expected a number of metrics per second above 0)")
+)
+
+// publishLimiter caps the metrics published by a task with a token bucket
+// refilled at the rate of the limit and holding up to one second worth of
+// metrics, shared by the publish nodes of the task.
+type publishLimiter struct {
+	sync.Mutex
+	rate        float64
+	burst       float64
+	buffer      bool
+	maxBuffered int
+	tokens      float64
+	last        time.Time
+	// buffered holds the metrics waiting to be published, by publish node
+	buffered map[*publishNode][]core.Metric
+}
+
+func newPublishLimiter(l *core.TaskPublishRateLimit) (*publishLimiter, error) {
+	if l == nil {
+		return nil, nil
+	}
+	if l.MetricsPerSecond <= 0 || math.IsInf(l.MetricsPerSecond, 0) || math.IsNaN(l.MetricsPerSecond) {
+		return nil, ErrInvalidPublishRate
+	}
+	p := &publishLimiter{
+		rate:     l.MetricsPerSecond,
+		burst:    math.Max(l.MetricsPerSecond, 1),
+		buffered: map[*publishNode][]core.Metric{},
+	}
+	switch l.Policy {
+	case "", core.PublishRateLimitDrop:
+	case core.PublishRateLimitBuffer:
+		p.buffer = true
+		p.maxBuffered = l.MaxBuffered
+		if p.maxBuffered == 0 {
+			p.maxBuffered = int(math.Ceil(l.MetricsPerSecond * defaultMaxBufferedSeconds))
+		}
+		if p.maxBuffered < 0 {
+			return nil, fmt.Errorf("Invalid maximum of buffered metrics %d for the publish rate limit (expected 0 or more)", l.MaxBuffered)
+		}
+	default:
+		return nil, fmt.Errorf("Unknown publish rate limit policy '%s' (expected drop or buffer)", l.Policy)
+	}
+	p.tokens = p.burst
+	return p, nil
+}
+
+// take returns the metrics the given publish node may publish now, the
+// metrics buffered for the node first, and the number of metrics dropped.
+// The metrics beyond the rate are dropped, or buffered with the buffer
+// policy.
+func (p *publishLimiter) take(pu *publishNode, mts []core.Metric, now time.Time) ([]core.Metric, int) {
+	p.Lock()
+	defer p.Unlock()
+	if !p.last.IsZero() {
+		p.tokens = math.Min(p.burst, p.tokens+now.Sub(p.last).Seconds()*p.rate)
+	}
+	p.last = now
+	if p.buffer {
+		mts = append(p.buffered[pu], mts...)
+	}
+	n := int(math.Min(p.tokens, float64(len(mts))))
+	p.tokens -= float64(n)
+	out, rest := mts[:n], mts[n:]
+	if !p.buffer {
+		return out, len(rest)
+	}
+	dropped := 0
+	if len(rest) > p.maxBuffered {
+		dropped = len(rest) - p.maxBuffered
+		rest = rest[dropped:]
+	}
+	if len(rest) == 0 {
+		delete(p.buffered, pu)
+	} else {
+		p.buffered[pu] = append([]core.Metric{}, rest...)
+	}
+	return out, dropped
+}
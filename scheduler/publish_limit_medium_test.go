@@ -0,0 +1,124 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func TestPublishLimiter(t *testing.T) {
+	metrics := func(n int) []core.Metric {
+		mts := make([]core.Metric, n)
+		for i := range mts {
+			mts[i] = plugin.MetricType{Namespace_: core.NewNamespace("foo", "bar"), Data_: i}
+		}
+		return mts
+	}
+	now := time.Now()
+	pu := &publishNode{}
+
+	Convey("Given a publish rate limit dropping metrics", t, func() {
+		l, err := newPublishLimiter(&core.TaskPublishRateLimit{MetricsPerSecond: 10})
+		So(err, ShouldBeNil)
+
+		Convey("the metrics beyond the rate are dropped", func() {
+			out, dropped := l.take(pu, metrics(15), now)
+			So(len(out), ShouldEqual, 10)
+			So(dropped, ShouldEqual, 5)
+			out, dropped = l.take(pu, metrics(15), now.Add(500*time.Millisecond))
+			So(len(out), ShouldEqual, 5)
+			So(dropped, ShouldEqual, 10)
+		})
+	})
+
+	Convey("Given a publish rate limit buffering metrics", t, func() {
+		l, err := newPublishLimiter(&core.TaskPublishRateLimit{
+			MetricsPerSecond: 10,
+			Policy:           core.PublishRateLimitBuffer,
+			MaxBuffered:      8,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("the metrics beyond the rate are published later, oldest first", func() {
+			out, dropped := l.take(pu, metrics(15), now)
+			So(len(out), ShouldEqual, 10)
+			So(dropped, ShouldEqual, 0)
+			out, dropped = l.take(pu, metrics(5), now.Add(time.Second))
+			So(len(out), ShouldEqual, 10)
+			So(out[0].Data(), ShouldEqual, 10)
+			So(dropped, ShouldEqual, 0)
+		})
+		Convey("the oldest metrics are dropped once the buffer is full", func() {
+			out, dropped := l.take(pu, metrics(20), now)
+			So(len(out), ShouldEqual, 10)
+			So(dropped, ShouldEqual, 2)
+			out, _ = l.take(pu, nil, now.Add(time.Second))
+			So(len(out), ShouldEqual, 8)
+			So(out[0].Data(), ShouldEqual, 12)
+		})
+	})
+
+	Convey("Invalid publish rate limits are rejected", t, func() {
+		_, err := newPublishLimiter(&core.TaskPublishRateLimit{MetricsPerSecond: 0})
+		So(err, ShouldEqual, ErrInvalidPublishRate)
+		_, err = newPublishLimiter(&core.TaskPublishRateLimit{MetricsPerSecond: 1, Policy: "queue"})
+		So(err, ShouldNotBeNil)
+		_, err = newPublishLimiter(&core.TaskPublishRateLimit{MetricsPerSecond: 1, Policy: core.PublishRateLimitBuffer, MaxBuffered: -1})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Given a task with a publish rate limit", t, func() {
+		s := New(GetDefaultConfig())
+		mm := &publishRecorder{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		w.Collect.Add(wmap.NewPublishNode("file", -1))
+
+		Convey("the metrics beyond the rate are not published", func() {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false,
+				core.OptionPublishRateLimit(&core.TaskPublishRateLimit{MetricsPerSecond: 1}))
+			So(errs.Errors(), ShouldBeEmpty)
+			tk := tsk.(*task)
+			So(tk.GetPublishRateLimit().MetricsPerSecond, ShouldEqual, 1)
+			tk.killChan = make(chan struct{})
+			tk.fire()
+			So(mm.published(), ShouldResemble, []int{1})
+		})
+		Convey("an invalid rate limit fails the creation of the task", func() {
+			_, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false,
+				core.OptionPublishRateLimit(&core.TaskPublishRateLimit{MetricsPerSecond: -1}))
+			So(errs.Errors(), ShouldNotBeEmpty)
+		})
+
+		s.Stop()
+	})
+}
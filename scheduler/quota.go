@@ -0,0 +1,226 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// The quotas of the scheduler
+const (
+	quotaMaxTasks                  = "max_tasks"
+	quotaMaxTasksPerPlugin         = "max_tasks_per_plugin"
+	quotaMaxSubscriptionsPerMetric = "max_subscriptions_per_metric"
+)
+
+// QuotaExceededError is the error of a task whose creation would exceed a
+// quota of the scheduler.  It is returned as is among the errors of the
+// creation of the task.
+type QuotaExceededError struct {
+	// Quota is the name of the exceeded quota in the scheduler
+	// configuration, e.g. max_tasks_per_plugin
+	Quota string
+	// Key is the plugin (type:name) or the metric namespace the quota is
+	// exceeded for; empty for max_tasks
+	Key string
+	// Limit is the value of the quota
+	Limit  int
+	fields map[string]interface{}
+}
+
+func (e *QuotaExceededError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("Scheduler quota exceeded: %s is %d", e.Quota, e.Limit)
+	}
+	return fmt.Sprintf("Scheduler quota exceeded: %s is %d for %s", e.Quota, e.Limit, e.Key)
+}
+
+// Fields returns the quota, its key and its limit
+func (e *QuotaExceededError) Fields() map[string]interface{} {
+	if e.fields == nil {
+		e.fields = map[string]interface{}{"quota": e.Quota, "limit": e.Limit}
+		if e.Key != "" {
+			e.fields["key"] = e.Key
+		}
+	}
+	return e.fields
+}
+
+func (e *QuotaExceededError) SetFields(f map[string]interface{}) {
+	e.fields = f
+}
+
+// quotaUsage is what a task counts against the quotas
+type quotaUsage struct {
+	plugins []string
+	metrics []string
+}
+
+// quotas counts the tasks, the tasks using every plugin and the tasks
+// subscribed to every metric, and enforces the quotas of the scheduler on
+// the creation of the tasks.  A zero quota does not limit anything.
+type quotas struct {
+	maxTasks                  int
+	maxTasksPerPlugin         int
+	maxSubscriptionsPerMetric int
+
+	sync.Mutex
+	plugins map[string]int
+	metrics map[string]int
+	tasks   map[string]quotaUsage
+}
+
+func newQuotas(cfg *Config) (*quotas, error) {
+	q := &quotas{
+		maxTasks:                  cfg.MaxTasks,
+		maxTasksPerPlugin:         cfg.MaxTasksPerPlugin,
+		maxSubscriptionsPerMetric: cfg.MaxSubscriptionsPerMetric,
+		plugins:                   map[string]int{},
+		metrics:                   map[string]int{},
+		tasks:                     map[string]quotaUsage{},
+	}
+	for _, v := range []struct {
+		name  string
+		value int
+	}{
+		{quotaMaxTasks, q.maxTasks},
+		{quotaMaxTasksPerPlugin, q.maxTasksPerPlugin},
+		{quotaMaxSubscriptionsPerMetric, q.maxSubscriptionsPerMetric},
+	} {
+		if v.value < 0 {
+			return nil, fmt.Errorf("Invalid %s %d (expected 0 or more)", v.name, v.value)
+		}
+	}
+	return q, nil
+}
+
+// reserve counts the task using the given plugins and subscribed to the
+// given metrics, unless it exceeds a quota or is already counted
+func (q *quotas) reserve(id string, plugins, metrics []string) error {
+	q.Lock()
+	defer q.Unlock()
+	if _, ok := q.tasks[id]; ok {
+		return ErrTaskHasAlreadyBeenAdded
+	}
+	if q.maxTasks > 0 && len(q.tasks) >= q.maxTasks {
+		return &QuotaExceededError{Quota: quotaMaxTasks, Limit: q.maxTasks}
+	}
+	if q.maxTasksPerPlugin > 0 {
+		for _, p := range plugins {
+			if q.plugins[p] >= q.maxTasksPerPlugin {
+				return &QuotaExceededError{Quota: quotaMaxTasksPerPlugin, Key: p, Limit: q.maxTasksPerPlugin}
+			}
+		}
+	}
+	if q.maxSubscriptionsPerMetric > 0 {
+		for _, m := range metrics {
+			if q.metrics[m] >= q.maxSubscriptionsPerMetric {
+				return &QuotaExceededError{Quota: quotaMaxSubscriptionsPerMetric, Key: m, Limit: q.maxSubscriptionsPerMetric}
+			}
+		}
+	}
+	for _, p := range plugins {
+		q.plugins[p]++
+	}
+	for _, m := range metrics {
+		q.metrics[m]++
+	}
+	q.tasks[id] = quotaUsage{plugins: plugins, metrics: metrics}
+	return nil
+}
+
+// release uncounts a task removed or not created
+func (q *quotas) release(id string) {
+	q.Lock()
+	defer q.Unlock()
+	u, ok := q.tasks[id]
+	if !ok {
+		return
+	}
+	delete(q.tasks, id)
+	for _, p := range u.plugins {
+		if q.plugins[p]--; q.plugins[p] <= 0 {
+			delete(q.plugins, p)
+		}
+	}
+	for _, m := range u.metrics {
+		if q.metrics[m]--; q.metrics[m] <= 0 {
+			delete(q.metrics, m)
+		}
+	}
+}
+
+// pluginsResolver is optionally implemented by a metric manager which can
+// tell the collector plugins exposing a metric
+type pluginsResolver interface {
+	GetPlugins(core.Namespace) ([]core.CatalogedPlugin, error)
+}
+
+// taskPlugins returns the plugins used by a task as type:name, each once.
+// The collectors are only known when the metric manager can resolve them
+// from the metrics of the task.
+func taskPlugins(mgr managesMetrics, depGroups depGroupMap) []string {
+	plugins := []string{}
+	seen := map[string]bool{}
+	add := func(typ, name string) {
+		key := typ + ":" + name
+		if !seen[key] {
+			seen[key] = true
+			plugins = append(plugins, key)
+		}
+	}
+	pr, resolves := mgr.(pluginsResolver)
+	for _, group := range depGroups {
+		for _, p := range group.subscribedPlugins {
+			add(p.TypeName(), p.Name())
+		}
+		if !resolves {
+			continue
+		}
+		for _, rm := range group.requestedMetrics {
+			cps, err := pr.GetPlugins(rm.Namespace())
+			if err != nil {
+				continue
+			}
+			for _, cp := range cps {
+				add(cp.TypeName(), cp.Name())
+			}
+		}
+	}
+	return plugins
+}
+
+// taskMetrics returns the namespaces of the metrics a task subscribes to,
+// each once
+func taskMetrics(mts []core.RequestedMetric) []string {
+	metrics := []string{}
+	seen := map[string]bool{}
+	for _, m := range mts {
+		ns := m.Namespace().String()
+		if !seen[ns] {
+			seen[ns] = true
+			metrics = append(metrics, ns)
+		}
+	}
+	return metrics
+}
@@ -0,0 +1,114 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// collectorsMetricManager resolves the metrics under /foo to the mock
+// collector
+type collectorsMetricManager struct {
+	*mockMetricManager
+}
+
+func (m *collectorsMetricManager) GetPlugins(ns core.Namespace) ([]core.CatalogedPlugin, error) {
+	return []core.CatalogedPlugin{catalogedPlugin{"collector", "mock", 1}}, nil
+}
+
+func TestQuotas(t *testing.T) {
+	Convey("Invalid quotas are rejected", t, func() {
+		_, err := newQuotas(&Config{MaxTasksPerPlugin: -1})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Given a scheduler with quotas", t, func() {
+		cfg := GetDefaultConfig()
+		cfg.MaxTasks = 3
+		cfg.MaxTasksPerPlugin = 2
+		cfg.MaxSubscriptionsPerMetric = 1
+		s := New(cfg)
+		s.SetMetricManager(&collectorsMetricManager{mockMetricManager: newMockMetricManager()})
+		s.Start()
+		workflow := func(metric, publisher string) *wmap.WorkflowMap {
+			w := wmap.NewWorkflowMap()
+			w.Collect.AddMetric(metric, 1)
+			w.Collect.Add(wmap.NewPublishNode(publisher, -1))
+			return w
+		}
+		create := func(w *wmap.WorkflowMap) (core.Task, core.TaskErrors) {
+			return s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+		}
+		quotaError := func(errs core.TaskErrors) *QuotaExceededError {
+			So(errs.Errors(), ShouldHaveLength, 1)
+			qe, ok := errs.Errors()[0].(*QuotaExceededError)
+			So(ok, ShouldBeTrue)
+			return qe
+		}
+
+		Convey("a metric is collected by as many tasks as its quota allows", func() {
+			t1, errs := create(workflow("/foo/bar", "file"))
+			So(errs.Errors(), ShouldBeEmpty)
+			_, errs = create(workflow("/foo/bar", "kafka"))
+			qe := quotaError(errs)
+			So(qe.Quota, ShouldEqual, "max_subscriptions_per_metric")
+			So(qe.Key, ShouldEqual, "/foo/bar")
+			So(qe.Limit, ShouldEqual, 1)
+			So(qe.Fields()["key"], ShouldEqual, "/foo/bar")
+
+			Convey("and by one more once a task is removed", func() {
+				So(s.RemoveTask(t1.ID()), ShouldBeNil)
+				_, errs = create(workflow("/foo/bar", "kafka"))
+				So(errs.Errors(), ShouldBeEmpty)
+			})
+		})
+		Convey("a plugin is used by as many tasks as its quota allows", func() {
+			_, errs := create(workflow("/foo/bar", "file"))
+			So(errs.Errors(), ShouldBeEmpty)
+			_, errs = create(workflow("/foo/baz", "file"))
+			So(errs.Errors(), ShouldBeEmpty)
+			_, errs = create(workflow("/foo/qux", "kafka"))
+			qe := quotaError(errs)
+			So(qe.Quota, ShouldEqual, "max_tasks_per_plugin")
+			So(qe.Key, ShouldEqual, "collector:mock")
+		})
+		Convey("the scheduler holds as many tasks as its quota allows", func() {
+			s.SetMetricManager(newMockMetricManager())
+			for _, p := range []string{"file", "kafka", "influxdb"} {
+				_, errs := create(workflow("/foo/"+p, p))
+				So(errs.Errors(), ShouldBeEmpty)
+			}
+			_, errs := create(workflow("/foo/d", "file"))
+			qe := quotaError(errs)
+			So(qe.Quota, ShouldEqual, "max_tasks")
+			So(qe.Error(), ShouldEqual, "Scheduler quota exceeded: max_tasks is 3")
+		})
+
+		s.Stop()
+	})
+}
@@ -0,0 +1,92 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// ErrInvalidPoolSize - The error message for a configuration reloaded with an empty worker pool
+var ErrInvalidPoolSize = errors.New("Work manager pool size must be at least 1")
+
+// defaultTimeouts holds the default timeouts of the phases of the tasks,
+// shared by the scheduler and its tasks so that a reload of the
+// configuration applies to the running tasks from their next run
+type defaultTimeouts struct {
+	sync.RWMutex
+	timeouts core.TaskTimeouts
+}
+
+// get returns the default timeouts; none are set on a nil defaultTimeouts
+func (d *defaultTimeouts) get() core.TaskTimeouts {
+	if d == nil {
+		return core.TaskTimeouts{}
+	}
+	d.RLock()
+	defer d.RUnlock()
+	return d.timeouts
+}
+
+func (d *defaultTimeouts) set(to core.TaskTimeouts) {
+	d.Lock()
+	defer d.Unlock()
+	d.timeouts = to
+}
+
+func taskTimeouts(cfg *Config) core.TaskTimeouts {
+	return core.TaskTimeouts{
+		Collect: cfg.CollectTimeout.Duration,
+		Process: cfg.ProcessTimeout.Duration,
+		Publish: cfg.PublishTimeout.Duration,
+	}
+}
+
+// Reload applies the settings of the configuration which can change while
+// the scheduler runs: the size of the worker pools, the default timeouts of
+// the phases of the tasks and the default options of the tasks created from
+// then on.  The running tasks are not interrupted; the workers removed from
+// the pools finish the job they run.  The other settings, e.g. the size of
+// the queues, require a restart.
+func (s *scheduler) Reload(cfg *Config) error {
+	if cfg.WorkManagerPoolSize < 1 {
+		return ErrInvalidPoolSize
+	}
+	defaults, err := taskDefaults(cfg)
+	if err != nil {
+		return err
+	}
+	s.workManager.resize(cfg.WorkManagerPoolSize)
+	s.dedicatedQueues.setPoolSize(cfg.WorkManagerPoolSize)
+	s.timeouts.set(taskTimeouts(cfg))
+	s.taskDefaults.set(defaults)
+	schedulerLogger.WithFields(log.Fields{
+		"_block":          "Reload",
+		"pool-size":       cfg.WorkManagerPoolSize,
+		"collect-timeout": cfg.CollectTimeout.Duration,
+		"process-timeout": cfg.ProcessTimeout.Duration,
+		"publish-timeout": cfg.PublishTimeout.Duration,
+	}).Info("scheduler configuration reloaded")
+	return nil
+}
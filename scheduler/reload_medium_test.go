@@ -0,0 +1,75 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func TestSchedulerReload(t *testing.T) {
+	Convey("Given a running scheduler with a task", t, func() {
+		cfg := GetDefaultConfig()
+		s := New(cfg)
+		mm := &publishRecorder{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		w.Collect.Add(wmap.NewPublishNode("file", -1))
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false, core.TaskDeadlineDuration(time.Second))
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+
+		Convey("the pools are resized and the default timeouts of the task changed", func() {
+			reloaded := GetDefaultConfig()
+			reloaded.WorkManagerPoolSize = 2
+			reloaded.CollectTimeout.Duration = 3 * time.Second
+			So(s.Reload(reloaded), ShouldBeNil)
+			So(s.workManager.collectWkrs, ShouldHaveLength, 2)
+			So(s.workManager.processWkrs, ShouldHaveLength, 2)
+			So(s.workManager.publishWkrs, ShouldHaveLength, 2)
+			So(s.dedicatedQueues.poolSize, ShouldEqual, 2)
+			So(tk.collectTimeout(), ShouldEqual, 3*time.Second)
+			So(tk.publishTimeout(), ShouldEqual, time.Second)
+
+			Convey("and the task still runs on the resized pools", func() {
+				tk.killChan = make(chan struct{})
+				r := tk.fire()
+				So(r.Errors, ShouldBeEmpty)
+				So(mm.published(), ShouldNotBeEmpty)
+			})
+		})
+		Convey("an empty worker pool is refused", func() {
+			reloaded := GetDefaultConfig()
+			reloaded.WorkManagerPoolSize = 0
+			So(s.Reload(reloaded), ShouldEqual, ErrInvalidPoolSize)
+			So(s.workManager.collectWkrs, ShouldHaveLength, int(cfg.WorkManagerPoolSize))
+		})
+	})
+}
@@ -0,0 +1,305 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	// remoteWriteRulePrefix prefixes the keys of the config naming the
+	// metrics of a namespace pattern
+	remoteWriteRulePrefix = "rule."
+	// remoteWriteLabelPrefix prefixes the keys of the config adding a label
+	// to every series
+	remoteWriteLabelPrefix = "label."
+)
+
+var (
+	// promInvalidName matches the characters not allowed in the names of
+	// the metrics and labels of prometheus
+	promInvalidName = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+	// promRulePlaceholder matches the elements of the patterns of the rules
+	// naming a label, e.g. {container}
+	promRulePlaceholder = regexp.MustCompile(`^\{([a-zA-Z_][a-zA-Z0-9_]*)\}$`)
+)
+
+// remoteWriteSink writes the numeric metrics to a prometheus remote write
+// endpoint, e.g. of prometheus, cortex or thanos, as a snappy compressed
+// protobuf WriteRequest.  A metric is named after the first rule matching
+// its namespace, or after the static elements of its namespace joined by
+// underscores; its tags and the dynamic elements of its namespace are its
+// labels.  The metrics whose data is not numeric are skipped.
+type remoteWriteSink struct {
+	*httpTarget
+	prefix string
+	rules  []remoteWriteRule
+	labels map[string]string
+}
+
+// remoteWriteRule names the metrics whose namespace matches the elements;
+// the elements * and {label} match any element, the latter being added as
+// the label of the given name
+type remoteWriteRule struct {
+	elements []string
+	name     string
+}
+
+func newRemoteWriteSink(config map[string]interface{}) (*remoteWriteSink, error) {
+	target, err := newHTTPTarget(sinkRemoteWrite, config)
+	if err != nil {
+		return nil, err
+	}
+	s := &remoteWriteSink{httpTarget: target, labels: map[string]string{}}
+	if s.prefix, err = sinkString(sinkRemoteWrite, config, "prefix", false); err != nil {
+		return nil, err
+	}
+	for k := range config {
+		switch {
+		case strings.HasPrefix(k, remoteWriteRulePrefix):
+			name, err := sinkString(sinkRemoteWrite, config, k, true)
+			if err != nil {
+				return nil, err
+			}
+			pattern := strings.TrimPrefix(k, remoteWriteRulePrefix)
+			if !strings.HasPrefix(pattern, namespaceSeparator) || promInvalidName.MatchString(name) || name == "" {
+				return nil, fmt.Errorf("Invalid rule '%s: %s' in built-in publisher 'remote_write' (expected a namespace pattern and a metric name)", pattern, name)
+			}
+			s.rules = append(s.rules, remoteWriteRule{
+				elements: strings.Split(strings.TrimPrefix(pattern, namespaceSeparator), namespaceSeparator),
+				name:     name,
+			})
+		case strings.HasPrefix(k, remoteWriteLabelPrefix):
+			value, err := sinkString(sinkRemoteWrite, config, k, true)
+			if err != nil {
+				return nil, err
+			}
+			s.labels[promLabelName(strings.TrimPrefix(k, remoteWriteLabelPrefix))] = value
+		}
+	}
+	// the more specific rules first: the rules with fewer wildcards, then
+	// in the order of their patterns
+	sort.Sort(remoteWriteRules(s.rules))
+	return s, nil
+}
+
+type remoteWriteRules []remoteWriteRule
+
+func (r remoteWriteRules) Len() int      { return len(r) }
+func (r remoteWriteRules) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r remoteWriteRules) Less(i, j int) bool {
+	if wi, wj := r[i].wildcards(), r[j].wildcards(); wi != wj {
+		return wi < wj
+	}
+	return strings.Join(r[i].elements, namespaceSeparator) < strings.Join(r[j].elements, namespaceSeparator)
+}
+
+func (r remoteWriteRule) wildcards() int {
+	n := 0
+	for _, e := range r.elements {
+		if e == namespaceAny || promRulePlaceholder.MatchString(e) {
+			n++
+		}
+	}
+	return n
+}
+
+// match returns the labels of the namespace named by the rule; false when
+// the rule does not match the namespace
+func (r remoteWriteRule) match(ns core.Namespace) (map[string]string, bool) {
+	if len(ns) != len(r.elements) {
+		return nil, false
+	}
+	labels := map[string]string{}
+	for i, e := range r.elements {
+		if m := promRulePlaceholder.FindStringSubmatch(e); m != nil {
+			labels[m[1]] = ns[i].Value
+		} else if e != namespaceAny && e != ns[i].Value {
+			return nil, false
+		}
+	}
+	return labels, true
+}
+
+// promSeries is a series of a write request: its labels, sorted by name,
+// and its samples
+type promSeries struct {
+	labels  []promLabel
+	samples []promSample
+}
+
+type promLabel struct {
+	name, value string
+}
+
+type promSample struct {
+	value     float64
+	timestamp int64
+}
+
+func (s *remoteWriteSink) publish(ctx context.Context, mts []core.Metric, _ time.Time) error {
+	series := s.series(mts)
+	if len(series) == 0 {
+		return nil
+	}
+	body := snappy.Encode(nil, encodeWriteRequest(series))
+	return s.post(ctx, body, http.Header{
+		"Content-Type":                      {"application/x-protobuf"},
+		"Content-Encoding":                  {"snappy"},
+		"X-Prometheus-Remote-Write-Version": {"0.1.0"},
+	})
+}
+
+// series groups the samples of the numeric metrics by series, in the order
+// the series first appear
+func (s *remoteWriteSink) series(mts []core.Metric) []*promSeries {
+	index := map[string]*promSeries{}
+	out := []*promSeries{}
+	for _, m := range mts {
+		value, ok := toFloat64(m.Data())
+		if !ok {
+			continue
+		}
+		labels := s.seriesLabels(m)
+		key := ""
+		for _, l := range labels {
+			key += l.name + "\xff" + l.value + "\xff"
+		}
+		ps, ok := index[key]
+		if !ok {
+			ps = &promSeries{labels: labels}
+			index[key] = ps
+			out = append(out, ps)
+		}
+		ps.samples = append(ps.samples, promSample{value: value, timestamp: m.Timestamp().UnixNano() / int64(time.Millisecond)})
+	}
+	return out
+}
+
+// seriesLabels returns the labels of the series of a metric, sorted by name:
+// the labels of the publisher, the tags of the metric, the dynamic elements
+// of its namespace and the labels of its rule, then its name
+func (s *remoteWriteSink) seriesLabels(m core.Metric) []promLabel {
+	labels := map[string]string{}
+	for k, v := range s.labels {
+		labels[k] = v
+	}
+	for k, v := range m.Tags() {
+		labels[promLabelName(k)] = v
+	}
+	var name string
+	for _, r := range s.rules {
+		if ruleLabels, ok := r.match(m.Namespace()); ok {
+			name = r.name
+			for k, v := range ruleLabels {
+				labels[k] = v
+			}
+			break
+		}
+	}
+	if name == "" {
+		elements := []string{}
+		for _, e := range m.Namespace() {
+			if e.IsDynamic() {
+				labels[promLabelName(e.Name)] = e.Value
+				continue
+			}
+			elements = append(elements, e.Value)
+		}
+		name = promInvalidName.ReplaceAllString(strings.Join(elements, "_"), "_")
+	}
+	if s.prefix != "" {
+		name = s.prefix + "_" + name
+	}
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	labels["__name__"] = name
+	out := make([]promLabel, 0, len(labels))
+	for k, v := range labels {
+		out = append(out, promLabel{name: k, value: v})
+	}
+	sort.Sort(promLabels(out))
+	return out
+}
+
+type promLabels []promLabel
+
+func (l promLabels) Len() int           { return len(l) }
+func (l promLabels) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l promLabels) Less(i, j int) bool { return l[i].name < l[j].name }
+
+// promLabelName returns a valid label name for the given name
+func promLabelName(name string) string {
+	name = promInvalidName.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// encodeWriteRequest encodes the series as the protobuf message
+// prometheus.WriteRequest of the remote write protocol:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(series []*promSeries) []byte {
+	req := proto.NewBuffer(nil)
+	for _, s := range series {
+		ts := proto.NewBuffer(nil)
+		for _, l := range s.labels {
+			lb := proto.NewBuffer(nil)
+			lb.EncodeVarint(1<<3 | proto.WireBytes)
+			lb.EncodeStringBytes(l.name)
+			lb.EncodeVarint(2<<3 | proto.WireBytes)
+			lb.EncodeStringBytes(l.value)
+			ts.EncodeVarint(1<<3 | proto.WireBytes)
+			ts.EncodeRawBytes(lb.Bytes())
+		}
+		for _, sample := range s.samples {
+			sb := proto.NewBuffer(nil)
+			sb.EncodeVarint(1<<3 | proto.WireFixed64)
+			sb.EncodeFixed64(math.Float64bits(sample.value))
+			sb.EncodeVarint(2<<3 | proto.WireVarint)
+			sb.EncodeVarint(uint64(sample.timestamp))
+			ts.EncodeVarint(2<<3 | proto.WireBytes)
+			ts.EncodeRawBytes(sb.Bytes())
+		}
+		req.EncodeVarint(1<<3 | proto.WireBytes)
+		req.EncodeRawBytes(ts.Bytes())
+	}
+	return req.Bytes()
+}
+
+func (s *remoteWriteSink) close() {}
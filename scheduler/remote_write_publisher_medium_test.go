@@ -0,0 +1,174 @@
+//go:build medium
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+// receiver decodes the write requests it receives into series, each
+// described by its labels and samples, and answers them with the given
+// statuses, in turn, then with 200
+type receiver struct {
+	sync.Mutex
+	statuses []int
+	series   []*promSeries
+	headers  []http.Header
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.Lock()
+	defer rc.Unlock()
+	compressed, _ := ioutil.ReadAll(r.Body)
+	body, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, ts := range decodeFields(body)[1] {
+		s := &promSeries{}
+		fields := decodeFields(ts.([]byte))
+		for _, l := range fields[1] {
+			lf := decodeFields(l.([]byte))
+			s.labels = append(s.labels, promLabel{name: string(lf[1][0].([]byte)), value: string(lf[2][0].([]byte))})
+		}
+		for _, sample := range fields[2] {
+			sf := decodeFields(sample.([]byte))
+			s.samples = append(s.samples, promSample{value: math.Float64frombits(sf[1][0].(uint64)), timestamp: int64(sf[2][0].(uint64))})
+		}
+		rc.series = append(rc.series, s)
+	}
+	rc.headers = append(rc.headers, r.Header)
+	if len(rc.statuses) > 0 {
+		w.WriteHeader(rc.statuses[0])
+		rc.statuses = rc.statuses[1:]
+	}
+}
+
+// decodeFields returns the values of the fields of a protobuf message by
+// field number
+func decodeFields(b []byte) map[uint64][]interface{} {
+	fields := map[uint64][]interface{}{}
+	buf := proto.NewBuffer(b)
+	for {
+		key, err := buf.DecodeVarint()
+		if err != nil {
+			return fields
+		}
+		var v interface{}
+		switch key & 7 {
+		case proto.WireBytes:
+			v, err = buf.DecodeRawBytes(true)
+		case proto.WireFixed64:
+			v, err = buf.DecodeFixed64()
+		default:
+			v, err = buf.DecodeVarint()
+		}
+		if err != nil {
+			return fields
+		}
+		fields[key>>3] = append(fields[key>>3], v)
+	}
+}
+
+func TestRemoteWriteSink(t *testing.T) {
+	Convey("Given the built-in remote write publisher", t, func() {
+		rc := &receiver{}
+		srv := httptest.NewServer(rc)
+		defer srv.Close()
+		now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+		ms := now.UnixNano() / int64(time.Millisecond)
+		docker := core.NewNamespace("intel", "docker").AddDynamicElement("container_id", "id of the container").AddStaticElements("cpu", "usage")
+		docker[2].Value = "abc"
+		mts := []core.Metric{
+			plugin.MetricType{Namespace_: core.NewNamespace("intel", "psutil", "load", "load1"), Timestamp_: now, Data_: 1.5, Tags_: map[string]string{"plugin_running_on": "host1"}},
+			plugin.MetricType{Namespace_: docker, Timestamp_: now, Data_: 42},
+			plugin.MetricType{Namespace_: core.NewNamespace("intel", "psutil", "load", "load1"), Timestamp_: now.Add(time.Second), Data_: 2.5, Tags_: map[string]string{"plugin_running_on": "host1"}},
+			plugin.MetricType{Namespace_: core.NewNamespace("intel", "os", "name"), Timestamp_: now, Data_: "linux"},
+		}
+
+		Convey("it writes the numeric metrics as series named after their namespace", func() {
+			s, err := newSink(sinkRemoteWrite, map[string]interface{}{"url": srv.URL, "label.env": "prod"})
+			So(err, ShouldBeNil)
+			So(s.publish(context.Background(), mts, now), ShouldBeNil)
+			So(rc.headers, ShouldHaveLength, 1)
+			So(rc.headers[0].Get("Content-Encoding"), ShouldEqual, "snappy")
+			So(rc.headers[0].Get("Content-Type"), ShouldEqual, "application/x-protobuf")
+			So(rc.headers[0].Get("X-Prometheus-Remote-Write-Version"), ShouldEqual, "0.1.0")
+			So(rc.series, ShouldResemble, []*promSeries{
+				{
+					labels:  []promLabel{{"__name__", "intel_psutil_load_load1"}, {"env", "prod"}, {"plugin_running_on", "host1"}},
+					samples: []promSample{{1.5, ms}, {2.5, ms + 1000}},
+				},
+				{
+					labels:  []promLabel{{"__name__", "intel_docker_cpu_usage"}, {"container_id", "abc"}, {"env", "prod"}},
+					samples: []promSample{{42, ms}},
+				},
+			})
+		})
+		Convey("it names the metrics after the most specific rule matching them", func() {
+			s, err := newSink(sinkRemoteWrite, map[string]interface{}{
+				"url":                           srv.URL,
+				"prefix":                        "snap",
+				"rule./intel/*/*/*":             "intel_metric",
+				"rule./intel/psutil/load/*":     "load",
+				"rule./intel/docker/{id}/cpu/*": "container_cpu",
+			})
+			So(err, ShouldBeNil)
+			So(s.publish(context.Background(), mts, now), ShouldBeNil)
+			So(rc.series, ShouldHaveLength, 2)
+			So(rc.series[0].labels, ShouldResemble, []promLabel{{"__name__", "snap_load"}, {"plugin_running_on", "host1"}})
+			So(rc.series[1].labels, ShouldResemble, []promLabel{{"__name__", "snap_container_cpu"}, {"id", "abc"}})
+		})
+		Convey("a server error is retryable", func() {
+			rc.statuses = []int{503}
+			s, err := newSink(sinkRemoteWrite, map[string]interface{}{"url": srv.URL})
+			So(err, ShouldBeNil)
+			err = s.publish(context.Background(), mts, now)
+			So(err, ShouldHaveSameTypeAs, &core.PublishError{})
+			So(err.(*core.PublishError).Retryable, ShouldBeTrue)
+		})
+		Convey("it rejects invalid settings", func() {
+			_, err := newSink(sinkRemoteWrite, map[string]interface{}{})
+			So(err.Error(), ShouldEqual, "Missing 'url' in the config of built-in publisher 'remote_write'")
+			_, err = newSink(sinkRemoteWrite, map[string]interface{}{"url": srv.URL, "rule.intel/*": "intel"})
+			So(err.Error(), ShouldStartWith, "Invalid rule 'intel/*: intel'")
+			_, err = newSink(sinkRemoteWrite, map[string]interface{}{"url": srv.URL, "rule./intel/*": "intel-metric"})
+			So(err.Error(), ShouldStartWith, "Invalid rule '/intel/*: intel-metric'")
+		})
+	})
+}
@@ -0,0 +1,121 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// namespaceRewriter rewrites the namespaces of the metrics of a task before
+// they are published, applying its rules in order
+type namespaceRewriter struct {
+	rules []namespaceRewriteRule
+}
+
+// namespaceRewriteRule is a prefix rule (rename) or a regex rule (re)
+type namespaceRewriteRule struct {
+	rename      *renameTransform
+	re          *regexp.Regexp
+	replacement string
+}
+
+// newNamespaceRewriter returns the rewriter of the rules of a workflow map;
+// nil when there are none
+func newNamespaceRewriter(rules []wmap.NamespaceRewrite) (*namespaceRewriter, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	r := &namespaceRewriter{rules: make([]namespaceRewriteRule, len(rules))}
+	for i, rule := range rules {
+		switch {
+		case rule.Prefix != "" && rule.Regex != "":
+			return nil, fmt.Errorf("Invalid namespace rewrite %d (expected a prefix or a regex, not both)", i)
+		case rule.Prefix != "":
+			tr, err := newTransform(transformRename, map[string]interface{}{"from": rule.Prefix, "to": rule.Replacement})
+			if err != nil {
+				return nil, fmt.Errorf("%v (while parsing namespace rewrite %d)", err, i)
+			}
+			r.rules[i].rename = tr.(*renameTransform)
+		case rule.Regex != "":
+			re, err := regexp.Compile(rule.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid regex '%s' in namespace rewrite %d: %v", rule.Regex, i, err)
+			}
+			if !strings.HasPrefix(rule.Replacement, namespaceSeparator) {
+				return nil, fmt.Errorf("Invalid replacement '%s' in namespace rewrite %d (expected a leading '/')", rule.Replacement, i)
+			}
+			r.rules[i].re = re
+			r.rules[i].replacement = rule.Replacement
+		default:
+			return nil, fmt.Errorf("Invalid namespace rewrite %d (expected a prefix or a regex)", i)
+		}
+	}
+	return r, nil
+}
+
+func (r *namespaceRewriter) apply(mts []core.Metric) []core.Metric {
+	for _, rule := range r.rules {
+		if rule.rename != nil {
+			mts = rule.rename.apply(mts)
+			continue
+		}
+		out := make([]core.Metric, 0, len(mts))
+		for _, m := range mts {
+			ns := m.Namespace()
+			joined := namespaceSeparator + strings.Join(ns.Strings(), namespaceSeparator)
+			if !rule.re.MatchString(joined) {
+				out = append(out, m)
+				continue
+			}
+			rewritten := rule.re.ReplaceAllString(joined, rule.replacement)
+			if rewritten == joined {
+				out = append(out, m)
+				continue
+			}
+			out = append(out, transformedMetric(m, rewrittenNamespace(ns, rewritten), m.Data(), m.Unit()))
+		}
+		mts = out
+	}
+	return mts
+}
+
+// rewrittenNamespace returns the namespace of the rewritten path; the
+// elements left in place keep their name and description, so the dynamic
+// elements of the namespace stay dynamic
+func rewrittenNamespace(ns core.Namespace, path string) core.Namespace {
+	rewritten := core.Namespace{}
+	for _, value := range strings.Split(path, namespaceSeparator) {
+		if value == "" {
+			continue
+		}
+		i := len(rewritten)
+		if i < len(ns) && ns[i].Value == value {
+			rewritten = append(rewritten, ns[i])
+			continue
+		}
+		rewritten = append(rewritten, core.NamespaceElement{Value: value})
+	}
+	return rewritten
+}
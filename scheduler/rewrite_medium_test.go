@@ -0,0 +1,87 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func TestNamespaceRewriter(t *testing.T) {
+	Convey("Given namespace rewrite rules", t, func() {
+		mt := func(ns core.Namespace) core.Metric {
+			return plugin.MetricType{Namespace_: ns, Data_: 1, Unit_: "B"}
+		}
+
+		Convey("no rules give no rewriter", func() {
+			r, err := newNamespaceRewriter(nil)
+			So(err, ShouldBeNil)
+			So(r, ShouldBeNil)
+		})
+		Convey("the rules are applied in order", func() {
+			r, err := newNamespaceRewriter([]wmap.NamespaceRewrite{
+				{Prefix: "/intel/*", Replacement: "/acme"},
+				{Regex: "^/acme/(cpu|mem)_([a-z]+)$", Replacement: "/acme/$1/$2"},
+			})
+			So(err, ShouldBeNil)
+			out := r.apply([]core.Metric{
+				mt(core.NewNamespace("intel", "procfs", "cpu_user")),
+				mt(core.NewNamespace("intel", "procfs", "disk_read")),
+				mt(core.NewNamespace("other", "cpu_user")),
+			})
+			So(len(out), ShouldEqual, 3)
+			So(out[0].Namespace().String(), ShouldEqual, "/acme/cpu/user")
+			So(out[0].Data(), ShouldEqual, 1)
+			So(out[0].Unit(), ShouldEqual, "B")
+			So(out[1].Namespace().String(), ShouldEqual, "/acme/disk_read")
+			So(out[2].Namespace().String(), ShouldEqual, "/other/cpu_user")
+		})
+		Convey("the elements left in place stay dynamic", func() {
+			r, err := newNamespaceRewriter([]wmap.NamespaceRewrite{{Regex: "^/intel/disk/", Replacement: "/acme/storage/"}})
+			So(err, ShouldBeNil)
+			ns := core.NewNamespace("intel", "disk").AddDynamicElement("device", "device name").AddStaticElement("reads")
+			ns[2].Value = "sda"
+			out := r.apply([]core.Metric{mt(ns)})
+			So(out[0].Namespace().String(), ShouldEqual, "/acme/storage/sda/reads")
+			So(out[0].Namespace()[2].IsDynamic(), ShouldBeTrue)
+			So(out[0].Namespace()[2].Name, ShouldEqual, "device")
+		})
+		Convey("invalid rules are rejected", func() {
+			for _, rules := range [][]wmap.NamespaceRewrite{
+				{{Replacement: "/acme"}},
+				{{Prefix: "/intel", Regex: "intel", Replacement: "/acme"}},
+				{{Prefix: "intel", Replacement: "/acme"}},
+				{{Prefix: "/intel", Replacement: "/acme/*"}},
+				{{Regex: "(", Replacement: "/acme"}},
+				{{Regex: "^/intel", Replacement: "acme"}},
+			} {
+				_, err := newNamespaceRewriter(rules)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
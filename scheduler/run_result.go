@@ -0,0 +1,120 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/intelsdi-x/snap/core/serror"
+)
+
+var (
+	// ErrMetricManagerUnavailable - The error message for a metric manager which cannot be reached
+	ErrMetricManagerUnavailable = errors.New("Metric manager is unavailable")
+)
+
+// RunResult describes the outcome of a single run of a task workflow.
+type RunResult struct {
+	// StartTime is when the run started
+	StartTime time.Time
+	// Duration is how long the whole workflow took
+	Duration time.Duration
+	// Collected is the number of metrics collected during the run
+	Collected int
+	// Errors holds the errors of every node of the workflow which failed
+	Errors []error
+	// FailedPlugins lists the plugins (type:name:version) reported by the
+	// errors of the run, e.g. the collectors which failed while the others
+	// succeeded
+	FailedPlugins []string
+}
+
+func newRunResult(start time.Time) *RunResult {
+	return &RunResult{
+		StartTime: start,
+	}
+}
+
+// Success returns true when no node of the workflow failed.
+func (r *RunResult) Success() bool {
+	return len(r.Errors) == 0
+}
+
+// Partial returns true when metrics were collected but some process or
+// publish node of the workflow failed.
+func (r *RunResult) Partial() bool {
+	return len(r.Errors) > 0 && r.Collected > 0
+}
+
+// LastError returns the message of the last error of the run or an empty
+// string when the run succeeded.
+func (r *RunResult) LastError() string {
+	if len(r.Errors) == 0 {
+		return ""
+	}
+	return r.Errors[len(r.Errors)-1].Error()
+}
+
+// Unavailable returns true when the run failed because a metric manager of
+// the task could not be reached.
+func (r *RunResult) Unavailable() bool {
+	for _, e := range r.Errors {
+		if isUnavailable(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// failedPlugins returns the plugins (type:name:version) reported by the
+// fields of the errors, without duplicates
+func failedPlugins(errs []error) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, e := range errs {
+		serr, ok := e.(serror.SnapError)
+		if !ok {
+			continue
+		}
+		f := serr.Fields()
+		typ, _ := f["plugin-type"].(string)
+		name, _ := f["plugin-name"].(string)
+		version, _ := f["plugin-version"].(int)
+		if typ == "" || name == "" {
+			continue
+		}
+		subject := pluginSubject(typ, name, version)
+		if !seen[subject] {
+			seen[subject] = true
+			out = append(out, subject)
+		}
+	}
+	return out
+}
+
+// isUnavailable returns true if the error reports a metric manager which
+// cannot be reached
+func isUnavailable(err error) bool {
+	return err == ErrMetricManagerUnavailable || grpc.Code(err) == codes.Unavailable
+}
@@ -0,0 +1,383 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	// maxListedFires is the number of fires listed per task by a run
+	// simulation
+	maxListedFires = 100
+	// maxListedCollisions is the number of collisions listed by a run
+	// simulation
+	maxListedCollisions = 100
+	// maxHotSpots is the number of busiest slots reported by a run simulation
+	maxHotSpots = 5
+	// defaultSimulationSlots is the number of slots the range of a run
+	// simulation is split in when no slot length is given
+	defaultSimulationSlots = 60
+	// maxSimulationSlots bounds the number of slots of a run simulation
+	maxSimulationSlots = 100000
+	// defaultCollisionWindow is how close the fires of different tasks are
+	// to collide when no collision window is given
+	defaultCollisionWindow = time.Second
+)
+
+var (
+	// ErrNoSimulatedTask - The error message for a run simulation without task
+	ErrNoSimulatedTask = errors.New("No task to simulate")
+	// ErrMissingSimulatedSchedule - The error message for a simulated task given neither by id nor by schedule
+	ErrMissingSimulatedSchedule = errors.New("A simulated task requires a task id or a schedule")
+	// ErrInvalidSimulationRange - The error message for a run simulation ending before its start
+	ErrInvalidSimulationRange = errors.New("The end of the simulated range must be after its start")
+	// ErrInvalidSimulationSlot - The error message for a run simulation split in too many or too short slots
+	ErrInvalidSimulationSlot = fmt.Errorf("The slot must be positive and split the simulated range in at most %d slots", maxSimulationSlots)
+	// ErrTooManySimulatedFires - The error message for a run simulation of too many fires
+	ErrTooManySimulatedFires = fmt.Errorf("The tasks fire more than %d times over the simulated range", maxSimulatedFires)
+)
+
+// simulatedTask is a task of a run simulation, with its schedule and the
+// durations of its runs
+type simulatedTask struct {
+	name      string
+	id        string
+	schedule  *core.Schedule
+	durations []time.Duration
+}
+
+// simulatedRun is a run of the task of the given index in a run simulation
+type simulatedRun struct {
+	task   int
+	fire   time.Time
+	finish time.Time
+}
+
+// SimulateRun simulates the schedules of the given tasks together over a
+// virtual time range: it reports when each task would fire, the fires of
+// different tasks colliding and the load of the runs compared to the size
+// of the worker pools.  Nothing is run; the outcome only depends on the
+// request and on the recorded durations of the runs.
+func (s *scheduler) SimulateRun(req *core.RunSimulationRequest) (*core.RunSimulation, error) {
+	if len(req.Tasks) == 0 {
+		return nil, ErrNoSimulatedTask
+	}
+	tasks := make([]simulatedTask, len(req.Tasks))
+	for i, st := range req.Tasks {
+		t, err := s.simulatedTask(st)
+		if err != nil {
+			return nil, fmt.Errorf("tasks[%d]: %v", i, err)
+		}
+		if t.name == "" {
+			t.name = fmt.Sprintf("tasks[%d]", i)
+		}
+		tasks[i] = t
+	}
+	sim, err := simulateRun(req, tasks)
+	if err != nil {
+		return nil, err
+	}
+	sim.Workers = s.workManager.poolSize()
+	sim.Overloaded = sim.PeakConcurrentRuns > int(sim.Workers)
+	return sim, nil
+}
+
+// simulatedTask returns the schedule and the run durations of a task of a
+// run simulation.  The duration given replaces the recorded durations of the
+// runs of the task, or of all the tasks of the daemon when the task has none.
+func (s *scheduler) simulatedTask(st core.SimulatedTask) (simulatedTask, error) {
+	t := simulatedTask{name: st.Name, id: st.TaskID, schedule: st.Schedule}
+	if st.TaskID != "" {
+		tk, err := s.getTask(st.TaskID)
+		if err != nil {
+			return t, err
+		}
+		if t.name == "" {
+			t.name = tk.GetName()
+		}
+		if t.schedule == nil {
+			if t.schedule, err = core.ScheduleFromSchedule(tk.Schedule()); err != nil {
+				return t, err
+			}
+		}
+		t.durations = s.latencies.get(st.TaskID)
+	}
+	if t.schedule == nil {
+		return t, ErrMissingSimulatedSchedule
+	}
+	if st.Duration != "" {
+		d, err := time.ParseDuration(st.Duration)
+		if err != nil {
+			return t, err
+		}
+		if d < 0 {
+			return t, fmt.Errorf("negative duration %s", d)
+		}
+		t.durations = []time.Duration{d}
+	}
+	if len(t.durations) == 0 {
+		t.durations = s.latencies.get("")
+	}
+	if len(t.durations) == 0 {
+		t.durations = []time.Duration{0}
+	}
+	return t, nil
+}
+
+// simulateRun fires the schedules of the tasks over the range of the request,
+// each run of a task lasting the next of its durations
+func simulateRun(req *core.RunSimulationRequest, tasks []simulatedTask) (*core.RunSimulation, error) {
+	if !req.End.After(req.Start) {
+		return nil, ErrInvalidSimulationRange
+	}
+	length := req.End.Sub(req.Start)
+	window := defaultCollisionWindow
+	if req.CollisionWindow != "" {
+		var err error
+		if window, err = time.ParseDuration(req.CollisionWindow); err != nil {
+			return nil, err
+		}
+	}
+	slot := length / defaultSimulationSlots
+	if req.Slot != "" {
+		var err error
+		if slot, err = time.ParseDuration(req.Slot); err != nil {
+			return nil, err
+		}
+		if slot <= 0 || length/slot >= maxSimulationSlots {
+			return nil, ErrInvalidSimulationSlot
+		}
+	}
+	if slot <= 0 {
+		slot = length
+	}
+
+	sim := &core.RunSimulation{
+		Start:      req.Start,
+		End:        req.End,
+		Tasks:      make([]core.SimulatedTaskRuns, len(tasks)),
+		Collisions: []core.ScheduleCollision{},
+	}
+	runs := []simulatedRun{}
+	// scheduled counts the fires of all the tasks, the missed ones included
+	scheduled := 0
+	for i, t := range tasks {
+		start, next, _, err := scheduleFires(t.schedule, req.Start)
+		if err != nil {
+			return nil, fmt.Errorf("tasks[%d]: %v", i, err)
+		}
+		bounded := func(fire time.Time) time.Time {
+			if scheduled++; scheduled > maxSimulatedFires {
+				return time.Time{}
+			}
+			return next(fire)
+		}
+		tr := core.SimulatedTaskRuns{
+			Name:         t.name,
+			TaskID:       t.id,
+			Schedule:     t.schedule,
+			MeanDuration: meanDuration(t.durations).String(),
+			Fires:        []time.Time{},
+		}
+		fires, missed, _ := simulateFires(bounded, start, req.End, t.durations, func(fire, finish time.Time) {
+			if len(tr.Fires) < maxListedFires {
+				tr.Fires = append(tr.Fires, fire)
+			}
+			runs = append(runs, simulatedRun{task: i, fire: fire, finish: finish})
+		})
+		if scheduled > maxSimulatedFires {
+			return nil, ErrTooManySimulatedFires
+		}
+		tr.FireCount = fires
+		tr.MissedFires = missed
+		sim.Tasks[i] = tr
+	}
+	sort.Sort(runsByFire(runs))
+	sim.CollisionCount, sim.Collisions = collisions(runs, tasks, window)
+	sim.HotSpots = loadSlots(sim, runs, slot)
+	return sim, nil
+}
+
+// collisions groups the runs, sorted by fire, whose fires are within the
+// window of the first fire of the group, and returns the number of groups
+// of different tasks and the first of them
+func collisions(runs []simulatedRun, tasks []simulatedTask, window time.Duration) (int, []core.ScheduleCollision) {
+	count := 0
+	listed := []core.ScheduleCollision{}
+	for i := 0; i < len(runs); {
+		j := i + 1
+		for j < len(runs) && runs[j].fire.Sub(runs[i].fire) <= window {
+			j++
+		}
+		seen := map[int]bool{}
+		names := []string{}
+		for _, r := range runs[i:j] {
+			if !seen[r.task] {
+				seen[r.task] = true
+				names = append(names, tasks[r.task].name)
+			}
+		}
+		if len(names) > 1 {
+			count++
+			if len(listed) < maxListedCollisions {
+				listed = append(listed, core.ScheduleCollision{At: runs[i].fire, Tasks: names})
+			}
+		}
+		i = j
+	}
+	return count, listed
+}
+
+// loadSlots sets the peak and mean number of concurrent runs of the
+// simulation and returns its busiest slots
+func loadSlots(sim *core.RunSimulation, runs []simulatedRun, slot time.Duration) []core.LoadSlot {
+	length := sim.End.Sub(sim.Start)
+	slots := make([]core.LoadSlot, int((length+slot-1)/slot))
+	busy := make([]time.Duration, len(slots))
+	for k := range slots {
+		slots[k].Start = sim.Start.Add(time.Duration(k) * slot)
+	}
+	slotOf := func(t time.Time) int {
+		return int(t.Sub(sim.Start) / slot)
+	}
+
+	var total time.Duration
+	events := make(loadEvents, 0, 2*len(runs))
+	for _, r := range runs {
+		slots[slotOf(r.fire)].Fires++
+		finish := r.finish
+		if finish.After(sim.End) {
+			finish = sim.End
+		}
+		total += finish.Sub(r.fire)
+		// the time spent running is split among the slots the run spans
+		for t := r.fire; t.Before(finish); {
+			k := slotOf(t)
+			end := slots[k].Start.Add(slot)
+			if end.After(finish) {
+				end = finish
+			}
+			busy[k] += end.Sub(t)
+			t = end
+		}
+		if r.finish.After(r.fire) {
+			events = append(events, loadEvent{at: r.fire, delta: 1}, loadEvent{at: r.finish, delta: -1})
+		}
+	}
+	sort.Sort(events)
+
+	// the runs going when a slot starts count in its peak
+	current, k := 0, 0
+	for _, e := range events {
+		if !e.at.Before(sim.End) {
+			break
+		}
+		for ; k < slotOf(e.at); k++ {
+			if current > slots[k+1].PeakConcurrentRuns {
+				slots[k+1].PeakConcurrentRuns = current
+			}
+		}
+		current += e.delta
+		if current > slots[k].PeakConcurrentRuns {
+			slots[k].PeakConcurrentRuns = current
+		}
+		if current > sim.PeakConcurrentRuns {
+			sim.PeakConcurrentRuns = current
+		}
+	}
+
+	for k := range slots {
+		d := slot
+		if end := slots[k].Start.Add(slot); end.After(sim.End) {
+			d = sim.End.Sub(slots[k].Start)
+		}
+		slots[k].Load = float64(busy[k]) / float64(d)
+	}
+	sim.MeanConcurrentRuns = float64(total) / float64(length)
+
+	hotSpots := []core.LoadSlot{}
+	for _, s := range slots {
+		if s.Fires > 0 || s.Load > 0 {
+			hotSpots = append(hotSpots, s)
+		}
+	}
+	sort.Stable(slotsByLoad(hotSpots))
+	if len(hotSpots) > maxHotSpots {
+		hotSpots = hotSpots[:maxHotSpots]
+	}
+	return hotSpots
+}
+
+func meanDuration(durations []time.Duration) time.Duration {
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total / time.Duration(len(durations))
+}
+
+// runsByFire sorts the runs by fire, then by task
+type runsByFire []simulatedRun
+
+func (r runsByFire) Len() int      { return len(r) }
+func (r runsByFire) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r runsByFire) Less(i, j int) bool {
+	if r[i].fire.Equal(r[j].fire) {
+		return r[i].task < r[j].task
+	}
+	return r[i].fire.Before(r[j].fire)
+}
+
+// loadEvent is the start or the end of a run
+type loadEvent struct {
+	at    time.Time
+	delta int
+}
+
+// loadEvents sorts the events by time, a run ending before another starting
+// at the same time
+type loadEvents []loadEvent
+
+func (e loadEvents) Len() int      { return len(e) }
+func (e loadEvents) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e loadEvents) Less(i, j int) bool {
+	if e[i].at.Equal(e[j].at) {
+		return e[i].delta < e[j].delta
+	}
+	return e[i].at.Before(e[j].at)
+}
+
+// slotsByLoad sorts the slots by decreasing load, then by decreasing fires
+type slotsByLoad []core.LoadSlot
+
+func (s slotsByLoad) Len() int      { return len(s) }
+func (s slotsByLoad) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s slotsByLoad) Less(i, j int) bool {
+	if s[i].Load == s[j].Load {
+		return s[i].Fires > s[j].Fires
+	}
+	return s[i].Load > s[j].Load
+}
@@ -22,11 +22,12 @@ package scheduler
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
-	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -38,10 +39,14 @@ import (
 	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/control_event"
 	"github.com/intelsdi-x/snap/core/ctypes"
 	"github.com/intelsdi-x/snap/core/scheduler_event"
 	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/chrono"
+	"github.com/intelsdi-x/snap/pkg/eventbus"
 	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/pkg/tracing"
 	"github.com/intelsdi-x/snap/scheduler/wmap"
 )
 
@@ -72,8 +77,16 @@ var (
 	ErrPluginIncompatibleWithScheduleType = errors.New("Plugin is incompatible with the tasks schedule type.")
 	// ErrMultipleStreamingPlugins - The error message when a task with a streaming schedule refers to multiple streaming plugins.
 	ErrMultipleStreamingPlugins = errors.New("Multiple streaming plugins within the same task is not supported.")
+	// ErrIntervalNotOverridable - The error message for when an interval override is given for a task whose schedule has no single interval
+	ErrIntervalNotOverridable = errors.New("Interval can only be overridden for tasks with a simple or windowed schedule.")
+	// ErrCatalogChanged - The error message for when the plugin catalog keeps changing while task dependencies are validated or subscribed
+	ErrCatalogChanged = errors.New("Plugin catalog changed while processing task dependencies.")
 )
 
+// maxCatalogRetries is the number of times validating or subscribing task
+// dependencies is retried when the plugin catalog changed in the meantime.
+const maxCatalogRetries = 5
+
 type schedulerState int
 
 const (
@@ -103,6 +116,27 @@ type managesMetrics interface {
 	UnsubscribeDeps(string) []serror.SnapError
 }
 
+// versionsCatalog is optionally implemented by a metric manager which can
+// report the generation of its plugin catalog.  The generation changes every
+// time a plugin is loaded, unloaded or swapped.
+type versionsCatalog interface {
+	CatalogGeneration() uint64
+}
+
+// tenantsRestricted is optionally implemented by a metric manager which
+// restricts the secrets the config of a task refers to by the tenant of the
+// task.
+type tenantsRestricted interface {
+	SetTaskTenant(id, tenant string)
+}
+
+// resolvesSecrets is optionally implemented by a metric manager which
+// resolves the references to secrets, e.g. in the settings of the built-in
+// publishers of a task.
+type resolvesSecrets interface {
+	ResolveSecret(id, value string) (string, error)
+}
+
 type collectsMetrics interface {
 	CollectMetrics(string, map[string]map[string]string) ([]core.Metric, []error)
 }
@@ -121,11 +155,55 @@ type processesMetrics interface {
 
 type scheduler struct {
 	workManager     *workManager
+	dedicatedQueues *dedicatedQueues
 	metricManager   managesMetrics
 	tasks           *taskCollection
 	state           schedulerState
-	eventManager    *gomit.EventController
+	eventManager    *eventbus.Bus
+	eventBatcher    *eventBatcher
+	eventLog        *eventLog
 	taskWatcherColl *taskWatcherCollection
+	placement       placesTasks
+	standby         *standbyTasks
+	leaderElection  electsLeaders
+	presets         core.TaskPresets
+	templates       *taskTemplates
+	taskDefaults    *defaultTaskOptions
+	// latencies holds the durations of the task runs schedules are simulated against
+	latencies *runLatencies
+	// exportPolicy restricts the metrics leaving the node to aggregates
+	exportPolicy exportPolicy
+	// deprecatedMetrics are reported by the task advisor
+	deprecatedMetrics []deprecatedMetric
+	// timeouts are the default timeouts of the phases of the tasks
+	timeouts *defaultTimeouts
+	// maintenance pauses the tasks during maintenance windows
+	maintenance *maintenance
+	// tracer records the task runs, nil when tracing is disabled
+	tracer *tracing.Tracer
+	// backfills are the backfills of the tasks
+	backfills *backfills
+	// clock tells the time to the tasks and their schedules
+	clock chrono.Clock
+	// inflight bounds the memory of the metrics being published, nil when
+	// it is not bounded
+	inflight *inflightBudget
+	// dedup records the batches acknowledged by the publish nodes
+	// deduplicating them
+	dedup *dedupWindows
+	// fileSinkDir is the directory the built-in file publishers write into
+	fileSinkDir string
+	// versionsMutex serializes the resolution of the version constraints of
+	// the running tasks
+	versionsMutex sync.Mutex
+	// cardinality tracks and limits the namespaces collected by the tasks
+	cardinality *cardinalityGuard
+	// quotas enforces the quotas of the tasks, tenants the ones of the
+	// tenants of the tasks
+	quotas  *quotas
+	tenants *tenantQuotas
+	// taskIDs generates the ids of the tasks
+	taskIDs generatesTaskIDs
 }
 
 type managesWork interface {
@@ -139,11 +217,12 @@ func autoDiscoverTasks(taskFiles []os.FileInfo, fullPath string,
 	fp func(sch schedule.Schedule,
 		wfMap *wmap.WorkflowMap,
 		startOnCreate bool,
-		opts ...core.TaskOption) (core.Task, core.TaskErrors)) {
+		opts ...core.TaskOption) (core.Task, core.TaskErrors),
+	admitters ...core.TaskAdmitter) {
 	// Note that the list of files is sorted by name due to ioutil.ReadDir
 	// default behaviour. See go doc ioutil.ReadDir
 	for _, file := range taskFiles {
-		f, err := os.Open(path.Join(fullPath, file.Name()))
+		f, err := os.Open(filepath.Join(fullPath, file.Name()))
 		if err != nil {
 			log.WithFields(log.Fields{
 				"_block":           "autoDiscoverTasks",
@@ -213,7 +292,7 @@ func autoDiscoverTasks(taskFiles []os.FileInfo, fullPath string,
 			defer f.Close()
 		}
 		mode := true
-		task, err := core.CreateTaskFromContent(f, &mode, fp)
+		task, err := core.CreateTaskFromContent(f, &mode, fp, admitters...)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"_block":           "autoDiscoverTasks",
@@ -259,15 +338,118 @@ func New(cfg *Config) *scheduler {
 	}
 	s := &scheduler{
 		tasks:           newTaskCollection(),
-		eventManager:    gomit.NewEventController(),
+		eventManager:    newEventBus(cfg),
 		taskWatcherColl: newTaskWatcherCollection(),
+		standby:         newStandbyTasks(),
+		presets:         cfg.TaskPresets,
+		taskDefaults:    &defaultTaskOptions{},
+		latencies:       newRunLatencies(),
+		maintenance:     newMaintenance(),
+		backfills:       newBackfills(),
+		clock:           chrono.Real,
+		tracer:          newTracer(cfg),
+		taskIDs:         uuidTaskIDs{},
+	}
+	policy, err := newExportPolicy(cfg.AggregationOnly)
+	if err != nil {
+		// raw metrics must not be published in place of aggregates
+		schedulerLogger.WithFields(log.Fields{
+			"_block": "New",
+		}).Fatal(err)
+	}
+	s.exportPolicy = policy
+	deprecated, err := newDeprecatedMetrics(cfg.DeprecatedMetrics)
+	if err != nil {
+		schedulerLogger.WithFields(log.Fields{
+			"_block": "New",
+		}).Error(err)
+	}
+	s.deprecatedMetrics = deprecated
+	s.timeouts = &defaultTimeouts{}
+	s.timeouts.set(taskTimeouts(cfg))
+	defaults, err := taskDefaults(cfg)
+	if err != nil {
+		// the tasks only get the options they set
+		schedulerLogger.WithFields(log.Fields{
+			"_block": "New",
+		}).Error(err)
+	}
+	s.taskDefaults.set(defaults)
+	inflight, err := newInflightBudget(cfg)
+	if err != nil {
+		// the metrics in flight are not bounded
+		schedulerLogger.WithFields(log.Fields{
+			"_block": "New",
+		}).Error(err)
+	}
+	s.inflight = inflight
+	dedup, err := newDedupWindows(cfg)
+	if err != nil {
+		// the acknowledged batches are only kept in memory
+		schedulerLogger.WithFields(log.Fields{
+			"_block": "New",
+		}).Error(err)
+	}
+	s.dedup = dedup
+	s.fileSinkDir = cfg.FilePublisherPath
+	if s.fileSinkDir == "" {
+		s.fileSinkDir = filepath.Join(os.TempDir(), defaultFileSinkDir)
+	}
+	cardinality, err := newCardinalityGuard(cfg)
+	if err != nil {
+		// the namespaces are tracked without global limit
+		schedulerLogger.WithFields(log.Fields{
+			"_block": "New",
+		}).Error(err)
+	}
+	s.cardinality = cardinality
+	quotas, err := newQuotas(cfg)
+	if err != nil {
+		// the tasks are not limited
+		schedulerLogger.WithFields(log.Fields{
+			"_block": "New",
+		}).Error(err)
+		quotas, _ = newQuotas(&Config{})
+	}
+	s.quotas = quotas
+	tenants, err := newTenantQuotas(cfg.TenantQuotas)
+	if err != nil {
+		// the tenants are not limited
+		schedulerLogger.WithFields(log.Fields{
+			"_block": "New",
+		}).Error(err)
+		tenants, _ = newTenantQuotas(nil)
 	}
+	s.tenants = tenants
 
 	// we are setting the size of the queue and number of workers for
 	// collect, process and publish consistently for now
 	s.workManager = newWorkManager(opts...)
 	s.workManager.Start()
-	s.eventManager.RegisterHandler(HandlerRegistrationName, s)
+	s.dedicatedQueues = newDedicatedQueues(cfg.WorkManagerQueueSize, cfg.WorkManagerPoolSize)
+	// the scheduler releases the plugins of the tasks which stop on their
+	// events, which are never queued nor dropped
+	s.eventManager.Subscribe(HandlerRegistrationName, s, eventbus.Synchronously())
+	s.eventBatcher = newEventBatcher(s.eventManager, cfg.EventBatchInterval.Duration, batchedNamespaces...)
+	eventLog, err := newEventLog(cfg.EventLogPath, cfg.EventLogRetention)
+	if err != nil {
+		// the events are still kept in memory
+		schedulerLogger.WithFields(log.Fields{
+			"_block": "New",
+		}).Error(err)
+		eventLog, _ = newEventLog("", cfg.EventLogRetention)
+	}
+	s.eventLog = eventLog
+	s.eventManager.RegisterHandler(EventLogRegistrationName, s.eventLog)
+	templates, err := newTaskTemplates(cfg.TaskTemplatePath)
+	if err != nil {
+		// the templates are still kept in memory
+		schedulerLogger.WithFields(log.Fields{
+			"_block": "New",
+		}).Error(err)
+		templates, _ = newTaskTemplates("")
+	}
+	s.templates = templates
 
 	return s
 }
@@ -284,8 +466,42 @@ func (s *scheduler) Name() string {
 	return "scheduler"
 }
 
-func (s *scheduler) RegisterEventHandler(name string, h gomit.Handler) error {
-	return s.eventManager.RegisterHandler(name, h)
+// RegisterEventHandler registers a handler of the scheduler events.  When
+// namespaces are given only the events of those namespaces are handed to the
+// handler; events coalesced in a scheduler_event.EventsBatchedEvent are
+// filtered individually.
+func (s *scheduler) RegisterEventHandler(name string, h gomit.Handler, namespaces ...string) error {
+	return s.SubscribeEvents(name, h, EventFilter{Namespaces: namespaces})
+}
+
+// SubscribeEvents registers a handler of the scheduler events selected by
+// the filter.
+func (s *scheduler) SubscribeEvents(name string, h gomit.Handler, filter EventFilter) error {
+	return s.eventManager.Subscribe(name, h, eventbus.WithFilter(filter.busFilter()))
+}
+
+// ReplayEvents registers a handler of the scheduler events selected by the
+// filter which first receives the logged events since the given time, as
+// scheduler_event.EventReplayedEvent, then the live events.  The live events
+// are handed to it by the event log.
+func (s *scheduler) ReplayEvents(name string, h gomit.Handler, filter EventFilter, since time.Time) error {
+	s.eventLog.subscribe(name, h, filter, core.EventQuery{Since: since})
+	return nil
+}
+
+// UnsubscribeEvents removes the handler of the scheduler events registered
+// under the given name, with or without a replay.
+func (s *scheduler) UnsubscribeEvents(name string) error {
+	if s.eventLog.unsubscribe(name) {
+		return nil
+	}
+	return s.eventManager.UnregisterHandler(name)
+}
+
+// QueryEvents returns the records of the event log selected by the query,
+// oldest first.
+func (s *scheduler) QueryEvents(q core.EventQuery) []core.EventRecord {
+	return s.eventLog.query(q)
 }
 
 // CreateTask creates and returns task
@@ -332,15 +548,43 @@ func (s *scheduler) createTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap, s
 		f.Error("Unable to generate workflow from workflow map")
 		return nil, te
 	}
+	wf.setFileSinkDir(s.fileSinkDir)
 
-	// Create the task object
-	task, err := newTask(sch, wf, s.workManager, s.metricManager, s.eventManager, opts...)
+	// Create the task object; a task imported with its id keeps it and the
+	// options of the task take precedence over the default ones
+	defaults := append([]core.TaskOption{withTaskID(s.taskIDs.NewTaskID())}, s.taskDefaults.get()...)
+	task, err := newTask(sch, wf, s.workManager, s.metricManager, s.eventBatcher, defaults...)
 	if err != nil {
 		te.errs = append(te.errs, serror.New(err))
 		f := buildErrorsLog(te.Errors(), logger)
 		f.Error("Unable to create task")
 		return nil, te
 	}
+	applyTaskOptions(task, opts)
+	task.setLeaderElection(s.leaderElection)
+	task.exportPolicy = s.exportPolicy
+	task.tracer = s.tracer
+	task.defaultTimeouts = s.timeouts
+	task.maintenance = s.maintenance
+	task.inflight = s.inflight
+	task.dedup = s.dedup
+	task.cardinality = s.cardinality
+	task.tenantLimiter = s.tenants.limiter(core.TenantOf(task))
+	task.setClock(s.clock)
+	if rs, ok := s.metricManager.(resolvesSecrets); ok {
+		id := task.id
+		wf.setSinkSecrets(func(value string) (string, error) {
+			return rs.ResolveSecret(id, value)
+		})
+	}
+
+	// Select the versions of the metrics requested with version constraints
+	if _, errs := resolveVersions(task.metricsManager, wf.metrics); len(errs) > 0 {
+		te.errs = append(te.errs, errs...)
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error("Unable to resolve version constraints")
+		return nil, te
+	}
 
 	// subscribedPluginAsserts includes rules that need to be evaluated once we
 	// have mapped the metrics to specific collector plugins.  Examples include
@@ -407,8 +651,7 @@ func (s *scheduler) createTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap, s
 			te.errs = append(te.errs, serror.New(err))
 			return nil, te
 		}
-		var errs []serror.SnapError
-		errs = manager.ValidateDeps(group.requestedMetrics, group.subscribedPlugins, wf.configTree, subscribedPluginAsserts...)
+		errs := validateDeps(manager, group.requestedMetrics, group.subscribedPlugins, wf.configTree, subscribedPluginAsserts...)
 
 		if len(errs) > 0 {
 			te.errs = append(te.errs, errs...)
@@ -416,8 +659,99 @@ func (s *scheduler) createTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap, s
 		}
 	}
 
+	// Cap the metrics published by the task
+	if task.publishRateLimit != nil {
+		l, err := newPublishLimiter(task.publishRateLimit)
+		if err != nil {
+			te.errs = append(te.errs, serror.New(err, map[string]interface{}{"metrics-per-second": task.publishRateLimit.MetricsPerSecond}))
+			f := buildErrorsLog(te.Errors(), logger)
+			f.Error("Invalid publish rate limit")
+			return nil, te
+		}
+		task.publishLimiter = l
+	}
+
+	// Limit the namespaces collected by the task
+	if task.cardinalityLimit != nil {
+		if err := validateCardinalityLimit(task.cardinalityLimit); err != nil {
+			te.errs = append(te.errs, serror.New(err, map[string]interface{}{"max-namespaces": task.cardinalityLimit.MaxNamespaces, "mode": task.cardinalityLimit.Mode}))
+			f := buildErrorsLog(te.Errors(), logger)
+			f.Error("Invalid cardinality limit")
+			return nil, te
+		}
+	}
+
+	// Run the task on its dedicated queue
+	if task.queueName != "" {
+		m, err := s.dedicatedQueues.acquire(task.queueName, task.queueSize)
+		if err != nil {
+			te.errs = append(te.errs, serror.New(err, map[string]interface{}{"queue-name": task.queueName}))
+			f := buildErrorsLog(te.Errors(), logger)
+			f.Error("Unable to get dedicated queue")
+			return nil, te
+		}
+		task.manager = m
+	}
+
+	// Run the collect jobs of the task on its pinned thread
+	if task.pinned != nil {
+		pt, err := newPinnedThread(task.id, task.pinned.CPUs)
+		if err != nil {
+			if task.queueName != "" {
+				s.dedicatedQueues.release(task.queueName)
+			}
+			te.errs = append(te.errs, serror.New(err, map[string]interface{}{"cpus": task.pinned.CPUs}))
+			f := buildErrorsLog(te.Errors(), logger)
+			f.Error("Unable to start pinned thread")
+			return nil, te
+		}
+		task.pinnedThread = pt
+	}
+
+	// Count the task against the quotas of the scheduler
+	if err := s.quotas.reserve(task.id, taskPlugins(task.metricsManager, depGroups), taskMetrics(wf.metrics)); err != nil {
+		if task.queueName != "" {
+			s.dedicatedQueues.release(task.queueName)
+		}
+		if task.pinnedThread != nil {
+			task.pinnedThread.stop()
+		}
+		if qe, ok := err.(*QuotaExceededError); ok {
+			te.errs = append(te.errs, qe)
+		} else {
+			te.errs = append(te.errs, serror.New(err))
+		}
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error("errors during task creation")
+		return nil, te
+	}
+
+	// Count the task against the quota of its tenant
+	tenant := core.TenantOf(task)
+	if err := s.tenants.reserve(tenant); err != nil {
+		s.quotas.release(task.id)
+		if task.queueName != "" {
+			s.dedicatedQueues.release(task.queueName)
+		}
+		if task.pinnedThread != nil {
+			task.pinnedThread.stop()
+		}
+		te.errs = append(te.errs, serror.New(err, map[string]interface{}{"tenant": tenant}))
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error("Tenant quota exceeded")
+		return nil, te
+	}
+
 	// Add task to taskCollection
 	if err := s.tasks.add(task); err != nil {
+		s.quotas.release(task.id)
+		s.tenants.release(tenant)
+		if task.queueName != "" {
+			s.dedicatedQueues.release(task.queueName)
+		}
+		if task.pinnedThread != nil {
+			task.pinnedThread.stop()
+		}
 		te.errs = append(te.errs, serror.New(err))
 		f := buildErrorsLog(te.Errors(), logger)
 		f.Error("errors during task creation")
@@ -451,6 +785,108 @@ func (s *scheduler) createTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap, s
 	return task, te
 }
 
+// CloneTask creates a new task with the workflow map, schedule and options of
+// the task with the given id.  The overrides are applied on top of the copied
+// settings.  The new task is returned with a new ID.
+func (s *scheduler) CloneTask(id string, overrides core.TaskOverrides) (core.Task, core.TaskErrors) {
+	te := &taskErrors{
+		errs: make([]serror.SnapError, 0),
+	}
+	t, err := s.getTask(id)
+	if err != nil {
+		schedulerLogger.WithFields(log.Fields{
+			"_block":  "clone-task",
+			"_error":  ErrTaskNotFound,
+			"task-id": id,
+		}).Error("error cloning task")
+		te.errs = append(te.errs, serror.New(err))
+		return nil, te
+	}
+
+	sch, err := copySchedule(t.schedule, overrides.Interval)
+	if err != nil {
+		te.errs = append(te.errs, serror.New(err, map[string]interface{}{"task-id": id}))
+		return nil, te
+	}
+	wfMap, err := copyWorkflowMap(t.workflow.workflowMap)
+	if err != nil {
+		te.errs = append(te.errs, serror.New(err, map[string]interface{}{"task-id": id}))
+		return nil, te
+	}
+
+	opts := t.options()
+	if overrides.Name != "" {
+		opts = append(opts, core.SetTaskName(overrides.Name))
+	}
+	opts = append(opts, overrides.Options...)
+
+	schedulerLogger.WithFields(log.Fields{
+		"_block":  "clone-task",
+		"task-id": id,
+	}).Debug("cloning task")
+	return s.CreateTask(sch, wfMap, overrides.Start, opts...)
+}
+
+// ExportTask returns a portable manifest of the task with the given id in the
+// given format ("json" or "yaml").  The manifest can be imported on any snap
+// instance with ImportTask.
+func (s *scheduler) ExportTask(id, format string) ([]byte, error) {
+	t, err := s.getTask(id)
+	if err != nil {
+		schedulerLogger.WithFields(log.Fields{
+			"_block":  "export-task",
+			"_error":  ErrTaskNotFound,
+			"task-id": id,
+		}).Error("error exporting task")
+		return nil, err
+	}
+	return core.ExportTask(t, format)
+}
+
+// ImportTask creates a task from a manifest produced by ExportTask.  The
+// imported task is given a new ID.
+func (s *scheduler) ImportTask(content []byte) (core.Task, error) {
+	return core.ImportTask(content, nil, s.CreateTask, s)
+}
+
+// CreateTaskFromManifest creates a task from a JSON or YAML task manifest,
+// which holds the schedule, the workflow and the options of the task along
+// with the version of the manifest schema.  The task is started when the
+// manifest says so.
+func (s *scheduler) CreateTaskFromManifest(r io.Reader) (core.Task, error) {
+	return core.CreateTaskFromManifest(r, nil, s.CreateTask, s)
+}
+
+// CreateTaskFromManifestFile creates a task from the task manifest in the
+// file at path (see CreateTaskFromManifest).
+func (s *scheduler) CreateTaskFromManifestFile(path string) (core.Task, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := s.CreateTaskFromManifest(f)
+	if err != nil {
+		schedulerLogger.WithFields(log.Fields{
+			"_block":   "create-task-from-manifest",
+			"_error":   err.Error(),
+			"manifest": path,
+		}).Error("error creating task")
+		return nil, err
+	}
+	return t, nil
+}
+
+// AdmitTask expands the task template the request refers to, if any, then
+// the task preset it refers to, if any, from the presets of the scheduler
+// configuration.
+func (s *scheduler) AdmitTask(tr *core.TaskCreationRequest) error {
+	if err := s.expandTemplate(tr); err != nil {
+		return err
+	}
+	return s.presets.AdmitTask(tr)
+}
+
 // RemoveTask given a tasks id.  The task must be stopped.
 // Can return errors ErrTaskNotFound and ErrTaskNotStopped.
 func (s *scheduler) RemoveTask(id string) error {
@@ -479,7 +915,31 @@ func (s *scheduler) removeTask(id, source string) error {
 	}
 
 	defer s.eventManager.Emit(event)
-	return s.tasks.remove(t)
+	s.standby.remove(t.ID())
+	if err := s.tasks.remove(t); err != nil {
+		return err
+	}
+	// the runs in flight no longer track the task once it is forgotten
+	t.markRemoved()
+	// the dependencies of the task are unsubscribed when it stops; the ones
+	// left behind, e.g. by a failed unsubscription, are released with it
+	t.UnsubscribePlugins()
+	s.latencies.forget(t.ID())
+	s.backfills.forget(t.ID())
+	s.dedup.forget(t.ID())
+	if t.workflow != nil {
+		t.workflow.closeSinks()
+	}
+	s.cardinality.forget(t.ID())
+	s.quotas.release(t.ID())
+	s.tenants.release(core.TenantOf(t))
+	if t.queueName != "" {
+		s.dedicatedQueues.release(t.queueName)
+	}
+	if t.pinnedThread != nil {
+		t.pinnedThread.stop()
+	}
+	return nil
 }
 
 // GetTasks returns a copy of the tasks in a map where the task id is the key
@@ -491,6 +951,19 @@ func (s *scheduler) GetTasks() map[string]core.Task {
 	return tasks
 }
 
+// ListTasks returns the tasks selected by the options, in the order of their
+// ids, and the id to list the next page of tasks after, empty on the last
+// page.  Unlike GetTasks, the tasks selected by name or label are looked up
+// in indexes rather than in a copy of all the tasks.
+func (s *scheduler) ListTasks(o core.TaskListOptions) ([]core.Task, string) {
+	ts, next := s.tasks.list(o)
+	tasks := make([]core.Task, len(ts))
+	for i, t := range ts {
+		tasks[i] = t
+	}
+	return tasks, next
+}
+
 // GetTask provided the task id a task is returned
 func (s *scheduler) GetTask(id string) (core.Task, error) {
 	t, err := s.getTask(id)
@@ -532,7 +1005,8 @@ func (s *scheduler) startTask(id, source string) []serror.SnapError {
 		}
 	}
 
-	if t.state == core.TaskDisabled {
+	state := t.State()
+	if state == core.TaskDisabled {
 		logger.WithFields(log.Fields{
 			"task-id": t.ID(),
 		}).Error("Task is disabled and must be enabled before starting")
@@ -541,10 +1015,10 @@ func (s *scheduler) startTask(id, source string) []serror.SnapError {
 		}
 	}
 
-	if t.state == core.TaskFiring || t.state == core.TaskSpinning {
+	if isStarted(state) {
 		logger.WithFields(log.Fields{
 			"task-id":    t.ID(),
-			"task-state": t.State(),
+			"task-state": state,
 		}).Info("task is already running")
 		return []serror.SnapError{
 			serror.New(ErrTaskAlreadyRunning),
@@ -561,6 +1035,21 @@ func (s *scheduler) startTask(id, source string) []serror.SnapError {
 		return errs
 	}
 
+	// the task is started by the member of the cluster owning it; singleton
+	// tasks run on every member and are fired by their leader
+	if !t.singleton && !s.owns(t.ID()) {
+		s.standby.add(t.ID())
+		event := &scheduler_event.TaskStartedEvent{
+			TaskID: t.ID(),
+			Source: source,
+		}
+		defer s.eventManager.Emit(event)
+		logger.WithFields(log.Fields{
+			"task-id": t.ID(),
+		}).Info("task is run by another member of the cluster")
+		return nil
+	}
+
 	// subscribe plugins to task
 	if _, err := t.SubscribePlugins(); len(err) != 0 {
 		return err
@@ -604,7 +1093,23 @@ func (s *scheduler) stopTask(id, source string) []serror.SnapError {
 		}
 	}
 
-	switch t.state {
+	// a task in standby is not running on this node
+	if s.standby.remove(t.ID()) {
+		event := &scheduler_event.TaskStoppedEvent{
+			TaskID:     t.ID(),
+			TaskName:   t.GetName(),
+			TaskLabels: t.GetLabels(),
+			Source:     source,
+			Why:        stopReason(source),
+		}
+		defer s.eventManager.Emit(event)
+		logger.WithFields(log.Fields{
+			"task-id": t.ID(),
+		}).Info("task stopped")
+		return nil
+	}
+
+	switch t.State() {
 	case core.TaskStopped:
 		logger.WithFields(log.Fields{
 			"task-id":    t.ID(),
@@ -630,7 +1135,16 @@ func (s *scheduler) stopTask(id, source string) []serror.SnapError {
 			serror.New(ErrTaskDisabledNotStoppable),
 		}
 	default:
-		t.Stop()
+		if err := t.stopFrom(source); err != nil {
+			logger.WithFields(log.Fields{
+				"_error":     err.Error(),
+				"task-id":    t.ID(),
+				"task-state": t.State(),
+			}).Error("error stopping task")
+			return []serror.SnapError{
+				serror.New(err),
+			}
+		}
 		logger.WithFields(log.Fields{
 			"task-id":    t.ID(),
 			"task-state": t.State(),
@@ -679,6 +1193,7 @@ func (s *scheduler) Start() error {
 		return ErrMetricManagerNotSet
 	}
 	s.state = schedulerStarted
+	s.eventBatcher.start()
 	schedulerLogger.WithFields(log.Fields{
 		"_block": "start-scheduler",
 	}).Info("scheduler started")
@@ -723,7 +1238,7 @@ func (s *scheduler) Start() error {
 				}
 				taskFiles = append(taskFiles, file)
 			}
-			autoDiscoverTasks(taskFiles, fullPath, s.CreateTask)
+			autoDiscoverTasks(taskFiles, fullPath, s.CreateTask, s)
 		}
 	} else {
 		schedulerLogger.WithFields(log.Fields{
@@ -737,10 +1252,14 @@ func (s *scheduler) Start() error {
 func (s *scheduler) Stop() {
 	s.state = schedulerStopped
 	// stop all tasks that are not already stopped
-	for _, t := range s.tasks.table {
+	for _, t := range s.tasks.Table() {
 		// Kill ensure another task can't turn it back on while we are shutting down
 		t.Kill()
 	}
+	// and the workers running their jobs
+	s.workManager.shutdown()
+	s.eventBatcher.halt()
+	s.tracer.Stop()
 	schedulerLogger.WithFields(log.Fields{
 		"_block": "stop-scheduler",
 	}).Info("scheduler stopped")
@@ -772,6 +1291,16 @@ func (s *scheduler) WatchTask(id string, tw core.TaskWatcherHandler) (core.TaskW
 func (s *scheduler) HandleGomitEvent(e gomit.Event) {
 
 	switch v := e.Body.(type) {
+	case *scheduler_event.EventsBatchedEvent:
+		log.WithFields(log.Fields{
+			"_module":         "scheduler-events",
+			"_block":          "handle-events",
+			"event-namespace": e.Namespace(),
+			"event-count":     len(v.Events),
+		}).Debug("event received")
+		for _, body := range v.Events {
+			s.HandleGomitEvent(gomit.Event{Header: e.Header, Body: body})
+		}
 	case *scheduler_event.MetricCollectedEvent:
 		log.WithFields(log.Fields{
 			"_module":         "scheduler-events",
@@ -780,7 +1309,8 @@ func (s *scheduler) HandleGomitEvent(e gomit.Event) {
 			"task-id":         v.TaskID,
 			"metric-count":    len(v.Metrics),
 		}).Debug("event received")
-		s.taskWatcherColl.handleMetricCollected(v.TaskID, v.Metrics)
+		// the metrics restricted by the export policy are not streamed
+		s.taskWatcherColl.handleMetricCollected(v.TaskID, s.exportPolicy.unrestricted(v.Metrics))
 	case *scheduler_event.MetricCollectionFailedEvent:
 		log.WithFields(log.Fields{
 			"_module":         "scheduler-events",
@@ -805,8 +1335,9 @@ func (s *scheduler) HandleGomitEvent(e gomit.Event) {
 			"task-id":         v.TaskID,
 		}).Debug("event received")
 		// We need to unsubscribe from deps when a task has stopped
-		task, _ := s.getTask(v.TaskID)
-		task.UnsubscribePlugins()
+		if task, err := s.getTask(v.TaskID); err == nil {
+			task.UnsubscribePlugins()
+		}
 		s.taskWatcherColl.handleTaskStopped(v.TaskID)
 	case *scheduler_event.TaskEndedEvent:
 		log.WithFields(log.Fields{
@@ -816,8 +1347,9 @@ func (s *scheduler) HandleGomitEvent(e gomit.Event) {
 			"task-id":         v.TaskID,
 		}).Debug("event received")
 		// We need to unsubscribe from deps when a task has ended
-		task, _ := s.getTask(v.TaskID)
-		task.UnsubscribePlugins()
+		if task, err := s.getTask(v.TaskID); err == nil {
+			task.UnsubscribePlugins()
+		}
 		s.taskWatcherColl.handleTaskEnded(v.TaskID)
 	case *scheduler_event.TaskDisabledEvent:
 		log.WithFields(log.Fields{
@@ -828,9 +1360,68 @@ func (s *scheduler) HandleGomitEvent(e gomit.Event) {
 			"disabled-reason": v.Why,
 		}).Debug("event received")
 		// We need to unsubscribe from deps when a task goes disabled
-		task, _ := s.getTask(v.TaskID)
-		task.UnsubscribePlugins()
+		if task, err := s.getTask(v.TaskID); err == nil {
+			task.UnsubscribePlugins()
+		}
 		s.taskWatcherColl.handleTaskDisabled(v.TaskID, v.Why)
+	case *control_event.PluginFrozenEvent:
+		key := fmt.Sprintf("%s"+core.Separator+"%s"+core.Separator+"%d", plugin.PluginType(v.Type).String(), v.Name, v.Version)
+		log.WithFields(log.Fields{
+			"_module":         "scheduler-events",
+			"_block":          "handle-events",
+			"event-namespace": e.Namespace(),
+			"plugin":          key,
+			"impacted-tasks":  v.ImpactedTasks,
+		}).Debug("event received")
+		for _, id := range v.ImpactedTasks {
+			if t, err := s.getTask(id); err == nil {
+				t.setDegraded(key, fmt.Sprintf("plugin %s:%s:%d is frozen", plugin.PluginType(v.Type).String(), v.Name, v.Version))
+			}
+		}
+	case *control_event.PluginThawedEvent:
+		key := fmt.Sprintf("%s"+core.Separator+"%s"+core.Separator+"%d", plugin.PluginType(v.Type).String(), v.Name, v.Version)
+		log.WithFields(log.Fields{
+			"_module":         "scheduler-events",
+			"_block":          "handle-events",
+			"event-namespace": e.Namespace(),
+			"plugin":          key,
+		}).Debug("event received")
+		for _, id := range v.ImpactedTasks {
+			if t, err := s.getTask(id); err == nil {
+				t.setDegraded(key, "")
+			}
+		}
+	case *control_event.LoadPluginEvent:
+		log.WithFields(log.Fields{
+			"_module":         "scheduler-events",
+			"_block":          "handle-events",
+			"event-namespace": e.Namespace(),
+			"plugin-name":     v.Name,
+			"plugin-version":  v.Version,
+		}).Debug("event received")
+		// a newly loaded version may satisfy version constraints better
+		s.resolveTaskVersions()
+	case *control_event.SwapPluginsEvent:
+		log.WithFields(log.Fields{
+			"_module":         "scheduler-events",
+			"_block":          "handle-events",
+			"event-namespace": e.Namespace(),
+			"plugin-name":     v.LoadedPluginName,
+			"plugin-version":  v.LoadedPluginVersion,
+		}).Debug("event received")
+		s.resolveTaskVersions()
+	case *control_event.TaskMetricsResolvedEvent:
+		// the tasks collect the metrics resolved again from their next run
+		log.WithFields(log.Fields{
+			"_module":         "scheduler-events",
+			"_block":          "handle-events",
+			"event-namespace": e.Namespace(),
+			"task-id":         v.TaskId,
+			"added-metrics":   len(v.Added),
+			"removed-metrics": len(v.Removed),
+		}).Debug("event received")
+	case *scheduler_event.TaskRunCompletedEvent:
+		s.latencies.record(v.TaskID, v.Duration)
 	case *scheduler_event.PluginsUnsubscribedEvent:
 		log.WithFields(log.Fields{
 			"_module":         "scheduler-events",
@@ -869,6 +1460,11 @@ func getWorkflowPlugins(prnodes []*processNode, pbnodes []*publishNode, requeste
 
 func walkWorkflowForDeps(prnodes []*processNode, pbnodes []*publishNode, requestedMetrics []core.RequestedMetric, depGroup depGroupMap) depGroupMap {
 	for _, pr := range prnodes {
+		// built-in processors need no plugin
+		if pr.transform != nil {
+			walkWorkflowForDeps(pr.ProcessNodes, pr.PublishNodes, requestedMetrics, depGroup)
+			continue
+		}
 		processors := depGroup[pr.Target]
 		if _, ok := depGroup[pr.Target]; ok {
 			processors.subscribedPlugins = append(processors.subscribedPlugins, pr)
@@ -879,6 +1475,10 @@ func walkWorkflowForDeps(prnodes []*processNode, pbnodes []*publishNode, request
 		walkWorkflowForDeps(pr.ProcessNodes, pr.PublishNodes, requestedMetrics, depGroup)
 	}
 	for _, pb := range pbnodes {
+		// built-in publishers need no plugin
+		if pb.sink != nil {
+			continue
+		}
 		publishers := depGroup[pb.Target]
 		if _, ok := depGroup[pb.Target]; ok {
 			publishers.subscribedPlugins = append(publishers.subscribedPlugins, pb)
@@ -890,6 +1490,105 @@ func walkWorkflowForDeps(prnodes []*processNode, pbnodes []*publishNode, request
 	return depGroup
 }
 
+// copySchedule returns a new, not yet validated, schedule with the settings of
+// the given schedule.  A non zero interval replaces the interval of a
+// windowed schedule.
+func copySchedule(sch schedule.Schedule, interval time.Duration) (schedule.Schedule, error) {
+	switch v := sch.(type) {
+	case *schedule.WindowedSchedule:
+		if interval == 0 {
+			interval = v.Interval
+		}
+		ws := schedule.NewWindowedSchedule(interval, v.StartTime, v.StopTime, v.Count)
+		ws.Aligned = v.Aligned
+		return ws, nil
+	case *schedule.CronSchedule:
+		if interval != 0 {
+			return nil, ErrIntervalNotOverridable
+		}
+		return schedule.NewCronSchedule(v.Entry()), nil
+	case *schedule.StreamingSchedule:
+		if interval != 0 {
+			return nil, ErrIntervalNotOverridable
+		}
+		return schedule.NewStreamingSchedule(), nil
+	case *schedule.CompositeSchedule:
+		// the schedules of a composite schedule have their own intervals
+		if interval != 0 {
+			return nil, ErrIntervalNotOverridable
+		}
+		schedules := make([]schedule.Schedule, len(v.Schedules))
+		for i, sub := range v.Schedules {
+			c, err := copySchedule(sub, 0)
+			if err != nil {
+				return nil, err
+			}
+			schedules[i] = c
+		}
+		return schedule.NewCompositeSchedule(schedules...), nil
+	default:
+		return nil, fmt.Errorf("unknown schedule type `%T`", sch)
+	}
+}
+
+// copyWorkflowMap returns a deep copy of the given workflow map.
+func copyWorkflowMap(wfMap *wmap.WorkflowMap) (*wmap.WorkflowMap, error) {
+	b, err := wfMap.ToJson()
+	if err != nil {
+		return nil, err
+	}
+	return wmap.FromJson(b)
+}
+
+// validateDeps validates the dependencies with the given manager.  If the
+// manager versions its catalog and the catalog changed during validation, the
+// validation is repeated so its result reflects a single catalog snapshot.
+func validateDeps(mgr managesMetrics, mts []core.RequestedMetric, plugins []core.SubscribedPlugin, cdt *cdata.ConfigDataTree, asserts ...core.SubscribedPluginAssert) []serror.SnapError {
+	vc, ok := mgr.(versionsCatalog)
+	if !ok {
+		return mgr.ValidateDeps(mts, plugins, cdt, asserts...)
+	}
+	for i := 0; ; i++ {
+		gen := vc.CatalogGeneration()
+		errs := mgr.ValidateDeps(mts, plugins, cdt, asserts...)
+		if vc.CatalogGeneration() == gen {
+			return errs
+		}
+		if i >= maxCatalogRetries {
+			return append(errs, serror.New(ErrCatalogChanged))
+		}
+		schedulerLogger.WithFields(log.Fields{
+			"_block":  "validate-deps",
+			"attempt": i + 1,
+		}).Debug("plugin catalog changed during validation, retrying")
+	}
+}
+
+// subscribeDeps subscribes the dependencies with the given manager.  If the
+// subscription fails while the manager's catalog is changing the
+// subscription is retried against the new catalog.
+func subscribeDeps(mgr managesMetrics, id string, mts []core.RequestedMetric, plugins []core.SubscribedPlugin, cdt *cdata.ConfigDataTree) []serror.SnapError {
+	vc, ok := mgr.(versionsCatalog)
+	if !ok {
+		return mgr.SubscribeDeps(id, mts, plugins, cdt)
+	}
+	for i := 0; ; i++ {
+		gen := vc.CatalogGeneration()
+		errs := mgr.SubscribeDeps(id, mts, plugins, cdt)
+		if len(errs) == 0 || vc.CatalogGeneration() == gen {
+			return errs
+		}
+		if i >= maxCatalogRetries {
+			return append(errs, serror.New(ErrCatalogChanged))
+		}
+		schedulerLogger.WithFields(log.Fields{
+			"_block":  "subscribe-deps",
+			"task-id": id,
+			"attempt": i + 1,
+		}).Debug("plugin catalog changed during subscription, retrying")
+	}
+}
+
 func returnCorePlugin(plugins []core.SubscribedPlugin) []core.Plugin {
 	cps := make([]core.Plugin, len(plugins))
 	for i, plugin := range plugins {
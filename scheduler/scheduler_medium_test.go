@@ -22,16 +22,27 @@ limitations under the License.
 package scheduler
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/ghodss/yaml"
+	"github.com/intelsdi-x/gomit"
 	"github.com/sirupsen/logrus"
 	. "github.com/smartystreets/goconvey/convey"
 
+	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/control_event"
 	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/core/scheduler_event"
 	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/pkg/schedule"
 	"github.com/intelsdi-x/snap/scheduler/fixtures"
@@ -372,7 +383,7 @@ func TestStopTask(t *testing.T) {
 		Convey("Should not return an error", func() {
 			So(err, ShouldBeNil)
 		})
-		time.Sleep(100 * time.Millisecond)
+		// StopTask returns once the task is stopped
 		Convey("State of the task should be TaskStopped", func() {
 			So(tsk.State(), ShouldEqual, core.TaskStopped)
 		})
@@ -391,8 +402,9 @@ func TestStopTask(t *testing.T) {
 			time.Sleep(100 * time.Millisecond)
 			So(t.State(), ShouldResemble, core.TaskFiring)
 
-			// stop task when task state is firing
-			t.(*task).Stop()
+			// stop task when task state is firing; Stop waits for the task
+			// to be stopped, which emits the event read below
+			go t.(*task).Stop()
 			// the last scheduled workflow execution should be allowed to finish
 			// so we expect that stopping the task is going to happen not early than 500ms (set by by timeToWait)
 
@@ -425,6 +437,28 @@ func TestStopTask(t *testing.T) {
 			So(err[0].Error(), ShouldEqual, ErrTaskAlreadyStopped.Error())
 		})
 	})
+	Convey("Calling Stop on a task whose routine does not exit", t, func() {
+		sch := schedule.NewWindowedSchedule(interval, nil, nil, 0)
+		tsk, _ := s.CreateTask(sch, w, false)
+		So(tsk, ShouldNotBeNil)
+		task := s.tasks.Get(tsk.ID())
+		timeout := stopTimeout
+		stopTimeout = 10 * time.Millisecond
+		defer func() { stopTimeout = timeout }()
+		task.Lock()
+		task.state = core.TaskSpinning
+		task.killChan = make(chan struct{})
+		task.doneChan = make(chan struct{})
+		task.Unlock()
+
+		err := task.Stop()
+		Convey("Should return an error", func() {
+			So(err, ShouldEqual, ErrTaskStopTimeout)
+		})
+		Convey("State of the task should be still TaskStopping", func() {
+			So(task.State(), ShouldEqual, core.TaskStopping)
+		})
+	})
 	Convey("Calling StopTask on a disabled task", t, func() {
 		sch := schedule.NewWindowedSchedule(interval, nil, nil, 0)
 		tskDisabled, _ := s.CreateTask(sch, w, false)
@@ -668,3 +702,553 @@ func TestEnableTask(t *testing.T) {
 
 	s.Stop()
 }
+
+func TestCloneTask(t *testing.T) {
+	s := newScheduler()
+	s.Start()
+	w := newMockWorkflowMap()
+
+	Convey("Calling CloneTask", t, func() {
+		sch := schedule.NewWindowedSchedule(interval, nil, nil, 0)
+		tsk, errs := s.CreateTask(sch, w, false, core.TaskDeadlineDuration(time.Second*7), core.SetTaskName("original"))
+		So(errs.Errors(), ShouldBeEmpty)
+
+		Convey("copies the schedule, workflow and options of the task", func() {
+			clone, errs := s.CloneTask(tsk.ID(), core.TaskOverrides{})
+			So(errs.Errors(), ShouldBeEmpty)
+			So(clone.ID(), ShouldNotEqual, tsk.ID())
+			So(clone.DeadlineDuration(), ShouldEqual, time.Second*7)
+			So(clone.Schedule().(*schedule.WindowedSchedule).Interval, ShouldEqual, interval)
+			So(clone.WMap(), ShouldNotPointTo, tsk.WMap())
+			cj, _ := clone.WMap().ToJson()
+			tj, _ := tsk.WMap().ToJson()
+			So(string(cj), ShouldEqual, string(tj))
+			So(clone.State(), ShouldEqual, core.TaskStopped)
+		})
+		Convey("applies the given overrides", func() {
+			clone, errs := s.CloneTask(tsk.ID(), core.TaskOverrides{
+				Name:     "clone",
+				Interval: time.Second * 3,
+			})
+			So(errs.Errors(), ShouldBeEmpty)
+			So(clone.GetName(), ShouldEqual, "clone")
+			So(clone.Schedule().(*schedule.WindowedSchedule).Interval, ShouldEqual, time.Second*3)
+			So(tsk.Schedule().(*schedule.WindowedSchedule).Interval, ShouldEqual, interval)
+		})
+		Convey("keeps the alignment of the schedule", func() {
+			asch := schedule.NewWindowedSchedule(interval, nil, nil, 0)
+			asch.Aligned = true
+			atsk, errs := s.CreateTask(asch, w, false)
+			So(errs.Errors(), ShouldBeEmpty)
+			clone, errs := s.CloneTask(atsk.ID(), core.TaskOverrides{Interval: time.Second * 3})
+			So(errs.Errors(), ShouldBeEmpty)
+			So(clone.Schedule().(*schedule.WindowedSchedule).Aligned, ShouldBeTrue)
+		})
+		Convey("returns an error when the task does not exist", func() {
+			clone, errs := s.CloneTask("1234", core.TaskOverrides{})
+			So(clone, ShouldBeNil)
+			So(errs.Errors(), ShouldNotBeEmpty)
+		})
+		Convey("returns an error when overriding the interval of a streaming task", func() {
+			stsk, errs := s.CreateTask(schedule.NewStreamingSchedule(), w, false)
+			So(errs.Errors(), ShouldBeEmpty)
+			clone, errs := s.CloneTask(stsk.ID(), core.TaskOverrides{Interval: time.Second})
+			So(clone, ShouldBeNil)
+			So(errs.Errors()[0].Error(), ShouldEqual, ErrIntervalNotOverridable.Error())
+		})
+		Convey("copies each schedule of a composite schedule", func() {
+			sub := schedule.NewWindowedSchedule(interval, nil, nil, 0)
+			ctsk, errs := s.CreateTask(schedule.NewCompositeSchedule(sub, schedule.NewWindowedSchedule(time.Second, nil, nil, 3)), w, false)
+			So(errs.Errors(), ShouldBeEmpty)
+			clone, errs := s.CloneTask(ctsk.ID(), core.TaskOverrides{})
+			So(errs.Errors(), ShouldBeEmpty)
+			csch := clone.Schedule().(*schedule.CompositeSchedule)
+			So(csch, ShouldNotPointTo, ctsk.Schedule())
+			So(csch.Schedules, ShouldHaveLength, 2)
+			So(csch.Schedules[0], ShouldNotPointTo, sub)
+			So(csch.Schedules[0].(*schedule.WindowedSchedule).Interval, ShouldEqual, interval)
+			So(csch.Schedules[1].(*schedule.WindowedSchedule).Count, ShouldEqual, 3)
+
+			Convey("but not its interval", func() {
+				clone, errs := s.CloneTask(ctsk.ID(), core.TaskOverrides{Interval: time.Second})
+				So(clone, ShouldBeNil)
+				So(errs.Errors()[0].Error(), ShouldEqual, ErrIntervalNotOverridable.Error())
+			})
+		})
+	})
+
+	s.Stop()
+}
+
+func TestExportImportTask(t *testing.T) {
+	s := newScheduler()
+	s.Start()
+	w := newMockWorkflowMap()
+
+	Convey("Calling ExportTask", t, func() {
+		sch := schedule.NewWindowedSchedule(interval, nil, nil, 5)
+		tsk, errs := s.CreateTask(sch, w, false, core.TaskDeadlineDuration(time.Second*7), core.SetTaskName("exported"))
+		So(errs.Errors(), ShouldBeEmpty)
+
+		for _, format := range []string{"json", "yaml"} {
+			Convey(fmt.Sprintf("returns a %s manifest which can be imported", format), func() {
+				b, err := s.ExportTask(tsk.ID(), format)
+				So(err, ShouldBeNil)
+				So(string(b), ShouldNotContainSubstring, tsk.ID())
+
+				imported, err := s.ImportTask(b)
+				So(err, ShouldBeNil)
+				So(imported.ID(), ShouldNotEqual, tsk.ID())
+				So(imported.GetName(), ShouldEqual, "exported")
+				So(imported.DeadlineDuration(), ShouldEqual, time.Second*7)
+				isch := imported.Schedule().(*schedule.WindowedSchedule)
+				So(isch.Interval, ShouldEqual, interval)
+				So(isch.Count, ShouldEqual, 5)
+				ij, _ := imported.WMap().ToJson()
+				tj, _ := tsk.WMap().ToJson()
+				So(string(ij), ShouldEqual, string(tj))
+			})
+		}
+		Convey("returns an invalid manifest error for a malformed manifest", func() {
+			_, err := s.ImportTask([]byte(`{"version": 1, "deadline": 5}`))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, core.ErrInvalidTaskManifest.Error())
+		})
+		Convey("returns an error for an unknown format", func() {
+			_, err := s.ExportTask(tsk.ID(), "xml")
+			So(err, ShouldEqual, core.ErrUnknownManifestFormat)
+		})
+		Convey("returns an error when the task does not exist", func() {
+			_, err := s.ExportTask("1234", "json")
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	s.Stop()
+}
+
+func TestCreateTaskFromManifest(t *testing.T) {
+	s := newScheduler()
+	s.Start()
+
+	Convey("Calling CreateTaskFromManifest", t, func() {
+		manifest := `
+version: 1
+name: from-manifest
+deadline: 5s
+schedule:
+  type: simple
+  interval: 1s
+workflow:
+  collect:
+    metrics:
+      /foo/bar: {}
+    publish:
+      - plugin_name: file
+`
+		Convey("creates the task described in a YAML manifest", func() {
+			tsk, err := s.CreateTaskFromManifest(strings.NewReader(manifest))
+			So(err, ShouldBeNil)
+			So(tsk.GetName(), ShouldEqual, "from-manifest")
+			So(tsk.DeadlineDuration(), ShouldEqual, 5*time.Second)
+			So(tsk.State(), ShouldEqual, core.TaskStopped)
+		})
+		Convey("creates the task described in a JSON manifest", func() {
+			js, err := yaml.YAMLToJSON([]byte(manifest))
+			So(err, ShouldBeNil)
+			tsk, err := s.CreateTaskFromManifest(bytes.NewReader(js))
+			So(err, ShouldBeNil)
+			So(tsk.GetName(), ShouldEqual, "from-manifest")
+		})
+		Convey("creates the task described in a manifest file", func() {
+			f, err := ioutil.TempFile("", "manifest")
+			So(err, ShouldBeNil)
+			defer os.Remove(f.Name())
+			f.WriteString(manifest)
+			f.Close()
+			tsk, err := s.CreateTaskFromManifestFile(f.Name())
+			So(err, ShouldBeNil)
+			So(tsk.GetName(), ShouldEqual, "from-manifest")
+		})
+		Convey("returns an error when the manifest has no version", func() {
+			_, err := s.CreateTaskFromManifest(strings.NewReader(strings.Replace(manifest, "version: 1", "", 1)))
+			So(err, ShouldEqual, core.ErrMissingManifestVersion)
+		})
+		Convey("returns an error when the manifest version is not supported", func() {
+			_, err := s.CreateTaskFromManifest(strings.NewReader(strings.Replace(manifest, "version: 1", "version: 2", 1)))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, core.ErrUnsupportedManifestVersion.Error())
+		})
+		Convey("returns an error when the manifest file does not exist", func() {
+			_, err := s.CreateTaskFromManifestFile("/does/not/exist.yaml")
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	s.Stop()
+}
+
+func TestTaskFailurePolicy(t *testing.T) {
+	s := newScheduler()
+	s.Start()
+	w := newMockWorkflowMap()
+	events := &recordingHandler{}
+	s.RegisterEventHandler("failure-policy", events, scheduler_event.TaskDisabled, scheduler_event.TaskStopped, scheduler_event.TaskFailureLimit)
+
+	Convey("Given a task which reached its limit of consecutive failures", t, func() {
+		newFailingTask := func(opts ...core.TaskOption) *task {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false, opts...)
+			So(errs.Errors(), ShouldBeEmpty)
+			tk := tsk.(*task)
+			tk.state = core.TaskSpinning
+			tk.killChan = make(chan struct{})
+			tk.lastFailureMessage = "collection failed"
+			return tk
+		}
+		lastEvent := func() gomit.Event {
+			received := events.received()
+			So(received, ShouldNotBeEmpty)
+			return received[len(received)-1]
+		}
+
+		Convey("the task is disabled by default", func() {
+			tk := newFailingTask()
+			So(tk.handleFailureLimit("spin", 10), ShouldBeTrue)
+			So(tk.State(), ShouldEqual, core.TaskDisabled)
+			So(lastEvent().Namespace(), ShouldEqual, scheduler_event.TaskDisabled)
+		})
+		Convey("the task is stopped with the stop policy", func() {
+			tk := newFailingTask(core.OptionFailurePolicy(core.FailurePolicyStop))
+			So(tk.handleFailureLimit("spin", 10), ShouldBeTrue)
+			So(tk.State(), ShouldEqual, core.TaskStopped)
+			So(lastEvent().Namespace(), ShouldEqual, scheduler_event.TaskStopped)
+		})
+		Convey("the task keeps running with the alert-and-continue policy", func() {
+			tk := newFailingTask(core.OptionFailurePolicy(core.FailurePolicyAlert))
+			So(tk.handleFailureLimit("spin", 10), ShouldBeFalse)
+			So(tk.State(), ShouldEqual, core.TaskSpinning)
+			alert, ok := lastEvent().Body.(*scheduler_event.TaskFailureLimitReachedEvent)
+			So(ok, ShouldBeTrue)
+			So(alert.TaskID, ShouldEqual, tk.ID())
+			So(alert.ConsecutiveFailures, ShouldEqual, 10)
+			So(alert.Why, ShouldEqual, "collection failed")
+		})
+		Convey("the task resumes after the cooldown with the restart-after-cooldown policy", func() {
+			tk := newFailingTask(
+				core.OptionFailurePolicy(core.FailurePolicyRestart),
+				core.OptionFailureCooldown(10*time.Millisecond))
+			So(tk.handleFailureLimit("spin", 10), ShouldBeFalse)
+			So(tk.State(), ShouldEqual, core.TaskSpinning)
+			So(lastEvent().Namespace(), ShouldEqual, scheduler_event.TaskFailureLimit)
+
+			Convey("unless it is stopped during the cooldown", func() {
+				tk.Option(core.OptionFailureCooldown(time.Hour))
+				close(tk.killChan)
+				So(tk.handleFailureLimit("spin", 10), ShouldBeTrue)
+				So(tk.State(), ShouldEqual, core.TaskStopped)
+			})
+		})
+	})
+
+	s.Stop()
+}
+
+func TestFrozenPluginDegradesTasks(t *testing.T) {
+	s := newScheduler()
+	s.Start()
+	w := newMockWorkflowMap()
+
+	Convey("Given a task depending on a plugin", t, func() {
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+		So(tk.Degraded(), ShouldBeEmpty)
+
+		Convey("the task is degraded while the plugin is frozen", func() {
+			s.HandleGomitEvent(gomit.Event{Body: &control_event.PluginFrozenEvent{
+				Name:          "mock",
+				Version:       1,
+				Type:          int(plugin.CollectorPluginType),
+				ImpactedTasks: []string{tk.ID()},
+			}})
+			So(tk.Degraded(), ShouldResemble, []string{"plugin collector:mock:1 is frozen"})
+
+			s.HandleGomitEvent(gomit.Event{Body: &control_event.PluginThawedEvent{
+				Name:          "mock",
+				Version:       1,
+				Type:          int(plugin.CollectorPluginType),
+				ImpactedTasks: []string{tk.ID()},
+			}})
+			So(tk.Degraded(), ShouldBeEmpty)
+		})
+	})
+
+	s.Stop()
+}
+
+type failingMetricManager struct {
+	*mockMetricManager
+	collectErr error
+	publishErr error
+	// partial returns the metrics of the collectors which succeeded along
+	// with collectErr
+	partial bool
+}
+
+func (m *failingMetricManager) CollectMetrics(string, map[string]map[string]string) ([]core.Metric, []error) {
+	mts := []core.Metric{plugin.MetricType{Namespace_: core.NewNamespace("foo", "bar")}}
+	if m.collectErr != nil {
+		if m.partial {
+			return mts, []error{m.collectErr}
+		}
+		return nil, []error{m.collectErr}
+	}
+	return mts, nil
+}
+
+func (m *failingMetricManager) PublishMetrics([]core.Metric, map[string]ctypes.ConfigValue, string, string, int) []error {
+	if m.publishErr != nil {
+		return []error{m.publishErr}
+	}
+	return nil
+}
+
+func TestTaskRunResult(t *testing.T) {
+	Convey("Given a task", t, func() {
+		s := New(GetDefaultConfig())
+		mm := &failingMetricManager{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newMockWorkflowMap(), false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+		tk.killChan = make(chan struct{})
+		So(tk.LastRunResult(), ShouldBeNil)
+
+		Convey("a successful run is reported as such", func() {
+			r := tk.fire()
+			So(r.Success(), ShouldBeTrue)
+			So(r.Partial(), ShouldBeFalse)
+			So(r.Collected, ShouldEqual, 1)
+			So(tk.FailedCount(), ShouldEqual, 0)
+			So(tk.LastRunResult(), ShouldEqual, r)
+		})
+		Convey("a run whose collection fails is a failure", func() {
+			mm.collectErr = errors.New("collect failed")
+			r := tk.fire()
+			So(r.Success(), ShouldBeFalse)
+			So(r.Partial(), ShouldBeFalse)
+			So(r.LastError(), ShouldEqual, "collect failed")
+			So(tk.FailedCount(), ShouldEqual, 1)
+		})
+		Convey("a run whose publishing fails is a partial failure counted once", func() {
+			mm.publishErr = errors.New("publish failed")
+			r := tk.fire()
+			So(r.Success(), ShouldBeFalse)
+			So(r.Partial(), ShouldBeTrue)
+			So(len(r.Errors), ShouldBeGreaterThan, 1)
+			So(tk.FailedCount(), ShouldEqual, 1)
+			So(tk.LastFailureMessage(), ShouldEqual, "publish failed")
+		})
+		Convey("a run where some collectors fail publishes the metrics of the others", func() {
+			mm.collectErr = serror.New(errors.New("collect failed"), map[string]interface{}{
+				"plugin-type":    "collector",
+				"plugin-name":    "mock",
+				"plugin-version": 2,
+			})
+			mm.partial = true
+			r := tk.fire()
+			So(r.Success(), ShouldBeFalse)
+			So(r.Partial(), ShouldBeTrue)
+			So(r.Collected, ShouldEqual, 1)
+			So(r.FailedPlugins, ShouldResemble, []string{"collector:mock:2"})
+			So(tk.FailedCount(), ShouldEqual, 1)
+		})
+
+		s.Stop()
+	})
+}
+
+type mockPlacement struct {
+	sync.Mutex
+	owned map[string]bool
+}
+
+func (p *mockPlacement) Owns(taskID string) bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.owned[taskID]
+}
+
+func (p *mockPlacement) set(taskID string, owned bool) {
+	p.Lock()
+	defer p.Unlock()
+	p.owned[taskID] = owned
+}
+
+func TestTaskPlacement(t *testing.T) {
+	s := New(GetDefaultConfig())
+	s.SetMetricManager(newChurningMetricManager())
+	p := &mockPlacement{owned: map[string]bool{}}
+	s.SetTaskPlacement(p)
+	s.Start()
+	w := newMockWorkflowMap()
+
+	Convey("Given a task run by another member of the cluster", t, func() {
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		So(s.StartTask(tsk.ID()), ShouldBeEmpty)
+		So(tsk.State(), ShouldEqual, core.TaskStopped)
+		So(s.StandbyTasks(), ShouldContain, tsk.ID())
+
+		Convey("the task is started when it moves to this node", func() {
+			p.set(tsk.ID(), true)
+			s.Rebalance()
+			So(tsk.State(), ShouldBeIn, core.TaskSpinning, core.TaskFiring)
+			So(s.StandbyTasks(), ShouldNotContain, tsk.ID())
+
+			Convey("and is stopped when it moves to another node", func() {
+				p.set(tsk.ID(), false)
+				s.Rebalance()
+				So(s.StandbyTasks(), ShouldContain, tsk.ID())
+				for i := 0; i < 100 && tsk.State() != core.TaskStopped; i++ {
+					time.Sleep(10 * time.Millisecond)
+				}
+				So(tsk.State(), ShouldEqual, core.TaskStopped)
+			})
+		})
+		Convey("the task leaves standby when it is stopped", func() {
+			So(s.StopTask(tsk.ID()), ShouldBeEmpty)
+			So(s.StandbyTasks(), ShouldNotContain, tsk.ID())
+			s.Rebalance()
+			p.set(tsk.ID(), true)
+			s.Rebalance()
+			So(tsk.State(), ShouldEqual, core.TaskStopped)
+		})
+	})
+
+	s.Stop()
+}
+
+func TestTaskStateHistory(t *testing.T) {
+	s := New(GetDefaultConfig())
+	s.SetMetricManager(newChurningMetricManager())
+	s.Start()
+	w := newMockWorkflowMap()
+
+	Convey("Given a task which is started and stopped", t, func() {
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		So(s.StartTask(tsk.ID()), ShouldBeEmpty)
+		So(s.StopTask(tsk.ID()), ShouldBeEmpty)
+		for i := 0; i < 100 && tsk.State() != core.TaskStopped; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		Convey("its state transitions are recorded with their reasons", func() {
+			history := tsk.StateHistory()
+			So(history, ShouldHaveLength, 4)
+			states := []core.TaskState{}
+			reasons := []string{}
+			for _, st := range history {
+				states = append(states, st.State)
+				reasons = append(reasons, st.Reason)
+			}
+			So(states, ShouldResemble, []core.TaskState{core.TaskStopped, core.TaskSpinning, core.TaskStopping, core.TaskStopped})
+			So(reasons, ShouldResemble, []string{"task created", "task started", "stop requested by user", "task stopped"})
+		})
+	})
+
+	Convey("Given a state history", t, func() {
+		h := newStateHistory()
+		Convey("transitions between spinning and firing are not recorded", func() {
+			h.record(core.TaskSpinning, core.TaskFiring, "")
+			h.record(core.TaskFiring, core.TaskSpinning, "")
+			So(h.list(), ShouldBeEmpty)
+		})
+		Convey("only the latest transitions are kept", func() {
+			for i := 0; i < maxStateHistory+10; i++ {
+				h.record(core.TaskStopped, core.TaskSpinning, fmt.Sprintf("%d", i))
+			}
+			So(h.list(), ShouldHaveLength, maxStateHistory)
+			So(h.list()[0].Reason, ShouldEqual, "10")
+		})
+	})
+
+	s.Stop()
+}
+
+type mockLeaderElection struct {
+	sync.Mutex
+	leader bool
+	term   uint64
+}
+
+func (e *mockLeaderElection) Leader(taskID string) (bool, uint64) {
+	e.Lock()
+	defer e.Unlock()
+	return e.leader, e.term
+}
+
+func (e *mockLeaderElection) set(leader bool, term uint64) {
+	e.Lock()
+	defer e.Unlock()
+	e.leader = leader
+	e.term = term
+}
+
+func TestSingletonTask(t *testing.T) {
+	s := New(GetDefaultConfig())
+	s.SetMetricManager(newChurningMetricManager())
+	e := &mockLeaderElection{}
+	s.SetLeaderElection(e)
+	s.Start()
+	w := newMockWorkflowMap()
+	waitForHits := func(tk *task, hits uint) bool {
+		for i := 0; i < 100; i++ {
+			if tk.HitCount() >= hits {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+
+	Convey("Given a running singleton task", t, func() {
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false, core.OptionSingleton(true))
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+		So(tk.IsSingleton(), ShouldBeTrue)
+		So(s.StartTask(tk.ID()), ShouldBeEmpty)
+
+		Convey("it is not fired while this node is not its leader", func() {
+			time.Sleep(100 * time.Millisecond)
+			So(tk.HitCount(), ShouldEqual, 0)
+			So(tk.State(), ShouldBeIn, core.TaskSpinning, core.TaskFiring)
+
+			Convey("it is fired once this node is elected", func() {
+				e.set(true, 2)
+				So(waitForHits(tk, 1), ShouldBeTrue)
+
+				Convey("and fenced when the term of its leadership is stale", func() {
+					e.set(true, 1)
+					time.Sleep(50 * time.Millisecond)
+					hits := tk.HitCount()
+					time.Sleep(100 * time.Millisecond)
+					So(tk.HitCount(), ShouldEqual, hits)
+				})
+				Convey("and fired again once it leaves its agreement", func() {
+					e.set(true, 0)
+					time.Sleep(50 * time.Millisecond)
+					So(waitForHits(tk, tk.HitCount()+1), ShouldBeTrue)
+				})
+			})
+		})
+		Reset(func() {
+			s.StopTask(tk.ID())
+			e.set(false, 0)
+		})
+	})
+
+	s.Stop()
+}
@@ -0,0 +1,68 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sort"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// runDurationBounds are the upper bounds, in seconds, of the buckets of the
+// histogram of the run durations
+var runDurationBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// RunDurationHistogram returns the histogram of the durations of the task
+// runs, in seconds.
+func (s *scheduler) RunDurationHistogram() core.Histogram {
+	s.latencies.Lock()
+	defer s.latencies.Unlock()
+	return s.latencies.histogram.Copy()
+}
+
+// WorkQueueDepths returns the number of jobs waiting for a worker in the
+// shared queues and in the dedicated queues.
+func (s *scheduler) WorkQueueDepths() []core.WorkQueueDepth {
+	depths := s.workManager.queueDepths("")
+	s.dedicatedQueues.Lock()
+	names := make([]string, 0, len(s.dedicatedQueues.queues))
+	for name := range s.dedicatedQueues.queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		depths = append(depths, s.dedicatedQueues.queues[name].manager.queueDepths(name)...)
+	}
+	s.dedicatedQueues.Unlock()
+	return depths
+}
+
+func (w *workManager) queueDepths(name string) []core.WorkQueueDepth {
+	depth := func(q *queue) int {
+		q.mutex.Lock()
+		defer q.mutex.Unlock()
+		return q.length()
+	}
+	return []core.WorkQueueDepth{
+		{Queue: name, Phase: "collect", Depth: depth(w.collectq)},
+		{Queue: name, Phase: "process", Depth: depth(w.processq)},
+		{Queue: name, Phase: "publish", Depth: depth(w.publishq)},
+	}
+}
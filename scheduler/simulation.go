@@ -0,0 +1,364 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+const (
+	// maxRecordedDurations is the number of run durations kept per task and
+	// for the whole daemon
+	maxRecordedDurations = 1000
+	// simulationPeriod is how long a schedule is simulated for
+	simulationPeriod = 24 * time.Hour
+	// maxSimulatedFires bounds the period a short interval is simulated for
+	maxSimulatedFires = 1000000
+	// acceptableMissRatio is the ratio of missed fires under which a schedule
+	// is recommended as is
+	acceptableMissRatio = 0.01
+)
+
+var (
+	// ErrNoRunDurations - The error message for a schedule simulated before any task run was recorded
+	ErrNoRunDurations = errors.New("No task run recorded yet to simulate the schedule against")
+	// ErrStreamingSimulation - The error message for the simulation of a streaming schedule
+	ErrStreamingSimulation = errors.New("Streaming schedules can not be simulated")
+
+	// suggestedIntervals are the intervals tried, in order, when the
+	// simulated interval misses too many fires
+	suggestedIntervals = []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 500 * time.Millisecond,
+		time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second, 30 * time.Second,
+		time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+		time.Hour,
+	}
+)
+
+// runDurations holds the most recent durations of the task runs, oldest
+// first
+type runDurations struct {
+	durations []time.Duration
+}
+
+func (r *runDurations) record(d time.Duration) {
+	r.durations = append(r.durations, d)
+	if n := len(r.durations) - maxRecordedDurations; n > 0 {
+		r.durations = append([]time.Duration{}, r.durations[n:]...)
+	}
+}
+
+// runLatencies records the durations of the task runs, per task and for the
+// whole daemon, from the scheduler_event.TaskRunCompletedEvent events
+type runLatencies struct {
+	sync.Mutex
+	daemon runDurations
+	tasks  map[string]*runDurations
+	// histogram counts all the run durations, in seconds
+	histogram *core.Histogram
+}
+
+func newRunLatencies() *runLatencies {
+	return &runLatencies{
+		tasks:     map[string]*runDurations{},
+		histogram: core.NewHistogram(runDurationBounds),
+	}
+}
+
+func (l *runLatencies) record(taskID string, d time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	l.daemon.record(d)
+	l.histogram.Observe(d.Seconds())
+	t, ok := l.tasks[taskID]
+	if !ok {
+		t = &runDurations{}
+		l.tasks[taskID] = t
+	}
+	t.record(d)
+}
+
+// forget drops the durations recorded for a task; they remain part of the
+// durations of the daemon
+func (l *runLatencies) forget(taskID string) {
+	l.Lock()
+	defer l.Unlock()
+	delete(l.tasks, taskID)
+}
+
+// get returns a copy of the durations recorded for the given task, or for
+// the whole daemon when the id is empty
+func (l *runLatencies) get(taskID string) []time.Duration {
+	l.Lock()
+	defer l.Unlock()
+	if taskID == "" {
+		return append([]time.Duration{}, l.daemon.durations...)
+	}
+	if t, ok := l.tasks[taskID]; ok {
+		return append([]time.Duration{}, t.durations...)
+	}
+	return nil
+}
+
+// SimulateSchedule runs the given schedule over a day against the recorded
+// run durations of a task, or of all the tasks of the daemon when the task
+// id is empty, and recommends an interval when too many fires would be
+// missed.
+func (s *scheduler) SimulateSchedule(sch *core.Schedule, taskID string) (*core.ScheduleSimulation, error) {
+	if taskID != "" {
+		if _, err := s.getTask(taskID); err != nil {
+			return nil, err
+		}
+	}
+	durations := s.latencies.get(taskID)
+	if len(durations) == 0 {
+		return nil, ErrNoRunDurations
+	}
+	return simulateSchedule(sch, durations, time.Now())
+}
+
+// simulateSchedule runs the schedule from the given time over the simulation
+// period, the runs lasting the given durations in turn
+func simulateSchedule(sch *core.Schedule, durations []time.Duration, now time.Time) (*core.ScheduleSimulation, error) {
+	start, next, interval, err := scheduleFires(sch, now)
+	if err != nil {
+		return nil, err
+	}
+	period := simulationPeriod
+	if interval > 0 && period/interval > maxSimulatedFires {
+		period = interval * maxSimulatedFires
+	}
+	end := start.Add(period)
+	fires, missed, overlaps := simulateFires(next, start, end, durations, nil)
+
+	var total, max time.Duration
+	for _, d := range durations {
+		total += d
+		if d > max {
+			max = d
+		}
+	}
+	sim := &core.ScheduleSimulation{
+		Schedule:       sch,
+		Period:         period.String(),
+		Samples:        len(durations),
+		MeanDuration:   (total / time.Duration(len(durations))).String(),
+		MaxDuration:    max.String(),
+		ScheduledFires: fires + missed,
+		Fires:          fires,
+		MissedFires:    missed,
+		Overlaps:       overlaps,
+	}
+	if sim.ScheduledFires > 0 {
+		sim.MissRatio = float64(missed) / float64(sim.ScheduledFires)
+	}
+
+	if sch.Type == "cron" || sch.Type == "composite" {
+		name := "composite schedule"
+		if sch.Type == "cron" {
+			name = fmt.Sprintf("cron schedule '%s'", sch.Interval)
+		}
+		sim.Recommendation = fmt.Sprintf("%s will miss ~%.1f%% of fires", name, sim.MissRatio*100)
+		if sim.MissRatio > acceptableMissRatio {
+			sim.Recommendation += fmt.Sprintf("; runs last up to %s", max)
+		}
+		return sim, nil
+	}
+	sim.Recommendation = fmt.Sprintf("interval %s will miss ~%.1f%% of fires", interval, sim.MissRatio*100)
+	if sim.MissRatio > acceptableMissRatio {
+		suggested := suggestInterval(interval, start, end, durations)
+		sim.SuggestedInterval = suggested.String()
+		sim.Recommendation += fmt.Sprintf("; suggest %s", suggested)
+	}
+	return sim, nil
+}
+
+// scheduleFires returns the first fire of the schedule from the given time,
+// zero when it never fires, and the function returning the fire following a
+// fire.  The interval is zero for a cron schedule and the shortest interval
+// of its schedules for a composite one.
+func scheduleFires(sch *core.Schedule, from time.Time) (time.Time, func(time.Time) time.Time, time.Duration, error) {
+	if sch == nil {
+		return time.Time{}, nil, 0, core.ErrMissingScheduleInterval
+	}
+	switch sch.Type {
+	case "simple", "windowed":
+		if sch.Interval == "" {
+			return time.Time{}, nil, 0, core.ErrMissingScheduleInterval
+		}
+		interval, err := time.ParseDuration(sch.Interval)
+		if err != nil {
+			return time.Time{}, nil, 0, err
+		}
+		if interval <= 0 {
+			return time.Time{}, nil, 0, schedule.ErrInvalidInterval
+		}
+		start := from
+		if sch.StartTimestamp != nil && sch.StartTimestamp.After(start) {
+			start = *sch.StartTimestamp
+		}
+		if sch.Aligned {
+			start = alignedFire(start, interval)
+		}
+		var stop time.Time
+		if sch.StopTimestamp != nil {
+			stop = *sch.StopTimestamp
+		} else if sch.Count > 0 {
+			stop = start.Add(time.Duration(sch.Count-1) * interval)
+		}
+		if !stop.IsZero() && start.After(stop) {
+			start = time.Time{}
+		}
+		return start, intervalFires(interval, stop), interval, nil
+	case "cron":
+		if sch.Interval == "" {
+			return time.Time{}, nil, 0, core.ErrMissingScheduleInterval
+		}
+		c, err := cron.Parse(sch.Interval)
+		if err != nil {
+			return time.Time{}, nil, 0, err
+		}
+		// a fire due at the given time is part of the schedule
+		return c.Next(from.Add(-time.Nanosecond)), c.Next, 0, nil
+	case "composite":
+		if len(sch.Schedules) == 0 {
+			return time.Time{}, nil, 0, core.ErrMissingSchedules
+		}
+		upcoming := make([]time.Time, len(sch.Schedules))
+		nexts := make([]func(time.Time) time.Time, len(sch.Schedules))
+		var interval time.Duration
+		for i := range sch.Schedules {
+			first, next, in, err := scheduleFires(&sch.Schedules[i], from)
+			if err != nil {
+				return time.Time{}, nil, 0, err
+			}
+			upcoming[i], nexts[i] = first, next
+			if in > 0 && (interval == 0 || in < interval) {
+				interval = in
+			}
+		}
+		// the fires are asked for in order: each schedule is moved past the
+		// given fire, the earliest of their next fires is the next one
+		next := func(t time.Time) time.Time {
+			var n time.Time
+			for i := range upcoming {
+				for !upcoming[i].IsZero() && !upcoming[i].After(t) {
+					upcoming[i] = nexts[i](upcoming[i])
+				}
+				if !upcoming[i].IsZero() && (n.IsZero() || upcoming[i].Before(n)) {
+					n = upcoming[i]
+				}
+			}
+			return n
+		}
+		var start time.Time
+		for _, u := range upcoming {
+			if !u.IsZero() && (start.IsZero() || u.Before(start)) {
+				start = u
+			}
+		}
+		return start, next, interval, nil
+	case "streaming":
+		return time.Time{}, nil, 0, ErrStreamingSimulation
+	default:
+		return time.Time{}, nil, 0, fmt.Errorf("unknown schedule type `%s`", sch.Type)
+	}
+}
+
+// intervalFires returns the next fire of an interval schedule, none being
+// due after the given stop time unless it is zero
+func intervalFires(interval time.Duration, stop time.Time) func(time.Time) time.Time {
+	return func(t time.Time) time.Time {
+		n := t.Add(interval)
+		if !stop.IsZero() && n.After(stop) {
+			return time.Time{}
+		}
+		return n
+	}
+}
+
+// alignedFire returns the first multiple of the interval since the Unix
+// epoch at or after t, which an aligned schedule first fires at
+func alignedFire(t time.Time, interval time.Duration) time.Time {
+	if r := time.Duration(t.UnixNano() % interval.Nanoseconds()); r != 0 {
+		return t.Add(interval - r)
+	}
+	return t
+}
+
+// simulateFires fires a schedule from start until end, each run lasting the
+// next of the durations.  As a task does, a fire due while the previous run
+// is still going is missed and the task waits for the next one.  It returns
+// the number of fires, of missed fires and of runs overlapping the next fire.
+// The run function, when not nil, is called with the fire and the end of
+// every run.
+func simulateFires(next func(time.Time) time.Time, start, end time.Time, durations []time.Duration, run func(time.Time, time.Time)) (uint, uint, uint) {
+	var fires, missed, overlaps uint
+	for i, t := 0, start; !t.IsZero() && t.Before(end); i++ {
+		fires++
+		finish := t.Add(durations[i%len(durations)])
+		if run != nil {
+			run(t, finish)
+		}
+		n := next(t)
+		if !n.IsZero() && n.Before(finish) && n.Before(end) {
+			overlaps++
+		}
+		for !n.IsZero() && n.Before(finish) && n.Before(end) {
+			missed++
+			n = next(n)
+		}
+		t = n
+	}
+	return fires, missed, overlaps
+}
+
+// suggestInterval returns the shortest of the suggested intervals longer
+// than the given one which misses an acceptable ratio of fires, or the
+// longest run duration rounded up to the second when none does
+func suggestInterval(interval time.Duration, start, end time.Time, durations []time.Duration) time.Duration {
+	for _, candidate := range suggestedIntervals {
+		if candidate <= interval {
+			continue
+		}
+		fires, missed, _ := simulateFires(intervalFires(candidate, time.Time{}), start, end, durations, nil)
+		if float64(missed)/float64(fires+missed) <= acceptableMissRatio {
+			return candidate
+		}
+	}
+	var max time.Duration
+	for _, d := range durations {
+		if d > max {
+			max = d
+		}
+	}
+	if r := max % time.Second; r != 0 {
+		max += time.Second - r
+	}
+	return max
+}
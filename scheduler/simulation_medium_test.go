@@ -0,0 +1,204 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func TestSimulateSchedule(t *testing.T) {
+	now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	Convey("Simulating a schedule", t, func() {
+		Convey("with runs shorter than the interval misses no fire", func() {
+			durations := []time.Duration{100 * time.Millisecond, 300 * time.Millisecond}
+			sim, err := simulateSchedule(&core.Schedule{Type: "simple", Interval: "1s"}, durations, now)
+			So(err, ShouldBeNil)
+			So(sim.ScheduledFires, ShouldEqual, 86400)
+			So(sim.Fires, ShouldEqual, 86400)
+			So(sim.MissedFires, ShouldEqual, 0)
+			So(sim.SuggestedInterval, ShouldBeEmpty)
+			So(sim.MeanDuration, ShouldEqual, "200ms")
+		})
+		Convey("with runs lasting past the next fire misses fires", func() {
+			// one run in ten lasts 1.5s and misses the next fire
+			durations := make([]time.Duration, 10)
+			for i := range durations {
+				durations[i] = 100 * time.Millisecond
+			}
+			durations[9] = 1500 * time.Millisecond
+			sim, err := simulateSchedule(&core.Schedule{Type: "simple", Interval: "1s"}, durations, now)
+			So(err, ShouldBeNil)
+			So(sim.MissedFires, ShouldEqual, 7854)
+			So(sim.Overlaps, ShouldEqual, sim.MissedFires)
+			So(sim.Fires+sim.MissedFires, ShouldEqual, 86400)
+			So(sim.SuggestedInterval, ShouldEqual, "2s")
+			So(sim.Recommendation, ShouldEqual, "interval 1s will miss ~9.1% of fires; suggest 2s")
+		})
+		Convey("within a window counts the fires of the window only", func() {
+			sim, err := simulateSchedule(&core.Schedule{Type: "windowed", Interval: "1m", Count: 10}, []time.Duration{time.Second}, now)
+			So(err, ShouldBeNil)
+			So(sim.Fires, ShouldEqual, 10)
+		})
+		Convey("with a cron entry", func() {
+			sim, err := simulateSchedule(&core.Schedule{Type: "cron", Interval: "0 * * * * *"}, []time.Duration{90 * time.Second}, now)
+			So(err, ShouldBeNil)
+			So(sim.Fires, ShouldEqual, 720)
+			So(sim.MissedFires, ShouldEqual, 720)
+			So(sim.Recommendation, ShouldEqual, "cron schedule '0 * * * * *' will miss ~50.0% of fires; runs last up to 1m30s")
+		})
+		Convey("with a composite schedule fires on the fires of all of its schedules", func() {
+			// the hourly and the daily fires at midnight are a single fire
+			sch := &core.Schedule{Type: "composite", Schedules: []core.Schedule{
+				{Type: "simple", Interval: "1h"},
+				{Type: "cron", Interval: "0 0 0 * * *"},
+				{Type: "cron", Interval: "0 30 12 * * *"},
+			}}
+			sim, err := simulateSchedule(sch, []time.Duration{time.Second}, now)
+			So(err, ShouldBeNil)
+			So(sim.Fires, ShouldEqual, 25)
+			So(sim.MissedFires, ShouldEqual, 0)
+			So(sim.Recommendation, ShouldEqual, "composite schedule will miss ~0.0% of fires")
+		})
+		Convey("fails for a streaming schedule", func() {
+			_, err := simulateSchedule(&core.Schedule{Type: "streaming"}, []time.Duration{time.Second}, now)
+			So(err, ShouldEqual, ErrStreamingSimulation)
+		})
+	})
+	Convey("Recording the durations of the task runs", t, func() {
+		l := newRunLatencies()
+		for i := 0; i < maxRecordedDurations+10; i++ {
+			l.record("task", time.Duration(i))
+		}
+		l.record("other", time.Second)
+		So(l.get("task"), ShouldHaveLength, maxRecordedDurations)
+		So(l.get("task")[0], ShouldEqual, time.Duration(10))
+		So(l.get(""), ShouldHaveLength, maxRecordedDurations)
+		l.forget("task")
+		So(l.get("task"), ShouldBeEmpty)
+	})
+}
+
+func TestSimulateRun(t *testing.T) {
+	start := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	Convey("Simulating the run of tasks over a minute", t, func() {
+		req := &core.RunSimulationRequest{Start: start, End: start.Add(time.Minute)}
+		tasks := []simulatedTask{
+			{name: "a", schedule: &core.Schedule{Type: "simple", Interval: "10s"}, durations: []time.Duration{time.Second}},
+			{name: "b", schedule: &core.Schedule{Type: "simple", Interval: "15s"}, durations: []time.Duration{2 * time.Second}},
+			{name: "c", schedule: &core.Schedule{Type: "cron", Interval: "*/20 * * * * *"}, durations: []time.Duration{0}},
+		}
+		sim, err := simulateRun(req, tasks)
+		So(err, ShouldBeNil)
+
+		Convey("reports when each task fires", func() {
+			So(sim.Tasks, ShouldHaveLength, 3)
+			So(sim.Tasks[0].FireCount, ShouldEqual, 6)
+			So(sim.Tasks[0].Fires[1], ShouldResemble, start.Add(10*time.Second))
+			So(sim.Tasks[1].FireCount, ShouldEqual, 4)
+			So(sim.Tasks[1].MeanDuration, ShouldEqual, "2s")
+			So(sim.Tasks[2].Fires, ShouldResemble, []time.Time{start, start.Add(20 * time.Second), start.Add(40 * time.Second)})
+		})
+		Convey("detects the fires of different tasks colliding", func() {
+			So(sim.CollisionCount, ShouldEqual, 4)
+			So(sim.Collisions[0], ShouldResemble, core.ScheduleCollision{At: start, Tasks: []string{"a", "b", "c"}})
+			So(sim.Collisions[1], ShouldResemble, core.ScheduleCollision{At: start.Add(20 * time.Second), Tasks: []string{"a", "c"}})
+		})
+		Convey("estimates the load of the runs", func() {
+			So(sim.PeakConcurrentRuns, ShouldEqual, 2)
+			So(sim.MeanConcurrentRuns, ShouldAlmostEqual, 14.0/60)
+			So(sim.HotSpots, ShouldHaveLength, maxHotSpots)
+			hotSpots := []time.Time{}
+			for _, s := range sim.HotSpots {
+				hotSpots = append(hotSpots, s.Start)
+			}
+			So(hotSpots, ShouldResemble, []time.Time{
+				start, start.Add(30 * time.Second), start.Add(20 * time.Second), start.Add(40 * time.Second), start.Add(10 * time.Second),
+			})
+			So(sim.HotSpots[0].Fires, ShouldEqual, 3)
+			So(sim.HotSpots[0].Load, ShouldEqual, 2)
+			So(sim.HotSpots[0].PeakConcurrentRuns, ShouldEqual, 2)
+		})
+		Convey("counts the fires missed by runs lasting too long", func() {
+			tasks[0].durations = []time.Duration{15 * time.Second}
+			sim, err := simulateRun(req, tasks[:1])
+			So(err, ShouldBeNil)
+			So(sim.Tasks[0].FireCount, ShouldEqual, 3)
+			So(sim.Tasks[0].MissedFires, ShouldEqual, 3)
+			So(sim.PeakConcurrentRuns, ShouldEqual, 1)
+		})
+		Convey("fails for an invalid request", func() {
+			_, err := simulateRun(&core.RunSimulationRequest{Start: start, End: start}, tasks)
+			So(err, ShouldEqual, ErrInvalidSimulationRange)
+			req.Slot = "1ns"
+			_, err = simulateRun(req, tasks)
+			So(err, ShouldEqual, ErrInvalidSimulationSlot)
+			req.Slot = ""
+			tasks[1].schedule = &core.Schedule{Type: "streaming"}
+			_, err = simulateRun(req, tasks)
+			So(err.Error(), ShouldEqual, "tasks[1]: "+ErrStreamingSimulation.Error())
+			tasks[1].schedule = &core.Schedule{Type: "simple", Interval: "1us"}
+			_, err = simulateRun(req, tasks)
+			So(err, ShouldEqual, ErrTooManySimulatedFires)
+		})
+	})
+	Convey("Given a scheduler with a task", t, func() {
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(newMockMetricManager())
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		w.Collect.Add(wmap.NewPublishNode("file", -1))
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(time.Second, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		s.latencies.record(tsk.ID(), 1500*time.Millisecond)
+		req := &core.RunSimulationRequest{Start: start, End: start.Add(10 * time.Second)}
+
+		Convey("the task is simulated with its schedule and run durations", func() {
+			req.Tasks = []core.SimulatedTask{{TaskID: tsk.ID()}, {Schedule: &core.Schedule{Type: "simple", Interval: "5s"}, Duration: "3s"}}
+			sim, err := s.SimulateRun(req)
+			So(err, ShouldBeNil)
+			So(sim.Tasks[0].Name, ShouldEqual, tsk.GetName())
+			So(sim.Tasks[0].FireCount, ShouldEqual, 5)
+			So(sim.Tasks[0].MissedFires, ShouldEqual, 5)
+			So(sim.Tasks[1].Name, ShouldEqual, "tasks[1]")
+			So(sim.Tasks[1].MeanDuration, ShouldEqual, "3s")
+			So(sim.Workers, ShouldEqual, s.workManager.poolSize())
+			So(sim.PeakConcurrentRuns, ShouldEqual, 2)
+			So(sim.Overloaded, ShouldBeFalse)
+		})
+		Convey("a task given neither by id nor by schedule is rejected", func() {
+			req.Tasks = []core.SimulatedTask{{Name: "x"}}
+			_, err := s.SimulateRun(req)
+			So(err.Error(), ShouldEqual, "tasks[0]: "+ErrMissingSimulatedSchedule.Error())
+			req.Tasks = nil
+			_, err = s.SimulateRun(req)
+			So(err, ShouldEqual, ErrNoSimulatedTask)
+		})
+	})
+}
@@ -0,0 +1,85 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	// maxStateHistory is the maximum number of state transitions kept per task
+	maxStateHistory = 1000
+	// stateHistoryRetention is how long the state transitions of a task are kept
+	stateHistoryRetention = 7 * 24 * time.Hour
+)
+
+// stateHistory is the timeline of the state transitions of a task.
+// A task alternates between spinning and firing on every run; those
+// transitions are reported through the run results and are not recorded.
+type stateHistory struct {
+	sync.Mutex
+	transitions []core.TaskStateTransition
+}
+
+func newStateHistory() *stateHistory {
+	return &stateHistory{
+		transitions: []core.TaskStateTransition{},
+	}
+}
+
+// record appends a transition and drops the ones past the retention period
+// or over the maximum size of the history
+func (h *stateHistory) record(from, to core.TaskState, reason string) {
+	if isRunning(from) && isRunning(to) {
+		return
+	}
+	h.Lock()
+	defer h.Unlock()
+	now := time.Now()
+	h.transitions = append(h.transitions, core.TaskStateTransition{
+		Time:   now,
+		State:  to,
+		Reason: reason,
+	})
+	i := 0
+	for i < len(h.transitions) && now.Sub(h.transitions[i].Time) > stateHistoryRetention {
+		i++
+	}
+	if n := len(h.transitions) - maxStateHistory; n > i {
+		i = n
+	}
+	if i > 0 {
+		h.transitions = append([]core.TaskStateTransition{}, h.transitions[i:]...)
+	}
+}
+
+// list returns a copy of the recorded transitions, oldest first
+func (h *stateHistory) list() []core.TaskStateTransition {
+	h.Lock()
+	defer h.Unlock()
+	return append([]core.TaskStateTransition{}, h.transitions...)
+}
+
+func isRunning(state core.TaskState) bool {
+	return state == core.TaskSpinning || state == core.TaskFiring
+}
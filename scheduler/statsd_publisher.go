@@ -0,0 +1,191 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// The flavors of the statsd publisher
+const (
+	statsdFlavorStatsd    = "statsd"
+	statsdFlavorDogstatsd = "dogstatsd"
+)
+
+// defaultStatsdPacketSize fits the packets in the MTU of an ethernet network
+const defaultStatsdPacketSize = 1432
+
+// statsdTypes maps the types of the statsd publisher to the statsd types
+var statsdTypes = map[string]string{
+	"gauge":   "g",
+	"counter": "c",
+	"timing":  "ms",
+}
+
+// statsdReplacer replaces the characters of the statsd protocol in the
+// names and tags of the metrics
+var statsdReplacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", " ", "_", "\n", "_")
+
+// statsdSink sends the numeric metrics as statsd lines over UDP, gathered in
+// packets of at most packetSize bytes.  The metrics are named after their
+// namespace joined by dots.  The dogstatsd flavor sends the tags of the
+// metrics too.  The metrics whose data is not numeric are skipped.
+type statsdSink struct {
+	address    string
+	flavor     string
+	typ        string
+	prefix     string
+	packetSize int
+
+	sync.Mutex
+	conn net.Conn
+}
+
+func newStatsdSink(config map[string]interface{}) (*statsdSink, error) {
+	address, err := sinkString(sinkStatsd, config, "address", true)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, fmt.Errorf("Invalid address '%s' in built-in publisher 'statsd' (expected host:port)", address)
+	}
+	s := &statsdSink{address: address}
+	if s.flavor, err = sinkString(sinkStatsd, config, "flavor", false); err != nil {
+		return nil, err
+	}
+	switch s.flavor {
+	case "":
+		s.flavor = statsdFlavorStatsd
+	case statsdFlavorStatsd, statsdFlavorDogstatsd:
+	default:
+		return nil, fmt.Errorf("Invalid flavor '%s' in built-in publisher 'statsd' (expected statsd or dogstatsd)", s.flavor)
+	}
+	typ, err := sinkString(sinkStatsd, config, "type", false)
+	if err != nil {
+		return nil, err
+	}
+	if typ == "" {
+		typ = "gauge"
+	}
+	var ok bool
+	if s.typ, ok = statsdTypes[typ]; !ok {
+		return nil, fmt.Errorf("Invalid type '%s' in built-in publisher 'statsd' (expected gauge, counter or timing)", typ)
+	}
+	if s.prefix, err = sinkString(sinkStatsd, config, "prefix", false); err != nil {
+		return nil, err
+	}
+	size, err := sinkFloat(sinkStatsd, config, "max_packet_size")
+	if err != nil {
+		return nil, err
+	}
+	s.packetSize = int(size)
+	if s.packetSize == 0 {
+		s.packetSize = defaultStatsdPacketSize
+	}
+	return s, nil
+}
+
+// publish sends the lines of the metrics; the error tells how many metrics
+// were sent before the failure
+func (s *statsdSink) publish(_ context.Context, mts []core.Metric, _ time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.conn == nil {
+		conn, err := net.Dial("udp", s.address)
+		if err != nil {
+			return &core.PublishError{Err: err, Retryable: true}
+		}
+		s.conn = conn
+	}
+	var packet []byte
+	// sent is the number of metrics sent before the packet
+	sent, pending := 0, 0
+	flush := func() error {
+		if len(packet) == 0 {
+			return nil
+		}
+		if _, err := s.conn.Write(packet); err != nil {
+			return &core.PublishError{Err: err, Published: sent, Retryable: true}
+		}
+		packet = packet[:0]
+		sent += pending
+		pending = 0
+		return nil
+	}
+	for _, m := range mts {
+		line, ok := s.line(m)
+		if !ok {
+			pending++
+			continue
+		}
+		if len(packet) > 0 && len(packet)+1+len(line) > s.packetSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+		pending++
+	}
+	return flush()
+}
+
+// line returns the statsd line of a metric; false when its data is not
+// numeric
+func (s *statsdSink) line(m core.Metric) (string, bool) {
+	value, ok := toFloat64(m.Data())
+	if !ok {
+		return "", false
+	}
+	name := statsdReplacer.Replace(strings.Join(m.Namespace().Strings(), "."))
+	if s.prefix != "" {
+		name = s.prefix + "." + name
+	}
+	line := name + ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|" + s.typ
+	if s.flavor == statsdFlavorDogstatsd && len(m.Tags()) > 0 {
+		tags := make([]string, 0, len(m.Tags()))
+		for k, v := range m.Tags() {
+			tags = append(tags, statsdReplacer.Replace(k)+":"+statsdReplacer.Replace(v))
+		}
+		sort.Strings(tags)
+		line += "|#" + strings.Join(tags, ",")
+	}
+	return line, true
+}
+
+func (s *statsdSink) close() {
+	s.Lock()
+	defer s.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
@@ -0,0 +1,126 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// readPackets returns the packets received until none comes for a while
+func readPackets(conn net.PacketConn) []string {
+	packets := []string{}
+	buf := make([]byte, 65536)
+	for {
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return packets
+		}
+		packets = append(packets, string(buf[:n]))
+	}
+}
+
+func TestStatsdSink(t *testing.T) {
+	Convey("Given the built-in statsd publisher", t, func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer conn.Close()
+		addr := conn.LocalAddr().String()
+		now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+		mts := []core.Metric{
+			plugin.MetricType{Namespace_: core.NewNamespace("intel", "procfs", "cpu"), Tags_: map[string]string{"host": "node-1", "dc": "eu west"}, Data_: 1.5},
+			plugin.MetricType{Namespace_: core.NewNamespace("intel", "procfs", "state"), Data_: "up"},
+			plugin.MetricType{Namespace_: core.NewNamespace("intel", "docker", "mem:rss"), Data_: uint64(2048)},
+		}
+
+		Convey("it sends the numeric metrics as statsd gauges", func() {
+			s, err := newSink(sinkStatsd, map[string]interface{}{"address": addr, "prefix": "snap"})
+			So(err, ShouldBeNil)
+			defer s.close()
+			So(s.publish(context.Background(), mts, now), ShouldBeNil)
+			So(readPackets(conn), ShouldResemble, []string{"snap.intel.procfs.cpu:1.5|g\nsnap.intel.docker.mem_rss:2048|g"})
+		})
+		Convey("the dogstatsd flavor sends the tags", func() {
+			s, err := newSink(sinkStatsd, map[string]interface{}{"address": addr, "flavor": "dogstatsd", "type": "counter"})
+			So(err, ShouldBeNil)
+			defer s.close()
+			So(s.publish(context.Background(), mts[:1], now), ShouldBeNil)
+			So(readPackets(conn), ShouldResemble, []string{"intel.procfs.cpu:1.5|c|#dc:eu_west,host:node-1"})
+		})
+		Convey("the lines are gathered in packets of the maximum size", func() {
+			s, err := newSink(sinkStatsd, map[string]interface{}{"address": addr, "max_packet_size": 30})
+			So(err, ShouldBeNil)
+			defer s.close()
+			So(s.publish(context.Background(), mts, now), ShouldBeNil)
+			So(readPackets(conn), ShouldResemble, []string{"intel.procfs.cpu:1.5|g", "intel.docker.mem_rss:2048|g"})
+		})
+		Convey("it rejects invalid settings", func() {
+			_, err := newSink(sinkStatsd, map[string]interface{}{})
+			So(err.Error(), ShouldEqual, "Missing 'address' in the config of built-in publisher 'statsd'")
+			_, err = newSink(sinkStatsd, map[string]interface{}{"address": "localhost"})
+			So(err.Error(), ShouldEqual, "Invalid address 'localhost' in built-in publisher 'statsd' (expected host:port)")
+			_, err = newSink(sinkStatsd, map[string]interface{}{"address": addr, "flavor": "graphite"})
+			So(err.Error(), ShouldEqual, "Invalid flavor 'graphite' in built-in publisher 'statsd' (expected statsd or dogstatsd)")
+			_, err = newSink(sinkStatsd, map[string]interface{}{"address": addr, "type": "set"})
+			So(err.Error(), ShouldEqual, "Invalid type 'set' in built-in publisher 'statsd' (expected gauge, counter or timing)")
+		})
+	})
+}
+
+func TestWorkflowStatsdSink(t *testing.T) {
+	Convey("Given a task publishing to the built-in statsd publisher", t, func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer conn.Close()
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(&publishRecorder{mockMetricManager: newMockMetricManager()})
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		pu := &wmap.PublishWorkflowMapNode{Builtin: "statsd"}
+		pu.AddConfigItem("address", conn.LocalAddr().String())
+		w.Collect.Add(pu)
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+		tk.killChan = make(chan struct{})
+
+		Convey("the metrics of the task are sent to statsd", func() {
+			tk.fire()
+			packets := readPackets(conn)
+			So(packets, ShouldHaveLength, 1)
+			So(strings.Split(packets[0], "\n"), ShouldResemble, []string{"foo.bar:1|g", "foo.baz:2|g"})
+			So(tk.DeliveryStats().Published, ShouldEqual, 2)
+		})
+	})
+}
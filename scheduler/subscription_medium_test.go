@@ -0,0 +1,93 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// subscriptionCounter holds the subscriptions of the tasks like control: a
+// task is unsubscribed once
+type subscriptionCounter struct {
+	*mockMetricManager
+	sync.Mutex
+	subscribed     map[string]int
+	unsubscribeErr int
+}
+
+func (m *subscriptionCounter) SubscribeDeps(taskID string, reqs []core.RequestedMetric, prs []core.SubscribedPlugin, ctree *cdata.ConfigDataTree) []serror.SnapError {
+	m.Lock()
+	defer m.Unlock()
+	m.subscribed[taskID]++
+	return nil
+}
+
+func (m *subscriptionCounter) UnsubscribeDeps(taskID string) []serror.SnapError {
+	m.Lock()
+	defer m.Unlock()
+	if m.subscribed[taskID] == 0 {
+		m.unsubscribeErr++
+		return []serror.SnapError{serror.New(core.ErrSubscriptionGroupDoesNotExist)}
+	}
+	delete(m.subscribed, taskID)
+	return nil
+}
+
+func TestTaskSubscriptions(t *testing.T) {
+	Convey("Given a scheduler whose metric manager counts the subscriptions", t, func() {
+		mm := &subscriptionCounter{mockMetricManager: newMockMetricManager(), subscribed: map[string]int{}}
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(mm)
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+
+		Convey("unsubscribing a task twice is a no-op", func() {
+			tk.SubscribePlugins()
+			So(tk.UnsubscribePlugins(), ShouldBeEmpty)
+			So(tk.UnsubscribePlugins(), ShouldBeEmpty)
+			So(mm.subscribed, ShouldBeEmpty)
+		})
+		Convey("removing a task releases the subscriptions it left behind", func() {
+			tk.SubscribePlugins()
+			So(mm.subscribed[tk.ID()], ShouldEqual, 1)
+			So(s.RemoveTask(tk.ID()), ShouldBeNil)
+			So(mm.subscribed, ShouldBeEmpty)
+		})
+		Convey("removing a task unsubscribed already is not an error", func() {
+			So(s.RemoveTask(tk.ID()), ShouldBeNil)
+			So(mm.unsubscribeErr, ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,132 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+// disconnectingMetricManager simulates a metric manager which cannot be
+// reached for a while
+type disconnectingMetricManager struct {
+	*churningMetricManager
+	unavailable int32
+}
+
+func (m *disconnectingMetricManager) CollectMetrics(taskID string, tags map[string]map[string]string) ([]core.Metric, []error) {
+	if atomic.LoadInt32(&m.unavailable) == 1 {
+		return nil, []error{ErrMetricManagerUnavailable}
+	}
+	return m.churningMetricManager.CollectMetrics(taskID, tags)
+}
+
+func (m *disconnectingMetricManager) setUnavailable(b bool) {
+	if b {
+		atomic.StoreInt32(&m.unavailable, 1)
+	} else {
+		atomic.StoreInt32(&m.unavailable, 0)
+	}
+}
+
+func waitForState(t core.Task, state core.TaskState) core.TaskState {
+	for i := 0; i < 200 && t.State() != state; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return t.State()
+}
+
+func TestTaskSuspend(t *testing.T) {
+	defaultRetries, defaultInterval := suspendRetries, suspendRetryInterval
+	suspendRetryInterval = 10 * time.Millisecond
+	defer func() {
+		suspendRetries, suspendRetryInterval = defaultRetries, defaultInterval
+	}()
+
+	setup := func(retries int) (*scheduler, *disconnectingMetricManager, core.Task) {
+		suspendRetries = retries
+		s := New(GetDefaultConfig())
+		mm := &disconnectingMetricManager{churningMetricManager: newChurningMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newMockWorkflowMap(), true, core.OptionStopOnFailure(2))
+		So(errs.Errors(), ShouldBeEmpty)
+		mm.setUnavailable(true)
+		return s, mm, tsk
+	}
+
+	Convey("Given a running task whose metric manager becomes unavailable", t, func() {
+		s, mm, tsk := setup(1000)
+
+		Convey("the task is suspended rather than disabled", func() {
+			So(waitForState(tsk, core.TaskSuspended), ShouldEqual, core.TaskSuspended)
+			time.Sleep(100 * time.Millisecond)
+			So(tsk.State(), ShouldEqual, core.TaskSuspended)
+
+			Convey("and resumes once the manager is back", func() {
+				mm.setUnavailable(false)
+				So(waitForState(tsk, core.TaskSpinning), ShouldEqual, core.TaskSpinning)
+			})
+			Convey("and can be stopped", func() {
+				So(s.StopTask(tsk.ID()), ShouldBeEmpty)
+				So(waitForState(tsk, core.TaskStopped), ShouldEqual, core.TaskStopped)
+			})
+		})
+
+		s.Stop()
+	})
+	Convey("Given a task whose metric manager stays unavailable", t, func() {
+		s, _, tsk := setup(2)
+
+		Convey("the failure policy applies once the retries are exhausted", func() {
+			So(waitForState(tsk, core.TaskDisabled), ShouldEqual, core.TaskDisabled)
+		})
+
+		s.Stop()
+	})
+}
+
+func TestRunResultUnavailable(t *testing.T) {
+	Convey("A run result", t, func() {
+		r := newRunResult(time.Now())
+		So(r.Unavailable(), ShouldBeFalse)
+		r.Errors = append(r.Errors, ErrTaskNotFound)
+		So(r.Unavailable(), ShouldBeFalse)
+
+		Convey("reports an unavailable metric manager", func() {
+			r.Errors = append(r.Errors, ErrMetricManagerUnavailable)
+			So(r.Unavailable(), ShouldBeTrue)
+		})
+		Convey("reports an unreachable remote metric manager", func() {
+			r.Errors = append(r.Errors, grpc.Errorf(codes.Unavailable, "transport is closing"))
+			So(r.Unavailable(), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,78 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"github.com/intelsdi-x/snap/core"
+)
+
+// tagFilterAny matches any value of a tag
+const tagFilterAny = "*"
+
+// tagFilter selects the metrics carrying all of its tags.  A tag whose value
+// is "*" only needs to be present.
+type tagFilter map[string]string
+
+// filter returns the metrics matching the filter
+func (f tagFilter) filter(mts []core.Metric) []core.Metric {
+	if len(f) == 0 {
+		return mts
+	}
+	out := make([]core.Metric, 0, len(mts))
+	for _, m := range mts {
+		if f.matches(m) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func (f tagFilter) matches(m core.Metric) bool {
+	tags := m.Tags()
+	for k, v := range f {
+		tv, ok := tags[k]
+		if !ok || (v != tagFilterAny && v != tv) {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeTaskTags returns the tags of the collect node with the tags of the
+// task added to the root branch.  Tags given for the root branch by the
+// collect node take precedence.
+func mergeTaskTags(tags map[string]map[string]string, taskTags map[string]string) map[string]map[string]string {
+	if len(taskTags) == 0 {
+		return tags
+	}
+	merged := make(map[string]map[string]string, len(tags)+1)
+	for ns, nsTags := range tags {
+		merged[ns] = nsTags
+	}
+	root := make(map[string]string, len(taskTags)+len(tags["/"]))
+	for k, v := range taskTags {
+		root[k] = v
+	}
+	for k, v := range tags["/"] {
+		root[k] = v
+	}
+	merged["/"] = root
+	return merged
+}
@@ -0,0 +1,87 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+func TestTagFilter(t *testing.T) {
+	Convey("Given metrics carrying tags", t, func() {
+		mt := func(tags map[string]string) core.Metric {
+			return plugin.MetricType{Namespace_: core.NewNamespace("intel", "mock"), Tags_: tags}
+		}
+		mts := []core.Metric{
+			mt(map[string]string{"dc": "east", "rack": "1"}),
+			mt(map[string]string{"dc": "west"}),
+			mt(nil),
+		}
+
+		Convey("a filter keeps the metrics carrying all its tags", func() {
+			So(len(tagFilter{"dc": "east"}.filter(mts)), ShouldEqual, 1)
+			So(len(tagFilter{"dc": "*"}.filter(mts)), ShouldEqual, 2)
+			So(len(tagFilter{"dc": "west", "rack": "*"}.filter(mts)), ShouldEqual, 0)
+			So(len(tagFilter{}.filter(mts)), ShouldEqual, 3)
+		})
+	})
+	Convey("Given the tags of a task", t, func() {
+		taskTags := map[string]string{"dc": "east", "env": "prod"}
+
+		Convey("they are added to the root branch of the collect node tags", func() {
+			tags := map[string]map[string]string{
+				"/":      {"env": "test"},
+				"/intel": {"rack": "1"},
+			}
+			merged := mergeTaskTags(tags, taskTags)
+			So(merged["/"], ShouldResemble, map[string]string{"dc": "east", "env": "test"})
+			So(merged["/intel"], ShouldResemble, map[string]string{"rack": "1"})
+			So(tags["/"], ShouldResemble, map[string]string{"env": "test"})
+		})
+	})
+	Convey("Given a task whose publisher filters metrics by tag", t, func() {
+		s := New(GetDefaultConfig())
+		mm := &publishRecorder{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		w := newBufferedWorkflowMap(nil)
+		w.Tags = map[string]string{"dc": "east"}
+
+		Convey("only the matching metrics are published", func() {
+			w.Collect.Publish[0].TagFilter = map[string]string{"dc": "west"}
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+			So(errs.Errors(), ShouldBeEmpty)
+			tk := tsk.(*task)
+			tk.killChan = make(chan struct{})
+			So(tk.workflow.tags["/"], ShouldResemble, map[string]string{"dc": "east"})
+			tk.fire()
+			So(mm.published(), ShouldBeEmpty)
+		})
+
+		s.Stop()
+	})
+}
@@ -20,22 +20,26 @@ limitations under the License.
 package scheduler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/intelsdi-x/gomit"
-	"github.com/pborman/uuid"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/scheduler_event"
 	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/grpc/controlproxy"
+	"github.com/intelsdi-x/snap/pkg/chrono"
 	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/pkg/tracing"
 	"github.com/intelsdi-x/snap/scheduler/wmap"
 )
 
@@ -44,6 +48,22 @@ const (
 	DefaultDeadlineDuration = time.Second * 5
 	// DefaultStopOnFailure is used to set the number of failures before a task is disabled
 	DefaultStopOnFailure = 10
+	// DefaultFailureCooldown is how long a task with the restart-after-cooldown
+	// failure policy waits before it resumes
+	DefaultFailureCooldown = time.Minute
+)
+
+var (
+	// suspendRetries is how many times a suspended task retries to reach its
+	// metric manager before its failure policy applies
+	suspendRetries = 10
+	// suspendRetryInterval is the delay before the first retry of a suspended
+	// task; it doubles on every retry up to maxSuspendRetryInterval
+	suspendRetryInterval    = time.Second
+	maxSuspendRetryInterval = time.Minute
+	// stopTimeout bounds the wait of Stop for the routine of the task to
+	// exit, which flushes the buffered metrics first
+	stopTimeout = 30 * time.Second
 )
 
 var (
@@ -59,18 +79,31 @@ var (
 	ErrTaskDisabledOnFailures = errors.New("Task disabled due to consecutive failures")
 	// ErrTaskNotDisabled - The error message for task must be disabled
 	ErrTaskNotDisabled = errors.New("Task must be disabled")
+	// ErrTaskStoppedOnFailures - The error message for task stopped due to consecutive failures
+	ErrTaskStoppedOnFailures = errors.New("Task stopped due to consecutive failures")
+	// ErrTaskFailureLimitReached - The error message for task which keeps running after reaching its limit of consecutive failures
+	ErrTaskFailureLimitReached = errors.New("Task reached its limit of consecutive failures")
+	// ErrTaskStopTimeout - The error message for task whose routine did not exit in time when stopped
+	ErrTaskStopTimeout = errors.New("Timed out waiting for task to stop")
 )
 
 type task struct {
-	sync.Mutex //protects state
+	sync.Mutex
 
 	id                 string
 	name               string
 	schResponseChan    chan schedule.Response
 	killChan           chan struct{}
+	doneChan           chan struct{} //closed when the routine running the task exits
+	ctxMutex           sync.Mutex    //protects ctx and cancel
+	ctx                context.Context
+	cancel             context.CancelFunc
+	stopSource         string
 	schedule           schedule.Schedule
 	workflow           *schedulerWorkflow
+	stateMutex         sync.RWMutex //protects state and transitionHooks
 	state              core.TaskState
+	transitionHooks    []transitionHook
 	creationTime       time.Time
 	lastFireTime       time.Time
 	manager            managesWork
@@ -78,17 +111,102 @@ type task struct {
 	deadlineDuration   time.Duration
 	hitCount           uint
 	missedIntervals    uint
-	failureMutex       sync.Mutex
+	failureMutex       sync.Mutex //protects failedRuns, lastFailureMessage, run and lastRun
 	failedRuns         uint
 	lastFailureMessage string
-	lastFailureTime    time.Time
+	run                *RunResult
+	lastRun            *RunResult
 	stopOnFailure      int
+	failurePolicy      core.FailurePolicy
+	failureCooldown    time.Duration
 	eventEmitter       gomit.Emitter
 	RemoteManagers     managers
 	isStream           bool
 
 	maxCollectDuration time.Duration
 	maxMetricsBuffer   int64
+
+	// degraded holds why the task runs degraded keyed by the plugin causing it
+	degradedMutex sync.Mutex
+	degraded      map[string]string
+
+	history *stateHistory
+
+	// singleton tasks are fired by the leader elected for the task only
+	singleton      bool
+	leaderMutex    sync.Mutex //protects leaderElection, leader and leaderTerm
+	leaderElection electsLeaders
+	leader         bool
+	leaderTerm     uint64
+
+	// the dedicated worker queue of the task; empty when using the shared queues
+	queueName string
+	queueSize int
+
+	// pinned runs the collect jobs of the task on pinnedThread; nil when
+	// they run on the collect workers
+	pinned       *core.TaskPinnedThread
+	pinnedThread *pinnedThread
+
+	// publishRateLimit caps the metrics published by the task through
+	// publishLimiter; nil when the task publishes all of its metrics
+	publishRateLimit *core.TaskPublishRateLimit
+	publishLimiter   *publishLimiter
+
+	// cardinalityLimit caps the unique namespaces of the metrics collected
+	// by a run of the task, nil when they are not limited
+	cardinalityLimit *core.TaskCardinalityLimit
+
+	// blackouts are the periods the fires of the task are suppressed in,
+	// counted by blackedOutFires apart from the missed intervals
+	blackouts       []core.BlackoutPeriod
+	blackedOutFires uint
+
+	// labels select the task in the listings of the tasks
+	labels map[string]string
+
+	// owner created the task, nil when it belongs to no tenant; the metrics
+	// published by the tasks of its tenant are capped by tenantLimiter, nil
+	// without a quota of metrics
+	owner         *core.TaskOwner
+	tenantLimiter *publishLimiter
+
+	// timeouts are the timeouts of the phases of the workflow set on the
+	// task and defaultTimeouts the ones of the scheduler; zero timeouts fall
+	// back to deadlineDuration
+	timeouts        core.TaskTimeouts
+	defaultTimeouts *defaultTimeouts
+
+	// maintenance pauses the task while it covers it
+	maintenance *maintenance
+
+	// exportPolicy restricts the metrics published by the task to aggregates
+	exportPolicy exportPolicy
+	// tracer records the runs of the task, nil when tracing is disabled
+	tracer *tracing.Tracer
+	// inflight bounds the memory of the metrics being published by all the
+	// tasks, nil when it is not bounded
+	inflight *inflightBudget
+	// dedup records the batches acknowledged by the publish nodes
+	// deduplicating them, nil when no batch is deduplicated
+	dedup *dedupWindows
+	// cardinality tracks and limits the namespaces collected by the tasks
+	cardinality *cardinalityGuard
+	// removed is set once the task is removed from the scheduler, so that
+	// the runs in flight do not track it again
+	removedMutex sync.RWMutex
+	removed      bool
+
+	// publishFailures counts the consecutive failures of the publish nodes
+	publishFailures publisherFailures
+
+	// usage accumulates the resources used by the jobs of the task
+	usage taskUsage
+	// delivery counts the deliveries of the metrics to the publishers
+	delivery taskDelivery
+
+	// clock tells the time to the task and its schedule
+	clock chrono.Clock
 }
 
 //NewTask creates a Task
@@ -98,8 +216,8 @@ func newTask(s schedule.Schedule, wf *schedulerWorkflow, m *workManager, mm mana
 	//However if a user want to change this name, she can pass optional arguments, in form of core.TaskOption
 	//The new name then get over written.
 
-	taskID := uuid.New()
-	name := fmt.Sprintf("Task-%s", taskID)
+	taskID := uuidTaskIDs{}.NewTaskID()
+	name := defaultTaskName(taskID)
 	wf.eventEmitter = emitter
 	mgrs := newManagers(mm)
 	err := createTaskClients(&mgrs, wf)
@@ -119,10 +237,15 @@ func newTask(s schedule.Schedule, wf *schedulerWorkflow, m *workManager, mm mana
 		metricsManager:   mm,
 		deadlineDuration: DefaultDeadlineDuration,
 		stopOnFailure:    DefaultStopOnFailure,
+		failureCooldown:  DefaultFailureCooldown,
 		eventEmitter:     emitter,
 		RemoteManagers:   mgrs,
 		isStream:         stream,
+		history:          newStateHistory(),
+		clock:            chrono.Real,
 	}
+	task.history.record(core.TaskStopped, core.TaskStopped, "task created")
+	task.onTransition(task.history.record)
 	//set options
 	for _, opt := range opts {
 		opt(task)
@@ -130,6 +253,14 @@ func newTask(s schedule.Schedule, wf *schedulerWorkflow, m *workManager, mm mana
 	return task, nil
 }
 
+// setClock sets the clock the task, and its schedule, tell the time with
+func (t *task) setClock(c chrono.Clock) {
+	t.clock = c
+	if cs, ok := t.schedule.(schedule.Clocked); ok {
+		cs.SetClock(c)
+	}
+}
+
 // Option sets the options specified.
 // Returns an option to optionally restore the last arg's previous value.
 func (t *task) Option(opts ...core.TaskOption) core.TaskOption {
@@ -140,6 +271,28 @@ func (t *task) Option(opts ...core.TaskOption) core.TaskOption {
 	return previous
 }
 
+// options returns the options needed to create a task with the same
+// settings as this task.
+func (t *task) options() []core.TaskOption {
+	return []core.TaskOption{
+		core.TaskDeadlineDuration(t.deadlineDuration),
+		core.OptionStopOnFailure(t.stopOnFailure),
+		core.OptionFailurePolicy(t.failurePolicy),
+		core.OptionFailureCooldown(t.failureCooldown),
+		core.SetMaxCollectDuration(t.maxCollectDuration),
+		core.SetMaxMetricsBuffer(t.maxMetricsBuffer),
+		core.OptionSingleton(t.singleton),
+		core.DedicatedQueue(t.queueName, t.queueSize),
+		core.OptionPinnedThread(t.pinned),
+		core.OptionPublishRateLimit(t.publishRateLimit),
+		core.OptionCardinalityLimit(t.cardinalityLimit),
+		core.OptionBlackouts(t.blackouts),
+		core.OptionOwner(t.owner),
+		core.OptionTimeouts(t.timeouts),
+		core.OptionLabels(t.labels),
+	}
+}
+
 func (t *task) MaxCollectDuration() time.Duration {
 	return t.maxCollectDuration
 }
@@ -178,6 +331,40 @@ func (t *task) SetDeadlineDuration(d time.Duration) {
 	t.deadlineDuration = d
 }
 
+func (t *task) SetTimeouts(to core.TaskTimeouts) {
+	t.timeouts = to
+}
+
+// GetTimeouts returns the timeouts of the phases set on the task; zero
+// timeouts fall back to the defaults of the scheduler, then to the deadline
+func (t *task) GetTimeouts() core.TaskTimeouts {
+	return t.timeouts
+}
+
+// collectTimeout, processTimeout and publishTimeout return how long the jobs
+// of each phase of the workflow may wait for a worker
+func (t *task) collectTimeout() time.Duration {
+	return t.phaseTimeout(t.timeouts.Collect, t.defaultTimeouts.get().Collect)
+}
+
+func (t *task) processTimeout() time.Duration {
+	return t.phaseTimeout(t.timeouts.Process, t.defaultTimeouts.get().Process)
+}
+
+func (t *task) publishTimeout() time.Duration {
+	return t.phaseTimeout(t.timeouts.Publish, t.defaultTimeouts.get().Publish)
+}
+
+func (t *task) phaseTimeout(timeout, def time.Duration) time.Duration {
+	if timeout > 0 {
+		return timeout
+	}
+	if def > 0 {
+		return def
+	}
+	return t.deadlineDuration
+}
+
 func (t *task) SetTaskID(id string) {
 	t.id = id
 }
@@ -192,6 +379,18 @@ func (t *task) ID() string {
 	return t.id
 }
 
+// ResourceUsage returns the approximate resources used by the runs of the
+// task since it was created
+func (t *task) ResourceUsage() core.TaskResourceUsage {
+	return t.usage.get()
+}
+
+// DeliveryStats returns the counts of the deliveries of the metrics of the
+// task to its publishers
+func (t *task) DeliveryStats() core.TaskDeliveryStats {
+	return t.delivery.get()
+}
+
 // LastRunTime returns the time of the tasks last run.
 func (t *task) LastRunTime() *time.Time {
 	return &t.lastFireTime
@@ -202,6 +401,12 @@ func (t *task) MissedCount() uint {
 	return t.missedIntervals
 }
 
+// BlackedOutCount returns the number of fires suppressed by the blackout
+// calendar of the task.
+func (t *task) BlackedOutCount() uint {
+	return t.blackedOutFires
+}
+
 // FailedRuns returns the number of intervals missed.
 func (t *task) FailedCount() uint {
 	return t.failedRuns
@@ -214,6 +419,8 @@ func (t *task) LastFailureMessage() string {
 
 // State returns state of the task.
 func (t *task) State() core.TaskState {
+	t.stateMutex.RLock()
+	defer t.stateMutex.RUnlock()
 	return t.state
 }
 
@@ -234,17 +441,170 @@ func (t *task) GetStopOnFailure() int {
 	return t.stopOnFailure
 }
 
+func (t *task) SetFailurePolicy(p core.FailurePolicy) {
+	t.failurePolicy = p
+}
+
+func (t *task) GetFailurePolicy() core.FailurePolicy {
+	return t.failurePolicy
+}
+
+func (t *task) SetFailureCooldown(d time.Duration) {
+	t.failureCooldown = d
+}
+
+func (t *task) GetFailureCooldown() time.Duration {
+	return t.failureCooldown
+}
+
+func (t *task) SetSingleton(b bool) {
+	t.singleton = b
+}
+
+func (t *task) IsSingleton() bool {
+	return t.singleton
+}
+
+func (t *task) SetDedicatedQueue(name string, size int) {
+	t.queueName = name
+	t.queueSize = size
+}
+
+// GetDedicatedQueue returns the name and size of the dedicated worker queue of
+// the task; the name is empty when the task uses the shared queues.
+func (t *task) GetDedicatedQueue() (string, int) {
+	return t.queueName, t.queueSize
+}
+
+func (t *task) SetPinnedThread(p *core.TaskPinnedThread) {
+	t.pinned = p
+}
+
+// GetPinnedThread returns the pinned thread option of the task, nil when its
+// collect jobs run on the collect workers
+func (t *task) GetPinnedThread() *core.TaskPinnedThread {
+	return t.pinned
+}
+
+func (t *task) SetPublishRateLimit(l *core.TaskPublishRateLimit) {
+	t.publishRateLimit = l
+}
+
+// GetPublishRateLimit returns the publish rate limit of the task, nil when
+// the task publishes all of its metrics
+func (t *task) GetPublishRateLimit() *core.TaskPublishRateLimit {
+	return t.publishRateLimit
+}
+
+func (t *task) SetCardinalityLimit(l *core.TaskCardinalityLimit) {
+	t.cardinalityLimit = l
+}
+
+// GetCardinalityLimit returns the cardinality limit of the task, nil when
+// the namespaces of its metrics are not limited
+func (t *task) GetCardinalityLimit() *core.TaskCardinalityLimit {
+	return t.cardinalityLimit
+}
+
+func (t *task) SetBlackouts(periods []core.BlackoutPeriod) {
+	t.blackouts = periods
+}
+
+// GetBlackouts returns the blackout calendar of the task
+func (t *task) GetBlackouts() []core.BlackoutPeriod {
+	return t.blackouts
+}
+
+func (t *task) SetOwner(o *core.TaskOwner) {
+	t.owner = o
+}
+
+// GetOwner returns the owner of the task, nil when it belongs to no tenant
+func (t *task) GetOwner() *core.TaskOwner {
+	return t.owner
+}
+
+// SetLabels sets the labels of the task
+func (t *task) SetLabels(l map[string]string) {
+	t.labels = l
+}
+
+// GetLabels returns the labels of the task, nil when it has none
+func (t *task) GetLabels() map[string]string {
+	return t.labels
+}
+
+// markRemoved records that the task is removed from the scheduler
+func (t *task) markRemoved() {
+	t.removedMutex.Lock()
+	defer t.removedMutex.Unlock()
+	t.removed = true
+}
+
+// isRemoved returns true once the task is removed from the scheduler
+func (t *task) isRemoved() bool {
+	t.removedMutex.RLock()
+	defer t.removedMutex.RUnlock()
+	return t.removed
+}
+
+// guardCardinality returns the metrics collected by a run within the
+// cardinality limits
+func (t *task) guardCardinality(mts []core.Metric) []core.Metric {
+	if t.cardinality == nil {
+		return mts
+	}
+	return t.cardinality.guard(t, mts)
+}
+
+// setDegraded marks the task as degraded because of the plugin with the given
+// key.  An empty reason clears the mark.
+func (t *task) setDegraded(pluginKey, reason string) {
+	t.degradedMutex.Lock()
+	defer t.degradedMutex.Unlock()
+	if reason == "" {
+		delete(t.degraded, pluginKey)
+		return
+	}
+	if t.degraded == nil {
+		t.degraded = map[string]string{}
+	}
+	t.degraded[pluginKey] = reason
+}
+
+// Degraded returns why the task runs degraded, sorted by the plugin causing
+// it.  The result is empty when the task is healthy.
+func (t *task) Degraded() []string {
+	t.degradedMutex.Lock()
+	defer t.degradedMutex.Unlock()
+	keys := make([]string, 0, len(t.degraded))
+	for k := range t.degraded {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	reasons := make([]string, len(keys))
+	for i, k := range keys {
+		reasons[i] = t.degraded[k]
+	}
+	return reasons
+}
+
 // Spin will start a task spinning in its own routine while it waits for its
 // schedule.
 func (t *task) Spin() {
 	// We need to lock long enough to change state
 	t.Lock()
 	defer t.Unlock()
+	if err := t.transition(core.TaskSpinning, "task started", core.TaskStopped, core.TaskEnded); err != nil {
+		t.logTransitionError("Spin", err)
+		return
+	}
+	t.killChan = make(chan struct{})
+	t.doneChan = make(chan struct{})
+	t.startContext()
 	// if this task is a streaming task
 	if t.isStream {
-		t.state = core.TaskSpinning
-		t.killChan = make(chan struct{})
-		go t.stream()
+		go t.runRoutine(t.stream)
 		return
 	}
 
@@ -254,13 +614,14 @@ func (t *task) Spin() {
 	// waiting a period of time, and starting the task won't show
 	// misses for the interval while stopped.
 	t.lastFireTime = time.Time{}
+	// spin in a goroutine
+	go t.runRoutine(t.spin)
+}
 
-	if t.state == core.TaskStopped || t.state == core.TaskEnded {
-		t.state = core.TaskSpinning
-		t.killChan = make(chan struct{})
-		// spin in a goroutine
-		go t.spin()
-	}
+// runRoutine runs the routine of the task, then tells Stop that it exited
+func (t *task) runRoutine(routine func()) {
+	defer close(t.doneChan)
+	routine()
 }
 
 // Fork stream stuff here
@@ -277,22 +638,16 @@ func (t *task) stream() {
 			consecutiveFailures++
 			// check task failures
 			if t.stopOnFailure >= 0 && consecutiveFailures >= t.stopOnFailure {
-				taskLogger.WithFields(log.Fields{
-					"_block":               "stream",
-					"task-id":              t.id,
-					"task-name":            t.name,
-					"consecutive failures": consecutiveFailures,
-					"error":                t.lastFailureMessage,
-				}).Error(ErrTaskDisabledOnFailures)
-				// disable the task
-				t.disable(t.lastFailureMessage)
-				return
+				if t.handleFailureLimit("stream", consecutiveFailures) {
+					return
+				}
+				consecutiveFailures = 0
 			}
 			// If we are unsuccessful at setting up the stream
 			// wait for a second and then try again until either
 			// the connection is successful or we pass the
 			// acceptable number of consecutive failures
-			time.Sleep(resetTime)
+			<-t.clock.After(resetTime)
 			continue
 		} else {
 			consecutiveFailures = 0
@@ -304,25 +659,34 @@ func (t *task) stream() {
 			}
 			select {
 			case <-t.killChan:
-				t.Lock()
-				t.state = core.TaskStopped
-				t.Unlock()
-				done = true
-				event := new(scheduler_event.TaskStoppedEvent)
-				event.TaskID = t.id
-				defer t.eventEmitter.Emit(event)
+				t.workflow.FlushBuffers(t)
+				t.stopped("task stopped", t.stopSource)
 				return
 			case mts, ok := <-metricsChan:
 				if !ok {
 					metricsChan = nil
 					break
 				}
-				if len(mts) == 0 {
+				if len(mts) == 0 || !t.leads() {
 					continue
 				}
 				t.hitCount++
-				consecutiveFailures = 0
-				t.workflow.StreamStart(t, mts)
+				span := t.startRunSpan()
+				t.beginRun(t.clock.Now())
+				t.workflow.StreamStart(t.runContext(t.hitCount, span), t, mts)
+				r := t.endRun()
+				finishRunSpan(span, r)
+				if r.Success() {
+					consecutiveFailures = 0
+					continue
+				}
+				consecutiveFailures++
+				if t.stopOnFailure >= 0 && consecutiveFailures >= t.stopOnFailure {
+					if t.handleFailureLimit("stream", consecutiveFailures) {
+						return
+					}
+					consecutiveFailures = 0
+				}
 			case err := <-errChan:
 				taskLogger.WithFields(log.Fields{
 					"_block":    "stream",
@@ -333,62 +697,98 @@ func (t *task) stream() {
 				if err.Error() == "connection broken" {
 					// Wait here before trying to reconnect to allow time
 					// for plugin restarts.
-					time.Sleep(resetTime)
+					<-t.clock.After(resetTime)
 					done = true
 				}
 				// check task failures
 				if t.stopOnFailure >= 0 && consecutiveFailures >= t.stopOnFailure {
-					taskLogger.WithFields(log.Fields{
-						"_block":               "stream",
-						"task-id":              t.id,
-						"task-name":            t.name,
-						"consecutive failures": consecutiveFailures,
-						"error":                t.lastFailureMessage,
-					}).Error(ErrTaskDisabledOnFailures)
-					// disable the task
-					t.disable(t.lastFailureMessage)
-					return
+					if t.handleFailureLimit("stream", consecutiveFailures) {
+						return
+					}
+					consecutiveFailures = 0
 				}
 			}
 		}
 	}
 }
 
-func (t *task) Stop() {
+// Stop stops the task and waits for the routine running it to exit, so that
+// the task is stopped when it returns nil.  ErrTaskStopTimeout is returned
+// when the routine does not exit in time; the task is then left stopping.
+func (t *task) Stop() error {
+	return t.stopFrom("")
+}
+
+// stopFrom stops the task; the source is reported in the emitted stop event
+func (t *task) stopFrom(source string) error {
+	// a run in progress is cancelled rather than awaited
+	if isStarted(t.State()) {
+		t.cancelRuns()
+	}
 	t.Lock()
-	defer t.Unlock()
-	if t.state == core.TaskFiring || t.state == core.TaskSpinning {
-		t.state = core.TaskStopping
+	if err := t.transition(core.TaskStopping, stopReason(source)); err != nil {
+		// a task already stopping is awaited all the same
+		if t.State() != core.TaskStopping {
+			t.Unlock()
+			t.logTransitionError("stop", err)
+			return err
+		}
+	} else {
+		t.stopSource = source
 		close(t.killChan)
 	}
+	done := t.doneChan
+	t.Unlock()
+	return t.waitStopped(done)
 }
 
-// UnsubscribePlugins groups task dependencies by the node they live in workflow and unsubscribe them
+// waitStopped waits for the routine of the task to exit
+func (t *task) waitStopped(done chan struct{}) error {
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-time.After(stopTimeout):
+		taskLogger.WithFields(log.Fields{
+			"_block":    "stop",
+			"task-id":   t.id,
+			"task-name": t.name,
+			"timeout":   stopTimeout,
+		}).Error(ErrTaskStopTimeout)
+		return ErrTaskStopTimeout
+	}
+}
+
+// UnsubscribePlugins groups task dependencies by the node they live in workflow and unsubscribe them.
+// Unsubscribing the dependencies already unsubscribed is a no-op.
 func (t *task) UnsubscribePlugins() []serror.SnapError {
 	depGroups := getWorkflowPlugins(t.workflow.processNodes, t.workflow.publishNodes, t.workflow.metrics)
 	var errs []serror.SnapError
 	for k := range depGroups {
+		mgr, err := t.RemoteManagers.Get(k)
+		if err != nil {
+			errs = append(errs, serror.New(err))
+			continue
+		}
+		uerrs := mgr.UnsubscribeDeps(t.ID())
+		if len(uerrs) == 1 && uerrs[0].Error() == core.ErrSubscriptionGroupDoesNotExist.Error() {
+			continue
+		}
+		errs = append(errs, uerrs...)
 		event := &scheduler_event.PluginsUnsubscribedEvent{
 			TaskID:  t.ID(),
 			Plugins: depGroups[k].subscribedPlugins,
 		}
 		defer t.eventEmitter.Emit(event)
-		mgr, err := t.RemoteManagers.Get(k)
-		if err != nil {
-			errs = append(errs, serror.New(err))
-		} else {
-			uerrs := mgr.UnsubscribeDeps(t.ID())
-			if len(uerrs) > 0 {
-				errs = append(errs, uerrs...)
-			}
-		}
 	}
 	for _, err := range errs {
 		taskLogger.WithFields(log.Fields{
 			"_block":     "UnsubscribePlugins",
 			"task-id":    t.id,
 			"task-name":  t.name,
-			"task-state": t.state,
+			"task-state": t.State(),
 		}).Error(err)
 	}
 	return errs
@@ -398,7 +798,17 @@ func (t *task) UnsubscribePlugins() []serror.SnapError {
 // If there are errors with subscribing any deps, manage unsubscribing all other deps that may have already been subscribed
 // and then return the errors.
 func (t *task) SubscribePlugins() ([]string, []serror.SnapError) {
-	depGroups := getWorkflowPlugins(t.workflow.processNodes, t.workflow.publishNodes, t.workflow.metrics)
+	// Versions selected by version constraints are resolved at subscription time
+	if _, errs := resolveVersions(t.metricsManager, t.workflow.metrics); len(errs) > 0 {
+		return nil, errs
+	}
+	return t.subscribeDeps(t.ID(), t.workflow.metrics)
+}
+
+// subscribeDeps subscribes the plugins of the workflow collecting the given
+// metrics under the given subscription id
+func (t *task) subscribeDeps(id string, mts []core.RequestedMetric) ([]string, []serror.SnapError) {
+	depGroups := getWorkflowPlugins(t.workflow.processNodes, t.workflow.publishNodes, mts)
 	var subbedDeps []string
 	for k := range depGroups {
 		var errs []serror.SnapError
@@ -406,7 +816,10 @@ func (t *task) SubscribePlugins() ([]string, []serror.SnapError) {
 		if err != nil {
 			errs = append(errs, serror.New(err))
 		} else {
-			errs = mgr.SubscribeDeps(t.ID(), depGroups[k].requestedMetrics, depGroups[k].subscribedPlugins, t.workflow.configTree)
+			if tr, ok := mgr.(tenantsRestricted); ok {
+				tr.SetTaskTenant(id, core.TenantOf(t))
+			}
+			errs = subscribeDeps(mgr, id, depGroups[k].requestedMetrics, depGroups[k].subscribedPlugins, t.workflow.configTree)
 		}
 		// If there are errors with subscribing any deps, go through and unsubscribe all other
 		// deps that may have already been subscribed then return the errors.
@@ -417,7 +830,7 @@ func (t *task) SubscribePlugins() ([]string, []serror.SnapError) {
 					errs = append(errs, serror.New(err))
 				} else {
 					// sending empty mts to unsubscribe to indicate task should not start
-					uerrs := mgr.UnsubscribeDeps(t.ID())
+					uerrs := mgr.UnsubscribeDeps(id)
 					errs = append(errs, uerrs...)
 				}
 			}
@@ -430,26 +843,42 @@ func (t *task) SubscribePlugins() ([]string, []serror.SnapError) {
 	return subbedDeps, nil
 }
 
+// unsubscribeDeps releases the subscription made by subscribeDeps under the
+// given id for the given metrics
+func (t *task) unsubscribeDeps(id string, mts []core.RequestedMetric) {
+	depGroups := getWorkflowPlugins(t.workflow.processNodes, t.workflow.publishNodes, mts)
+	for k := range depGroups {
+		mgr, err := t.RemoteManagers.Get(k)
+		if err != nil {
+			continue
+		}
+		mgr.UnsubscribeDeps(id)
+	}
+}
+
 //Enable changes the state from Disabled to Stopped
 func (t *task) Enable() error {
 	t.Lock()
 	defer t.Unlock()
 
-	if t.state != core.TaskDisabled {
+	if err := t.transition(core.TaskStopped, "task enabled", core.TaskDisabled); err != nil {
 		return ErrTaskNotDisabled
 	}
-	t.state = core.TaskStopped
-
 	return nil
 }
 
+// Kill disables a running task so that it can't be started again; the
+// routine running it stops without changing its state.
 func (t *task) Kill() {
+	if isStarted(t.State()) {
+		t.cancelRuns()
+	}
 	t.Lock()
 	defer t.Unlock()
-	if t.state == core.TaskFiring || t.state == core.TaskSpinning {
-		close(t.killChan)
-		t.state = core.TaskDisabled
+	if err := t.transition(core.TaskDisabled, "task killed", core.TaskSpinning, core.TaskFiring, core.TaskSuspended); err != nil {
+		return
 	}
+	close(t.killChan)
 }
 
 func (t *task) WMap() *wmap.WorkflowMap {
@@ -462,6 +891,8 @@ func (t *task) Schedule() schedule.Schedule {
 
 func (t *task) spin() {
 	var consecutiveFailures int
+	// paused is true while the fires are skipped for maintenance or a blackout
+	var paused bool
 	for {
 		taskLogger.Debug("task spin loop")
 		// Start go routine to wait on schedule
@@ -474,9 +905,50 @@ func (t *task) spin() {
 			switch sr.State() {
 			// If response show this schedule is still active we fire
 			case schedule.Active:
-				t.missedIntervals += sr.Missed()
-				t.fire()
-				if t.lastFailureTime == t.lastFireTime {
+				// the fires skipped during maintenance are not missed
+				if t.maintenance.covers(t.id) {
+					paused = true
+					continue
+				}
+				// and neither are the fires suppressed by the blackouts
+				if b := core.Blackout(t.blackouts, sr.LastTime()); b != nil {
+					if !paused {
+						taskLogger.WithFields(log.Fields{
+							"_block":    "spin",
+							"task-id":   t.id,
+							"task-name": t.name,
+							"until":     b.End,
+							"reason":    b.Reason,
+						}).Info("Task fires suppressed by a blackout period")
+					}
+					t.blackedOutFires++
+					paused = true
+					continue
+				}
+				if paused {
+					paused = false
+				} else {
+					t.missedIntervals += sr.Missed()
+				}
+				// a singleton task is fired by its leader only
+				if !t.leads() {
+					continue
+				}
+				r := t.fire()
+				if r == nil {
+					// the task is stopping
+					continue
+				}
+				if r.Unavailable() {
+					// failures of an unavailable metric manager are not
+					// counted; the task is suspended until it is back
+					if t.suspend(r) {
+						return
+					}
+					consecutiveFailures = 0
+					continue
+				}
+				if !r.Success() {
 					consecutiveFailures++
 					taskLogger.WithFields(log.Fields{
 						"_block":                    "spin",
@@ -484,80 +956,325 @@ func (t *task) spin() {
 						"task-name":                 t.name,
 						"consecutive failures":      consecutiveFailures,
 						"consecutive failure limit": t.stopOnFailure,
-						"error":                     t.lastFailureMessage,
+						"partial":                   r.Partial(),
+						"error":                     r.LastError(),
 					}).Warn("Task failed")
 				} else {
 					consecutiveFailures = 0
 				}
 				if t.stopOnFailure >= 0 && consecutiveFailures >= t.stopOnFailure {
-					taskLogger.WithFields(log.Fields{
-						"_block":               "spin",
-						"task-id":              t.id,
-						"task-name":            t.name,
-						"consecutive failures": consecutiveFailures,
-						"error":                t.lastFailureMessage,
-					}).Error(ErrTaskDisabledOnFailures)
-
-					// disable the task
-					t.disable(t.lastFailureMessage)
-					return
+					if t.handleFailureLimit("spin", consecutiveFailures) {
+						return
+					}
+					consecutiveFailures = 0
 				}
 
 			// Schedule has ended
 			case schedule.Ended:
-				// You must lock task to change state
-				t.Lock()
-				t.state = core.TaskEnded
-				t.Unlock()
+				// publish the metrics still buffered
+				t.workflow.FlushBuffers(t)
+				if err := t.transition(core.TaskEnded, "schedule ended"); err != nil {
+					t.logTransitionError("spin", err)
+					return
+				}
 				// Send task ended event
 				event := new(scheduler_event.TaskEndedEvent)
 				event.TaskID = t.id
+				event.TaskName = t.name
+				event.TaskLabels = t.labels
+				event.Why = "schedule ended"
 				defer t.eventEmitter.Emit(event)
 				return //spin
 
 			// Schedule has errored
 			case schedule.Error:
-				// disable the task
 				failureMessage := sr.Error().Error()
+				t.eventEmitter.Emit(&scheduler_event.TaskErroredEvent{
+					TaskID:     t.id,
+					TaskName:   t.name,
+					TaskLabels: t.labels,
+					Why:        failureMessage,
+				})
+				// disable the task
 				t.disable(failureMessage)
 				return //spin
 
 			}
 		case <-t.killChan:
+			// publish the metrics still buffered
+			t.workflow.FlushBuffers(t)
 			// Only here can it truly be stopped
-			t.Lock()
-			t.state = core.TaskStopped
-			t.lastFireTime = time.Time{}
-			t.Unlock()
-			event := new(scheduler_event.TaskStoppedEvent)
-			event.TaskID = t.id
-			defer t.eventEmitter.Emit(event)
+			t.stopped("task stopped", t.stopSource)
 			return
 		}
 	}
 }
 
-func (t *task) fire() {
+// fire runs the workflow of the task once and returns the result of the
+// run, or nil when the task is stopping or disabled, including when it was
+// stopped or killed during the run.  A spinning task is firing during the
+// run; a task fired while it is not started keeps its state.
+func (t *task) fire() *RunResult {
 	t.Lock()
 	defer t.Unlock()
 
-	t.state = core.TaskFiring
-	t.lastFireTime = time.Now()
-	t.workflow.Start(t)
+	if t.cancelled() {
+		return nil
+	}
+	if err := t.transition(core.TaskFiring, "task fired", core.TaskSpinning); err != nil {
+		if state := t.State(); state == core.TaskStopping || state == core.TaskDisabled {
+			return nil
+		}
+		return t.runWorkflow()
+	}
+	r := t.runWorkflow()
+	if err := t.transition(core.TaskSpinning, "task run completed", core.TaskFiring); err != nil {
+		t.logTransitionError("fire", err)
+	}
+	if t.cancelled() {
+		return nil
+	}
+	return r
+}
+
+// retry runs the workflow of a suspended task once without changing its state
+func (t *task) retry() *RunResult {
+	t.Lock()
+	defer t.Unlock()
+	return t.runWorkflow()
+}
+
+// runWorkflow runs the workflow of the task once.  The task must be locked.
+func (t *task) runWorkflow() *RunResult {
+	t.lastFireTime = t.clock.Now()
+	span := t.startRunSpan()
+	t.beginRun(t.lastFireTime)
+	t.workflow.Start(t.runContext(t.hitCount+1, span), t)
 	t.hitCount++
-	t.state = core.TaskSpinning
+	r := t.endRun()
+	finishRunSpan(span, r)
+	return r
 }
 
-// disable proceeds disabling a task which consists of changing task state to disabled and emitting an appropriate event
-func (t *task) disable(failureMsg string) {
+// suspend suspends the task while its metric manager cannot be reached.  The
+// workflow is retried with a growing delay until the manager is reached again
+// or the retries are exhausted, in which case the failure policy of the task
+// applies.  It returns true if the task stopped running.
+func (t *task) suspend(r *RunResult) bool {
+	// a task stopping meanwhile is stopped by the first retry
+	if err := t.transition(core.TaskSuspended, r.LastError(), core.TaskSpinning); err == nil {
+		taskLogger.WithFields(log.Fields{
+			"_block":    "suspend",
+			"task-id":   t.id,
+			"task-name": t.name,
+			"error":     r.LastError(),
+		}).Warn(ErrMetricManagerUnavailable)
+		t.eventEmitter.Emit(&scheduler_event.TaskSuspendedEvent{
+			TaskID: t.id,
+			Why:    r.LastError(),
+		})
+	}
+
+	delay := suspendRetryInterval
+	for i := 0; i < suspendRetries; i++ {
+		select {
+		case <-t.killChan:
+			t.stopped("task stopped", t.stopSource)
+			return true
+		case <-t.clock.After(delay):
+		}
+		if r = t.retry(); !r.Unavailable() {
+			t.resume("metric manager is available")
+			return false
+		}
+		delay *= 2
+		if delay > maxSuspendRetryInterval {
+			delay = maxSuspendRetryInterval
+		}
+	}
+	if t.handleFailureLimit("suspend", suspendRetries+1) {
+		return true
+	}
+	t.resume("failure limit reached")
+	return false
+}
+
+// resume puts a suspended task back to running
+func (t *task) resume(reason string) {
+	if err := t.transition(core.TaskSpinning, reason, core.TaskSuspended); err != nil {
+		return
+	}
+	t.eventEmitter.Emit(&scheduler_event.TaskResumedEvent{TaskID: t.id})
+}
+
+// beginRun starts collecting the result of a run of the workflow
+func (t *task) beginRun(start time.Time) {
+	t.failureMutex.Lock()
+	defer t.failureMutex.Unlock()
+	t.run = newRunResult(start)
+}
+
+// endRun completes the result of the current run, records it as the last run
+// of the task and emits a scheduler_event.TaskRunCompletedEvent
+func (t *task) endRun() *RunResult {
+	t.failureMutex.Lock()
+	r := t.run
+	t.run = nil
+	r.Duration = t.clock.Now().Sub(r.StartTime)
+	if !r.Success() {
+		t.failedRuns++
+	}
+	t.lastRun = r
+	t.failureMutex.Unlock()
+
+	event := &scheduler_event.TaskRunCompletedEvent{
+		TaskID:        t.id,
+		StartTime:     r.StartTime,
+		Duration:      r.Duration,
+		Success:       r.Success(),
+		Partial:       r.Partial(),
+		Errors:        r.Errors,
+		FailedPlugins: r.FailedPlugins,
+	}
+	t.eventEmitter.Emit(event)
+	return r
+}
+
+// LastRunResult returns the result of the last run of the task or nil if the
+// task has not run yet
+func (t *task) LastRunResult() *RunResult {
+	t.failureMutex.Lock()
+	defer t.failureMutex.Unlock()
+	return t.lastRun
+}
+
+// recordCollected adds the number of metrics collected to the current run
+func (t *task) recordCollected(n int) {
+	t.failureMutex.Lock()
+	defer t.failureMutex.Unlock()
+	if t.run != nil {
+		t.run.Collected += n
+	}
+}
+
+// handleFailureLimit applies the failure policy of the task once it reached
+// its limit of consecutive failures.  It returns true when the task is no
+// longer running and the caller must return.
+func (t *task) handleFailureLimit(block string, consecutiveFailures int) bool {
+	fields := log.Fields{
+		"_block":               block,
+		"task-id":              t.id,
+		"task-name":            t.name,
+		"consecutive failures": consecutiveFailures,
+		"failure policy":       t.failurePolicy.String(),
+		"error":                t.lastFailureMessage,
+	}
+	switch t.failurePolicy {
+	case core.FailurePolicyStop:
+		taskLogger.WithFields(fields).Error(ErrTaskStoppedOnFailures)
+		t.stopped(fmt.Sprintf("%s: %s", ErrTaskStoppedOnFailures, t.lastFailureMessage), "")
+		return true
+	case core.FailurePolicyAlert:
+		taskLogger.WithFields(fields).Warn(ErrTaskFailureLimitReached)
+		t.alertFailureLimit(consecutiveFailures)
+		return false
+	case core.FailurePolicyRestart:
+		fields["cooldown"] = t.failureCooldown
+		taskLogger.WithFields(fields).Warn(ErrTaskFailureLimitReached)
+		t.alertFailureLimit(consecutiveFailures)
+		select {
+		case <-t.killChan:
+			t.stopped("task stopped", t.stopSource)
+			return true
+		case <-t.clock.After(t.failureCooldown):
+		}
+		// misses are not tracked for the cooldown period
+		t.Lock()
+		t.lastFireTime = time.Time{}
+		t.Unlock()
+		return false
+	default:
+		taskLogger.WithFields(fields).Error(ErrTaskDisabledOnFailures)
+		// disable the task
+		t.disable(t.lastFailureMessage)
+		return true
+	}
+}
+
+// logTransitionError logs an illegal transition of the task, e.g. a task
+// killed while it was stopping
+func (t *task) logTransitionError(block string, err error) {
+	taskLogger.WithFields(log.Fields{
+		"_block":    block,
+		"task-id":   t.id,
+		"task-name": t.name,
+		"_error":    err.Error(),
+	}).Debug("task state unchanged")
+}
+
+// StateHistory returns the timeline of the state transitions of the task,
+// oldest first.
+func (t *task) StateHistory() []core.TaskStateTransition {
+	return t.history.list()
+}
+
+func stopReason(source string) string {
+	if source == "" {
+		return "stop requested"
+	}
+	return fmt.Sprintf("stop requested by %s", source)
+}
+
+// stopped changes the task state to stopped and emits an appropriate event
+// with the source of the stop.  A task killed meanwhile stays disabled.
+func (t *task) stopped(reason, source string) {
 	t.Lock()
-	t.state = core.TaskDisabled
+	err := t.transition(core.TaskStopped, reason, core.TaskSpinning, core.TaskSuspended, core.TaskStopping)
+	t.lastFireTime = time.Time{}
 	t.Unlock()
+	if err != nil {
+		t.logTransitionError("stopped", err)
+		// the routine of a killed task stops all the same and its plugins
+		// are unsubscribed on the event
+		if e, ok := err.(*TransitionError); !ok || e.From != core.TaskDisabled {
+			return
+		}
+	}
+
+	event := new(scheduler_event.TaskStoppedEvent)
+	event.TaskID = t.id
+	event.TaskName = t.name
+	event.TaskLabels = t.labels
+	event.Source = source
+	event.Why = reason
+	defer t.eventEmitter.Emit(event)
+}
+
+// alertFailureLimit emits an event telling the task reached its limit of
+// consecutive failures but keeps running
+func (t *task) alertFailureLimit(consecutiveFailures int) {
+	event := new(scheduler_event.TaskFailureLimitReachedEvent)
+	event.TaskID = t.id
+	event.ConsecutiveFailures = consecutiveFailures
+	event.Policy = t.failurePolicy.String()
+	event.Why = t.lastFailureMessage
+	defer t.eventEmitter.Emit(event)
+}
+
+// disable proceeds disabling a task which consists of changing task state to disabled and emitting an appropriate event
+func (t *task) disable(failureMsg string) {
+	why := fmt.Sprintf("Task disabled with error: %s", failureMsg)
+	if err := t.transition(core.TaskDisabled, why); err != nil {
+		t.logTransitionError("disable", err)
+		return
+	}
 
 	// Send task disabled event
 	event := new(scheduler_event.TaskDisabledEvent)
 	event.TaskID = t.id
-	event.Why = fmt.Sprintf("Task disabled with error: %s", failureMsg)
+	event.TaskName = t.name
+	event.TaskLabels = t.labels
+	event.Why = why
 	defer t.eventEmitter.Emit(event)
 }
 
@@ -569,34 +1286,52 @@ func (t *task) waitForSchedule() {
 	}
 }
 
-// RecordFailure updates the failed runs and last failure properties
+// RecordFailure updates the failed runs and last failure properties.
+// Failures recorded during a run are added to the result of the run which is
+// counted once as a failed run when it ends.
 func (t *task) RecordFailure(e []error) {
 	// We synchronize this update to ensure it is atomic
 	t.failureMutex.Lock()
 	defer t.failureMutex.Unlock()
-	t.failedRuns++
-	t.lastFailureTime = t.lastFireTime
+	if t.run != nil {
+		t.run.Errors = append(t.run.Errors, e...)
+		t.run.FailedPlugins = failedPlugins(t.run.Errors)
+	} else {
+		t.failedRuns++
+	}
 	t.lastFailureMessage = e[len(e)-1].Error()
 }
 
+// taskCollection holds the tasks of the scheduler.  The reads, e.g. the
+// status polling of the REST API, share the lock; only adding and removing
+// tasks take it exclusively.
 type taskCollection struct {
-	*sync.Mutex
+	*sync.RWMutex
 
 	table map[string]*task
+	// ids are the ids of the tasks in order, which the listings of the
+	// tasks are paginated by
+	ids []string
+	// byName and byLabel index the tasks by name and by label, formatted as
+	// key=value
+	byName  map[string]map[string]*task
+	byLabel map[string]map[string]*task
 }
 
 func newTaskCollection() *taskCollection {
 	return &taskCollection{
-		Mutex: &sync.Mutex{},
+		RWMutex: &sync.RWMutex{},
 
-		table: make(map[string]*task),
+		table:   make(map[string]*task),
+		byName:  make(map[string]map[string]*task),
+		byLabel: make(map[string]map[string]*task),
 	}
 }
 
 // Get given a task id returns a Task or nil if not found
 func (t *taskCollection) Get(id string) *task {
-	t.Lock()
-	defer t.Unlock()
+	t.RLock()
+	defer t.RUnlock()
 
 	if t, ok := t.table[id]; ok {
 		return t
@@ -613,6 +1348,7 @@ func (t *taskCollection) add(task *task) error {
 	if _, ok := t.table[task.id]; !ok {
 		//If we don't already have this task in the collection save it
 		t.table[task.id] = task
+		t.index(task)
 	} else {
 		taskLogger.WithFields(log.Fields{
 			"_module": "scheduler-taskCollection",
@@ -631,7 +1367,7 @@ func (t *taskCollection) remove(task *task) error {
 	t.Lock()
 	defer t.Unlock()
 	if _, ok := t.table[task.id]; ok {
-		if task.state != core.TaskStopped && task.state != core.TaskDisabled && task.state != core.TaskEnded {
+		if state := task.State(); state != core.TaskStopped && state != core.TaskDisabled && state != core.TaskEnded {
 			taskLogger.WithFields(log.Fields{
 				"_block":  "remove",
 				"task id": task.id,
@@ -639,6 +1375,7 @@ func (t *taskCollection) remove(task *task) error {
 			return ErrTaskNotStopped
 		}
 		delete(t.table, task.id)
+		t.unindex(task)
 	} else {
 		taskLogger.WithFields(log.Fields{
 			"_block":  "remove",
@@ -649,11 +1386,113 @@ func (t *taskCollection) remove(task *task) error {
 	return nil
 }
 
+// index adds the task to the ids and the indexes; the name and the labels
+// of a task do not change once it is created
+func (t *taskCollection) index(task *task) {
+	i := sort.SearchStrings(t.ids, task.id)
+	t.ids = append(t.ids, "")
+	copy(t.ids[i+1:], t.ids[i:])
+	t.ids[i] = task.id
+	addToIndex(t.byName, task.name, task)
+	for k, v := range task.labels {
+		addToIndex(t.byLabel, labelKey(k, v), task)
+	}
+}
+
+func (t *taskCollection) unindex(task *task) {
+	if i := sort.SearchStrings(t.ids, task.id); i < len(t.ids) && t.ids[i] == task.id {
+		t.ids = append(t.ids[:i], t.ids[i+1:]...)
+	}
+	removeFromIndex(t.byName, task.name, task.id)
+	for k, v := range task.labels {
+		removeFromIndex(t.byLabel, labelKey(k, v), task.id)
+	}
+}
+
+func addToIndex(index map[string]map[string]*task, key string, t *task) {
+	if index[key] == nil {
+		index[key] = map[string]*task{}
+	}
+	index[key][t.id] = t
+}
+
+func removeFromIndex(index map[string]map[string]*task, key, id string) {
+	delete(index[key], id)
+	if len(index[key]) == 0 {
+		delete(index, key)
+	}
+}
+
+func labelKey(k, v string) string {
+	return k + "=" + v
+}
+
+// list returns the tasks selected by the options, in the order of their
+// ids, and the id to list the next page after, empty on the last page.  The
+// tasks are looked up in the smallest index matching the options rather
+// than in the whole table.
+func (t *taskCollection) list(o core.TaskListOptions) ([]*task, string) {
+	t.RLock()
+	defer t.RUnlock()
+	ids := t.candidates(o)
+	start := sort.Search(len(ids), func(i int) bool { return ids[i] > o.After })
+	tasks := []*task{}
+	for _, id := range ids[start:] {
+		task := t.table[id]
+		if !task.matches(o) {
+			continue
+		}
+		if o.Limit > 0 && len(tasks) == o.Limit {
+			return tasks, tasks[len(tasks)-1].id
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, ""
+}
+
+// candidates returns the sorted ids of the tasks of the smallest index
+// matching the options, all the ids without name and labels
+func (t *taskCollection) candidates(o core.TaskListOptions) []string {
+	var smallest map[string]*task
+	indexed := false
+	if o.Name != "" {
+		smallest, indexed = t.byName[o.Name], true
+	}
+	for k, v := range o.Labels {
+		if l := t.byLabel[labelKey(k, v)]; !indexed || len(l) < len(smallest) {
+			smallest, indexed = l, true
+		}
+	}
+	if !indexed {
+		return t.ids
+	}
+	ids := make([]string, 0, len(smallest))
+	for id := range smallest {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// matches returns true when the task has the name and the labels of the
+// options and is visible
+func (t *task) matches(o core.TaskListOptions) bool {
+	if o.Name != "" && t.name != o.Name {
+		return false
+	}
+	for k, v := range o.Labels {
+		if l, ok := t.labels[k]; !ok || l != v {
+			return false
+		}
+	}
+	return o.Visible == nil || o.Visible(t)
+}
+
 // Table returns a copy of the taskCollection
 func (t *taskCollection) Table() map[string]*task {
-	t.Lock()
-	defer t.Unlock()
-	tasks := make(map[string]*task)
+	t.RLock()
+	defer t.RUnlock()
+	tasks := make(map[string]*task, len(t.table))
 	for id, t := range t.table {
 		tasks[id] = t
 	}
@@ -663,10 +1502,23 @@ func (t *taskCollection) Table() map[string]*task {
 // createTaskClients walks the workflowmap and creates clients for this task
 // remoteManagers so that nodes that require proxy request can make them.
 func createTaskClients(mgrs *managers, wf *schedulerWorkflow) error {
-	return walkWorkflow(wf.processNodes, wf.publishNodes, mgrs)
+	var creds credentials.TransportCredentials
+	if sc, ok := mgrs.local.(securesRemoteControl); ok {
+		var err error
+		if creds, err = sc.RemoteControlCredentials(); err != nil {
+			return err
+		}
+	}
+	return walkWorkflow(wf.processNodes, wf.publishNodes, mgrs, creds)
+}
+
+// securesRemoteControl is implemented by a metric manager which secures the
+// connections to the remote targets of the process and publish nodes
+type securesRemoteControl interface {
+	RemoteControlCredentials() (credentials.TransportCredentials, error)
 }
 
-func walkWorkflow(prnodes []*processNode, pbnodes []*publishNode, mgrs *managers) error {
+func walkWorkflow(prnodes []*processNode, pbnodes []*publishNode, mgrs *managers, creds credentials.TransportCredentials) error {
 	for _, pr := range prnodes {
 		if pr.Target != "" {
 			host, port, err := net.SplitHostPort(pr.Target)
@@ -677,13 +1529,13 @@ func walkWorkflow(prnodes []*processNode, pbnodes []*publishNode, mgrs *managers
 			if err != nil {
 				return err
 			}
-			proxy, err := controlproxy.New(host, p)
+			proxy, err := controlproxy.NewWithCredentials(host, p, creds)
 			if err != nil {
 				return err
 			}
 			mgrs.Add(pr.Target, proxy)
 		}
-		err := walkWorkflow(pr.ProcessNodes, pr.PublishNodes, mgrs)
+		err := walkWorkflow(pr.ProcessNodes, pr.PublishNodes, mgrs, creds)
 		if err != nil {
 			return err
 		}
@@ -699,7 +1551,7 @@ func walkWorkflow(prnodes []*processNode, pbnodes []*publishNode, mgrs *managers
 			if err != nil {
 				return err
 			}
-			proxy, err := controlproxy.New(host, p)
+			proxy, err := controlproxy.NewWithCredentials(host, p, creds)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,135 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+func listedIDs(tasks []core.Task) []string {
+	ids := make([]string, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID()
+	}
+	return ids
+}
+
+func TestListTasks(t *testing.T) {
+	Convey("Given a scheduler with labelled tasks", t, func() {
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(newMockMetricManager())
+		s.SetTaskIDGenerator(&sequentialTaskIDs{node: "node"})
+		s.Start()
+		defer s.Stop()
+		create := func(name string, labels map[string]string) core.Task {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newMockWorkflowMap(), false,
+				core.SetTaskName(name), core.OptionLabels(labels))
+			So(errs.Errors(), ShouldBeEmpty)
+			return tsk
+		}
+		create("cpu", map[string]string{"team": "compute", "env": "prod"})
+		create("disk", map[string]string{"team": "storage", "env": "prod"})
+		create("cpu", map[string]string{"team": "compute", "env": "dev"})
+		create("disk", map[string]string{"team": "storage", "env": "dev"})
+		create("net", nil)
+
+		Convey("all the tasks are listed in the order of their ids", func() {
+			tasks, next := s.ListTasks(core.TaskListOptions{})
+			So(listedIDs(tasks), ShouldResemble, []string{"node-1", "node-2", "node-3", "node-4", "node-5"})
+			So(next, ShouldBeEmpty)
+		})
+		Convey("the tasks are selected by name and labels", func() {
+			tasks, _ := s.ListTasks(core.TaskListOptions{Name: "cpu"})
+			So(listedIDs(tasks), ShouldResemble, []string{"node-1", "node-3"})
+			tasks, _ = s.ListTasks(core.TaskListOptions{Labels: map[string]string{"env": "prod"}})
+			So(listedIDs(tasks), ShouldResemble, []string{"node-1", "node-2"})
+			tasks, _ = s.ListTasks(core.TaskListOptions{Name: "disk", Labels: map[string]string{"env": "dev"}})
+			So(listedIDs(tasks), ShouldResemble, []string{"node-4"})
+			tasks, _ = s.ListTasks(core.TaskListOptions{Labels: map[string]string{"team": "network"}})
+			So(tasks, ShouldBeEmpty)
+		})
+		Convey("the tasks are listed a page at a time", func() {
+			tasks, next := s.ListTasks(core.TaskListOptions{Limit: 2})
+			So(listedIDs(tasks), ShouldResemble, []string{"node-1", "node-2"})
+			So(next, ShouldEqual, "node-2")
+			tasks, next = s.ListTasks(core.TaskListOptions{Limit: 2, After: next})
+			So(listedIDs(tasks), ShouldResemble, []string{"node-3", "node-4"})
+			tasks, next = s.ListTasks(core.TaskListOptions{Limit: 2, After: next})
+			So(listedIDs(tasks), ShouldResemble, []string{"node-5"})
+			So(next, ShouldBeEmpty)
+			tasks, next = s.ListTasks(core.TaskListOptions{Limit: 1, Name: "cpu"})
+			So(listedIDs(tasks), ShouldResemble, []string{"node-1"})
+			So(next, ShouldEqual, "node-1")
+		})
+		Convey("the tasks which are not visible are left out before the tasks are paged", func() {
+			visible := func(t core.Task) bool { return t.ID() != "node-2" && t.ID() != "node-3" }
+			tasks, next := s.ListTasks(core.TaskListOptions{Limit: 2, Visible: visible})
+			So(listedIDs(tasks), ShouldResemble, []string{"node-1", "node-4"})
+			So(next, ShouldEqual, "node-4")
+			tasks, next = s.ListTasks(core.TaskListOptions{Limit: 2, After: next, Visible: visible})
+			So(listedIDs(tasks), ShouldResemble, []string{"node-5"})
+			So(next, ShouldBeEmpty)
+			tasks, next = s.ListTasks(core.TaskListOptions{Limit: 1, Visible: func(t core.Task) bool { return t.ID() == "node-5" }})
+			So(listedIDs(tasks), ShouldResemble, []string{"node-5"})
+			So(next, ShouldBeEmpty)
+		})
+		Convey("the tasks are read while others are created", func() {
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newMockWorkflowMap(), false,
+						core.OptionLabels(map[string]string{"batch": fmt.Sprint(i % 2)}))
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					s.GetTasks()
+					s.GetTask("node-1")
+					s.ListTasks(core.TaskListOptions{Labels: map[string]string{"batch": "0"}, Limit: 10})
+				}
+			}()
+			wg.Wait()
+			tasks, _ := s.ListTasks(core.TaskListOptions{Labels: map[string]string{"batch": "1"}})
+			So(len(tasks), ShouldEqual, 25)
+			So(len(s.GetTasks()), ShouldEqual, 55)
+		})
+		Convey("the removed tasks are no longer indexed", func() {
+			So(s.RemoveTask("node-1"), ShouldBeNil)
+			tasks, _ := s.ListTasks(core.TaskListOptions{Name: "cpu"})
+			So(listedIDs(tasks), ShouldResemble, []string{"node-3"})
+			tasks, _ = s.ListTasks(core.TaskListOptions{Labels: map[string]string{"team": "compute"}})
+			So(listedIDs(tasks), ShouldResemble, []string{"node-3"})
+			tasks, _ = s.ListTasks(core.TaskListOptions{})
+			So(len(tasks), ShouldEqual, 4)
+		})
+	})
+}
@@ -0,0 +1,86 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func TestTaskConfig(t *testing.T) {
+	Convey("Given a workflow with a task config", t, func() {
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/intel/mock/foo", 1)
+		w.Collect.AddMetric("/other/bar", 1)
+		w.Collect.AddConfigItem("/intel/mock", "user", "metric")
+		pr := wmap.NewProcessNode("passthru", 1)
+		pr.AddConfigItem("user", "processor")
+		pu := wmap.NewPublishNode("file", 1)
+		pr.Add(pu)
+		w.Collect.Add(pr)
+		w.Config = map[string]interface{}{"user": "task", "password": "secret", "port": float64(8080)}
+
+		wf, err := wmapToWorkflow(w)
+		So(err, ShouldBeNil)
+
+		Convey("the config of the metrics overrides it", func() {
+			cfg := wf.configTree.Get([]string{"intel", "mock", "foo"}).Table()
+			So(cfg["user"], ShouldResemble, ctypes.ConfigValueStr{Value: "metric"})
+			So(cfg["password"], ShouldResemble, ctypes.ConfigValueStr{Value: "secret"})
+			So(cfg["port"], ShouldResemble, ctypes.ConfigValueInt{Value: 8080})
+		})
+		Convey("it applies to the metrics without config", func() {
+			cfg := wf.configTree.Get([]string{"other", "bar"}).Table()
+			So(cfg["user"], ShouldResemble, ctypes.ConfigValueStr{Value: "task"})
+		})
+		Convey("the config of the nodes overrides it", func() {
+			prCfg := wf.processNodes[0].config.Table()
+			So(prCfg["user"], ShouldResemble, ctypes.ConfigValueStr{Value: "processor"})
+			So(prCfg["password"], ShouldResemble, ctypes.ConfigValueStr{Value: "secret"})
+			puCfg := wf.processNodes[0].PublishNodes[0].config.Table()
+			So(puCfg["user"], ShouldResemble, ctypes.ConfigValueStr{Value: "task"})
+		})
+		Convey("it is kept when the config tree is encoded", func() {
+			var buf bytes.Buffer
+			So(gob.NewEncoder(&buf).Encode(wf.configTree), ShouldBeNil)
+			cdt := cdata.NewTree()
+			So(gob.NewDecoder(&buf).Decode(cdt), ShouldBeNil)
+			cfg := cdt.Get([]string{"intel", "mock", "foo"}).Table()
+			So(cfg["user"], ShouldResemble, ctypes.ConfigValueStr{Value: "metric"})
+			So(cfg["password"], ShouldResemble, ctypes.ConfigValueStr{Value: "secret"})
+		})
+	})
+	Convey("Given a workflow with an invalid task config", t, func() {
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/intel/mock/foo", 1)
+		w.Config = map[string]interface{}{"hosts": []interface{}{"a", "b"}}
+		_, err := wmapToWorkflow(w)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,83 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// defaultTaskOptions holds the task options applied to every task the
+// scheduler creates, before the options of the task itself
+type defaultTaskOptions struct {
+	sync.RWMutex
+	opts []core.TaskOption
+}
+
+func (d *defaultTaskOptions) get() []core.TaskOption {
+	d.RLock()
+	defer d.RUnlock()
+	return d.opts
+}
+
+func (d *defaultTaskOptions) set(opts []core.TaskOption) {
+	d.Lock()
+	defer d.Unlock()
+	d.opts = opts
+}
+
+// SetDefaultTaskOptions sets the options applied to the tasks created from
+// now on, e.g. their deadline, max-failures or labels.  The options given
+// when a task is created take precedence; the labels of a task are added to
+// the default ones.
+func (s *scheduler) SetDefaultTaskOptions(opts ...core.TaskOption) {
+	s.taskDefaults.set(append([]core.TaskOption{}, opts...))
+}
+
+// applyTaskOptions applies the options given when a task is created over the
+// default ones it was created with; its labels are added to the default
+// labels, overriding the default value of the same label
+func applyTaskOptions(t *task, opts []core.TaskOption) {
+	defaults := t.labels
+	t.Option(opts...)
+	if len(defaults) == 0 {
+		return
+	}
+	labels := make(map[string]string, len(defaults)+len(t.labels))
+	for k, v := range defaults {
+		labels[k] = v
+	}
+	for k, v := range t.labels {
+		labels[k] = v
+	}
+	t.labels = labels
+}
+
+// taskDefaults returns the default task options of the configuration
+func taskDefaults(cfg *Config) ([]core.TaskOption, error) {
+	if cfg.TaskDefaults == nil {
+		return nil, nil
+	}
+	if err := cfg.TaskDefaults.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg.TaskDefaults.Options()
+}
@@ -0,0 +1,86 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+func TestDefaultTaskOptions(t *testing.T) {
+	Convey("Given a scheduler with default task options in its configuration", t, func() {
+		cfg := GetDefaultConfig()
+		cfg.TaskDefaults = &core.TaskPreset{
+			Deadline:    "3s",
+			MaxFailures: 7,
+			Labels:      map[string]string{"env": "prod"},
+		}
+		s := New(cfg)
+		s.SetMetricManager(newMockMetricManager())
+		s.Start()
+		defer s.Stop()
+		create := func(opts ...core.TaskOption) core.Task {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newMockWorkflowMap(), false, opts...)
+			So(errs.Errors(), ShouldBeEmpty)
+			return tsk
+		}
+
+		Convey("the tasks get the default options", func() {
+			tsk := create()
+			So(tsk.DeadlineDuration(), ShouldEqual, 3*time.Second)
+			So(tsk.GetStopOnFailure(), ShouldEqual, 7)
+			So(tsk.GetLabels(), ShouldResemble, map[string]string{"env": "prod"})
+		})
+		Convey("the options of a task take precedence", func() {
+			tsk := create(core.TaskDeadlineDuration(time.Second), core.OptionLabels(map[string]string{"team": "ops"}))
+			So(tsk.DeadlineDuration(), ShouldEqual, time.Second)
+			So(tsk.GetStopOnFailure(), ShouldEqual, 7)
+			So(tsk.GetLabels(), ShouldResemble, map[string]string{"env": "prod", "team": "ops"})
+		})
+		Convey("the labels of a task override the default labels of the same name", func() {
+			tsk := create(core.OptionLabels(map[string]string{"env": "dev"}))
+			So(tsk.GetLabels(), ShouldResemble, map[string]string{"env": "dev"})
+		})
+		Convey("the default options set afterwards apply to the tasks created from then on", func() {
+			before := create()
+			s.SetDefaultTaskOptions(core.OptionStopOnFailure(2))
+			tsk := create()
+			So(tsk.GetStopOnFailure(), ShouldEqual, 2)
+			So(tsk.DeadlineDuration(), ShouldEqual, DefaultDeadlineDuration)
+			So(before.GetStopOnFailure(), ShouldEqual, 7)
+		})
+		Convey("the default options are reloaded with the configuration", func() {
+			reloaded := GetDefaultConfig()
+			reloaded.TaskDefaults = &core.TaskPreset{Deadline: "4s"}
+			So(s.Reload(reloaded), ShouldBeNil)
+			So(create().DeadlineDuration(), ShouldEqual, 4*time.Second)
+			reloaded.TaskDefaults = &core.TaskPreset{Deadline: "soon"}
+			So(s.Reload(reloaded), ShouldNotBeNil)
+			So(create().DeadlineDuration(), ShouldEqual, 4*time.Second)
+		})
+	})
+}
@@ -0,0 +1,98 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+// erroringSchedule errors on its first wait
+type erroringSchedule struct{}
+
+func (erroringSchedule) GetState() schedule.ScheduleState { return schedule.Active }
+func (erroringSchedule) Validate() error                  { return nil }
+func (erroringSchedule) Wait(time.Time) schedule.Response { return erroringResponse{} }
+
+type erroringResponse struct{}
+
+func (erroringResponse) Error() error                  { return errors.New("schedule is invalid") }
+func (erroringResponse) State() schedule.ScheduleState { return schedule.Error }
+func (erroringResponse) Missed() uint                  { return 0 }
+func (erroringResponse) LastTime() time.Time           { return time.Time{} }
+
+func TestTaskEndEvents(t *testing.T) {
+	Convey("Given a scheduler", t, func() {
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(newChurningMetricManager())
+		s.Start()
+		defer s.Stop()
+		h := &recordingHandler{}
+		s.eventManager.RegisterHandler("test", h)
+		labels := map[string]string{"team": "compute"}
+		endEvent := func(namespace string) scheduler_event.TaskEndEvent {
+			for i := 0; i < 200; i++ {
+				for _, e := range h.received() {
+					if te, ok := e.Body.(scheduler_event.TaskEndEvent); ok && e.Namespace() == namespace {
+						return te
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			return nil
+		}
+
+		Convey("a task whose schedule errors emits an errored then a disabled event", func() {
+			tsk, errs := s.CreateTask(erroringSchedule{}, newMockWorkflowMap(), true,
+				core.SetTaskName("cpu"), core.OptionLabels(labels))
+			So(errs.Errors(), ShouldBeEmpty)
+			So(waitForState(tsk, core.TaskDisabled), ShouldEqual, core.TaskDisabled)
+			for _, namespace := range []string{scheduler_event.TaskErrored, scheduler_event.TaskDisabled} {
+				e := endEvent(namespace)
+				So(e, ShouldNotBeNil)
+				So(e.GetTaskID(), ShouldEqual, tsk.ID())
+				So(e.GetTaskName(), ShouldEqual, "cpu")
+				So(e.GetTaskLabels(), ShouldResemble, labels)
+				So(e.GetWhy(), ShouldContainSubstring, "schedule is invalid")
+			}
+		})
+		Convey("a task whose schedule ends emits an ended event", func() {
+			stop := time.Now().Add(50 * time.Millisecond)
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(10*time.Millisecond, nil, &stop, 0), newMockWorkflowMap(), true,
+				core.SetTaskName("disk"), core.OptionLabels(labels))
+			So(errs.Errors(), ShouldBeEmpty)
+			e := endEvent(scheduler_event.TaskEnded)
+			So(e, ShouldNotBeNil)
+			So(e.GetTaskID(), ShouldEqual, tsk.ID())
+			So(e.GetTaskName(), ShouldEqual, "disk")
+			So(e.GetTaskLabels(), ShouldResemble, labels)
+			So(e.GetWhy(), ShouldEqual, "schedule ended")
+		})
+	})
+}
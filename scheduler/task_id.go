@@ -0,0 +1,73 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/pborman/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// generatesTaskIDs generates the ids of the tasks created by the scheduler.
+// The ids must be unique across the restarts of snapteld and the members of
+// a cluster, as the tasks are persisted and shared under their ids.
+type generatesTaskIDs interface {
+	NewTaskID() string
+}
+
+// uuidTaskIDs generates random UUIDs, the default ids of the tasks
+type uuidTaskIDs struct{}
+
+func (uuidTaskIDs) NewTaskID() string {
+	return uuid.New()
+}
+
+// SetTaskIDGenerator sets the generator of the ids of the tasks created from
+// now on.  Without it, the tasks get random UUIDs.  The tasks imported with
+// their id keep it.
+func (s *scheduler) SetTaskIDGenerator(g generatesTaskIDs) {
+	if g == nil {
+		g = uuidTaskIDs{}
+	}
+	s.taskIDs = g
+	schedulerLogger.WithFields(log.Fields{
+		"_block":    "set-task-id-generator",
+		"generator": fmt.Sprintf("%T", g),
+	}).Debug("task id generator linked")
+}
+
+// defaultTaskName names a task created without a name
+func defaultTaskName(id string) string {
+	return fmt.Sprintf("Task-%s", id)
+}
+
+// withTaskID gives a new task its id and the default name derived from it;
+// the options given when creating the task apply after it
+func withTaskID(id string) core.TaskOption {
+	return func(t core.Task) core.TaskOption {
+		previous := t.ID()
+		t.SetID(id)
+		t.SetName(defaultTaskName(id))
+		return withTaskID(previous)
+	}
+}
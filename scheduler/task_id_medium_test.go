@@ -0,0 +1,94 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pborman/uuid"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+// sequentialTaskIDs numbers the tasks of a node
+type sequentialTaskIDs struct {
+	node string
+	n    int
+}
+
+func (s *sequentialTaskIDs) NewTaskID() string {
+	s.n++
+	return fmt.Sprintf("%s-%d", s.node, s.n)
+}
+
+func TestTaskIDGenerator(t *testing.T) {
+	Convey("Given a scheduler", t, func() {
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(newMockMetricManager())
+		s.Start()
+		defer s.Stop()
+		create := func(opts ...core.TaskOption) (core.Task, []error) {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newMockWorkflowMap(), false, opts...)
+			var es []error
+			for _, e := range errs.Errors() {
+				es = append(es, e)
+			}
+			return tsk, es
+		}
+
+		Convey("the tasks get random UUIDs by default", func() {
+			tsk, errs := create()
+			So(errs, ShouldBeEmpty)
+			So(uuid.Parse(tsk.ID()), ShouldNotBeNil)
+			So(tsk.GetName(), ShouldEqual, "Task-"+tsk.ID())
+		})
+		Convey("with a generator", func() {
+			s.SetTaskIDGenerator(&sequentialTaskIDs{node: "node1"})
+			Convey("the tasks get the generated ids", func() {
+				tsk, errs := create()
+				So(errs, ShouldBeEmpty)
+				So(tsk.ID(), ShouldEqual, "node1-1")
+				So(tsk.GetName(), ShouldEqual, "Task-node1-1")
+				tsk, errs = create(core.SetTaskName("named"))
+				So(errs, ShouldBeEmpty)
+				So(tsk.ID(), ShouldEqual, "node1-2")
+				So(tsk.GetName(), ShouldEqual, "named")
+			})
+			Convey("a task imported with its id keeps it", func() {
+				tsk, errs := create(core.SetTaskID("imported"))
+				So(errs, ShouldBeEmpty)
+				So(tsk.ID(), ShouldEqual, "imported")
+				_, errs = create(core.SetTaskID("imported"))
+				So(errs, ShouldNotBeEmpty)
+			})
+			Convey("a nil generator restores the UUIDs", func() {
+				s.SetTaskIDGenerator(nil)
+				tsk, errs := create()
+				So(errs, ShouldBeEmpty)
+				So(uuid.Parse(tsk.ID()), ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,118 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// taskTransitions are the legal transitions between the states of a task.
+// A task is started from stopped or ended, alternates between spinning and
+// firing while it runs, and leaves running through stopping when stopped,
+// or directly when its schedule ends or its failure policy applies.  A
+// disabled task is enabled back to stopped.
+var taskTransitions = map[core.TaskState][]core.TaskState{
+	core.TaskStopped:   {core.TaskSpinning},
+	core.TaskEnded:     {core.TaskSpinning},
+	core.TaskSpinning:  {core.TaskFiring, core.TaskSuspended, core.TaskStopping, core.TaskStopped, core.TaskEnded, core.TaskDisabled},
+	core.TaskFiring:    {core.TaskSpinning, core.TaskStopping, core.TaskDisabled},
+	core.TaskSuspended: {core.TaskSpinning, core.TaskStopping, core.TaskStopped, core.TaskDisabled},
+	core.TaskStopping:  {core.TaskStopped, core.TaskEnded, core.TaskDisabled},
+	core.TaskDisabled:  {core.TaskStopped},
+}
+
+// taskStateNames name the states of the tasks; unlike core.TaskStateLookup
+// they tell spinning and firing apart
+var taskStateNames = map[core.TaskState]string{
+	core.TaskDisabled:  "Disabled",
+	core.TaskStopped:   "Stopped",
+	core.TaskSpinning:  "Spinning",
+	core.TaskFiring:    "Firing",
+	core.TaskEnded:     "Ended",
+	core.TaskStopping:  "Stopping",
+	core.TaskSuspended: "Suspended",
+}
+
+// TransitionError is the error of an illegal transition of a task between
+// two states; the state of the task is left unchanged.
+type TransitionError struct {
+	TaskID string
+	From   core.TaskState
+	To     core.TaskState
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("Illegal transition of task %s from %s to %s", e.TaskID, taskStateNames[e.From], taskStateNames[e.To])
+}
+
+// transitionHook is called on every transition of the state of a task
+type transitionHook func(from, to core.TaskState, reason string)
+
+// canTransition returns true when a task can go from a state to the other
+func canTransition(from, to core.TaskState) bool {
+	for _, s := range taskTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transition changes the state of the task to the given state and calls
+// the transition hooks.  When states are given, the task must be in one of
+// them.  A *TransitionError is returned, and the state is unchanged, when
+// the transition is illegal.  The hooks are called with the state locked
+// and must not change it.
+func (t *task) transition(to core.TaskState, reason string, from ...core.TaskState) error {
+	t.stateMutex.Lock()
+	defer t.stateMutex.Unlock()
+	if !canTransition(t.state, to) || (len(from) > 0 && !stateIn(t.state, from)) {
+		return &TransitionError{TaskID: t.id, From: t.state, To: to}
+	}
+	prev := t.state
+	t.state = to
+	for _, h := range t.transitionHooks {
+		h(prev, to, reason)
+	}
+	return nil
+}
+
+// onTransition adds a hook called on every transition of the state of the
+// task
+func (t *task) onTransition(h transitionHook) {
+	t.stateMutex.Lock()
+	defer t.stateMutex.Unlock()
+	t.transitionHooks = append(t.transitionHooks, h)
+}
+
+func stateIn(state core.TaskState, states []core.TaskState) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// isStarted returns true when the task was started and is not stopping
+func isStarted(state core.TaskState) bool {
+	return state == core.TaskSpinning || state == core.TaskFiring || state == core.TaskSuspended
+}
@@ -0,0 +1,108 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+// transitionRecorder records the transitions of a task
+type transitionRecorder struct {
+	sync.Mutex
+	transitions [][2]core.TaskState
+}
+
+func (r *transitionRecorder) record(from, to core.TaskState, _ string) {
+	r.Lock()
+	defer r.Unlock()
+	r.transitions = append(r.transitions, [2]core.TaskState{from, to})
+}
+
+func (r *transitionRecorder) states() [][2]core.TaskState {
+	r.Lock()
+	defer r.Unlock()
+	return append([][2]core.TaskState{}, r.transitions...)
+}
+
+func TestTaskStateMachine(t *testing.T) {
+	Convey("The transitions between the states of a task", t, func() {
+		So(canTransition(core.TaskStopped, core.TaskSpinning), ShouldBeTrue)
+		So(canTransition(core.TaskSpinning, core.TaskFiring), ShouldBeTrue)
+		So(canTransition(core.TaskFiring, core.TaskSpinning), ShouldBeTrue)
+		So(canTransition(core.TaskSpinning, core.TaskEnded), ShouldBeTrue)
+		So(canTransition(core.TaskStopping, core.TaskStopped), ShouldBeTrue)
+		So(canTransition(core.TaskDisabled, core.TaskStopped), ShouldBeTrue)
+		So(canTransition(core.TaskStopped, core.TaskFiring), ShouldBeFalse)
+		So(canTransition(core.TaskDisabled, core.TaskSpinning), ShouldBeFalse)
+		So(canTransition(core.TaskStopping, core.TaskFiring), ShouldBeFalse)
+		So(canTransition(core.TaskSpinning, core.TaskSpinning), ShouldBeFalse)
+	})
+	Convey("Given a task", t, func() {
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(newChurningMetricManager())
+		s.Start()
+		defer s.Stop()
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), newMockWorkflowMap(), false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+		r := &transitionRecorder{}
+		tk.onTransition(r.record)
+
+		Convey("an illegal transition returns a typed error and keeps the state", func() {
+			err := tk.transition(core.TaskFiring, "task fired")
+			So(err, ShouldHaveSameTypeAs, &TransitionError{})
+			So(err.(*TransitionError).From, ShouldEqual, core.TaskStopped)
+			So(err.(*TransitionError).To, ShouldEqual, core.TaskFiring)
+			So(err.Error(), ShouldContainSubstring, "from Stopped to Firing")
+			So(tk.State(), ShouldEqual, core.TaskStopped)
+			So(r.states(), ShouldBeEmpty)
+		})
+		Convey("a transition from a state the task is not in is illegal", func() {
+			So(tk.transition(core.TaskSpinning, "resumed", core.TaskSuspended), ShouldNotBeNil)
+			So(tk.State(), ShouldEqual, core.TaskStopped)
+		})
+		Convey("the hooks see the transitions of the task started and stopped", func() {
+			So(s.StartTask(tk.ID()), ShouldBeEmpty)
+			So(s.StopTask(tk.ID()), ShouldBeEmpty)
+			So(waitForState(tk, core.TaskStopped), ShouldEqual, core.TaskStopped)
+			states := r.states()
+			So(states[0], ShouldResemble, [2]core.TaskState{core.TaskStopped, core.TaskSpinning})
+			So(states[len(states)-1], ShouldResemble, [2]core.TaskState{core.TaskStopping, core.TaskStopped})
+		})
+		Convey("a killed task stays disabled once its routine stopped", func() {
+			So(s.StartTask(tk.ID()), ShouldBeEmpty)
+			tk.Kill()
+			time.Sleep(50 * time.Millisecond)
+			So(tk.State(), ShouldEqual, core.TaskDisabled)
+			So(s.StartTask(tk.ID()), ShouldNotBeEmpty)
+		})
+		Convey("a task which is not disabled is not enabled", func() {
+			So(tk.Enable(), ShouldEqual, ErrTaskNotDisabled)
+		})
+	})
+}
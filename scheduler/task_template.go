@@ -0,0 +1,205 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+var templateLogger = schedulerLogger.WithField("_block", "task-templates")
+
+// taskTemplates holds the task templates by name, stored in a directory, one
+// <name>.json file per template, when a path is set
+type taskTemplates struct {
+	path string
+
+	sync.RWMutex
+	templates map[string]*core.TaskTemplate
+}
+
+// newTaskTemplates returns the task templates stored in the directory at
+// path, which is created if needed.  Files which are not valid templates are
+// skipped.
+func newTaskTemplates(path string) (*taskTemplates, error) {
+	tt := &taskTemplates{
+		path:      path,
+		templates: map[string]*core.TaskTemplate{},
+	}
+	if path == "" {
+		return tt, nil
+	}
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, fmt.Errorf("%v (while parsing 'scheduler::task_template_path')", err)
+	}
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("%v (while parsing 'scheduler::task_template_path')", err)
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		t, err := readTaskTemplate(filepath.Join(path, file.Name()))
+		if err != nil {
+			templateLogger.WithFields(log.Fields{
+				"_error": err.Error(),
+				"file":   file.Name(),
+			}).Error("unable to read task template")
+			continue
+		}
+		tt.templates[t.Name] = t
+	}
+	return tt, nil
+}
+
+func readTaskTemplate(file string) (*core.TaskTemplate, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	t := &core.TaskTemplate{}
+	if err := json.Unmarshal(b, t); err != nil {
+		return nil, err
+	}
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (tt *taskTemplates) file(name string) string {
+	return filepath.Join(tt.path, name+".json")
+}
+
+func (tt *taskTemplates) add(t *core.TaskTemplate) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	tt.Lock()
+	defer tt.Unlock()
+	if _, ok := tt.templates[t.Name]; ok {
+		return fmt.Errorf("%v '%s'", core.ErrTaskTemplateExists, t.Name)
+	}
+	if tt.path != "" {
+		b, err := json.MarshalIndent(t, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(tt.file(t.Name), b, 0600); err != nil {
+			return err
+		}
+	}
+	tt.templates[t.Name] = t
+	return nil
+}
+
+func (tt *taskTemplates) remove(name string) error {
+	tt.Lock()
+	defer tt.Unlock()
+	if _, ok := tt.templates[name]; !ok {
+		return fmt.Errorf("%v '%s'", core.ErrUnknownTaskTemplate, name)
+	}
+	if tt.path != "" {
+		if err := os.Remove(tt.file(name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	delete(tt.templates, name)
+	return nil
+}
+
+func (tt *taskTemplates) get(name string) (*core.TaskTemplate, error) {
+	tt.RLock()
+	defer tt.RUnlock()
+	t, ok := tt.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%v '%s'", core.ErrUnknownTaskTemplate, name)
+	}
+	return t, nil
+}
+
+// list returns the templates sorted by name
+func (tt *taskTemplates) list() []*core.TaskTemplate {
+	tt.RLock()
+	defer tt.RUnlock()
+	names := make([]string, 0, len(tt.templates))
+	for name := range tt.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	templates := make([]*core.TaskTemplate, len(names))
+	for i, name := range names {
+		templates[i] = tt.templates[name]
+	}
+	return templates
+}
+
+// AddTaskTemplate registers a task template the tasks can then be created
+// from, stored in the template directory of the configuration if any.
+func (s *scheduler) AddTaskTemplate(t *core.TaskTemplate) error {
+	if err := s.templates.add(t); err != nil {
+		return err
+	}
+	templateLogger.WithField("template", t.Name).Info("task template registered")
+	return nil
+}
+
+// RemoveTaskTemplate removes a task template.  The tasks created from it are
+// not affected.
+func (s *scheduler) RemoveTaskTemplate(name string) error {
+	if err := s.templates.remove(name); err != nil {
+		return err
+	}
+	templateLogger.WithField("template", name).Info("task template removed")
+	return nil
+}
+
+// GetTaskTemplate returns the task template of the given name
+func (s *scheduler) GetTaskTemplate(name string) (*core.TaskTemplate, error) {
+	return s.templates.get(name)
+}
+
+// GetTaskTemplates returns the task templates sorted by name
+func (s *scheduler) GetTaskTemplates() []*core.TaskTemplate {
+	return s.templates.list()
+}
+
+// expandTemplate expands the task template the request refers to, if any
+func (s *scheduler) expandTemplate(tr *core.TaskCreationRequest) error {
+	if tr.Template == "" {
+		return nil
+	}
+	t, err := s.templates.get(tr.Template)
+	if err != nil {
+		return err
+	}
+	return t.Expand(tr)
+}
@@ -0,0 +1,92 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+func TestTaskTemplates(t *testing.T) {
+	Convey("Given a scheduler storing its task templates in a directory", t, func() {
+		dir, err := ioutil.TempDir("", "snap-templates")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		cfg := GetDefaultConfig()
+		cfg.TaskTemplatePath = dir
+		s := New(cfg)
+		s.SetMetricManager(newMockMetricManager())
+		s.Start()
+		defer s.Stop()
+		template := &core.TaskTemplate{
+			Name:      "foo",
+			Variables: []core.TaskTemplateVariable{{Name: "interval"}},
+			Task: json.RawMessage(`{
+				"schedule": {"type": "simple", "interval": "{{interval}}"},
+				"workflow": {"collect": {"metrics": {"/foo/bar": {}}}}
+			}`),
+		}
+		So(s.AddTaskTemplate(template), ShouldBeNil)
+
+		Convey("the template is listed and read back from the directory", func() {
+			So(s.GetTaskTemplates(), ShouldResemble, []*core.TaskTemplate{template})
+			templates, err := newTaskTemplates(dir)
+			So(err, ShouldBeNil)
+			read, err := templates.get("foo")
+			So(err, ShouldBeNil)
+			So(read.Name, ShouldEqual, "foo")
+			So(read.Variables, ShouldResemble, template.Variables)
+		})
+		Convey("a template is only registered once", func() {
+			err := s.AddTaskTemplate(template)
+			So(err.Error(), ShouldStartWith, core.ErrTaskTemplateExists.Error())
+		})
+		Convey("a task is created from the template", func() {
+			body := ioutil.NopCloser(bytes.NewBufferString(`{"name": "t1", "template": "foo", "variables": {"interval": "2s"}}`))
+			tsk, err := core.CreateTaskFromContent(body, nil, s.CreateTask, s)
+			So(err, ShouldBeNil)
+			So(tsk.GetName(), ShouldEqual, "t1")
+			sch, err := core.ScheduleFromSchedule(tsk.Schedule())
+			So(err, ShouldBeNil)
+			So(sch.Interval, ShouldEqual, "2s")
+		})
+		Convey("a task referring to an unknown template is rejected", func() {
+			body := ioutil.NopCloser(bytes.NewBufferString(`{"template": "bar"}`))
+			_, err := core.CreateTaskFromContent(body, nil, s.CreateTask, s)
+			So(err.Error(), ShouldStartWith, core.ErrUnknownTaskTemplate.Error())
+		})
+		Convey("the template is removed with its file", func() {
+			So(s.RemoveTaskTemplate("foo"), ShouldBeNil)
+			So(s.GetTaskTemplates(), ShouldBeEmpty)
+			_, err := os.Stat(s.templates.file("foo"))
+			So(os.IsNotExist(err), ShouldBeTrue)
+			So(s.RemoveTaskTemplate("foo"), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,83 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// slowCollector takes delay to collect its metrics
+type slowCollector struct {
+	*publishRecorder
+	delay time.Duration
+}
+
+func (m *slowCollector) CollectMetrics(id string, tags map[string]map[string]string) ([]core.Metric, []error) {
+	time.Sleep(m.delay)
+	return m.publishRecorder.CollectMetrics(id, tags)
+}
+
+func TestTaskTimeouts(t *testing.T) {
+	Convey("Given a scheduler with a default publish timeout", t, func() {
+		cfg := GetDefaultConfig()
+		cfg.PublishTimeout.Duration = 3 * time.Second
+		s := New(cfg)
+		mm := &slowCollector{
+			publishRecorder: &publishRecorder{mockMetricManager: newMockMetricManager()},
+			delay:           200 * time.Millisecond,
+		}
+		s.SetMetricManager(mm)
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		w.Collect.Add(wmap.NewPublishNode("file", -1))
+		create := func(opts ...core.TaskOption) *task {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false, opts...)
+			So(errs.Errors(), ShouldBeEmpty)
+			return tsk.(*task)
+		}
+
+		Convey("the phases without a timeout fall back to the default, then to the deadline", func() {
+			tk := create(core.TaskDeadlineDuration(time.Second), core.OptionTimeouts(core.TaskTimeouts{Collect: 2 * time.Second}))
+			So(tk.collectTimeout(), ShouldEqual, 2*time.Second)
+			So(tk.processTimeout(), ShouldEqual, time.Second)
+			So(tk.publishTimeout(), ShouldEqual, 3*time.Second)
+			So(tk.GetTimeouts(), ShouldResemble, core.TaskTimeouts{Collect: 2 * time.Second})
+		})
+		Convey("a slow collection does not get the publishing refused", func() {
+			tk := create(core.TaskDeadlineDuration(100 * time.Millisecond))
+			tk.killChan = make(chan struct{})
+			r := tk.fire()
+			So(r.Errors, ShouldBeEmpty)
+			So(mm.published(), ShouldResemble, []int{2})
+		})
+
+		s.Stop()
+	})
+}
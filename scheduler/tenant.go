@@ -0,0 +1,114 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+var (
+	// ErrTenantTaskQuota - The error message for a task created beyond the quota of tasks of its tenant
+	ErrTenantTaskQuota = errors.New("tenant quota exceeded (maximum number of tasks reached)")
+)
+
+// TenantQuota caps the resources used by the tasks of a tenant.  A zero
+// value does not cap them.
+type TenantQuota struct {
+	// MaxTasks caps the number of tasks of the tenant
+	MaxTasks int `json:"max_tasks"yaml:"max_tasks"`
+	// MaxMetricsPerSecond caps the metrics published by all the tasks of
+	// the tenant; the metrics beyond it are dropped
+	MaxMetricsPerSecond float64 `json:"max_metrics_per_second"yaml:"max_metrics_per_second"`
+}
+
+// tenantQuotas counts the tasks of every tenant and enforces the quotas of
+// the tenants
+type tenantQuotas struct {
+	sync.Mutex
+	quotas map[string]TenantQuota
+	// limiters cap the metrics published by the tasks of the tenants with
+	// a quota of metrics, shared by those tasks
+	limiters map[string]*publishLimiter
+	tasks    map[string]int
+}
+
+func newTenantQuotas(quotas map[string]TenantQuota) (*tenantQuotas, error) {
+	q := &tenantQuotas{
+		quotas:   map[string]TenantQuota{},
+		limiters: map[string]*publishLimiter{},
+		tasks:    map[string]int{},
+	}
+	for tenant, quota := range quotas {
+		if quota.MaxTasks < 0 {
+			return nil, fmt.Errorf("Invalid maximum of tasks %d for tenant %s (expected 0 or more)", quota.MaxTasks, tenant)
+		}
+		if quota.MaxMetricsPerSecond < 0 {
+			return nil, fmt.Errorf("Invalid maximum of metrics per second %v for tenant %s (expected 0 or more)", quota.MaxMetricsPerSecond, tenant)
+		}
+		if quota.MaxMetricsPerSecond > 0 {
+			l, err := newPublishLimiter(&core.TaskPublishRateLimit{MetricsPerSecond: quota.MaxMetricsPerSecond})
+			if err != nil {
+				return nil, fmt.Errorf("%v for tenant %s", err, tenant)
+			}
+			q.limiters[tenant] = l
+		}
+		q.quotas[tenant] = quota
+	}
+	return q, nil
+}
+
+// reserve counts a new task of the tenant, unless the tenant has as many
+// tasks as its quota allows.  The tasks of no tenant are not counted.
+func (q *tenantQuotas) reserve(tenant string) error {
+	if tenant == "" {
+		return nil
+	}
+	q.Lock()
+	defer q.Unlock()
+	if max := q.quotas[tenant].MaxTasks; max > 0 && q.tasks[tenant] >= max {
+		return ErrTenantTaskQuota
+	}
+	q.tasks[tenant]++
+	return nil
+}
+
+// release uncounts a task of the tenant, removed or not created
+func (q *tenantQuotas) release(tenant string) {
+	if tenant == "" {
+		return
+	}
+	q.Lock()
+	defer q.Unlock()
+	if q.tasks[tenant] <= 1 {
+		delete(q.tasks, tenant)
+		return
+	}
+	q.tasks[tenant]--
+}
+
+// limiter returns the limiter shared by the tasks of the tenant, nil when
+// the tenant has no quota of metrics
+func (q *tenantQuotas) limiter(tenant string) *publishLimiter {
+	return q.limiters[tenant]
+}
@@ -0,0 +1,109 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func TestTenantQuotas(t *testing.T) {
+	Convey("Invalid tenant quotas are rejected", t, func() {
+		_, err := newTenantQuotas(map[string]TenantQuota{"team-a": {MaxTasks: -1}})
+		So(err, ShouldNotBeNil)
+		_, err = newTenantQuotas(map[string]TenantQuota{"team-a": {MaxMetricsPerSecond: -1}})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Given a scheduler with tenant quotas", t, func() {
+		cfg := GetDefaultConfig()
+		cfg.TenantQuotas = map[string]TenantQuota{
+			"team-a": {MaxTasks: 2, MaxMetricsPerSecond: 1},
+			"team-b": {MaxMetricsPerSecond: 10},
+		}
+		s := New(cfg)
+		mm := &publishRecorder{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		w.Collect.Add(wmap.NewPublishNode("file", -1))
+		create := func(tenant string) (core.Task, core.TaskErrors) {
+			var owner *core.TaskOwner
+			if tenant != "" {
+				owner = &core.TaskOwner{Name: "user", Tenant: tenant}
+			}
+			return s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false, core.OptionOwner(owner))
+		}
+
+		Convey("a tenant creates as many tasks as its quota allows", func() {
+			t1, errs := create("team-a")
+			So(errs.Errors(), ShouldBeEmpty)
+			So(t1.GetOwner(), ShouldResemble, &core.TaskOwner{Name: "user", Tenant: "team-a"})
+			_, errs = create("team-a")
+			So(errs.Errors(), ShouldBeEmpty)
+			_, errs = create("team-a")
+			So(errs.Errors(), ShouldNotBeEmpty)
+			So(errs.Errors()[0].Error(), ShouldEqual, ErrTenantTaskQuota.Error())
+
+			Convey("and one more once a task is removed", func() {
+				So(s.RemoveTask(t1.ID()), ShouldBeNil)
+				_, errs = create("team-a")
+				So(errs.Errors(), ShouldBeEmpty)
+			})
+			Convey("while other tenants and the tasks of no tenant are not limited", func() {
+				for i := 0; i < 3; i++ {
+					_, errs = create("team-b")
+					So(errs.Errors(), ShouldBeEmpty)
+					_, errs = create("")
+					So(errs.Errors(), ShouldBeEmpty)
+				}
+			})
+		})
+		Convey("the tasks of a tenant share its quota of metrics", func() {
+			t1, errs := create("team-a")
+			So(errs.Errors(), ShouldBeEmpty)
+			t2, errs := create("team-a")
+			So(errs.Errors(), ShouldBeEmpty)
+			So(t1.(*task).tenantLimiter, ShouldNotBeNil)
+			So(t1.(*task).tenantLimiter, ShouldEqual, t2.(*task).tenantLimiter)
+			t3, errs := create("")
+			So(errs.Errors(), ShouldBeEmpty)
+			So(t3.(*task).tenantLimiter, ShouldBeNil)
+
+			tk := t1.(*task)
+			tk.killChan = make(chan struct{})
+			tk.fire()
+			tk = t2.(*task)
+			tk.killChan = make(chan struct{})
+			tk.fire()
+			So(mm.published(), ShouldResemble, []int{1})
+		})
+
+		s.Stop()
+	})
+}
@@ -0,0 +1,116 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/pkg/tracing"
+)
+
+const (
+	// traceServiceName names snapteld in the exported traces
+	traceServiceName = "snapteld"
+	// traceExportTimeout bounds the time an export of spans may take
+	traceExportTimeout = 10 * time.Second
+)
+
+// tracedJob is implemented by the jobs, see coreJob
+type tracedJob interface {
+	setSpan(*tracing.Span)
+}
+
+// newTracer returns the tracer exporting the spans of the task runs to the
+// configured OTLP endpoint, nil when tracing is disabled
+func newTracer(cfg *Config) *tracing.Tracer {
+	if cfg.TraceEndpoint == "" {
+		return nil
+	}
+	exporter, err := tracing.NewOTLPExporter(cfg.TraceEndpoint, traceServiceName, traceExportTimeout)
+	if err != nil {
+		schedulerLogger.WithFields(log.Fields{
+			"_block": "New",
+			"_error": err.Error(),
+		}).Error("tracing disabled, invalid 'scheduler::trace_endpoint'")
+		return nil
+	}
+	schedulerLogger.WithFields(log.Fields{
+		"_block":       "New",
+		"endpoint":     cfg.TraceEndpoint,
+		"sample-ratio": cfg.TraceSampleRatio,
+	}).Info("tracing task runs")
+	return tracing.NewTracer(cfg.TraceSampleRatio, exporter)
+}
+
+// startRunSpan starts the trace of a run of the task, nil when the task is
+// not traced or the run is not sampled
+func (t *task) startRunSpan() *tracing.Span {
+	span := t.tracer.Start("task.run")
+	span.SetAttribute("snap.task.id", t.id)
+	span.SetAttribute("snap.task.name", t.name)
+	return span
+}
+
+func finishRunSpan(span *tracing.Span, r *RunResult) {
+	span.SetAttribute("snap.metrics.collected", strconv.Itoa(r.Collected))
+	span.SetErrors(r.Errors...)
+	span.Finish()
+}
+
+// startJobSpan starts the span of a job of the workflow of the task, from
+// its submission to its completion, so that it includes the time the job
+// waits for a worker
+func startJobSpan(parent *tracing.Span, t *task, name string, pluginName string, pluginVersion int) *tracing.Span {
+	span := parent.Child(name)
+	span.SetAttribute("snap.task.id", t.id)
+	if pluginName != "" {
+		span.SetAttribute("snap.plugin.name", pluginName)
+		span.SetAttribute("snap.plugin.version", strconv.Itoa(pluginVersion))
+	}
+	return span
+}
+
+// finishJobSpan ends the span of a job which handled the given number of
+// metrics and completed with the errors
+func finishJobSpan(span *tracing.Span, metrics int, errs []error) {
+	span.SetAttribute("snap.metrics.count", strconv.Itoa(metrics))
+	span.SetErrors(errs...)
+	span.Finish()
+}
+
+// traceJob sets the span of the job, which is handed to the metric manager
+func traceJob(j job, span *tracing.Span) job {
+	if tj, ok := j.(tracedJob); ok && span != nil {
+		tj.setSpan(span)
+	}
+	return j
+}
+
+func (c *coreJob) setSpan(span *tracing.Span) {
+	c.span = span
+}
+
+// traceRunStart records the time the job waited for a worker
+func (c *coreJob) traceRunStart() {
+	c.span.SetAttribute("snap.queue.wait", time.Since(c.starttime).String())
+}
@@ -0,0 +1,95 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/pkg/tracing"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+type spanRecorder struct {
+	sync.Mutex
+	spans map[string]*tracing.Span
+}
+
+func (r *spanRecorder) Export(spans []*tracing.Span) error {
+	r.Lock()
+	defer r.Unlock()
+	for _, s := range spans {
+		r.spans[s.Name] = s
+	}
+	return nil
+}
+
+func TestTracing(t *testing.T) {
+	Convey("Given a scheduler tracing the task runs", t, func() {
+		rec := &spanRecorder{spans: map[string]*tracing.Span{}}
+		s := New(GetDefaultConfig())
+		s.tracer = tracing.NewTracer(1, rec)
+		s.SetMetricManager(newMockMetricManager())
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		w.Collect.Add(wmap.NewPublishNode("file", -1))
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+
+		Convey("a run records its jobs as children of the run", func() {
+			tk := tsk.(*task)
+			tk.killChan = make(chan struct{})
+			tk.fire()
+			s.tracer.Stop()
+
+			So(rec.spans, ShouldContainKey, "task.run")
+			So(rec.spans, ShouldContainKey, "collect")
+			So(rec.spans, ShouldContainKey, "publish")
+			run := rec.spans["task.run"]
+			So(run.Parent.IsValid(), ShouldBeFalse)
+			So(run.Attributes["snap.task.id"], ShouldEqual, tk.ID())
+			for _, name := range []string{"collect", "publish"} {
+				So(rec.spans[name].Context.TraceID, ShouldEqual, run.Context.TraceID)
+				So(rec.spans[name].Parent, ShouldEqual, run.Context.SpanID)
+				So(rec.spans[name].Attributes, ShouldContainKey, "snap.queue.wait")
+			}
+			So(rec.spans["publish"].Attributes["snap.plugin.name"], ShouldEqual, "file")
+		})
+		Reset(func() {
+			s.Stop()
+		})
+	})
+	Convey("Tracing is disabled without an endpoint", t, func() {
+		So(newTracer(GetDefaultConfig()), ShouldBeNil)
+		cfg := GetDefaultConfig()
+		cfg.TraceEndpoint = "localhost:4318"
+		So(newTracer(cfg), ShouldBeNil)
+		cfg.TraceEndpoint = "http://localhost:4318"
+		tracer := newTracer(cfg)
+		So(tracer, ShouldNotBeNil)
+		tracer.Stop()
+	})
+}
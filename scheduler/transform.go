@@ -0,0 +1,355 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/expression"
+)
+
+// The built-in processors
+const (
+	transformRename = "rename"
+	transformDrop   = "drop"
+	transformScale  = "scale"
+	transformRate   = "rate"
+	transformFilter = "filter"
+)
+
+// transform is a built-in processor run by the workflow engine itself
+// instead of a processor plugin
+type transform interface {
+	apply(mts []core.Metric) []core.Metric
+}
+
+// newTransform returns the built-in processor of the given name set up with
+// the config of its process node
+func newTransform(name string, config map[string]interface{}) (transform, error) {
+	switch name {
+	case transformRename:
+		from, err := transformString(name, config, "from", true)
+		if err != nil {
+			return nil, err
+		}
+		to, err := transformString(name, config, "to", true)
+		if err != nil {
+			return nil, err
+		}
+		fromPattern, err := parseNamespacePattern(from)
+		if err != nil {
+			return nil, fmt.Errorf("%v in built-in processor 'rename'", err)
+		}
+		toPattern, err := parseNamespacePattern(to)
+		if err != nil {
+			return nil, fmt.Errorf("%v in built-in processor 'rename'", err)
+		}
+		for _, e := range toPattern {
+			if e == namespaceAny {
+				return nil, fmt.Errorf("Invalid namespace '%s' in built-in processor 'rename' (expected no '*' element)", to)
+			}
+		}
+		return &renameTransform{from: fromPattern, to: toPattern}, nil
+	case transformDrop:
+		expr, err := transformString(name, config, "regex", true)
+		if err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid regex '%s' in built-in processor 'drop': %v", expr, err)
+		}
+		return &dropTransform{re: re}, nil
+	case transformScale:
+		factor, err := transformFloat(name, config, "factor", 1)
+		if err != nil {
+			return nil, err
+		}
+		offset, err := transformFloat(name, config, "offset", 0)
+		if err != nil {
+			return nil, err
+		}
+		unit, err := transformString(name, config, "unit", false)
+		if err != nil {
+			return nil, err
+		}
+		return &scaleTransform{factor: factor, offset: offset, unit: unit}, nil
+	case transformRate:
+		unit, err := transformString(name, config, "unit", false)
+		if err != nil {
+			return nil, err
+		}
+		return &rateTransform{unit: unit, last: map[string]rateSample{}}, nil
+	case transformFilter:
+		text, err := transformString(name, config, "expression", true)
+		if err != nil {
+			return nil, err
+		}
+		return newFilterTransform(text)
+	case transformAlert:
+		return newAlertTransform(config)
+	case transformAnomaly:
+		return newAnomalyTransform(config)
+	}
+	return nil, fmt.Errorf("Unknown built-in processor '%s' (expected one of rename, drop, scale, rate, filter, alert or anomaly)", name)
+}
+
+// transformString returns a string setting of a built-in processor
+func transformString(name string, config map[string]interface{}, key string, required bool) (string, error) {
+	v, ok := config[key]
+	if !ok {
+		if required {
+			return "", fmt.Errorf("Missing '%s' in the config of built-in processor '%s'", key, name)
+		}
+		return "", nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("Invalid '%s' in the config of built-in processor '%s' (expected a string)", key, name)
+	}
+	return s, nil
+}
+
+// transformFloat returns a numeric setting of a built-in processor, or the
+// given default when it is not set
+func transformFloat(name string, config map[string]interface{}, key string, def float64) (float64, error) {
+	v, ok := config[key]
+	if !ok {
+		return def, nil
+	}
+	f, ok := toFloat64(v)
+	if !ok {
+		return 0, fmt.Errorf("Invalid '%s' in the config of built-in processor '%s' (expected a number)", key, name)
+	}
+	return f, nil
+}
+
+// transformedMetric returns a copy of the metric with the given namespace,
+// data and unit
+func transformedMetric(m core.Metric, ns core.Namespace, data interface{}, unit string) core.Metric {
+	return plugin.MetricType{
+		Namespace_:          ns,
+		Version_:            m.Version(),
+		Tags_:               m.Tags(),
+		Timestamp_:          m.Timestamp(),
+		LastAdvertisedTime_: m.LastAdvertisedTime(),
+		Unit_:               unit,
+		Description_:        m.Description(),
+		Data_:               data,
+	}
+}
+
+// renameTransform replaces the leading elements of the namespaces matching
+// its "from" pattern by its "to" elements; the other metrics are unchanged.
+type renameTransform struct {
+	from namespacePattern
+	to   namespacePattern
+}
+
+func (r *renameTransform) apply(mts []core.Metric) []core.Metric {
+	out := make([]core.Metric, 0, len(mts))
+	for _, m := range mts {
+		ns := m.Namespace()
+		if !r.from.matches(ns) {
+			out = append(out, m)
+			continue
+		}
+		renamed := core.NewNamespace(r.to...)
+		renamed = append(renamed, ns[len(r.from):]...)
+		out = append(out, transformedMetric(m, renamed, m.Data(), m.Unit()))
+	}
+	return out
+}
+
+// dropTransform drops the metrics whose namespace matches its regex
+type dropTransform struct {
+	re *regexp.Regexp
+}
+
+func (d *dropTransform) apply(mts []core.Metric) []core.Metric {
+	out := make([]core.Metric, 0, len(mts))
+	for _, m := range mts {
+		if !d.re.MatchString(m.Namespace().String()) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// scaleTransform converts the numeric data of the metrics to
+// data*factor+offset, and sets their unit when one is given.  Metrics whose
+// data is not numeric are unchanged.
+type scaleTransform struct {
+	factor float64
+	offset float64
+	unit   string
+}
+
+func (s *scaleTransform) apply(mts []core.Metric) []core.Metric {
+	out := make([]core.Metric, 0, len(mts))
+	for _, m := range mts {
+		v, ok := toFloat64(m.Data())
+		if !ok {
+			workflowLogger.WithFields(log.Fields{
+				"_block":    "transform-scale",
+				"namespace": m.Namespace().String(),
+			}).Debug("Metric data is not numeric and is not scaled")
+			out = append(out, m)
+			continue
+		}
+		unit := m.Unit()
+		if s.unit != "" {
+			unit = s.unit
+		}
+		out = append(out, transformedMetric(m, m.Namespace(), v*s.factor+s.offset, unit))
+	}
+	return out
+}
+
+// rateSample is the last value of a series seen by a rate transform
+type rateSample struct {
+	value     float64
+	timestamp time.Time
+}
+
+// rateTransform replaces the numeric data of the metrics by their rate of
+// change per second since the previous value of their series (namespace and
+// tags).  The first value of a series, a value which does not move forward
+// in time and a decreasing value (a counter reset) produce no metric.
+type rateTransform struct {
+	sync.Mutex
+	unit string
+	last map[string]rateSample
+}
+
+func (r *rateTransform) apply(mts []core.Metric) []core.Metric {
+	r.Lock()
+	defer r.Unlock()
+	out := make([]core.Metric, 0, len(mts))
+	for _, m := range mts {
+		v, ok := toFloat64(m.Data())
+		if !ok {
+			workflowLogger.WithFields(log.Fields{
+				"_block":    "transform-rate",
+				"namespace": m.Namespace().String(),
+			}).Debug("Metric data is not numeric and is dropped")
+			continue
+		}
+		key := seriesKey(m)
+		prev, ok := r.last[key]
+		r.last[key] = rateSample{value: v, timestamp: m.Timestamp()}
+		if !ok {
+			continue
+		}
+		elapsed := m.Timestamp().Sub(prev.timestamp).Seconds()
+		if elapsed <= 0 || v < prev.value {
+			continue
+		}
+		unit := m.Unit()
+		if r.unit != "" {
+			unit = r.unit
+		}
+		out = append(out, transformedMetric(m, m.Namespace(), (v-prev.value)/elapsed, unit))
+	}
+	return out
+}
+
+// filterIdentifiers are the identifiers of the expressions over the values
+// of a metric; tags.<key> is the value of a tag
+var filterIdentifiers = map[string]struct{}{
+	"value":   {},
+	"ns":      {},
+	"unit":    {},
+	"version": {},
+}
+
+// filterTransform keeps the metrics matching its expression
+type filterTransform struct {
+	expr *expression.Expression
+}
+
+func newFilterTransform(text string) (*filterTransform, error) {
+	expr, err := parseMetricExpression(transformFilter, text)
+	if err != nil {
+		return nil, err
+	}
+	return &filterTransform{expr: expr}, nil
+}
+
+// parseMetricExpression parses an expression of a built-in processor over
+// the values of a metric
+func parseMetricExpression(name, text string) (*expression.Expression, error) {
+	expr, err := expression.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%v in built-in processor '%s'", err, name)
+	}
+	for _, id := range expr.Identifiers() {
+		if _, ok := filterIdentifiers[id]; !ok && !strings.HasPrefix(id, "tags.") {
+			return nil, fmt.Errorf("Unknown identifier '%s' in built-in processor '%s' (expected value, ns, unit, version or tags.<key>)", id, name)
+		}
+	}
+	return expr, nil
+}
+
+func (f *filterTransform) apply(mts []core.Metric) []core.Metric {
+	out := make([]core.Metric, 0, len(mts))
+	for _, m := range mts {
+		if f.expr.Match(metricResolver(m)) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// metricResolver returns the values of the identifiers of a filter
+// expression for the given metric
+func metricResolver(m core.Metric) expression.Resolver {
+	return func(id string) interface{} {
+		switch id {
+		case "value":
+			switch v := m.Data().(type) {
+			case string, bool:
+				return v
+			}
+			if v, ok := toFloat64(m.Data()); ok {
+				return v
+			}
+			return nil
+		case "ns":
+			return m.Namespace().String()
+		case "unit":
+			return m.Unit()
+		case "version":
+			return float64(m.Version())
+		}
+		if v, ok := m.Tags()[strings.TrimPrefix(id, "tags.")]; ok {
+			return v
+		}
+		return nil
+	}
+}
@@ -0,0 +1,156 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func TestTransforms(t *testing.T) {
+	Convey("Given built-in processors", t, func() {
+		now := time.Now()
+		mt := func(ts time.Time, data interface{}, ns ...string) core.Metric {
+			return plugin.MetricType{Namespace_: core.NewNamespace(ns...), Timestamp_: ts, Data_: data, Unit_: "B"}
+		}
+
+		Convey("rename replaces the leading elements of the matching namespaces", func() {
+			tr, err := newTransform("rename", map[string]interface{}{"from": "/intel/*", "to": "/acme"})
+			So(err, ShouldBeNil)
+			out := tr.apply([]core.Metric{
+				mt(now, 1, "intel", "mock", "foo"),
+				mt(now, 2, "other", "bar"),
+			})
+			So(len(out), ShouldEqual, 2)
+			So(out[0].Namespace().String(), ShouldEqual, "/acme/foo")
+			So(out[0].Data(), ShouldEqual, 1)
+			So(out[1].Namespace().String(), ShouldEqual, "/other/bar")
+		})
+		Convey("drop removes the metrics matching the regex", func() {
+			tr, err := newTransform("drop", map[string]interface{}{"regex": "^/intel/mock/(bar|baz)$"})
+			So(err, ShouldBeNil)
+			out := tr.apply([]core.Metric{
+				mt(now, 1, "intel", "mock", "foo"),
+				mt(now, 2, "intel", "mock", "bar"),
+			})
+			So(len(out), ShouldEqual, 1)
+			So(out[0].Namespace().String(), ShouldEqual, "/intel/mock/foo")
+		})
+		Convey("scale converts the numeric data and unit", func() {
+			tr, err := newTransform("scale", map[string]interface{}{"factor": 0.001, "unit": "kB"})
+			So(err, ShouldBeNil)
+			out := tr.apply([]core.Metric{
+				mt(now, 2000, "intel", "mock", "foo"),
+				mt(now, "text", "intel", "mock", "bar"),
+			})
+			So(len(out), ShouldEqual, 2)
+			So(out[0].Data(), ShouldEqual, 2)
+			So(out[0].Unit(), ShouldEqual, "kB")
+			So(out[1].Data(), ShouldEqual, "text")
+			So(out[1].Unit(), ShouldEqual, "B")
+		})
+		Convey("rate returns the change per second of every series", func() {
+			tr, err := newTransform("rate", map[string]interface{}{"unit": "B/s"})
+			So(err, ShouldBeNil)
+			So(tr.apply([]core.Metric{mt(now, 100, "intel", "mock", "foo")}), ShouldBeEmpty)
+			out := tr.apply([]core.Metric{mt(now.Add(2*time.Second), 300, "intel", "mock", "foo")})
+			So(len(out), ShouldEqual, 1)
+			So(out[0].Data(), ShouldEqual, 100)
+			So(out[0].Unit(), ShouldEqual, "B/s")
+			Convey("a counter reset produces no metric", func() {
+				So(tr.apply([]core.Metric{mt(now.Add(3*time.Second), 10, "intel", "mock", "foo")}), ShouldBeEmpty)
+			})
+		})
+		Convey("filter keeps the metrics matching the expression", func() {
+			tr, err := newTransform("filter", map[string]interface{}{"expression": `value > 90 && ns =~ "^/intel/psutil/cpu/" && tags.host == "node1"`})
+			So(err, ShouldBeNil)
+			host := func(m core.Metric, h string) core.Metric {
+				mt := m.(plugin.MetricType)
+				mt.Tags_ = map[string]string{"host": h}
+				return mt
+			}
+			out := tr.apply([]core.Metric{
+				host(mt(now, 95, "intel", "psutil", "cpu", "user"), "node1"),
+				host(mt(now, 95, "intel", "psutil", "cpu", "user"), "node2"),
+				host(mt(now, 50, "intel", "psutil", "cpu", "system"), "node1"),
+				host(mt(now, 99, "intel", "psutil", "load"), "node1"),
+				host(mt(now, "text", "intel", "psutil", "cpu", "idle"), "node1"),
+			})
+			So(len(out), ShouldEqual, 1)
+			So(out[0].Tags()["host"], ShouldEqual, "node1")
+			So(out[0].Namespace().String(), ShouldEqual, "/intel/psutil/cpu/user")
+		})
+		Convey("invalid settings are rejected", func() {
+			_, err := newTransform("median", nil)
+			So(err, ShouldNotBeNil)
+			_, err = newTransform("filter", map[string]interface{}{"expression": "value >"})
+			So(err, ShouldNotBeNil)
+			_, err = newTransform("filter", map[string]interface{}{"expression": "host == 1"})
+			So(err, ShouldNotBeNil)
+			_, err = newTransform("rename", map[string]interface{}{"from": "/intel"})
+			So(err, ShouldNotBeNil)
+			_, err = newTransform("rename", map[string]interface{}{"from": "/intel", "to": "/acme/*"})
+			So(err, ShouldNotBeNil)
+			_, err = newTransform("drop", map[string]interface{}{"regex": "("})
+			So(err, ShouldNotBeNil)
+			_, err = newTransform("scale", map[string]interface{}{"factor": "ten"})
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given a task with a built-in processor", t, func() {
+		s := New(GetDefaultConfig())
+		mm := &publishRecorder{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		pr := &wmap.ProcessWorkflowMapNode{Builtin: "drop"}
+		pr.AddConfigItem("regex", "baz$")
+		pr.Add(wmap.NewPublishNode("file", -1))
+		w.Collect.Add(pr)
+
+		Convey("the metrics are transformed without a processor plugin", func() {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+			So(errs.Errors(), ShouldBeEmpty)
+			tk := tsk.(*task)
+			So(tk.workflow.processNodes[0].transform, ShouldNotBeNil)
+			tk.killChan = make(chan struct{})
+			tk.fire()
+			So(mm.published(), ShouldResemble, []int{1})
+		})
+		Convey("a built-in processor naming a plugin is rejected", func() {
+			w.Collect.Process[0].PluginName = "passthru"
+			_, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+			So(errs.Errors(), ShouldNotBeEmpty)
+		})
+
+		s.Stop()
+	})
+}
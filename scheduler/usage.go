@@ -0,0 +1,104 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// timedJob is implemented by the jobs recording how long they ran
+type timedJob interface {
+	RunTime() time.Duration
+}
+
+// taskUsage accumulates the resources used by the jobs of a task
+type taskUsage struct {
+	sync.Mutex
+	runTime time.Duration
+	metrics uint64
+	bytes   uint64
+}
+
+// record adds the time the job ran and the metrics it handled; nothing is
+// recorded for a job refused by its worker
+func (u *taskUsage) record(j job, mts []core.Metric) {
+	tj, ok := j.(timedJob)
+	if !ok || tj.RunTime() == 0 {
+		return
+	}
+	var bytes uint64
+	for _, m := range mts {
+		bytes += metricSize(m)
+	}
+	u.Lock()
+	defer u.Unlock()
+	u.runTime += tj.RunTime()
+	u.metrics += uint64(len(mts))
+	u.bytes += bytes
+}
+
+func (u *taskUsage) get() core.TaskResourceUsage {
+	u.Lock()
+	defer u.Unlock()
+	return core.TaskResourceUsage{
+		WorkflowSeconds:  u.runTime.Seconds(),
+		MetricsProcessed: u.metrics,
+		BytesProcessed:   u.bytes,
+	}
+}
+
+// metricSize estimates the size of a metric from its namespace, tags and
+// data; the data of unknown types count as a word
+func metricSize(m core.Metric) uint64 {
+	var size int
+	for _, e := range m.Namespace() {
+		size += len(e.Value)
+	}
+	for k, v := range m.Tags() {
+		size += len(k) + len(v)
+	}
+	switch d := m.Data().(type) {
+	case nil:
+	case string:
+		size += len(d)
+	case []byte:
+		size += len(d)
+	case bool, int8, uint8:
+		size++
+	case int16, uint16:
+		size += 2
+	case int32, uint32, float32:
+		size += 4
+	case []string:
+		for _, s := range d {
+			size += len(s)
+		}
+	case []float64:
+		size += 8 * len(d)
+	case []int64:
+		size += 8 * len(d)
+	default:
+		size += 8
+	}
+	return uint64(size)
+}
@@ -0,0 +1,70 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func TestTaskResourceUsage(t *testing.T) {
+	Convey("Given a task collecting and publishing two metrics", t, func() {
+		s := New(GetDefaultConfig())
+		mm := &publishRecorder{mockMetricManager: newMockMetricManager()}
+		s.SetMetricManager(mm)
+		s.Start()
+		w := wmap.NewWorkflowMap()
+		w.Collect.AddMetric("/foo/bar", 1)
+		w.Collect.Add(wmap.NewPublishNode("file", -1))
+		tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(interval, nil, nil, 0), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		tk := tsk.(*task)
+		So(tk.ResourceUsage(), ShouldResemble, core.TaskResourceUsage{})
+
+		Convey("the jobs of its runs are accounted for", func() {
+			tk.killChan = make(chan struct{})
+			tk.fire()
+			tk.fire()
+			usage := tk.ResourceUsage()
+			So(usage.WorkflowSeconds, ShouldBeGreaterThan, 0)
+			// two metrics collected then published on each run
+			So(usage.MetricsProcessed, ShouldEqual, 8)
+			So(usage.BytesProcessed, ShouldEqual, 8*(len("foo")+len("bar")+8))
+		})
+	})
+	Convey("The size of metrics is estimated from their namespace, tags and data", t, func() {
+		m := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "load"),
+			Tags_:      map[string]string{"host": "h1"},
+			Data_:      "high",
+		}
+		So(metricSize(m), ShouldEqual, 9+6+4)
+		m.Data_ = []float64{1, 2}
+		So(metricSize(m), ShouldEqual, 9+6+16)
+	})
+}
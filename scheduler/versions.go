@@ -0,0 +1,190 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
+)
+
+var (
+	// ErrVersionConstraintsUnsupported - The error message for a metric manager which cannot resolve version constraints
+	ErrVersionConstraintsUnsupported = errors.New("Metric manager does not support version constraints")
+	// ErrNoVersionSatisfiesConstraint - The error message for a version constraint no loaded plugin satisfies
+	ErrNoVersionSatisfiesConstraint = errors.New("No loaded plugin version satisfies the version constraint")
+)
+
+// versionsResolver is optionally implemented by a metric manager which can
+// list the versions of the plugins exposing a metric.
+type versionsResolver interface {
+	GetMetricVersions(core.Namespace) ([]core.CatalogedMetric, error)
+}
+
+// versionsStagingSuffix names the subscription a running task makes to the
+// newly resolved versions of its plugins before it switches to them
+const versionsStagingSuffix = "-versions"
+
+// resolveVersions sets the version of the requested metrics having a version
+// constraint to the latest version in the catalog of the manager which
+// satisfies it.  It returns true when the version of any metric changed.
+func resolveVersions(mgr managesMetrics, mts []core.RequestedMetric) (bool, []serror.SnapError) {
+	resolved, changed, errs := resolvedVersions(mgr, mts)
+	copy(mts, resolved)
+	return changed, errs
+}
+
+// resolvedVersions returns the requested metrics with the version of the ones
+// having a version constraint set to the latest version in the catalog of the
+// manager which satisfies it; the metrics whose version changed are copied,
+// the given ones are left untouched.  It returns true when the version of any
+// metric changed.
+func resolvedVersions(mgr managesMetrics, mts []core.RequestedMetric) ([]core.RequestedMetric, bool, []serror.SnapError) {
+	var errs []serror.SnapError
+	changed := false
+	resolved := make([]core.RequestedMetric, len(mts))
+	copy(resolved, mts)
+	for i, rm := range mts {
+		m, ok := rm.(*metric)
+		if !ok || m.constraint == nil {
+			continue
+		}
+		fields := map[string]interface{}{
+			"namespace":          m.namespace.String(),
+			"version-constraint": m.constraint.String(),
+		}
+		vr, ok := mgr.(versionsResolver)
+		if !ok {
+			errs = append(errs, serror.New(ErrVersionConstraintsUnsupported, fields))
+			continue
+		}
+		cmts, err := vr.GetMetricVersions(m.namespace)
+		if err != nil {
+			errs = append(errs, serror.New(err, fields))
+			continue
+		}
+		vers := make([]int, len(cmts))
+		for i, cmt := range cmts {
+			vers[i] = cmt.Version()
+		}
+		v, ok := m.constraint.Resolve(vers)
+		if !ok {
+			errs = append(errs, serror.New(ErrNoVersionSatisfiesConstraint, fields))
+			continue
+		}
+		if m.version != v {
+			schedulerLogger.WithFields(log.Fields{
+				"_block":             "resolve-versions",
+				"namespace":          m.namespace.String(),
+				"version-constraint": m.constraint.String(),
+				"old-version":        m.version,
+				"new-version":        v,
+			}).Debug("version constraint resolved")
+			rm := *m
+			rm.version = v
+			resolved[i] = &rm
+			changed = true
+		}
+	}
+	return resolved, changed, errs
+}
+
+// hasVersionConstraints returns true if any of the requested metrics selects
+// its version with a version constraint.
+func hasVersionConstraints(mts []core.RequestedMetric) bool {
+	for _, rm := range mts {
+		if m, ok := rm.(*metric); ok && m.constraint != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveTaskVersions resolves again the version constraints of the running
+// tasks after the plugin catalog changed.  A task whose resolved versions
+// changed is first subscribed to the new versions, then switches to them
+// between two of its runs and is finally unsubscribed from the previous
+// ones, so that it keeps collecting from the previous versions, without gap,
+// when the new ones can not be subscribed to.
+func (s *scheduler) resolveTaskVersions() {
+	// the metrics of the tasks are only replaced here
+	s.versionsMutex.Lock()
+	defer s.versionsMutex.Unlock()
+	for _, t := range s.tasks.Table() {
+		if !hasVersionConstraints(t.workflow.metrics) {
+			continue
+		}
+		switch t.State() {
+		case core.TaskSpinning, core.TaskFiring, core.TaskSuspended:
+		default:
+			// versions are resolved when the task is started
+			continue
+		}
+		logger := schedulerLogger.WithFields(log.Fields{
+			"_block":  "resolve-task-versions",
+			"task-id": t.ID(),
+		})
+		resolved, changed, errs := resolvedVersions(t.metricsManager, t.workflow.metrics)
+		if len(errs) > 0 {
+			buildErrorsLog(errs, logger).Warn("unable to resolve version constraints of task")
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if errs := t.switchVersions(resolved); len(errs) > 0 {
+			buildErrorsLog(errs, logger).Error("unable to subscribe plugins of task to the new versions, task keeps the previous versions")
+			continue
+		}
+		logger.Info("task subscribed to the new plugin versions")
+	}
+}
+
+// switchVersions switches the task to the metrics of the newly resolved
+// versions.  The new versions are subscribed to under a staging subscription
+// first, so that their plugins are running when the task switches to them
+// under its lock, between two runs; the staging subscription is released
+// once the task subscribed to them.  The task is subscribed back to the
+// previous versions when the switch fails.
+func (t *task) switchVersions(resolved []core.RequestedMetric) []serror.SnapError {
+	staging := t.ID() + versionsStagingSuffix
+	if _, errs := t.subscribeDeps(staging, resolved); len(errs) > 0 {
+		return errs
+	}
+	defer t.unsubscribeDeps(staging, resolved)
+
+	t.Lock()
+	defer t.Unlock()
+	previous := t.workflow.metrics
+	t.UnsubscribePlugins()
+	t.workflow.metrics = resolved
+	_, errs := t.subscribeDeps(t.ID(), resolved)
+	if len(errs) == 0 {
+		return nil
+	}
+	t.workflow.metrics = previous
+	if _, rerrs := t.subscribeDeps(t.ID(), previous); len(rerrs) > 0 {
+		errs = append(errs, rerrs...)
+	}
+	return errs
+}
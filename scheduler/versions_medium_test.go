@@ -0,0 +1,195 @@
+// +build medium
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/gomit"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/control_event"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+type catalogedVersion struct {
+	*metric
+}
+
+func (c catalogedVersion) Policy() *cpolicy.ConfigPolicyNode {
+	return nil
+}
+
+// versionedMetricManager exposes every metric in the loaded versions and
+// records the versions each task subscribed to.
+type versionedMetricManager struct {
+	*mockMetricManager
+	sync.Mutex
+	versions   []int
+	subscribed map[string][]int
+	// failing is a version which can not be subscribed to
+	failing int
+	// calls records the subscriptions and unsubscriptions in order
+	calls []string
+}
+
+func newVersionedMetricManager(versions ...int) *versionedMetricManager {
+	return &versionedMetricManager{
+		mockMetricManager: &mockMetricManager{},
+		versions:          versions,
+		subscribed:        map[string][]int{},
+	}
+}
+
+func (m *versionedMetricManager) GetMetricVersions(ns core.Namespace) ([]core.CatalogedMetric, error) {
+	m.Lock()
+	defer m.Unlock()
+	mts := make([]core.CatalogedMetric, len(m.versions))
+	for i, v := range m.versions {
+		mts[i] = catalogedVersion{&metric{namespace: ns, version: v}}
+	}
+	return mts, nil
+}
+
+func (m *versionedMetricManager) SubscribeDeps(taskID string, reqs []core.RequestedMetric, prs []core.SubscribedPlugin, ctree *cdata.ConfigDataTree) []serror.SnapError {
+	m.Lock()
+	defer m.Unlock()
+	vers := make([]int, len(reqs))
+	for i, r := range reqs {
+		if r.Version() == m.failing {
+			return []serror.SnapError{serror.New(fmt.Errorf("version %d can not be subscribed to", m.failing))}
+		}
+		vers[i] = r.Version()
+	}
+	m.subscribed[taskID] = vers
+	m.calls = append(m.calls, fmt.Sprintf("subscribe %s %v", taskID, vers))
+	return nil
+}
+
+func (m *versionedMetricManager) UnsubscribeDeps(taskID string) []serror.SnapError {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.subscribed, taskID)
+	m.calls = append(m.calls, "unsubscribe "+taskID)
+	return nil
+}
+
+func (m *versionedMetricManager) load(v int) {
+	m.Lock()
+	m.versions = append(m.versions, v)
+	m.Unlock()
+}
+
+func (m *versionedMetricManager) subscribedVersions(taskID string) []int {
+	m.Lock()
+	defer m.Unlock()
+	return m.subscribed[taskID]
+}
+
+func newConstrainedWorkflowMap(constraint string) *wmap.WorkflowMap {
+	w := wmap.NewWorkflowMap()
+	w.Collect.AddMetricConstraint("/foo/bar", constraint)
+	return w
+}
+
+func TestVersionConstraints(t *testing.T) {
+	Convey("Given a scheduler whose metric manager lists plugin versions", t, func() {
+		s := New(GetDefaultConfig())
+		mm := newVersionedMetricManager(1, 2, 5)
+		s.SetMetricManager(mm)
+		s.Start()
+		sch := schedule.NewWindowedSchedule(time.Second, nil, nil, 0)
+
+		Convey("a task is subscribed to the latest version satisfying its constraint", func() {
+			tsk, errs := s.CreateTask(sch, newConstrainedWorkflowMap(">=2 <4"), true)
+			So(errs.Errors(), ShouldBeEmpty)
+			So(mm.subscribedVersions(tsk.ID()), ShouldResemble, []int{2})
+
+			Convey("and moved to a newly loaded version satisfying it", func() {
+				mm.load(3)
+				s.HandleGomitEvent(gomit.Event{Body: &control_event.LoadPluginEvent{Name: "foo", Version: 3}})
+				So(mm.subscribedVersions(tsk.ID()), ShouldResemble, []int{3})
+
+				Convey("after subscribing to it and before being unsubscribed from the previous one", func() {
+					id := tsk.ID()
+					So(mm.calls, ShouldResemble, []string{
+						"subscribe " + id + " [2]",
+						"subscribe " + id + versionsStagingSuffix + " [3]",
+						"unsubscribe " + id,
+						"subscribe " + id + " [3]",
+						"unsubscribe " + id + versionsStagingSuffix,
+					})
+				})
+				Convey("but not to a version outside of it", func() {
+					mm.load(4)
+					s.HandleGomitEvent(gomit.Event{Body: &control_event.LoadPluginEvent{Name: "foo", Version: 4}})
+					So(mm.subscribedVersions(tsk.ID()), ShouldResemble, []int{3})
+				})
+			})
+			s.StopTask(tsk.ID())
+		})
+		Convey("a task keeps its versions when the new ones can not be subscribed to", func() {
+			tsk, errs := s.CreateTask(sch, newConstrainedWorkflowMap(">=2 <4"), true)
+			So(errs.Errors(), ShouldBeEmpty)
+			mm.Lock()
+			mm.failing = 3
+			mm.Unlock()
+			mm.load(3)
+			s.HandleGomitEvent(gomit.Event{Body: &control_event.LoadPluginEvent{Name: "foo", Version: 3}})
+			So(mm.subscribedVersions(tsk.ID()), ShouldResemble, []int{2})
+			So(mm.subscribedVersions(tsk.ID()+versionsStagingSuffix), ShouldBeNil)
+			So(tsk.(*task).workflow.metrics[0].Version(), ShouldEqual, 2)
+			s.StopTask(tsk.ID())
+		})
+		Convey("latest resolves to the highest loaded version", func() {
+			tsk, errs := s.CreateTask(sch, newConstrainedWorkflowMap("latest"), true)
+			So(errs.Errors(), ShouldBeEmpty)
+			So(mm.subscribedVersions(tsk.ID()), ShouldResemble, []int{5})
+			s.StopTask(tsk.ID())
+		})
+		Convey("a task cannot be created when no version satisfies its constraint", func() {
+			tsk, errs := s.CreateTask(sch, newConstrainedWorkflowMap(">=6"), false)
+			So(tsk, ShouldBeNil)
+			So(errs.Errors(), ShouldHaveLength, 1)
+			So(errs.Errors()[0].Error(), ShouldEqual, ErrNoVersionSatisfiesConstraint.Error())
+		})
+	})
+	Convey("Given a metric manager which does not list plugin versions", t, func() {
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(&mockMetricManager{})
+		s.Start()
+
+		Convey("version constraints are rejected", func() {
+			tsk, errs := s.CreateTask(schedule.NewWindowedSchedule(time.Second, nil, nil, 0), newConstrainedWorkflowMap("latest"), false)
+			So(tsk, ShouldBeNil)
+			So(errs.Errors()[0].Error(), ShouldEqual, ErrVersionConstraintsUnsupported.Error())
+		})
+	})
+}
@@ -0,0 +1,71 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wmap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateGraph checks the graph of the process and publish nodes of a
+// workflow map built in code, which is not validated as a document is: every
+// plugin processor has a process or publish node under it, else its output
+// would be discarded, and the process nodes are nested no deeper than
+// MaxWorkflowDepth.  The publish nodes are the leaves of the graph and, the
+// nodes holding their children by value, the graph has no cycle.  It returns
+// nil or ValidationErrors listing every problem found.
+func (w *WorkflowMap) ValidateGraph() error {
+	if w.Collect == nil {
+		return nil
+	}
+	v := &validator{}
+	v.graph("collect", w.Collect.Process, 1)
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+// MaxWorkflowDepth is the maximum number of process nodes chained in a
+// workflow
+const MaxWorkflowDepth = 32
+
+func (v *validator) graph(field string, prs []ProcessWorkflowMapNode, depth int) {
+	for i, pr := range prs {
+		f := fmt.Sprintf("%s[%d]", join(field, "process"), i)
+		if depth > MaxWorkflowDepth {
+			v.addf(f, "process nodes are chained deeper than %d", MaxWorkflowDepth)
+			return
+		}
+		if pr.Builtin == "" && len(pr.Process) == 0 && len(pr.Publish) == 0 {
+			v.addf(f, "processor '%s' has no process nor publish node, its output would be discarded", pr.PluginName)
+		}
+		v.graph(f, pr.Process, depth+1)
+	}
+}
+
+// cycleError rewords the error of a YAML anchor referring to itself, the
+// only way for a document to describe a cycle
+func cycleError(msg string) string {
+	if strings.Contains(msg, "contains itself") {
+		return "the workflow has a cycle, " + msg
+	}
+	return msg
+}
@@ -0,0 +1,88 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wmap
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// chain returns a workflow whose collect node has the given number of
+// process nodes chained, the last one publishing
+func chain(depth int) *WorkflowMap {
+	w := NewWorkflowMap()
+	w.Collect.AddMetric("/foo/bar", 1)
+	pr := NewProcessNode("passthru", 1)
+	pr.Add(NewPublishNode("file", 1))
+	for i := 1; i < depth; i++ {
+		parent := NewProcessNode("passthru", 1)
+		parent.Add(pr)
+		pr = parent
+	}
+	w.Collect.Add(pr)
+	return w
+}
+
+func TestValidateGraph(t *testing.T) {
+	Convey("Validating the graph of a workflow map", t, func() {
+		Convey("accepts chains of processors ending in publishers", func() {
+			So(chain(3).ValidateGraph(), ShouldBeNil)
+			So(chain(MaxWorkflowDepth).ValidateGraph(), ShouldBeNil)
+		})
+		Convey("reports the processors without a node under them", func() {
+			w := chain(2)
+			w.Collect.Process[0].Process[0].Add(NewProcessNode("dropper", 1))
+			alert := NewProcessNode("", 0)
+			alert.Builtin = "alert"
+			w.Collect.Add(alert)
+			err := w.ValidateGraph()
+			So(err, ShouldHaveSameTypeAs, ValidationErrors{})
+			So(err.(ValidationErrors), ShouldHaveLength, 1)
+			So(err.Error(), ShouldEqual, "Invalid workflow: collect.process[0].process[0].process[0]: processor 'dropper' has no process nor publish node, its output would be discarded")
+		})
+		Convey("reports the chains too deep", func() {
+			err := chain(MaxWorkflowDepth + 1).ValidateGraph()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "process nodes are chained deeper than 32")
+		})
+	})
+	Convey("Validating a workflow document", t, func() {
+		Convey("reports the publishers which are not leaves", func() {
+			err := Validate(`{"collect": {"metrics": {"/foo/bar": {}}, "publish": [{"plugin_name": "file", "publish": [{"plugin_name": "kafka"}]}]}}`)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Invalid workflow: collect.publish[0]: publisher 'file' must be a leaf of the workflow, it takes no process nor publish node")
+		})
+		Convey("reports the cycles", func() {
+			err := Validate("collect:\n  metrics:\n    /foo/bar: {}\n  process:\n    - &p\n      plugin_name: passthru\n      process:\n        - *p\n")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "Invalid workflow: the workflow has a cycle")
+		})
+		Convey("reports the chains too deep", func() {
+			doc := strings.Repeat(`{"plugin_name": "passthru", "process": [`, MaxWorkflowDepth+1) + `{"plugin_name": "passthru", "publish": [{"plugin_name": "file"}]}` + strings.Repeat("]}", MaxWorkflowDepth+1)
+			err := Validate(`{"collect": {"metrics": {"/foo/bar": {}}, "process": [` + doc + `]}}`)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "process nodes are chained deeper than 32")
+		})
+	})
+}
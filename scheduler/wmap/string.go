@@ -21,6 +21,7 @@ package wmap
 
 import (
 	"fmt"
+	"strings"
 )
 
 func (w *WorkflowMap) String() string {
@@ -32,6 +33,12 @@ func (w *WorkflowMap) String() string {
 	} else {
 		out += "\n"
 	}
+	if len(w.Tags) > 0 {
+		out += "   Tags:\n"
+		for k, v := range w.Tags {
+			out += "      " + fmt.Sprintf("%s=%+v\n", k, v)
+		}
+	}
 
 	return out
 }
@@ -41,7 +48,7 @@ func (c *CollectWorkflowMapNode) String(pad string) string {
 	out += pad + "Metrics:\n"
 	for k, v := range c.Metrics {
 		out += pad + fmt.Sprintf("      Namespace: %s\n", k)
-		out += pad + fmt.Sprintf("         Version: %d\n", v.Version_)
+		out += pad + fmt.Sprintf("         Version: %v\n", v.version())
 	}
 	out += "\n"
 	out += pad + "Config:\n"
@@ -60,6 +67,10 @@ func (c *CollectWorkflowMapNode) String(pad string) string {
 		}
 	}
 	out += "\n"
+	if c.ForEach != "" {
+		out += pad + fmt.Sprintf("For Each: %s\n", c.ForEach)
+		out += "\n"
+	}
 	out += pad + "Process Nodes:\n"
 	for _, pr := range c.Process {
 		out += pr.String(pad)
@@ -75,14 +86,28 @@ func (c *CollectWorkflowMapNode) String(pad string) string {
 
 func (p *ProcessWorkflowMapNode) String(pad string) string {
 	var out string
-	out += pad + fmt.Sprintf("   Name: %s\n", p.PluginName)
-	out += pad + fmt.Sprintf("   Version: %d\n", p.PluginVersion)
+	if p.Builtin != "" {
+		out += pad + fmt.Sprintf("   Builtin: %s\n", p.Builtin)
+	} else {
+		out += pad + fmt.Sprintf("   Name: %s\n", p.PluginName)
+		out += pad + fmt.Sprintf("   Version: %d\n", p.PluginVersion)
+	}
 
 	out += pad + "   Config:\n"
 	for k, v := range p.Config {
 		out += pad + "      " + fmt.Sprintf("%s=%+v\n", k, v)
 	}
 	out += pad + "   Target:" + p.Target + "\n"
+	if len(p.TagFilter) > 0 {
+		out += pad + "   Tag Filter:\n"
+		for k, v := range p.TagFilter {
+			out += pad + "      " + fmt.Sprintf("%s=%s\n", k, v)
+		}
+	}
+	out += p.When.String(pad)
+	if p.Otherwise {
+		out += pad + "   Otherwise: true\n"
+	}
 
 	out += pad + "   Process Nodes:\n"
 	for _, pr := range p.Process {
@@ -97,12 +122,59 @@ func (p *ProcessWorkflowMapNode) String(pad string) string {
 
 func (p *PublishWorkflowMapNode) String(pad string) string {
 	var out string
-	out += pad + fmt.Sprintf("   Name: %s\n", p.PluginName)
-	out += pad + fmt.Sprintf("   Version: %d\n", p.PluginVersion)
+	if p.Builtin != "" {
+		out += pad + fmt.Sprintf("   Builtin: %s\n", p.Builtin)
+	} else {
+		out += pad + fmt.Sprintf("   Name: %s\n", p.PluginName)
+		out += pad + fmt.Sprintf("   Version: %d\n", p.PluginVersion)
+	}
 
 	out += pad + "   Config:\n"
 	for k, v := range p.Config {
 		out += pad + "      " + fmt.Sprintf("%s=%+v\n", k, v)
 	}
+	if len(p.TagFilter) > 0 {
+		out += pad + "   Tag Filter:\n"
+		for k, v := range p.TagFilter {
+			out += pad + "      " + fmt.Sprintf("%s=%s\n", k, v)
+		}
+	}
+	out += p.When.String(pad)
+	if p.Otherwise {
+		out += pad + "   Otherwise: true\n"
+	}
+	if len(p.Aggregate) > 0 {
+		out += pad + fmt.Sprintf("   Aggregate: %s\n", strings.Join(p.Aggregate, ","))
+	}
+	if p.Ordered {
+		out += pad + "   Ordered: true\n"
+	}
+	if p.Durable {
+		out += pad + "   Durable: true\n"
+	}
+	if p.Dedup {
+		out += pad + "   Dedup: true\n"
+	}
+	if p.OnFailure != "" {
+		out += pad + fmt.Sprintf("   On Failure: %s\n", p.OnFailure)
+	}
+	if p.Buffer != nil {
+		out += pad + fmt.Sprintf("   Buffer: intervals=%d metrics=%d\n", p.Buffer.Intervals, p.Buffer.Metrics)
+	}
+	return out
+}
+
+// String returns the condition of a node; empty without condition
+func (c *MetricCondition) String(pad string) string {
+	if c == nil {
+		return ""
+	}
+	out := pad + "   When:\n"
+	if len(c.Namespaces) > 0 {
+		out += pad + "      " + fmt.Sprintf("namespaces=%s\n", strings.Join(c.Namespaces, ","))
+	}
+	for k, v := range c.Tags {
+		out += pad + "      " + fmt.Sprintf("tags.%s=%s\n", k, strings.Join(v, ","))
+	}
 	return out
 }
@@ -0,0 +1,512 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// ValidationError is a problem found in a workflow map.  Field is the path
+// of the offending field, e.g. "collect.process[0].plugin_name"; Line is
+// set, instead of Field, for the errors in the syntax of the document.
+type ValidationError struct {
+	Field   string
+	Line    int
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	switch {
+	case e.Line > 0:
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	case e.Field != "":
+		return fmt.Sprintf("%s: %s", e.Field, e.Message)
+	}
+	return e.Message
+}
+
+// ValidationErrors are all the problems found in a workflow map
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "Invalid workflow: " + strings.Join(msgs, "; ")
+}
+
+var yamlLine = regexp.MustCompile(`line (\d+): `)
+
+// Validate checks the structure of a JSON or YAML workflow map (string or
+// []byte) before it is turned into a task: the keys are known, the plugin
+// nodes name their plugin, the versions are integers or version constraints,
+// no plugin processor discards its output for lack of a process or publish
+// node under it, the publish nodes are leaves and the graph of the nodes has
+// no cycle.  It returns nil or ValidationErrors listing every problem found.
+func Validate(payload interface{}) error {
+	p, err := inStringBytes(payload)
+	if err != nil {
+		return err
+	}
+	// JSON is a subset of YAML so both formats are handled by the conversion
+	js, err := yaml.YAMLToJSON(p)
+	if err != nil {
+		msg := strings.TrimPrefix(err.Error(), "error converting YAML to JSON: ")
+		msg = cycleError(strings.TrimPrefix(msg, "yaml: "))
+		if m := yamlLine.FindStringSubmatch(msg); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return ValidationErrors{{Line: line, Message: strings.TrimPrefix(msg, m[0])}}
+		}
+		return ValidationErrors{{Message: msg}}
+	}
+	var doc interface{}
+	if err := json.Unmarshal(js, &doc); err != nil {
+		return ValidationErrors{{Message: err.Error()}}
+	}
+	v := &validator{}
+	v.workflow(doc)
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) addf(field, format string, a ...interface{}) {
+	v.errs = append(v.errs, ValidationError{Field: field, Message: fmt.Sprintf(format, a...)})
+}
+
+// object returns the value as an object with known keys, reporting the
+// other keys, or nil when it is not an object
+func (v *validator) object(field string, value interface{}, keys ...string) map[string]interface{} {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		v.addf(field, "must be an object, not %s", typeName(value))
+		return nil
+	}
+	unknown := []string{}
+	for k := range obj {
+		if !contains(keys, k) {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	for _, k := range unknown {
+		v.addf(join(field, k), "unknown key, expected one of %s", strings.Join(keys, ", "))
+	}
+	return obj
+}
+
+func (v *validator) workflow(doc interface{}) {
+	w := v.object("", doc, "collect", "tags", "config", "rewrite")
+	if w == nil {
+		return
+	}
+	if w["collect"] == nil {
+		v.addf("collect", "is required")
+	} else {
+		v.collect("collect", w["collect"])
+	}
+	if w["tags"] != nil {
+		v.stringMap("tags", w["tags"])
+	}
+	if w["config"] != nil {
+		v.config("config", w["config"])
+	}
+	if w["rewrite"] != nil {
+		if rules, ok := w["rewrite"].([]interface{}); !ok {
+			v.addf("rewrite", "must be a list, not %s", typeName(w["rewrite"]))
+		} else {
+			for i, r := range rules {
+				v.rewrite(fmt.Sprintf("rewrite[%d]", i), r)
+			}
+		}
+	}
+}
+
+// rewrite validates a namespace rewrite rule, which has either a prefix or
+// a regex
+func (v *validator) rewrite(field string, value interface{}) {
+	r := v.object(field, value, "prefix", "regex", "replacement")
+	if r == nil {
+		return
+	}
+	prefix := v.string(join(field, "prefix"), r["prefix"])
+	regex := v.string(join(field, "regex"), r["regex"])
+	switch {
+	case prefix == "" && regex == "":
+		v.addf(field, "requires a prefix or a regex")
+	case prefix != "" && regex != "":
+		v.addf(field, "takes a prefix or a regex, not both")
+	case prefix != "" && !strings.HasPrefix(prefix, "/"):
+		v.addf(join(field, "prefix"), "must start with '/'")
+	case regex != "":
+		if _, err := regexp.Compile(regex); err != nil {
+			v.addf(join(field, "regex"), "%v", err)
+		}
+	}
+	switch replacement := r["replacement"].(type) {
+	case nil:
+		v.addf(join(field, "replacement"), "is required")
+	case string:
+		if !strings.HasPrefix(replacement, "/") {
+			v.addf(join(field, "replacement"), "must start with '/'")
+		}
+	default:
+		v.string(join(field, "replacement"), replacement)
+	}
+}
+
+func (v *validator) collect(field string, value interface{}) {
+	c := v.object(field, value, "metrics", "config", "tags", "for_each", "process", "publish")
+	if c == nil {
+		return
+	}
+	metrics, ok := c["metrics"].(map[string]interface{})
+	switch {
+	case c["metrics"] == nil:
+		v.addf(join(field, "metrics"), "is required")
+	case !ok:
+		v.addf(join(field, "metrics"), "must be an object, not %s", typeName(c["metrics"]))
+	}
+	for _, ns := range sortedKeys(metrics) {
+		v.metric(join(join(field, "metrics"), ns), metrics[ns])
+	}
+	if c["config"] != nil {
+		if config := v.objectOf(join(field, "config"), c["config"]); config != nil {
+			for _, ns := range sortedKeys(config) {
+				v.config(join(join(field, "config"), ns), config[ns])
+			}
+		}
+	}
+	if c["tags"] != nil {
+		if tags := v.objectOf(join(field, "tags"), c["tags"]); tags != nil {
+			for _, ns := range sortedKeys(tags) {
+				v.stringMap(join(join(field, "tags"), ns), tags[ns])
+			}
+		}
+	}
+	if forEach := v.string(join(field, "for_each"), c["for_each"]); forEach != "" {
+		if !strings.HasPrefix(forEach, "/") {
+			v.addf(join(field, "for_each"), "must start with '/'")
+		} else if !contains(strings.Split(forEach, "/"), "*") {
+			v.addf(join(field, "for_each"), "must have a wildcard (*) element matching the instances")
+		}
+	}
+	v.nodes(field, c)
+}
+
+// objectOf returns the value as an object with any keys, or nil when it is
+// not an object
+func (v *validator) objectOf(field string, value interface{}) map[string]interface{} {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		v.addf(field, "must be an object, not %s", typeName(value))
+	}
+	return obj
+}
+
+func (v *validator) metric(field string, value interface{}) {
+	if value == nil {
+		return
+	}
+	m := v.object(field, value, "version")
+	if m == nil || m["version"] == nil {
+		return
+	}
+	var info metricInfo
+	if err := info.setVersion(m["version"]); err != nil {
+		v.addf(join(field, "version"), "%v", err)
+	}
+}
+
+// nodes validates the process and publish nodes under a node
+func (v *validator) nodes(field string, n map[string]interface{}) {
+	if n["process"] != nil {
+		if nodes, ok := n["process"].([]interface{}); !ok {
+			v.addf(join(field, "process"), "must be a list, not %s", typeName(n["process"]))
+		} else {
+			for i, p := range nodes {
+				v.process(fmt.Sprintf("%s[%d]", join(field, "process"), i), p)
+			}
+		}
+	}
+	if n["publish"] != nil {
+		if nodes, ok := n["publish"].([]interface{}); !ok {
+			v.addf(join(field, "publish"), "must be a list, not %s", typeName(n["publish"]))
+		} else {
+			for i, p := range nodes {
+				v.publish(fmt.Sprintf("%s[%d]", join(field, "publish"), i), p)
+			}
+		}
+	}
+}
+
+func (v *validator) process(field string, value interface{}) {
+	p := v.object(field, value, "plugin_name", "plugin_version", "process", "publish", "config", "target", "tag_filter", "builtin", "when", "otherwise")
+	if p == nil {
+		return
+	}
+	if strings.Count(field, "process[") > MaxWorkflowDepth {
+		v.addf(field, "process nodes are chained deeper than %d", MaxWorkflowDepth)
+		return
+	}
+	builtin := v.string(join(field, "builtin"), p["builtin"])
+	if builtin != "" {
+		name, _ := p["plugin_name"].(string)
+		target, _ := p["target"].(string)
+		if name != "" || target != "" {
+			v.addf(field, "built-in processor '%s' takes no plugin_name nor target", builtin)
+		}
+	} else {
+		v.plugin(field, p)
+		if empty(p["process"]) && empty(p["publish"]) {
+			v.addf(field, "processor '%v' has no process nor publish node, its output would be discarded", p["plugin_name"])
+		}
+	}
+	v.string(join(field, "target"), p["target"])
+	if p["config"] != nil {
+		v.config(join(field, "config"), p["config"])
+	}
+	if p["tag_filter"] != nil {
+		v.stringMap(join(field, "tag_filter"), p["tag_filter"])
+	}
+	v.branch(field, p)
+	v.nodes(field, p)
+}
+
+func (v *validator) publish(field string, value interface{}) {
+	p := v.object(field, value, "plugin_name", "plugin_version", "config", "target", "builtin", "buffer", "ordered", "aggregate", "tag_filter", "durable", "dedup", "on_failure", "when", "otherwise", "process", "publish")
+	if p == nil {
+		return
+	}
+	if p["process"] != nil || p["publish"] != nil {
+		v.addf(field, "publisher '%v' must be a leaf of the workflow, it takes no process nor publish node", p["plugin_name"])
+	}
+	builtin := v.string(join(field, "builtin"), p["builtin"])
+	if builtin != "" {
+		name, _ := p["plugin_name"].(string)
+		target, _ := p["target"].(string)
+		if name != "" || target != "" {
+			v.addf(field, "built-in publisher '%s' takes no plugin_name nor target", builtin)
+		}
+	} else {
+		v.plugin(field, p)
+	}
+	v.string(join(field, "target"), p["target"])
+	if p["config"] != nil {
+		v.config(join(field, "config"), p["config"])
+	}
+	if p["buffer"] != nil {
+		if b := v.object(join(field, "buffer"), p["buffer"], "intervals", "metrics"); b != nil {
+			v.count(join(join(field, "buffer"), "intervals"), b["intervals"])
+			v.count(join(join(field, "buffer"), "metrics"), b["metrics"])
+		}
+	}
+	v.bool(join(field, "ordered"), p["ordered"])
+	v.bool(join(field, "durable"), p["durable"])
+	v.bool(join(field, "dedup"), p["dedup"])
+	if p["aggregate"] != nil {
+		if funcs, ok := p["aggregate"].([]interface{}); !ok {
+			v.addf(join(field, "aggregate"), "must be a list, not %s", typeName(p["aggregate"]))
+		} else {
+			for i, f := range funcs {
+				v.string(fmt.Sprintf("%s[%d]", join(field, "aggregate"), i), f)
+			}
+		}
+	}
+	if p["tag_filter"] != nil {
+		v.stringMap(join(field, "tag_filter"), p["tag_filter"])
+	}
+	v.branch(field, p)
+	switch policy := v.string(join(field, "on_failure"), p["on_failure"]); policy {
+	case "", "fail", "ignore":
+	default:
+		v.addf(join(field, "on_failure"), "must be 'fail' or 'ignore', not '%s'", policy)
+	}
+}
+
+// branch validates the condition routing the metrics to a process or publish
+// node
+func (v *validator) branch(field string, n map[string]interface{}) {
+	v.bool(join(field, "otherwise"), n["otherwise"])
+	if n["when"] == nil {
+		return
+	}
+	if otherwise, _ := n["otherwise"].(bool); otherwise {
+		v.addf(field, "takes a when condition or otherwise, not both")
+	}
+	field = join(field, "when")
+	w := v.object(field, n["when"], "namespaces", "tags")
+	if w == nil {
+		return
+	}
+	var tags map[string]interface{}
+	if w["tags"] != nil {
+		tags = v.objectOf(join(field, "tags"), w["tags"])
+	}
+	if empty(w["namespaces"]) && len(tags) == 0 {
+		v.addf(field, "must hold namespaces or tags")
+	}
+	if w["namespaces"] != nil {
+		if nss, ok := w["namespaces"].([]interface{}); !ok {
+			v.addf(join(field, "namespaces"), "must be a list, not %s", typeName(w["namespaces"]))
+		} else {
+			for i, ns := range nss {
+				f := fmt.Sprintf("%s[%d]", join(field, "namespaces"), i)
+				if s, ok := ns.(string); !ok {
+					v.string(f, ns)
+				} else if !strings.HasPrefix(s, "/") || len(s) < 2 {
+					v.addf(f, "must be a namespace (/<element>/...), not '%s'", s)
+				}
+			}
+		}
+	}
+	for _, k := range sortedKeys(tags) {
+		if values, ok := tags[k].([]interface{}); !ok {
+			v.addf(join(join(field, "tags"), k), "must be a list, not %s", typeName(tags[k]))
+		} else {
+			for i, value := range values {
+				v.string(fmt.Sprintf("%s[%d]", join(join(field, "tags"), k), i), value)
+			}
+		}
+	}
+}
+
+// plugin validates the plugin name and version of a process or publish node
+func (v *validator) plugin(field string, n map[string]interface{}) {
+	if name := v.string(join(field, "plugin_name"), n["plugin_name"]); name == "" && n["plugin_name"] == nil {
+		v.addf(join(field, "plugin_name"), "is required")
+	} else if name == "" {
+		v.addf(join(field, "plugin_name"), "must not be empty")
+	}
+	if n["plugin_version"] != nil {
+		if f, ok := n["plugin_version"].(float64); !ok || f != float64(int(f)) {
+			v.addf(join(field, "plugin_version"), "must be an integer, not %s", typeName(n["plugin_version"]))
+		}
+	}
+}
+
+// config validates the configuration of a node, whose values are strings,
+// numbers or booleans
+func (v *validator) config(field string, value interface{}) {
+	config := v.objectOf(field, value)
+	for _, k := range sortedKeys(config) {
+		switch config[k].(type) {
+		case string, float64, bool:
+		default:
+			v.addf(join(field, k), "must be a string, a number or a boolean, not %s", typeName(config[k]))
+		}
+	}
+}
+
+// stringMap validates an object whose values are strings
+func (v *validator) stringMap(field string, value interface{}) {
+	obj := v.objectOf(field, value)
+	for _, k := range sortedKeys(obj) {
+		v.string(join(field, k), obj[k])
+	}
+}
+
+// string returns the value of a string field, reporting any other type
+func (v *validator) string(field string, value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	s, ok := value.(string)
+	if !ok {
+		v.addf(field, "must be a string, not %s", typeName(value))
+	}
+	return s
+}
+
+func (v *validator) bool(field string, value interface{}) {
+	if _, ok := value.(bool); value != nil && !ok {
+		v.addf(field, "must be a boolean, not %s", typeName(value))
+	}
+}
+
+func (v *validator) count(field string, value interface{}) {
+	if value == nil {
+		return
+	}
+	if f, ok := value.(float64); !ok || f != float64(int(f)) || f < 0 {
+		v.addf(field, "must be a positive integer, not %v", value)
+	}
+}
+
+func typeName(value interface{}) string {
+	switch x := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(x)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case bool:
+		return "a boolean"
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func empty(value interface{}) bool {
+	nodes, _ := value.([]interface{})
+	return len(nodes) == 0
+}
+
+func join(field, key string) string {
+	if field == "" {
+		return key
+	}
+	return field + "." + key
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
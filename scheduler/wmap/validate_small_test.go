@@ -0,0 +1,149 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2017 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wmap
+
+import (
+	"io/ioutil"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/scheduler/wmap/fixtures"
+)
+
+func TestValidate(t *testing.T) {
+	Convey("Validating a workflow map", t, func() {
+		Convey("accepts valid workflows", func() {
+			So(Validate(SampleWorkflowMapJson()), ShouldBeNil)
+			So(Validate(SampleWorkflowMapYaml()), ShouldBeNil)
+			for _, sample := range []string{"sample/1.json", "sample/1.yml"} {
+				b, err := ioutil.ReadFile(sample)
+				So(err, ShouldBeNil)
+				So(Validate(b), ShouldBeNil)
+			}
+		})
+		Convey("accepts built-in processors without a node under them", func() {
+			So(Validate(`{"collect": {"metrics": {"/foo/bar": {}}, "process": [{"builtin": "alert", "config": {"name": "high"}}]}}`), ShouldBeNil)
+		})
+		Convey("reports the processors discarding their output", func() {
+			err := Validate(fixtures.TaskJSON)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "collect.process[0].process[0]: processor 'oslo' has no process nor publish node")
+		})
+		Convey("reports every problem with its field", func() {
+			err := Validate(`{
+				"collect": {
+					"metrics": {"/foo/bar": {"version": 1.5}, "/foo/baz": {"versoin": 1}},
+					"config": {"/foo": {"user": ["root"]}},
+					"process": [
+						{"plugin_version": "2", "publish": [{"plugin_name": "file"}]},
+						{"plugin_name": "passthru"},
+						{"builtin": "rename", "plugin_name": "passthru", "publish": [{"plugin_name": "file"}]}
+					],
+					"publish": [{"plugin_name": "file", "buffer": {"intervals": -1}, "on_failure": "retry", "colour": "red"}]
+				}
+			}`)
+			So(err, ShouldHaveSameTypeAs, ValidationErrors{})
+			fields := []string{}
+			for _, e := range err.(ValidationErrors) {
+				fields = append(fields, e.Field)
+			}
+			So(fields, ShouldResemble, []string{
+				"collect.metrics./foo/bar.version",
+				"collect.metrics./foo/baz.versoin",
+				"collect.config./foo.user",
+				"collect.process[0].plugin_name",
+				"collect.process[0].plugin_version",
+				"collect.process[1]",
+				"collect.process[2]",
+				"collect.publish[0].colour",
+				"collect.publish[0].buffer.intervals",
+				"collect.publish[0].on_failure",
+			})
+		})
+		Convey("checks the namespace rewrite rules", func() {
+			So(Validate(`{"collect": {"metrics": {"/foo/bar": {}}}, "rewrite": [{"prefix": "/foo", "replacement": "/acme"}, {"regex": "^/acme/(.*)$", "replacement": "/org/$1"}]}`), ShouldBeNil)
+			err := Validate(`{"collect": {"metrics": {"/foo/bar": {}}}, "rewrite": [{"replacement": "/acme"}, {"prefix": "/foo", "regex": "foo", "replacement": "/acme"}, {"regex": "(", "replacement": "acme"}, {"prefix": "/foo"}]}`)
+			So(err, ShouldHaveSameTypeAs, ValidationErrors{})
+			fields := []string{}
+			for _, e := range err.(ValidationErrors) {
+				fields = append(fields, e.Field)
+			}
+			So(fields, ShouldResemble, []string{
+				"rewrite[0]",
+				"rewrite[1]",
+				"rewrite[2].regex",
+				"rewrite[2].replacement",
+				"rewrite[3].replacement",
+			})
+		})
+		Convey("checks the conditions of the branches", func() {
+			So(Validate(`{"collect": {"metrics": {"/foo/bar": {}}, "publish": [{"plugin_name": "influxdb", "when": {"namespaces": ["/foo/*/baz"], "tags": {"env": ["prod"]}}}, {"plugin_name": "file", "otherwise": true}]}}`), ShouldBeNil)
+			err := Validate(`{"collect": {"metrics": {"/foo/bar": {}}, "publish": [{"plugin_name": "influxdb", "when": {"namespaces": ["/foo"]}, "otherwise": true}, {"plugin_name": "file", "when": {}}, {"plugin_name": "kafka", "when": {"namespaces": ["foo", 1], "tags": {"env": "prod"}}, "otherwise": "yes"}]}}`)
+			So(err, ShouldHaveSameTypeAs, ValidationErrors{})
+			fields := []string{}
+			for _, e := range err.(ValidationErrors) {
+				fields = append(fields, e.Field)
+			}
+			So(fields, ShouldResemble, []string{
+				"collect.publish[0]",
+				"collect.publish[1].when",
+				"collect.publish[2].otherwise",
+				"collect.publish[2].when.namespaces[0]",
+				"collect.publish[2].when.namespaces[1]",
+				"collect.publish[2].when.tags.env",
+			})
+			So(err.(ValidationErrors)[3].Message, ShouldEqual, "must be a namespace (/<element>/...), not 'foo'")
+		})
+		Convey("checks the for_each pattern of the collect node", func() {
+			So(Validate(`{"collect": {"metrics": {"/intel/docker/*/cpu": {}}, "for_each": "/intel/docker/*", "publish": [{"plugin_name": "file"}]}}`), ShouldBeNil)
+			err := Validate(`{"collect": {"metrics": {"/intel/docker/*/cpu": {}}, "for_each": "/intel/docker"}}`)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Invalid workflow: collect.for_each: must have a wildcard (*) element matching the instances")
+			err = Validate(`{"collect": {"metrics": {"/intel/docker/*/cpu": {}}, "for_each": "intel/*"}}`)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Invalid workflow: collect.for_each: must start with '/'")
+		})
+		Convey("checks the built-in publishers", func() {
+			So(Validate(`{"collect": {"metrics": {"/foo/bar": {}}, "publish": [{"builtin": "file", "config": {"path": "published", "max_size": 1048576}}]}}`), ShouldBeNil)
+			err := Validate(`{"collect": {"metrics": {"/foo/bar": {}}, "publish": [{"builtin": "file", "plugin_name": "file"}]}}`)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Invalid workflow: collect.publish[0]: built-in publisher 'file' takes no plugin_name nor target")
+		})
+		Convey("requires the collect node and its metrics", func() {
+			err := Validate(`{"tags": {"env": "test"}}`)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Invalid workflow: collect: is required")
+			err = Validate(`{"collect": {}}`)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Invalid workflow: collect.metrics: is required")
+		})
+		Convey("reports the line of syntax errors", func() {
+			err := Validate("collect:\n  metrics:\n    /foo/bar: {}\n   publish: [\n")
+			So(err, ShouldNotBeNil)
+			So(err.(ValidationErrors)[0].Line, ShouldBeGreaterThan, 0)
+		})
+		Convey("rejects payloads which are not strings nor bytes", func() {
+			So(Validate(1), ShouldEqual, InvalidPayload)
+		})
+	})
+}
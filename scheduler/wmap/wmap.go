@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	yaml "gopkg.in/yaml.v2"
@@ -30,6 +31,7 @@ import (
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/ctypes"
 	"github.com/intelsdi-x/snap/pkg/stringutils"
+	"github.com/intelsdi-x/snap/pkg/versions"
 )
 
 var (
@@ -132,6 +134,26 @@ func Sample() *WorkflowMap {
 type WorkflowMap struct {
 	// required: true
 	Collect *CollectWorkflowMapNode `json:"collect"yaml:"collect"`
+	// Tags are added to every metric collected by the task
+	Tags map[string]string `json:"tags,omitempty"yaml:"tags"`
+	// Config is the configuration of every plugin of the task, overridden
+	// by the config of the metrics and of the process and publish nodes
+	Config map[string]interface{} `json:"config,omitempty"yaml:"config"`
+	// Rewrite are the rules rewriting the namespaces of the metrics of the
+	// task before they are published, applied in order
+	Rewrite []NamespaceRewrite `json:"rewrite,omitempty"yaml:"rewrite"`
+}
+
+// NamespaceRewrite is a rule rewriting the namespaces of the metrics of a
+// task before they are published.  With Prefix, the leading elements of a
+// namespace matching the prefix, where "*" matches any element, are replaced
+// by the elements of Replacement.  With Regex, the regular expression is
+// matched against the namespace joined by "/", e.g. /intel/mock/foo, and
+// replaced by Replacement, which may refer to the submatches as $1...
+type NamespaceRewrite struct {
+	Prefix      string `json:"prefix,omitempty"yaml:"prefix"`
+	Regex       string `json:"regex,omitempty"yaml:"regex"`
+	Replacement string `json:"replacement"yaml:"replacement"`
 }
 
 func (w *WorkflowMap) UnmarshalJSON(data []byte) error {
@@ -145,6 +167,18 @@ func (w *WorkflowMap) UnmarshalJSON(data []byte) error {
 			if err := json.Unmarshal(v, &w.Collect); err != nil {
 				return err
 			}
+		case "tags":
+			if err := json.Unmarshal(v, &w.Tags); err != nil {
+				return fmt.Errorf("%v (while parsing 'tags')", err)
+			}
+		case "config":
+			if err := json.Unmarshal(v, &w.Config); err != nil {
+				return fmt.Errorf("%v (while parsing 'config')", err)
+			}
+		case "rewrite":
+			if err := json.Unmarshal(v, &w.Rewrite); err != nil {
+				return fmt.Errorf("%v (while parsing 'rewrite')", err)
+			}
 		default:
 			return fmt.Errorf("Unrecognized key '%v' in workflow of task.", k)
 		}
@@ -162,6 +196,14 @@ func NewWorkflowMap() *WorkflowMap {
 	return w
 }
 
+// GetConfigNode returns the configuration of every plugin of the task
+func (w *WorkflowMap) GetConfigNode() (*cdata.ConfigDataNode, error) {
+	if w.Config == nil {
+		return cdata.NewNode(), nil
+	}
+	return configtoConfigDataNode(w.Config, "")
+}
+
 func (w *WorkflowMap) ToJson() ([]byte, error) {
 	return json.Marshal(w)
 }
@@ -176,8 +218,13 @@ type CollectWorkflowMapNode struct {
 	Metrics map[string]metricInfo             `json:"metrics"yaml:"metrics"`
 	Config  map[string]map[string]interface{} `json:"config,omitempty"yaml:"config"`
 	Tags    map[string]map[string]string      `json:"tags,omitempty"yaml:"tags"`
-	Process []ProcessWorkflowMapNode          `json:"process,omitempty"yaml:"process"`
-	Publish []PublishWorkflowMapNode          `json:"publish,omitempty"yaml:"publish"`
+	// ForEach is a namespace with wildcards, e.g. /intel/docker/*, fanning
+	// the workflow out per instance of the dynamic metrics it matches: the
+	// metrics of every instance go down the process and publish nodes as a
+	// batch of their own.
+	ForEach string                   `json:"for_each,omitempty"yaml:"for_each"`
+	Process []ProcessWorkflowMapNode `json:"process,omitempty"yaml:"process"`
+	Publish []PublishWorkflowMapNode `json:"publish,omitempty"yaml:"publish"`
 }
 
 func (cw *CollectWorkflowMapNode) UnmarshalJSON(data []byte) error {
@@ -199,6 +246,10 @@ func (cw *CollectWorkflowMapNode) UnmarshalJSON(data []byte) error {
 			if err := json.Unmarshal(v, &cw.Tags); err != nil {
 				return fmt.Errorf("%v (while parsing 'tags')", err)
 			}
+		case "for_each":
+			if err := json.Unmarshal(v, &cw.ForEach); err != nil {
+				return fmt.Errorf("%v (while parsing 'for_each')", err)
+			}
 		case "process":
 			if err := json.Unmarshal(v, &cw.Process); err != nil {
 				return err
@@ -223,8 +274,9 @@ func (c *CollectWorkflowMapNode) GetMetrics() []Metric {
 		firstChar := stringutils.GetFirstChar(k)
 		ns := strings.Trim(k, firstChar)
 		metrics[i] = Metric{
-			namespace: strings.Split(ns, firstChar),
-			version:   v.Version_,
+			namespace:  strings.Split(ns, firstChar),
+			version:    v.Version_,
+			constraint: v.Constraint_,
 		}
 		i++
 	}
@@ -277,6 +329,16 @@ func (c *CollectWorkflowMapNode) AddMetric(ns string, v int) error {
 	return nil
 }
 
+// AddMetricConstraint adds a metric whose plugin version is chosen by the
+// given version constraint (e.g. ">=2 <4" or "latest").
+func (c *CollectWorkflowMapNode) AddMetricConstraint(ns, constraint string) error {
+	if _, err := versions.ParseConstraint(constraint); err != nil {
+		return err
+	}
+	c.Metrics[ns] = metricInfo{Constraint_: constraint}
+	return nil
+}
+
 func (c *CollectWorkflowMapNode) AddConfigItem(ns, key string, value interface{}) {
 	if c.Config[ns] == nil {
 		c.Config[ns] = make(map[string]interface{})
@@ -293,6 +355,28 @@ type ProcessWorkflowMapNode struct {
 	// Config the configuration of a processor.
 	Config map[string]interface{} `json:"config,omitempty"yaml:"config"`
 	Target string                 `json:"target"yaml:"target"`
+	// TagFilter restricts the metrics received by the processor to the
+	// metrics carrying the given tags
+	TagFilter map[string]string `json:"tag_filter,omitempty"yaml:"tag_filter"`
+	// Builtin is the built-in processor (rename, drop, scale, rate, filter,
+	// alert or anomaly) run by the workflow engine instead of a processor
+	// plugin; Config holds its settings
+	Builtin string `json:"builtin,omitempty"yaml:"builtin"`
+	// When routes to the processor the metrics meeting the condition only
+	When *MetricCondition `json:"when,omitempty"yaml:"when"`
+	// Otherwise routes to the processor the metrics meeting none of the
+	// conditions of its sibling process and publish nodes
+	Otherwise bool `json:"otherwise,omitempty"yaml:"otherwise"`
+}
+
+// MetricCondition is the condition of a branch of the workflow, evaluated
+// on every metric.  A metric meets it when its namespace starts with one of
+// Namespaces, where "*" matches any element, and when each of the Tags of the
+// condition holds one of the listed values, "*" matching any value.  An empty
+// list of namespaces matches every namespace.
+type MetricCondition struct {
+	Namespaces []string            `json:"namespaces,omitempty"yaml:"namespaces"`
+	Tags       map[string][]string `json:"tags,omitempty"yaml:"tags"`
 }
 
 func (pw *ProcessWorkflowMapNode) UnmarshalJSON(data []byte) error {
@@ -326,6 +410,22 @@ func (pw *ProcessWorkflowMapNode) UnmarshalJSON(data []byte) error {
 			if err := json.Unmarshal(v, &pw.Target); err != nil {
 				return fmt.Errorf("%v (while parsing 'target')", err)
 			}
+		case "tag_filter":
+			if err := json.Unmarshal(v, &pw.TagFilter); err != nil {
+				return fmt.Errorf("%v (while parsing 'tag_filter')", err)
+			}
+		case "builtin":
+			if err := json.Unmarshal(v, &pw.Builtin); err != nil {
+				return fmt.Errorf("%v (while parsing 'builtin')", err)
+			}
+		case "when":
+			if err := json.Unmarshal(v, &pw.When); err != nil {
+				return fmt.Errorf("%v (while parsing 'when')", err)
+			}
+		case "otherwise":
+			if err := json.Unmarshal(v, &pw.Otherwise); err != nil {
+				return fmt.Errorf("%v (while parsing 'otherwise')", err)
+			}
 		default:
 			return fmt.Errorf("Unrecognized key '%v' in process workflow of task.", k)
 		}
@@ -376,6 +476,45 @@ type PublishWorkflowMapNode struct {
 	// Config the config of a publisher
 	Config map[string]interface{} `json:"config,omitempty"yaml:"config"`
 	Target string                 `json:"target"yaml:"target"`
+	// Builtin is the built-in publisher (file, http, kafka, statsd or
+	// remote_write) run by the workflow engine instead of a publisher
+	// plugin; Config holds its settings
+	Builtin string `json:"builtin,omitempty"yaml:"builtin"`
+	// Buffer holds the metrics received by the publisher and publishes them
+	// in batches
+	Buffer *BufferWorkflowMapNode `json:"buffer,omitempty"yaml:"buffer"`
+	// Ordered sorts the metrics handed to the publisher by namespace then
+	// timestamp
+	Ordered bool `json:"ordered,omitempty"yaml:"ordered"`
+	// Aggregate lists the functions (min, max, avg, sum, count) reducing
+	// the metrics of every batch before they are published
+	Aggregate []string `json:"aggregate,omitempty"yaml:"aggregate"`
+	// TagFilter restricts the metrics received by the publisher to the
+	// metrics carrying the given tags
+	TagFilter map[string]string `json:"tag_filter,omitempty"yaml:"tag_filter"`
+	// Durable publishers are published to before their sibling publish
+	// nodes, which are only submitted once the durable ones completed
+	Durable bool `json:"durable,omitempty"yaml:"durable"`
+	// Dedup suppresses the batches of metrics already acknowledged by the
+	// publisher, e.g. when a batch is published again by a retry
+	Dedup bool `json:"dedup,omitempty"yaml:"dedup"`
+	// OnFailure is the policy applied to publish failures: "fail" (the
+	// default) records them against the task, "ignore" only logs them
+	OnFailure string `json:"on_failure,omitempty"yaml:"on_failure"`
+	// When routes to the publisher the metrics meeting the condition only
+	When *MetricCondition `json:"when,omitempty"yaml:"when"`
+	// Otherwise routes to the publisher the metrics meeting none of the
+	// conditions of its sibling process and publish nodes
+	Otherwise bool `json:"otherwise,omitempty"yaml:"otherwise"`
+}
+
+// BufferWorkflowMapNode describes when the metrics buffered for a publisher
+// are published.  The buffer is published once either limit is reached.
+type BufferWorkflowMapNode struct {
+	// Intervals is the number of task runs buffered before publishing
+	Intervals int `json:"intervals,omitempty"yaml:"intervals"`
+	// Metrics is the number of metrics buffered before publishing
+	Metrics int `json:"metrics,omitempty"yaml:"metrics"`
 }
 
 func (pw *PublishWorkflowMapNode) UnmarshalJSON(data []byte) error {
@@ -401,6 +540,46 @@ func (pw *PublishWorkflowMapNode) UnmarshalJSON(data []byte) error {
 			if err := json.Unmarshal(v, &pw.Target); err != nil {
 				return fmt.Errorf("%v (while parsing 'target')", err)
 			}
+		case "builtin":
+			if err := json.Unmarshal(v, &pw.Builtin); err != nil {
+				return fmt.Errorf("%v (while parsing 'builtin')", err)
+			}
+		case "buffer":
+			if err := json.Unmarshal(v, &pw.Buffer); err != nil {
+				return fmt.Errorf("%v (while parsing 'buffer')", err)
+			}
+		case "ordered":
+			if err := json.Unmarshal(v, &pw.Ordered); err != nil {
+				return fmt.Errorf("%v (while parsing 'ordered')", err)
+			}
+		case "aggregate":
+			if err := json.Unmarshal(v, &pw.Aggregate); err != nil {
+				return fmt.Errorf("%v (while parsing 'aggregate')", err)
+			}
+		case "tag_filter":
+			if err := json.Unmarshal(v, &pw.TagFilter); err != nil {
+				return fmt.Errorf("%v (while parsing 'tag_filter')", err)
+			}
+		case "durable":
+			if err := json.Unmarshal(v, &pw.Durable); err != nil {
+				return fmt.Errorf("%v (while parsing 'durable')", err)
+			}
+		case "dedup":
+			if err := json.Unmarshal(v, &pw.Dedup); err != nil {
+				return fmt.Errorf("%v (while parsing 'dedup')", err)
+			}
+		case "on_failure":
+			if err := json.Unmarshal(v, &pw.OnFailure); err != nil {
+				return fmt.Errorf("%v (while parsing 'on_failure')", err)
+			}
+		case "when":
+			if err := json.Unmarshal(v, &pw.When); err != nil {
+				return fmt.Errorf("%v (while parsing 'when')", err)
+			}
+		case "otherwise":
+			if err := json.Unmarshal(v, &pw.Otherwise); err != nil {
+				return fmt.Errorf("%v (while parsing 'otherwise')", err)
+			}
 		default:
 			return fmt.Errorf("Unrecognized key '%v' in publish workflow of task.", k)
 		}
@@ -432,6 +611,9 @@ func (p *PublishWorkflowMapNode) GetConfigNode() (*cdata.ConfigDataNode, error)
 
 type metricInfo struct {
 	Version_ int `json:"version"yaml:"version"`
+	// Constraint_ is set instead of Version_ when the version of the metric
+	// is given as a version constraint (e.g. ">=2 <4" or "latest").
+	Constraint_ string `json:"-"yaml:"-"`
 }
 
 func (m *metricInfo) UnmarshalJSON(data []byte) error {
@@ -442,7 +624,11 @@ func (m *metricInfo) UnmarshalJSON(data []byte) error {
 	for k, v := range t {
 		switch k {
 		case "version":
-			if err := json.Unmarshal(v, &m.Version_); err != nil {
+			var version interface{}
+			if err := json.Unmarshal(v, &version); err != nil {
+				return fmt.Errorf("%v (while parsing 'version')", err)
+			}
+			if err := m.setVersion(version); err != nil {
 				return fmt.Errorf("%v (while parsing 'version')", err)
 			}
 		default:
@@ -452,9 +638,71 @@ func (m *metricInfo) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+func (m *metricInfo) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	t := make(map[string]interface{})
+	if err := unmarshal(&t); err != nil {
+		return err
+	}
+	for k, v := range t {
+		switch k {
+		case "version":
+			if err := m.setVersion(v); err != nil {
+				return fmt.Errorf("%v (while parsing 'version')", err)
+			}
+		default:
+			return fmt.Errorf("Unrecognized key '%v' in metrics in collect workflow of task", k)
+		}
+	}
+	return nil
+}
+
+// setVersion sets either the version or the version constraint of the metric
+// depending on the type of the given value.
+func (m *metricInfo) setVersion(v interface{}) error {
+	switch x := v.(type) {
+	case nil:
+	case int:
+		m.Version_ = x
+	case float64:
+		if x != float64(int(x)) {
+			return fmt.Errorf("version must be an integer or a version constraint, not %v", x)
+		}
+		m.Version_ = int(x)
+	case string:
+		if i, err := strconv.Atoi(strings.TrimSpace(x)); err == nil {
+			m.Version_ = i
+			return nil
+		}
+		if _, err := versions.ParseConstraint(x); err != nil {
+			return err
+		}
+		m.Constraint_ = x
+	default:
+		return fmt.Errorf("version must be an integer or a version constraint, not %v", x)
+	}
+	return nil
+}
+
+// version returns the version or the version constraint of the metric
+func (m metricInfo) version() interface{} {
+	if m.Constraint_ != "" {
+		return m.Constraint_
+	}
+	return m.Version_
+}
+
+func (m metricInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"version": m.version()})
+}
+
+func (m metricInfo) MarshalYAML() (interface{}, error) {
+	return map[string]interface{}{"version": m.version()}, nil
+}
+
 type Metric struct {
-	namespace []string
-	version   int
+	namespace  []string
+	version    int
+	constraint string
 }
 
 func (m Metric) Namespace() []string {
@@ -465,6 +713,12 @@ func (m Metric) Version() int {
 	return m.version
 }
 
+// Constraint returns the version constraint of the metric, or an empty string
+// when the metric requests a single version.
+func (m Metric) Constraint() string {
+	return m.constraint
+}
+
 func configtoConfigDataNode(cmap map[string]interface{}, ns string) (*cdata.ConfigDataNode, error) {
 	cdn := cdata.NewNode()
 	for ck, cv := range cmap {
@@ -244,3 +244,45 @@ func TestMetricSeparator(t *testing.T) {
 		})
 	})
 }
+
+func TestMetricVersionConstraint(t *testing.T) {
+	Convey("Metric versions", t, func() {
+		Convey("are read as a version or a version constraint from json", func() {
+			wmap, err := FromJson(`{"collect": {"metrics": {"/foo/bar": {"version": 2}, "/foo/baz": {"version": ">=2 <4"}, "/foo/qux": {"version": "3"}}}}`)
+			So(err, ShouldBeNil)
+			So(wmap.Collect.Metrics["/foo/bar"], ShouldResemble, metricInfo{Version_: 2})
+			So(wmap.Collect.Metrics["/foo/baz"], ShouldResemble, metricInfo{Constraint_: ">=2 <4"})
+			So(wmap.Collect.Metrics["/foo/qux"], ShouldResemble, metricInfo{Version_: 3})
+
+			Convey("and written back the same way", func() {
+				b, err := wmap.ToJson()
+				So(err, ShouldBeNil)
+				wmap2, err := FromJson(b)
+				So(err, ShouldBeNil)
+				So(wmap2.Collect.Metrics, ShouldResemble, wmap.Collect.Metrics)
+			})
+		})
+		Convey("are read as a version or a version constraint from yaml", func() {
+			wmap, err := FromYaml("collect:\n  metrics:\n    /foo/bar:\n      version: 2\n    /foo/baz:\n      version: latest\n")
+			So(err, ShouldBeNil)
+			So(wmap.Collect.Metrics["/foo/bar"], ShouldResemble, metricInfo{Version_: 2})
+			So(wmap.Collect.Metrics["/foo/baz"], ShouldResemble, metricInfo{Constraint_: "latest"})
+
+			Convey("and written back the same way", func() {
+				b, err := wmap.ToYaml()
+				So(err, ShouldBeNil)
+				wmap2, err := FromYaml(b)
+				So(err, ShouldBeNil)
+				So(wmap2.Collect.Metrics, ShouldResemble, wmap.Collect.Metrics)
+			})
+		})
+		Convey("are rejected when the constraint is invalid", func() {
+			_, err := FromJson(`{"collect": {"metrics": {"/foo/bar": {"version": ">=two"}}}}`)
+			So(err, ShouldNotBeNil)
+			c := NewCollectWorkflowMapNode()
+			So(c.AddMetricConstraint("/foo/bar", ">=two"), ShouldNotBeNil)
+			So(c.AddMetricConstraint("/foo/bar", ">=2"), ShouldBeNil)
+			So(c.GetMetrics()[0].Constraint(), ShouldEqual, ">=2")
+		})
+	})
+}
@@ -61,6 +61,7 @@ type workManager struct {
 	processchan    chan queuedJob
 	kill           chan struct{}
 	mutex          *sync.Mutex
+	shutdownOnce   sync.Once
 }
 
 type workManagerState int
@@ -215,6 +216,23 @@ func (w *workManager) Stop() {
 	close(w.kill)
 }
 
+// shutdown stops the queues and the workers of the work manager.  Unlike Stop
+// it leaves the workers of the other work managers running, and it may be
+// called more than once.
+func (w *workManager) shutdown() {
+	w.shutdownOnce.Do(func() {
+		w.collectq.Stop()
+		w.processq.Stop()
+		w.publishq.Stop()
+		for _, wkrs := range [][]*worker{w.collectWkrs, w.processWkrs, w.publishWkrs} {
+			for _, wkr := range wkrs {
+				close(wkr.kamikaze)
+			}
+		}
+		close(w.kill)
+	})
+}
+
 // Work dispatches jobs to worker pools for processing.
 //
 // Returns a queued job to the caller, which will be
@@ -271,3 +289,35 @@ func (w *workManager) sendToWorker(j queuedJob) {
 		w.processchan <- j
 	}
 }
+
+// poolSize returns the number of workers of each pool
+func (w *workManager) poolSize() uint {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.collectWkrSize
+}
+
+// resize changes the number of workers of each pool to the given size.  The
+// workers removed finish the job they are running, if any, then stop.
+func (w *workManager) resize(size uint) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.collectWkrs = resizePool(w.collectWkrs, size, w.collectchan)
+	w.collectWkrSize = size
+	w.publishWkrs = resizePool(w.publishWkrs, size, w.publishchan)
+	w.publishWkrSize = size
+	w.processWkrs = resizePool(w.processWkrs, size, w.processchan)
+	w.processWkrSize = size
+}
+
+func resizePool(wkrs []*worker, size uint, rcv chan queuedJob) []*worker {
+	for uint(len(wkrs)) < size {
+		nw := newWorker(rcv)
+		go nw.start()
+		wkrs = append(wkrs, nw)
+	}
+	for _, wkr := range wkrs[size:] {
+		close(wkr.kamikaze)
+	}
+	return wkrs[:size]
+}
@@ -22,6 +22,7 @@ limitations under the License.
 package scheduler
 
 import (
+	"context"
 	"sync"
 	"testing"
 	"time"
@@ -87,6 +88,8 @@ func (mj *mockJob) Type() jobType        { return collectJobType }
 func (mj *mockJob) TypeString() string   { return "" }
 func (mj *mockJob) TaskID() string       { return "" }
 
+func (mj *mockJob) Context() context.Context { return context.Background() }
+
 // Complete the first incomplete rendez-vous (if there is one)
 func (mj *mockJob) RendezVous() {
 	mj.Lock()
@@ -48,11 +48,14 @@ func (w *worker) start() {
 		select {
 		case q := <-w.rcv:
 			// assert that deadline is not exceeded
-			if chrono.Chrono.Now().Before(q.Job().Deadline()) {
-				q.Job().Run()
-			} else {
+			if !chrono.Chrono.Now().Before(q.Job().Deadline()) {
 				// the deadline was exceeded and this job will not run
 				q.Job().AddErrors(errors.New("Worker refused to run overdue job."))
+			} else if q.Job().Context().Err() != nil {
+				// the task was stopped while the job waited for a worker
+				q.Job().AddErrors(ErrJobCancelled)
+			} else {
+				q.Job().Run()
 			}
 
 			// mark the job complete
@@ -20,7 +20,9 @@ limitations under the License.
 package scheduler
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -31,6 +33,8 @@ import (
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/scheduler_event"
+	"github.com/intelsdi-x/snap/pkg/tracing"
+	"github.com/intelsdi-x/snap/pkg/versions"
 	"github.com/intelsdi-x/snap/scheduler/wmap"
 )
 
@@ -58,21 +62,48 @@ var (
 
 // WmapToWorkflow attempts to convert a wmap.WorkflowMap to a schedulerWorkflow instance.
 func wmapToWorkflow(wfMap *wmap.WorkflowMap) (*schedulerWorkflow, error) {
+	// the workflow maps built in code are not validated as documents
+	if err := wfMap.ValidateGraph(); err != nil {
+		return nil, err
+	}
 	wf := &schedulerWorkflow{}
 	err := convertCollectionNode(wfMap.Collect, wf)
 	if err != nil {
 		return nil, err
 	}
-	// ***
-	// TODO validate workflow makes sense here
-	// - flows that don't end in publishers?
-	// - duplicate child nodes anywhere?
-	//***
+	// Add the tags of the task to the tags of the collected metrics
+	wf.tags = mergeTaskTags(wf.tags, wfMap.Tags)
+	// The config of the task applies to all its plugins, below the config
+	// of the metrics and of the nodes
+	cdn, err := wfMap.GetConfigNode()
+	if err != nil {
+		return nil, err
+	}
+	if len(cdn.Table()) > 0 {
+		wf.configTree.SetDefaults(cdn)
+		applyTaskConfig(wf.processNodes, wf.publishNodes, cdn)
+	}
+	wf.rewrite, err = newNamespaceRewriter(wfMap.Rewrite)
+	if err != nil {
+		return nil, err
+	}
 	// Retain a copy of the original workflow map
 	wf.workflowMap = wfMap
 	return wf, nil
 }
 
+// applyTaskConfig merges the config of the task below the config of the
+// process and publish nodes
+func applyTaskConfig(prNodes []*processNode, puNodes []*publishNode, cdn *cdata.ConfigDataNode) {
+	for _, pr := range prNodes {
+		pr.config.ReverseMergeInPlace(cdn)
+		applyTaskConfig(pr.ProcessNodes, pr.PublishNodes, cdn)
+	}
+	for _, pu := range puNodes {
+		pu.config.ReverseMergeInPlace(cdn)
+	}
+}
+
 func convertCollectionNode(cnode *wmap.CollectWorkflowMapNode, wf *schedulerWorkflow) error {
 	// Collection root
 	// Validate collection node exists
@@ -87,7 +118,15 @@ func convertCollectionNode(cnode *wmap.CollectWorkflowMapNode, wf *schedulerWork
 	mts := cnode.GetMetrics()
 	wf.metrics = make([]core.RequestedMetric, len(mts))
 	for i, m := range mts {
-		wf.metrics[i] = &metric{namespace: core.NewNamespace(m.Namespace()...), version: m.Version()}
+		mt := &metric{namespace: core.NewNamespace(m.Namespace()...), version: m.Version()}
+		if m.Constraint() != "" {
+			vc, err := versions.ParseConstraint(m.Constraint())
+			if err != nil {
+				return err
+			}
+			mt.constraint = vc
+		}
+		wf.metrics[i] = mt
 	}
 	// get tags defined
 	wf.tags = cnode.GetTags()
@@ -98,6 +137,13 @@ func convertCollectionNode(cnode *wmap.CollectWorkflowMapNode, wf *schedulerWork
 		return err
 	}
 	wf.configTree = cdt
+	if cnode.ForEach != "" {
+		fe, err := newForEach(cnode.ForEach)
+		if err != nil {
+			return err
+		}
+		wf.forEach = fe
+	}
 	// Iterate over first level process nodes
 	pr, err := convertProcessNode(cnode.Process)
 	if err != nil {
@@ -110,6 +156,7 @@ func convertCollectionNode(cnode *wmap.CollectWorkflowMapNode, wf *schedulerWork
 		return err
 	}
 	wf.publishNodes = pu
+	linkBranches(pr, pu)
 	return nil
 }
 
@@ -128,7 +175,31 @@ func convertProcessNode(pr []wmap.ProcessWorkflowMapNode) ([]*processNode, error
 		if err != nil {
 			return nil, err
 		}
+		linkBranches(prC, puC)
+		br, err := newBranch(p.When, p.Otherwise)
+		if err != nil {
+			return nil, err
+		}
 
+		if p.Builtin != "" {
+			if p.PluginName != "" || p.Target != "" {
+				return nil, fmt.Errorf("Built-in processor '%s' takes no plugin_name nor target", p.Builtin)
+			}
+			tr, err := newTransform(p.Builtin, p.Config)
+			if err != nil {
+				return nil, err
+			}
+			prNodes[i] = &processNode{
+				name:         p.Builtin,
+				config:       cdn,
+				ProcessNodes: prC,
+				PublishNodes: puC,
+				filter:       p.TagFilter,
+				branch:       br,
+				transform:    tr,
+			}
+			continue
+		}
 		// If version is not 1+ we use -1 to indicate we want
 		// the plugin manager to select the highest version
 		// available on plugin calls
@@ -143,6 +214,8 @@ func convertProcessNode(pr []wmap.ProcessWorkflowMapNode) ([]*processNode, error
 			Target:       p.Target,
 			ProcessNodes: prC,
 			PublishNodes: puC,
+			filter:       p.TagFilter,
+			branch:       br,
 		}
 	}
 	return prNodes, nil
@@ -163,11 +236,47 @@ func convertPublishNode(pu []wmap.PublishWorkflowMapNode) ([]*publishNode, error
 			p.PluginVersion = -1
 		}
 		p.PluginName = strings.ToLower(p.PluginName)
+		buf, err := newMetricBuffer(p.Buffer)
+		if err != nil {
+			return nil, err
+		}
+		agg, err := newAggregator(p.Aggregate)
+		if err != nil {
+			return nil, err
+		}
+		ignoreFailures, err := parsePublishFailurePolicy(p.OnFailure)
+		if err != nil {
+			return nil, err
+		}
+		br, err := newBranch(p.When, p.Otherwise)
+		if err != nil {
+			return nil, err
+		}
+		var sk sink
+		if p.Builtin != "" {
+			if p.PluginName != "" || p.Target != "" {
+				return nil, fmt.Errorf("Built-in publisher '%s' takes no plugin_name nor target", p.Builtin)
+			}
+			if sk, err = newSink(p.Builtin, p.Config); err != nil {
+				return nil, err
+			}
+			p.PluginName = p.Builtin
+			p.PluginVersion = 0
+		}
 		puNodes[i] = &publishNode{
-			name:    p.PluginName,
-			version: p.PluginVersion,
-			config:  cdn,
-			Target:  p.Target,
+			name:           p.PluginName,
+			version:        p.PluginVersion,
+			config:         cdn,
+			Target:         p.Target,
+			buffer:         buf,
+			ordered:        p.Ordered,
+			aggregator:     agg,
+			filter:         p.TagFilter,
+			branch:         br,
+			durable:        p.Durable,
+			dedup:          p.Dedup,
+			ignoreFailures: ignoreFailures,
+			sink:           sk,
 		}
 	}
 	return puNodes, nil
@@ -185,6 +294,12 @@ type schedulerWorkflow struct {
 	workflowMap  *wmap.WorkflowMap
 	eventEmitter gomit.Emitter
 	tags         map[string]map[string]string
+	// rewrite rewrites the namespaces of the metrics before they are
+	// published; nil without rewrite rules
+	rewrite *namespaceRewriter
+	// forEach fans the workflow out per instance of the dynamic metrics;
+	// nil when the metrics go down the workflow together
+	forEach *forEach
 }
 
 type processNode struct {
@@ -195,6 +310,14 @@ type processNode struct {
 	ProcessNodes       []*processNode
 	PublishNodes       []*publishNode
 	InboundContentType string
+	// filter selects the metrics received by the node by their tags
+	filter tagFilter
+	// branch selects the metrics routed to the node; nil when it receives
+	// all the metrics of its parent
+	branch *branch
+	// transform is the built-in processor run in place of a plugin; nil for
+	// a plugin node
+	transform transform
 }
 
 func (p *processNode) Name() string {
@@ -219,6 +342,27 @@ type publishNode struct {
 	config             *cdata.ConfigDataNode
 	Target             string
 	InboundContentType string
+	// buffer holds the metrics until a batch is due; nil when unbuffered
+	buffer *metricBuffer
+	// ordered sorts the metrics by namespace then timestamp before publishing
+	ordered bool
+	// aggregator reduces the metrics before publishing; nil when disabled
+	aggregator *aggregator
+	// filter selects the metrics received by the node by their tags
+	filter tagFilter
+	// branch selects the metrics routed to the node; nil when it receives
+	// all the metrics of its parent
+	branch *branch
+	// durable nodes are published to before their siblings
+	durable bool
+	// dedup suppresses the batches the publisher acknowledged already
+	dedup bool
+	// ignoreFailures logs the failures of the node instead of recording
+	// them against the task
+	ignoreFailures bool
+	// sink is the built-in publisher run in place of a plugin; nil for a
+	// plugin node
+	sink sink
 }
 
 func (p *publishNode) Name() string {
@@ -239,29 +383,51 @@ func (p *publishNode) TypeName() string {
 
 type wfContentTypes map[string]map[string][]string
 
-// Start starts a workflow
-func (s *schedulerWorkflow) Start(t *task) {
+// Start starts a workflow for the run of the context, whose cancellation
+// cancels the jobs of the run; the jobs are recorded as children of the span
+// carried by the context, if any
+func (s *schedulerWorkflow) Start(ctx context.Context, t *task) {
 	workflowLogger.WithFields(log.Fields{
 		"_block":    "workflow-start",
 		"task-id":   t.id,
 		"task-name": t.name,
 	}).Debug("Starting workflow")
 	s.state = WorkflowStarted
-	j := newCollectorJob(s.metrics, t.deadlineDuration, t.metricsManager, t.workflow.configTree, t.id, s.tags)
+	cspan := startJobSpan(tracing.FromContext(ctx), t, "collect", "", 0)
+	j := withContext(ctx, traceJob(newCollectorJob(s.metrics, t.collectTimeout(), t.metricsManager, t.workflow.configTree, t.id, s.tags), cspan))
 
 	// dispatch 'collect' job to be worked
 	// Block until the job has been either run or skipped.
-	errors := t.manager.Work(j).Promise().Await()
+	var errors []error
+	if t.pinnedThread != nil {
+		errors = t.pinnedThread.Work(j).Promise().Await()
+	} else {
+		errors = t.manager.Work(j).Promise().Await()
+	}
+	finishJobSpan(cspan, len(j.Metrics()), errors)
+	t.usage.record(j, j.Metrics())
 
 	if len(errors) > 0 {
 		t.RecordFailure(errors)
-		event := new(scheduler_event.MetricCollectionFailedEvent)
-		event.TaskID = t.id
-		event.Errors = errors
-		defer s.eventEmitter.Emit(event)
-		return
+		// the metrics of the collectors which succeeded still go down the
+		// workflow; the run is a partial failure
+		if len(j.(*collectorJob).metrics) == 0 {
+			event := new(scheduler_event.MetricCollectionFailedEvent)
+			event.TaskID = t.id
+			event.Errors = errors
+			defer s.eventEmitter.Emit(event)
+			return
+		}
+		workflowLogger.WithFields(log.Fields{
+			"_block":    "workflow-start",
+			"task-id":   t.id,
+			"task-name": t.name,
+			"plugins":   failedPlugins(errors),
+		}).Warn("Partial collection, some collector plugins failed")
 	}
 
+	t.recordCollected(len(j.(*collectorJob).metrics))
+	j.(*collectorJob).metrics = t.guardCardinality(j.(*collectorJob).metrics)
 	// Send event
 	event := new(scheduler_event.MetricCollectedEvent)
 	event.TaskID = t.id
@@ -269,7 +435,55 @@ func (s *schedulerWorkflow) Start(t *task) {
 	defer s.eventEmitter.Emit(event)
 
 	// walk through the tree and dispatch work
-	workJobs(s.processNodes, s.publishNodes, t, j)
+	s.work(ctx, t, j)
+}
+
+// work dispatches the metrics of a run to the process and publish nodes,
+// per instance when the workflow fans out
+func (s *schedulerWorkflow) work(ctx context.Context, t *task, j job) {
+	if s.forEach != nil {
+		workInstances(ctx, s.forEach, s.processNodes, s.publishNodes, t, j)
+		return
+	}
+	workJobs(ctx, s.processNodes, s.publishNodes, t, j)
+}
+
+// FlushBuffers publishes the metrics left in the buffers of the publish
+// nodes of the workflow.  It is called when the task stops running, so the
+// metrics are published with a context which is not cancelled.
+func (s *schedulerWorkflow) FlushBuffers(t *task) {
+	wg := &sync.WaitGroup{}
+	flushBuffers(t.detachedContext(), s.processNodes, s.publishNodes, t, wg)
+	wg.Wait()
+}
+
+func flushBuffers(ctx context.Context, prs []*processNode, pus []*publishNode, t *task, wg *sync.WaitGroup) {
+	durable, pus := splitDurable(pus)
+	for _, pu := range durable {
+		if pu.buffer == nil {
+			continue
+		}
+		if mts := pu.buffer.flush(); len(mts) > 0 {
+			publish(ctx, newBatchJob(collectJobType, time.Now().Add(t.deadlineDuration), t.id, mts), t, pu)
+		}
+	}
+	for _, pr := range prs {
+		flushBuffers(ctx, pr.ProcessNodes, pr.PublishNodes, t, wg)
+	}
+	for _, pu := range pus {
+		if pu.buffer == nil {
+			continue
+		}
+		mts := pu.buffer.flush()
+		if len(mts) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(pu *publishNode) {
+			defer wg.Done()
+			publish(ctx, newBatchJob(collectJobType, time.Now().Add(t.deadlineDuration), t.id, mts), t, pu)
+		}(pu)
+	}
 }
 
 func (s *schedulerWorkflow) State() WorkflowState {
@@ -280,26 +494,32 @@ func (s *schedulerWorkflow) StateString() string {
 	return WorkflowStateLookup[s.state]
 }
 
-func (s *schedulerWorkflow) StreamStart(t *task, metrics []core.Metric) {
+// StreamStart runs the workflow for the metrics streamed to the task, as
+// Start does for the collected ones
+func (s *schedulerWorkflow) StreamStart(ctx context.Context, t *task, metrics []core.Metric) {
 	j := &collectorJob{
 		collector:      t.metricsManager,
 		metricTypes:    []core.RequestedMetric{},
 		metrics:        metrics,
-		coreJob:        newCoreJob(collectJobType, time.Now().Add(t.deadlineDuration), t.id, "", 0),
+		coreJob:        newCoreJob(collectJobType, time.Now().Add(t.collectTimeout()), t.id, "", 0),
 		configDataTree: t.workflow.configTree,
 		tags:           t.workflow.tags,
 	}
+	j.setContext(ctx)
+	t.recordCollected(len(metrics))
+	j.metrics = t.guardCardinality(j.metrics)
 	// Send event
 	event := new(scheduler_event.MetricCollectedEvent)
 	event.TaskID = t.id
 	event.Metrics = j.metrics
 	defer s.eventEmitter.Emit(event)
-	workJobs(s.processNodes, s.publishNodes, t, j)
+	s.work(ctx, t, j)
 }
 
 // workJobs takes a slice of process and publish nodes and submits jobs for each for a task.
-// It then iterates down any process nodes to submit their child node jobs for the task
-func workJobs(prs []*processNode, pus []*publishNode, t *task, pj job) {
+// It then iterates down any process nodes to submit their child node jobs for the task.
+// The jobs are submitted with the context, their spans are children of the span it carries.
+func workJobs(ctx context.Context, prs []*processNode, pus []*publishNode, t *task, pj job) {
 	// optimize for no jobs
 	if len(prs) == 0 && len(pus) == 0 {
 		return
@@ -314,19 +534,30 @@ func workJobs(prs []*processNode, pus []*publishNode, t *task, pj job) {
 		"count-publish-nodes": len(pus),
 		"parent-node-type":    pj.TypeString(),
 	}).Debug("Batch submission of process and publish nodes")
+	// the durable publish jobs complete before any other job is submitted so
+	// the metrics are stored even when the other destinations are down
+	durable, pus := splitDurable(pus)
+	if len(durable) > 0 {
+		dwg := &sync.WaitGroup{}
+		for _, pu := range durable {
+			dwg.Add(1)
+			go submitPublishJob(ctx, pj, t, dwg, pu)
+		}
+		dwg.Wait()
+	}
 	// range over the process jobs and call submitProcessJob
 	for _, pr := range prs {
 		// increment the wait group (before starting goroutine to prevent a race condition)
 		wg.Add(1)
 		// Start goroutine to submit the process job
-		go submitProcessJob(pj, t, wg, pr)
+		go submitProcessJob(ctx, pj, t, wg, pr)
 	}
 	// range over the publish jobs and call submitPublishJob
 	for _, pu := range pus {
 		// increment the wait group (before starting goroutine to prevent a race condition)
 		wg.Add(1)
 		// Start goroutine to submit the process job
-		go submitPublishJob(pj, t, wg, pu)
+		go submitPublishJob(ctx, pj, t, wg, pu)
 	}
 	// Wait until all job submisson goroutines are done
 	wg.Wait()
@@ -340,9 +571,45 @@ func workJobs(prs []*processNode, pus []*publishNode, t *task, pj job) {
 	}).Debug("Batch submission complete")
 }
 
-func submitProcessJob(pj job, t *task, wg *sync.WaitGroup, pr *processNode) {
+func submitProcessJob(ctx context.Context, pj job, t *task, wg *sync.WaitGroup, pr *processNode) {
 	// Decrement the waitgroup
 	defer wg.Done()
+	if len(pr.filter) > 0 || pr.branch != nil {
+		mts := pr.branch.filter(pr.filter.filter(pj.Metrics()))
+		if len(mts) == 0 {
+			workflowLogger.WithFields(log.Fields{
+				"_block":           "submit-process-job",
+				"task-id":          t.id,
+				"task-name":        t.name,
+				"process-name":     pr.Name(),
+				"process-version":  pr.Version(),
+				"parent-node-type": pj.TypeString(),
+			}).Debug("No metrics match the tag filter and the condition of the node")
+			return
+		}
+		pj = newBatchJob(pj.Type(), pj.Deadline(), t.id, mts)
+	}
+	// a built-in processor runs in the workflow engine, without a job
+	if pr.transform != nil {
+		var mts []core.Metric
+		if a, ok := pr.transform.(*alertTransform); ok {
+			var changes []core.Alert
+			changes, mts = a.evaluate(pj.Metrics())
+			t.notifyAlerts(a, changes)
+		} else {
+			mts = pr.transform.apply(pj.Metrics())
+		}
+		j := newBatchJob(pj.Type(), pj.Deadline(), t.id, mts)
+		workflowLogger.WithFields(log.Fields{
+			"_block":           "submit-process-job",
+			"task-id":          t.id,
+			"task-name":        t.name,
+			"process-builtin":  pr.Name(),
+			"parent-node-type": pj.TypeString(),
+		}).Debug("Built-in processor completed")
+		workJobs(ctx, pr.ProcessNodes, pr.PublishNodes, t, j)
+		return
+	}
 	// Create a new process job
 	mgr, err := t.RemoteManagers.Get(pr.Target)
 	if err != nil {
@@ -357,7 +624,8 @@ func submitProcessJob(pj job, t *task, wg *sync.WaitGroup, pr *processNode) {
 		}).Warn("Error getting control instance")
 		return
 	}
-	j := newProcessJob(pj, pr.Name(), pr.Version(), pr.InboundContentType, pr.config.Table(), mgr, t.id)
+	pspan := startJobSpan(tracing.FromContext(ctx), t, "process", pr.Name(), pr.Version())
+	j := withContext(ctx, traceJob(newProcessJob(pj, time.Now().Add(t.processTimeout()), pr.Name(), pr.Version(), pr.InboundContentType, pr.config.Table(), mgr, t.id), pspan))
 	workflowLogger.WithFields(log.Fields{
 		"_block":           "submit-process-job",
 		"task-id":          t.id,
@@ -368,6 +636,8 @@ func submitProcessJob(pj job, t *task, wg *sync.WaitGroup, pr *processNode) {
 	}).Debug("Submitting process job")
 	// Submit the job against the task.managesWork
 	errors := t.manager.Work(j).Promise().Await()
+	finishJobSpan(pspan, len(j.Metrics()), errors)
+	t.usage.record(j, pj.Metrics())
 	// Check for errors and update the task
 	if len(errors) != 0 {
 		// Record the failures in the task
@@ -392,14 +662,116 @@ func submitProcessJob(pj job, t *task, wg *sync.WaitGroup, pr *processNode) {
 		"parent-node-type": pj.TypeString(),
 	}).Debug("Process job completed")
 	// Iterate into any child process or publish nodes
-	workJobs(pr.ProcessNodes, pr.PublishNodes, t, j)
+	workJobs(tracing.NewContext(ctx, pspan), pr.ProcessNodes, pr.PublishNodes, t, j)
 }
 
-func submitPublishJob(pj job, t *task, wg *sync.WaitGroup, pu *publishNode) {
+func submitPublishJob(ctx context.Context, pj job, t *task, wg *sync.WaitGroup, pu *publishNode) {
 	// Decrement the waitgroup
 	defer wg.Done()
+	if len(pu.filter) > 0 || pu.branch != nil {
+		mts := pu.branch.filter(pu.filter.filter(pj.Metrics()))
+		// a buffered node still counts the run
+		if len(mts) == 0 && pu.buffer == nil {
+			workflowLogger.WithFields(log.Fields{
+				"_block":           "submit-publish-job",
+				"task-id":          t.id,
+				"task-name":        t.name,
+				"publish-name":     pu.Name(),
+				"publish-version":  pu.Version(),
+				"parent-node-type": pj.TypeString(),
+			}).Debug("No metrics match the tag filter and the condition of the node")
+			return
+		}
+		pj = newBatchJob(pj.Type(), pj.Deadline(), t.id, mts)
+	}
+	if pu.buffer != nil {
+		mts := pu.buffer.add(pj.Metrics())
+		if mts == nil {
+			workflowLogger.WithFields(log.Fields{
+				"_block":           "submit-publish-job",
+				"task-id":          t.id,
+				"task-name":        t.name,
+				"publish-name":     pu.Name(),
+				"publish-version":  pu.Version(),
+				"parent-node-type": pj.TypeString(),
+			}).Debug("Metrics buffered")
+			return
+		}
+		pj = newBatchJob(pj.Type(), pj.Deadline(), t.id, mts)
+	}
+	publish(ctx, pj, t, pu)
+}
+
+// publish submits a publish job for the metrics of the parent job with the
+// context, recorded as a child of the span it carries
+func publish(ctx context.Context, pj job, t *task, pu *publishNode) {
+	// the metrics restricted by the export policy only leave the node as
+	// aggregates over large enough groups
+	if pu.aggregator != nil {
+		pj = newBatchJob(pj.Type(), pj.Deadline(), t.id, pu.aggregator.aggregate(pj.Metrics(), t.exportPolicy))
+	} else if len(t.exportPolicy) > 0 {
+		pj = newBatchJob(pj.Type(), pj.Deadline(), t.id, t.exportPolicy.unrestricted(pj.Metrics()))
+	}
+	if t.workflow != nil && t.workflow.rewrite != nil {
+		pj = newBatchJob(pj.Type(), pj.Deadline(), t.id, t.workflow.rewrite.apply(pj.Metrics()))
+	}
+	if pu.ordered {
+		pj = newBatchJob(pj.Type(), pj.Deadline(), t.id, sortMetrics(pj.Metrics()))
+	}
+	if t.publishLimiter != nil {
+		mts, dropped := t.publishLimiter.take(pu, pj.Metrics(), time.Now())
+		if dropped > 0 {
+			workflowLogger.WithFields(log.Fields{
+				"_block":          "submit-publish-job",
+				"task-id":         t.id,
+				"task-name":       t.name,
+				"publish-name":    pu.Name(),
+				"publish-version": pu.Version(),
+				"dropped":         dropped,
+			}).Warn("Publish rate limit exceeded, dropping metrics")
+		}
+		if len(mts) == 0 {
+			return
+		}
+		pj = newBatchJob(pj.Type(), pj.Deadline(), t.id, mts)
+	}
+	if t.tenantLimiter != nil {
+		mts, dropped := t.tenantLimiter.take(pu, pj.Metrics(), time.Now())
+		if dropped > 0 {
+			workflowLogger.WithFields(log.Fields{
+				"_block":          "submit-publish-job",
+				"task-id":         t.id,
+				"task-name":       t.name,
+				"tenant":          core.TenantOf(t),
+				"publish-name":    pu.Name(),
+				"publish-version": pu.Version(),
+				"dropped":         dropped,
+			}).Warn("Tenant quota of metrics exceeded, dropping metrics")
+		}
+		if len(mts) == 0 {
+			return
+		}
+		pj = newBatchJob(pj.Type(), pj.Deadline(), t.id, mts)
+	}
+	if t.inflight != nil {
+		size, ok := t.inflight.admit(t, pu, pj.Metrics())
+		if !ok {
+			return
+		}
+		defer t.inflight.release(size)
+	}
+	submitPublish(ctx, pj, t, pu)
+}
+
+// submitPublish submits a publish job for the metrics of the parent job and
+// waits for its completion
+func submitPublish(ctx context.Context, pj job, t *task, pu *publishNode) {
 	// Create a new process job
-	mgr, err := t.RemoteManagers.Get(pu.Target)
+	var mgr managesMetrics
+	var err error
+	if pu.sink == nil {
+		mgr, err = t.RemoteManagers.Get(pu.Target)
+	}
 	if err != nil {
 		t.RecordFailure([]error{err})
 		workflowLogger.WithFields(log.Fields{
@@ -412,18 +784,110 @@ func submitPublishJob(pj job, t *task, wg *sync.WaitGroup, pu *publishNode) {
 		}).Warn("Error getting control instance")
 		return
 	}
-	j := newPublishJob(pj, pu.Name(), pu.Version(), pu.InboundContentType, pu.config.Table(), mgr, t.id)
-	workflowLogger.WithFields(log.Fields{
-		"_block":           "submit-publish-job",
-		"task-id":          t.id,
-		"task-name":        t.name,
-		"publish-name":     pu.Name(),
-		"publish-version":  pu.Version(),
-		"parent-node-type": pj.TypeString(),
-	}).Debug("Submitting publish job")
-	// Submit the job against the task.managesWork
-	errors := t.manager.Work(j).Promise().Await()
+	var window *dedupWindow
+	var id string
+	if pu.dedup && t.dedup != nil {
+		window = t.dedup.window(t, pu)
+	}
+	if window != nil {
+		id = batchID(pj.Metrics())
+		if window.seen(id) {
+			t.delivery.deduplicated(len(pj.Metrics()))
+			workflowLogger.WithFields(log.Fields{
+				"_block":          "submit-publish-job",
+				"task-id":         t.id,
+				"task-name":       t.name,
+				"publish-name":    pu.Name(),
+				"publish-version": pu.Version(),
+				"batch-id":        id,
+			}).Debug("Batch acknowledged already, not published again")
+			return
+		}
+	}
+	// a retryable failure of the publisher is retried with backoff for the
+	// metrics it did not acknowledge, until the deadline of the publish
+	deadline := time.Now().Add(t.publishTimeout())
+	delay := publishRetryInterval
+	var errors []error
+	for {
+		if pu.sink != nil {
+			// a built-in publisher runs in the workflow engine, without a job
+			errors = nil
+			sctx, cancel := context.WithDeadline(ctx, deadline)
+			if err := pu.sink.publish(sctx, pj.Metrics(), t.clock.Now()); err != nil {
+				errors = []error{err}
+			}
+			cancel()
+		} else {
+			pspan := startJobSpan(tracing.FromContext(ctx), t, "publish", pu.Name(), pu.Version())
+			j := withContext(ctx, traceJob(newPublishJob(pj, deadline, pu.Name(), pu.Version(), pu.InboundContentType, pu.config.Table(), mgr, t.id), pspan))
+			workflowLogger.WithFields(log.Fields{
+				"_block":           "submit-publish-job",
+				"task-id":          t.id,
+				"task-name":        t.name,
+				"publish-name":     pu.Name(),
+				"publish-version":  pu.Version(),
+				"parent-node-type": pj.TypeString(),
+			}).Debug("Submitting publish job")
+			// Submit the job against the task.managesWork
+			errors = t.manager.Work(j).Promise().Await()
+			finishJobSpan(pspan, len(pj.Metrics()), errors)
+			t.usage.record(j, pj.Metrics())
+		}
+		published, retryable := t.delivery.record(len(pj.Metrics()), errors)
+		if retryable == nil {
+			if len(errors) > 0 {
+				t.delivery.undelivered(len(pj.Metrics()) - published)
+			}
+			break
+		}
+		remaining := pj.Metrics()[published:]
+		if !time.Now().Add(delay).Before(deadline) {
+			t.delivery.undelivered(len(remaining))
+			break
+		}
+		workflowLogger.WithFields(log.Fields{
+			"_block":          "submit-publish-job",
+			"task-id":         t.id,
+			"task-name":       t.name,
+			"publish-name":    pu.Name(),
+			"publish-version": pu.Version(),
+			"published":       published,
+			"remaining":       len(remaining),
+			"retry-in":        delay,
+			"_error":          retryable.Error(),
+		}).Warn("Publish job failed, retrying")
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			t.delivery.undelivered(len(remaining))
+			break
+		}
+		t.delivery.retried()
+		pj = newBatchJob(pj.Type(), pj.Deadline(), t.id, remaining)
+		if delay *= 2; delay > maxPublishRetryInterval {
+			delay = maxPublishRetryInterval
+		}
+	}
+	if window != nil && len(errors) == 0 {
+		window.add(id)
+	}
+	t.publishFailures.record(pluginSubject(core.PublisherPluginType.String(), pu.Name(), pu.Version()), errors)
 	// Check for errors and update the task
+	if len(errors) != 0 && pu.ignoreFailures {
+		workflowLogger.WithFields(log.Fields{
+			"_block":           "submit-publish-job",
+			"task-id":          t.id,
+			"task-name":        t.name,
+			"publish-name":     pu.Name(),
+			"publish-version":  pu.Version(),
+			"parent-node-type": pj.TypeString(),
+			"_error":           errors[0].Error(),
+		}).Warn("Publish job failed, failure ignored")
+		return
+	}
 	if len(errors) != 0 {
 		// Record the failures in the task
 		// note: this function is thread safe against t
@@ -22,6 +22,7 @@ limitations under the License.
 package scheduler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -36,6 +37,7 @@ import (
 	"github.com/intelsdi-x/snap/core/ctypes"
 	"github.com/intelsdi-x/snap/core/scheduler_event"
 	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/chrono"
 	"github.com/intelsdi-x/snap/pkg/promise"
 	"github.com/intelsdi-x/snap/pkg/schedule"
 	"github.com/intelsdi-x/snap/plugin/helper"
@@ -122,6 +124,20 @@ func (m MockMetricType) Data() interface{} {
 	return nil
 }
 
+// fireUntil advances the clock of the scheduler by the interval of the task
+// each time the task waits on its schedule, until the listener is done
+func fireUntil(clock *chrono.FakeClock, interval time.Duration, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		clock.BlockUntil(1)
+		clock.Advance(interval)
+	}
+}
+
 func TestCollectPublishWorkflow(t *testing.T) {
 	log.SetLevel(log.FatalLevel)
 	Convey("Given a started plugin control", t, func() {
@@ -135,6 +151,8 @@ func TestCollectPublishWorkflow(t *testing.T) {
 		c.Start()
 		cfg := GetDefaultConfig()
 		s := New(cfg)
+		clock := chrono.NewFakeClock(time.Now())
+		s.clock = clock
 		s.SetMetricManager(c)
 		Convey("create a workflow", func() {
 			rp, err := core.NewRequestedPlugin(snap_collector_mock2_path, c.GetTempDir(), nil)
@@ -185,14 +203,14 @@ func TestCollectPublishWorkflow(t *testing.T) {
 					t, err := s.CreateTask(sch, w, true)
 					So(err.Errors(), ShouldBeEmpty)
 					So(t, ShouldNotBeNil)
-					<-el.done
+					fireUntil(clock, time.Millisecond*200, el.done)
 					So(t.LastFailureMessage(), ShouldBeEmpty)
 					So(t.FailedCount(), ShouldEqual, 0)
 					So(t.HitCount(), ShouldBeGreaterThan, metricsToCollect)
 
 					// check if task fails after unloading publisher
 					c.Unload(plugPublisher)
-					<-el.done
+					fireUntil(clock, time.Millisecond*200, el.done)
 					So(t.LastFailureMessage(), ShouldNotBeEmpty)
 					So(t.FailedCount(), ShouldBeGreaterThan, 0)
 				})
@@ -208,6 +226,8 @@ func TestProcessChainingWorkflow(t *testing.T) {
 		c.Start()
 		cfg := GetDefaultConfig()
 		s := New(cfg)
+		clock := chrono.NewFakeClock(time.Now())
+		s.clock = clock
 		s.SetMetricManager(c)
 		Convey("create a workflow with chained processors", func() {
 			lpe := newEventListener()
@@ -258,7 +278,7 @@ func TestProcessChainingWorkflow(t *testing.T) {
 					s.RegisterEventHandler("TestProcessChainingWorkflow", lpe)
 					So(err.Errors(), ShouldBeEmpty)
 					So(t, ShouldNotBeNil)
-					<-lpe.done
+					fireUntil(clock, time.Millisecond*200, lpe.done)
 					So(t.LastFailureMessage(), ShouldBeEmpty)
 					So(t.FailedCount(), ShouldEqual, 0)
 					So(t.HitCount(), ShouldBeGreaterThan, metricsToCollect)
@@ -349,7 +369,7 @@ func TestWorkJobs(t *testing.T) {
 				prs = append(prs, pr)
 				pus = append(pus, pu)
 			}
-			workJobs(prs, pus, t, pj)
+			workJobs(context.Background(), prs, pus, t, pj)
 			So(t.failedRuns, ShouldEqual, 0)
 			So(m1.queue["processor"], ShouldEqual, 3)
 			So(m1.queue["publisher"], ShouldEqual, 3)
@@ -385,7 +405,7 @@ func TestWorkJobs(t *testing.T) {
 				pr.ProcessNodes = cprs
 				pr.PublishNodes = cpus
 			}
-			workJobs(prs, pus, t, pj)
+			workJobs(context.Background(), prs, pus, t, pj)
 			So(t.failedRuns, ShouldEqual, 0)
 			// (3*3)+3
 			So(m2.queue["processor"], ShouldEqual, 12)
@@ -426,7 +446,7 @@ func TestWorkJobs(t *testing.T) {
 				pr.ProcessNodes = cprs
 				pr.PublishNodes = cpus
 			}
-			workJobs(prs, pus, t, pj)
+			workJobs(context.Background(), prs, pus, t, pj)
 			So(t.failedRuns, ShouldEqual, 1)
 			So(t.lastFailureMessage, ShouldEqual, "I am an error")
 			// (3*3)+3
@@ -40,11 +40,17 @@ import (
 	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/intelsdi-x/snap/control"
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/mgmt/rest"
+	"github.com/intelsdi-x/snap/mgmt/rest/api"
 	"github.com/intelsdi-x/snap/mgmt/tribe"
 	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
 	"github.com/intelsdi-x/snap/pkg/cfgfile"
+	"github.com/intelsdi-x/snap/pkg/logging"
+	"github.com/intelsdi-x/snap/pkg/netutil"
+	"github.com/intelsdi-x/snap/pkg/psigning"
 	"github.com/intelsdi-x/snap/scheduler"
 	"google.golang.org/grpc/grpclog"
 )
@@ -79,6 +85,11 @@ var (
 		Usage:  fmt.Sprintf("1-5 (Debug, Info, Warning, Error, Fatal; default: %v)", defaultLogLevel),
 		EnvVar: "SNAP_LOG_LEVEL",
 	}
+	flLogFormat = cli.StringFlag{
+		Name:   "log-format",
+		Usage:  fmt.Sprintf("Format of the log entries: text or json (default: %v)", defaultLogFormat),
+		EnvVar: "SNAP_LOG_FORMAT",
+	}
 	flConfig = cli.StringFlag{
 		Name:   "config",
 		Usage:  "A path to a config file",
@@ -114,23 +125,39 @@ const (
 	defaultLogPath     string = ""
 	defaultLogTruncate bool   = false
 	defaultLogColors   bool   = true
-	defaultConfigPath  string = "/etc/snap/snapteld.conf"
+	defaultLogFormat   string = "text"
+	// the log file is not rotated by default
+	defaultLogMaxSize    int = 0
+	defaultLogMaxBackups int = 5
 )
 
+// defaultConfigPath is the configuration file read when none is given,
+// %ProgramData%\snap\snapteld.conf on Windows
+var defaultConfigPath = func() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "snap", "snapteld.conf")
+	}
+	return "/etc/snap/snapteld.conf"
+}()
+
 // holds the configuration passed in through the SNAP config file
 //   Note: if this struct is modified, then the switch statement in the
 //         UnmarshalJSON method in this same file needs to be modified to
 //         match the field mapping that is defined here
 type Config struct {
-	LogLevel    int               `json:"log_level,omitempty"yaml:"log_level,omitempty"`
-	GoMaxProcs  int               `json:"gomaxprocs,omitempty"yaml:"gomaxprocs,omitempty"`
-	LogPath     string            `json:"log_path,omitempty"yaml:"log_path,omitempty"`
-	LogTruncate bool              `json:"log_truncate,omitempty"yaml:"log_truncate,omitempty"`
-	LogColors   bool              `json:"log_colors,omitempty"yaml:"log_colors,omitempty"`
-	Control     *control.Config   `json:"control,omitempty"yaml:"control,omitempty"`
-	Scheduler   *scheduler.Config `json:"scheduler,omitempty"yaml:"scheduler,omitempty"`
-	RestAPI     *rest.Config      `json:"restapi,omitempty"yaml:"restapi,omitempty"`
-	Tribe       *tribe.Config     `json:"tribe,omitempty"yaml:"tribe,omitempty"`
+	LogLevel      int               `json:"log_level,omitempty"yaml:"log_level,omitempty"`
+	GoMaxProcs    int               `json:"gomaxprocs,omitempty"yaml:"gomaxprocs,omitempty"`
+	LogPath       string            `json:"log_path,omitempty"yaml:"log_path,omitempty"`
+	LogTruncate   bool              `json:"log_truncate,omitempty"yaml:"log_truncate,omitempty"`
+	LogColors     bool              `json:"log_colors,omitempty"yaml:"log_colors,omitempty"`
+	LogFormat     string            `json:"log_format,omitempty"yaml:"log_format,omitempty"`
+	LogLevels     map[string]int    `json:"log_levels,omitempty"yaml:"log_levels,omitempty"`
+	LogMaxSize    int               `json:"log_max_size,omitempty"yaml:"log_max_size,omitempty"`
+	LogMaxBackups int               `json:"log_max_backups,omitempty"yaml:"log_max_backups,omitempty"`
+	Control       *control.Config   `json:"control,omitempty"yaml:"control,omitempty"`
+	Scheduler     *scheduler.Config `json:"scheduler,omitempty"yaml:"scheduler,omitempty"`
+	RestAPI       *rest.Config      `json:"restapi,omitempty"yaml:"restapi,omitempty"`
+	Tribe         *tribe.Config     `json:"tribe,omitempty"yaml:"tribe,omitempty"`
 }
 
 const (
@@ -157,6 +184,30 @@ const (
 				"description": "log file colored output default is true",
 				"type": "boolean"
 			},
+			"log_format": {
+				"description": "format of the log entries, text or json",
+				"type": "string",
+				"enum": ["text", "json"]
+			},
+			"log_levels": {
+				"description": "log verbosity level of the modules of snapteld, e.g. scheduler, control or rest",
+				"type": ["object", "null"],
+				"additionalProperties": {
+					"type": "integer",
+					"minimum": 1,
+					"maximum": 5
+				}
+			},
+			"log_max_size": {
+				"description": "size in megabytes the log file is rotated at, 0 disables the rotation",
+				"type": "integer",
+				"minimum": 0
+			},
+			"log_max_backups": {
+				"description": "number of rotated log files kept",
+				"type": "integer",
+				"minimum": 0
+			},
 			"gomaxprocs": {
 				"description": "value to be used for gomaxprocs",
 				"type": "integer",
@@ -218,6 +269,7 @@ func main() {
 		flLogPath,
 		flLogTruncate,
 		flLogColors,
+		flLogFormat,
 		flMaxProcs,
 		flConfig,
 	}
@@ -249,13 +301,8 @@ func action(ctx *cli.Context) error {
 	// test the resulting configuration to ensure the values it contains still pass the
 	// constraints after applying the environment variables and command-line parameters;
 	// if errors are found, report them and exit with a fatal error
-	jb, _ := json.Marshal(cfg)
-	serrs := cfgfile.ValidateSchema(CONFIG_CONSTRAINTS, string(jb))
-	if serrs != nil {
-		for _, serr := range serrs {
-			log.WithFields(serr.Fields()).Error(serr.Error())
-		}
-		log.Fatal("Errors found after applying command-line flags")
+	if err := validateConfig(cfg); err != nil {
+		log.Fatal(err)
 	}
 
 	// If logPath is set, we verify the logPath and set it so that all logging
@@ -269,11 +316,7 @@ func action(ctx *cli.Context) error {
 		if !f.IsDir() {
 			log.Fatal("log path provided must be a directory")
 		}
-		aMode := os.O_APPEND
-		if cfg.LogTruncate {
-			aMode = os.O_TRUNC
-		}
-		file, err := os.OpenFile(fmt.Sprintf("%s/snapteld.log", logPath), os.O_RDWR|os.O_CREATE|aMode, 0666)
+		file, err := logging.NewRotatingFile(fmt.Sprintf("%s/snapteld.log", logPath), int64(cfg.LogMaxSize)*1024*1024, cfg.LogMaxBackups, cfg.LogTruncate)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -299,17 +342,24 @@ func action(ctx *cli.Context) error {
 	// We could also restrict this command line parameter to only apply when no logpath is given
 	// and forcing the coloring to off when using a file but this might not please users who like to use
 	// redirect mechanisms like # snapteld -t 0 -l 1 2>&1 | tee my.log
-	if !cfg.LogColors {
-		log.SetFormatter(&log.TextFormatter{FullTimestamp: true, DisableColors: true})
-	} else {
-		log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	var formatter log.Formatter
+	switch {
+	case cfg.LogFormat == "json":
+		formatter = &log.JSONFormatter{}
+	case !cfg.LogColors:
+		formatter = &log.TextFormatter{FullTimestamp: true, DisableColors: true}
+	default:
+		formatter = &log.TextFormatter{FullTimestamp: true}
 	}
 
 	// Validate log level and trust level settings for snapteld
 	validateLevelSettings(cfg.LogLevel, cfg.Control.PluginTrust)
 
-	// Switch log level to user defined
-	log.SetLevel(getLevel(cfg.LogLevel))
+	// Switch log levels to user defined, for snapteld and for its modules
+	logFilter, err := logging.New(formatter, logLevels(cfg))
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	//Set standard logger as logger for grpc
 	grpclog.SetLogger(log.StandardLogger())
@@ -333,8 +383,14 @@ func action(ctx *cli.Context) error {
 	coreModules = append(coreModules, c)
 	s := scheduler.New(cfg.Scheduler)
 	s.SetMetricManager(c)
+	// the scheduler tracks the tasks impacted by frozen plugins
+	c.RegisterEventHandler("scheduler", s)
 	coreModules = append(coreModules, s)
 
+	// the settings which change without a restart are reloaded on SIGHUP
+	// or through the REST API
+	rl := &reloader{ctx: ctx, cfg: cfg, control: c, scheduler: s, logging: logFilter}
+
 	// Auth requested and not provided as part of config
 	if cfg.RestAPI.Enable && cfg.RestAPI.RestAuth && cfg.RestAPI.RestAuthPassword == "" {
 		fmt.Println("What password do you want to use for authentication?")
@@ -364,6 +420,8 @@ func action(ctx *cli.Context) error {
 		t.SetPluginCatalog(c)
 		s.RegisterEventHandler("tribe", t)
 		t.SetTaskManager(s)
+		s.SetTaskPlacement(t)
+		s.SetLeaderElection(t)
 		coreModules = append(coreModules, t)
 		tr = t
 	}
@@ -377,6 +435,9 @@ func action(ctx *cli.Context) error {
 		r.BindMetricManager(c)
 		r.BindConfigManager(c.Config)
 		r.BindTaskManager(s)
+		r.BindDaemonInfo(daemonInfo(cfg, c.Config.IsTLSEnabled()))
+		r.BindLogging(logFilter)
+		r.BindReloader(rl)
 
 		//Rest Authentication
 		if cfg.RestAPI.RestAuth {
@@ -388,6 +449,12 @@ func action(ctx *cli.Context) error {
 				log.Warning("Using REST API authentication without HTTPS enabled.")
 			}
 		}
+		if cfg.RestAPI.Auth != nil {
+			log.Info("REST API role-based authorization is enabled")
+			if !cfg.RestAPI.HTTPS {
+				log.Warning("Using REST API credentials without HTTPS enabled.")
+			}
+		}
 
 		if tr != nil {
 			r.BindTribeManager(tr)
@@ -399,9 +466,9 @@ func action(ctx *cli.Context) error {
 		log.Info("REST API is disabled")
 	}
 
-	// Set interrupt handling so we can either restart the app on a SIGHUP or
-	// die gracefully when an interrupt, kill, etc. are received
-	startInterruptHandling(coreModules...)
+	// Set interrupt handling so we can either reload the configuration on a
+	// SIGHUP or die gracefully when an interrupt, kill, etc. are received
+	startInterruptHandling(rl, coreModules...)
 
 	// Start our modules
 	var started []coreModule
@@ -418,89 +485,24 @@ func action(ctx *cli.Context) error {
 	// Plugin Trust
 	c.SetPluginTrustLevel(cfg.Control.PluginTrust)
 	log.Info("setting plugin trust level to: ", t[cfg.Control.PluginTrust])
+	if cfg.Control.PluginTrust == control.PluginTrustDisabled {
+		log.WithFields(
+			log.Fields{
+				"block":   "main",
+				"_module": logModule,
+			}).Warning("plugin signatures are not verified, any executable can be loaded as a plugin")
+	}
 	// Keyring checking for trust levels 1 and 2
 	if cfg.Control.PluginTrust > 0 {
-		keyrings := filepath.SplitList(cfg.Control.KeyringPaths)
-		if len(keyrings) == 0 {
+		keyrings, err := keyringFiles(cfg.Control.KeyringPaths)
+		if err != nil {
 			log.WithFields(
 				log.Fields{
 					"block":   "main",
 					"_module": logModule,
-				}).Fatal("need keyring file when trust is on (--keyring-file or -k)")
-		}
-		for _, k := range keyrings {
-			keyringPath, err := filepath.Abs(k)
-			if err != nil {
-				log.WithFields(
-					log.Fields{
-						"block":       "main",
-						"_module":     logModule,
-						"error":       err.Error(),
-						"keyringPath": keyringPath,
-					}).Fatal("Unable to determine absolute path to keyring file")
-			}
-			f, err := os.Stat(keyringPath)
-			if err != nil {
-				log.WithFields(
-					log.Fields{
-						"block":       "main",
-						"_module":     logModule,
-						"error":       err.Error(),
-						"keyringPath": keyringPath,
-					}).Fatal("bad keyring file")
-			}
-			if f.IsDir() {
-				log.Info("Adding keyrings from: ", keyringPath)
-				files, err := ioutil.ReadDir(keyringPath)
-				if err != nil {
-					log.WithFields(
-						log.Fields{
-							"_block":      "main",
-							"_module":     logModule,
-							"error":       err.Error(),
-							"keyringPath": keyringPath,
-						}).Fatal(err)
-				}
-				if len(files) == 0 {
-					log.Fatal(fmt.Sprintf("given keyring path [%s] is an empty directory!", keyringPath))
-				}
-				for _, keyringFile := range files {
-					if keyringFile.IsDir() {
-						continue
-					}
-					if strings.HasSuffix(keyringFile.Name(), ".gpg") || (strings.HasSuffix(keyringFile.Name(), ".pub")) || (strings.HasSuffix(keyringFile.Name(), ".pubring")) {
-						f, err := os.Open(keyringPath)
-						if err != nil {
-							log.WithFields(
-								log.Fields{
-									"block":       "main",
-									"_module":     logModule,
-									"error":       err.Error(),
-									"keyringPath": keyringPath,
-								}).Warning("unable to open keyring file. not adding to keyring path")
-							continue
-						}
-						f.Close()
-						log.Info("adding keyring file: ", keyringPath+"/"+keyringFile.Name())
-						c.SetKeyringFile(keyringPath + "/" + keyringFile.Name())
-					}
-				}
-			} else {
-				f, err := os.Open(keyringPath)
-				if err != nil {
-					log.WithFields(
-						log.Fields{
-							"block":       "main",
-							"_module":     logModule,
-							"error":       err.Error(),
-							"keyringPath": keyringPath,
-						}).Fatal("unable to open keyring file.")
-				}
-				f.Close()
-				log.Info("adding keyring file ", keyringPath)
-				c.SetKeyringFile(keyringPath)
-			}
+				}).Fatal(err)
 		}
+		c.SetKeyringFiles(keyrings)
 	}
 
 	log.WithFields(
@@ -549,23 +551,34 @@ func action(ctx *cli.Context) error {
 // get the default snapteld configuration
 func getDefaultConfig() *Config {
 	return &Config{
-		LogLevel:    defaultLogLevel,
-		GoMaxProcs:  defaultGoMaxProcs,
-		LogPath:     defaultLogPath,
-		LogTruncate: defaultLogTruncate,
-		LogColors:   defaultLogColors,
-		Control:     control.GetDefaultConfig(),
-		Scheduler:   scheduler.GetDefaultConfig(),
-		RestAPI:     rest.GetDefaultConfig(),
-		Tribe:       tribe.GetDefaultConfig(),
+		LogLevel:      defaultLogLevel,
+		GoMaxProcs:    defaultGoMaxProcs,
+		LogPath:       defaultLogPath,
+		LogTruncate:   defaultLogTruncate,
+		LogColors:     defaultLogColors,
+		LogFormat:     defaultLogFormat,
+		LogMaxSize:    defaultLogMaxSize,
+		LogMaxBackups: defaultLogMaxBackups,
+		Control:       control.GetDefaultConfig(),
+		Scheduler:     scheduler.GetDefaultConfig(),
+		RestAPI:       rest.GetDefaultConfig(),
+		Tribe:         tribe.GetDefaultConfig(),
 	}
 }
 
 // Read the snapteld configuration from a configuration file
 func readConfig(cfg *Config, fpath string) {
+	if err := readConfigFile(cfg, fpath); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// readConfigFile reads the snapteld configuration from the given file, or
+// from the default configuration file when none is given
+func readConfigFile(cfg *Config, fpath string) error {
 	var path string
 	if !defaultConfigFile() && fpath == "" {
-		return
+		return nil
 	}
 	if defaultConfigFile() && fpath == "" {
 		path = defaultConfigPath
@@ -573,10 +586,10 @@ func readConfig(cfg *Config, fpath string) {
 	if fpath != "" {
 		f, err := os.Stat(fpath)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 		if f.IsDir() {
-			log.Fatal("configuration path provided must be a file")
+			return fmt.Errorf("configuration path provided must be a file")
 		}
 		path = fpath
 	}
@@ -586,8 +599,92 @@ func readConfig(cfg *Config, fpath string) {
 		for _, serr := range serrs {
 			log.WithFields(serr.Fields()).Error(serr.Error())
 		}
-		log.Fatal("Errors found while parsing global configuration file")
+		return fmt.Errorf("Errors found while parsing global configuration file")
 	}
+	return nil
+}
+
+// validateConfig tests the configuration against the constraints of the
+// configuration file, reporting the values which do not pass them
+func validateConfig(cfg *Config) error {
+	jb, _ := json.Marshal(cfg)
+	serrs := cfgfile.ValidateSchema(CONFIG_CONSTRAINTS, string(jb))
+	if serrs != nil {
+		for _, serr := range serrs {
+			log.WithFields(serr.Fields()).Error(serr.Error())
+		}
+		return fmt.Errorf("Errors found after applying command-line flags")
+	}
+	return nil
+}
+
+// logLevels returns the log levels of snapteld and of its modules
+func logLevels(cfg *Config) logging.Levels {
+	levels := logging.Levels{Default: getLevel(cfg.LogLevel).String(), Modules: map[string]string{}}
+	for module, level := range cfg.LogLevels {
+		levels.Modules[module] = getLevel(level).String()
+	}
+	return levels
+}
+
+// keyringFiles returns the keyring files found in the list of keyring files
+// and directories
+func keyringFiles(paths string) ([]string, error) {
+	keyrings := filepath.SplitList(paths)
+	if len(keyrings) == 0 {
+		return nil, fmt.Errorf("need keyring file when trust is on (--keyring-file or -k)")
+	}
+	files := []string{}
+	for _, k := range keyrings {
+		keyringPath, err := filepath.Abs(k)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to determine absolute path to keyring file %s: %v", k, err)
+		}
+		f, err := os.Stat(keyringPath)
+		if err != nil {
+			return nil, fmt.Errorf("bad keyring file: %v", err)
+		}
+		if !f.IsDir() {
+			f, err := os.Open(keyringPath)
+			if err != nil {
+				return nil, fmt.Errorf("unable to open keyring file: %v", err)
+			}
+			f.Close()
+			log.Info("adding keyring file ", keyringPath)
+			files = append(files, keyringPath)
+			continue
+		}
+		log.Info("Adding keyrings from: ", keyringPath)
+		dir, err := ioutil.ReadDir(keyringPath)
+		if err != nil {
+			return nil, err
+		}
+		if len(dir) == 0 {
+			return nil, fmt.Errorf("given keyring path [%s] is an empty directory!", keyringPath)
+		}
+		for _, keyringFile := range dir {
+			if keyringFile.IsDir() {
+				continue
+			}
+			if strings.HasSuffix(keyringFile.Name(), ".gpg") || (strings.HasSuffix(keyringFile.Name(), ".pub")) || (strings.HasSuffix(keyringFile.Name(), ".pubring")) || psigning.IsCertificateFile(keyringFile.Name()) {
+				f, err := os.Open(keyringPath)
+				if err != nil {
+					log.WithFields(
+						log.Fields{
+							"block":       "main",
+							"_module":     logModule,
+							"error":       err.Error(),
+							"keyringPath": keyringPath,
+						}).Warning("unable to open keyring file. not adding to keyring path")
+					continue
+				}
+				f.Close()
+				log.Info("adding keyring file: ", keyringPath+"/"+keyringFile.Name())
+				files = append(files, keyringPath+"/"+keyringFile.Name())
+			}
+		}
+	}
+	return files, nil
 }
 
 func defaultConfigFile() bool {
@@ -690,16 +787,16 @@ func isValidAddress(addr string, errPrefix string) error {
 // sanity checks the input address and port values to ensure they are set
 // appropriately, specifically:
 //
-//	  - ensure that if the port value is set, the addr value does not also
-//		include as part of the addr value (i.e. that the addr value is not a
-//		string of the form IP_ADDR:PORT or HOSTNAME:PORT)
-//	  - ensures that if a port is specified as part of the addr value, that port
-//		string is not an empty string (i.e. that the ':' character is not the
-//		last character in the addr value)
-//	  - ensures that the address portion of the addr value can be either parsed
-//		as an IP address or used as a hostname
-//	  - ensures that the port detected as part of the addr value (if there is one)
-//		can be parsed as an integer
+//   - ensure that if the port value is set, the addr value does not also
+//     include as part of the addr value (i.e. that the addr value is not a
+//     string of the form IP_ADDR:PORT or HOSTNAME:PORT)
+//   - ensures that if a port is specified as part of the addr value, that port
+//     string is not an empty string (i.e. that the ':' character is not the
+//     last character in the addr value)
+//   - ensures that the address portion of the addr value can be either parsed
+//     as an IP address or used as a hostname
+//   - ensures that the port detected as part of the addr value (if there is one)
+//     can be parsed as an integer
 //
 // this function returns a boolean indicating whether or not a port number was
 // found in the address and either nil or an error (depending on whether or not
@@ -710,13 +807,22 @@ func checkHostPortVals(addr string, port *int, errPrefix string) (bool, error) {
 	if len(addr) == 0 {
 		return portInAddrFlag, nil
 	}
+	// a Unix domain socket address has no port
+	if _, ok := netutil.UnixSocketPath(addr); ok {
+		return portInAddrFlag, nil
+	}
 	// If the input address ontains a comma, return an error
 	if strings.Index(addr, ",") != -1 {
 		errString := fmt.Sprintf("%s Invalid address; comma-separated IP address values are not supported", errPrefix)
 		return false, errors.New(errString)
 	}
-	// check to see if the input address contains a colon or not
-	idx := strings.Index(addr, ":")
+	// an IPv6 address without a port, which may be enclosed in square brackets
+	if net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")) != nil {
+		return portInAddrFlag, nil
+	}
+	// check to see if the input address contains a colon or not (the last
+	// one, as IPv6 addresses with a port are of the form [IPV6_ADDR]:PORT)
+	idx := strings.LastIndex(addr, ":")
 	if idx == -1 {
 		// if we don't find a colon in the address, then just try to parse it as
 		// an IP address; return an error if we can't parse it successfully
@@ -843,11 +949,17 @@ func applyCmdLineFlags(cfg *Config, ctx runtimeFlagsContext) {
 	cfg.LogPath = setStringVal(cfg.LogPath, ctx, "log-path")
 	cfg.LogTruncate = setBoolVal(cfg.LogTruncate, ctx, "log-truncate")
 	cfg.LogColors = setBoolVal(cfg.LogColors, ctx, "log-colors")
+	cfg.LogFormat = setStringVal(cfg.LogFormat, ctx, "log-format")
 	// next for the flags related to the control package
 	cfg.Control.MaxRunningPlugins = setIntVal(cfg.Control.MaxRunningPlugins, ctx, "max-running-plugins")
 	cfg.Control.PluginLoadTimeout = setIntVal(cfg.Control.PluginLoadTimeout, ctx, "plugin-load-timeout")
 	cfg.Control.PluginTrust = setIntVal(cfg.Control.PluginTrust, ctx, "plugin-trust")
+	// --insecure overrides the plugin trust level of the configuration
+	if ctx.Bool("insecure") {
+		cfg.Control.PluginTrust = control.PluginTrustDisabled
+	}
 	cfg.Control.AutoDiscoverPath = setStringVal(cfg.Control.AutoDiscoverPath, ctx, "auto-discover")
+	cfg.Control.AutoDiscoverWatchInterval = jsonutil.Duration{setDurationVal(cfg.Control.AutoDiscoverWatchInterval.Duration, ctx, "auto-discover-watch-interval")}
 	cfg.Control.KeyringPaths = setStringVal(cfg.Control.KeyringPaths, ctx, "keyring-paths")
 	cfg.Control.CacheExpiration = jsonutil.Duration{setDurationVal(cfg.Control.CacheExpiration.Duration, ctx, "cache-expiration")}
 	cfg.Control.ListenAddr = setStringVal(cfg.Control.ListenAddr, ctx, "control-listen-addr")
@@ -864,9 +976,11 @@ func applyCmdLineFlags(cfg *Config, ctx runtimeFlagsContext) {
 	cfg.RestAPI.HTTPS = setBoolVal(cfg.RestAPI.HTTPS, ctx, "rest-https")
 	cfg.RestAPI.RestCertificate = setStringVal(cfg.RestAPI.RestCertificate, ctx, "rest-cert")
 	cfg.RestAPI.RestKey = setStringVal(cfg.RestAPI.RestKey, ctx, "rest-key")
+	cfg.RestAPI.RestClientCA = setStringVal(cfg.RestAPI.RestClientCA, ctx, "rest-client-ca")
 	cfg.RestAPI.RestAuth = setBoolVal(cfg.RestAPI.RestAuth, ctx, "rest-auth")
 	cfg.RestAPI.RestAuthPassword = setStringVal(cfg.RestAPI.RestAuthPassword, ctx, "rest-auth-pwd")
 	cfg.RestAPI.Pprof = setBoolVal(cfg.RestAPI.Pprof, ctx, "pprof")
+	cfg.RestAPI.Metrics = setBoolVal(cfg.RestAPI.Metrics, ctx, "rest-metrics")
 	cfg.RestAPI.Corsd = setStringVal(cfg.RestAPI.Corsd, ctx, "allowed_origins")
 
 	// next for the scheduler related flags
@@ -899,8 +1013,10 @@ func applyCmdLineFlags(cfg *Config, ctx runtimeFlagsContext) {
 		// (regardless of whether it came from the default configuration, configuration
 		// file, an environment variable, or a command-line flag); in that case we should
 		// set the address in the RestAPI configuration to be the current address and port
-		// (separated by a ':')
-		cfg.RestAPI.Address = fmt.Sprintf("%v:%v", cfg.RestAPI.Address, cfg.RestAPI.Port)
+		// (separated by a ':'), unless the address is a Unix domain socket
+		if _, ok := netutil.UnixSocketPath(cfg.RestAPI.Address); !ok {
+			cfg.RestAPI.Address = netutil.JoinHostPort(cfg.RestAPI.Address, cfg.RestAPI.Port)
+		}
 	}
 }
 
@@ -1004,6 +1120,22 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 			if err := json.Unmarshal(v, &(c.LogColors)); err != nil {
 				return fmt.Errorf("%v (while parsing 'log_colors')", err)
 			}
+		case "log_format":
+			if err := json.Unmarshal(v, &(c.LogFormat)); err != nil {
+				return fmt.Errorf("%v (while parsing 'log_format')", err)
+			}
+		case "log_levels":
+			if err := json.Unmarshal(v, &(c.LogLevels)); err != nil {
+				return fmt.Errorf("%v (while parsing 'log_levels')", err)
+			}
+		case "log_max_size":
+			if err := json.Unmarshal(v, &(c.LogMaxSize)); err != nil {
+				return fmt.Errorf("%v (while parsing 'log_max_size')", err)
+			}
+		case "log_max_backups":
+			if err := json.Unmarshal(v, &(c.LogMaxBackups)); err != nil {
+				return fmt.Errorf("%v (while parsing 'log_max_backups')", err)
+			}
 		case "control":
 			if err := json.Unmarshal(v, c.Control); err != nil {
 				return err
@@ -1050,13 +1182,53 @@ func printErrorAndExit(name string, err error) {
 		}).Fatal("error starting module")
 }
 
-func startInterruptHandling(modules ...coreModule) {
+// daemonInfo describes this snapteld for the clients of its REST API
+func daemonInfo(cfg *Config, pluginTLS bool) *api.DaemonInfo {
+	info := &api.DaemonInfo{
+		Version:   gitversion,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Subsystems: map[string]bool{
+			"tribe":      cfg.Tribe.Enable,
+			"rest_https": cfg.RestAPI.HTTPS,
+			"rest_mtls":  cfg.RestAPI.HTTPS && cfg.RestAPI.RestClientCA != "",
+			"rest_auth":  cfg.RestAPI.RestAuth || cfg.RestAPI.Auth != nil,
+			"plugin_tls": pluginTLS,
+		},
+		ScheduleTypes: core.ScheduleTypes,
+	}
+	for _, t := range plugin.RPCTypes {
+		info.PluginRPCTypes = append(info.PluginRPCTypes, t.String())
+	}
+	return info
+}
+
+func startInterruptHandling(rl *reloader, modules ...coreModule) {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, os.Kill, syscall.SIGTERM, syscall.SIGHUP)
 
 	//Let's block until someone tells us to quit
 	go func() {
 		sig := <-c
+		for sig == syscall.SIGHUP {
+			// reload the configuration, the running tasks go on
+			log.WithFields(
+				log.Fields{
+					"block":   "main",
+					"_module": logModule,
+					"signal":  sig.String(),
+				}).Info("reloading configuration")
+			if _, err := rl.Reload(); err != nil {
+				log.WithFields(
+					log.Fields{
+						"block":   "main",
+						"_module": logModule,
+						"error":   err.Error(),
+					}).Error("unable to reload configuration, keeping the current one")
+			}
+			sig = <-c
+		}
 		log.WithFields(
 			log.Fields{
 				"block":   "main",
@@ -1072,28 +1244,13 @@ func startInterruptHandling(modules ...coreModule) {
 				}).Info("stopping module")
 			m.Stop()
 		}
-		if sig == syscall.SIGHUP {
-			// log the action we're taking (restarting the app)
-			log.WithFields(
-				log.Fields{
-					"block":   "main",
-					"_module": logModule,
-					"signal":  sig.String(),
-				}).Info("restarting app")
-			// and restart the app (with the current configuration)
-			err := cliApp.Run(os.Args)
-			if err != nil {
-				os.Exit(1)
-			}
-		} else {
-			log.WithFields(
-				log.Fields{
-					"block":   "main",
-					"_module": logModule,
-					"signal":  sig.String(),
-				}).Info("exiting on signal")
-			os.Exit(0)
-		}
+		log.WithFields(
+			log.Fields{
+				"block":   "main",
+				"_module": logModule,
+				"signal":  sig.String(),
+			}).Info("exiting on signal")
+		os.Exit(0)
 	}()
 }
 
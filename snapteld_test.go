@@ -261,6 +261,32 @@ func Test_checkCmdLineFlags(t *testing.T) {
 			wantErr:        false,
 			wantPort:       9002,
 			wantPortInAddr: true},
+		{name: "CmdlineArgsWithIPv6AddressParseWell",
+			msg: func(f func(string)) {
+				f("Having an IPv6 API address on command line, parsing succeeds")
+			},
+			ctx:            testCtx.getCopy().update("api-addr", "::1"),
+			wantErr:        false,
+			wantPort:       9000,
+			wantPortInAddr: false},
+		{name: "CmdlineArgsWithIPv6AddressAndPortParseWell",
+			msg: func(f func(string)) {
+				f("Having an IPv6 API address with a port on command line, parsing succeeds")
+			},
+			ctx: testCtx.
+				copyWithout("api-port").
+				update("api-addr", "[::1]:9003"),
+			wantErr:        false,
+			wantPort:       9003,
+			wantPortInAddr: true},
+		{name: "CmdlineArgsWithUnixSocketParseWell",
+			msg: func(f func(string)) {
+				f("Having a Unix domain socket API address on command line, parsing succeeds")
+			},
+			ctx:            testCtx.getCopy().update("api-addr", "unix:///var/run/snap/snapteld.sock"),
+			wantErr:        false,
+			wantPort:       9000,
+			wantPortInAddr: false},
 		{name: "ArgsWithTLSCertWithoutKey_Fail",
 			msg: func(f func(string)) {
 				f("Having command line flags with TLS cert without key, parsing fails")